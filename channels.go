@@ -0,0 +1,101 @@
+package showtime
+
+import (
+	"fmt"
+
+	"github.com/golden-vcr/showtime/internal/events"
+	"github.com/golden-vcr/showtime/internal/twitch"
+	"github.com/nicklaw5/helix/v2"
+)
+
+// PartnerRequiredSubscriptions declares the subscriptions that we maintain for partner
+// channels: we track their broadcasts and alert on incoming raids, none of which
+// requires the partner broadcaster to authorize our app
+var PartnerRequiredSubscriptions = []events.RequiredSubscription{
+	{
+		Type:    helix.EventSubTypeChannelUpdate,
+		Version: "2",
+		TemplatedCondition: helix.EventSubCondition{
+			BroadcasterUserID: "{{.ChannelUserId}}",
+		},
+	},
+	{
+		Type:    helix.EventSubTypeStreamOnline,
+		Version: "1",
+		TemplatedCondition: helix.EventSubCondition{
+			BroadcasterUserID: "{{.ChannelUserId}}",
+		},
+	},
+	{
+		Type:    helix.EventSubTypeStreamOffline,
+		Version: "1",
+		TemplatedCondition: helix.EventSubCondition{
+			BroadcasterUserID: "{{.ChannelUserId}}",
+		},
+	},
+	{
+		Type:    helix.EventSubTypeChannelRaid,
+		Version: "1",
+		TemplatedCondition: helix.EventSubCondition{
+			ToBroadcasterUserID: "{{.ChannelUserId}}",
+		},
+	},
+}
+
+// Channel is a Twitch channel for which showtime tracks broadcasts, screenings, chat,
+// and alerts. The primary channel (i.e. GoldenVCR) is the one whose broadcaster has
+// authorized our app, and whose viewers earn fun points: all other channels are
+// partner channels.
+type Channel struct {
+	UserId    string `json:"userId"`
+	Name      string `json:"name"`
+	IsPrimary bool   `json:"isPrimary"`
+}
+
+// RequiredSubscriptions returns the set of EventSub subscriptions that we require in
+// order to support this channel
+func (c *Channel) RequiredSubscriptions() []events.RequiredSubscription {
+	if c.IsPrimary {
+		return RequiredSubscriptions
+	}
+	return PartnerRequiredSubscriptions
+}
+
+// ResolveChannels looks up the user ID of the broadcaster for the primary channel and
+// for each partner channel, returning the primary channel first. Partner channels that
+// are listed more than once (or that duplicate the primary channel) are ignored.
+func ResolveChannels(client twitch.UserReader, primaryChannelName string, partnerChannelNames []string) ([]Channel, error) {
+	channels := make([]Channel, 0, 1+len(partnerChannelNames))
+	seen := make(map[string]struct{}, 1+len(partnerChannelNames))
+	for i, name := range append([]string{primaryChannelName}, partnerChannelNames...) {
+		if name == "" {
+			continue
+		}
+		if _, ok := seen[name]; ok {
+			continue
+		}
+		seen[name] = struct{}{}
+
+		userId, err := twitch.GetChannelUserId(client, name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get user ID for channel '%s': %w", name, err)
+		}
+		channels = append(channels, Channel{
+			UserId:    userId,
+			Name:      name,
+			IsPrimary: i == 0,
+		})
+	}
+	return channels, nil
+}
+
+// FindChannel returns the channel with the given name, or nil if there is no such
+// channel
+func FindChannel(channels []Channel, name string) *Channel {
+	for i := range channels {
+		if channels[i].Name == name {
+			return &channels[i]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,49 @@
+package showtime
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/golden-vcr/showtime/internal/events"
+	"github.com/golden-vcr/showtime/internal/twitch"
+	"github.com/golden-vcr/showtime/internal/twitchtest"
+	"github.com/nicklaw5/helix/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_ResolveChannels(t *testing.T) {
+	fake := twitchtest.New(twitchtest.Config{
+		ClientId:     "my-client-id",
+		ClientSecret: "my-client-secret",
+		Users: []helix.User{
+			{ID: "953753877", Login: "goldenvcr", DisplayName: "GoldenVCR"},
+			{ID: "1234", Login: "friend", DisplayName: "Friend"},
+		},
+	})
+	api := httptest.NewServer(fake)
+	defer api.Close()
+	c, err := twitch.NewClientWithAppToken(fake.Endpoints(api.URL), "my-client-id", "my-client-secret")
+	assert.NoError(t, err)
+
+	// The primary channel should be first, and duplicate partner channels should be
+	// ignored
+	channels, err := ResolveChannels(c, "goldenvcr", []string{"friend", "goldenvcr", "friend"})
+	assert.NoError(t, err)
+	assert.Equal(t, []Channel{
+		{UserId: "953753877", Name: "goldenvcr", IsPrimary: true},
+		{UserId: "1234", Name: "friend", IsPrimary: false},
+	}, channels)
+	assert.Equal(t, RequiredSubscriptions, channels[0].RequiredSubscriptions())
+	assert.Equal(t, PartnerRequiredSubscriptions, channels[1].RequiredSubscriptions())
+	assert.Equal(t, &channels[1], FindChannel(channels, "friend"))
+	assert.Nil(t, FindChannel(channels, "stranger"))
+
+	// A channel that doesn't exist should result in an error
+	_, err = ResolveChannels(c, "goldenvcr", []string{"stranger"})
+	assert.Error(t, err)
+}
+
+func Test_PartnerRequiredSubscriptions(t *testing.T) {
+	// Partner broadcasters never authorize our app, so we can't require any scopes
+	assert.Empty(t, events.GetRequiredUserScopes(PartnerRequiredSubscriptions))
+}
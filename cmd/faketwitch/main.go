@@ -0,0 +1,208 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/codingconcepts/env"
+	"github.com/joho/godotenv"
+	"github.com/nicklaw5/helix/v2"
+
+	"github.com/golden-vcr/showtime"
+	"github.com/golden-vcr/showtime/internal/events"
+	"github.com/golden-vcr/showtime/internal/twitchtest"
+)
+
+type Config struct {
+	TwitchChannelName  string `env:"TWITCH_CHANNEL_NAME" default:"goldenvcr"`
+	TwitchClientId     string `env:"TWITCH_CLIENT_ID" required:"true"`
+	TwitchClientSecret string `env:"TWITCH_CLIENT_SECRET" required:"true"`
+
+	// Each of TwitchPartnerChannelNames is served as an additional user, with an
+	// arbitrary user ID, so that partner channels can be resolved
+	TwitchPartnerChannelNames []string `env:"TWITCH_PARTNER_CHANNEL_NAMES"`
+}
+
+func main() {
+	port := flag.Int("port", 5010, "Port on which to serve the fake Twitch API")
+	channelId := flag.String("channel-id", "953753877", "Twitch user ID of the broadcaster whose channel is served by the fake API")
+	callbackUrl := flag.String("callback-url", "http://localhost:5001/callback", "URL to which all EventSub webhook messages are delivered, regardless of the callback URL that each subscription was created with")
+	flag.Parse()
+
+	// Parse config from environment variables: the fake API accepts the same client
+	// credentials as the real Twitch app, so the same .env file can be used for both
+	err := godotenv.Load()
+	if err != nil && !os.IsNotExist(err) {
+		log.Fatalf("error loading .env file: %v", err)
+	}
+	config := Config{}
+	if err := env.Set(&config); err != nil {
+		log.Fatalf("error loading config: %v", err)
+	}
+
+	// Run a fake Twitch API with a user for the configured channel, who automatically
+	// grants our app any scopes it asks for, plus a user for each partner channel
+	users := []helix.User{
+		{
+			ID:          *channelId,
+			Login:       config.TwitchChannelName,
+			DisplayName: config.TwitchChannelName,
+			CreatedAt:   helix.Time{Time: time.Now().Add(-365 * 24 * time.Hour)},
+		},
+	}
+	for i, name := range config.TwitchPartnerChannelNames {
+		users = append(users, helix.User{
+			ID:          fmt.Sprintf("%d", 100000001+i),
+			Login:       name,
+			DisplayName: name,
+			CreatedAt:   helix.Time{Time: time.Now().Add(-365 * 24 * time.Hour)},
+		})
+	}
+	fake := twitchtest.New(twitchtest.Config{
+		ClientId:        config.TwitchClientId,
+		ClientSecret:    config.TwitchClientSecret,
+		Users:           users,
+		AuthorizeAs:     *channelId,
+		ResolveCallback: func(string) string { return *callbackUrl },
+	})
+	c := &controller{fake: fake, channelUserId: *channelId}
+
+	mux := http.NewServeMux()
+	mux.Handle("/oauth2/", fake)
+	mux.Handle("/helix/", fake)
+	mux.HandleFunc("/fake/subscriptions", c.handleGetSubscriptions)
+	mux.HandleFunc("/fake/notifications", c.handlePostNotification)
+	mux.HandleFunc("/fake/revocations", c.handlePostRevocation)
+	mux.HandleFunc("/fake/user-tokens", c.handlePostUserToken)
+	mux.HandleFunc("/fake/expire-tokens", c.handlePostExpireTokens)
+
+	baseUrl := fmt.Sprintf("http://localhost:%d", *port)
+	endpoints := fake.Endpoints(baseUrl)
+	fmt.Printf("Serving a fake Twitch API at %s; to use it, set:\n", baseUrl)
+	fmt.Printf("  TWITCH_API_URL=%s\n", endpoints.ApiUrl)
+	fmt.Printf("  TWITCH_AUTH_URL=%s\n", endpoints.AuthUrl)
+	fmt.Printf("EventSub webhook messages will be delivered to %s.\n", *callbackUrl)
+	server := &http.Server{
+		Addr:    fmt.Sprintf(":%d", *port),
+		Handler: mux,
+	}
+	if err := server.ListenAndServe(); err != nil {
+		log.Fatalf("error running server: %v", err)
+	}
+}
+
+// controller exposes endpoints under /fake that allow scripts to manipulate the state
+// of the fake Twitch API, e.g. to deliver EventSub notifications
+type controller struct {
+	fake          *twitchtest.Server
+	channelUserId string
+}
+
+// handleGetSubscriptions lists all EventSub subscriptions, regardless of which token
+// they were created with
+func (c *controller) handleGetSubscriptions(res http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		http.Error(res, "method not supported", http.StatusMethodNotAllowed)
+		return
+	}
+	writeJson(res, c.fake.Subscriptions())
+}
+
+// handlePostNotification delivers an event to all matching webhook subscriptions,
+// given a JSON body of the form {"type": "channel.follow", "event": {...}}. If the
+// body has no "condition", the event is delivered to subscriptions for which the
+// broadcaster is the configured channel.
+func (c *controller) handlePostNotification(res http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		http.Error(res, "method not supported", http.StatusMethodNotAllowed)
+		return
+	}
+	var body struct {
+		Type      string                   `json:"type"`
+		Condition *helix.EventSubCondition `json:"condition"`
+		Event     json.RawMessage          `json:"event"`
+	}
+	if err := json.NewDecoder(req.Body).Decode(&body); err != nil || body.Type == "" || body.Event == nil {
+		http.Error(res, "request body must specify type and event", http.StatusBadRequest)
+		return
+	}
+	condition := helix.EventSubCondition{}
+	if body.Condition != nil {
+		condition = *body.Condition
+	} else if body.Type != helix.EventSubTypeChannelRaid {
+		condition.BroadcasterUserID = c.channelUserId
+	}
+	n, err := c.fake.Notify(body.Type, condition, body.Event)
+	if err != nil {
+		http.Error(res, err.Error(), http.StatusBadGateway)
+		return
+	}
+	writeJson(res, map[string]int{"delivered": n})
+}
+
+// handlePostRevocation revokes a subscription, given a JSON body of the form
+// {"id": "...", "reason": "authorization_revoked"}
+func (c *controller) handlePostRevocation(res http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		http.Error(res, "method not supported", http.StatusMethodNotAllowed)
+		return
+	}
+	var body struct {
+		Id     string `json:"id"`
+		Reason string `json:"reason"`
+	}
+	if err := json.NewDecoder(req.Body).Decode(&body); err != nil || body.Id == "" {
+		http.Error(res, "request body must specify id", http.StatusBadRequest)
+		return
+	}
+	if body.Reason == "" {
+		body.Reason = helix.EventSubStatusAuthorizationRevoked
+	}
+	if err := c.fake.Revoke(body.Id, body.Reason); err != nil {
+		http.Error(res, err.Error(), http.StatusBadGateway)
+		return
+	}
+	res.WriteHeader(http.StatusNoContent)
+}
+
+// handlePostUserToken issues a user access token for the configured channel, granting
+// all scopes required by RequiredSubscriptions, so that it can be supplied to
+// 'init apply -user-token' or as TWITCH_USER_ACCESS_TOKEN without a browser
+func (c *controller) handlePostUserToken(res http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		http.Error(res, "method not supported", http.StatusMethodNotAllowed)
+		return
+	}
+	scopes := events.GetRequiredUserScopes(showtime.RequiredSubscriptions)
+	token := c.fake.IssueUserToken(c.channelUserId, scopes)
+	writeJson(res, map[string]any{
+		"user_id":       token.UserId,
+		"access_token":  token.AccessToken,
+		"refresh_token": token.RefreshToken,
+		"scopes":        token.Scopes,
+		"expires_at":    token.ExpiresAt,
+	})
+}
+
+// handlePostExpireTokens invalidates all access tokens, so that clients' handling of
+// 401 responses can be exercised
+func (c *controller) handlePostExpireTokens(res http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		http.Error(res, "method not supported", http.StatusMethodNotAllowed)
+		return
+	}
+	c.fake.ExpireTokens()
+	res.WriteHeader(http.StatusNoContent)
+}
+
+func writeJson(res http.ResponseWriter, value any) {
+	res.Header().Set("content-type", "application/json")
+	if err := json.NewEncoder(res).Encode(value); err != nil {
+		fmt.Printf("Failed to encode response: %v\n", err)
+	}
+}
@@ -2,182 +2,507 @@ package main
 
 import (
 	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"flag"
 	"fmt"
-	"log"
 	"net/http"
 	"os"
+	"time"
 
 	"github.com/codingconcepts/env"
+	"github.com/golden-vcr/server-common/db"
 	"github.com/golden-vcr/showtime"
+	"github.com/golden-vcr/showtime/gen/queries"
 	"github.com/golden-vcr/showtime/internal/events"
 	"github.com/golden-vcr/showtime/internal/twitch"
 	"github.com/joho/godotenv"
+	_ "github.com/lib/pq"
 	"github.com/nicklaw5/helix/v2"
 )
 
+// Exit codes returned by this program, so that subscription drift can be detected
+// from scripts
+const (
+	// exitOK indicates that all required subscriptions exist and are healthy (or, for
+	// delete-all, that all subscriptions were deleted)
+	exitOK = 0
+	// exitError indicates that an unexpected error occurred
+	exitError = 1
+	// exitUsage indicates that the program was invoked incorrectly
+	exitUsage = 2
+	// exitDrift indicates that subscriptions need to be created or deleted: from plan,
+	// this means that apply would make changes; from apply, it means that deletions
+	// are required but were not confirmed with -force
+	exitDrift = 3
+)
+
 type Config struct {
 	TwitchChannelName        string `env:"TWITCH_CHANNEL_NAME" required:"true"`
 	TwitchClientId           string `env:"TWITCH_CLIENT_ID" required:"true"`
 	TwitchClientSecret       string `env:"TWITCH_CLIENT_SECRET" required:"true"`
 	TwitchWebhookCallbackUrl string `env:"TWITCH_WEBHOOK_CALLBACK_URL" default:"https://goldenvcr.com/api/showtime/callback"`
 	TwitchWebhookSecret      string `env:"TWITCH_WEBHOOK_SECRET" required:"true"`
+	TwitchUserAccessToken    string `env:"TWITCH_USER_ACCESS_TOKEN"`
+
+	// TwitchPartnerChannelNames lists additional channels whose subscriptions may be
+	// managed by passing -channel to plan, apply or delete-all
+	TwitchPartnerChannelNames []string `env:"TWITCH_PARTNER_CHANNEL_NAMES"`
+
+	// TwitchApiUrl and TwitchAuthUrl may be overridden to point at a fake Twitch server
+	// (see cmd/faketwitch) for testing
+	TwitchApiUrl  string `env:"TWITCH_API_URL" default:"https://api.twitch.tv/helix"`
+	TwitchAuthUrl string `env:"TWITCH_AUTH_URL" default:"https://id.twitch.tv/oauth2"`
+
+	// If TwitchTokenEncryptionKey is set, user access tokens granted via the browser are
+	// stored in the database (encrypted with this key) so that the server can use them
+	TwitchTokenEncryptionKey string `env:"TWITCH_TOKEN_ENCRYPTION_KEY"`
+
+	DatabaseHost     string `env:"PGHOST"`
+	DatabasePort     int    `env:"PGPORT"`
+	DatabaseName     string `env:"PGDATABASE"`
+	DatabaseUser     string `env:"PGUSER"`
+	DatabasePassword string `env:"PGPASSWORD"`
+	DatabaseSslMode  string `env:"PGSSLMODE"`
+}
+
+func usage() {
+	fmt.Fprintf(os.Stderr, `Usage: init <command> [flags]
+
+Commands:
+  plan        Print the subscriptions that apply would delete and create
+  apply       Delete and create subscriptions so that they match events.go
+  delete-all  Delete all subscriptions that notify our webhook callback URL
+  authorize   Prompt the broadcaster to grant a user access token, and store it
+
+Exit codes:
+  %d  All required subscriptions exist and are healthy
+  %d  An error occurred
+  %d  Invalid usage
+  %d  Subscriptions need to be changed (plan), or deletions were not confirmed (apply)
+
+Run 'init <command> -h' for the flags accepted by each command.
+`, exitOK, exitError, exitUsage, exitDrift)
 }
 
 func main() {
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(exitUsage)
+	}
+	var run func(args []string) int
+	switch os.Args[1] {
+	case "plan":
+		run = runPlan
+	case "apply":
+		run = runApply
+	case "delete-all":
+		run = runDeleteAll
+	case "authorize":
+		run = runAuthorize
+	case "-h", "-help", "--help", "help":
+		usage()
+		os.Exit(exitOK)
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown command '%s'\n\n", os.Args[1])
+		usage()
+		os.Exit(exitUsage)
+	}
+	os.Exit(run(os.Args[2:]))
+}
+
+// session holds everything we need in order to inspect and manage the subscriptions
+// that deliver events to our webhook callback URL for a single channel
+type session struct {
+	config        Config
+	endpoints     twitch.Endpoints
+	c             *twitch.Client
+	channel       *showtime.Channel
+	channelUserId string
+	transport     helix.EventSubTransport
+}
+
+// newSession prepares a session for the channel with the given name, which must be
+// either TWITCH_CHANNEL_NAME or one of TWITCH_PARTNER_CHANNEL_NAMES: if empty, the
+// primary channel (TWITCH_CHANNEL_NAME) is used
+func newSession(channelName string) (*session, error) {
 	// Initialize config from environment vars
 	err := godotenv.Load()
 	if err != nil && !os.IsNotExist(err) {
-		log.Fatalf("error loading .env file: %v", err)
+		return nil, fmt.Errorf("error loading .env file: %w", err)
 	}
 	config := Config{}
 	if err := env.Set(&config); err != nil {
-		log.Fatalf("error parsing config: %v", err)
+		return nil, fmt.Errorf("error parsing config: %w", err)
 	}
-	fmt.Printf("Configured for Twitch channel: %s\n", config.TwitchChannelName)
 
 	// Initialize a Twitch API client so we can use EventSub API endpoints to manage
 	// event subscriptions
-	c, err := twitch.NewClientWithAppToken(config.TwitchClientId, config.TwitchClientSecret)
+	endpoints := twitch.Endpoints{ApiUrl: config.TwitchApiUrl, AuthUrl: config.TwitchAuthUrl}
+	c, err := twitch.NewClientWithAppToken(endpoints, config.TwitchClientId, config.TwitchClientSecret)
 	if err != nil {
-		log.Fatalf("failed to initialize Twitch API client: %v", err)
+		return nil, err
 	}
 
-	// Resolve the user ID for the channel so we can target the channel in EventSub
-	// subscriptions
-	channelUserId, err := twitch.GetChannelUserId(c, config.TwitchChannelName)
+	// Resolve the user ID for each channel so we can target the chosen channel in
+	// EventSub subscriptions
+	channels, err := showtime.ResolveChannels(c, config.TwitchChannelName, config.TwitchPartnerChannelNames)
 	if err != nil {
-		log.Fatalf("failed to get user ID for channel '%s': %v", config.TwitchChannelName, err)
+		return nil, err
 	}
+	channel := &channels[0]
+	if channelName != "" {
+		channel = showtime.FindChannel(channels, channelName)
+		if channel == nil {
+			return nil, fmt.Errorf("channel '%s' is not listed in TWITCH_CHANNEL_NAME or TWITCH_PARTNER_CHANNEL_NAMES", channelName)
+		}
+	}
+
+	return &session{
+		config:        config,
+		endpoints:     endpoints,
+		c:             c,
+		channel:       channel,
+		channelUserId: channel.UserId,
+		transport: helix.EventSubTransport{
+			Method:   events.TransportMethodWebhook,
+			Callback: config.TwitchWebhookCallbackUrl,
+		},
+	}, nil
+}
 
-	// Query the API to get a list of all current subscriptions that are relevant to
-	// our app
-	subscriptions, err := events.GetOwnedSubscriptions(c, channelUserId, config.TwitchWebhookCallbackUrl)
+// plan queries the API to get a list of all current subscriptions that are relevant
+// to our app, then determines what changes are required to reconcile that list
+// against the declared set of subscriptions that we require
+func (s *session) plan() (*events.SubscriptionPlan, error) {
+	owned, err := events.GetOwnedSubscriptions(s.c, s.channelUserId, s.transport)
 	if err != nil {
-		log.Fatalf("failed to get list of subscriptions from Twitch API: %v", err)
+		return nil, fmt.Errorf("failed to get list of subscriptions from Twitch API: %w", err)
 	}
-	fmt.Printf("\nFound %d existing subscriptions:\n", len(subscriptions))
-	for _, subscription := range subscriptions {
-		fmt.Printf("- [%s] %s v%s (%s)\n", subscription.Status, subscription.Type, subscription.Version, subscription.ID)
+	reconciled, err := events.ReconcileRequiredSubscriptions(s.channel.RequiredSubscriptions(), owned, s.channelUserId, s.transport)
+	if err != nil {
+		return nil, fmt.Errorf("failed to reconcile required subscriptions: %w", err)
 	}
+	return events.PlanSubscriptionChanges(reconciled, s.channelUserId)
+}
 
-	// If the user just wants to nuke everything and start fresh, delete all
-	// subscriptions and exit
-	deleteAll := len(os.Args) > 1 && os.Args[1] == "delete-all"
-	if deleteAll {
-		fmt.Printf("Deleting all %d event subscriptions that notify %s...\n", len(subscriptions), config.TwitchWebhookCallbackUrl)
-		for _, subscription := range subscriptions {
-			if err := deleteSubscription(c, subscription.ID); err != nil {
-				log.Fatalf("Failed to delete subscription %s: %v", subscription.ID, err)
-			}
-			fmt.Printf("Subscription %s deleted.\n", subscription.ID)
-		}
-		fmt.Printf("Done.\n")
-		os.Exit(0)
+func runPlan(args []string) int {
+	fs := flag.NewFlagSet("plan", flag.ContinueOnError)
+	asJson := fs.Bool("json", false, "Emit the plan as JSON instead of a human-readable summary")
+	channel := fs.String("channel", "", "Name of the channel whose subscriptions are managed (defaults to TWITCH_CHANNEL_NAME)")
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
 	}
 
-	// Reconcile that list against the declared set of subscriptions that we require
-	reconciled, err := events.ReconcileRequiredSubscriptions(showtime.RequiredSubscriptions, subscriptions, channelUserId)
+	s, err := newSession(*channel)
 	if err != nil {
-		log.Fatalf("failed to reconcile required subscriptions: %v", err)
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		return exitError
+	}
+	plan, err := s.plan()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		return exitError
 	}
 
-	// First iterate through any required subscriptions that already exist: if their
-	// status is pending or enabled, then we don't need to do anything; otherwise we
-	// want to delete them
-	nonEnabledSubscriptions := make([]events.ExistingSubscription, 0, len(reconciled.Existing))
-	for _, existing := range reconciled.Existing {
-		if existing.Value.Status != helix.EventSubStatusPending && existing.Value.Status != helix.EventSubStatusEnabled {
-			nonEnabledSubscriptions = append(nonEnabledSubscriptions, existing)
+	if *asJson {
+		if err := json.NewEncoder(os.Stdout).Encode(plan); err != nil {
+			fmt.Fprintf(os.Stderr, "failed to encode plan as JSON: %v\n", err)
+			return exitError
 		}
+	} else {
+		printPlan(plan, s.channel.Name, s.config.TwitchWebhookCallbackUrl)
+	}
+	if plan.HasChanges() {
+		return exitDrift
 	}
+	return exitOK
+}
 
-	// Open a web browser and prompt the user to log into their Twitch account and
-	// grant access to the Golden VCR app with the requested scopes. This initiates an
-	// OAuth code grant flow, giving us an authorization code that can be exchanged for
-	// a user access token.
-	//
-	// Note that our Twitch app MUST be configured with a redirect URL matching the
-	// supplied port (e.g. 'http://localhost:3033/auth'), and that port must be free
-	// for us to run a small HTTP server on for the duration of this function call
-	//
-	// Note also that we don't actually need to exchange the authoriztaion code for a
-	// user access token, because all of the EventSub API operations used by this
-	// program (i.e. creating subscriptions and querying the status of existing
-	// subscriptions) use an application access token. However, the individual events
-	// that we're subscribing to still require that the user (i.e. the Twitch channel)
-	// we're getting events for has authorized our app. Until the user explicitly
-	// grants authorization via an in-browser OAuth flow, the Twitch API will respond
-	// with 403 errors when we attempt to create EventSub subscriptions.
-	scopes := events.GetRequiredUserScopes(showtime.RequiredSubscriptions)
-	_, err = twitch.PromptForCodeGrant(context.Background(), config.TwitchClientId, scopes, 3033)
+func printPlan(plan *events.SubscriptionPlan, channelName string, callbackUrl string) {
+	fmt.Printf("Subscriptions for %s that notify %s:\n", channelName, callbackUrl)
+	if !plan.HasChanges() {
+		fmt.Printf("No changes required: all %d required subscriptions exist.\n", plan.NumUnchanged)
+		return
+	}
+	for _, deletion := range plan.ToDelete {
+		fmt.Printf("- delete %s %s v%s: %s\n", deletion.Id, deletion.Type, deletion.Version, deletion.Reason)
+	}
+	for _, creation := range plan.ToCreate {
+		fmt.Printf("+ create %s v%s: %s\n", creation.Type, creation.Version, creation.Reason)
+	}
+	fmt.Printf("%d to delete, %d to create, %d unchanged.\n", len(plan.ToDelete), len(plan.ToCreate), plan.NumUnchanged)
+}
+
+func runApply(args []string) int {
+	fs := flag.NewFlagSet("apply", flag.ContinueOnError)
+	force := fs.Bool("force", false, "Allow existing subscriptions to be deleted")
+	userToken := fs.String("user-token", "", "Stored user access token for the broadcaster, used instead of prompting for authorization in a browser (defaults to TWITCH_USER_ACCESS_TOKEN)")
+	channel := fs.String("channel", "", "Name of the channel whose subscriptions are managed (defaults to TWITCH_CHANNEL_NAME)")
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+
+	s, err := newSession(*channel)
 	if err != nil {
-		log.Fatalf("failed to get user authorization: %v", err)
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		return exitError
+	}
+	plan, err := s.plan()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		return exitError
+	}
+	printPlan(plan, s.channel.Name, s.config.TwitchWebhookCallbackUrl)
+	if !plan.HasChanges() {
+		return exitOK
 	}
 
-	// If we have subscriptions that we need to delete, get rid of them before doing
-	// anything else, but require that the user has confirmed by passing a flag
-	allowDelete := len(os.Args) > 1 && (os.Args[1] == "-f" || os.Args[1] == "--force")
-	if len(nonEnabledSubscriptions) > 0 || len(reconciled.ToDelete) > 0 {
-		fmt.Printf("\nThe following subscriptions need to be deleted:\n")
-		for _, existing := range nonEnabledSubscriptions {
-			fmt.Printf("- %s (%s): status is '%s'\n", existing.Value.ID, existing.Value.Type, existing.Value.Status)
+	// If we have subscriptions that we need to delete, require that the user has
+	// confirmed by passing a flag, and don't create anything until they have
+	if len(plan.ToDelete) > 0 && !*force {
+		fmt.Printf("Please re-run with -force if you wish to delete these subscriptions.\n")
+		fmt.Printf("New subscriptions will not be created until deletion occurs.\n")
+		return exitDrift
+	}
+
+	// The individual events that we're subscribing to require that the user (i.e. the
+	// Twitch channel) we're getting events for has authorized our app with the
+	// relevant scopes: until they have, the Twitch API will respond with 403 errors
+	// when we attempt to create EventSub subscriptions, even though the EventSub API
+	// operations themselves use an application access token. Partner channels only
+	// require subscriptions that need no authorization.
+	if len(plan.ToCreate) > 0 && s.channel.IsPrimary {
+		token := *userToken
+		if token == "" {
+			token = s.config.TwitchUserAccessToken
 		}
-		for _, subscription := range reconciled.ToDelete {
-			fmt.Printf("- %s (%s): notifies %s but is not declared in RequiredSubscriptions\n", subscription.ID, subscription.Type, config.TwitchWebhookCallbackUrl)
+		if err := s.ensureUserAuthorization(token); err != nil {
+			fmt.Fprintf(os.Stderr, "failed to get user authorization: %v\n", err)
+			return exitError
 		}
-		if !allowDelete {
-			fmt.Printf("Please re-run with -f/--force if you wish to delete these subscriptions.\n")
-			fmt.Printf("New subscriptions will not be created until deletion occurs.\n")
-			os.Exit(1)
+	}
+
+	for _, deletion := range plan.ToDelete {
+		fmt.Printf("Deleting subscription %s (%s)...\n", deletion.Id, deletion.Type)
+		if err := deleteSubscription(s.c, deletion.Id); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to delete subscription %s: %v\n", deletion.Id, err)
+			return exitError
 		}
-		for _, existing := range nonEnabledSubscriptions {
-			fmt.Printf("Deleting subscription %s...\n", existing.Value.ID)
-			if err := deleteSubscription(c, existing.Value.ID); err != nil {
-				log.Fatalf("Failed to delete subscription %s: %v\n", existing.Value.ID, err)
-			}
+	}
 
-			// Ensure that we recreate this subscription (in the hopes that it will
-			// become successfully enabled)
-			fmt.Printf("A new '%s' subscription will be created to replace subscription %s.\n", existing.Required.Type, existing.Value.ID)
-			reconciled.ToCreate = append(reconciled.ToCreate, existing.Required)
+	// If we can reach the database, record that any revoked subscriptions we recreate
+	// have been repaired, so that the health check stops reporting their revocations
+	var q *queries.Queries
+	if len(plan.ToCreate) > 0 {
+		if s.hasDatabaseConfig() {
+			q, err = s.openQueries()
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "%v\n", err)
+				return exitError
+			}
+		} else {
+			fmt.Printf("PGHOST etc. are not set; prior revocations will not be marked as resolved.\n")
 		}
-		for _, subscription := range reconciled.ToDelete {
-			fmt.Printf("Deleting subscription %s...\n", subscription.ID)
-			if err := deleteSubscription(c, subscription.ID); err != nil {
-				log.Fatalf("Failed to delete subscription %s\n", subscription.ID)
+	}
+	for _, creation := range plan.ToCreate {
+		fmt.Printf("Creating a new '%s' v%s subscription...\n", creation.Type, creation.Version)
+		if err := createSubscription(s.c, creation, s.config.TwitchWebhookCallbackUrl, s.config.TwitchWebhookSecret); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to create subscription: %v\n", err)
+			return exitError
+		}
+		if q != nil {
+			if err := events.ResolveRevocations(context.Background(), q, creation.Type, creation.Version, &creation.Condition); err != nil {
+				fmt.Fprintf(os.Stderr, "Failed to resolve revocations of '%s' subscription: %v\n", creation.Type, err)
 			}
 		}
-		fmt.Printf("Deletion complete.\n")
 	}
 
-	// Create any new subscriptions that are required according to events.go but that
-	// don't currently exist
-	for _, required := range reconciled.ToCreate {
-		fmt.Printf("\nCreating a new '%s' v%s subscription...\n", required.Type, required.Version)
-		if err := createSubscription(c, required, channelUserId, config.TwitchWebhookCallbackUrl, config.TwitchWebhookSecret); err != nil {
-			log.Fatalf("Failed to create subscription: %v", err)
+	fmt.Printf("All required subscriptions to %s (as declared in events.go) exist.\n", s.config.TwitchWebhookCallbackUrl)
+	return exitOK
+}
+
+// ensureUserAuthorization verifies that the broadcaster has granted our app all
+// scopes required by our subscriptions. If a user access token is supplied, we check
+// the scopes it grants. Otherwise we check the token that's stored in the database (if
+// TWITCH_TOKEN_ENCRYPTION_KEY is set), and if there's no usable stored token, we
+// prompt the broadcaster to grant a new one via authorize.
+func (s *session) ensureUserAuthorization(userAccessToken string) error {
+	scopes := events.GetRequiredUserScopes(showtime.RequiredSubscriptions)
+	if userAccessToken != "" {
+		return twitch.ValidateUserToken(s.c, userAccessToken, s.channelUserId, scopes)
+	}
+
+	store, err := s.openTokenStore()
+	if err != nil {
+		return err
+	}
+	if store != nil {
+		userTokens := twitch.NewUserTokenManager(store, s.newOAuthClient(), s.channelUserId)
+		storedToken, err := userTokens.GetUserAccessToken(context.Background())
+		if err == nil {
+			err = twitch.ValidateUserToken(s.c, storedToken, s.channelUserId, scopes)
 		}
-		fmt.Printf("Relevant %s events will now notify: %s\n", required.Type, config.TwitchWebhookCallbackUrl)
+		if err == nil {
+			fmt.Printf("Using the stored user access token for %s.\n", s.config.TwitchChannelName)
+			return nil
+		}
+		if !errors.Is(err, twitch.ErrNoUserToken) {
+			fmt.Printf("The stored user access token can't be used: %v\n", err)
+		}
+	}
+	return s.authorize(store, scopes)
+}
+
+// authorize opens a web browser and prompts the user to log into their Twitch account
+// and grant access with the given scopes, initiating an OAuth code grant flow. The
+// resulting code is exchanged for a user access token, which is stored if possible.
+//
+// Note that our Twitch app MUST be configured with a redirect URL matching the
+// supplied port (i.e. 'http://localhost:3033/auth'), and that port must be free for us
+// to run a small HTTP server on for the duration of this call.
+func (s *session) authorize(store twitch.TokenStore, scopes []string) error {
+	ctx := context.Background()
+	code, err := twitch.PromptForCodeGrant(ctx, s.endpoints, s.config.TwitchClientId, scopes, 3033)
+	if err != nil {
+		return err
+	}
+	token, err := s.newOAuthClient().ExchangeCode(ctx, code)
+	if err != nil {
+		return fmt.Errorf("failed to exchange authorization code for user access token: %w", err)
+	}
+	if err := twitch.ValidateUserToken(s.c, token.AccessToken, s.channelUserId, scopes); err != nil {
+		return err
+	}
+	token.UserId = s.channelUserId
+
+	if store == nil {
+		fmt.Printf("The user access token was not stored, since TWITCH_TOKEN_ENCRYPTION_KEY is not set.\n")
+		return nil
+	}
+	if err := store.SaveUserToken(ctx, token); err != nil {
+		return fmt.Errorf("failed to store user access token: %w", err)
+	}
+	fmt.Printf("Stored a user access token for %s, expiring at %s.\n", s.config.TwitchChannelName, token.ExpiresAt.Format(time.RFC3339))
+	return nil
+}
+
+// openTokenStore connects to the database and returns a store for user access tokens,
+// or nil if TWITCH_TOKEN_ENCRYPTION_KEY is not set
+func (s *session) openTokenStore() (twitch.TokenStore, error) {
+	if s.config.TwitchTokenEncryptionKey == "" {
+		return nil, nil
+	}
+	key, err := twitch.ParseTokenEncryptionKey(s.config.TwitchTokenEncryptionKey)
+	if err != nil {
+		return nil, fmt.Errorf("invalid TWITCH_TOKEN_ENCRYPTION_KEY: %w", err)
+	}
+	if !s.hasDatabaseConfig() {
+		return nil, fmt.Errorf("PGHOST, PGPORT, PGDATABASE, PGUSER, and PGPASSWORD are required when TWITCH_TOKEN_ENCRYPTION_KEY is set")
+	}
+	q, err := s.openQueries()
+	if err != nil {
+		return nil, err
 	}
+	return twitch.NewDBTokenStore(q, key)
+}
+
+// hasDatabaseConfig returns true if the environment specifies how to connect to the
+// database
+func (s *session) hasDatabaseConfig() bool {
+	return s.config.DatabaseHost != "" && s.config.DatabasePort != 0 && s.config.DatabaseName != "" && s.config.DatabaseUser != "" && s.config.DatabasePassword != ""
+}
+
+// openQueries connects to the database
+func (s *session) openQueries() (*queries.Queries, error) {
+	connectionString := db.FormatConnectionString(
+		s.config.DatabaseHost,
+		s.config.DatabasePort,
+		s.config.DatabaseName,
+		s.config.DatabaseUser,
+		s.config.DatabasePassword,
+		s.config.DatabaseSslMode,
+	)
+	conn, err := sql.Open("postgres", connectionString)
+	if err != nil {
+		return nil, fmt.Errorf("error opening database: %w", err)
+	}
+	if err := conn.Ping(); err != nil {
+		return nil, fmt.Errorf("error connecting to database: %w", err)
+	}
+	return queries.New(conn), nil
+}
 
-	fmt.Printf("\nAll required subscriptions to %s (as declared in events.go) exist.\n", config.TwitchWebhookCallbackUrl)
+func (s *session) newOAuthClient() *twitch.OAuthClient {
+	return twitch.NewOAuthClient(s.endpoints, s.config.TwitchClientId, s.config.TwitchClientSecret)
 }
 
-func createSubscription(c *helix.Client, required events.RequiredSubscription, channelUserId string, webhookCallbackUrl string, webhookSecret string) error {
-	params := events.RequiredSubscriptionConditionParams{
-		ChannelUserId: channelUserId,
+func runAuthorize(args []string) int {
+	fs := flag.NewFlagSet("authorize", flag.ContinueOnError)
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+
+	s, err := newSession("")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		return exitError
 	}
-	requiredCondition, err := params.Format(&required.TemplatedCondition)
+	store, err := s.openTokenStore()
 	if err != nil {
-		return fmt.Errorf("failed to format condition for required '%s' subscription: %v", required.Type, err)
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		return exitError
+	}
+	if store == nil {
+		fmt.Fprintf(os.Stderr, "TWITCH_TOKEN_ENCRYPTION_KEY is required in order to store a user access token\n")
+		return exitError
+	}
+	scopes := events.GetRequiredUserScopes(showtime.RequiredSubscriptions)
+	if err := s.authorize(store, scopes); err != nil {
+		fmt.Fprintf(os.Stderr, "failed to get user authorization: %v\n", err)
+		return exitError
 	}
+	return exitOK
+}
+
+func runDeleteAll(args []string) int {
+	fs := flag.NewFlagSet("delete-all", flag.ContinueOnError)
+	channel := fs.String("channel", "", "Name of the channel whose subscriptions are managed (defaults to TWITCH_CHANNEL_NAME)")
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+
+	s, err := newSession(*channel)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		return exitError
+	}
+	subscriptions, err := events.GetOwnedSubscriptions(s.c, s.channelUserId, s.transport)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to get list of subscriptions from Twitch API: %v\n", err)
+		return exitError
+	}
+
+	fmt.Printf("Deleting all %d event subscriptions for %s that notify %s...\n", len(subscriptions), s.channel.Name, s.config.TwitchWebhookCallbackUrl)
+	for _, subscription := range subscriptions {
+		if err := deleteSubscription(s.c, subscription.ID); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to delete subscription %s: %v\n", subscription.ID, err)
+			return exitError
+		}
+		fmt.Printf("Subscription %s deleted.\n", subscription.ID)
+	}
+	fmt.Printf("Done.\n")
+	return exitOK
+}
 
+func createSubscription(c twitch.SubscriptionManager, creation events.PlannedCreation, webhookCallbackUrl string, webhookSecret string) error {
 	r, err := c.CreateEventSubSubscription(&helix.EventSubSubscription{
-		Type:      required.Type,
-		Version:   required.Version,
-		Condition: *requiredCondition,
+		Type:      creation.Type,
+		Version:   creation.Version,
+		Condition: creation.Condition,
 		Transport: helix.EventSubTransport{
-			Method:   "webhook",
+			Method:   events.TransportMethodWebhook,
 			Callback: webhookCallbackUrl,
 			Secret:   webhookSecret,
 		},
@@ -191,7 +516,7 @@ func createSubscription(c *helix.Client, required events.RequiredSubscription, c
 	return nil
 }
 
-func deleteSubscription(c *helix.Client, subscriptionId string) error {
+func deleteSubscription(c twitch.SubscriptionManager, subscriptionId string) error {
 	r, err := c.RemoveEventSubSubscription(subscriptionId)
 	if err != nil {
 		return err
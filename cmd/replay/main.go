@@ -0,0 +1,237 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/codingconcepts/env"
+	"github.com/joho/godotenv"
+	_ "github.com/lib/pq"
+
+	"github.com/golden-vcr/auth"
+	"github.com/golden-vcr/ledger"
+	"github.com/golden-vcr/server-common/db"
+	"github.com/golden-vcr/showtime/gen/queries"
+	"github.com/golden-vcr/showtime/internal/alerts"
+	"github.com/golden-vcr/showtime/internal/events"
+	"github.com/golden-vcr/showtime/internal/progress"
+	"github.com/golden-vcr/showtime/internal/replay"
+	"github.com/golden-vcr/showtime/internal/twitch"
+)
+
+type Config struct {
+	TwitchChannelName   string `env:"TWITCH_CHANNEL_NAME"`
+	TwitchClientId      string `env:"TWITCH_CLIENT_ID"`
+	TwitchClientSecret  string `env:"TWITCH_CLIENT_SECRET"`
+	TwitchWebhookSecret string `env:"TWITCH_WEBHOOK_SECRET"`
+
+	// TwitchApiUrl and TwitchAuthUrl may be overridden to point at a fake Twitch server
+	// (see cmd/faketwitch) for testing
+	TwitchApiUrl  string `env:"TWITCH_API_URL" default:"https://api.twitch.tv/helix"`
+	TwitchAuthUrl string `env:"TWITCH_AUTH_URL" default:"https://id.twitch.tv/oauth2"`
+
+	CheerAlertMinBits     int           `env:"CHEER_ALERT_MIN_BITS" default:"1"`
+	BroadcastResumeWindow time.Duration `env:"BROADCAST_RESUME_WINDOW" default:"15m"`
+
+	AuthURL          string `env:"AUTH_URL" default:"http://localhost:5002"`
+	AuthSharedSecret string `env:"AUTH_SHARED_SECRET"`
+	LedgerURL        string `env:"LEDGER_URL" default:"http://localhost:5003"`
+
+	DatabaseHost     string `env:"PGHOST"`
+	DatabasePort     int    `env:"PGPORT"`
+	DatabaseName     string `env:"PGDATABASE"`
+	DatabaseUser     string `env:"PGUSER"`
+	DatabasePassword string `env:"PGPASSWORD"`
+	DatabaseSslMode  string `env:"PGSSLMODE"`
+}
+
+func main() {
+	file := flag.String("file", "", "Path to a JSONL file of notifications to replay; if omitted, notifications are read from the EventSub inbox in the database")
+	types := flag.String("type", "", "Comma-separated list of subscription types to replay, e.g. 'channel.cheer,channel.subscribe'; if omitted, all types are replayed")
+	since := flag.String("since", "", "Only replay notifications sent at or after this time (RFC3339)")
+	until := flag.String("until", "", "Only replay notifications sent before this time (RFC3339)")
+	url := flag.String("url", "http://localhost:5001/callback", "URL of the EventSub callback endpoint to replay notifications against")
+	direct := flag.Bool("direct", false, "Handle notifications in-process via events.Handler instead of sending them to a running server")
+	dryRun := flag.Bool("dry-run", false, "Only print the notifications that would be replayed, without replaying them")
+	flag.Parse()
+	if flag.NArg() > 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	// Parse config from environment variables
+	err := godotenv.Load()
+	if err != nil && !os.IsNotExist(err) {
+		log.Fatalf("error loading .env file: %v", err)
+	}
+	config := Config{}
+	if err := env.Set(&config); err != nil {
+		log.Fatalf("error loading config: %v", err)
+	}
+
+	// Work out which notifications we're interested in
+	filter, err := parseFilter(*types, *since, *until)
+	if err != nil {
+		log.Fatalf("invalid filter: %v", err)
+	}
+
+	// We need a database connection if we're reading notifications from the inbox, or
+	// if we're handling them in-process
+	ctx := context.Background()
+	var q *queries.Queries
+	if *file == "" || (*direct && !*dryRun) {
+		if config.DatabaseHost == "" || config.DatabasePort == 0 || config.DatabaseName == "" || config.DatabaseUser == "" || config.DatabasePassword == "" {
+			log.Fatalf("PGHOST, PGPORT, PGDATABASE, PGUSER, and PGPASSWORD are required unless using -file with a running server")
+		}
+		connectionString := db.FormatConnectionString(
+			config.DatabaseHost,
+			config.DatabasePort,
+			config.DatabaseName,
+			config.DatabaseUser,
+			config.DatabasePassword,
+			config.DatabaseSslMode,
+		)
+		db, err := sql.Open("postgres", connectionString)
+		if err != nil {
+			log.Fatalf("error opening database: %v", err)
+		}
+		defer db.Close()
+		if err := db.Ping(); err != nil {
+			log.Fatalf("error connecting to database: %v", err)
+		}
+		q = queries.New(db)
+	}
+
+	// Load the notifications that we want to replay
+	var notifications []replay.Notification
+	if *file != "" {
+		f, err := os.Open(*file)
+		if err != nil {
+			log.Fatalf("error opening %s: %v", *file, err)
+		}
+		notifications, err = replay.ReadJsonl(f)
+		f.Close()
+		if err != nil {
+			log.Fatalf("error reading %s: %v", *file, err)
+		}
+	} else {
+		notifications, err = replay.LoadFromDB(ctx, q, filter)
+		if err != nil {
+			log.Fatalf("error loading notifications from database: %v", err)
+		}
+	}
+
+	// Prepare the target that will handle each notification
+	var target replay.Target
+	var drain func()
+	if *direct {
+		if *dryRun {
+			target = replay.NewHandlerTarget(nil)
+		} else {
+			handler, stop, err := initHandler(ctx, &config, q)
+			if err != nil {
+				log.Fatalf("error initializing events.Handler: %v", err)
+			}
+			target = replay.NewHandlerTarget(events.RouteEventsByChannel(handler))
+			drain = stop
+		}
+	} else {
+		if config.TwitchWebhookSecret == "" {
+			log.Fatalf("TWITCH_WEBHOOK_SECRET is required unless using -direct")
+		}
+		target = replay.NewWebhookTarget(*url, config.TwitchWebhookSecret)
+	}
+
+	// Replay all matching notifications in order
+	result := replay.Run(ctx, notifications, filter, target, *dryRun, os.Stdout)
+	if drain != nil {
+		drain()
+	}
+	verb := "Replayed"
+	if *dryRun {
+		verb = "Would replay"
+	}
+	fmt.Printf("%s %d notification(s); %d skipped by filter; %d failed\n", verb, result.NumReplayed, result.NumSkipped, result.NumFailed)
+	if result.NumFailed > 0 {
+		os.Exit(1)
+	}
+}
+
+func parseFilter(types string, since string, until string) (replay.Filter, error) {
+	filter := replay.Filter{}
+	for _, t := range strings.Split(types, ",") {
+		if t = strings.TrimSpace(t); t != "" {
+			filter.Types = append(filter.Types, t)
+		}
+	}
+	if since != "" {
+		t, err := time.Parse(time.RFC3339, since)
+		if err != nil {
+			return replay.Filter{}, fmt.Errorf("-since: %w", err)
+		}
+		filter.Since = t
+	}
+	if until != "" {
+		t, err := time.Parse(time.RFC3339, until)
+		if err != nil {
+			return replay.Filter{}, fmt.Errorf("-until: %w", err)
+		}
+		filter.Until = t
+	}
+	if !filter.Since.IsZero() && !filter.Until.IsZero() && !filter.Until.After(filter.Since) {
+		return replay.Filter{}, fmt.Errorf("-until must be later than -since")
+	}
+	return filter, nil
+}
+
+// initHandler prepares an events.Handler configured in the same way as the one used by
+// the server, except that cheer commands that generate images are disabled, and alerts
+// and progress events are printed to stdout rather than being sent to the overlay.
+// Only events for TWITCH_CHANNEL_NAME are handled. The returned function must be called once all events have been handled: it waits for
+// any pending gift sub alerts to be flushed.
+func initHandler(ctx context.Context, config *Config, q *queries.Queries) (*events.Handler, func(), error) {
+	if config.TwitchChannelName == "" || config.TwitchClientId == "" || config.TwitchClientSecret == "" {
+		return nil, nil, fmt.Errorf("TWITCH_CHANNEL_NAME, TWITCH_CLIENT_ID, and TWITCH_CLIENT_SECRET are required with -direct")
+	}
+	if config.AuthSharedSecret == "" {
+		return nil, nil, fmt.Errorf("AUTH_SHARED_SECRET is required with -direct")
+	}
+
+	twitchClient, err := twitch.NewClientWithAppToken(twitch.Endpoints{ApiUrl: config.TwitchApiUrl, AuthUrl: config.TwitchAuthUrl}, config.TwitchClientId, config.TwitchClientSecret)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to initialize Twitch API client: %w", err)
+	}
+	channelUserId, err := twitch.GetChannelUserId(twitchClient, config.TwitchChannelName)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get Twitch channel user ID: %w", err)
+	}
+	cheermoteResolver := events.NewCheermoteResolver(twitchClient, channelUserId)
+
+	authServiceClient := auth.NewServiceClient(config.AuthURL, config.AuthSharedSecret)
+	ledgerClient := ledger.NewClient(config.LedgerURL)
+
+	alertsChan := make(chan *alerts.Alert, 32)
+	progressChan := make(chan *progress.Event, 32)
+	go func() {
+		for alert := range alertsChan {
+			fmt.Printf("- alert: %s\n", alert.Type)
+		}
+	}()
+	go func() {
+		for ev := range progressChan {
+			fmt.Printf("- progress: %s %s\n", ev.Type, ev.Phase)
+		}
+	}()
+
+	handler := events.NewHandler(ctx, q, channelUserId, alertsChan, progressChan, authServiceClient, ledgerClient, nil, cheermoteResolver.Resolve, config.CheerAlertMinBits, config.BroadcastResumeWindow, nil)
+	drain := func() {
+		time.Sleep(events.DefaultGiftBurstWindow)
+	}
+	return handler, drain, nil
+}
@@ -3,9 +3,11 @@ package main
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"log"
+	"net/http"
 	"os"
 	"time"
 
@@ -13,11 +15,13 @@ import (
 	"github.com/gorilla/mux"
 	"github.com/joho/godotenv"
 	"github.com/lib/pq"
+	"github.com/nicklaw5/helix/v2"
 
 	"github.com/golden-vcr/auth"
 	"github.com/golden-vcr/ledger"
 	"github.com/golden-vcr/server-common/db"
 	"github.com/golden-vcr/server-common/entry"
+	"github.com/golden-vcr/showtime"
 	"github.com/golden-vcr/showtime/gen/queries"
 	"github.com/golden-vcr/showtime/internal/admin"
 	"github.com/golden-vcr/showtime/internal/alerts"
@@ -27,8 +31,10 @@ import (
 	"github.com/golden-vcr/showtime/internal/health"
 	"github.com/golden-vcr/showtime/internal/history"
 	"github.com/golden-vcr/showtime/internal/imagegen"
+	"github.com/golden-vcr/showtime/internal/progress"
 	"github.com/golden-vcr/showtime/internal/sse"
 	"github.com/golden-vcr/showtime/internal/twitch"
+	"github.com/golden-vcr/showtime/internal/viewers"
 )
 
 type Config struct {
@@ -42,6 +48,50 @@ type Config struct {
 	TwitchWebhookCallbackUrl string `env:"TWITCH_WEBHOOK_CALLBACK_URL" default:"https://goldenvcr.com/api/showtime/callback"`
 	TwitchWebhookSecret      string `env:"TWITCH_WEBHOOK_SECRET" required:"true"`
 
+	// TwitchPartnerChannelNames lists additional channels (e.g. "channela,channelb")
+	// whose broadcasts, chat and raid alerts are tracked alongside TwitchChannelName:
+	// each channel's state is served under /channels/:userId
+	TwitchPartnerChannelNames []string `env:"TWITCH_PARTNER_CHANNEL_NAMES"`
+
+	// TwitchApiUrl and TwitchAuthUrl may be overridden to point at a fake Twitch server
+	// (see cmd/faketwitch) for testing
+	TwitchApiUrl  string `env:"TWITCH_API_URL" default:"https://api.twitch.tv/helix"`
+	TwitchAuthUrl string `env:"TWITCH_AUTH_URL" default:"https://id.twitch.tv/oauth2"`
+
+	TwitchEventSubMaxMessageAge time.Duration `env:"TWITCH_EVENTSUB_MAX_MESSAGE_AGE" default:"10m"`
+	TwitchEventSubTransport     string        `env:"TWITCH_EVENTSUB_TRANSPORT" default:"webhook"`
+	TwitchEventSubWebSocketUrl  string        `env:"TWITCH_EVENTSUB_WEBSOCKET_URL" default:"wss://eventsub.wss.twitch.tv/ws"`
+	TwitchUserAccessToken       string        `env:"TWITCH_USER_ACCESS_TOKEN"`
+
+	// TwitchTokenEncryptionKey is a hex-encoded 256-bit key used to encrypt the user
+	// access tokens that cmd/init stores in the database: if set, the server uses (and
+	// refreshes) the broadcaster's stored token in preference to TwitchUserAccessToken
+	TwitchTokenEncryptionKey string `env:"TWITCH_TOKEN_ENCRYPTION_KEY"`
+
+	// TwitchEventSubReconcileInterval enables the background subscription reconciler
+	// when nonzero: it only applies to the webhook transport
+	TwitchEventSubReconcileInterval time.Duration `env:"TWITCH_EVENTSUB_RECONCILE_INTERVAL" default:"0"`
+
+	CheerAlertMinBits int `env:"CHEER_ALERT_MIN_BITS" default:"1"`
+
+	BroadcastResumeWindow time.Duration `env:"BROADCAST_RESUME_WINDOW" default:"15m"`
+
+	// AlertRetention is how long alerts are persisted: clients that reconnect to
+	// /alerts with a Last-Event-ID are sent any alerts they missed within this window
+	AlertRetention time.Duration `env:"ALERT_RETENTION" default:"24h"`
+
+	// AlertModerationTypes lists the types of alert (e.g. 'subscribe,cheer') that are
+	// held for moderation until the broadcaster approves them via /admin/alerts:
+	// pending alerts are approved automatically after AlertModerationTimeout, unless
+	// that timeout is 0
+	AlertModerationTypes   []string      `env:"ALERT_MODERATION_TYPES"`
+	AlertModerationTimeout time.Duration `env:"ALERT_MODERATION_TIMEOUT" default:"5m"`
+
+	EventSubInboxNumWorkers  int           `env:"EVENTSUB_INBOX_NUM_WORKERS" default:"4"`
+	EventSubInboxMaxAttempts int           `env:"EVENTSUB_INBOX_MAX_ATTEMPTS" default:"8"`
+	EventSubInboxBaseBackoff time.Duration `env:"EVENTSUB_INBOX_BASE_BACKOFF" default:"5s"`
+	EventSubInboxMaxBackoff  time.Duration `env:"EVENTSUB_INBOX_MAX_BACKOFF" default:"30m"`
+
 	OpenaiApiKey string `env:"OPENAI_API_KEY" required:"true"`
 
 	DiscordGhostsWebhookUrl string `env:"DISCORD_GHOSTS_WEBHOOK_URL" required:"true"`
@@ -99,10 +149,11 @@ func main() {
 	}
 	q := queries.New(db)
 
-	// Using the same connection string, prepare a broadcast.ChangeListener, which will
-	// maintain a dedicated connection to the postgres server and use LISTEN to receive
-	// asynchronous notifications (via the 'showtime' NOTIFY channel) whenever broadcast
-	// or screening records are inserted/updated
+	// Using the same connection string, prepare a pq.Listener, which will maintain a
+	// dedicated connection to the postgres server: once we know which channels we're
+	// tracking, we'll use it to LISTEN for asynchronous notifications (via the
+	// 'showtime' NOTIFY channel) whenever broadcast or screening records are
+	// inserted/updated
 	pqListener := pq.NewListener(connectionString, 10*time.Second, time.Minute, func(ev pq.ListenerEventType, err error) {
 		switch ev {
 		case pq.ListenerEventConnected:
@@ -118,17 +169,6 @@ func main() {
 			log.Fatalf("pq.Listener failed: %v", err)
 		}
 	})
-	changeListener, err := broadcast.NewChangeListener(app.Context(), pqListener, q)
-	if err != nil {
-		app.Fail("Failed to initialize ChangeListener", err)
-	}
-	go func() {
-		err := changeListener.Run(app.Context())
-		if err != nil && !errors.Is(err, context.Canceled) {
-			app.Fail("ChangeListener got an error", err)
-		}
-	}()
-
 	// We need an auth service client so that when Twitch tells us about a particular
 	// user action that should result in state changes on the Golden VCR backend, we can
 	// request a JWT that will authorize requests made against that user's state
@@ -143,111 +183,372 @@ func main() {
 	}
 	ledgerClient := ledger.NewClient(config.LedgerURL)
 
-	// Prepare a Twitch client and use it to get the user ID for the configured channel,
-	// so we can identify the broadcaster
-	twitchClient, err := twitch.NewClientWithAppToken(config.TwitchClientId, config.TwitchClientSecret)
+	// Prepare a Twitch client and use it to get the user ID for each configured channel,
+	// so we can identify the broadcasters: the primary channel (TWITCH_CHANNEL_NAME) is
+	// always first, and it's the only channel whose broadcaster authorizes our app
+	twitchEndpoints := twitch.Endpoints{ApiUrl: config.TwitchApiUrl, AuthUrl: config.TwitchAuthUrl}
+	twitchClient, err := twitch.NewClientWithAppToken(twitchEndpoints, config.TwitchClientId, config.TwitchClientSecret)
 	if err != nil {
 		app.Fail("Failed to initialize Twitch API client", err)
 	}
-	channelUserId, err := twitch.GetChannelUserId(twitchClient, config.TwitchChannelName)
+	channels, err := showtime.ResolveChannels(twitchClient, config.TwitchChannelName, config.TwitchPartnerChannelNames)
 	if err != nil {
-		app.Fail("Failed to get Twitch channel user ID", err)
+		app.Fail("Failed to get Twitch channel user IDs", err)
+	}
+	channelUserId := channels[0].UserId
+	channelUserIds := make([]string, 0, len(channels))
+	for _, channel := range channels {
+		channelUserIds = append(channelUserIds, channel.UserId)
+	}
+
+	// The broadcast.ChangeListener tracks the state of the current broadcast in each
+	// channel, updating it in response to notifications from the database
+	changeListener, err := broadcast.NewChangeListener(app.Context(), pqListener, q, channelUserIds)
+	if err != nil {
+		app.Fail("Failed to initialize ChangeListener", err)
+	}
+	go func() {
+		err := changeListener.Run(app.Context())
+		if err != nil && !errors.Is(err, context.Canceled) {
+			app.Fail("ChangeListener got an error", err)
+		}
+	}()
+
+	// If we have a user access token for the broadcaster, we can use it to make API
+	// calls that require the broadcaster's authorization, such as fulfilling or
+	// canceling channel points redemptions. Preferably, that's the token that was
+	// granted via cmd/init and stored in the database, in which case
+	// twitch.UserTokenManager keeps it refreshed and updates userClient accordingly;
+	// otherwise we fall back to a static token supplied via TWITCH_USER_ACCESS_TOKEN.
+	var userClient *twitch.Client
+	if config.TwitchTokenEncryptionKey != "" {
+		key, err := twitch.ParseTokenEncryptionKey(config.TwitchTokenEncryptionKey)
+		if err != nil {
+			app.Fail("Invalid TWITCH_TOKEN_ENCRYPTION_KEY", err)
+		}
+		tokenStore, err := twitch.NewDBTokenStore(q, key)
+		if err != nil {
+			app.Fail("Failed to initialize Twitch user token store", err)
+		}
+		userTokens := twitch.NewUserTokenManager(tokenStore, twitch.NewOAuthClient(twitchEndpoints, config.TwitchClientId, config.TwitchClientSecret), channelUserId)
+		userClient, err = userTokens.NewClient(app.Context(), twitchEndpoints, config.TwitchClientId)
+		if errors.Is(err, twitch.ErrNoUserToken) {
+			fmt.Printf("No user access token is stored for %s; run cmd/init to grant one.\n", config.TwitchChannelName)
+		} else if err != nil {
+			app.Fail("Failed to initialize Twitch API client with stored user token", err)
+		} else {
+			go func() {
+				err := userTokens.Run(app.Context())
+				if err != nil && !errors.Is(err, context.Canceled) {
+					app.Fail("UserTokenManager got an error", err)
+				}
+			}()
+		}
+	}
+	if userClient == nil && config.TwitchUserAccessToken != "" {
+		userClient, err = twitch.NewClientWithUserToken(twitchEndpoints, config.TwitchClientId, config.TwitchUserAccessToken)
+		if err != nil {
+			app.Fail("Failed to initialize Twitch API client with user token", err)
+		}
 	}
 
 	// Start setting up our HTTP handlers, using gorilla/mux for routing
 	r := mux.NewRouter()
 
+	// GET /channels lists the channels we're tracking, each of which has its own set of
+	// routes under /channels/:userId: the routes for the primary channel are also
+	// served at the top level (e.g. /alerts)
+	r.Path("/channels").Methods("GET").HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		res.Header().Set("content-type", "application/json")
+		if err := json.NewEncoder(res).Encode(channels); err != nil {
+			http.Error(res, err.Error(), http.StatusInternalServerError)
+		}
+	})
+
 	// Clients can hit GET /alerts to receive notifications in response to follows,
-	// raids, etc.: these are largely initiated in response to Twitch EventSub callbacks
-	alertsChan := make(chan *alerts.Alert, 32)
+	// raids, etc.: these are largely initiated in response to Twitch EventSub callbacks.
+	// Clients can also hit GET /progress to receive real-time updates on hype trains,
+	// polls, and predictions, so that the overlay can render their progress. Each
+	// channel has its own alerts and progress events.
+	alertsChans := make(map[string]chan *alerts.Alert, len(channels))
+	progressChans := make(map[string]chan *progress.Event, len(channels))
+	for _, channel := range channels {
+		alertsChans[channel.UserId] = make(chan *alerts.Alert, 32)
+		progressChans[channel.UserId] = make(chan *progress.Event, 32)
+	}
+
+	// alerts.Moderator holds alerts of the configured types until the broadcaster
+	// approves them: all other alerts are passed along immediately
+	moderator, err := alerts.NewModerator(q, config.AlertModerationTypes, config.AlertModerationTimeout)
+	if err != nil {
+		app.Fail("Failed to initialize alert moderator", err)
+	}
+
+	// imagegen.Server generates images to be displayed onscreen as ghost alerts: they're
+	// requested by viewers via cheer commands, which call into it directly
+	imageGeneration := imagegen.NewGenerationClient(config.OpenaiApiKey)
+	imageStorage, err := imagegen.NewStorageClient(
+		config.SpacesAccessKeyId,
+		config.SpacesSecretKey,
+		config.SpacesEndpointOrigin,
+		config.SpacesRegionName,
+		config.SpacesBucketName,
+	)
+	if err != nil {
+		log.Fatalf("Failed to initialize storage client for image generation: %v", err)
+	}
+	imagegenServer := imagegen.NewServer(q, channelUserId, ledgerClient, imageGeneration, imageStorage, config.DiscordGhostsWebhookUrl, alertsChans[channelUserId])
+
+	var eventSubClient twitch.SubscriptionReader = twitchClient
+	var getEventSubTransport health.GetTransportFunc
+	var getReconcileReport health.GetReconcileReportFunc
 	{
 		// events.Handler gets called in response to EventSub notifications, and
 		// whenever it decides that we should broadcast an alert, it write a new
-		// alert.Alert into alertsChan
-		eventsHandler := events.NewHandler(app.Context(), q, alertsChan, authServiceClient, ledgerClient)
+		// alert.Alert into its channel's alertsChan. Each channel has its own handler,
+		// and events are routed to the handler for the channel that their subscription
+		// is conditioned on. Channel points redemptions are only marked as
+		// fulfilled or canceled if we have a user access token. Cheer alerts are only
+		// emitted for cheers of at least CHEER_ALERT_MIN_BITS, and their messages have
+		// cheermotes resolved to image URLs. Cheers may also invoke commands, such as
+		// requesting a ghost alert from the imagegen.Server.
+		var redemptionUpdater twitch.RedemptionUpdater
+		if userClient != nil {
+			redemptionUpdater = userClient
+		}
+		cheermoteResolver := events.NewCheermoteResolver(twitchClient, channelUserId)
+		eventsHandlers := make([]*events.Handler, 0, len(channels))
+		for _, channel := range channels {
+			eventsHandlers = append(eventsHandlers, events.NewHandler(app.Context(), q, channel.UserId, alertsChans[channel.UserId], progressChans[channel.UserId], authServiceClient, ledgerClient, redemptionUpdater, cheermoteResolver.Resolve, config.CheerAlertMinBits, config.BroadcastResumeWindow, imagegenServer))
+		}
 
-		// events.Server implements the POST callback that Twitch hits (once we've run
-		// cmd/init/main.go to create all EventSub notifications mandated by events.go)
-		// in order to let us know when relevant events occur on Twitch: it responds by
-		// sending those events to the events.Handler
-		eventsServer := events.NewServer(config.TwitchWebhookSecret, eventsHandler)
-		r.Path("/callback").Methods("POST").Handler(eventsServer)
+		// events.Inbox processes EventSub notifications that have been durably recorded
+		// in the database, passing each one to the events.Handler in the background
+		// and retrying with exponential backoff if handling fails. Notifications that
+		// still fail after the maximum number of attempts are dead-lettered, at which
+		// point they can be inspected, retried or discarded via /admin.
+		inbox := events.NewInbox(q, events.RouteEventsByChannel(eventsHandlers...), events.InboxConfig{
+			NumWorkers:    config.EventSubInboxNumWorkers,
+			MaxAttempts:   config.EventSubInboxMaxAttempts,
+			BaseBackoff:   config.EventSubInboxBaseBackoff,
+			MaxBackoff:    config.EventSubInboxMaxBackoff,
+			LeaseDuration: 2 * time.Minute,
+			PollInterval:  5 * time.Second,
+		})
+		go func() {
+			err := inbox.Run(app.Context())
+			if err != nil && !errors.Is(err, context.Canceled) {
+				app.Fail("EventSub inbox got an error", err)
+			}
+		}()
 
-		// The sse.Handler exposes our Alert channel via an SSE endpoint, notifying HTTP
-		// clients whenever a Twitch-initiated event results in a new alert
-		alertsHandler := sse.NewHandler[*alerts.Alert](app.Context(), alertsChan)
-		r.Path("/alerts").Methods("GET").Handler(alertsHandler)
+		switch config.TwitchEventSubTransport {
+		case events.TransportMethodWebhook:
+			// events.Server implements the POST callback that Twitch hits (once we've
+			// run cmd/init/main.go to create all EventSub notifications mandated by
+			// events.go) in order to let us know when relevant events occur on Twitch:
+			// it responds by writing those events to the inbox before acknowledging
+			// them. Each message ID is recorded so that redelivered messages are only
+			// handled once, and messages older than the configured max age are rejected
+			// outright.
+			eventsServer := events.NewServer(config.TwitchWebhookSecret, config.TwitchEventSubMaxMessageAge, q, inbox)
+			r.Path("/callback").Methods("POST").Handler(eventsServer)
+			getEventSubTransport = func() helix.EventSubTransport {
+				return helix.EventSubTransport{
+					Method:   events.TransportMethodWebhook,
+					Callback: config.TwitchWebhookCallbackUrl,
+				}
+			}
+
+			// If enabled, events.Reconciler periodically recreates any required webhook
+			// subscriptions that are missing or that Twitch has disabled, using our app
+			// access token, so that they don't have to be repaired by hand with
+			// cmd/init. Each channel has its own reconciler. Each repair is recorded in
+			// the database, and the outcome of the most recent pass is reported by the
+			// health check: preferring a pass that failed, if any channel's did.
+			if config.TwitchEventSubReconcileInterval > 0 {
+				reconcilers := make([]*events.Reconciler, 0, len(channels))
+				for _, channel := range channels {
+					reconciler := events.NewReconciler(twitchClient, q, channel.RequiredSubscriptions(), channel.UserId, helix.EventSubTransport{
+						Method:   events.TransportMethodWebhook,
+						Callback: config.TwitchWebhookCallbackUrl,
+						Secret:   config.TwitchWebhookSecret,
+					}, config.TwitchEventSubReconcileInterval)
+					go func() {
+						err := reconciler.Run(app.Context())
+						if err != nil && !errors.Is(err, context.Canceled) {
+							app.Fail("EventSub subscription reconciler got an error", err)
+						}
+					}()
+					reconcilers = append(reconcilers, reconciler)
+				}
+				getReconcileReport = func() *events.ReconcileReport {
+					for _, reconciler := range reconcilers {
+						if report := reconciler.GetLastReport(); report != nil && !report.IsHealthy() {
+							return report
+						}
+					}
+					return reconcilers[0].GetLastReport()
+				}
+			}
+		case events.TransportMethodWebSocket:
+			// Alternatively, events.WebSocketClient connects to Twitch and receives
+			// EventSub notifications over a WebSocket, writing them to the same inbox:
+			// this requires no public callback URL, which makes it convenient for local
+			// development. WebSocket subscriptions are tied to the session, so they're
+			// created for every channel whenever a new session is established, which
+			// requires a user access token for the primary channel's broadcaster.
+			if userClient == nil {
+				app.Fail("Failed to load config", fmt.Errorf("a stored user access token (via TWITCH_TOKEN_ENCRYPTION_KEY) or TWITCH_USER_ACCESS_TOKEN is required when TWITCH_EVENTSUB_TRANSPORT is %q", events.TransportMethodWebSocket))
+			}
+			welcomeFuncs := make([]events.SessionWelcomeFunc, 0, len(channels))
+			for _, channel := range channels {
+				welcomeFuncs = append(welcomeFuncs, events.EnsureWebSocketSubscriptions(userClient, q, channel.RequiredSubscriptions(), channel.UserId))
+			}
+			onWelcome := func(ctx context.Context, sessionId string) error {
+				// Subscribe every channel even if another channel's subscriptions fail,
+				// since nothing will retry until the next session is established
+				errs := make([]error, 0)
+				for _, f := range welcomeFuncs {
+					if err := f(ctx, sessionId); err != nil {
+						errs = append(errs, err)
+					}
+				}
+				return errors.Join(errs...)
+			}
+			webSocketClient := events.NewWebSocketClient(config.TwitchEventSubWebSocketUrl, config.TwitchEventSubMaxMessageAge, q, inbox, onWelcome)
+			go func() {
+				err := webSocketClient.Run(app.Context())
+				if err != nil && !errors.Is(err, context.Canceled) {
+					app.Fail("EventSub WebSocket client got an error", err)
+				}
+			}()
+			eventSubClient = userClient
+			getEventSubTransport = webSocketClient.GetTransport
+		default:
+			app.Fail("Failed to load config", fmt.Errorf("unsupported TWITCH_EVENTSUB_TRANSPORT %q", config.TwitchEventSubTransport))
+		}
+
+		// The sse.Handler exposes each channel's Alert channel via an SSE endpoint,
+		// notifying HTTP clients whenever a Twitch-initiated event results in a new
+		// alert. Alerts that require moderation are held until they're approved. Each
+		// alert is then persisted by an alerts.Recorder, which assigns it an ID:
+		// clients that reconnect with a Last-Event-ID are sent any alerts they missed.
+		// Progress events are exposed in the same manner, via a separate SSE endpoint,
+		// but are not moderated or persisted.
+		for _, channel := range channels {
+			channelId := channel.UserId
+			moderatedAlertsChan := make(chan *alerts.Alert, 32)
+			go func(src <-chan *alerts.Alert) {
+				err := moderator.Run(app.Context(), channelId, src, moderatedAlertsChan)
+				if err != nil && !errors.Is(err, context.Canceled) {
+					app.Fail("Alert moderator got an error", err)
+				}
+			}(alertsChans[channelId])
+			recorder := alerts.NewRecorder(q, channelId, config.AlertRetention)
+			recordedAlertsChan := make(chan *alerts.Alert, 32)
+			go func() {
+				err := recorder.Run(app.Context(), moderatedAlertsChan, recordedAlertsChan)
+				if err != nil && !errors.Is(err, context.Canceled) {
+					app.Fail("Alert recorder got an error", err)
+				}
+			}()
+			alertsHandler := sse.NewHandler[*alerts.Alert](app.Context(), recordedAlertsChan)
+			alertsHandler.EventIdFunc = alerts.GetEventId
+			alertsHandler.ReplayFunc = recorder.Replay
+			registerChannelRoute(r, &channel, "/alerts", alertsHandler)
+			progressHandler := sse.NewHandler[*progress.Event](app.Context(), progressChans[channel.UserId])
+			registerChannelRoute(r, &channel, "/progress", progressHandler)
+		}
 	}
 
 	// Clients can hit GET /chat to open an SSE connection into which we'll write chat
 	// log events
 	var getChatStatus health.GetChatStatusFunc
 	{
-		// The chat.Agent sits in IRC chat and interprets messages, writing to our
-		// logEventsChan whenever the chat log UI should be updated
-		logEventsChan := make(chan *chat.LogEvent, 32)
-		chatAgent, err := chat.NewAgent(app.Context(), 64, logEventsChan, config.TwitchChannelName, time.Second)
-		if err != nil {
-			log.Fatalf("error initializing chat agent: %v", err)
+		chatAgents := make([]*chat.Agent, 0, len(channels))
+		for _, channel := range channels {
+			// Each chat.Agent sits in a channel's IRC chat and interprets messages,
+			// writing to its logEventsChan whenever the chat log UI should be updated
+			logEventsChan := make(chan *chat.LogEvent, 32)
+			chatAgent, err := chat.NewAgent(app.Context(), 64, logEventsChan, channel.Name, time.Second)
+			if err != nil {
+				log.Fatalf("error initializing chat agent for %s: %v", channel.Name, err)
+			}
+			defer chatAgent.Disconnect()
+			chatAgents = append(chatAgents, chatAgent)
+
+			// The sse.Handler exposes that LogEvent channel via an SSE endpoint
+			chatHandler := sse.NewHandler[*chat.LogEvent](app.Context(), logEventsChan)
+			registerChannelRoute(r, &channel, "/chat", chatHandler)
 		}
 		getChatStatus = func() error {
-			return chatAgent.GetStatus()
+			for i, chatAgent := range chatAgents {
+				if err := chatAgent.GetStatus(); err != nil {
+					if !channels[i].IsPrimary {
+						return fmt.Errorf("chat for partner channel %s: %w", channels[i].Name, err)
+					}
+					return err
+				}
+			}
+			return nil
 		}
-		defer chatAgent.Disconnect()
-
-		// The sse.Handler exposes that LogEvent channel via an SSE endpoint
-		chatHandler := sse.NewHandler[*chat.LogEvent](app.Context(), logEventsChan)
-		r.Path("/chat").Methods("GET").Handler(chatHandler)
 	}
 
 	// Clients can hit GET / to get the health of the Golden VCR Twitch integration,
 	// with the response certifying whether all EventSub subscriptions are enabled and
 	// the chat agent is connected to IRC
 	{
-		healthServer := health.NewServer(twitchClient, channelUserId, config.TwitchWebhookCallbackUrl, getChatStatus)
+		healthServer := health.NewServer(eventSubClient, q, channels, getEventSubTransport, getChatStatus, getReconcileReport)
 		r.Path("/").Methods("GET").Handler(healthServer)
 	}
 
 	// POST /admin/tape/:id etc. allow the broadcaster to update the state of streams
 	{
-		adminServer := admin.NewServer(q)
+		adminServer := admin.NewServer(db, q, channelUserId, moderator)
 		adminServer.RegisterRoutes(authClient, r.PathPrefix("/admin").Subrouter())
 	}
 
 	// GET /state provides clients with real-time information about the current state of
 	// the broadcast: whether we've live, what tape is being screened, etc.
-	{
-		stateHandler := sse.NewHandler(app.Context(), changeListener.GetStateChanges())
+	for _, channel := range channels {
+		channelId := channel.UserId
+		stateHandler := sse.NewHandler(app.Context(), changeListener.GetStateChanges(channelId))
 		stateHandler.OnConnectEventFunc = func() broadcast.State {
-			return changeListener.GetState()
+			return changeListener.GetState(channelId)
 		}
-		r.Path("/state").Methods("GET").Handler(stateHandler)
+		registerChannelRoute(r, &channel, "/state", stateHandler)
 	}
 
 	// GET /history exposes endpoints that provide information about past broadcasts
-	{
-		historyServer := history.NewServer(q)
-		historyServer.RegisterRoutes(r.PathPrefix("/history").Subrouter())
+	for _, channel := range channels {
+		historyServer := history.NewServer(q, channel.UserId)
+		historyServer.RegisterRoutes(r.PathPrefix(fmt.Sprintf("/channels/%s/history", channel.UserId)).Subrouter())
+		if channel.IsPrimary {
+			historyServer.RegisterRoutes(r.PathPrefix("/history").Subrouter())
+		}
 	}
 
-	// POST /image-gen allows requests to be submitted for image generation
+	// GET /viewers exposes information about individual viewers, such as whether
+	// they're currently subscribed
 	{
-		imageGeneration := imagegen.NewGenerationClient(config.OpenaiApiKey)
-		imageStorage, err := imagegen.NewStorageClient(
-			config.SpacesAccessKeyId,
-			config.SpacesSecretKey,
-			config.SpacesEndpointOrigin,
-			config.SpacesRegionName,
-			config.SpacesBucketName,
-		)
-		if err != nil {
-			log.Fatalf("Failed to initialize storage client for image generation: %v", err)
-		}
-		imagegenServer := imagegen.NewServer(q, ledgerClient, imageGeneration, imageStorage, config.DiscordGhostsWebhookUrl, alertsChan)
-		imagegenServer.RegisterRoutes(authClient, r.PathPrefix("/image-gen").Subrouter())
+		viewersServer := viewers.NewServer(q)
+		viewersServer.RegisterRoutes(r.PathPrefix("/viewers").Subrouter())
 	}
 
+	// POST /image-gen allows requests to be submitted for image generation
+	imagegenServer.RegisterRoutes(authClient, r.PathPrefix("/image-gen").Subrouter())
+
 	// Handle incoming HTTP connections until our top-level context is canceled, at
 	// which point shut down cleanly
 	entry.RunServer(app, r, config.BindAddr, int(config.ListenPort))
 }
+
+// registerChannelRoute serves the given handler at GET /channels/:userId<path> for the
+// given channel: the primary channel's handler is also served at GET <path>
+func registerChannelRoute(r *mux.Router, channel *showtime.Channel, path string, handler http.Handler) {
+	r.Path(fmt.Sprintf("/channels/%s%s", channel.UserId, path)).Methods("GET").Handler(handler)
+	if channel.IsPrimary {
+		r.Path(path).Methods("GET").Handler(handler)
+	}
+}
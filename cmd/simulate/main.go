@@ -5,6 +5,7 @@ import (
 	"crypto/sha256"
 	"encoding/hex"
 	"encoding/json"
+	"flag"
 	"fmt"
 	"log"
 	"net/http"
@@ -13,12 +14,9 @@ import (
 	"time"
 
 	"github.com/codingconcepts/env"
-	"github.com/golden-vcr/showtime"
-	"github.com/golden-vcr/showtime/internal/events"
 	"github.com/golden-vcr/showtime/internal/twitch"
 	"github.com/google/uuid"
 	"github.com/joho/godotenv"
-	"github.com/nicklaw5/helix/v2"
 )
 
 const (
@@ -27,18 +25,44 @@ const (
 	TwitchHeaderMessageSignature = "twitch-eventsub-message-signature"
 )
 
+// defaultOfflineChannelUserId is the Twitch user ID that's used for the broadcaster in
+// simulated events when running with -offline, unless overridden with -channel-id
+const defaultOfflineChannelUserId = "953753877"
+
+// defaultOfflineChannelName is the channel name used with -offline when
+// TWITCH_CHANNEL_NAME is not set
+const defaultOfflineChannelName = "goldenvcr"
+
 type Config struct {
-	TwitchChannelName   string `env:"TWITCH_CHANNEL_NAME" required:"true"`
-	TwitchClientId      string `env:"TWITCH_CLIENT_ID" required:"true"`
-	TwitchClientSecret  string `env:"TWITCH_CLIENT_SECRET" required:"true"`
+	TwitchChannelName   string `env:"TWITCH_CHANNEL_NAME"`
+	TwitchClientId      string `env:"TWITCH_CLIENT_ID"`
+	TwitchClientSecret  string `env:"TWITCH_CLIENT_SECRET"`
 	TwitchWebhookSecret string `env:"TWITCH_WEBHOOK_SECRET" required:"true"`
+
+	// TwitchApiUrl and TwitchAuthUrl may be overridden to point at a fake Twitch server
+	// (see cmd/faketwitch) for testing
+	TwitchApiUrl  string `env:"TWITCH_API_URL" default:"https://api.twitch.tv/helix"`
+	TwitchAuthUrl string `env:"TWITCH_AUTH_URL" default:"https://id.twitch.tv/oauth2"`
 }
 
 func main() {
-	// We only want to simulate events locally for now; events that can be recorded in
-	// the production DB and affect the state of the actual, deployed webapp should only
-	// come from Twitch itself
-	url := "http://localhost:5001/callback"
+	// We only want to simulate events locally by default; events that can be recorded
+	// in the production DB and affect the state of the actual, deployed webapp should
+	// only come from Twitch itself
+	url := flag.String("url", "http://localhost:5001/callback", "URL of the EventSub callback endpoint to send events to")
+	offline := flag.Bool("offline", false, "Use a fixed channel user ID instead of looking it up via the Twitch API")
+	channelId := flag.String("channel-id", "", "Twitch user ID of the broadcaster to use in simulated events (implies -offline)")
+	outgoing := flag.Bool("outgoing", false, "Simulate an outgoing raid instead of an incoming one (channel.raid only)")
+	scenarioPath := flag.String("scenario", "", "Path to a .yaml or .jsonl file describing a timed sequence of events to send")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: simulate [flags] <subscription-type>\n       simulate [flags] -scenario <file>\n\nSupported subscription types:\n")
+		for _, subscriptionType := range supportedSubscriptionTypes() {
+			fmt.Fprintf(flag.CommandLine.Output(), "  %s\n", subscriptionType)
+		}
+		fmt.Fprintf(flag.CommandLine.Output(), "\nFlags:\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
 
 	// Parse config from environment variables
 	err := godotenv.Load()
@@ -50,30 +74,110 @@ func main() {
 		log.Fatalf("error loading config: %v", err)
 	}
 
-	// Parse the name of the subscription type we want to emulate, passed as a CLI arg
-	if len(os.Args) <= 1 {
-		log.Fatalf("Usage: simulate [channel.follow|...]")
+	// Figure out which steps we want to play: either a single event of the type given
+	// as a CLI arg, or the full sequence of events from a scenario file
+	var steps []Step
+	if *scenarioPath != "" {
+		if flag.NArg() > 0 {
+			log.Fatalf("a subscription type may not be specified along with -scenario")
+		}
+		steps, err = loadScenario(*scenarioPath)
+		if err != nil {
+			log.Fatalf("error loading scenario: %v", err)
+		}
+	} else {
+		if flag.NArg() != 1 {
+			flag.Usage()
+			os.Exit(2)
+		}
+		steps = []Step{{Type: flag.Arg(0), Outgoing: *outgoing}}
+	}
+
+	// Resolve the user ID for the configured Twitch channel, either by looking it up
+	// via the Twitch API or by using a fixed value if we're running offline
+	channel, err := resolveChannel(&config, *offline, *channelId)
+	if err != nil {
+		log.Fatalf("error resolving channel: %v", err)
+	}
+
+	// Build all payloads up front so that we fail fast if a scenario is invalid
+	payloads := make([]MessagePayload, 0, len(steps))
+	for i, step := range steps {
+		payload, err := buildMessagePayload(step, channel, *url)
+		if err != nil {
+			if *scenarioPath != "" {
+				log.Fatalf("error in step %d of scenario: %v", i, err)
+			}
+			log.Fatalf("%v", err)
+		}
+		payloads = append(payloads, payload)
+	}
+
+	// Send each event, waiting until its scheduled offset from the time we started
+	start := time.Now()
+	for i := range steps {
+		if wait := time.Until(start.Add(steps[i].At.Duration)); wait > 0 {
+			time.Sleep(wait)
+		}
+		if *scenarioPath != "" {
+			fmt.Printf("[%s] step %d: %s\n", steps[i].At.Duration, i, steps[i].Type)
+		}
+		if err := send(*url, config.TwitchWebhookSecret, &payloads[i]); err != nil {
+			log.Fatalf("%v", err)
+		}
 	}
-	subscriptionType := os.Args[1]
+}
 
-	// Get the user ID for the configured Twitch channel
-	channelUserId, err := getChannelUserId(config.TwitchChannelName, config.TwitchClientId, config.TwitchClientSecret)
+// Channel identifies the broadcaster that simulated events are sent on behalf of
+type Channel struct {
+	UserId string
+	Name   string
+}
+
+func resolveChannel(config *Config, offline bool, channelId string) (Channel, error) {
+	if offline || channelId != "" {
+		if channelId == "" {
+			channelId = defaultOfflineChannelUserId
+		}
+		name := config.TwitchChannelName
+		if name == "" {
+			name = defaultOfflineChannelName
+		}
+		return Channel{UserId: channelId, Name: name}, nil
+	}
+
+	if config.TwitchChannelName == "" || config.TwitchClientId == "" || config.TwitchClientSecret == "" {
+		return Channel{}, fmt.Errorf("TWITCH_CHANNEL_NAME, TWITCH_CLIENT_ID, and TWITCH_CLIENT_SECRET are required unless running with -offline")
+	}
+	channelUserId, err := getChannelUserId(twitch.Endpoints{ApiUrl: config.TwitchApiUrl, AuthUrl: config.TwitchAuthUrl}, config.TwitchChannelName, config.TwitchClientId, config.TwitchClientSecret)
 	if err != nil {
-		log.Fatalf("error getting channel user ID: %v", err)
+		return Channel{}, fmt.Errorf("error getting channel user ID: %w", err)
 	}
+	return Channel{UserId: channelUserId, Name: config.TwitchChannelName}, nil
+}
 
+func getChannelUserId(endpoints twitch.Endpoints, channelName string, clientId string, clientSecret string) (string, error) {
+	client, err := twitch.NewClientWithAppToken(endpoints, clientId, clientSecret)
+	if err != nil {
+		return "", err
+	}
+	return twitch.GetChannelUserId(client, channelName)
+}
+
+// send delivers a single EventSub notification to the given URL, signed with the
+// webhook secret, and returns an error if we don't get an OK response
+func send(url string, secret string, payload *MessagePayload) error {
 	// Prepare the JSON-encoded message that we want to send
-	payload := buildMessagePayload(subscriptionType, channelUserId, config.TwitchChannelName, url)
 	messageBytes, err := json.Marshal(payload)
 	if err != nil {
-		log.Fatalf("failed to encode message payload: %v", err)
+		return fmt.Errorf("failed to encode message payload: %w", err)
 	}
 	message := string(messageBytes)
 
 	// Prepare the HTTP request that will carry that message in its body
 	req, err := http.NewRequest(http.MethodPost, url, strings.NewReader(message))
 	if err != nil {
-		log.Fatalf("error initializing HTTP request: %v", err)
+		return fmt.Errorf("error initializing HTTP request: %w", err)
 	}
 
 	// Set Twitch-Eventsub-* headers to identify the message and cryptographically sign
@@ -81,7 +185,7 @@ func main() {
 	// verify
 	req.Header.Set(TwitchHeaderMessageId, uuid.New().String())
 	req.Header.Set(TwitchHeaderMessageTimestamp, time.Now().Format(time.RFC3339))
-	req.Header.Set(TwitchHeaderMessageSignature, computeSignature(config.TwitchWebhookSecret, req.Header, message))
+	req.Header.Set(TwitchHeaderMessageSignature, computeSignature(secret, req.Header, message))
 
 	// Print the details of the request to stdout
 	fmt.Printf("%s %s\n", req.Method, req.URL)
@@ -92,165 +196,21 @@ func main() {
 	}
 	pretty, err := json.MarshalIndent(payload, "", "    ")
 	if err != nil {
-		log.Fatalf("failed to pretty-print JSON payload: %v", err)
+		return fmt.Errorf("failed to pretty-print JSON payload: %w", err)
 	}
 	fmt.Printf("\n%s\n\n", pretty)
 
 	// Send the request and verify that we get an OK response
 	res, err := http.DefaultClient.Do(req)
 	if err != nil {
-		log.Fatalf("error sending HTTP request: %v", err)
+		return fmt.Errorf("error sending HTTP request: %w", err)
 	}
+	defer res.Body.Close()
 	if res.StatusCode != http.StatusOK {
-		log.Fatalf("got response %d", res.StatusCode)
+		return fmt.Errorf("got response %d", res.StatusCode)
 	}
 	fmt.Printf("< %d\n", res.StatusCode)
-}
-
-func getChannelUserId(channelName string, clientId string, clientSecret string) (string, error) {
-	client, err := twitch.NewClientWithAppToken(clientId, clientSecret)
-	if err != nil {
-		return "", err
-	}
-	return twitch.GetChannelUserId(client, channelName)
-}
-
-type MessagePayload struct {
-	Subscription helix.EventSubSubscription `json:"subscription"`
-	Challenge    string                     `json:"challenge"`
-	Event        json.RawMessage            `json:"event"`
-}
-
-func buildMessagePayload(subscriptionType string, channelUserId string, channelName string, webhookUrl string) MessagePayload {
-	params := events.RequiredSubscriptionConditionParams{
-		ChannelUserId: channelUserId,
-	}
-
-	p := MessagePayload{}
-	for _, required := range showtime.RequiredSubscriptions {
-		if required.Type == subscriptionType {
-			p.Subscription.Type = subscriptionType
-			p.Subscription.Version = required.Version
-			cond, err := params.Format(&required.TemplatedCondition)
-			if err != nil {
-				log.Fatalf("failed to format subscription condition from template: %v", err)
-			}
-			p.Subscription.Condition = *cond
-			break
-		}
-	}
-	if p.Subscription.Type == "" {
-		log.Fatalf("no subscription of type %s is required in events.go", subscriptionType)
-	}
-	p.Subscription.ID = uuid.New().String()
-	p.Subscription.Status = helix.EventSubStatusEnabled
-	p.Subscription.Transport.Method = "webhook"
-	p.Subscription.Transport.Callback = webhookUrl
-	p.Subscription.CreatedAt = helix.Time{Time: time.Now().Add(-5 * time.Minute)}
-
-	p.Event = buildEventPayload(subscriptionType, channelUserId, channelName)
-
-	return p
-}
-
-func buildEventPayload(subscriptionType string, channelUserId string, channelName string) json.RawMessage {
-	var p any = nil
-	switch subscriptionType {
-	case helix.EventSubTypeStreamOnline:
-		p = &helix.EventSubStreamOnlineEvent{
-			ID:                   "9001",
-			BroadcasterUserID:    channelUserId,
-			BroadcasterUserLogin: channelName,
-			BroadcasterUserName:  channelName,
-			Type:                 "live",
-			StartedAt:            helix.Time{Time: time.Now()},
-		}
-	case helix.EventSubTypeStreamOffline:
-		p = &helix.EventSubStreamOfflineEvent{
-			BroadcasterUserID:    channelUserId,
-			BroadcasterUserLogin: channelName,
-			BroadcasterUserName:  channelName,
-		}
-	case helix.EventSubTypeChannelFollow:
-		p = &helix.EventSubChannelFollowEvent{
-			UserID:               "1337",
-			UserLogin:            "bigjim",
-			UserName:             "BigJim",
-			BroadcasterUserID:    channelUserId,
-			BroadcasterUserLogin: channelName,
-			BroadcasterUserName:  channelName,
-			FollowedAt:           helix.Time{Time: time.Now()},
-		}
-	case helix.EventSubTypeChannelCheer:
-		p = &helix.EventSubChannelCheerEvent{
-			IsAnonymous:          false,
-			UserID:               "1337",
-			UserLogin:            "bigjim",
-			UserName:             "BigJim",
-			BroadcasterUserID:    channelUserId,
-			BroadcasterUserLogin: channelName,
-			BroadcasterUserName:  channelName,
-			Message:              "ghost of a baby seal",
-			Bits:                 200,
-		}
-	case helix.EventSubTypeChannelSubscription:
-		p = &helix.EventSubChannelSubscribeEvent{
-			UserID:               "1337",
-			UserLogin:            "bigjim",
-			UserName:             "BigJim",
-			BroadcasterUserID:    channelUserId,
-			BroadcasterUserLogin: channelName,
-			BroadcasterUserName:  channelName,
-			Tier:                 "1000",
-			IsGift:               false,
-		}
-	case helix.EventSubTypeChannelSubscriptionEnd:
-		p = &helix.EventSubChannelSubscribeEvent{
-			UserID:               "1337",
-			UserLogin:            "bigjim",
-			UserName:             "BigJim",
-			BroadcasterUserID:    channelUserId,
-			BroadcasterUserLogin: channelName,
-			BroadcasterUserName:  channelName,
-			Tier:                 "1000",
-			IsGift:               false,
-		}
-	case helix.EventSubTypeChannelSubscriptionGift:
-		p = &helix.EventSubChannelSubscriptionGiftEvent{
-			UserID:               "6969",
-			UserLogin:            "generousphil",
-			UserName:             "GenerousPhil",
-			BroadcasterUserID:    channelUserId,
-			BroadcasterUserLogin: channelName,
-			BroadcasterUserName:  channelName,
-			Total:                5,
-			Tier:                 "1000",
-		}
-	case helix.EventSubTypeChannelSubscriptionMessage:
-		p = &helix.EventSubChannelSubscriptionMessageEvent{
-			UserID:               "1337",
-			UserLogin:            "bigjim",
-			UserName:             "BigJim",
-			BroadcasterUserID:    channelUserId,
-			BroadcasterUserLogin: channelName,
-			BroadcasterUserName:  channelName,
-			Tier:                 "1000",
-			Message: helix.EventSubMessage{
-				Text: "hello, I have resubscribed",
-			},
-			CumulativeMonths: 3,
-			StreakMonths:     3,
-			DurationMonths:   1,
-		}
-	default:
-		log.Fatalf("subscription type %s is not supported in buildEventPayload", subscriptionType)
-	}
-
-	data, err := json.Marshal(p)
-	if err != nil {
-		log.Fatalf("failed to encode event for subscription type %s: %v", subscriptionType, err)
-	}
-	return data
+	return nil
 }
 
 func computeSignature(secret string, h http.Header, message string) string {
@@ -0,0 +1,392 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/golden-vcr/showtime"
+	"github.com/golden-vcr/showtime/internal/events"
+	"github.com/google/uuid"
+	"github.com/nicklaw5/helix/v2"
+)
+
+// MessagePayload is the body of an EventSub notification, as sent by Twitch
+type MessagePayload struct {
+	Subscription helix.EventSubSubscription `json:"subscription"`
+	Challenge    string                     `json:"challenge"`
+	Event        json.RawMessage            `json:"event"`
+}
+
+// supportedSubscriptionTypes returns the distinct types of all subscriptions declared
+// in RequiredSubscriptions, in order
+func supportedSubscriptionTypes() []string {
+	types := make([]string, 0, len(showtime.RequiredSubscriptions))
+	seen := make(map[string]struct{})
+	for _, required := range showtime.RequiredSubscriptions {
+		if _, ok := seen[required.Type]; ok {
+			continue
+		}
+		seen[required.Type] = struct{}{}
+		types = append(types, required.Type)
+	}
+	return types
+}
+
+// findRequiredSubscription returns the subscription from RequiredSubscriptions that
+// would cause Twitch to deliver the event described by step: for channel.raid, we
+// subscribe once for incoming and once for outgoing raids, distinguished by condition
+func findRequiredSubscription(step Step) (*events.RequiredSubscription, error) {
+	for i := range showtime.RequiredSubscriptions {
+		required := &showtime.RequiredSubscriptions[i]
+		if required.Type != step.Type {
+			continue
+		}
+		if required.Type == helix.EventSubTypeChannelRaid {
+			isOutgoing := required.TemplatedCondition.FromBroadcasterUserID != ""
+			if isOutgoing != step.Outgoing {
+				continue
+			}
+		} else if step.Outgoing {
+			return nil, fmt.Errorf("outgoing may only be set for %s", helix.EventSubTypeChannelRaid)
+		}
+		return required, nil
+	}
+	return nil, fmt.Errorf("no subscription of type %s is required in events.go", step.Type)
+}
+
+func buildMessagePayload(step Step, channel Channel, webhookUrl string) (MessagePayload, error) {
+	required, err := findRequiredSubscription(step)
+	if err != nil {
+		return MessagePayload{}, err
+	}
+
+	params := events.RequiredSubscriptionConditionParams{
+		ChannelUserId: channel.UserId,
+	}
+	cond, err := params.Format(&required.TemplatedCondition)
+	if err != nil {
+		return MessagePayload{}, fmt.Errorf("failed to format subscription condition from template: %w", err)
+	}
+
+	p := MessagePayload{}
+	p.Subscription.ID = uuid.New().String()
+	p.Subscription.Type = required.Type
+	p.Subscription.Version = required.Version
+	p.Subscription.Status = helix.EventSubStatusEnabled
+	p.Subscription.Condition = *cond
+	p.Subscription.Transport.Method = "webhook"
+	p.Subscription.Transport.Callback = webhookUrl
+	p.Subscription.CreatedAt = helix.Time{Time: time.Now().Add(-5 * time.Minute)}
+
+	event, err := buildEventPayload(step.Type, step.Outgoing, channel, time.Now())
+	if err != nil {
+		return MessagePayload{}, err
+	}
+	if len(step.Event) > 0 {
+		event, err = applyOverrides(event, step.Event)
+		if err != nil {
+			return MessagePayload{}, fmt.Errorf("failed to apply event overrides: %w", err)
+		}
+	}
+	p.Event = event
+
+	return p, nil
+}
+
+// applyOverrides merges the given values into the JSON object encoded in data:
+// nested objects are merged recursively, and all other values replace the original
+func applyOverrides(data json.RawMessage, overrides map[string]any) (json.RawMessage, error) {
+	var event map[string]any
+	if err := json.Unmarshal(data, &event); err != nil {
+		return nil, err
+	}
+	mergeValues(event, overrides)
+	return json.Marshal(event)
+}
+
+func mergeValues(dst map[string]any, src map[string]any) {
+	for k, v := range src {
+		srcMap, srcIsMap := v.(map[string]any)
+		dstMap, dstIsMap := dst[k].(map[string]any)
+		if srcIsMap && dstIsMap {
+			mergeValues(dstMap, srcMap)
+		} else {
+			dst[k] = v
+		}
+	}
+}
+
+func buildEventPayload(subscriptionType string, outgoing bool, channel Channel, now time.Time) (json.RawMessage, error) {
+	channelUserId := channel.UserId
+	channelName := channel.Name
+	bigjim := helix.EventSubContribution{
+		UserID:    "1337",
+		UserLogin: "bigjim",
+		UserName:  "BigJim",
+		Type:      "bits",
+		Total:     500,
+	}
+	pollChoices := []helix.PollChoice{
+		{ID: "poll-choice-1", Title: "Rewind it"},
+		{ID: "poll-choice-2", Title: "Eject it"},
+	}
+	predictionOutcomes := []helix.EventSubOutcome{
+		{ID: "prediction-outcome-1", Title: "It's a good tape", Color: "blue"},
+		{ID: "prediction-outcome-2", Title: "It's a bad tape", Color: "pink"},
+	}
+
+	var p any = nil
+	switch subscriptionType {
+	case helix.EventSubTypeChannelUpdate:
+		p = &helix.EventSubChannelUpdateEvent{
+			BroadcasterUserID:    channelUserId,
+			BroadcasterUserLogin: channelName,
+			BroadcasterUserName:  channelName,
+			Title:                "Watching some old tapes",
+			Language:             "en",
+			CategoryID:           "509658",
+			CategoryName:         "Just Chatting",
+		}
+	case helix.EventSubTypeStreamOnline:
+		p = &helix.EventSubStreamOnlineEvent{
+			ID:                   "9001",
+			BroadcasterUserID:    channelUserId,
+			BroadcasterUserLogin: channelName,
+			BroadcasterUserName:  channelName,
+			Type:                 "live",
+			StartedAt:            helix.Time{Time: now},
+		}
+	case helix.EventSubTypeStreamOffline:
+		p = &helix.EventSubStreamOfflineEvent{
+			BroadcasterUserID:    channelUserId,
+			BroadcasterUserLogin: channelName,
+			BroadcasterUserName:  channelName,
+		}
+	case helix.EventSubTypeChannelFollow:
+		p = &helix.EventSubChannelFollowEvent{
+			UserID:               "1337",
+			UserLogin:            "bigjim",
+			UserName:             "BigJim",
+			BroadcasterUserID:    channelUserId,
+			BroadcasterUserLogin: channelName,
+			BroadcasterUserName:  channelName,
+			FollowedAt:           helix.Time{Time: now},
+		}
+	case helix.EventSubTypeChannelRaid:
+		if outgoing {
+			p = &helix.EventSubChannelRaidEvent{
+				FromBroadcasterUserID:    channelUserId,
+				FromBroadcasterUserLogin: channelName,
+				FromBroadcasterUserName:  channelName,
+				ToBroadcasterUserID:      "4242",
+				ToBroadcasterUserLogin:   "tapefriend",
+				ToBroadcasterUserName:    "TapeFriend",
+				Viewers:                  12,
+			}
+		} else {
+			p = &helix.EventSubChannelRaidEvent{
+				FromBroadcasterUserID:    "4242",
+				FromBroadcasterUserLogin: "tapefriend",
+				FromBroadcasterUserName:  "TapeFriend",
+				ToBroadcasterUserID:      channelUserId,
+				ToBroadcasterUserLogin:   channelName,
+				ToBroadcasterUserName:    channelName,
+				Viewers:                  25,
+			}
+		}
+	case helix.EventSubTypeChannelCheer:
+		p = &helix.EventSubChannelCheerEvent{
+			IsAnonymous:          false,
+			UserID:               "1337",
+			UserLogin:            "bigjim",
+			UserName:             "BigJim",
+			BroadcasterUserID:    channelUserId,
+			BroadcasterUserLogin: channelName,
+			BroadcasterUserName:  channelName,
+			Message:              "ghost of a baby seal",
+			Bits:                 200,
+		}
+	case helix.EventSubTypeChannelSubscription:
+		p = &helix.EventSubChannelSubscribeEvent{
+			UserID:               "1337",
+			UserLogin:            "bigjim",
+			UserName:             "BigJim",
+			BroadcasterUserID:    channelUserId,
+			BroadcasterUserLogin: channelName,
+			BroadcasterUserName:  channelName,
+			Tier:                 "1000",
+			IsGift:               false,
+		}
+	case helix.EventSubTypeChannelSubscriptionEnd:
+		p = &helix.EventSubChannelSubscribeEvent{
+			UserID:               "1337",
+			UserLogin:            "bigjim",
+			UserName:             "BigJim",
+			BroadcasterUserID:    channelUserId,
+			BroadcasterUserLogin: channelName,
+			BroadcasterUserName:  channelName,
+			Tier:                 "1000",
+			IsGift:               false,
+		}
+	case helix.EventSubTypeChannelSubscriptionGift:
+		p = &helix.EventSubChannelSubscriptionGiftEvent{
+			UserID:               "6969",
+			UserLogin:            "generousphil",
+			UserName:             "GenerousPhil",
+			BroadcasterUserID:    channelUserId,
+			BroadcasterUserLogin: channelName,
+			BroadcasterUserName:  channelName,
+			Total:                5,
+			Tier:                 "1000",
+		}
+	case helix.EventSubTypeChannelSubscriptionMessage:
+		p = &helix.EventSubChannelSubscriptionMessageEvent{
+			UserID:               "1337",
+			UserLogin:            "bigjim",
+			UserName:             "BigJim",
+			BroadcasterUserID:    channelUserId,
+			BroadcasterUserLogin: channelName,
+			BroadcasterUserName:  channelName,
+			Tier:                 "1000",
+			Message: helix.EventSubMessage{
+				Text: "hello, I have resubscribed",
+			},
+			CumulativeMonths: 3,
+			StreakMonths:     3,
+			DurationMonths:   1,
+		}
+	case helix.EventSubTypeChannelPointsCustomRewardRedemptionAdd:
+		p = &helix.EventSubChannelPointsCustomRewardRedemptionEvent{
+			ID:                   uuid.New().String(),
+			BroadcasterUserID:    channelUserId,
+			BroadcasterUserLogin: channelName,
+			BroadcasterUserName:  channelName,
+			UserID:               "1337",
+			UserLogin:            "bigjim",
+			UserName:             "BigJim",
+			UserInput:            "please play the one with the dinosaurs",
+			Status:               "unfulfilled",
+			Reward: helix.EventSubReward{
+				ID:     "simulated-reward",
+				Title:  "Request a tape",
+				Cost:   1000,
+				Prompt: "Tell us which tape you'd like to see",
+			},
+			RedeemedAt: helix.Time{Time: now},
+		}
+	case helix.EventSubTypeHypeTrainBegin, helix.EventSubTypeHypeTrainProgress:
+		p = &struct {
+			ID string `json:"id"`
+			helix.EventSubHypeTrainProgressEvent
+		}{
+			ID: "simulated-hype-train",
+			EventSubHypeTrainProgressEvent: helix.EventSubHypeTrainProgressEvent{
+				BroadcasterUserID:    channelUserId,
+				BroadcasterUserLogin: channelName,
+				BroadcasterUserName:  channelName,
+				Level:                1,
+				Total:                500,
+				Progress:             500,
+				Goal:                 1800,
+				TopContributions:     []helix.EventSubContribution{bigjim},
+				LastContribution:     bigjim,
+				StartedAt:            helix.Time{Time: now},
+				ExpiresAt:            helix.Time{Time: now.Add(5 * time.Minute)},
+			},
+		}
+	case helix.EventSubTypeHypeTrainEnd:
+		p = &struct {
+			ID string `json:"id"`
+			helix.EventSubHypeTrainEndEvent
+			EndedAt helix.Time `json:"ended_at"`
+		}{
+			ID: "simulated-hype-train",
+			EventSubHypeTrainEndEvent: helix.EventSubHypeTrainEndEvent{
+				BroadcasterUserID:    channelUserId,
+				BroadcasterUserLogin: channelName,
+				BroadcasterUserName:  channelName,
+				Level:                2,
+				Total:                2300,
+				TopContributions:     []helix.EventSubContribution{bigjim},
+				StartedAt:            helix.Time{Time: now.Add(-5 * time.Minute)},
+				CooldownEndsAt:       helix.Time{Time: now.Add(time.Hour)},
+			},
+			EndedAt: helix.Time{Time: now},
+		}
+	case helix.EventSubTypeChannelPollBegin, helix.EventSubTypeChannelPollProgress:
+		p = &helix.EventSubChannelPollBeginEvent{
+			ID:                   "simulated-poll",
+			BroadcasterUserID:    channelUserId,
+			BroadcasterUserLogin: channelName,
+			BroadcasterUserName:  channelName,
+			Title:                "What should we do with this tape?",
+			Choices:              pollChoices,
+			StartedAt:            helix.Time{Time: now},
+			EndsAt:               helix.Time{Time: now.Add(2 * time.Minute)},
+		}
+	case helix.EventSubTypeChannelPollEnd:
+		pollChoices[0].Votes = 7
+		pollChoices[1].Votes = 3
+		p = &helix.EventSubChannelPollEndEvent{
+			ID:                   "simulated-poll",
+			BroadcasterUserID:    channelUserId,
+			BroadcasterUserLogin: channelName,
+			BroadcasterUserName:  channelName,
+			Title:                "What should we do with this tape?",
+			Choices:              pollChoices,
+			Status:               "completed",
+			StartedAt:            helix.Time{Time: now.Add(-2 * time.Minute)},
+			EndedAt:              helix.Time{Time: now},
+		}
+	case helix.EventSubTypeChannelPredictionBegin, helix.EventSubTypeChannelPredictionProgress:
+		p = &helix.EventSubChannelPredictionBeginEvent{
+			ID:                   "simulated-prediction",
+			BroadcasterUserID:    channelUserId,
+			BroadcasterUserLogin: channelName,
+			BroadcasterUserName:  channelName,
+			Title:                "Is this tape any good?",
+			Outcomes:             predictionOutcomes,
+			StartedAt:            helix.Time{Time: now},
+			LocksAt:              helix.Time{Time: now.Add(2 * time.Minute)},
+		}
+	case helix.EventSubTypeChannelPredictionLock:
+		p = &helix.EventSubChannelPredictionLockEvent{
+			ID:                   "simulated-prediction",
+			BroadcasterUserID:    channelUserId,
+			BroadcasterUserLogin: channelName,
+			BroadcasterUserName:  channelName,
+			Title:                "Is this tape any good?",
+			Outcomes:             predictionOutcomes,
+			Status:               "locked",
+			StartedAt:            helix.Time{Time: now.Add(-2 * time.Minute)},
+			LockedAt:             helix.Time{Time: now},
+		}
+	case helix.EventSubTypeChannelPredictionEnd:
+		p = &struct {
+			helix.EventSubChannelPredictionEndEvent
+			EndedAt helix.Time `json:"ended_at"`
+		}{
+			EventSubChannelPredictionEndEvent: helix.EventSubChannelPredictionEndEvent{
+				ID:                   "simulated-prediction",
+				BroadcasterUserID:    channelUserId,
+				BroadcasterUserLogin: channelName,
+				BroadcasterUserName:  channelName,
+				Title:                "Is this tape any good?",
+				WinningOutcomeID:     predictionOutcomes[0].ID,
+				Outcomes:             predictionOutcomes,
+				Status:               "resolved",
+				StartedAt:            helix.Time{Time: now.Add(-5 * time.Minute)},
+			},
+			EndedAt: helix.Time{Time: now},
+		}
+	default:
+		return nil, fmt.Errorf("subscription type %s is not supported in buildEventPayload", subscriptionType)
+	}
+
+	data, err := json.Marshal(p)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode event for subscription type %s: %w", subscriptionType, err)
+	}
+	return data, nil
+}
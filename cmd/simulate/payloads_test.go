@@ -0,0 +1,73 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/golden-vcr/showtime"
+	"github.com/nicklaw5/helix/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_buildMessagePayload(t *testing.T) {
+	channel := Channel{UserId: defaultOfflineChannelUserId, Name: defaultOfflineChannelName}
+	for _, required := range showtime.RequiredSubscriptions {
+		step := Step{
+			Type:     required.Type,
+			Outgoing: required.TemplatedCondition.FromBroadcasterUserID != "",
+		}
+		t.Run(step.Type, func(t *testing.T) {
+			p, err := buildMessagePayload(step, channel, "http://localhost:5001/callback")
+			assert.NoError(t, err)
+			assert.Equal(t, step.Type, p.Subscription.Type)
+			assert.Equal(t, required.Version, p.Subscription.Version)
+
+			var event map[string]any
+			err = json.Unmarshal(p.Event, &event)
+			assert.NoError(t, err)
+			assert.NotEmpty(t, event)
+		})
+	}
+}
+
+func Test_buildMessagePayload_raid(t *testing.T) {
+	channel := Channel{UserId: "1234", Name: "somechannel"}
+
+	p, err := buildMessagePayload(Step{Type: helix.EventSubTypeChannelRaid}, channel, "")
+	assert.NoError(t, err)
+	assert.Equal(t, "1234", p.Subscription.Condition.ToBroadcasterUserID)
+	var incoming helix.EventSubChannelRaidEvent
+	assert.NoError(t, json.Unmarshal(p.Event, &incoming))
+	assert.Equal(t, "1234", incoming.ToBroadcasterUserID)
+
+	p, err = buildMessagePayload(Step{Type: helix.EventSubTypeChannelRaid, Outgoing: true}, channel, "")
+	assert.NoError(t, err)
+	assert.Equal(t, "1234", p.Subscription.Condition.FromBroadcasterUserID)
+	var outgoing helix.EventSubChannelRaidEvent
+	assert.NoError(t, json.Unmarshal(p.Event, &outgoing))
+	assert.Equal(t, "1234", outgoing.FromBroadcasterUserID)
+
+	_, err = buildMessagePayload(Step{Type: helix.EventSubTypeChannelFollow, Outgoing: true}, channel, "")
+	assert.EqualError(t, err, "outgoing may only be set for channel.raid")
+}
+
+func Test_buildMessagePayload_overrides(t *testing.T) {
+	channel := Channel{UserId: "1234", Name: "somechannel"}
+	p, err := buildMessagePayload(Step{
+		Type: helix.EventSubTypeChannelPointsCustomRewardRedemptionAdd,
+		Event: map[string]any{
+			"user_input": "hello",
+			"reward": map[string]any{
+				"id": "some-reward",
+			},
+		},
+	}, channel, "")
+	assert.NoError(t, err)
+
+	var ev helix.EventSubChannelPointsCustomRewardRedemptionEvent
+	assert.NoError(t, json.Unmarshal(p.Event, &ev))
+	assert.Equal(t, "hello", ev.UserInput)
+	assert.Equal(t, "some-reward", ev.Reward.ID)
+	assert.Equal(t, "Request a tape", ev.Reward.Title)
+	assert.Equal(t, "1234", ev.BroadcasterUserID)
+}
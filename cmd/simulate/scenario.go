@@ -0,0 +1,138 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Step is a single event in a scenario, sent At some offset from the time the scenario
+// starts playing
+type Step struct {
+	// At is the time at which the event should be sent, relative to the start of the
+	// scenario, e.g. "90s" or "1h2m"
+	At Duration `json:"at" yaml:"at"`
+	// Type is the EventSub subscription type of the event, e.g. "stream.online"
+	Type string `json:"type" yaml:"type"`
+	// Outgoing indicates that a channel.raid event is a raid from our channel to
+	// another, rather than an incoming raid
+	Outgoing bool `json:"outgoing,omitempty" yaml:"outgoing,omitempty"`
+	// Event contains values that will be merged into the default event payload for
+	// the given type, e.g. {"bits": 500, "message": "hello"}
+	Event map[string]any `json:"event,omitempty" yaml:"event,omitempty"`
+}
+
+// Duration is a time.Duration that's encoded as a string like "1m30s"
+type Duration struct {
+	time.Duration
+}
+
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	return d.parse(s)
+}
+
+func (d *Duration) UnmarshalYAML(value *yaml.Node) error {
+	var s string
+	if err := value.Decode(&s); err != nil {
+		return err
+	}
+	return d.parse(s)
+}
+
+func (d *Duration) parse(s string) error {
+	parsed, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	if parsed < 0 {
+		return fmt.Errorf("duration may not be negative")
+	}
+	d.Duration = parsed
+	return nil
+}
+
+// yamlScenario is the top-level structure of a YAML scenario file
+type yamlScenario struct {
+	Events []Step `yaml:"events"`
+}
+
+// loadScenario reads a scenario from the file at the given path, which must have an
+// extension of .yaml, .yml, or .jsonl, and returns its steps in the order they should
+// be played
+func loadScenario(path string) ([]Step, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".yaml", ".yml":
+		return parseYamlScenario(data)
+	case ".jsonl":
+		return parseJsonlScenario(data)
+	default:
+		return nil, fmt.Errorf("unsupported scenario file extension '%s': expected .yaml, .yml, or .jsonl", ext)
+	}
+}
+
+func parseYamlScenario(data []byte) ([]Step, error) {
+	var scenario yamlScenario
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&scenario); err != nil {
+		return nil, fmt.Errorf("failed to parse YAML: %w", err)
+	}
+	return finalizeScenario(scenario.Events)
+}
+
+func parseJsonlScenario(data []byte) ([]Step, error) {
+	steps := make([]Step, 0)
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		var step Step
+		decoder := json.NewDecoder(bytes.NewReader(line))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&step); err != nil {
+			return nil, fmt.Errorf("failed to parse line %d: %w", lineNumber, err)
+		}
+		steps = append(steps, step)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return finalizeScenario(steps)
+}
+
+// finalizeScenario validates the steps of a scenario and orders them by time, while
+// preserving the order of steps that are scheduled for the same time
+func finalizeScenario(steps []Step) ([]Step, error) {
+	if len(steps) == 0 {
+		return nil, fmt.Errorf("scenario contains no events")
+	}
+	for i := range steps {
+		if steps[i].Type == "" {
+			return nil, fmt.Errorf("events[%d]: type is required", i)
+		}
+	}
+	sort.SliceStable(steps, func(i, j int) bool {
+		return steps[i].At.Duration < steps[j].At.Duration
+	})
+	return steps, nil
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_parseYamlScenario(t *testing.T) {
+	steps, err := parseYamlScenario([]byte(`
+events:
+  - at: 1m
+    type: stream.offline
+  - at: 0s
+    type: stream.online
+  - at: 30s
+    type: channel.raid
+    outgoing: true
+    event:
+      viewers: 12
+`))
+	assert.NoError(t, err)
+	assert.Equal(t, []Step{
+		{At: Duration{0}, Type: "stream.online"},
+		{At: Duration{30 * time.Second}, Type: "channel.raid", Outgoing: true, Event: map[string]any{"viewers": 12}},
+		{At: Duration{time.Minute}, Type: "stream.offline"},
+	}, steps)
+}
+
+func Test_parseJsonlScenario(t *testing.T) {
+	steps, err := parseJsonlScenario([]byte(`{"at": "0s", "type": "stream.online"}
+
+{"at": "10s", "type": "channel.cheer", "event": {"bits": 500}}
+{"at": "10s", "type": "channel.follow"}
+`))
+	assert.NoError(t, err)
+	assert.Equal(t, []Step{
+		{At: Duration{0}, Type: "stream.online"},
+		{At: Duration{10 * time.Second}, Type: "channel.cheer", Event: map[string]any{"bits": float64(500)}},
+		{At: Duration{10 * time.Second}, Type: "channel.follow"},
+	}, steps)
+}
+
+func Test_parseScenario_errors(t *testing.T) {
+	_, err := parseJsonlScenario([]byte(`{"at": "soon", "type": "stream.online"}`))
+	assert.EqualError(t, err, `failed to parse line 1: time: invalid duration "soon"`)
+
+	_, err = parseJsonlScenario([]byte(`{"at": "1s"}`))
+	assert.EqualError(t, err, "events[0]: type is required")
+
+	_, err = parseYamlScenario([]byte(`events: []`))
+	assert.EqualError(t, err, "scenario contains no events")
+}
+
+func Test_loadScenario_examples(t *testing.T) {
+	channel := Channel{UserId: defaultOfflineChannelUserId, Name: defaultOfflineChannelName}
+	steps, err := loadScenario("scenarios/stream.yaml")
+	assert.NoError(t, err)
+	for _, step := range steps {
+		_, err := buildMessagePayload(step, channel, "")
+		assert.NoError(t, err)
+	}
+}
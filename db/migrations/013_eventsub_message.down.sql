@@ -0,0 +1,5 @@
+begin;
+
+drop table showtime.eventsub_message;
+
+commit;
@@ -0,0 +1,33 @@
+begin;
+
+create table showtime.eventsub_message (
+    message_id        text primary key,
+    message_type      text not null,
+    subscription_type text not null,
+    message_timestamp timestamptz not null,
+    received_at       timestamptz not null default now()
+);
+
+comment on table showtime.eventsub_message is
+    'Records the fact that we''ve received a particular message from the Twitch '
+    'EventSub API. Twitch may redeliver a message if it doesn''t receive a timely '
+    'response, so we keep track of message IDs in order to ensure that each event is '
+    'handled only once.';
+comment on column showtime.eventsub_message.message_id is
+    'Unique ID of the message, as supplied in the Twitch-Eventsub-Message-Id header. '
+    'Redelivered messages retain the same ID.';
+comment on column showtime.eventsub_message.message_type is
+    'Type of message, as supplied in the Twitch-Eventsub-Message-Type header: e.g. '
+    '"notification", "webhook_callback_verification", or "revocation".';
+comment on column showtime.eventsub_message.subscription_type is
+    'Type of the EventSub subscription that produced the message, e.g. '
+    '"channel.follow".';
+comment on column showtime.eventsub_message.message_timestamp is
+    'Time at which Twitch sent the message, as supplied in the '
+    'Twitch-Eventsub-Message-Timestamp header.';
+comment on column showtime.eventsub_message.received_at is
+    'Time at which we first received the message.';
+
+create index eventsub_message_received_at_index on showtime.eventsub_message (received_at);
+
+commit;
@@ -0,0 +1,56 @@
+begin;
+
+create table showtime.eventsub_inbox (
+    message_id       text primary key,
+    subscription     jsonb not null,
+    event            jsonb not null,
+    created_at       timestamptz not null default now(),
+    num_attempts     integer not null default 0,
+    next_attempt_at  timestamptz not null default now(),
+    locked_until     timestamptz,
+    last_error       text,
+    processed_at     timestamptz,
+    dead_lettered_at timestamptz,
+    discarded_at     timestamptz
+);
+
+comment on table showtime.eventsub_inbox is
+    'Durable record of an EventSub notification that we''ve acknowledged and need to '
+    'process. Notifications are written to the inbox before we respond to Twitch, then '
+    'processed asynchronously, with failed attempts retried (with exponential backoff) '
+    'until the notification is either handled successfully or dead-lettered.';
+comment on column showtime.eventsub_inbox.message_id is
+    'ID of the EventSub message that carried this notification.';
+comment on column showtime.eventsub_inbox.subscription is
+    'JSON-encoded subscription details from the notification payload.';
+comment on column showtime.eventsub_inbox.event is
+    'JSON-encoded event data from the notification payload.';
+comment on column showtime.eventsub_inbox.created_at is
+    'Time at which the notification was received and written to the inbox.';
+comment on column showtime.eventsub_inbox.num_attempts is
+    'Number of times that we''ve attempted to process this notification.';
+comment on column showtime.eventsub_inbox.next_attempt_at is
+    'Earliest time at which the notification may next be processed.';
+comment on column showtime.eventsub_inbox.locked_until is
+    'If set and in the future, a worker has claimed this notification and is '
+    'processing it; other workers should leave it alone until this time has elapsed.';
+comment on column showtime.eventsub_inbox.last_error is
+    'Error message from the most recent failed attempt to process the notification.';
+comment on column showtime.eventsub_inbox.processed_at is
+    'Time at which the notification was successfully processed, if ever.';
+comment on column showtime.eventsub_inbox.dead_lettered_at is
+    'Time at which we gave up on processing the notification, if ever. A '
+    'dead-lettered notification will not be processed again unless the broadcaster '
+    'explicitly retries it.';
+comment on column showtime.eventsub_inbox.discarded_at is
+    'Time at which the broadcaster chose to discard the dead-lettered notification, if '
+    'ever.';
+
+alter table showtime.eventsub_inbox
+    add constraint eventsub_inbox_message_id_fk
+    foreign key (message_id) references showtime.eventsub_message (message_id);
+
+create index eventsub_inbox_pending_index on showtime.eventsub_inbox (next_attempt_at)
+    where processed_at is null and dead_lettered_at is null;
+
+commit;
@@ -0,0 +1,41 @@
+begin;
+
+create table showtime.eventsub_revocation (
+    message_id           text primary key,
+    subscription_id      text not null,
+    subscription_type    text not null,
+    subscription_version text not null,
+    reason               text not null,
+    condition            jsonb not null,
+    revoked_at           timestamptz not null default now()
+);
+
+comment on table showtime.eventsub_revocation is
+    'Records the fact that Twitch revoked one of our EventSub subscriptions, meaning '
+    'that we will no longer receive notifications for it until the subscription is '
+    'recreated.';
+comment on column showtime.eventsub_revocation.message_id is
+    'ID of the EventSub message that notified us of the revocation.';
+comment on column showtime.eventsub_revocation.subscription_id is
+    'ID of the subscription that was revoked.';
+comment on column showtime.eventsub_revocation.subscription_type is
+    'Type of the subscription that was revoked, e.g. "channel.follow".';
+comment on column showtime.eventsub_revocation.subscription_version is
+    'Version of the subscription that was revoked, e.g. "2".';
+comment on column showtime.eventsub_revocation.reason is
+    'Reason for the revocation, as given by the subscription status: '
+    '"user_removed", "authorization_revoked", "notification_failures_exceeded", or '
+    '"version_removed".';
+comment on column showtime.eventsub_revocation.condition is
+    'JSON-encoded condition of the subscription that was revoked.';
+comment on column showtime.eventsub_revocation.revoked_at is
+    'Time at which we were notified of the revocation.';
+
+alter table showtime.eventsub_revocation
+    add constraint eventsub_revocation_message_id_fk
+    foreign key (message_id) references showtime.eventsub_message (message_id);
+
+create index eventsub_revocation_subscription_type_index
+    on showtime.eventsub_revocation (subscription_type, revoked_at);
+
+commit;
@@ -0,0 +1,8 @@
+begin;
+
+drop trigger notify_on_channel_update_change on showtime.channel_update;
+drop function emit_channel_update_change_notification;
+
+drop table showtime.channel_update;
+
+commit;
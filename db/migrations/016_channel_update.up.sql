@@ -0,0 +1,55 @@
+begin;
+
+create table showtime.channel_update (
+    id            uuid primary key default gen_random_uuid(),
+    broadcast_id  integer,
+    title         text not null,
+    category_id   text not null,
+    category_name text not null,
+    changed_at    timestamptz not null default now()
+);
+
+alter table showtime.channel_update
+    add constraint channel_update_broadcast_id_fk
+    foreign key (broadcast_id) references showtime.broadcast (id);
+
+comment on table showtime.channel_update is
+    'Records the fact that the title and/or category of the Twitch channel was '
+    'changed.';
+comment on column showtime.channel_update.id is
+    'Unique ID for this channel update.';
+comment on column showtime.channel_update.broadcast_id is
+    'ID of the broadcast that was live at the time of the change, if any.';
+comment on column showtime.channel_update.title is
+    'Title of the stream as of this change.';
+comment on column showtime.channel_update.category_id is
+    'ID of the Twitch category (i.e. game) that the stream was in as of this change.';
+comment on column showtime.channel_update.category_name is
+    'Name of the Twitch category (i.e. game) that the stream was in as of this change.';
+comment on column showtime.channel_update.changed_at is
+    'Time at which the change was recorded.';
+
+create index channel_update_broadcast_id_index on showtime.channel_update (broadcast_id);
+create index channel_update_changed_at_index on showtime.channel_update (changed_at);
+
+create function emit_channel_update_change_notification() returns trigger as $trigger$
+begin
+    perform pg_notify('showtime', json_build_object(
+        'type', 'channel_update',
+        'data', json_build_object(
+            'broadcast_id', NEW.broadcast_id,
+            'title', NEW.title,
+            'category_id', NEW.category_id,
+            'category_name', NEW.category_name,
+            'changed_at', NEW.changed_at
+        )
+    )::text);
+    return NEW;
+end;
+$trigger$ language plpgsql;
+
+create trigger notify_on_channel_update_change
+    after insert or update on showtime.channel_update
+    for each row execute procedure emit_channel_update_change_notification();
+
+commit;
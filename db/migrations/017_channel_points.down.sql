@@ -0,0 +1,6 @@
+begin;
+
+drop table showtime.channel_points_redemption;
+drop table showtime.channel_points_reward;
+
+commit;
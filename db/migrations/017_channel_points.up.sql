@@ -0,0 +1,69 @@
+begin;
+
+create table showtime.channel_points_reward (
+    reward_id     text primary key,
+    action        text not null,
+    num_points    integer not null default 0,
+    overlay_event text not null default '',
+    created_at    timestamptz not null default now(),
+    updated_at    timestamptz not null default now()
+);
+
+comment on table showtime.channel_points_reward is
+    'Configures the action that should be taken when a viewer redeems a particular '
+    'Twitch channel points reward.';
+comment on column showtime.channel_points_reward.reward_id is
+    'ID of the custom channel points reward, as assigned by Twitch.';
+comment on column showtime.channel_points_reward.action is
+    'Action to take when the reward is redeemed: "alert" to display an alert, '
+    '"credit" to credit the viewer with fun points, or "overlay" to send an event to '
+    'the overlay.';
+comment on column showtime.channel_points_reward.num_points is
+    'Number of fun points to credit to the viewer, if action is "credit".';
+comment on column showtime.channel_points_reward.overlay_event is
+    'Name of the event to send to the overlay, if action is "overlay".';
+comment on column showtime.channel_points_reward.created_at is
+    'Time at which the action was first configured for this reward.';
+comment on column showtime.channel_points_reward.updated_at is
+    'Time at which the action for this reward was last changed.';
+
+alter table showtime.channel_points_reward
+    add constraint channel_points_reward_action_check
+    check (action in ('alert', 'credit', 'overlay'));
+
+create table showtime.channel_points_redemption (
+    redemption_id  text primary key,
+    reward_id      text not null,
+    twitch_user_id text not null,
+    user_input     text not null,
+    redeemed_at    timestamptz not null,
+    resolved_at    timestamptz,
+    status         text,
+    error_message  text
+);
+
+comment on table showtime.channel_points_redemption is
+    'Records the fact that a viewer redeemed a channel points reward, along with the '
+    'outcome of the action we took in response.';
+comment on column showtime.channel_points_redemption.redemption_id is
+    'ID of the redemption, as assigned by Twitch.';
+comment on column showtime.channel_points_redemption.reward_id is
+    'ID of the reward that was redeemed.';
+comment on column showtime.channel_points_redemption.twitch_user_id is
+    'ID of the viewer who redeemed the reward.';
+comment on column showtime.channel_points_redemption.user_input is
+    'Text entered by the viewer when redeeming the reward, if any.';
+comment on column showtime.channel_points_redemption.redeemed_at is
+    'Time at which the viewer redeemed the reward.';
+comment on column showtime.channel_points_redemption.resolved_at is
+    'Time at which we finished handling the redemption, if we have.';
+comment on column showtime.channel_points_redemption.status is
+    'Outcome of the redemption: "FULFILLED" if the action succeeded, or "CANCELED" if '
+    'it failed and the viewer''s channel points should be refunded.';
+comment on column showtime.channel_points_redemption.error_message is
+    'Details of the error that caused the redemption to be canceled, if any.';
+
+create index channel_points_redemption_twitch_user_id_index
+    on showtime.channel_points_redemption (twitch_user_id);
+
+commit;
@@ -0,0 +1,7 @@
+begin;
+
+drop table showtime.prediction;
+drop table showtime.poll;
+drop table showtime.hype_train;
+
+commit;
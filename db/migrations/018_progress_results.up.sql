@@ -0,0 +1,106 @@
+begin;
+
+create table showtime.hype_train (
+    id                text primary key,
+    broadcast_id      integer,
+    level             integer not null,
+    total             integer not null,
+    top_contributions jsonb not null,
+    started_at        timestamptz not null,
+    ended_at          timestamptz not null
+);
+
+alter table showtime.hype_train
+    add constraint hype_train_broadcast_id_fk
+    foreign key (broadcast_id) references showtime.broadcast (id);
+
+comment on table showtime.hype_train is
+    'Records the final results of a hype train that occurred on the Twitch channel.';
+comment on column showtime.hype_train.id is
+    'ID of the hype train, as assigned by Twitch.';
+comment on column showtime.hype_train.broadcast_id is
+    'ID of the broadcast that was live when the hype train started, if any.';
+comment on column showtime.hype_train.level is
+    'Final level reached by the hype train.';
+comment on column showtime.hype_train.total is
+    'Total number of points contributed to the hype train.';
+comment on column showtime.hype_train.top_contributions is
+    'JSON array describing the top contributors to the hype train.';
+comment on column showtime.hype_train.started_at is
+    'Time at which the hype train started.';
+comment on column showtime.hype_train.ended_at is
+    'Time at which the hype train ended.';
+
+create index hype_train_broadcast_id_index on showtime.hype_train (broadcast_id);
+
+create table showtime.poll (
+    id           text primary key,
+    broadcast_id integer,
+    title        text not null,
+    status       text not null,
+    choices      jsonb not null,
+    started_at   timestamptz not null,
+    ended_at     timestamptz not null
+);
+
+alter table showtime.poll
+    add constraint poll_broadcast_id_fk
+    foreign key (broadcast_id) references showtime.broadcast (id);
+
+comment on table showtime.poll is
+    'Records the final results of a poll that was run on the Twitch channel.';
+comment on column showtime.poll.id is
+    'ID of the poll, as assigned by Twitch.';
+comment on column showtime.poll.broadcast_id is
+    'ID of the broadcast that was live when the poll started, if any.';
+comment on column showtime.poll.title is
+    'Question that was asked in the poll.';
+comment on column showtime.poll.status is
+    'Status of the poll when it ended: "completed" or "terminated".';
+comment on column showtime.poll.choices is
+    'JSON array describing each choice in the poll, along with its final vote count.';
+comment on column showtime.poll.started_at is
+    'Time at which the poll started.';
+comment on column showtime.poll.ended_at is
+    'Time at which the poll ended.';
+
+create index poll_broadcast_id_index on showtime.poll (broadcast_id);
+
+create table showtime.prediction (
+    id                 text primary key,
+    broadcast_id       integer,
+    title              text not null,
+    status             text not null,
+    winning_outcome_id text,
+    outcomes           jsonb not null,
+    started_at         timestamptz not null,
+    ended_at           timestamptz not null
+);
+
+alter table showtime.prediction
+    add constraint prediction_broadcast_id_fk
+    foreign key (broadcast_id) references showtime.broadcast (id);
+
+comment on table showtime.prediction is
+    'Records the final results of a prediction that was run on the Twitch channel.';
+comment on column showtime.prediction.id is
+    'ID of the prediction, as assigned by Twitch.';
+comment on column showtime.prediction.broadcast_id is
+    'ID of the broadcast that was live when the prediction started, if any.';
+comment on column showtime.prediction.title is
+    'Title of the prediction.';
+comment on column showtime.prediction.status is
+    'Status of the prediction when it ended: "resolved" or "canceled".';
+comment on column showtime.prediction.winning_outcome_id is
+    'ID of the outcome that won, if the prediction was resolved.';
+comment on column showtime.prediction.outcomes is
+    'JSON array describing each outcome of the prediction, along with the final '
+    'number of users and channel points that were wagered on it.';
+comment on column showtime.prediction.started_at is
+    'Time at which the prediction started.';
+comment on column showtime.prediction.ended_at is
+    'Time at which the prediction ended.';
+
+create index prediction_broadcast_id_index on showtime.prediction (broadcast_id);
+
+commit;
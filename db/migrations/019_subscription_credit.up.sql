@@ -0,0 +1,29 @@
+begin;
+
+create table showtime.subscription_credit (
+    message_id     text not null,
+    twitch_user_id text not null,
+    claimed_at     timestamptz not null default now(),
+    credited_at    timestamptz
+);
+
+alter table showtime.subscription_credit
+    add constraint subscription_credit_pk
+    primary key (message_id, twitch_user_id);
+
+comment on table showtime.subscription_credit is
+    'Records the fact that we have credited (or are in the process of crediting) a '
+    'viewer with fun points in response to a subscription-related EventSub '
+    'notification, ensuring that each viewer is only credited once per notification, '
+    'even if handling the notification is retried.';
+comment on column showtime.subscription_credit.message_id is
+    'ID of the EventSub message that prompted the credit.';
+comment on column showtime.subscription_credit.twitch_user_id is
+    'ID of the viewer being credited.';
+comment on column showtime.subscription_credit.claimed_at is
+    'Time at which we first attempted to credit the viewer.';
+comment on column showtime.subscription_credit.credited_at is
+    'Time at which the ledger accepted the credit, or NULL if it has not yet been '
+    'applied.';
+
+commit;
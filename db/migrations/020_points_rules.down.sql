@@ -0,0 +1,5 @@
+begin;
+
+drop table showtime.points_rules;
+
+commit;
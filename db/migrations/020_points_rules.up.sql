@@ -0,0 +1,45 @@
+begin;
+
+create table showtime.points_rules (
+    id         serial primary key,
+    rules      jsonb not null,
+    created_at timestamptz not null default now(),
+    created_by text not null
+);
+
+comment on table showtime.points_rules is
+    'Append-only record of the rules that determine how many fun points viewers are '
+    'awarded in response to Twitch events. The most recently-created row contains the '
+    'rules that are currently in effect; prior rows serve as an audit trail of '
+    'changes.';
+comment on column showtime.points_rules.id is
+    'Serial ID identifying this revision of the rules.';
+comment on column showtime.points_rules.rules is
+    'JSON representation of the rules, as defined by points.Rules: awards per event '
+    'type and tier, bonus multipliers, and the number of bits required for a ghost '
+    'alert.';
+comment on column showtime.points_rules.created_at is
+    'Time at which this revision of the rules was put into effect.';
+comment on column showtime.points_rules.created_by is
+    'Name of the user who made the change.';
+
+insert into showtime.points_rules (rules, created_by) values (
+    '{
+        "awards": [
+            {"eventType": "subscription", "tier": "1000", "basePoints": 600, "multiplier": 1},
+            {"eventType": "subscription", "tier": "2000", "basePoints": 600, "multiplier": 2},
+            {"eventType": "subscription", "tier": "3000", "basePoints": 600, "multiplier": 5},
+            {"eventType": "resubscription", "tier": "1000", "basePoints": 600, "multiplier": 1},
+            {"eventType": "resubscription", "tier": "2000", "basePoints": 600, "multiplier": 2},
+            {"eventType": "resubscription", "tier": "3000", "basePoints": 600, "multiplier": 5},
+            {"eventType": "gift-sub", "tier": "1000", "basePoints": 200, "multiplier": 1},
+            {"eventType": "gift-sub", "tier": "2000", "basePoints": 200, "multiplier": 2},
+            {"eventType": "gift-sub", "tier": "3000", "basePoints": 200, "multiplier": 5}
+        ],
+        "bonuses": [],
+        "ghostAlertBits": 200
+    }',
+    'migration'
+);
+
+commit;
@@ -0,0 +1,56 @@
+begin;
+
+create table showtime.subscription (
+    id                    serial primary key,
+    twitch_user_id        text not null,
+    tier                  text not null,
+    is_gift               boolean not null,
+    gifter_twitch_user_id text,
+    cumulative_months     integer not null default 1,
+    streak_months         integer,
+    started_at            timestamptz not null default now(),
+    ended_at              timestamptz
+);
+
+comment on table showtime.subscription is
+    'Records a period of time during which a viewer was (or still is) subscribed to '
+    'GoldenVCR on Twitch. A viewer has at most one ongoing subscription at a time; '
+    'resubscriptions update the ongoing subscription rather than starting a new one.';
+comment on column showtime.subscription.id is
+    'Serial ID of this subscription record.';
+comment on column showtime.subscription.twitch_user_id is
+    'ID of the subscribed viewer.';
+comment on column showtime.subscription.tier is
+    'Subscription tier, as reported by Twitch: "1000", "2000", or "3000".';
+comment on column showtime.subscription.is_gift is
+    'Whether the subscription was gifted to the viewer by another user.';
+comment on column showtime.subscription.gifter_twitch_user_id is
+    'ID of the user who gifted the subscription, if it was gifted and we were able to '
+    'determine who gifted it. NULL for anonymous gifts.';
+comment on column showtime.subscription.cumulative_months is
+    'Total number of months for which the viewer has been subscribed, as of the most '
+    'recent resubscription message they shared.';
+comment on column showtime.subscription.streak_months is
+    'Number of consecutive months for which the viewer has been subscribed, if they '
+    'chose to share it in a resubscription message.';
+comment on column showtime.subscription.started_at is
+    'Time at which we first recorded this subscription.';
+comment on column showtime.subscription.ended_at is
+    'Time at which the subscription expired, or NULL if it is still ongoing.';
+
+alter table showtime.subscription
+    add constraint subscription_twitch_user_id_fk
+    foreign key (twitch_user_id) references showtime.viewer (twitch_user_id);
+
+alter table showtime.subscription
+    add constraint subscription_gifter_twitch_user_id_fk
+    foreign key (gifter_twitch_user_id) references showtime.viewer (twitch_user_id);
+
+create unique index subscription_ongoing_twitch_user_id_index
+    on showtime.subscription (twitch_user_id)
+    where ended_at is null;
+
+create index subscription_twitch_user_id_started_at_index
+    on showtime.subscription (twitch_user_id, started_at);
+
+commit;
@@ -0,0 +1,12 @@
+begin;
+
+drop index showtime.broadcast_twitch_stream_id_index;
+
+alter table showtime.broadcast
+    drop constraint broadcast_stream_type_check;
+
+alter table showtime.broadcast
+    drop column twitch_stream_id,
+    drop column stream_type;
+
+commit;
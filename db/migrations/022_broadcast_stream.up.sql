@@ -0,0 +1,21 @@
+begin;
+
+alter table showtime.broadcast
+    add column twitch_stream_id text,
+    add column stream_type text not null default 'live';
+
+comment on column showtime.broadcast.twitch_stream_id is
+    'ID of the Twitch stream that started this broadcast, as reported in the '
+    'stream.online event. NULL for broadcasts recorded before this was tracked.';
+comment on column showtime.broadcast.stream_type is
+    'Type of stream that started this broadcast, as reported by Twitch: "live" or '
+    '"premiere". Other stream types (e.g. "rerun") do not start broadcasts.';
+
+alter table showtime.broadcast
+    add constraint broadcast_stream_type_check
+    check (stream_type in ('live', 'playlist', 'watch_party', 'premiere', 'rerun'));
+
+create unique index broadcast_twitch_stream_id_index
+    on showtime.broadcast (twitch_stream_id);
+
+commit;
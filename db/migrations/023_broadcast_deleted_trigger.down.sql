@@ -0,0 +1,7 @@
+begin;
+
+drop trigger notify_on_broadcast_delete on showtime.broadcast;
+
+drop function emit_broadcast_deleted_notification;
+
+commit;
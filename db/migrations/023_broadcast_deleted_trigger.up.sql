@@ -0,0 +1,21 @@
+begin;
+
+create function emit_broadcast_deleted_notification() returns trigger as $trigger$
+begin
+    perform pg_notify('showtime', json_build_object(
+        'type', 'broadcast_deleted',
+        'data', json_build_object(
+            'id', OLD.id,
+            'started_at', OLD.started_at,
+            'ended_at', OLD.ended_at
+        )
+    )::text);
+    return OLD;
+end;
+$trigger$ language plpgsql;
+
+create trigger notify_on_broadcast_delete
+    after delete on showtime.broadcast
+    for each row execute procedure emit_broadcast_deleted_notification();
+
+commit;
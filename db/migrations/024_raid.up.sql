@@ -0,0 +1,59 @@
+begin;
+
+create table showtime.raid (
+    id                  uuid primary key default gen_random_uuid(),
+    eventsub_message_id text,
+    direction           text not null,
+    twitch_user_id      text not null,
+    twitch_login        text not null,
+    twitch_display_name text not null,
+    num_viewers         integer not null,
+    broadcast_id        integer,
+    screening_id        uuid,
+    raided_at           timestamptz not null default now()
+);
+
+alter table showtime.raid
+    add constraint raid_direction_check
+    check (direction in ('incoming', 'outgoing'));
+
+alter table showtime.raid
+    add constraint raid_broadcast_id_fk
+    foreign key (broadcast_id) references showtime.broadcast (id);
+
+alter table showtime.raid
+    add constraint raid_screening_id_fk
+    foreign key (screening_id) references showtime.screening (id);
+
+comment on table showtime.raid is
+    'Records a raid, either from another channel into the GoldenVCR channel, or from '
+    'the GoldenVCR channel into another channel.';
+comment on column showtime.raid.id is
+    'Unique ID for this raid.';
+comment on column showtime.raid.eventsub_message_id is
+    'ID of the EventSub message that notified us of this raid, if any; used to ensure '
+    'that each raid is only recorded once, even if the notification is redelivered.';
+comment on column showtime.raid.direction is
+    'Whether the raid was ''incoming'' (another channel raided us) or ''outgoing'' (we '
+    'raided another channel).';
+comment on column showtime.raid.twitch_user_id is
+    'Twitch user ID of the other broadcaster: the raider, for incoming raids, or the '
+    'channel we raided, for outgoing raids.';
+comment on column showtime.raid.twitch_login is
+    'Twitch login of the other broadcaster, as of the time of the raid.';
+comment on column showtime.raid.twitch_display_name is
+    'Twitch display name of the other broadcaster, as of the time of the raid.';
+comment on column showtime.raid.num_viewers is
+    'Number of viewers who participated in the raid.';
+comment on column showtime.raid.broadcast_id is
+    'ID of the broadcast that was live at the time of the raid, if any.';
+comment on column showtime.raid.screening_id is
+    'ID of the screening that was in progress at the time of the raid, if any.';
+comment on column showtime.raid.raided_at is
+    'Time at which the raid was recorded.';
+
+create unique index raid_eventsub_message_id_index on showtime.raid (eventsub_message_id);
+create index raid_twitch_user_id_index on showtime.raid (twitch_user_id);
+create index raid_broadcast_id_index on showtime.raid (broadcast_id);
+
+commit;
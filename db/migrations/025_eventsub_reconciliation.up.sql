@@ -0,0 +1,28 @@
+begin;
+
+create table showtime.eventsub_reconciliation (
+    id          uuid primary key default gen_random_uuid(),
+    started_at  timestamptz not null,
+    finished_at timestamptz not null,
+    actions     jsonb not null,
+    error       text
+);
+
+comment on table showtime.eventsub_reconciliation is
+    'Records a pass of the background subscription reconciler that had to take action '
+    'to repair our EventSub subscriptions, or that failed. Passes that find all '
+    'required subscriptions healthy are not recorded.';
+comment on column showtime.eventsub_reconciliation.started_at is
+    'Time at which the reconciler began inspecting our subscriptions.';
+comment on column showtime.eventsub_reconciliation.finished_at is
+    'Time at which the reconciler finished.';
+comment on column showtime.eventsub_reconciliation.actions is
+    'JSON array describing each subscription that the reconciler deleted or created, '
+    'including the error that occurred if the operation failed.';
+comment on column showtime.eventsub_reconciliation.error is
+    'Error that prevented the reconciler from determining which subscriptions needed '
+    'to be repaired, if any.';
+
+create index eventsub_reconciliation_started_at_index on showtime.eventsub_reconciliation (started_at);
+
+commit;
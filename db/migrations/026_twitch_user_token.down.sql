@@ -0,0 +1,5 @@
+begin;
+
+drop table showtime.twitch_user_token;
+
+commit;
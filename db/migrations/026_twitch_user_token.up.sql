@@ -0,0 +1,33 @@
+begin;
+
+create table showtime.twitch_user_token (
+    twitch_user_id          text primary key,
+    access_token_encrypted  bytea not null,
+    refresh_token_encrypted bytea not null,
+    scopes                  text[] not null,
+    expires_at              timestamptz not null,
+    created_at              timestamptz not null default now(),
+    updated_at              timestamptz not null default now()
+);
+
+comment on table showtime.twitch_user_token is
+    'Stores the most recent OAuth user access token that a Twitch user (typically the '
+    'broadcaster) has granted to our app, along with the refresh token used to renew '
+    'it before it expires.';
+comment on column showtime.twitch_user_token.twitch_user_id is
+    'ID of the Twitch user who granted the token.';
+comment on column showtime.twitch_user_token.access_token_encrypted is
+    'User access token, encrypted with AES-GCM using the key configured via '
+    'TWITCH_TOKEN_ENCRYPTION_KEY: the random nonce is prepended to the ciphertext.';
+comment on column showtime.twitch_user_token.refresh_token_encrypted is
+    'Refresh token, encrypted in the same manner as access_token_encrypted.';
+comment on column showtime.twitch_user_token.scopes is
+    'Scopes granted by the token.';
+comment on column showtime.twitch_user_token.expires_at is
+    'Time at which the access token will expire, after which it must be refreshed.';
+comment on column showtime.twitch_user_token.created_at is
+    'Time at which the user first granted a token.';
+comment on column showtime.twitch_user_token.updated_at is
+    'Time at which the token was most recently granted or refreshed.';
+
+commit;
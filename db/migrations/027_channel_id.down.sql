@@ -0,0 +1,71 @@
+begin;
+
+create or replace function emit_broadcast_change_notification() returns trigger as $trigger$
+begin
+    perform pg_notify('showtime', json_build_object(
+        'type', 'broadcast',
+        'data', json_build_object(
+            'id', NEW.id,
+            'started_at', NEW.started_at,
+            'ended_at', NEW.ended_at
+        )
+    )::text);
+    return NEW;
+end;
+$trigger$ language plpgsql;
+
+create or replace function emit_broadcast_deleted_notification() returns trigger as $trigger$
+begin
+    perform pg_notify('showtime', json_build_object(
+        'type', 'broadcast_deleted',
+        'data', json_build_object(
+            'id', OLD.id,
+            'started_at', OLD.started_at,
+            'ended_at', OLD.ended_at
+        )
+    )::text);
+    return OLD;
+end;
+$trigger$ language plpgsql;
+
+create or replace function emit_screening_change_notification() returns trigger as $trigger$
+begin
+    perform pg_notify('showtime', json_build_object(
+        'type', 'screening',
+        'data', json_build_object(
+            'broadcast_id', NEW.broadcast_id,
+            'tape_id', NEW.tape_id,
+            'started_at', NEW.started_at,
+            'ended_at', NEW.ended_at
+        )
+    )::text);
+    return NEW;
+end;
+$trigger$ language plpgsql;
+
+create or replace function emit_channel_update_change_notification() returns trigger as $trigger$
+begin
+    perform pg_notify('showtime', json_build_object(
+        'type', 'channel_update',
+        'data', json_build_object(
+            'broadcast_id', NEW.broadcast_id,
+            'title', NEW.title,
+            'category_id', NEW.category_id,
+            'category_name', NEW.category_name,
+            'changed_at', NEW.changed_at
+        )
+    )::text);
+    return NEW;
+end;
+$trigger$ language plpgsql;
+
+drop index showtime.raid_channel_id_index;
+alter table showtime.raid drop column channel_id;
+
+drop index showtime.channel_update_channel_id_changed_at_index;
+alter table showtime.channel_update drop column channel_id;
+
+drop index showtime.broadcast_channel_id_started_at_index;
+alter table showtime.broadcast drop column channel_id;
+
+commit;
@@ -0,0 +1,105 @@
+begin;
+
+-- Broadcasts, channel updates and raids were previously all recorded for the
+-- GoldenVCR channel: scope them by the Twitch user ID of the broadcaster, backfilling
+-- existing records to GoldenVCR (Twitch user ID 953753877)
+alter table showtime.broadcast add column channel_id text;
+update showtime.broadcast set channel_id = '953753877';
+alter table showtime.broadcast alter column channel_id set not null;
+
+comment on column showtime.broadcast.channel_id is
+    'Twitch user ID of the broadcaster whose channel this broadcast took place on.';
+
+create index broadcast_channel_id_started_at_index
+    on showtime.broadcast (channel_id, started_at);
+
+alter table showtime.channel_update add column channel_id text;
+update showtime.channel_update set channel_id = '953753877';
+alter table showtime.channel_update alter column channel_id set not null;
+
+comment on column showtime.channel_update.channel_id is
+    'Twitch user ID of the broadcaster whose channel was updated.';
+
+create index channel_update_channel_id_changed_at_index
+    on showtime.channel_update (channel_id, changed_at);
+
+alter table showtime.raid add column channel_id text;
+update showtime.raid set channel_id = '953753877';
+alter table showtime.raid alter column channel_id set not null;
+
+comment on column showtime.raid.channel_id is
+    'Twitch user ID of the broadcaster on whose behalf the raid was recorded: the '
+    'channel that was raided, for incoming raids, or the channel that raided, for '
+    'outgoing raids.';
+
+create index raid_channel_id_index on showtime.raid (channel_id);
+
+-- Include the channel ID in all change notifications, so that listeners can track
+-- the state of each channel independently
+create or replace function emit_broadcast_change_notification() returns trigger as $trigger$
+begin
+    perform pg_notify('showtime', json_build_object(
+        'type', 'broadcast',
+        'data', json_build_object(
+            'id', NEW.id,
+            'channel_id', NEW.channel_id,
+            'started_at', NEW.started_at,
+            'ended_at', NEW.ended_at
+        )
+    )::text);
+    return NEW;
+end;
+$trigger$ language plpgsql;
+
+create or replace function emit_broadcast_deleted_notification() returns trigger as $trigger$
+begin
+    perform pg_notify('showtime', json_build_object(
+        'type', 'broadcast_deleted',
+        'data', json_build_object(
+            'id', OLD.id,
+            'channel_id', OLD.channel_id,
+            'started_at', OLD.started_at,
+            'ended_at', OLD.ended_at
+        )
+    )::text);
+    return OLD;
+end;
+$trigger$ language plpgsql;
+
+create or replace function emit_screening_change_notification() returns trigger as $trigger$
+begin
+    perform pg_notify('showtime', json_build_object(
+        'type', 'screening',
+        'data', json_build_object(
+            'channel_id', (
+                select broadcast.channel_id from showtime.broadcast
+                where broadcast.id = NEW.broadcast_id
+            ),
+            'broadcast_id', NEW.broadcast_id,
+            'tape_id', NEW.tape_id,
+            'started_at', NEW.started_at,
+            'ended_at', NEW.ended_at
+        )
+    )::text);
+    return NEW;
+end;
+$trigger$ language plpgsql;
+
+create or replace function emit_channel_update_change_notification() returns trigger as $trigger$
+begin
+    perform pg_notify('showtime', json_build_object(
+        'type', 'channel_update',
+        'data', json_build_object(
+            'channel_id', NEW.channel_id,
+            'broadcast_id', NEW.broadcast_id,
+            'title', NEW.title,
+            'category_id', NEW.category_id,
+            'category_name', NEW.category_name,
+            'changed_at', NEW.changed_at
+        )
+    )::text);
+    return NEW;
+end;
+$trigger$ language plpgsql;
+
+commit;
@@ -0,0 +1,7 @@
+begin;
+
+drop index showtime.alert_created_at_index;
+drop index showtime.alert_channel_id_id_index;
+drop table showtime.alert;
+
+commit;
@@ -0,0 +1,30 @@
+begin;
+
+create table showtime.alert (
+    id         bigserial primary key,
+    channel_id text not null,
+    type       text not null,
+    data       jsonb not null,
+    created_at timestamptz not null default now()
+);
+
+comment on table showtime.alert is
+    'Record of an alert that was sent to the stream graphics overlay (via GET /alerts), '
+    'retained for a limited time so that clients which were disconnected when the '
+    'alert was emitted can replay it upon reconnecting.';
+comment on column showtime.alert.id is
+    'Sequential ID of the alert, sent to clients as the SSE event ID: a client that '
+    'reconnects with a Last-Event-ID receives all subsequent alerts.';
+comment on column showtime.alert.channel_id is
+    'Twitch user ID of the broadcaster whose channel the alert was emitted for.';
+comment on column showtime.alert.type is
+    'Type of alert, e.g. ''follow'' or ''raid''.';
+comment on column showtime.alert.data is
+    'JSON-serialized payload of the alert, in the format dictated by type.';
+comment on column showtime.alert.created_at is
+    'Time at which the alert was emitted.';
+
+create index alert_channel_id_id_index on showtime.alert (channel_id, id);
+create index alert_created_at_index on showtime.alert (created_at);
+
+commit;
@@ -0,0 +1,9 @@
+begin;
+
+drop index showtime.alert_moderation_action_alert_moderation_id_index;
+drop table showtime.alert_moderation_action;
+
+drop index showtime.alert_moderation_status_created_at_index;
+drop table showtime.alert_moderation;
+
+commit;
@@ -0,0 +1,91 @@
+begin;
+
+create table showtime.alert_moderation (
+    id            bigserial primary key,
+    channel_id    text not null,
+    type          text not null,
+    data          jsonb not null,
+    original_data jsonb not null,
+    status        text not null default 'pending',
+    created_at    timestamptz not null default now(),
+    decided_at    timestamptz,
+    decided_by    text
+);
+
+alter table showtime.alert_moderation
+    add constraint alert_moderation_status_check
+    check (status in ('pending', 'approved', 'rejected'));
+
+alter table showtime.alert_moderation
+    add constraint alert_moderation_decided_check
+    check ((status = 'pending') = (decided_at is null));
+
+create index alert_moderation_status_created_at_index
+    on showtime.alert_moderation (status, created_at);
+
+comment on table showtime.alert_moderation is
+    'Records an alert that was held for moderation, rather than being sent straight to '
+    'the stream graphics overlay, since its type is listed in ALERT_MODERATION_TYPES. '
+    'The alert is only sent once it''s approved by the broadcaster, or automatically '
+    'after ALERT_MODERATION_TIMEOUT.';
+comment on column showtime.alert_moderation.id is
+    'Sequential ID of the held alert, used to approve, reject or edit it via '
+    '/admin/alerts. Distinct from the ID assigned in showtime.alert once the alert is '
+    'sent.';
+comment on column showtime.alert_moderation.channel_id is
+    'Twitch user ID of the broadcaster whose channel the alert was emitted for.';
+comment on column showtime.alert_moderation.type is
+    'Type of alert, e.g. ''subscribe'' or ''generated-images''.';
+comment on column showtime.alert_moderation.data is
+    'JSON-serialized payload of the alert, including any edits made by the '
+    'broadcaster.';
+comment on column showtime.alert_moderation.original_data is
+    'JSON-serialized payload of the alert as originally emitted, before any edits.';
+comment on column showtime.alert_moderation.status is
+    'Whether the alert is ''pending'' a decision, or has been ''approved'' (and sent) '
+    'or ''rejected'' (and discarded).';
+comment on column showtime.alert_moderation.created_at is
+    'Time at which the alert was emitted and held for moderation.';
+comment on column showtime.alert_moderation.decided_at is
+    'Time at which the alert was approved or rejected, if no longer pending.';
+comment on column showtime.alert_moderation.decided_by is
+    'Display name of the user who approved or rejected the alert, or ''auto'' if it '
+    'was approved automatically after timing out.';
+
+create table showtime.alert_moderation_action (
+    id                  bigserial primary key,
+    alert_moderation_id bigint not null,
+    action              text not null,
+    actor               text not null,
+    data                jsonb not null,
+    acted_at            timestamptz not null default now()
+);
+
+alter table showtime.alert_moderation_action
+    add constraint alert_moderation_action_action_check
+    check (action in ('edit', 'approve', 'reject', 'auto-approve'));
+
+alter table showtime.alert_moderation_action
+    add constraint alert_moderation_action_alert_moderation_id_fk
+    foreign key (alert_moderation_id) references showtime.alert_moderation (id);
+
+create index alert_moderation_action_alert_moderation_id_index
+    on showtime.alert_moderation_action (alert_moderation_id);
+
+comment on table showtime.alert_moderation_action is
+    'Audit trail of every action taken on an alert that was held for moderation.';
+comment on column showtime.alert_moderation_action.id is
+    'Sequential ID of the action.';
+comment on column showtime.alert_moderation_action.alert_moderation_id is
+    'ID of the held alert that was acted upon.';
+comment on column showtime.alert_moderation_action.action is
+    'Type of action: ''edit'', ''approve'', ''reject'', or ''auto-approve''.';
+comment on column showtime.alert_moderation_action.actor is
+    'Display name of the user who took the action, or ''auto'' for auto-approvals.';
+comment on column showtime.alert_moderation_action.data is
+    'JSON-serialized payload of the alert as of the action: i.e. the edited payload, '
+    'for edits, or the payload that was sent or discarded, for decisions.';
+comment on column showtime.alert_moderation_action.acted_at is
+    'Time at which the action was taken.';
+
+commit;
@@ -0,0 +1,6 @@
+begin;
+
+drop index showtime.eventsub_revocation_resolved_at_index;
+alter table showtime.eventsub_revocation drop column resolved_at;
+
+commit;
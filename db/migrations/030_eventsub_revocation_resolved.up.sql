@@ -0,0 +1,26 @@
+begin;
+
+alter table showtime.eventsub_revocation
+    add column resolved_at timestamptz;
+
+comment on column showtime.eventsub_revocation.resolved_at is
+    'Time at which we recreated a subscription with the same type, version, and '
+    'condition as the one that was revoked, or NULL if the revocation has not yet been '
+    'resolved.';
+
+-- Revocations were previously considered resolved once any subscription of the same
+-- type was verified: carry that forward so that existing revocations aren't reported
+-- again
+update showtime.eventsub_revocation set resolved_at = (
+    select min(eventsub_message.received_at)
+    from showtime.eventsub_message
+    where eventsub_message.message_type = 'webhook_callback_verification'
+        and eventsub_message.subscription_type = eventsub_revocation.subscription_type
+        and eventsub_message.received_at > eventsub_revocation.revoked_at
+);
+
+create index eventsub_revocation_resolved_at_index
+    on showtime.eventsub_revocation (revoked_at)
+    where resolved_at is null;
+
+commit;
@@ -0,0 +1,7 @@
+begin;
+
+comment on column showtime.broadcast.twitch_stream_id is
+    'ID of the Twitch stream that started this broadcast, as reported in the '
+    'stream.online event. NULL for broadcasts recorded before this was tracked.';
+
+commit;
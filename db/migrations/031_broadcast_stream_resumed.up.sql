@@ -0,0 +1,8 @@
+begin;
+
+comment on column showtime.broadcast.twitch_stream_id is
+    'ID of the Twitch stream that most recently started or resumed this broadcast, as '
+    'reported in the stream.online event. NULL for broadcasts recorded before this was '
+    'tracked.';
+
+commit;
@@ -0,0 +1,11 @@
+begin;
+
+comment on table showtime.subscription_credit is
+    'Records the fact that we have credited (or are in the process of crediting) a '
+    'viewer with fun points in response to a subscription-related EventSub '
+    'notification, ensuring that each viewer is only credited once per notification, '
+    'even if handling the notification is retried.';
+comment on column showtime.subscription_credit.message_id is
+    'ID of the EventSub message that prompted the credit.';
+
+commit;
@@ -0,0 +1,12 @@
+begin;
+
+comment on table showtime.subscription_credit is
+    'Records the fact that we have credited (or are in the process of crediting) a '
+    'viewer with fun points in response to a subscription-related EventSub '
+    'notification or a channel points redemption, ensuring that each viewer is only '
+    'credited once per notification or redemption, even if handling it is retried.';
+comment on column showtime.subscription_credit.message_id is
+    'ID of the EventSub message, or of the channel points redemption, that prompted '
+    'the credit.';
+
+commit;
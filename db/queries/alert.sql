@@ -0,0 +1,30 @@
+-- name: RecordAlert :one
+insert into showtime.alert (
+    channel_id,
+    type,
+    data,
+    created_at
+) values (
+    sqlc.arg('channel_id'),
+    sqlc.arg('type'),
+    sqlc.arg('data'),
+    now()
+)
+returning alert.id, alert.created_at;
+
+-- name: GetAlertsSince :many
+select
+    alert.id,
+    alert.type,
+    alert.data,
+    alert.created_at
+from showtime.alert
+where alert.channel_id = sqlc.arg('channel_id')
+    and alert.id > sqlc.arg('last_id')
+    and alert.created_at >= sqlc.arg('not_before')
+order by alert.id;
+
+-- name: PurgeAlerts :execrows
+delete from showtime.alert
+where alert.channel_id = sqlc.arg('channel_id')
+    and alert.created_at < sqlc.arg('not_before');
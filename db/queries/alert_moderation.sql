@@ -0,0 +1,143 @@
+-- name: EnqueueModeratedAlert :one
+insert into showtime.alert_moderation (
+    channel_id,
+    type,
+    data,
+    original_data,
+    status,
+    created_at
+) values (
+    sqlc.arg('channel_id'),
+    sqlc.arg('type'),
+    sqlc.arg('data'),
+    sqlc.arg('data'),
+    'pending',
+    now()
+)
+returning alert_moderation.id;
+
+-- name: GetModeratedAlert :one
+select
+    alert_moderation.id,
+    alert_moderation.channel_id,
+    alert_moderation.type,
+    alert_moderation.data,
+    alert_moderation.original_data,
+    alert_moderation.status,
+    alert_moderation.created_at,
+    alert_moderation.decided_at,
+    alert_moderation.decided_by
+from showtime.alert_moderation
+where alert_moderation.id = sqlc.arg('id');
+
+-- name: GetModeratedAlerts :many
+select
+    alert_moderation.id,
+    alert_moderation.channel_id,
+    alert_moderation.type,
+    alert_moderation.data,
+    alert_moderation.original_data,
+    alert_moderation.status,
+    alert_moderation.created_at,
+    alert_moderation.decided_at,
+    alert_moderation.decided_by
+from showtime.alert_moderation
+where alert_moderation.status = sqlc.arg('status')
+order by alert_moderation.id desc
+limit 100;
+
+-- name: GetModeratedAlertActions :many
+select
+    alert_moderation_action.action,
+    alert_moderation_action.actor,
+    alert_moderation_action.data,
+    alert_moderation_action.acted_at
+from showtime.alert_moderation_action
+where alert_moderation_action.alert_moderation_id = sqlc.arg('alert_moderation_id')
+order by alert_moderation_action.id;
+
+-- name: EditModeratedAlert :execrows
+with edited as (
+    update showtime.alert_moderation set data = sqlc.arg('data')
+    where alert_moderation.id = sqlc.arg('id')
+        and alert_moderation.status = 'pending'
+    returning alert_moderation.id
+)
+insert into showtime.alert_moderation_action (
+    alert_moderation_id,
+    action,
+    actor,
+    data,
+    acted_at
+)
+select
+    edited.id,
+    'edit',
+    sqlc.arg('actor'),
+    sqlc.arg('data'),
+    now()
+from edited;
+
+-- name: DecideModeratedAlert :one
+with decided as (
+    update showtime.alert_moderation set
+        status = sqlc.arg('status'),
+        decided_at = now(),
+        decided_by = sqlc.arg('actor')
+    where alert_moderation.id = sqlc.arg('id')
+        and alert_moderation.status = 'pending'
+    returning
+        alert_moderation.id,
+        alert_moderation.channel_id,
+        alert_moderation.type,
+        alert_moderation.data
+), recorded as (
+    insert into showtime.alert_moderation_action (
+        alert_moderation_id,
+        action,
+        actor,
+        data,
+        acted_at
+    )
+    select decided.id, sqlc.arg('action'), sqlc.arg('actor'), decided.data, now()
+    from decided
+)
+select
+    decided.id,
+    decided.channel_id,
+    decided.type,
+    decided.data
+from decided;
+
+-- name: AutoApproveModeratedAlerts :many
+with decided as (
+    update showtime.alert_moderation set
+        status = 'approved',
+        decided_at = now(),
+        decided_by = 'auto'
+    where alert_moderation.channel_id = sqlc.arg('channel_id')
+        and alert_moderation.status = 'pending'
+        and alert_moderation.created_at < sqlc.arg('created_before')
+    returning
+        alert_moderation.id,
+        alert_moderation.channel_id,
+        alert_moderation.type,
+        alert_moderation.data
+), recorded as (
+    insert into showtime.alert_moderation_action (
+        alert_moderation_id,
+        action,
+        actor,
+        data,
+        acted_at
+    )
+    select decided.id, 'auto-approve', 'auto', decided.data, now()
+    from decided
+)
+select
+    decided.id,
+    decided.channel_id,
+    decided.type,
+    decided.data
+from decided
+order by decided.id;
@@ -2,30 +2,113 @@
 select
     broadcast.id,
     broadcast.started_at,
-    broadcast.ended_at
+    broadcast.ended_at,
+    broadcast.twitch_stream_id
 from showtime.broadcast
+where broadcast.channel_id = sqlc.arg('channel_id')
 order by broadcast.started_at desc
 limit 1;
 
 -- name: RecordBroadcastStarted :one
 insert into showtime.broadcast (
-    started_at
+    channel_id,
+    started_at,
+    twitch_stream_id,
+    stream_type
 ) values (
-    now()
+    sqlc.arg('channel_id'),
+    sqlc.arg('started_at'),
+    sqlc.arg('twitch_stream_id'),
+    sqlc.arg('stream_type')
 )
 returning broadcast.id;
 
 -- name: RecordBroadcastResumed :exec
-update showtime.broadcast set ended_at = null
+update showtime.broadcast set
+    ended_at = null,
+    twitch_stream_id = coalesce(sqlc.narg('twitch_stream_id'), broadcast.twitch_stream_id)
 where broadcast.id = sqlc.arg('broadcast_id')
     and broadcast.ended_at is not null;
 
 -- name: RecordBroadcastEnded :exec
 with most_recent_broadcast as (
     select broadcast.id from showtime.broadcast
+    where broadcast.channel_id = sqlc.arg('channel_id')
     order by broadcast.started_at desc
     limit 1
 )
 update showtime.broadcast set ended_at = now()
 where broadcast.id = (select id from most_recent_broadcast)
     and broadcast.ended_at is null;
+
+-- name: GetNextBroadcast :one
+select
+    broadcast.id,
+    broadcast.started_at,
+    broadcast.ended_at,
+    broadcast.vod_url,
+    broadcast.twitch_stream_id,
+    broadcast.stream_type,
+    broadcast.channel_id
+from showtime.broadcast
+join showtime.broadcast as previous_broadcast
+    on previous_broadcast.id = sqlc.arg('broadcast_id')
+where broadcast.channel_id = previous_broadcast.channel_id
+    and broadcast.started_at > previous_broadcast.started_at
+order by broadcast.started_at
+limit 1;
+
+-- name: SetBroadcastEndedAt :exec
+update showtime.broadcast set ended_at = sqlc.narg('ended_at')
+where broadcast.id = sqlc.arg('broadcast_id');
+
+-- name: RecordBroadcastSplit :one
+insert into showtime.broadcast (
+    channel_id,
+    started_at,
+    ended_at,
+    stream_type
+) values (
+    sqlc.arg('channel_id'),
+    sqlc.arg('started_at'),
+    sqlc.narg('ended_at'),
+    sqlc.arg('stream_type')
+)
+returning broadcast.id;
+
+-- name: RecordBroadcastsMerged :exec
+update showtime.broadcast set
+    ended_at = sqlc.narg('ended_at'),
+    vod_url = coalesce(broadcast.vod_url, sqlc.narg('vod_url')),
+    twitch_stream_id = coalesce(broadcast.twitch_stream_id, sqlc.narg('twitch_stream_id'))
+where broadcast.id = sqlc.arg('broadcast_id');
+
+-- name: ReassignBroadcastRecords :exec
+with reassigned_screening as (
+    update showtime.screening set broadcast_id = sqlc.arg('to_broadcast_id')
+    where screening.broadcast_id = sqlc.arg('from_broadcast_id')
+        and (sqlc.narg('since')::timestamptz is null or screening.started_at >= sqlc.narg('since'))
+), reassigned_channel_update as (
+    update showtime.channel_update set broadcast_id = sqlc.arg('to_broadcast_id')
+    where channel_update.broadcast_id = sqlc.arg('from_broadcast_id')
+        and (sqlc.narg('since')::timestamptz is null or channel_update.changed_at >= sqlc.narg('since'))
+), reassigned_hype_train as (
+    update showtime.hype_train set broadcast_id = sqlc.arg('to_broadcast_id')
+    where hype_train.broadcast_id = sqlc.arg('from_broadcast_id')
+        and (sqlc.narg('since')::timestamptz is null or hype_train.started_at >= sqlc.narg('since'))
+), reassigned_poll as (
+    update showtime.poll set broadcast_id = sqlc.arg('to_broadcast_id')
+    where poll.broadcast_id = sqlc.arg('from_broadcast_id')
+        and (sqlc.narg('since')::timestamptz is null or poll.started_at >= sqlc.narg('since'))
+), reassigned_raid as (
+    update showtime.raid set broadcast_id = sqlc.arg('to_broadcast_id')
+    where raid.broadcast_id = sqlc.arg('from_broadcast_id')
+        and (sqlc.narg('since')::timestamptz is null or raid.raided_at >= sqlc.narg('since'))
+)
+update showtime.prediction set broadcast_id = sqlc.arg('to_broadcast_id')
+where prediction.broadcast_id = sqlc.arg('from_broadcast_id')
+    and (sqlc.narg('since')::timestamptz is null or prediction.started_at >= sqlc.narg('since'));
+
+-- name: DeleteBroadcast :exec
+delete from showtime.broadcast
+where broadcast.id = sqlc.arg('broadcast_id');
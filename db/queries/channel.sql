@@ -0,0 +1,60 @@
+-- name: RecordChannelUpdate :exec
+with current_broadcast as (
+    select broadcast.id from showtime.broadcast
+    where broadcast.channel_id = sqlc.arg('channel_id')
+        and broadcast.ended_at is null
+    order by broadcast.started_at desc
+    limit 1
+)
+insert into showtime.channel_update (
+    channel_id,
+    broadcast_id,
+    title,
+    category_id,
+    category_name,
+    changed_at
+) values (
+    sqlc.arg('channel_id'),
+    (select id from current_broadcast),
+    sqlc.arg('title'),
+    sqlc.arg('category_id'),
+    sqlc.arg('category_name'),
+    now()
+);
+
+-- name: GetMostRecentChannelUpdate :one
+select
+    channel_update.title,
+    channel_update.category_id,
+    channel_update.category_name,
+    channel_update.changed_at
+from showtime.channel_update
+where channel_update.channel_id = sqlc.arg('channel_id')
+order by channel_update.changed_at desc
+limit 1;
+
+-- name: GetChannelUpdatesByBroadcastId :many
+select
+    channel_update.title,
+    channel_update.category_id,
+    channel_update.category_name,
+    channel_update.changed_at
+from showtime.channel_update
+join showtime.broadcast
+    on broadcast.id = channel_update.broadcast_id
+where broadcast.id = sqlc.arg('broadcast_id')
+union all (
+    select
+        channel_update.title,
+        channel_update.category_id,
+        channel_update.category_name,
+        channel_update.changed_at
+    from showtime.channel_update
+    join showtime.broadcast
+        on channel_update.channel_id = broadcast.channel_id
+        and channel_update.changed_at < broadcast.started_at
+    where broadcast.id = sqlc.arg('broadcast_id')
+    order by channel_update.changed_at desc
+    limit 1
+)
+order by changed_at;
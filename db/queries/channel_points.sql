@@ -0,0 +1,70 @@
+-- name: GetChannelPointsRewards :many
+select
+    channel_points_reward.reward_id,
+    channel_points_reward.action,
+    channel_points_reward.num_points,
+    channel_points_reward.overlay_event,
+    channel_points_reward.updated_at
+from showtime.channel_points_reward
+order by channel_points_reward.created_at;
+
+-- name: GetChannelPointsReward :one
+select
+    channel_points_reward.reward_id,
+    channel_points_reward.action,
+    channel_points_reward.num_points,
+    channel_points_reward.overlay_event,
+    channel_points_reward.updated_at
+from showtime.channel_points_reward
+where channel_points_reward.reward_id = sqlc.arg('reward_id');
+
+-- name: SetChannelPointsReward :exec
+insert into showtime.channel_points_reward (
+    reward_id,
+    action,
+    num_points,
+    overlay_event,
+    created_at,
+    updated_at
+) values (
+    sqlc.arg('reward_id'),
+    sqlc.arg('action'),
+    sqlc.arg('num_points'),
+    sqlc.arg('overlay_event'),
+    now(),
+    now()
+)
+on conflict (reward_id) do update set
+    action = excluded.action,
+    num_points = excluded.num_points,
+    overlay_event = excluded.overlay_event,
+    updated_at = excluded.updated_at;
+
+-- name: DeleteChannelPointsReward :execresult
+delete from showtime.channel_points_reward
+where channel_points_reward.reward_id = sqlc.arg('reward_id');
+
+-- name: RecordChannelPointsRedemption :one
+insert into showtime.channel_points_redemption (
+    redemption_id,
+    reward_id,
+    twitch_user_id,
+    user_input,
+    redeemed_at
+) values (
+    sqlc.arg('redemption_id'),
+    sqlc.arg('reward_id'),
+    sqlc.arg('twitch_user_id'),
+    sqlc.arg('user_input'),
+    sqlc.arg('redeemed_at')
+)
+on conflict (redemption_id) do update set
+    redemption_id = excluded.redemption_id
+returning channel_points_redemption.resolved_at;
+
+-- name: RecordChannelPointsRedemptionResolved :exec
+update showtime.channel_points_redemption set
+    resolved_at = now(),
+    status = sqlc.arg('status'),
+    error_message = sqlc.narg('error_message')
+where channel_points_redemption.redemption_id = sqlc.arg('redemption_id');
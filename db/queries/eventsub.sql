@@ -0,0 +1,199 @@
+-- name: RecordEventSubMessage :execresult
+insert into showtime.eventsub_message (
+    message_id,
+    message_type,
+    subscription_type,
+    message_timestamp,
+    received_at
+) values (
+    sqlc.arg('message_id'),
+    sqlc.arg('message_type'),
+    sqlc.arg('subscription_type'),
+    sqlc.arg('message_timestamp'),
+    now()
+)
+on conflict (message_id) do nothing;
+
+-- name: EnqueueEventSubNotification :execresult
+with message as (
+    insert into showtime.eventsub_message (
+        message_id,
+        message_type,
+        subscription_type,
+        message_timestamp,
+        received_at
+    ) values (
+        sqlc.arg('message_id'),
+        'notification',
+        sqlc.arg('subscription_type'),
+        sqlc.arg('message_timestamp'),
+        now()
+    )
+    on conflict (message_id) do nothing
+    returning eventsub_message.message_id
+)
+insert into showtime.eventsub_inbox (
+    message_id,
+    subscription,
+    event
+)
+select
+    message.message_id,
+    sqlc.arg('subscription')::jsonb,
+    sqlc.arg('event')::jsonb
+from message;
+
+-- name: ClaimEventSubInboxEntry :one
+update showtime.eventsub_inbox set
+    locked_until = sqlc.arg('locked_until')::timestamptz
+where eventsub_inbox.message_id = (
+    select pending.message_id from showtime.eventsub_inbox as pending
+    where pending.processed_at is null
+        and pending.dead_lettered_at is null
+        and pending.next_attempt_at <= now()
+        and (pending.locked_until is null or pending.locked_until < now())
+    order by pending.next_attempt_at
+    limit 1
+    for update skip locked
+)
+returning
+    eventsub_inbox.message_id,
+    eventsub_inbox.subscription,
+    eventsub_inbox.event,
+    eventsub_inbox.num_attempts;
+
+-- name: RecordEventSubInboxSuccess :exec
+update showtime.eventsub_inbox set
+    num_attempts = eventsub_inbox.num_attempts + 1,
+    locked_until = null,
+    last_error = null,
+    processed_at = now()
+where eventsub_inbox.message_id = sqlc.arg('message_id');
+
+-- name: RecordEventSubInboxFailure :exec
+update showtime.eventsub_inbox set
+    num_attempts = eventsub_inbox.num_attempts + 1,
+    locked_until = null,
+    last_error = sqlc.arg('error_message')::text,
+    next_attempt_at = sqlc.arg('next_attempt_at')::timestamptz
+where eventsub_inbox.message_id = sqlc.arg('message_id');
+
+-- name: RecordEventSubInboxDeadLettered :exec
+update showtime.eventsub_inbox set
+    num_attempts = eventsub_inbox.num_attempts + 1,
+    locked_until = null,
+    last_error = sqlc.arg('error_message')::text,
+    dead_lettered_at = now()
+where eventsub_inbox.message_id = sqlc.arg('message_id');
+
+-- name: GetDeadLetteredEventSubInboxEntries :many
+select
+    eventsub_inbox.message_id,
+    eventsub_inbox.subscription,
+    eventsub_inbox.event,
+    eventsub_inbox.created_at,
+    eventsub_inbox.num_attempts,
+    eventsub_inbox.last_error,
+    eventsub_inbox.dead_lettered_at
+from showtime.eventsub_inbox
+where eventsub_inbox.dead_lettered_at is not null
+    and eventsub_inbox.discarded_at is null
+order by eventsub_inbox.created_at;
+
+-- name: RetryDeadLetteredEventSubInboxEntry :execresult
+update showtime.eventsub_inbox set
+    num_attempts = 0,
+    next_attempt_at = now(),
+    dead_lettered_at = null
+where eventsub_inbox.message_id = sqlc.arg('message_id')
+    and eventsub_inbox.dead_lettered_at is not null
+    and eventsub_inbox.discarded_at is null;
+
+-- name: DiscardDeadLetteredEventSubInboxEntry :execresult
+update showtime.eventsub_inbox set
+    discarded_at = now()
+where eventsub_inbox.message_id = sqlc.arg('message_id')
+    and eventsub_inbox.dead_lettered_at is not null
+    and eventsub_inbox.discarded_at is null;
+
+-- name: RecordEventSubRevocation :execresult
+with message as (
+    insert into showtime.eventsub_message (
+        message_id,
+        message_type,
+        subscription_type,
+        message_timestamp,
+        received_at
+    ) values (
+        sqlc.arg('message_id'),
+        'revocation',
+        sqlc.arg('subscription_type'),
+        sqlc.arg('message_timestamp'),
+        now()
+    )
+    on conflict (message_id) do nothing
+    returning eventsub_message.message_id
+)
+insert into showtime.eventsub_revocation (
+    message_id,
+    subscription_id,
+    subscription_type,
+    subscription_version,
+    reason,
+    condition,
+    revoked_at
+)
+select
+    message.message_id,
+    sqlc.arg('subscription_id'),
+    sqlc.arg('subscription_type'),
+    sqlc.arg('subscription_version'),
+    sqlc.arg('reason'),
+    sqlc.arg('condition')::jsonb,
+    now()
+from message;
+
+-- name: GetUnresolvedEventSubRevocations :many
+select
+    eventsub_revocation.subscription_id,
+    eventsub_revocation.subscription_type,
+    eventsub_revocation.subscription_version,
+    eventsub_revocation.reason,
+    eventsub_revocation.revoked_at
+from showtime.eventsub_revocation
+where eventsub_revocation.resolved_at is null
+order by eventsub_revocation.revoked_at;
+
+-- name: ResolveEventSubRevocations :execrows
+update showtime.eventsub_revocation set resolved_at = now()
+where eventsub_revocation.resolved_at is null
+    and eventsub_revocation.subscription_type = sqlc.arg('subscription_type')
+    and eventsub_revocation.subscription_version = sqlc.arg('subscription_version')
+    and eventsub_revocation.condition = sqlc.arg('condition')::jsonb;
+
+-- name: GetEventSubNotificationsForReplay :many
+select
+    eventsub_inbox.message_id,
+    eventsub_message.subscription_type,
+    eventsub_message.message_timestamp,
+    eventsub_inbox.subscription,
+    eventsub_inbox.event
+from showtime.eventsub_inbox
+join showtime.eventsub_message
+    on eventsub_message.message_id = eventsub_inbox.message_id
+where eventsub_message.message_timestamp >= sqlc.arg('since')
+    and eventsub_message.message_timestamp < sqlc.arg('until')
+order by eventsub_message.message_timestamp, eventsub_inbox.message_id;
+
+-- name: RecordEventSubReconciliation :exec
+insert into showtime.eventsub_reconciliation (
+    started_at,
+    finished_at,
+    actions,
+    error
+) values (
+    sqlc.arg('started_at'),
+    sqlc.arg('finished_at'),
+    sqlc.arg('actions')::jsonb,
+    sqlc.narg('error')
+);
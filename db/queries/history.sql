@@ -3,10 +3,12 @@ select
     broadcast.id,
     broadcast.started_at,
     broadcast.vod_url,
+    broadcast.twitch_stream_id,
     array_agg(screening.tape_id order by screening.started_at)::integer[] as tape_ids
 from showtime.broadcast
 join showtime.screening
     on screening.broadcast_id = broadcast.id
+where broadcast.channel_id = sqlc.arg('channel_id')
 group by broadcast.id
 order by broadcast.started_at;
 
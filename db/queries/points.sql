@@ -0,0 +1,24 @@
+-- name: GetCurrentPointsRules :one
+select points_rules.rules
+from showtime.points_rules
+order by points_rules.id desc
+limit 1;
+
+-- name: GetPointsRulesHistory :many
+select
+    points_rules.id,
+    points_rules.rules,
+    points_rules.created_at,
+    points_rules.created_by
+from showtime.points_rules
+order by points_rules.id desc;
+
+-- name: RecordPointsRules :one
+insert into showtime.points_rules (
+    rules,
+    created_by
+) values (
+    sqlc.arg('rules'),
+    sqlc.arg('created_by')
+)
+returning points_rules.id;
@@ -0,0 +1,138 @@
+-- name: RecordHypeTrainResult :exec
+with containing_broadcast as (
+    select broadcast.id from showtime.broadcast
+    where broadcast.channel_id = sqlc.arg('channel_id')
+        and broadcast.started_at <= sqlc.arg('started_at')
+        and (broadcast.ended_at is null or broadcast.ended_at >= sqlc.arg('started_at'))
+    order by broadcast.started_at desc
+    limit 1
+)
+insert into showtime.hype_train (
+    id,
+    broadcast_id,
+    level,
+    total,
+    top_contributions,
+    started_at,
+    ended_at
+) values (
+    sqlc.arg('id'),
+    (select id from containing_broadcast),
+    sqlc.arg('level'),
+    sqlc.arg('total'),
+    sqlc.arg('top_contributions'),
+    sqlc.arg('started_at'),
+    sqlc.arg('ended_at')
+)
+on conflict (id) do update set
+    level = excluded.level,
+    total = excluded.total,
+    top_contributions = excluded.top_contributions,
+    ended_at = excluded.ended_at;
+
+-- name: RecordPollResult :exec
+with containing_broadcast as (
+    select broadcast.id from showtime.broadcast
+    where broadcast.channel_id = sqlc.arg('channel_id')
+        and broadcast.started_at <= sqlc.arg('started_at')
+        and (broadcast.ended_at is null or broadcast.ended_at >= sqlc.arg('started_at'))
+    order by broadcast.started_at desc
+    limit 1
+)
+insert into showtime.poll (
+    id,
+    broadcast_id,
+    title,
+    status,
+    choices,
+    started_at,
+    ended_at
+) values (
+    sqlc.arg('id'),
+    (select id from containing_broadcast),
+    sqlc.arg('title'),
+    sqlc.arg('status'),
+    sqlc.arg('choices'),
+    sqlc.arg('started_at'),
+    sqlc.arg('ended_at')
+)
+on conflict (id) do update set
+    title = excluded.title,
+    status = excluded.status,
+    choices = excluded.choices,
+    ended_at = excluded.ended_at;
+
+-- name: RecordPredictionResult :exec
+with containing_broadcast as (
+    select broadcast.id from showtime.broadcast
+    where broadcast.channel_id = sqlc.arg('channel_id')
+        and broadcast.started_at <= sqlc.arg('started_at')
+        and (broadcast.ended_at is null or broadcast.ended_at >= sqlc.arg('started_at'))
+    order by broadcast.started_at desc
+    limit 1
+)
+insert into showtime.prediction (
+    id,
+    broadcast_id,
+    title,
+    status,
+    winning_outcome_id,
+    outcomes,
+    started_at,
+    ended_at
+) values (
+    sqlc.arg('id'),
+    (select id from containing_broadcast),
+    sqlc.arg('title'),
+    sqlc.arg('status'),
+    sqlc.narg('winning_outcome_id'),
+    sqlc.arg('outcomes'),
+    sqlc.arg('started_at'),
+    sqlc.arg('ended_at')
+)
+on conflict (id) do update set
+    title = excluded.title,
+    status = excluded.status,
+    winning_outcome_id = excluded.winning_outcome_id,
+    outcomes = excluded.outcomes,
+    ended_at = excluded.ended_at;
+
+-- name: GetHypeTrainsByBroadcastId :many
+select
+    hype_train.level,
+    hype_train.total,
+    hype_train.top_contributions,
+    hype_train.started_at,
+    hype_train.ended_at
+from showtime.hype_train
+join showtime.broadcast
+    on broadcast.id = hype_train.broadcast_id
+where broadcast.id = sqlc.arg('broadcast_id')
+order by hype_train.started_at;
+
+-- name: GetPollsByBroadcastId :many
+select
+    poll.title,
+    poll.status,
+    poll.choices,
+    poll.started_at,
+    poll.ended_at
+from showtime.poll
+join showtime.broadcast
+    on broadcast.id = poll.broadcast_id
+where broadcast.id = sqlc.arg('broadcast_id')
+order by poll.started_at;
+
+-- name: GetPredictionsByBroadcastId :many
+select
+    prediction.title,
+    prediction.status,
+    prediction.winning_outcome_id,
+    prediction.outcomes,
+    prediction.started_at,
+    prediction.ended_at
+from showtime.prediction
+join showtime.broadcast
+    on broadcast.id = prediction.broadcast_id
+where broadcast.id = sqlc.arg('broadcast_id')
+order by prediction.started_at;
@@ -0,0 +1,69 @@
+-- name: RecordRaid :exec
+with current_broadcast as (
+    select broadcast.id from showtime.broadcast
+    where broadcast.channel_id = sqlc.arg('channel_id')
+        and broadcast.ended_at is null
+    order by broadcast.started_at desc
+    limit 1
+),
+current_screening as (
+    select screening.id from showtime.screening
+    where screening.broadcast_id = (select id from current_broadcast)
+        and screening.ended_at is null
+    order by screening.started_at desc
+    limit 1
+)
+insert into showtime.raid (
+    channel_id,
+    eventsub_message_id,
+    direction,
+    twitch_user_id,
+    twitch_login,
+    twitch_display_name,
+    num_viewers,
+    broadcast_id,
+    screening_id,
+    raided_at
+) values (
+    sqlc.arg('channel_id'),
+    sqlc.narg('eventsub_message_id'),
+    sqlc.arg('direction'),
+    sqlc.arg('twitch_user_id'),
+    sqlc.arg('twitch_login'),
+    sqlc.arg('twitch_display_name'),
+    sqlc.arg('num_viewers'),
+    (select id from current_broadcast),
+    (select id from current_screening),
+    now()
+)
+on conflict (eventsub_message_id) do nothing;
+
+-- name: GetRaidHistory :many
+select
+    raid.direction,
+    raid.twitch_user_id,
+    raid.twitch_login,
+    raid.twitch_display_name,
+    raid.num_viewers,
+    raid.broadcast_id,
+    screening.tape_id,
+    raid.raided_at
+from showtime.raid
+left join showtime.screening
+    on screening.id = raid.screening_id
+where raid.channel_id = sqlc.arg('channel_id')
+order by raid.raided_at desc;
+
+-- name: GetRaidSummaryByBroadcaster :many
+select
+    raid.twitch_user_id,
+    (array_agg(raid.twitch_display_name order by raid.raided_at desc))[1]::text as twitch_display_name,
+    count(*) filter (where raid.direction = 'incoming') as num_incoming_raids,
+    coalesce(sum(raid.num_viewers) filter (where raid.direction = 'incoming'), 0)::integer as num_incoming_viewers,
+    count(*) filter (where raid.direction = 'outgoing') as num_outgoing_raids,
+    coalesce(sum(raid.num_viewers) filter (where raid.direction = 'outgoing'), 0)::integer as num_outgoing_viewers,
+    max(raid.raided_at)::timestamptz as last_raided_at
+from showtime.raid
+where raid.channel_id = sqlc.arg('channel_id')
+group by raid.twitch_user_id
+order by last_raided_at desc;
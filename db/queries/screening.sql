@@ -33,5 +33,56 @@ select
 from showtime.broadcast
 join showtime.screening
     on screening.broadcast_id = broadcast.id
+where broadcast.channel_id = sqlc.arg('channel_id')
 order by screening.started_at desc, broadcast.started_at desc
 limit 1;
+
+-- name: GetScreeningInProgressAt :one
+select
+    screening.id::uuid as id,
+    screening.tape_id,
+    screening.started_at,
+    screening.ended_at
+from showtime.screening
+where screening.broadcast_id = sqlc.arg('broadcast_id')
+    and screening.started_at < sqlc.arg('at')
+    and (screening.ended_at is null or screening.ended_at > sqlc.arg('at'))
+order by screening.started_at desc
+limit 1;
+
+-- name: SetScreeningEndedAt :exec
+update showtime.screening set ended_at = sqlc.narg('ended_at')
+where screening.id = sqlc.arg('screening_id');
+
+-- name: RecordScreeningSplit :one
+insert into showtime.screening (
+    broadcast_id,
+    tape_id,
+    started_at,
+    ended_at
+) values (
+    sqlc.arg('broadcast_id'),
+    sqlc.arg('tape_id'),
+    sqlc.arg('started_at'),
+    sqlc.narg('ended_at')
+)
+returning screening.id::uuid as id;
+
+-- name: ReassignScreeningImageRequests :exec
+update showtime.image_request set screening_id = sqlc.arg('to_screening_id')
+where image_request.screening_id = sqlc.arg('from_screening_id')
+    and image_request.created_at >= sqlc.arg('since');
+
+-- name: ReassignScreeningRaids :exec
+update showtime.raid set screening_id = sqlc.arg('to_screening_id')
+where raid.screening_id = sqlc.arg('from_screening_id')
+    and raid.raided_at >= sqlc.arg('since');
+
+-- name: RenotifyMostRecentScreening :exec
+update showtime.screening set ended_at = screening.ended_at
+where screening.id = (
+    select most_recent_screening.id from showtime.screening as most_recent_screening
+    where most_recent_screening.broadcast_id = sqlc.arg('broadcast_id')
+    order by most_recent_screening.started_at desc
+    limit 1
+);
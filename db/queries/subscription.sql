@@ -0,0 +1,80 @@
+-- name: ClaimSubscriptionCredit :one
+insert into showtime.subscription_credit (
+    message_id,
+    twitch_user_id
+) values (
+    sqlc.arg('message_id'),
+    sqlc.arg('twitch_user_id')
+)
+on conflict (message_id, twitch_user_id) do update set
+    message_id = excluded.message_id
+returning subscription_credit.credited_at;
+
+-- name: RecordSubscriptionCreditApplied :exec
+update showtime.subscription_credit set
+    credited_at = now()
+where subscription_credit.message_id = sqlc.arg('message_id')
+    and subscription_credit.twitch_user_id = sqlc.arg('twitch_user_id');
+
+-- name: RecordSubscriptionStarted :exec
+insert into showtime.subscription (
+    twitch_user_id,
+    tier,
+    is_gift
+) values (
+    sqlc.arg('twitch_user_id'),
+    sqlc.arg('tier'),
+    sqlc.arg('is_gift')
+)
+on conflict (twitch_user_id) where ended_at is null do update set
+    tier = excluded.tier,
+    is_gift = excluded.is_gift;
+
+-- name: RecordSubscriptionMessage :exec
+insert into showtime.subscription (
+    twitch_user_id,
+    tier,
+    is_gift,
+    cumulative_months,
+    streak_months
+) values (
+    sqlc.arg('twitch_user_id'),
+    sqlc.arg('tier'),
+    false,
+    sqlc.arg('cumulative_months'),
+    sqlc.narg('streak_months')
+)
+on conflict (twitch_user_id) where ended_at is null do update set
+    tier = excluded.tier,
+    cumulative_months = excluded.cumulative_months,
+    streak_months = excluded.streak_months;
+
+-- name: RecordSubscriptionEnded :exec
+update showtime.subscription set
+    ended_at = now()
+where subscription.twitch_user_id = sqlc.arg('twitch_user_id')
+    and subscription.ended_at is null;
+
+-- name: RecordSubscriptionGifter :exec
+update showtime.subscription set
+    gifter_twitch_user_id = sqlc.arg('gifter_twitch_user_id')
+where subscription.twitch_user_id = any(sqlc.arg('twitch_user_ids')::text[])
+    and subscription.ended_at is null
+    and subscription.is_gift
+    and subscription.gifter_twitch_user_id is null;
+
+-- name: GetViewerSubscriptions :many
+select
+    subscription.tier,
+    subscription.is_gift,
+    subscription.gifter_twitch_user_id,
+    gifter.twitch_display_name as gifter_twitch_display_name,
+    subscription.cumulative_months,
+    subscription.streak_months,
+    subscription.started_at,
+    subscription.ended_at
+from showtime.subscription
+left join showtime.viewer as gifter
+    on gifter.twitch_user_id = subscription.gifter_twitch_user_id
+where subscription.twitch_user_id = sqlc.arg('twitch_user_id')
+order by subscription.started_at desc;
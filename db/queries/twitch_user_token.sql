@@ -0,0 +1,33 @@
+-- name: GetTwitchUserToken :one
+select
+    twitch_user_token.access_token_encrypted,
+    twitch_user_token.refresh_token_encrypted,
+    twitch_user_token.scopes,
+    twitch_user_token.expires_at
+from showtime.twitch_user_token
+where twitch_user_token.twitch_user_id = sqlc.arg('twitch_user_id');
+
+-- name: StoreTwitchUserToken :exec
+insert into showtime.twitch_user_token (
+    twitch_user_id,
+    access_token_encrypted,
+    refresh_token_encrypted,
+    scopes,
+    expires_at,
+    created_at,
+    updated_at
+) values (
+    sqlc.arg('twitch_user_id'),
+    sqlc.arg('access_token_encrypted'),
+    sqlc.arg('refresh_token_encrypted'),
+    sqlc.arg('scopes')::text[],
+    sqlc.arg('expires_at'),
+    now(),
+    now()
+)
+on conflict (twitch_user_id) do update set
+    access_token_encrypted = excluded.access_token_encrypted,
+    refresh_token_encrypted = excluded.refresh_token_encrypted,
+    scopes = excluded.scopes,
+    expires_at = excluded.expires_at,
+    updated_at = excluded.updated_at;
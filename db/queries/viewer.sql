@@ -36,3 +36,12 @@ insert into showtime.viewer (
 on conflict (twitch_user_id) do update set
     twitch_display_name = excluded.twitch_display_name,
     first_subscribed_at = coalesce(viewer.first_subscribed_at, excluded.first_subscribed_at);
+
+-- name: GetViewer :one
+select
+    viewer.twitch_user_id,
+    viewer.twitch_display_name,
+    viewer.first_followed_at,
+    viewer.first_subscribed_at
+from showtime.viewer
+where viewer.twitch_user_id = sqlc.arg('twitch_user_id');
@@ -47,6 +47,13 @@ var RequiredSubscriptions = []events.RequiredSubscription{
 			ToBroadcasterUserID: "{{.ChannelUserId}}",
 		},
 	},
+	{
+		Type:    helix.EventSubTypeChannelRaid,
+		Version: "1",
+		TemplatedCondition: helix.EventSubCondition{
+			FromBroadcasterUserID: "{{.ChannelUserId}}",
+		},
+	},
 	{
 		Type:    helix.EventSubTypeChannelCheer,
 		Version: "1",
@@ -97,4 +104,114 @@ var RequiredSubscriptions = []events.RequiredSubscription{
 			"channel:read:subscriptions",
 		},
 	},
+	{
+		Type:    helix.EventSubTypeChannelPointsCustomRewardRedemptionAdd,
+		Version: "1",
+		TemplatedCondition: helix.EventSubCondition{
+			BroadcasterUserID: "{{.ChannelUserId}}",
+		},
+		RequiredScopes: []string{
+			"channel:manage:redemptions",
+		},
+	},
+	{
+		Type:    helix.EventSubTypeHypeTrainBegin,
+		Version: "1",
+		TemplatedCondition: helix.EventSubCondition{
+			BroadcasterUserID: "{{.ChannelUserId}}",
+		},
+		RequiredScopes: []string{
+			"channel:read:hype_train",
+		},
+	},
+	{
+		Type:    helix.EventSubTypeHypeTrainProgress,
+		Version: "1",
+		TemplatedCondition: helix.EventSubCondition{
+			BroadcasterUserID: "{{.ChannelUserId}}",
+		},
+		RequiredScopes: []string{
+			"channel:read:hype_train",
+		},
+	},
+	{
+		Type:    helix.EventSubTypeHypeTrainEnd,
+		Version: "1",
+		TemplatedCondition: helix.EventSubCondition{
+			BroadcasterUserID: "{{.ChannelUserId}}",
+		},
+		RequiredScopes: []string{
+			"channel:read:hype_train",
+		},
+	},
+	{
+		Type:    helix.EventSubTypeChannelPollBegin,
+		Version: "1",
+		TemplatedCondition: helix.EventSubCondition{
+			BroadcasterUserID: "{{.ChannelUserId}}",
+		},
+		RequiredScopes: []string{
+			"channel:read:polls",
+		},
+	},
+	{
+		Type:    helix.EventSubTypeChannelPollProgress,
+		Version: "1",
+		TemplatedCondition: helix.EventSubCondition{
+			BroadcasterUserID: "{{.ChannelUserId}}",
+		},
+		RequiredScopes: []string{
+			"channel:read:polls",
+		},
+	},
+	{
+		Type:    helix.EventSubTypeChannelPollEnd,
+		Version: "1",
+		TemplatedCondition: helix.EventSubCondition{
+			BroadcasterUserID: "{{.ChannelUserId}}",
+		},
+		RequiredScopes: []string{
+			"channel:read:polls",
+		},
+	},
+	{
+		Type:    helix.EventSubTypeChannelPredictionBegin,
+		Version: "1",
+		TemplatedCondition: helix.EventSubCondition{
+			BroadcasterUserID: "{{.ChannelUserId}}",
+		},
+		RequiredScopes: []string{
+			"channel:read:predictions",
+		},
+	},
+	{
+		Type:    helix.EventSubTypeChannelPredictionProgress,
+		Version: "1",
+		TemplatedCondition: helix.EventSubCondition{
+			BroadcasterUserID: "{{.ChannelUserId}}",
+		},
+		RequiredScopes: []string{
+			"channel:read:predictions",
+		},
+	},
+	{
+		Type:    helix.EventSubTypeChannelPredictionLock,
+		Version: "1",
+		TemplatedCondition: helix.EventSubCondition{
+			BroadcasterUserID: "{{.ChannelUserId}}",
+		},
+		RequiredScopes: []string{
+			"channel:read:predictions",
+		},
+	},
+	{
+		Type:    helix.EventSubTypeChannelPredictionEnd,
+		Version: "1",
+		TemplatedCondition: helix.EventSubCondition{
+			BroadcasterUserID: "{{.ChannelUserId}}",
+		},
+		RequiredScopes: []string{
+			"channel:read:predictions",
+		},
+	},
 }
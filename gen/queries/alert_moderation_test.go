@@ -0,0 +1,100 @@
+package queries_test
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/golden-vcr/server-common/querytest"
+	"github.com/golden-vcr/showtime/gen/queries"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_ModeratedAlerts(t *testing.T) {
+	tx := querytest.PrepareTx(t)
+	q := queries.New(tx)
+
+	// Enqueue two alerts: both should be pending
+	firstId, err := q.EnqueueModeratedAlert(context.Background(), queries.EnqueueModeratedAlertParams{
+		ChannelID: "953753877",
+		Type:      "subscribe",
+		Data:      json.RawMessage(`{"username":"alice","message":"hello"}`),
+	})
+	assert.NoError(t, err)
+	secondId, err := q.EnqueueModeratedAlert(context.Background(), queries.EnqueueModeratedAlertParams{
+		ChannelID: "953753877",
+		Type:      "subscribe",
+		Data:      json.RawMessage(`{"username":"bob","message":"rude"}`),
+	})
+	assert.NoError(t, err)
+	pending, err := q.GetModeratedAlerts(context.Background(), "pending")
+	assert.NoError(t, err)
+	assert.Len(t, pending, 2)
+
+	// Editing a pending alert should update its data, preserving the original
+	numRows, err := q.EditModeratedAlert(context.Background(), queries.EditModeratedAlertParams{
+		Data:  json.RawMessage(`{"username":"bob","message":"nice"}`),
+		ID:    secondId,
+		Actor: "GoldenVCR",
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), numRows)
+	row, err := q.GetModeratedAlert(context.Background(), secondId)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"username":"bob","message":"nice"}`, string(row.Data))
+	assert.JSONEq(t, `{"username":"bob","message":"rude"}`, string(row.OriginalData))
+	assert.Equal(t, "pending", row.Status)
+
+	// Approving a pending alert should return its current data, and it can only be
+	// decided once
+	decided, err := q.DecideModeratedAlert(context.Background(), queries.DecideModeratedAlertParams{
+		Status: "approved",
+		Actor:  "GoldenVCR",
+		ID:     secondId,
+		Action: "approve",
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "953753877", decided.ChannelID)
+	assert.JSONEq(t, `{"username":"bob","message":"nice"}`, string(decided.Data))
+	_, err = q.DecideModeratedAlert(context.Background(), queries.DecideModeratedAlertParams{
+		Status: "rejected",
+		Actor:  "GoldenVCR",
+		ID:     secondId,
+		Action: "reject",
+	})
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+	numRows, err = q.EditModeratedAlert(context.Background(), queries.EditModeratedAlertParams{
+		Data:  json.RawMessage(`{"username":"bob","message":"too late"}`),
+		ID:    secondId,
+		Actor: "GoldenVCR",
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), numRows)
+
+	// Pending alerts older than the cutoff should be auto-approved
+	approved, err := q.AutoApproveModeratedAlerts(context.Background(), queries.AutoApproveModeratedAlertsParams{
+		ChannelID:     "953753877",
+		CreatedBefore: time.Now().Add(time.Minute),
+	})
+	assert.NoError(t, err)
+	assert.Len(t, approved, 1)
+	assert.Equal(t, firstId, approved[0].ID)
+	row, err = q.GetModeratedAlert(context.Background(), firstId)
+	assert.NoError(t, err)
+	assert.Equal(t, "approved", row.Status)
+	assert.Equal(t, "auto", row.DecidedBy.String)
+
+	// Every action should be recorded for auditing
+	actions, err := q.GetModeratedAlertActions(context.Background(), secondId)
+	assert.NoError(t, err)
+	assert.Len(t, actions, 2)
+	assert.Equal(t, "edit", actions[0].Action)
+	assert.Equal(t, "approve", actions[1].Action)
+	actions, err = q.GetModeratedAlertActions(context.Background(), firstId)
+	assert.NoError(t, err)
+	assert.Len(t, actions, 1)
+	assert.Equal(t, "auto-approve", actions[0].Action)
+	assert.Equal(t, "auto", actions[0].Actor)
+}
@@ -0,0 +1,80 @@
+package queries_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/golden-vcr/server-common/querytest"
+	"github.com/golden-vcr/showtime/gen/queries"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Alerts(t *testing.T) {
+	tx := querytest.PrepareTx(t)
+	q := queries.New(tx)
+
+	// Each recorded alert should be assigned an increasing ID
+	first, err := q.RecordAlert(context.Background(), queries.RecordAlertParams{
+		ChannelID: "953753877",
+		Type:      "follow",
+		Data:      json.RawMessage(`{"username":"alice"}`),
+	})
+	assert.NoError(t, err)
+	second, err := q.RecordAlert(context.Background(), queries.RecordAlertParams{
+		ChannelID: "953753877",
+		Type:      "raid",
+		Data:      json.RawMessage(`{"username":"bob","numViewers":5}`),
+	})
+	assert.NoError(t, err)
+	assert.Greater(t, second.ID, first.ID)
+	_, err = q.RecordAlert(context.Background(), queries.RecordAlertParams{
+		ChannelID: "1234",
+		Type:      "follow",
+		Data:      json.RawMessage(`{"username":"carol"}`),
+	})
+	assert.NoError(t, err)
+
+	// Alerts after the given ID should be returned in order, for the requested channel
+	// only
+	rows, err := q.GetAlertsSince(context.Background(), queries.GetAlertsSinceParams{
+		ChannelID: "953753877",
+		LastID:    0,
+		NotBefore: time.Now().Add(-time.Hour),
+	})
+	assert.NoError(t, err)
+	assert.Len(t, rows, 2)
+	assert.Equal(t, first.ID, rows[0].ID)
+	assert.Equal(t, "follow", rows[0].Type)
+	assert.JSONEq(t, `{"username":"alice"}`, string(rows[0].Data))
+	assert.Equal(t, second.ID, rows[1].ID)
+
+	rows, err = q.GetAlertsSince(context.Background(), queries.GetAlertsSinceParams{
+		ChannelID: "953753877",
+		LastID:    first.ID,
+		NotBefore: time.Now().Add(-time.Hour),
+	})
+	assert.NoError(t, err)
+	assert.Len(t, rows, 1)
+	assert.Equal(t, second.ID, rows[0].ID)
+
+	// Alerts older than the retention window should not be returned, and should be
+	// purged
+	_, err = tx.Exec(`UPDATE showtime.alert SET created_at = now() - '2h'::interval WHERE id = $1`, first.ID)
+	assert.NoError(t, err)
+	rows, err = q.GetAlertsSince(context.Background(), queries.GetAlertsSinceParams{
+		ChannelID: "953753877",
+		LastID:    0,
+		NotBefore: time.Now().Add(-time.Hour),
+	})
+	assert.NoError(t, err)
+	assert.Len(t, rows, 1)
+	numPurged, err := q.PurgeAlerts(context.Background(), queries.PurgeAlertsParams{
+		ChannelID: "953753877",
+		NotBefore: time.Now().Add(-time.Hour),
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), numPurged)
+	querytest.AssertCount(t, tx, 2, "SELECT COUNT(*) FROM showtime.alert")
+}
@@ -11,32 +11,122 @@ import (
 	"time"
 )
 
+const deleteBroadcast = `-- name: DeleteBroadcast :exec
+delete from showtime.broadcast
+where broadcast.id = $1
+`
+
+func (q *Queries) DeleteBroadcast(ctx context.Context, broadcastID int32) error {
+	_, err := q.db.ExecContext(ctx, deleteBroadcast, broadcastID)
+	return err
+}
+
 const getMostRecentBroadcast = `-- name: GetMostRecentBroadcast :one
 select
     broadcast.id,
     broadcast.started_at,
-    broadcast.ended_at
+    broadcast.ended_at,
+    broadcast.twitch_stream_id
 from showtime.broadcast
+where broadcast.channel_id = $1
 order by broadcast.started_at desc
 limit 1
 `
 
 type GetMostRecentBroadcastRow struct {
-	ID        int32
-	StartedAt time.Time
-	EndedAt   sql.NullTime
+	ID             int32
+	StartedAt      time.Time
+	EndedAt        sql.NullTime
+	TwitchStreamID sql.NullString
 }
 
-func (q *Queries) GetMostRecentBroadcast(ctx context.Context) (GetMostRecentBroadcastRow, error) {
-	row := q.db.QueryRowContext(ctx, getMostRecentBroadcast)
+func (q *Queries) GetMostRecentBroadcast(ctx context.Context, channelID string) (GetMostRecentBroadcastRow, error) {
+	row := q.db.QueryRowContext(ctx, getMostRecentBroadcast, channelID)
 	var i GetMostRecentBroadcastRow
-	err := row.Scan(&i.ID, &i.StartedAt, &i.EndedAt)
+	err := row.Scan(
+		&i.ID,
+		&i.StartedAt,
+		&i.EndedAt,
+		&i.TwitchStreamID,
+	)
+	return i, err
+}
+
+const getNextBroadcast = `-- name: GetNextBroadcast :one
+select
+    broadcast.id,
+    broadcast.started_at,
+    broadcast.ended_at,
+    broadcast.vod_url,
+    broadcast.twitch_stream_id,
+    broadcast.stream_type,
+    broadcast.channel_id
+from showtime.broadcast
+join showtime.broadcast as previous_broadcast
+    on previous_broadcast.id = $1
+where broadcast.channel_id = previous_broadcast.channel_id
+    and broadcast.started_at > previous_broadcast.started_at
+order by broadcast.started_at
+limit 1
+`
+
+func (q *Queries) GetNextBroadcast(ctx context.Context, broadcastID int32) (ShowtimeBroadcast, error) {
+	row := q.db.QueryRowContext(ctx, getNextBroadcast, broadcastID)
+	var i ShowtimeBroadcast
+	err := row.Scan(
+		&i.ID,
+		&i.StartedAt,
+		&i.EndedAt,
+		&i.VodUrl,
+		&i.TwitchStreamID,
+		&i.StreamType,
+		&i.ChannelID,
+	)
 	return i, err
 }
 
+const reassignBroadcastRecords = `-- name: ReassignBroadcastRecords :exec
+with reassigned_screening as (
+    update showtime.screening set broadcast_id = $1
+    where screening.broadcast_id = $2
+        and ($3::timestamptz is null or screening.started_at >= $3)
+), reassigned_channel_update as (
+    update showtime.channel_update set broadcast_id = $1
+    where channel_update.broadcast_id = $2
+        and ($3::timestamptz is null or channel_update.changed_at >= $3)
+), reassigned_hype_train as (
+    update showtime.hype_train set broadcast_id = $1
+    where hype_train.broadcast_id = $2
+        and ($3::timestamptz is null or hype_train.started_at >= $3)
+), reassigned_poll as (
+    update showtime.poll set broadcast_id = $1
+    where poll.broadcast_id = $2
+        and ($3::timestamptz is null or poll.started_at >= $3)
+), reassigned_raid as (
+    update showtime.raid set broadcast_id = $1
+    where raid.broadcast_id = $2
+        and ($3::timestamptz is null or raid.raided_at >= $3)
+)
+update showtime.prediction set broadcast_id = $1
+where prediction.broadcast_id = $2
+    and ($3::timestamptz is null or prediction.started_at >= $3)
+`
+
+type ReassignBroadcastRecordsParams struct {
+	ToBroadcastID   int32
+	FromBroadcastID int32
+	Since           sql.NullTime
+}
+
+func (q *Queries) ReassignBroadcastRecords(ctx context.Context, arg ReassignBroadcastRecordsParams) error {
+	_, err := q.db.ExecContext(ctx, reassignBroadcastRecords, arg.ToBroadcastID, arg.FromBroadcastID, arg.Since)
+	return err
+}
+
 const recordBroadcastEnded = `-- name: RecordBroadcastEnded :exec
 with most_recent_broadcast as (
     select broadcast.id from showtime.broadcast
+    where broadcast.channel_id = $1
     order by broadcast.started_at desc
     limit 1
 )
@@ -45,34 +135,133 @@ where broadcast.id = (select id from most_recent_broadcast)
     and broadcast.ended_at is null
 `
 
-func (q *Queries) RecordBroadcastEnded(ctx context.Context) error {
-	_, err := q.db.ExecContext(ctx, recordBroadcastEnded)
+func (q *Queries) RecordBroadcastEnded(ctx context.Context, channelID string) error {
+	_, err := q.db.ExecContext(ctx, recordBroadcastEnded, channelID)
 	return err
 }
 
 const recordBroadcastResumed = `-- name: RecordBroadcastResumed :exec
-update showtime.broadcast set ended_at = null
-where broadcast.id = $1
+update showtime.broadcast set
+    ended_at = null,
+    twitch_stream_id = coalesce($1, broadcast.twitch_stream_id)
+where broadcast.id = $2
     and broadcast.ended_at is not null
 `
 
-func (q *Queries) RecordBroadcastResumed(ctx context.Context, broadcastID int32) error {
-	_, err := q.db.ExecContext(ctx, recordBroadcastResumed, broadcastID)
+type RecordBroadcastResumedParams struct {
+	TwitchStreamID sql.NullString
+	BroadcastID    int32
+}
+
+func (q *Queries) RecordBroadcastResumed(ctx context.Context, arg RecordBroadcastResumedParams) error {
+	_, err := q.db.ExecContext(ctx, recordBroadcastResumed, arg.TwitchStreamID, arg.BroadcastID)
 	return err
 }
 
+const recordBroadcastSplit = `-- name: RecordBroadcastSplit :one
+insert into showtime.broadcast (
+    channel_id,
+    started_at,
+    ended_at,
+    stream_type
+) values (
+    $1,
+    $2,
+    $3,
+    $4
+)
+returning broadcast.id
+`
+
+type RecordBroadcastSplitParams struct {
+	ChannelID  string
+	StartedAt  time.Time
+	EndedAt    sql.NullTime
+	StreamType string
+}
+
+func (q *Queries) RecordBroadcastSplit(ctx context.Context, arg RecordBroadcastSplitParams) (int32, error) {
+	row := q.db.QueryRowContext(ctx, recordBroadcastSplit,
+		arg.ChannelID,
+		arg.StartedAt,
+		arg.EndedAt,
+		arg.StreamType,
+	)
+	var id int32
+	err := row.Scan(&id)
+	return id, err
+}
+
 const recordBroadcastStarted = `-- name: RecordBroadcastStarted :one
 insert into showtime.broadcast (
-    started_at
+    channel_id,
+    started_at,
+    twitch_stream_id,
+    stream_type
 ) values (
-    now()
+    $1,
+    $2,
+    $3,
+    $4
 )
 returning broadcast.id
 `
 
-func (q *Queries) RecordBroadcastStarted(ctx context.Context) (int32, error) {
-	row := q.db.QueryRowContext(ctx, recordBroadcastStarted)
+type RecordBroadcastStartedParams struct {
+	ChannelID      string
+	StartedAt      time.Time
+	TwitchStreamID sql.NullString
+	StreamType     string
+}
+
+func (q *Queries) RecordBroadcastStarted(ctx context.Context, arg RecordBroadcastStartedParams) (int32, error) {
+	row := q.db.QueryRowContext(ctx, recordBroadcastStarted,
+		arg.ChannelID,
+		arg.StartedAt,
+		arg.TwitchStreamID,
+		arg.StreamType,
+	)
 	var id int32
 	err := row.Scan(&id)
 	return id, err
 }
+
+const recordBroadcastsMerged = `-- name: RecordBroadcastsMerged :exec
+update showtime.broadcast set
+    ended_at = $1,
+    vod_url = coalesce(broadcast.vod_url, $2),
+    twitch_stream_id = coalesce(broadcast.twitch_stream_id, $3)
+where broadcast.id = $4
+`
+
+type RecordBroadcastsMergedParams struct {
+	EndedAt        sql.NullTime
+	VodUrl         sql.NullString
+	TwitchStreamID sql.NullString
+	BroadcastID    int32
+}
+
+func (q *Queries) RecordBroadcastsMerged(ctx context.Context, arg RecordBroadcastsMergedParams) error {
+	_, err := q.db.ExecContext(ctx, recordBroadcastsMerged,
+		arg.EndedAt,
+		arg.VodUrl,
+		arg.TwitchStreamID,
+		arg.BroadcastID,
+	)
+	return err
+}
+
+const setBroadcastEndedAt = `-- name: SetBroadcastEndedAt :exec
+update showtime.broadcast set ended_at = $1
+where broadcast.id = $2
+`
+
+type SetBroadcastEndedAtParams struct {
+	EndedAt     sql.NullTime
+	BroadcastID int32
+}
+
+func (q *Queries) SetBroadcastEndedAt(ctx context.Context, arg SetBroadcastEndedAtParams) error {
+	_, err := q.db.ExecContext(ctx, setBroadcastEndedAt, arg.EndedAt, arg.BroadcastID)
+	return err
+}
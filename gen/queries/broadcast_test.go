@@ -4,6 +4,7 @@ import (
 	"context"
 	"database/sql"
 	"testing"
+	"time"
 
 	"github.com/golden-vcr/server-common/querytest"
 	"github.com/golden-vcr/showtime/gen/queries"
@@ -14,18 +15,28 @@ func Test_GetMostRecentBroadcast(t *testing.T) {
 	tx := querytest.PrepareTx(t)
 	q := queries.New(tx)
 
-	_, err := q.GetMostRecentBroadcast(context.Background())
+	_, err := q.GetMostRecentBroadcast(context.Background(), "953753877")
 	assert.ErrorIs(t, err, sql.ErrNoRows)
 
 	_, err = tx.Exec(`
-		INSERT INTO showtime.broadcast (id, started_at, ended_at) VALUES (1, now() - '1h'::interval, now())
+		INSERT INTO showtime.broadcast (channel_id, id, started_at, ended_at) VALUES ('953753877', 1, now() - '1h'::interval, now())
 	`)
 	assert.NoError(t, err)
 
-	broadcast, err := q.GetMostRecentBroadcast(context.Background())
+	broadcast, err := q.GetMostRecentBroadcast(context.Background(), "953753877")
 	assert.NoError(t, err)
 	assert.Equal(t, int32(1), broadcast.ID)
 	assert.True(t, broadcast.EndedAt.Valid)
+
+	// A more recent broadcast in another channel should not be considered
+	_, err = tx.Exec(`
+		INSERT INTO showtime.broadcast (channel_id, id, started_at) VALUES ('12345', 2, now())
+	`)
+	assert.NoError(t, err)
+
+	broadcast, err = q.GetMostRecentBroadcast(context.Background(), "953753877")
+	assert.NoError(t, err)
+	assert.Equal(t, int32(1), broadcast.ID)
 }
 
 func Test_RecordBroadcastStarted(t *testing.T) {
@@ -34,13 +45,21 @@ func Test_RecordBroadcastStarted(t *testing.T) {
 
 	querytest.AssertCount(t, tx, 0, "SELECT COUNT(*) FROM showtime.broadcast")
 
-	broadcastId, err := q.RecordBroadcastStarted(context.Background())
+	broadcastId, err := q.RecordBroadcastStarted(context.Background(), queries.RecordBroadcastStartedParams{
+		ChannelID:      "953753877",
+		StartedAt:      time.Date(1997, 9, 1, 12, 0, 0, 0, time.UTC),
+		TwitchStreamID: sql.NullString{String: "40078987165", Valid: true},
+		StreamType:     "premiere",
+	})
 	assert.NoError(t, err)
 
 	querytest.AssertCount(t, tx, 1, `
 		SELECT COUNT(*) FROM showtime.broadcast
 			WHERE id = $1
+			AND started_at = '1997-09-01 12:00:00+00'
 			AND ended_at IS NULL
+			AND twitch_stream_id = '40078987165'
+			AND stream_type = 'premiere'
 	`, broadcastId)
 }
 
@@ -50,9 +69,13 @@ func Test_RecordBroadcastEnded(t *testing.T) {
 
 	querytest.AssertCount(t, tx, 0, "SELECT COUNT(*) FROM showtime.broadcast")
 
-	broadcastId, err := q.RecordBroadcastStarted(context.Background())
+	broadcastId, err := q.RecordBroadcastStarted(context.Background(), queries.RecordBroadcastStartedParams{
+		ChannelID:  "953753877",
+		StartedAt:  time.Now(),
+		StreamType: "live",
+	})
 	assert.NoError(t, err)
-	err = q.RecordBroadcastEnded(context.Background())
+	err = q.RecordBroadcastEnded(context.Background(), "953753877")
 	assert.NoError(t, err)
 
 	querytest.AssertCount(t, tx, 1, `
@@ -68,16 +91,124 @@ func Test_RecordBroadcastResumed(t *testing.T) {
 
 	querytest.AssertCount(t, tx, 0, "SELECT COUNT(*) FROM showtime.broadcast")
 
-	broadcastId, err := q.RecordBroadcastStarted(context.Background())
+	broadcastId, err := q.RecordBroadcastStarted(context.Background(), queries.RecordBroadcastStartedParams{
+		ChannelID:      "953753877",
+		StartedAt:      time.Now(),
+		TwitchStreamID: sql.NullString{String: "stream-a", Valid: true},
+		StreamType:     "live",
+	})
 	assert.NoError(t, err)
-	err = q.RecordBroadcastEnded(context.Background())
+	err = q.RecordBroadcastEnded(context.Background(), "953753877")
 	assert.NoError(t, err)
-	err = q.RecordBroadcastResumed(context.Background(), broadcastId)
+	err = q.RecordBroadcastResumed(context.Background(), queries.RecordBroadcastResumedParams{
+		BroadcastID: broadcastId,
+	})
 	assert.NoError(t, err)
 
+	// Resuming without a stream ID should leave the original stream ID in place
 	querytest.AssertCount(t, tx, 1, `
 		SELECT COUNT(*) FROM showtime.broadcast
 			WHERE id = $1
 			AND ended_at IS NULL
+			AND twitch_stream_id = 'stream-a'
 	`, broadcastId)
+
+	// Resuming from a new stream should record that stream's ID
+	err = q.RecordBroadcastEnded(context.Background(), "953753877")
+	assert.NoError(t, err)
+	err = q.RecordBroadcastResumed(context.Background(), queries.RecordBroadcastResumedParams{
+		TwitchStreamID: sql.NullString{String: "stream-b", Valid: true},
+		BroadcastID:    broadcastId,
+	})
+	assert.NoError(t, err)
+	querytest.AssertCount(t, tx, 1, `
+		SELECT COUNT(*) FROM showtime.broadcast
+			WHERE id = $1
+			AND ended_at IS NULL
+			AND twitch_stream_id = 'stream-b'
+	`, broadcastId)
+}
+
+func Test_GetNextBroadcast(t *testing.T) {
+	tx := querytest.PrepareTx(t)
+	q := queries.New(tx)
+
+	_, err := tx.Exec(`
+		INSERT INTO showtime.broadcast (channel_id, id, started_at, ended_at) VALUES
+			('953753877', 1, now() - '3h'::interval, now() - '2h'::interval),
+			('953753877', 2, now() - '1h'::interval, NULL)
+	`)
+	assert.NoError(t, err)
+
+	next, err := q.GetNextBroadcast(context.Background(), 1)
+	assert.NoError(t, err)
+	assert.Equal(t, int32(2), next.ID)
+
+	_, err = q.GetNextBroadcast(context.Background(), 2)
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+}
+
+func Test_ReassignBroadcastRecords(t *testing.T) {
+	tx := querytest.PrepareTx(t)
+	q := queries.New(tx)
+
+	_, err := tx.Exec(`
+		INSERT INTO showtime.broadcast (channel_id, id, started_at, ended_at) VALUES
+			('953753877', 1, '1997-09-01 12:00:00+00', '1997-09-01 14:00:00+00'),
+			('953753877', 2, '1997-09-01 14:00:00+00', NULL);
+		INSERT INTO showtime.screening (broadcast_id, tape_id, started_at, ended_at) VALUES
+			(1, 10, '1997-09-01 12:30:00+00', '1997-09-01 13:00:00+00'),
+			(1, 20, '1997-09-01 14:30:00+00', NULL);
+		INSERT INTO showtime.channel_update (channel_id, broadcast_id, title, category_id, category_name, changed_at) VALUES
+			('953753877', 1, 'before', '1', 'Art', '1997-09-01 12:10:00+00'),
+			('953753877', 1, 'after', '1', 'Art', '1997-09-01 14:10:00+00');
+	`)
+	assert.NoError(t, err)
+
+	// Records from the split time onward should be moved to the other broadcast
+	err = q.ReassignBroadcastRecords(context.Background(), queries.ReassignBroadcastRecordsParams{
+		ToBroadcastID:   2,
+		FromBroadcastID: 1,
+		Since:           sql.NullTime{Time: time.Date(1997, 9, 1, 14, 0, 0, 0, time.UTC), Valid: true},
+	})
+	assert.NoError(t, err)
+	querytest.AssertCount(t, tx, 1, "SELECT COUNT(*) FROM showtime.screening WHERE broadcast_id = 2 AND tape_id = 20")
+	querytest.AssertCount(t, tx, 1, "SELECT COUNT(*) FROM showtime.channel_update WHERE broadcast_id = 2 AND title = 'after'")
+	querytest.AssertCount(t, tx, 1, "SELECT COUNT(*) FROM showtime.screening WHERE broadcast_id = 1")
+
+	// With no cutoff, all records should be moved
+	err = q.ReassignBroadcastRecords(context.Background(), queries.ReassignBroadcastRecordsParams{
+		ToBroadcastID:   1,
+		FromBroadcastID: 2,
+	})
+	assert.NoError(t, err)
+	querytest.AssertCount(t, tx, 2, "SELECT COUNT(*) FROM showtime.screening WHERE broadcast_id = 1")
+	querytest.AssertCount(t, tx, 2, "SELECT COUNT(*) FROM showtime.channel_update WHERE broadcast_id = 1")
+}
+
+func Test_RecordBroadcastsMerged(t *testing.T) {
+	tx := querytest.PrepareTx(t)
+	q := queries.New(tx)
+
+	_, err := tx.Exec(`
+		INSERT INTO showtime.broadcast (channel_id, id, started_at, ended_at, twitch_stream_id) VALUES
+			('953753877', 1, '1997-09-01 12:00:00+00', '1997-09-01 13:00:00+00', '1111')
+	`)
+	assert.NoError(t, err)
+
+	err = q.RecordBroadcastsMerged(context.Background(), queries.RecordBroadcastsMergedParams{
+		EndedAt:        sql.NullTime{Time: time.Date(1997, 9, 1, 15, 0, 0, 0, time.UTC), Valid: true},
+		VodUrl:         sql.NullString{String: "https://vods.com/2", Valid: true},
+		TwitchStreamID: sql.NullString{String: "2222", Valid: true},
+		BroadcastID:    1,
+	})
+	assert.NoError(t, err)
+
+	querytest.AssertCount(t, tx, 1, `
+		SELECT COUNT(*) FROM showtime.broadcast
+			WHERE id = 1
+			AND ended_at = '1997-09-01 15:00:00+00'
+			AND vod_url = 'https://vods.com/2'
+			AND twitch_stream_id = '1111'
+	`)
 }
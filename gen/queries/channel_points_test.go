@@ -0,0 +1,108 @@
+package queries_test
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/golden-vcr/server-common/querytest"
+	"github.com/golden-vcr/showtime/gen/queries"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_SetChannelPointsReward(t *testing.T) {
+	tx := querytest.PrepareTx(t)
+	q := queries.New(tx)
+
+	_, err := q.GetChannelPointsReward(context.Background(), "reward-1")
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+
+	err = q.SetChannelPointsReward(context.Background(), queries.SetChannelPointsRewardParams{
+		RewardID: "reward-1",
+		Action:   "alert",
+	})
+	assert.NoError(t, err)
+	reward, err := q.GetChannelPointsReward(context.Background(), "reward-1")
+	assert.NoError(t, err)
+	assert.Equal(t, "alert", reward.Action)
+
+	// Setting the same reward again should replace its config
+	err = q.SetChannelPointsReward(context.Background(), queries.SetChannelPointsRewardParams{
+		RewardID:  "reward-1",
+		Action:    "credit",
+		NumPoints: 100,
+	})
+	assert.NoError(t, err)
+	rewards, err := q.GetChannelPointsRewards(context.Background())
+	assert.NoError(t, err)
+	assert.Len(t, rewards, 1)
+	assert.Equal(t, "credit", rewards[0].Action)
+	assert.Equal(t, int32(100), rewards[0].NumPoints)
+
+	// Unsupported actions should be rejected
+	err = q.SetChannelPointsReward(context.Background(), queries.SetChannelPointsRewardParams{
+		RewardID: "reward-2",
+		Action:   "explode",
+	})
+	assert.Error(t, err)
+}
+
+func Test_DeleteChannelPointsReward(t *testing.T) {
+	tx := querytest.PrepareTx(t)
+	q := queries.New(tx)
+
+	_, err := tx.Exec(`
+		INSERT INTO showtime.channel_points_reward (reward_id, action) VALUES ('reward-1', 'alert')
+	`)
+	assert.NoError(t, err)
+
+	res, err := q.DeleteChannelPointsReward(context.Background(), "reward-1")
+	assert.NoError(t, err)
+	querytest.AssertNumRowsChanged(t, res, 1)
+
+	res, err = q.DeleteChannelPointsReward(context.Background(), "reward-1")
+	assert.NoError(t, err)
+	querytest.AssertNumRowsChanged(t, res, 0)
+}
+
+func Test_RecordChannelPointsRedemption(t *testing.T) {
+	tx := querytest.PrepareTx(t)
+	q := queries.New(tx)
+
+	params := queries.RecordChannelPointsRedemptionParams{
+		RedemptionID: "redemption-1",
+		RewardID:     "reward-1",
+		TwitchUserID: "1234",
+		UserInput:    "boo",
+		RedeemedAt:   time.Now(),
+	}
+	resolvedAt, err := q.RecordChannelPointsRedemption(context.Background(), params)
+	assert.NoError(t, err)
+	assert.False(t, resolvedAt.Valid)
+
+	// Recording the same redemption again should be a no-op
+	resolvedAt, err = q.RecordChannelPointsRedemption(context.Background(), params)
+	assert.NoError(t, err)
+	assert.False(t, resolvedAt.Valid)
+	querytest.AssertCount(t, tx, 1, `
+		SELECT COUNT(*) FROM showtime.channel_points_redemption
+	`)
+
+	// Once resolved, the redemption should report when it was resolved
+	err = q.RecordChannelPointsRedemptionResolved(context.Background(), queries.RecordChannelPointsRedemptionResolvedParams{
+		Status:       sql.NullString{String: "CANCELED", Valid: true},
+		ErrorMessage: sql.NullString{String: "mock error", Valid: true},
+		RedemptionID: "redemption-1",
+	})
+	assert.NoError(t, err)
+	querytest.AssertCount(t, tx, 1, `
+		SELECT COUNT(*) FROM showtime.channel_points_redemption
+			WHERE redemption_id = 'redemption-1'
+			AND status = 'CANCELED'
+			AND error_message = 'mock error'
+	`)
+	resolvedAt, err = q.RecordChannelPointsRedemption(context.Background(), params)
+	assert.NoError(t, err)
+	assert.True(t, resolvedAt.Valid)
+}
@@ -0,0 +1,107 @@
+package queries_test
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/golden-vcr/server-common/querytest"
+	"github.com/golden-vcr/showtime/gen/queries"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_RecordChannelUpdate(t *testing.T) {
+	tx := querytest.PrepareTx(t)
+	q := queries.New(tx)
+
+	_, err := q.GetMostRecentChannelUpdate(context.Background(), "953753877")
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+
+	// A change made while no broadcast is live should not be associated with any
+	// broadcast
+	err = q.RecordChannelUpdate(context.Background(), queries.RecordChannelUpdateParams{
+		ChannelID:    "953753877",
+		Title:        "Coming up: tapes",
+		CategoryID:   "1234",
+		CategoryName: "Retro",
+	})
+	assert.NoError(t, err)
+	querytest.AssertCount(t, tx, 1, `
+		SELECT COUNT(*) FROM showtime.channel_update
+			WHERE title = 'Coming up: tapes'
+			AND broadcast_id IS NULL
+	`)
+
+	// A change made during a broadcast should be associated with it
+	_, err = tx.Exec(`
+		INSERT INTO showtime.broadcast (channel_id, id, started_at) VALUES ('953753877', 1, now())
+	`)
+	assert.NoError(t, err)
+	err = q.RecordChannelUpdate(context.Background(), queries.RecordChannelUpdateParams{
+		ChannelID:    "953753877",
+		Title:        "Watching tapes",
+		CategoryID:   "1234",
+		CategoryName: "Retro",
+	})
+	assert.NoError(t, err)
+	querytest.AssertCount(t, tx, 1, `
+		SELECT COUNT(*) FROM showtime.channel_update
+			WHERE title = 'Watching tapes'
+			AND broadcast_id = 1
+	`)
+}
+
+func Test_GetChannelUpdatesByBroadcastId(t *testing.T) {
+	tx := querytest.PrepareTx(t)
+	q := queries.New(tx)
+
+	_, err := tx.Exec(`
+		INSERT INTO showtime.broadcast (channel_id, id, started_at, ended_at) VALUES
+			('953753877', 1, now() - '3h'::interval, now() - '2h'::interval);
+		INSERT INTO showtime.channel_update (channel_id, broadcast_id, title, category_id, category_name, changed_at) VALUES
+			('953753877', NULL, 'Old title', '1', 'Old category', now() - '5h'::interval),
+			('953753877', NULL, 'Starting soon', '2', 'Retro', now() - '4h'::interval),
+			('953753877', 1, 'Watching tapes', '2', 'Retro', now() - '150m'::interval),
+			('953753877', NULL, 'Thanks for watching', '2', 'Retro', now() - '1h'::interval);
+	`)
+	assert.NoError(t, err)
+
+	// The timeline should include the title we started with, along with all changes
+	// made during the broadcast
+	rows, err := q.GetChannelUpdatesByBroadcastId(context.Background(), 1)
+	assert.NoError(t, err)
+	assert.Len(t, rows, 2)
+	assert.Equal(t, "Starting soon", rows[0].Title)
+	assert.Equal(t, "Watching tapes", rows[1].Title)
+
+	update, err := q.GetMostRecentChannelUpdate(context.Background(), "953753877")
+	assert.NoError(t, err)
+	assert.Equal(t, "Thanks for watching", update.Title)
+}
+
+func Test_GetChannelUpdatesByBroadcastId_multipleChannels(t *testing.T) {
+	tx := querytest.PrepareTx(t)
+	q := queries.New(tx)
+
+	_, err := tx.Exec(`
+		INSERT INTO showtime.broadcast (channel_id, id, started_at, ended_at) VALUES
+			('953753877', 1, now() - '3h'::interval, now() - '2h'::interval),
+			('90001', 2, now() - '3h'::interval, now() - '2h'::interval);
+		INSERT INTO showtime.channel_update (channel_id, broadcast_id, title, category_id, category_name, changed_at) VALUES
+			('953753877', NULL, 'Starting soon', '2', 'Retro', now() - '5h'::interval),
+			('90001', NULL, 'Partner starting soon', '3', 'Chatting', now() - '4h'::interval);
+	`)
+	assert.NoError(t, err)
+
+	// The title in effect when each broadcast started should come from its own
+	// channel, even if another channel was updated more recently
+	rows, err := q.GetChannelUpdatesByBroadcastId(context.Background(), 1)
+	assert.NoError(t, err)
+	assert.Len(t, rows, 1)
+	assert.Equal(t, "Starting soon", rows[0].Title)
+
+	rows, err = q.GetChannelUpdatesByBroadcastId(context.Background(), 2)
+	assert.NoError(t, err)
+	assert.Len(t, rows, 1)
+	assert.Equal(t, "Partner starting soon", rows[0].Title)
+}
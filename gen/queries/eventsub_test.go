@@ -0,0 +1,320 @@
+package queries_test
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/golden-vcr/server-common/querytest"
+	"github.com/golden-vcr/showtime/gen/queries"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_RecordEventSubMessage(t *testing.T) {
+	tx := querytest.PrepareTx(t)
+	q := queries.New(tx)
+
+	querytest.AssertCount(t, tx, 0, "SELECT COUNT(*) FROM showtime.eventsub_message")
+
+	params := queries.RecordEventSubMessageParams{
+		MessageID:        "befa7b53-d79d-478f-86b9-120f112b044e",
+		MessageType:      "notification",
+		SubscriptionType: "channel.follow",
+		MessageTimestamp: time.Now().Add(-5 * time.Second),
+	}
+	res, err := q.RecordEventSubMessage(context.Background(), params)
+	assert.NoError(t, err)
+	querytest.AssertNumRowsChanged(t, res, 1)
+
+	querytest.AssertCount(t, tx, 1, `
+		SELECT COUNT(*) FROM showtime.eventsub_message
+			WHERE message_id = 'befa7b53-d79d-478f-86b9-120f112b044e'
+			AND message_type = 'notification'
+			AND subscription_type = 'channel.follow'
+			AND received_at IS NOT NULL
+	`)
+
+	// Recording a message with the same ID again should be a no-op
+	res, err = q.RecordEventSubMessage(context.Background(), params)
+	assert.NoError(t, err)
+	querytest.AssertNumRowsChanged(t, res, 0)
+
+	querytest.AssertCount(t, tx, 1, "SELECT COUNT(*) FROM showtime.eventsub_message")
+}
+
+func Test_EnqueueEventSubNotification(t *testing.T) {
+	tx := querytest.PrepareTx(t)
+	q := queries.New(tx)
+
+	querytest.AssertCount(t, tx, 0, "SELECT COUNT(*) FROM showtime.eventsub_inbox")
+
+	params := queries.EnqueueEventSubNotificationParams{
+		MessageID:        "5ec2b1ca-d1a9-4bba-9bd2-d8b0bf7f6a5e",
+		SubscriptionType: "channel.follow",
+		MessageTimestamp: time.Now().Add(-5 * time.Second),
+		Subscription:     json.RawMessage(`{"type":"channel.follow"}`),
+		Event:            json.RawMessage(`{"user_name":"BigJim"}`),
+	}
+	res, err := q.EnqueueEventSubNotification(context.Background(), params)
+	assert.NoError(t, err)
+	querytest.AssertNumRowsChanged(t, res, 1)
+
+	querytest.AssertCount(t, tx, 1, `
+		SELECT COUNT(*) FROM showtime.eventsub_message
+			WHERE message_id = '5ec2b1ca-d1a9-4bba-9bd2-d8b0bf7f6a5e'
+			AND message_type = 'notification'
+	`)
+	querytest.AssertCount(t, tx, 1, `
+		SELECT COUNT(*) FROM showtime.eventsub_inbox
+			WHERE message_id = '5ec2b1ca-d1a9-4bba-9bd2-d8b0bf7f6a5e'
+			AND event->>'user_name' = 'BigJim'
+			AND num_attempts = 0
+			AND processed_at IS NULL
+	`)
+
+	// Enqueueing a message with the same ID again should be a no-op
+	res, err = q.EnqueueEventSubNotification(context.Background(), params)
+	assert.NoError(t, err)
+	querytest.AssertNumRowsChanged(t, res, 0)
+	querytest.AssertCount(t, tx, 1, "SELECT COUNT(*) FROM showtime.eventsub_inbox")
+}
+
+func Test_EventSubInbox(t *testing.T) {
+	tx := querytest.PrepareTx(t)
+	q := queries.New(tx)
+
+	_, err := q.EnqueueEventSubNotification(context.Background(), queries.EnqueueEventSubNotificationParams{
+		MessageID:        "message-a",
+		SubscriptionType: "channel.follow",
+		MessageTimestamp: time.Now(),
+		Subscription:     json.RawMessage(`{"type":"channel.follow"}`),
+		Event:            json.RawMessage(`{}`),
+	})
+	assert.NoError(t, err)
+
+	// Claiming the entry should lock it, so that it can't be claimed again
+	entry, err := q.ClaimEventSubInboxEntry(context.Background(), time.Now().Add(time.Minute))
+	assert.NoError(t, err)
+	assert.Equal(t, "message-a", entry.MessageID)
+	assert.Equal(t, int32(0), entry.NumAttempts)
+	_, err = q.ClaimEventSubInboxEntry(context.Background(), time.Now().Add(time.Minute))
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+
+	// Recording a failure should unlock it but defer the next attempt
+	err = q.RecordEventSubInboxFailure(context.Background(), queries.RecordEventSubInboxFailureParams{
+		ErrorMessage:  "mock error",
+		NextAttemptAt: time.Now().Add(time.Hour),
+		MessageID:     "message-a",
+	})
+	assert.NoError(t, err)
+	_, err = q.ClaimEventSubInboxEntry(context.Background(), time.Now().Add(time.Minute))
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+	querytest.AssertCount(t, tx, 1, `
+		SELECT COUNT(*) FROM showtime.eventsub_inbox
+			WHERE message_id = 'message-a'
+			AND num_attempts = 1
+			AND last_error = 'mock error'
+			AND locked_until IS NULL
+	`)
+
+	// Dead-lettering should make the entry show up in our list
+	err = q.RecordEventSubInboxDeadLettered(context.Background(), queries.RecordEventSubInboxDeadLetteredParams{
+		ErrorMessage: "mock error again",
+		MessageID:    "message-a",
+	})
+	assert.NoError(t, err)
+	deadLettered, err := q.GetDeadLetteredEventSubInboxEntries(context.Background())
+	assert.NoError(t, err)
+	assert.Len(t, deadLettered, 1)
+	assert.Equal(t, "message-a", deadLettered[0].MessageID)
+	assert.Equal(t, int32(2), deadLettered[0].NumAttempts)
+	assert.Equal(t, "mock error again", deadLettered[0].LastError.String)
+
+	// Retrying should make the entry immediately eligible for processing
+	res, err := q.RetryDeadLetteredEventSubInboxEntry(context.Background(), "message-a")
+	assert.NoError(t, err)
+	querytest.AssertNumRowsChanged(t, res, 1)
+	entry, err = q.ClaimEventSubInboxEntry(context.Background(), time.Now().Add(time.Minute))
+	assert.NoError(t, err)
+	assert.Equal(t, int32(0), entry.NumAttempts)
+
+	// Once processed, the entry should not be claimed again
+	err = q.RecordEventSubInboxSuccess(context.Background(), "message-a")
+	assert.NoError(t, err)
+	_, err = q.ClaimEventSubInboxEntry(context.Background(), time.Now().Add(time.Minute))
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+
+	// Only dead-lettered entries may be discarded
+	res, err = q.DiscardDeadLetteredEventSubInboxEntry(context.Background(), "message-a")
+	assert.NoError(t, err)
+	querytest.AssertNumRowsChanged(t, res, 0)
+}
+
+func Test_EventSubRevocation(t *testing.T) {
+	tx := querytest.PrepareTx(t)
+	q := queries.New(tx)
+
+	// Recording a revocation should make it show up as unresolved
+	res, err := q.RecordEventSubRevocation(context.Background(), queries.RecordEventSubRevocationParams{
+		MessageID:           "message-a",
+		SubscriptionType:    "channel.follow",
+		MessageTimestamp:    time.Now(),
+		SubscriptionID:      "subscription-a",
+		SubscriptionVersion: "2",
+		Reason:              "authorization_revoked",
+		Condition:           json.RawMessage(`{"broadcaster_user_id":"1234"}`),
+	})
+	assert.NoError(t, err)
+	querytest.AssertNumRowsChanged(t, res, 1)
+	revocations, err := q.GetUnresolvedEventSubRevocations(context.Background())
+	assert.NoError(t, err)
+	assert.Len(t, revocations, 1)
+	assert.Equal(t, "subscription-a", revocations[0].SubscriptionID)
+	assert.Equal(t, "authorization_revoked", revocations[0].Reason)
+
+	// Recording the same message again should be a no-op
+	res, err = q.RecordEventSubRevocation(context.Background(), queries.RecordEventSubRevocationParams{
+		MessageID:           "message-a",
+		SubscriptionType:    "channel.follow",
+		MessageTimestamp:    time.Now(),
+		SubscriptionID:      "subscription-a",
+		SubscriptionVersion: "2",
+		Reason:              "authorization_revoked",
+		Condition:           json.RawMessage(`{"broadcaster_user_id":"1234"}`),
+	})
+	assert.NoError(t, err)
+	querytest.AssertNumRowsChanged(t, res, 0)
+
+	// Verifying a new subscription of the same type should not resolve the revocation,
+	// since that subscription may have a different condition
+	_, err = tx.Exec(`
+		INSERT INTO showtime.eventsub_message (
+			message_id, message_type, subscription_type, message_timestamp, received_at
+		) VALUES (
+			'message-b', 'webhook_callback_verification', 'channel.follow', now(), now() + interval '1 second'
+		)
+	`)
+	assert.NoError(t, err)
+	revocations, err = q.GetUnresolvedEventSubRevocations(context.Background())
+	assert.NoError(t, err)
+	assert.Len(t, revocations, 1)
+
+	// Once a subscription with the same type, version, and condition has been
+	// recreated, the revocation should be considered resolved
+	numRows, err := q.ResolveEventSubRevocations(context.Background(), queries.ResolveEventSubRevocationsParams{
+		SubscriptionType:    "channel.follow",
+		SubscriptionVersion: "2",
+		Condition:           json.RawMessage(`{"broadcaster_user_id":"1234"}`),
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), numRows)
+	revocations, err = q.GetUnresolvedEventSubRevocations(context.Background())
+	assert.NoError(t, err)
+	assert.Len(t, revocations, 0)
+}
+
+func Test_ResolveEventSubRevocations(t *testing.T) {
+	tx := querytest.PrepareTx(t)
+	q := queries.New(tx)
+
+	// We subscribe to raids both to and from our channel: both are revoked
+	for i, condition := range []string{
+		`{"from_broadcaster_user_id":"","to_broadcaster_user_id":"1234"}`,
+		`{"from_broadcaster_user_id":"1234","to_broadcaster_user_id":""}`,
+	} {
+		_, err := q.RecordEventSubRevocation(context.Background(), queries.RecordEventSubRevocationParams{
+			MessageID:           fmt.Sprintf("message-%d", i),
+			SubscriptionType:    "channel.raid",
+			MessageTimestamp:    time.Now(),
+			SubscriptionID:      fmt.Sprintf("subscription-%d", i),
+			SubscriptionVersion: "1",
+			Reason:              "authorization_revoked",
+			Condition:           json.RawMessage(condition),
+		})
+		assert.NoError(t, err)
+	}
+
+	// Recreating a subscription with a different version should not resolve either
+	numRows, err := q.ResolveEventSubRevocations(context.Background(), queries.ResolveEventSubRevocationsParams{
+		SubscriptionType:    "channel.raid",
+		SubscriptionVersion: "2",
+		Condition:           json.RawMessage(`{"from_broadcaster_user_id":"","to_broadcaster_user_id":"1234"}`),
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), numRows)
+
+	// Recreating the incoming raid subscription should only resolve that revocation,
+	// regardless of how its condition is formatted
+	numRows, err = q.ResolveEventSubRevocations(context.Background(), queries.ResolveEventSubRevocationsParams{
+		SubscriptionType:    "channel.raid",
+		SubscriptionVersion: "1",
+		Condition:           json.RawMessage(`{"to_broadcaster_user_id": "1234", "from_broadcaster_user_id": ""}`),
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), numRows)
+	revocations, err := q.GetUnresolvedEventSubRevocations(context.Background())
+	assert.NoError(t, err)
+	assert.Len(t, revocations, 1)
+	assert.Equal(t, "subscription-1", revocations[0].SubscriptionID)
+}
+
+func Test_GetEventSubNotificationsForReplay(t *testing.T) {
+	tx := querytest.PrepareTx(t)
+	q := queries.New(tx)
+
+	start := time.Date(1997, 9, 1, 12, 0, 0, 0, time.UTC)
+	for i, subscriptionType := range []string{"channel.follow", "channel.cheer", "channel.follow"} {
+		_, err := q.EnqueueEventSubNotification(context.Background(), queries.EnqueueEventSubNotificationParams{
+			MessageID:        fmt.Sprintf("message-%d", i),
+			SubscriptionType: subscriptionType,
+			MessageTimestamp: start.Add(time.Duration(i) * time.Minute),
+			Subscription:     json.RawMessage(fmt.Sprintf(`{"type":"%s"}`, subscriptionType)),
+			Event:            json.RawMessage(`{}`),
+		})
+		assert.NoError(t, err)
+	}
+
+	// The time range should include since and exclude until
+	rows, err := q.GetEventSubNotificationsForReplay(context.Background(), queries.GetEventSubNotificationsForReplayParams{
+		Since: start.Add(time.Minute),
+		Until: start.Add(2 * time.Minute),
+	})
+	assert.NoError(t, err)
+	assert.Len(t, rows, 1)
+	assert.Equal(t, "message-1", rows[0].MessageID)
+	assert.Equal(t, "channel.cheer", rows[0].SubscriptionType)
+
+	// Notifications should be returned in the order they were sent
+	rows, err = q.GetEventSubNotificationsForReplay(context.Background(), queries.GetEventSubNotificationsForReplayParams{
+		Since: start,
+		Until: start.Add(time.Hour),
+	})
+	assert.NoError(t, err)
+	assert.Len(t, rows, 3)
+	assert.Equal(t, "message-0", rows[0].MessageID)
+	assert.Equal(t, "message-2", rows[2].MessageID)
+}
+
+func Test_RecordEventSubReconciliation(t *testing.T) {
+	tx := querytest.PrepareTx(t)
+	q := queries.New(tx)
+
+	startedAt := time.Date(1997, 9, 1, 12, 0, 0, 0, time.UTC)
+	err := q.RecordEventSubReconciliation(context.Background(), queries.RecordEventSubReconciliationParams{
+		StartedAt:  startedAt,
+		FinishedAt: startedAt.Add(time.Second),
+		Actions:    json.RawMessage(`[{"action":"create","subscriptionType":"channel.follow"}]`),
+		Error:      sql.NullString{},
+	})
+	assert.NoError(t, err)
+
+	querytest.AssertCount(t, tx, 1, `
+		SELECT COUNT(*) FROM showtime.eventsub_reconciliation
+			WHERE started_at = $1
+			AND actions->0->>'action' = 'create'
+			AND error IS NULL
+	`, startedAt)
+}
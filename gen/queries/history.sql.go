@@ -16,7 +16,7 @@ import (
 
 const getBroadcastById = `-- name: GetBroadcastById :one
 select
-    id, started_at, ended_at, vod_url
+    id, started_at, ended_at, vod_url, twitch_stream_id, stream_type, channel_id
 from showtime.broadcast
 where broadcast.id = $1
 `
@@ -29,6 +29,9 @@ func (q *Queries) GetBroadcastById(ctx context.Context, broadcastID int32) (Show
 		&i.StartedAt,
 		&i.EndedAt,
 		&i.VodUrl,
+		&i.TwitchStreamID,
+		&i.StreamType,
+		&i.ChannelID,
 	)
 	return i, err
 }
@@ -38,23 +41,26 @@ select
     broadcast.id,
     broadcast.started_at,
     broadcast.vod_url,
+    broadcast.twitch_stream_id,
     array_agg(screening.tape_id order by screening.started_at)::integer[] as tape_ids
 from showtime.broadcast
 join showtime.screening
     on screening.broadcast_id = broadcast.id
+where broadcast.channel_id = $1
 group by broadcast.id
 order by broadcast.started_at
 `
 
 type GetBroadcastHistoryRow struct {
-	ID        int32
-	StartedAt time.Time
-	VodUrl    sql.NullString
-	TapeIds   []int32
+	ID             int32
+	StartedAt      time.Time
+	VodUrl         sql.NullString
+	TwitchStreamID sql.NullString
+	TapeIds        []int32
 }
 
-func (q *Queries) GetBroadcastHistory(ctx context.Context) ([]GetBroadcastHistoryRow, error) {
-	rows, err := q.db.QueryContext(ctx, getBroadcastHistory)
+func (q *Queries) GetBroadcastHistory(ctx context.Context, channelID string) ([]GetBroadcastHistoryRow, error) {
+	rows, err := q.db.QueryContext(ctx, getBroadcastHistory, channelID)
 	if err != nil {
 		return nil, err
 	}
@@ -66,6 +72,7 @@ func (q *Queries) GetBroadcastHistory(ctx context.Context) ([]GetBroadcastHistor
 			&i.ID,
 			&i.StartedAt,
 			&i.VodUrl,
+			&i.TwitchStreamID,
 			pq.Array(&i.TapeIds),
 		); err != nil {
 			return nil, err
@@ -16,16 +16,16 @@ func Test_GetTapeBroadcastHistory(t *testing.T) {
 	q := queries.New(tx)
 
 	// We should have no broadcast history initially
-	rows, err := q.GetBroadcastHistory(context.Background())
+	rows, err := q.GetBroadcastHistory(context.Background(), "953753877")
 	assert.NoError(t, err)
 	assert.Len(t, rows, 0)
 
 	// Simulate three broadcasts
 	_, err = tx.Exec(`
-		INSERT INTO showtime.broadcast (id, started_at, ended_at, vod_url) VALUES
-			(1, now() - '12h'::interval, now() - '10h'::interval, 'https://vods.com/1'),
-			(2, now() - '6h'::interval, now() - '4h'::interval, NULL),
-			(3, now() - '2h'::interval, NULL, NULL);
+		INSERT INTO showtime.broadcast (channel_id, id, started_at, ended_at, vod_url) VALUES
+			('953753877', 1, now() - '12h'::interval, now() - '10h'::interval, 'https://vods.com/1'),
+			('953753877', 2, now() - '6h'::interval, now() - '4h'::interval, NULL),
+			('953753877', 3, now() - '2h'::interval, NULL, NULL);
 	`)
 	assert.NoError(t, err)
 
@@ -44,7 +44,7 @@ func Test_GetTapeBroadcastHistory(t *testing.T) {
 
 	// Our screening history should now reflect our state, with entries for the 4 unique
 	// tapes that we've screened
-	rows, err = q.GetBroadcastHistory(context.Background())
+	rows, err = q.GetBroadcastHistory(context.Background(), "953753877")
 	assert.NoError(t, err)
 	assert.Len(t, rows, 3)
 	assert.Equal(t, int32(1), rows[0].ID)
@@ -69,8 +69,8 @@ func Test_GetBroadcastById(t *testing.T) {
 	assert.ErrorIs(t, err, sql.ErrNoRows)
 
 	_, err = tx.Exec(`
-		INSERT INTO showtime.broadcast (id, started_at, ended_at) VALUES
-			(1, now() - '12h'::interval, now() - '10h'::interval)
+		INSERT INTO showtime.broadcast (channel_id, id, started_at, ended_at) VALUES
+			('953753877', 1, now() - '12h'::interval, now() - '10h'::interval)
 	`)
 	assert.NoError(t, err)
 
@@ -90,8 +90,8 @@ func Test_GetScreeningsByBroadcastId(t *testing.T) {
 	assert.Len(t, screenings, 0)
 
 	_, err = tx.Exec(`
-		INSERT INTO showtime.broadcast (id, started_at, ended_at) VALUES
-			(1, now() - '12h'::interval, now() - '10h'::interval)
+		INSERT INTO showtime.broadcast (channel_id, id, started_at, ended_at) VALUES
+			('953753877', 1, now() - '12h'::interval, now() - '10h'::interval)
 	`)
 	assert.NoError(t, err)
 	_, err = tx.Exec(`
@@ -116,7 +116,8 @@ func Test_GetViewerLookupForBroadcast(t *testing.T) {
 	tx := querytest.PrepareTx(t)
 	q := queries.New(tx)
 
-	_, err := tx.Exec(`INSERT INTO showtime.broadcast (id, started_at, ended_at) VALUES (
+	_, err := tx.Exec(`INSERT INTO showtime.broadcast (channel_id, id, started_at, ended_at) VALUES (
+		'953753877',
 		1,
 		now() - '12h'::interval,
 		now() - '10h'::interval
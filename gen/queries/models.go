@@ -6,11 +6,64 @@ package queries
 
 import (
 	"database/sql"
+	"encoding/json"
 	"time"
 
 	"github.com/google/uuid"
 )
 
+// Record of an alert that was sent to the stream graphics overlay (via GET /alerts), retained for a limited time so that clients which were disconnected when the alert was emitted can replay it upon reconnecting.
+type ShowtimeAlert struct {
+	// Sequential ID of the alert, sent to clients as the SSE event ID: a client that reconnects with a Last-Event-ID receives all subsequent alerts.
+	ID int64
+	// Twitch user ID of the broadcaster whose channel the alert was emitted for.
+	ChannelID string
+	// Type of alert, e.g. 'follow' or 'raid'.
+	Type string
+	// JSON-serialized payload of the alert, in the format dictated by type.
+	Data json.RawMessage
+	// Time at which the alert was emitted.
+	CreatedAt time.Time
+}
+
+// Records an alert that was held for moderation, rather than being sent straight to the stream graphics overlay, since its type is listed in ALERT_MODERATION_TYPES. The alert is only sent once it's approved by the broadcaster, or automatically after ALERT_MODERATION_TIMEOUT.
+type ShowtimeAlertModeration struct {
+	// Sequential ID of the held alert, used to approve, reject or edit it via /admin/alerts. Distinct from the ID assigned in showtime.alert once the alert is sent.
+	ID int64
+	// Twitch user ID of the broadcaster whose channel the alert was emitted for.
+	ChannelID string
+	// Type of alert, e.g. 'subscribe' or 'generated-images'.
+	Type string
+	// JSON-serialized payload of the alert, including any edits made by the broadcaster.
+	Data json.RawMessage
+	// JSON-serialized payload of the alert as originally emitted, before any edits.
+	OriginalData json.RawMessage
+	// Whether the alert is 'pending' a decision, or has been 'approved' (and sent) or 'rejected' (and discarded).
+	Status string
+	// Time at which the alert was emitted and held for moderation.
+	CreatedAt time.Time
+	// Time at which the alert was approved or rejected, if no longer pending.
+	DecidedAt sql.NullTime
+	// Display name of the user who approved or rejected the alert, or 'auto' if it was approved automatically after timing out.
+	DecidedBy sql.NullString
+}
+
+// Audit trail of every action taken on an alert that was held for moderation.
+type ShowtimeAlertModerationAction struct {
+	// Sequential ID of the action.
+	ID int64
+	// ID of the held alert that was acted upon.
+	AlertModerationID int64
+	// Type of action: 'edit', 'approve', 'reject', or 'auto-approve'.
+	Action string
+	// Display name of the user who took the action, or 'auto' for auto-approvals.
+	Actor string
+	// JSON-serialized payload of the alert as of the action: i.e. the edited payload, for edits, or the payload that was sent or discarded, for decisions.
+	Data json.RawMessage
+	// Time at which the action was taken.
+	ActedAt time.Time
+}
+
 // Record of a broadcast that occurred (or is occurring) on the GoldenVCR Twitch channel.
 type ShowtimeBroadcast struct {
 	// Serial ID used to correlate other records with this broadcast.
@@ -21,6 +74,157 @@ type ShowtimeBroadcast struct {
 	EndedAt sql.NullTime
 	// Absolute URL to a page where the recording of this broadcast can be viewed, if available.
 	VodUrl sql.NullString
+	// ID of the Twitch stream that most recently started or resumed this broadcast, as reported in the stream.online event. NULL for broadcasts recorded before this was tracked.
+	TwitchStreamID sql.NullString
+	// Type of stream that started this broadcast, as reported by Twitch: "live" or "premiere". Other stream types (e.g. "rerun") do not start broadcasts.
+	StreamType string
+	// Twitch user ID of the broadcaster whose channel this broadcast took place on.
+	ChannelID string
+}
+
+// Records the fact that a viewer redeemed a channel points reward, along with the outcome of the action we took in response.
+type ShowtimeChannelPointsRedemption struct {
+	// ID of the redemption, as assigned by Twitch.
+	RedemptionID string
+	// ID of the reward that was redeemed.
+	RewardID string
+	// ID of the viewer who redeemed the reward.
+	TwitchUserID string
+	// Text entered by the viewer when redeeming the reward, if any.
+	UserInput string
+	// Time at which the viewer redeemed the reward.
+	RedeemedAt time.Time
+	// Time at which we finished handling the redemption, if we have.
+	ResolvedAt sql.NullTime
+	// Outcome of the redemption: "FULFILLED" if the action succeeded, or "CANCELED" if it failed and the viewer's channel points should be refunded.
+	Status sql.NullString
+	// Details of the error that caused the redemption to be canceled, if any.
+	ErrorMessage sql.NullString
+}
+
+// Configures the action that should be taken when a viewer redeems a particular Twitch channel points reward.
+type ShowtimeChannelPointsReward struct {
+	// ID of the custom channel points reward, as assigned by Twitch.
+	RewardID string
+	// Action to take when the reward is redeemed: "alert" to display an alert, "credit" to credit the viewer with fun points, or "overlay" to send an event to the overlay.
+	Action string
+	// Number of fun points to credit to the viewer, if action is "credit".
+	NumPoints int32
+	// Name of the event to send to the overlay, if action is "overlay".
+	OverlayEvent string
+	// Time at which the action was first configured for this reward.
+	CreatedAt time.Time
+	// Time at which the action for this reward was last changed.
+	UpdatedAt time.Time
+}
+
+// Records the fact that the title and/or category of the Twitch channel was changed.
+type ShowtimeChannelUpdate struct {
+	// Unique ID for this channel update.
+	ID uuid.UUID
+	// ID of the broadcast that was live at the time of the change, if any.
+	BroadcastID sql.NullInt32
+	// Title of the stream as of this change.
+	Title string
+	// ID of the Twitch category (i.e. game) that the stream was in as of this change.
+	CategoryID string
+	// Name of the Twitch category (i.e. game) that the stream was in as of this change.
+	CategoryName string
+	// Time at which the change was recorded.
+	ChangedAt time.Time
+	// Twitch user ID of the broadcaster whose channel was updated.
+	ChannelID string
+}
+
+// Durable record of an EventSub notification that we've acknowledged and need to process. Notifications are written to the inbox before we respond to Twitch, then processed asynchronously, with failed attempts retried (with exponential backoff) until the notification is either handled successfully or dead-lettered.
+type ShowtimeEventsubInbox struct {
+	// ID of the EventSub message that carried this notification.
+	MessageID string
+	// JSON-encoded subscription details from the notification payload.
+	Subscription json.RawMessage
+	// JSON-encoded event data from the notification payload.
+	Event json.RawMessage
+	// Time at which the notification was received and written to the inbox.
+	CreatedAt time.Time
+	// Number of times that we've attempted to process this notification.
+	NumAttempts int32
+	// Earliest time at which the notification may next be processed.
+	NextAttemptAt time.Time
+	// If set and in the future, a worker has claimed this notification and is processing it; other workers should leave it alone until this time has elapsed.
+	LockedUntil sql.NullTime
+	// Error message from the most recent failed attempt to process the notification.
+	LastError sql.NullString
+	// Time at which the notification was successfully processed, if ever.
+	ProcessedAt sql.NullTime
+	// Time at which we gave up on processing the notification, if ever. A dead-lettered notification will not be processed again unless the broadcaster explicitly retries it.
+	DeadLetteredAt sql.NullTime
+	// Time at which the broadcaster chose to discard the dead-lettered notification, if ever.
+	DiscardedAt sql.NullTime
+}
+
+// Records the fact that we've received a particular message from the Twitch EventSub API. Twitch may redeliver a message if it doesn't receive a timely response, so we keep track of message IDs in order to ensure that each event is handled only once.
+type ShowtimeEventsubMessage struct {
+	// Unique ID of the message, as supplied in the Twitch-Eventsub-Message-Id header. Redelivered messages retain the same ID.
+	MessageID string
+	// Type of message, as supplied in the Twitch-Eventsub-Message-Type header: e.g. "notification", "webhook_callback_verification", or "revocation".
+	MessageType string
+	// Type of the EventSub subscription that produced the message, e.g. "channel.follow".
+	SubscriptionType string
+	// Time at which Twitch sent the message, as supplied in the Twitch-Eventsub-Message-Timestamp header.
+	MessageTimestamp time.Time
+	// Time at which we first received the message.
+	ReceivedAt time.Time
+}
+
+// Records a pass of the background subscription reconciler that had to take action to repair our EventSub subscriptions, or that failed. Passes that find all required subscriptions healthy are not recorded.
+type ShowtimeEventsubReconciliation struct {
+	ID uuid.UUID
+	// Time at which the reconciler began inspecting our subscriptions.
+	StartedAt time.Time
+	// Time at which the reconciler finished.
+	FinishedAt time.Time
+	// JSON array describing each subscription that the reconciler deleted or created, including the error that occurred if the operation failed.
+	Actions json.RawMessage
+	// Error that prevented the reconciler from determining which subscriptions needed to be repaired, if any.
+	Error sql.NullString
+}
+
+// Records the fact that Twitch revoked one of our EventSub subscriptions, meaning that we will no longer receive notifications for it until the subscription is recreated.
+type ShowtimeEventsubRevocation struct {
+	// ID of the EventSub message that notified us of the revocation.
+	MessageID string
+	// ID of the subscription that was revoked.
+	SubscriptionID string
+	// Type of the subscription that was revoked, e.g. "channel.follow".
+	SubscriptionType string
+	// Version of the subscription that was revoked, e.g. "2".
+	SubscriptionVersion string
+	// Reason for the revocation, as given by the subscription status: "user_removed", "authorization_revoked", "notification_failures_exceeded", or "version_removed".
+	Reason string
+	// JSON-encoded condition of the subscription that was revoked.
+	Condition json.RawMessage
+	// Time at which we were notified of the revocation.
+	RevokedAt time.Time
+	// Time at which we recreated a subscription with the same type, version, and condition as the one that was revoked, or NULL if the revocation has not yet been resolved.
+	ResolvedAt sql.NullTime
+}
+
+// Records the final results of a hype train that occurred on the Twitch channel.
+type ShowtimeHypeTrain struct {
+	// ID of the hype train, as assigned by Twitch.
+	ID string
+	// ID of the broadcast that was live when the hype train started, if any.
+	BroadcastID sql.NullInt32
+	// Final level reached by the hype train.
+	Level int32
+	// Total number of points contributed to the hype train.
+	Total int32
+	// JSON array describing the top contributors to the hype train.
+	TopContributions json.RawMessage
+	// Time at which the hype train started.
+	StartedAt time.Time
+	// Time at which the hype train ended.
+	EndedAt time.Time
 }
 
 // Record of an image that was successfully generated from a user-submitted image request. An image request may result in multiple images. Images are ordered by index, matching the array in which they were returned by the image generation API.
@@ -53,6 +257,82 @@ type ShowtimeImageRequest struct {
 	ScreeningID uuid.NullUUID
 }
 
+// Append-only record of the rules that determine how many fun points viewers are awarded in response to Twitch events. The most recently-created row contains the rules that are currently in effect; prior rows serve as an audit trail of changes.
+type ShowtimePointsRule struct {
+	// Serial ID identifying this revision of the rules.
+	ID int32
+	// JSON representation of the rules, as defined by points.Rules: awards per event type and tier, bonus multipliers, and the number of bits required for a ghost alert.
+	Rules json.RawMessage
+	// Time at which this revision of the rules was put into effect.
+	CreatedAt time.Time
+	// Name of the user who made the change.
+	CreatedBy string
+}
+
+// Records the final results of a poll that was run on the Twitch channel.
+type ShowtimePoll struct {
+	// ID of the poll, as assigned by Twitch.
+	ID string
+	// ID of the broadcast that was live when the poll started, if any.
+	BroadcastID sql.NullInt32
+	// Question that was asked in the poll.
+	Title string
+	// Status of the poll when it ended: "completed" or "terminated".
+	Status string
+	// JSON array describing each choice in the poll, along with its final vote count.
+	Choices json.RawMessage
+	// Time at which the poll started.
+	StartedAt time.Time
+	// Time at which the poll ended.
+	EndedAt time.Time
+}
+
+// Records the final results of a prediction that was run on the Twitch channel.
+type ShowtimePrediction struct {
+	// ID of the prediction, as assigned by Twitch.
+	ID string
+	// ID of the broadcast that was live when the prediction started, if any.
+	BroadcastID sql.NullInt32
+	// Title of the prediction.
+	Title string
+	// Status of the prediction when it ended: "resolved" or "canceled".
+	Status string
+	// ID of the outcome that won, if the prediction was resolved.
+	WinningOutcomeID sql.NullString
+	// JSON array describing each outcome of the prediction, along with the final number of users and channel points that were wagered on it.
+	Outcomes json.RawMessage
+	// Time at which the prediction started.
+	StartedAt time.Time
+	// Time at which the prediction ended.
+	EndedAt time.Time
+}
+
+// Records a raid, either from another channel into the GoldenVCR channel, or from the GoldenVCR channel into another channel.
+type ShowtimeRaid struct {
+	// Unique ID for this raid.
+	ID uuid.UUID
+	// ID of the EventSub message that notified us of this raid, if any; used to ensure that each raid is only recorded once, even if the notification is redelivered.
+	EventsubMessageID sql.NullString
+	// Whether the raid was 'incoming' (another channel raided us) or 'outgoing' (we raided another channel).
+	Direction string
+	// Twitch user ID of the other broadcaster: the raider, for incoming raids, or the channel we raided, for outgoing raids.
+	TwitchUserID string
+	// Twitch login of the other broadcaster, as of the time of the raid.
+	TwitchLogin string
+	// Twitch display name of the other broadcaster, as of the time of the raid.
+	TwitchDisplayName string
+	// Number of viewers who participated in the raid.
+	NumViewers int32
+	// ID of the broadcast that was live at the time of the raid, if any.
+	BroadcastID sql.NullInt32
+	// ID of the screening that was in progress at the time of the raid, if any.
+	ScreeningID uuid.NullUUID
+	// Time at which the raid was recorded.
+	RaidedAt time.Time
+	// Twitch user ID of the broadcaster on whose behalf the raid was recorded: the channel that was raided, for incoming raids, or the channel that raided, for outgoing raids.
+	ChannelID string
+}
+
 // Records the fact that a particular tape was played during a broadcast.
 type ShowtimeScreening struct {
 	// ID of the broadcast that was live at the time the screening started.
@@ -67,6 +347,58 @@ type ShowtimeScreening struct {
 	ID uuid.NullUUID
 }
 
+// Records a period of time during which a viewer was (or still is) subscribed to GoldenVCR on Twitch. A viewer has at most one ongoing subscription at a time; resubscriptions update the ongoing subscription rather than starting a new one.
+type ShowtimeSubscription struct {
+	// Serial ID of this subscription record.
+	ID int32
+	// ID of the subscribed viewer.
+	TwitchUserID string
+	// Subscription tier, as reported by Twitch: "1000", "2000", or "3000".
+	Tier string
+	// Whether the subscription was gifted to the viewer by another user.
+	IsGift bool
+	// ID of the user who gifted the subscription, if it was gifted and we were able to determine who gifted it. NULL for anonymous gifts.
+	GifterTwitchUserID sql.NullString
+	// Total number of months for which the viewer has been subscribed, as of the most recent resubscription message they shared.
+	CumulativeMonths int32
+	// Number of consecutive months for which the viewer has been subscribed, if they chose to share it in a resubscription message.
+	StreakMonths sql.NullInt32
+	// Time at which we first recorded this subscription.
+	StartedAt time.Time
+	// Time at which the subscription expired, or NULL if it is still ongoing.
+	EndedAt sql.NullTime
+}
+
+// Records the fact that we have credited (or are in the process of crediting) a viewer with fun points in response to a subscription-related EventSub notification or a channel points redemption, ensuring that each viewer is only credited once per notification or redemption, even if handling it is retried.
+type ShowtimeSubscriptionCredit struct {
+	// ID of the EventSub message, or of the channel points redemption, that prompted the credit.
+	MessageID string
+	// ID of the viewer being credited.
+	TwitchUserID string
+	// Time at which we first attempted to credit the viewer.
+	ClaimedAt time.Time
+	// Time at which the ledger accepted the credit, or NULL if it has not yet been applied.
+	CreditedAt sql.NullTime
+}
+
+// Stores the most recent OAuth user access token that a Twitch user (typically the broadcaster) has granted to our app, along with the refresh token used to renew it before it expires.
+type ShowtimeTwitchUserToken struct {
+	// ID of the Twitch user who granted the token.
+	TwitchUserID string
+	// User access token, encrypted with AES-GCM using the key configured via TWITCH_TOKEN_ENCRYPTION_KEY: the random nonce is prepended to the ciphertext.
+	AccessTokenEncrypted []byte
+	// Refresh token, encrypted in the same manner as access_token_encrypted.
+	RefreshTokenEncrypted []byte
+	// Scopes granted by the token.
+	Scopes []string
+	// Time at which the access token will expire, after which it must be refreshed.
+	ExpiresAt time.Time
+	// Time at which the user first granted a token.
+	CreatedAt time.Time
+	// Time at which the token was most recently granted or refreshed.
+	UpdatedAt time.Time
+}
+
 // Details about a user who has interacted with Golden VCR at some point, either directly via goldenvcr.com or via Twitch.
 type ShowtimeViewer struct {
 	// Text-formatted integer identifying this user in the Twitch API.
@@ -0,0 +1,37 @@
+package queries_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/golden-vcr/server-common/querytest"
+	"github.com/golden-vcr/showtime/gen/queries"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_RecordPointsRules(t *testing.T) {
+	tx := querytest.PrepareTx(t)
+	q := queries.New(tx)
+
+	// The initial rules should be seeded by the migration
+	rules, err := q.GetCurrentPointsRules(context.Background())
+	assert.NoError(t, err)
+	assert.Contains(t, string(rules), `"ghostAlertBits": 200`)
+
+	// Recording new rules should replace the current rules while preserving history
+	_, err = q.RecordPointsRules(context.Background(), queries.RecordPointsRulesParams{
+		Rules:     json.RawMessage(`{"awards":[],"bonuses":[],"ghostAlertBits":0}`),
+		CreatedBy: "Broadcaster",
+	})
+	assert.NoError(t, err)
+	rules, err = q.GetCurrentPointsRules(context.Background())
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"awards":[],"bonuses":[],"ghostAlertBits":0}`, string(rules))
+
+	history, err := q.GetPointsRulesHistory(context.Background())
+	assert.NoError(t, err)
+	assert.Len(t, history, 2)
+	assert.Equal(t, "Broadcaster", history[0].CreatedBy)
+	assert.Equal(t, "migration", history[1].CreatedBy)
+}
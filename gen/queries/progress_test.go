@@ -0,0 +1,115 @@
+package queries_test
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/golden-vcr/server-common/querytest"
+	"github.com/golden-vcr/showtime/gen/queries"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_RecordHypeTrainResult(t *testing.T) {
+	tx := querytest.PrepareTx(t)
+	q := queries.New(tx)
+
+	_, err := tx.Exec(`
+		INSERT INTO showtime.broadcast (channel_id, id, started_at, ended_at) VALUES
+			('953753877', 1, now() - '3h'::interval, now() - '2h'::interval);
+	`)
+	assert.NoError(t, err)
+
+	// A hype train that started during a broadcast should be associated with it
+	err = q.RecordHypeTrainResult(context.Background(), queries.RecordHypeTrainResultParams{
+		ChannelID:        "953753877",
+		StartedAt:        time.Now().Add(-150 * time.Minute),
+		ID:               "train-1",
+		Level:            2,
+		Total:            1200,
+		TopContributions: []byte(`[]`),
+		EndedAt:          time.Now().Add(-140 * time.Minute),
+	})
+	assert.NoError(t, err)
+
+	// A hype train that started after the broadcast should not be
+	err = q.RecordHypeTrainResult(context.Background(), queries.RecordHypeTrainResultParams{
+		ChannelID:        "953753877",
+		StartedAt:        time.Now().Add(-10 * time.Minute),
+		ID:               "train-2",
+		Level:            1,
+		Total:            300,
+		TopContributions: []byte(`[]`),
+		EndedAt:          time.Now(),
+	})
+	assert.NoError(t, err)
+	querytest.AssertCount(t, tx, 1, `
+		SELECT COUNT(*) FROM showtime.hype_train
+			WHERE id = 'train-2' AND broadcast_id IS NULL
+	`)
+
+	rows, err := q.GetHypeTrainsByBroadcastId(context.Background(), 1)
+	assert.NoError(t, err)
+	assert.Len(t, rows, 1)
+	assert.Equal(t, int32(2), rows[0].Level)
+}
+
+func Test_RecordPollResult(t *testing.T) {
+	tx := querytest.PrepareTx(t)
+	q := queries.New(tx)
+
+	_, err := tx.Exec(`
+		INSERT INTO showtime.broadcast (channel_id, id, started_at) VALUES ('953753877', 1, now() - '1h'::interval);
+	`)
+	assert.NoError(t, err)
+
+	params := queries.RecordPollResultParams{
+		ChannelID: "953753877",
+		StartedAt: time.Now().Add(-5 * time.Minute),
+		ID:        "poll-1",
+		Title:     "Which tape next?",
+		Status:    "completed",
+		Choices:   []byte(`[{"id":"a","title":"Tape 44","numVotes":12}]`),
+		EndedAt:   time.Now(),
+	}
+	err = q.RecordPollResult(context.Background(), params)
+	assert.NoError(t, err)
+
+	// Recording the same poll again should update it in place
+	params.Status = "terminated"
+	err = q.RecordPollResult(context.Background(), params)
+	assert.NoError(t, err)
+
+	rows, err := q.GetPollsByBroadcastId(context.Background(), 1)
+	assert.NoError(t, err)
+	assert.Len(t, rows, 1)
+	assert.Equal(t, "terminated", rows[0].Status)
+}
+
+func Test_RecordPredictionResult(t *testing.T) {
+	tx := querytest.PrepareTx(t)
+	q := queries.New(tx)
+
+	_, err := tx.Exec(`
+		INSERT INTO showtime.broadcast (channel_id, id, started_at) VALUES ('953753877', 1, now() - '1h'::interval);
+	`)
+	assert.NoError(t, err)
+
+	err = q.RecordPredictionResult(context.Background(), queries.RecordPredictionResultParams{
+		ChannelID:        "953753877",
+		StartedAt:        time.Now().Add(-5 * time.Minute),
+		ID:               "prediction-1",
+		Title:            "Will the tape be watchable?",
+		Status:           "resolved",
+		WinningOutcomeID: sql.NullString{Valid: true, String: "y"},
+		Outcomes:         []byte(`[{"id":"y","title":"Yes","color":"blue","numUsers":3,"numChannelPoints":500}]`),
+		EndedAt:          time.Now(),
+	})
+	assert.NoError(t, err)
+
+	rows, err := q.GetPredictionsByBroadcastId(context.Background(), 1)
+	assert.NoError(t, err)
+	assert.Len(t, rows, 1)
+	assert.Equal(t, "y", rows[0].WinningOutcomeID.String)
+}
@@ -0,0 +1,77 @@
+package queries_test
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/golden-vcr/server-common/querytest"
+	"github.com/golden-vcr/showtime/gen/queries"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_RecordRaid(t *testing.T) {
+	tx := querytest.PrepareTx(t)
+	q := queries.New(tx)
+
+	_, err := tx.Exec(`
+		INSERT INTO showtime.broadcast (channel_id, id, started_at) VALUES ('953753877', 1, now() - '1h'::interval);
+		INSERT INTO showtime.screening (broadcast_id, tape_id, started_at) VALUES (1, 44, now() - '10m'::interval);
+	`)
+	assert.NoError(t, err)
+
+	params := queries.RecordRaidParams{
+		ChannelID:         "953753877",
+		EventsubMessageID: sql.NullString{String: "message-1", Valid: true},
+		Direction:         "incoming",
+		TwitchUserID:      "1234",
+		TwitchLogin:       "friend",
+		TwitchDisplayName: "Friend",
+		NumViewers:        30,
+	}
+	err = q.RecordRaid(context.Background(), params)
+	assert.NoError(t, err)
+
+	// Recording the same message again should have no effect
+	err = q.RecordRaid(context.Background(), params)
+	assert.NoError(t, err)
+
+	querytest.AssertCount(t, tx, 1, `
+		SELECT COUNT(*) FROM showtime.raid
+			JOIN showtime.screening ON screening.id = raid.screening_id
+			WHERE raid.direction = 'incoming'
+			AND raid.twitch_user_id = '1234'
+			AND raid.num_viewers = 30
+			AND raid.broadcast_id = 1
+			AND screening.tape_id = 44
+	`)
+}
+
+func Test_GetRaidSummaryByBroadcaster(t *testing.T) {
+	tx := querytest.PrepareTx(t)
+	q := queries.New(tx)
+
+	_, err := tx.Exec(`
+		INSERT INTO showtime.raid (channel_id, direction, twitch_user_id, twitch_login, twitch_display_name, num_viewers, raided_at) VALUES
+			('953753877', 'incoming', '1234', 'friend', 'friend', 10, '1997-09-01 12:00:00+00'),
+			('953753877', 'incoming', '1234', 'friend', 'Friend', 20, '1997-09-02 12:00:00+00'),
+			('953753877', 'outgoing', '1234', 'friend', 'Friend', 5, '1997-09-03 12:00:00+00'),
+			('953753877', 'incoming', '5678', 'other', 'Other', 3, '1997-09-01 18:00:00+00')
+	`)
+	assert.NoError(t, err)
+
+	rows, err := q.GetRaidSummaryByBroadcaster(context.Background(), "953753877")
+	assert.NoError(t, err)
+	assert.Len(t, rows, 2)
+
+	assert.Equal(t, "1234", rows[0].TwitchUserID)
+	assert.Equal(t, "Friend", rows[0].TwitchDisplayName)
+	assert.Equal(t, int64(2), rows[0].NumIncomingRaids)
+	assert.Equal(t, int32(30), rows[0].NumIncomingViewers)
+	assert.Equal(t, int64(1), rows[0].NumOutgoingRaids)
+	assert.Equal(t, int32(5), rows[0].NumOutgoingViewers)
+
+	assert.Equal(t, "5678", rows[1].TwitchUserID)
+	assert.Equal(t, int64(0), rows[1].NumOutgoingRaids)
+	assert.Equal(t, int32(0), rows[1].NumOutgoingViewers)
+}
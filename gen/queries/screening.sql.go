@@ -22,6 +22,7 @@ select
 from showtime.broadcast
 join showtime.screening
     on screening.broadcast_id = broadcast.id
+where broadcast.channel_id = $1
 order by screening.started_at desc, broadcast.started_at desc
 limit 1
 `
@@ -33,8 +34,8 @@ type GetCurrentScreeningRow struct {
 	EndedAt   sql.NullTime
 }
 
-func (q *Queries) GetCurrentScreening(ctx context.Context) (GetCurrentScreeningRow, error) {
-	row := q.db.QueryRowContext(ctx, getCurrentScreening)
+func (q *Queries) GetCurrentScreening(ctx context.Context, channelID string) (GetCurrentScreeningRow, error) {
+	row := q.db.QueryRowContext(ctx, getCurrentScreening, channelID)
 	var i GetCurrentScreeningRow
 	err := row.Scan(
 		&i.ID,
@@ -69,6 +70,78 @@ func (q *Queries) GetMostRecentScreening(ctx context.Context, broadcastID int32)
 	return i, err
 }
 
+const getScreeningInProgressAt = `-- name: GetScreeningInProgressAt :one
+select
+    screening.id::uuid as id,
+    screening.tape_id,
+    screening.started_at,
+    screening.ended_at
+from showtime.screening
+where screening.broadcast_id = $1
+    and screening.started_at < $2
+    and (screening.ended_at is null or screening.ended_at > $2)
+order by screening.started_at desc
+limit 1
+`
+
+type GetScreeningInProgressAtParams struct {
+	BroadcastID int32
+	At          time.Time
+}
+
+type GetScreeningInProgressAtRow struct {
+	ID        uuid.UUID
+	TapeID    int32
+	StartedAt time.Time
+	EndedAt   sql.NullTime
+}
+
+func (q *Queries) GetScreeningInProgressAt(ctx context.Context, arg GetScreeningInProgressAtParams) (GetScreeningInProgressAtRow, error) {
+	row := q.db.QueryRowContext(ctx, getScreeningInProgressAt, arg.BroadcastID, arg.At)
+	var i GetScreeningInProgressAtRow
+	err := row.Scan(
+		&i.ID,
+		&i.TapeID,
+		&i.StartedAt,
+		&i.EndedAt,
+	)
+	return i, err
+}
+
+const reassignScreeningImageRequests = `-- name: ReassignScreeningImageRequests :exec
+update showtime.image_request set screening_id = $1
+where image_request.screening_id = $2
+    and image_request.created_at >= $3
+`
+
+type ReassignScreeningImageRequestsParams struct {
+	ToScreeningID   uuid.NullUUID
+	FromScreeningID uuid.NullUUID
+	Since           time.Time
+}
+
+func (q *Queries) ReassignScreeningImageRequests(ctx context.Context, arg ReassignScreeningImageRequestsParams) error {
+	_, err := q.db.ExecContext(ctx, reassignScreeningImageRequests, arg.ToScreeningID, arg.FromScreeningID, arg.Since)
+	return err
+}
+
+const reassignScreeningRaids = `-- name: ReassignScreeningRaids :exec
+update showtime.raid set screening_id = $1
+where raid.screening_id = $2
+    and raid.raided_at >= $3
+`
+
+type ReassignScreeningRaidsParams struct {
+	ToScreeningID   uuid.NullUUID
+	FromScreeningID uuid.NullUUID
+	Since           time.Time
+}
+
+func (q *Queries) ReassignScreeningRaids(ctx context.Context, arg ReassignScreeningRaidsParams) error {
+	_, err := q.db.ExecContext(ctx, reassignScreeningRaids, arg.ToScreeningID, arg.FromScreeningID, arg.Since)
+	return err
+}
+
 const recordScreeningEnded = `-- name: RecordScreeningEnded :exec
 update showtime.screening set ended_at = now()
 where screening.broadcast_id = $1
@@ -80,6 +153,40 @@ func (q *Queries) RecordScreeningEnded(ctx context.Context, broadcastID int32) e
 	return err
 }
 
+const recordScreeningSplit = `-- name: RecordScreeningSplit :one
+insert into showtime.screening (
+    broadcast_id,
+    tape_id,
+    started_at,
+    ended_at
+) values (
+    $1,
+    $2,
+    $3,
+    $4
+)
+returning screening.id::uuid as id
+`
+
+type RecordScreeningSplitParams struct {
+	BroadcastID int32
+	TapeID      int32
+	StartedAt   time.Time
+	EndedAt     sql.NullTime
+}
+
+func (q *Queries) RecordScreeningSplit(ctx context.Context, arg RecordScreeningSplitParams) (uuid.UUID, error) {
+	row := q.db.QueryRowContext(ctx, recordScreeningSplit,
+		arg.BroadcastID,
+		arg.TapeID,
+		arg.StartedAt,
+		arg.EndedAt,
+	)
+	var id uuid.UUID
+	err := row.Scan(&id)
+	return id, err
+}
+
 const recordScreeningStarted = `-- name: RecordScreeningStarted :exec
 insert into showtime.screening (
     broadcast_id,
@@ -101,3 +208,33 @@ func (q *Queries) RecordScreeningStarted(ctx context.Context, arg RecordScreenin
 	_, err := q.db.ExecContext(ctx, recordScreeningStarted, arg.BroadcastID, arg.TapeID)
 	return err
 }
+
+const renotifyMostRecentScreening = `-- name: RenotifyMostRecentScreening :exec
+update showtime.screening set ended_at = screening.ended_at
+where screening.id = (
+    select most_recent_screening.id from showtime.screening as most_recent_screening
+    where most_recent_screening.broadcast_id = $1
+    order by most_recent_screening.started_at desc
+    limit 1
+)
+`
+
+func (q *Queries) RenotifyMostRecentScreening(ctx context.Context, broadcastID int32) error {
+	_, err := q.db.ExecContext(ctx, renotifyMostRecentScreening, broadcastID)
+	return err
+}
+
+const setScreeningEndedAt = `-- name: SetScreeningEndedAt :exec
+update showtime.screening set ended_at = $1
+where screening.id = $2
+`
+
+type SetScreeningEndedAtParams struct {
+	EndedAt     sql.NullTime
+	ScreeningID uuid.NullUUID
+}
+
+func (q *Queries) SetScreeningEndedAt(ctx context.Context, arg SetScreeningEndedAtParams) error {
+	_, err := q.db.ExecContext(ctx, setScreeningEndedAt, arg.EndedAt, arg.ScreeningID)
+	return err
+}
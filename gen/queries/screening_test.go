@@ -4,6 +4,7 @@ import (
 	"context"
 	"database/sql"
 	"testing"
+	"time"
 
 	"github.com/golden-vcr/server-common/querytest"
 	"github.com/golden-vcr/showtime/gen/queries"
@@ -18,7 +19,7 @@ func Test_GetMostRecentScreening(t *testing.T) {
 	querytest.AssertCount(t, tx, 0, "SELECT COUNT(*) FROM showtime.screening")
 
 	var broadcastId int32
-	rows, err := tx.Query("INSERT INTO showtime.broadcast (started_at) VALUES (now() - '1h'::interval) RETURNING id")
+	rows, err := tx.Query("INSERT INTO showtime.broadcast (channel_id, started_at) VALUES ('953753877', now() - '1h'::interval) RETURNING id")
 	assert.NoError(t, err)
 	assert.True(t, rows.Next())
 	err = rows.Scan(&broadcastId)
@@ -59,7 +60,11 @@ func Test_RecordScreeningStarted(t *testing.T) {
 	querytest.AssertCount(t, tx, 0, "SELECT COUNT(*) FROM showtime.broadcast")
 	querytest.AssertCount(t, tx, 0, "SELECT COUNT(*) FROM showtime.screening")
 
-	broadcastId, err := q.RecordBroadcastStarted(context.Background())
+	broadcastId, err := q.RecordBroadcastStarted(context.Background(), queries.RecordBroadcastStartedParams{
+		ChannelID:  "953753877",
+		StartedAt:  time.Now(),
+		StreamType: "live",
+	})
 	assert.NoError(t, err)
 
 	err = q.RecordScreeningStarted(context.Background(), queries.RecordScreeningStartedParams{
@@ -82,7 +87,11 @@ func Test_RecordScreeningEnded(t *testing.T) {
 
 	querytest.AssertCount(t, tx, 0, "SELECT COUNT(*) FROM showtime.screening")
 
-	broadcastId, err := q.RecordBroadcastStarted(context.Background())
+	broadcastId, err := q.RecordBroadcastStarted(context.Background(), queries.RecordBroadcastStartedParams{
+		ChannelID:  "953753877",
+		StartedAt:  time.Now(),
+		StreamType: "live",
+	})
 	assert.NoError(t, err)
 
 	err = q.RecordScreeningStarted(context.Background(), queries.RecordScreeningStartedParams{
@@ -107,3 +116,62 @@ func Test_RecordScreeningEnded(t *testing.T) {
 			AND ended_at IS NOT NULL
 	`, broadcastId)
 }
+
+func Test_GetScreeningInProgressAt(t *testing.T) {
+	tx := querytest.PrepareTx(t)
+	q := queries.New(tx)
+
+	_, err := tx.Exec(`
+		INSERT INTO showtime.broadcast (channel_id, id, started_at) VALUES ('953753877', 1, '1997-09-01 12:00:00+00');
+		INSERT INTO showtime.screening (broadcast_id, tape_id, started_at, ended_at) VALUES
+			(1, 10, '1997-09-01 12:00:00+00', '1997-09-01 13:00:00+00'),
+			(1, 20, '1997-09-01 13:00:00+00', NULL);
+	`)
+	assert.NoError(t, err)
+
+	screening, err := q.GetScreeningInProgressAt(context.Background(), queries.GetScreeningInProgressAtParams{
+		BroadcastID: 1,
+		At:          time.Date(1997, 9, 1, 12, 30, 0, 0, time.UTC),
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, int32(10), screening.TapeID)
+
+	screening, err = q.GetScreeningInProgressAt(context.Background(), queries.GetScreeningInProgressAtParams{
+		BroadcastID: 1,
+		At:          time.Date(1997, 9, 1, 14, 0, 0, 0, time.UTC),
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, int32(20), screening.TapeID)
+
+	_, err = q.GetScreeningInProgressAt(context.Background(), queries.GetScreeningInProgressAtParams{
+		BroadcastID: 1,
+		At:          time.Date(1997, 9, 1, 11, 0, 0, 0, time.UTC),
+	})
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+}
+
+func Test_RecordScreeningSplit(t *testing.T) {
+	tx := querytest.PrepareTx(t)
+	q := queries.New(tx)
+
+	_, err := tx.Exec(`
+		INSERT INTO showtime.broadcast (channel_id, id, started_at) VALUES ('953753877', 1, '1997-09-01 12:00:00+00')
+	`)
+	assert.NoError(t, err)
+
+	screeningId, err := q.RecordScreeningSplit(context.Background(), queries.RecordScreeningSplitParams{
+		BroadcastID: 1,
+		TapeID:      10,
+		StartedAt:   time.Date(1997, 9, 1, 13, 0, 0, 0, time.UTC),
+	})
+	assert.NoError(t, err)
+
+	querytest.AssertCount(t, tx, 1, `
+		SELECT COUNT(*) FROM showtime.screening
+			WHERE id = $1
+			AND broadcast_id = 1
+			AND tape_id = 10
+			AND started_at = '1997-09-01 13:00:00+00'
+			AND ended_at IS NULL
+	`, screeningId)
+}
@@ -0,0 +1,128 @@
+package queries_test
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/golden-vcr/server-common/querytest"
+	"github.com/golden-vcr/showtime/gen/queries"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_ClaimSubscriptionCredit(t *testing.T) {
+	tx := querytest.PrepareTx(t)
+	q := queries.New(tx)
+
+	params := queries.ClaimSubscriptionCreditParams{
+		MessageID:    "message-1",
+		TwitchUserID: "1234",
+	}
+	creditedAt, err := q.ClaimSubscriptionCredit(context.Background(), params)
+	assert.NoError(t, err)
+	assert.False(t, creditedAt.Valid)
+
+	// Claiming the same credit again before it's applied should allow a retry
+	creditedAt, err = q.ClaimSubscriptionCredit(context.Background(), params)
+	assert.NoError(t, err)
+	assert.False(t, creditedAt.Valid)
+
+	// Once applied, subsequent claims should indicate that the credit is done
+	err = q.RecordSubscriptionCreditApplied(context.Background(), queries.RecordSubscriptionCreditAppliedParams{
+		MessageID:    "message-1",
+		TwitchUserID: "1234",
+	})
+	assert.NoError(t, err)
+	creditedAt, err = q.ClaimSubscriptionCredit(context.Background(), params)
+	assert.NoError(t, err)
+	assert.True(t, creditedAt.Valid)
+
+	// Other users credited in response to the same message are tracked separately
+	creditedAt, err = q.ClaimSubscriptionCredit(context.Background(), queries.ClaimSubscriptionCreditParams{
+		MessageID:    "message-1",
+		TwitchUserID: "5678",
+	})
+	assert.NoError(t, err)
+	assert.False(t, creditedAt.Valid)
+	querytest.AssertCount(t, tx, 2, `
+		SELECT COUNT(*) FROM showtime.subscription_credit WHERE message_id = 'message-1'
+	`)
+}
+
+func Test_RecordSubscriptionStarted(t *testing.T) {
+	tx := querytest.PrepareTx(t)
+	q := queries.New(tx)
+
+	for _, id := range []string{"1234", "5678"} {
+		err := q.RecordViewerIdentity(context.Background(), queries.RecordViewerIdentityParams{
+			TwitchUserID:      id,
+			TwitchDisplayName: "user-" + id,
+		})
+		assert.NoError(t, err)
+	}
+
+	// Starting a gifted subscription should record an ongoing subscription with no
+	// gifter yet
+	err := q.RecordSubscriptionStarted(context.Background(), queries.RecordSubscriptionStartedParams{
+		TwitchUserID: "1234",
+		Tier:         "1000",
+		IsGift:       true,
+	})
+	assert.NoError(t, err)
+	querytest.AssertCount(t, tx, 1, `
+		SELECT COUNT(*) FROM showtime.subscription
+			WHERE twitch_user_id = '1234' AND is_gift AND gifter_twitch_user_id IS NULL
+			AND ended_at IS NULL
+	`)
+
+	// Recording the start of the same subscription again should not create a duplicate
+	err = q.RecordSubscriptionStarted(context.Background(), queries.RecordSubscriptionStartedParams{
+		TwitchUserID: "1234",
+		Tier:         "1000",
+		IsGift:       true,
+	})
+	assert.NoError(t, err)
+	querytest.AssertCount(t, tx, 1, "SELECT COUNT(*) FROM showtime.subscription")
+
+	// Attributing a gifter should update the ongoing subscription
+	err = q.RecordSubscriptionGifter(context.Background(), queries.RecordSubscriptionGifterParams{
+		GifterTwitchUserID: sql.NullString{Valid: true, String: "5678"},
+		TwitchUserIds:      []string{"1234"},
+	})
+	assert.NoError(t, err)
+
+	// A resub message should update the tenure of the ongoing subscription
+	err = q.RecordSubscriptionMessage(context.Background(), queries.RecordSubscriptionMessageParams{
+		TwitchUserID:     "1234",
+		Tier:             "1000",
+		CumulativeMonths: 2,
+		StreakMonths:     sql.NullInt32{Valid: true, Int32: 2},
+	})
+	assert.NoError(t, err)
+	querytest.AssertCount(t, tx, 1, "SELECT COUNT(*) FROM showtime.subscription")
+
+	rows, err := q.GetViewerSubscriptions(context.Background(), "1234")
+	assert.NoError(t, err)
+	assert.Len(t, rows, 1)
+	assert.Equal(t, "user-5678", rows[0].GifterTwitchDisplayName.String)
+	assert.Equal(t, int32(2), rows[0].CumulativeMonths)
+	assert.Equal(t, int32(2), rows[0].StreakMonths.Int32)
+	assert.False(t, rows[0].EndedAt.Valid)
+
+	// Once ended, a new subscription should be recorded separately
+	err = q.RecordSubscriptionEnded(context.Background(), "1234")
+	assert.NoError(t, err)
+	err = q.RecordSubscriptionStarted(context.Background(), queries.RecordSubscriptionStartedParams{
+		TwitchUserID: "1234",
+		Tier:         "2000",
+		IsGift:       false,
+	})
+	assert.NoError(t, err)
+	rows, err = q.GetViewerSubscriptions(context.Background(), "1234")
+	assert.NoError(t, err)
+	assert.Len(t, rows, 2)
+	querytest.AssertCount(t, tx, 1, `
+		SELECT COUNT(*) FROM showtime.subscription
+			WHERE twitch_user_id = '1234' AND ended_at IS NULL AND tier = '2000'
+	`)
+}
@@ -0,0 +1,53 @@
+package queries_test
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/golden-vcr/server-common/querytest"
+	"github.com/golden-vcr/showtime/gen/queries"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_TwitchUserToken(t *testing.T) {
+	tx := querytest.PrepareTx(t)
+	q := queries.New(tx)
+
+	// Before a token has been stored, we should get no rows
+	_, err := q.GetTwitchUserToken(context.Background(), "1234")
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+
+	// Storing a token should make it available
+	expiresAt := time.Date(1997, 9, 1, 16, 0, 0, 0, time.UTC)
+	err = q.StoreTwitchUserToken(context.Background(), queries.StoreTwitchUserTokenParams{
+		TwitchUserID:          "1234",
+		AccessTokenEncrypted:  []byte("access-1"),
+		RefreshTokenEncrypted: []byte("refresh-1"),
+		Scopes:                []string{"bits:read", "channel:read:subscriptions"},
+		ExpiresAt:             expiresAt,
+	})
+	assert.NoError(t, err)
+	row, err := q.GetTwitchUserToken(context.Background(), "1234")
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("access-1"), row.AccessTokenEncrypted)
+	assert.Equal(t, []byte("refresh-1"), row.RefreshTokenEncrypted)
+	assert.Equal(t, []string{"bits:read", "channel:read:subscriptions"}, row.Scopes)
+	assert.True(t, expiresAt.Equal(row.ExpiresAt))
+
+	// Storing a new token for the same user should replace the old one
+	err = q.StoreTwitchUserToken(context.Background(), queries.StoreTwitchUserTokenParams{
+		TwitchUserID:          "1234",
+		AccessTokenEncrypted:  []byte("access-2"),
+		RefreshTokenEncrypted: []byte("refresh-2"),
+		Scopes:                []string{"bits:read"},
+		ExpiresAt:             expiresAt.Add(4 * time.Hour),
+	})
+	assert.NoError(t, err)
+	row, err = q.GetTwitchUserToken(context.Background(), "1234")
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("access-2"), row.AccessTokenEncrypted)
+	assert.Equal(t, []string{"bits:read"}, row.Scopes)
+	querytest.AssertCount(t, tx, 1, "SELECT COUNT(*) FROM showtime.twitch_user_token")
+}
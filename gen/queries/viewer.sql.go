@@ -7,8 +7,38 @@ package queries
 
 import (
 	"context"
+	"database/sql"
 )
 
+const getViewer = `-- name: GetViewer :one
+select
+    viewer.twitch_user_id,
+    viewer.twitch_display_name,
+    viewer.first_followed_at,
+    viewer.first_subscribed_at
+from showtime.viewer
+where viewer.twitch_user_id = $1
+`
+
+type GetViewerRow struct {
+	TwitchUserID      string
+	TwitchDisplayName string
+	FirstFollowedAt   sql.NullTime
+	FirstSubscribedAt sql.NullTime
+}
+
+func (q *Queries) GetViewer(ctx context.Context, twitchUserID string) (GetViewerRow, error) {
+	row := q.db.QueryRowContext(ctx, getViewer, twitchUserID)
+	var i GetViewerRow
+	err := row.Scan(
+		&i.TwitchUserID,
+		&i.TwitchDisplayName,
+		&i.FirstFollowedAt,
+		&i.FirstSubscribedAt,
+	)
+	return i, err
+}
+
 const recordViewerFollow = `-- name: RecordViewerFollow :exec
 insert into showtime.viewer (
     twitch_user_id,
@@ -2,6 +2,7 @@ package queries_test
 
 import (
 	"context"
+	"database/sql"
 	"testing"
 
 	"github.com/golden-vcr/server-common/querytest"
@@ -73,3 +74,23 @@ func Test_RecordViewerFollow(t *testing.T) {
 	// We should end up with 1 viewer record
 	querytest.AssertCount(t, tx, 1, "SELECT COUNT(*) FROM showtime.viewer")
 }
+
+func Test_GetViewer(t *testing.T) {
+	tx := querytest.PrepareTx(t)
+	q := queries.New(tx)
+
+	_, err := q.GetViewer(context.Background(), "1234")
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+
+	err = q.RecordViewerSubscribe(context.Background(), queries.RecordViewerSubscribeParams{
+		TwitchUserID:      "1234",
+		TwitchDisplayName: "bungus",
+	})
+	assert.NoError(t, err)
+
+	viewer, err := q.GetViewer(context.Background(), "1234")
+	assert.NoError(t, err)
+	assert.Equal(t, "bungus", viewer.TwitchDisplayName)
+	assert.False(t, viewer.FirstFollowedAt.Valid)
+	assert.True(t, viewer.FirstSubscribedAt.Valid)
+}
@@ -0,0 +1,225 @@
+package admin
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/golden-vcr/auth"
+	"github.com/golden-vcr/showtime/gen/queries"
+	"github.com/golden-vcr/showtime/internal/alerts"
+	"github.com/gorilla/mux"
+)
+
+// ModeratedAlert is an alert that has been held for moderation, along with the
+// broadcaster's decision, if any
+type ModeratedAlert struct {
+	Id           int64                  `json:"id"`
+	ChannelId    string                 `json:"channelId"`
+	Type         string                 `json:"type"`
+	Data         json.RawMessage        `json:"data"`
+	OriginalData json.RawMessage        `json:"originalData"`
+	Status       string                 `json:"status"`
+	CreatedAt    time.Time              `json:"createdAt"`
+	DecidedAt    *time.Time             `json:"decidedAt,omitempty"`
+	DecidedBy    string                 `json:"decidedBy,omitempty"`
+	Actions      []ModeratedAlertAction `json:"actions,omitempty"`
+}
+
+// ModeratedAlertAction is a single entry in the audit trail of actions taken on an
+// alert that has been held for moderation
+type ModeratedAlertAction struct {
+	Action  string          `json:"action"`
+	Actor   string          `json:"actor"`
+	Data    json.RawMessage `json:"data"`
+	ActedAt time.Time       `json:"actedAt"`
+}
+
+// EditModeratedAlertRequest is the payload accepted by PATCH /alerts/:id
+type EditModeratedAlertRequest struct {
+	Text string `json:"text"`
+}
+
+func (s *Server) handleGetModeratedAlerts(res http.ResponseWriter, req *http.Request) {
+	// List pending alerts unless the caller asks for alerts with another status
+	status := req.URL.Query().Get("status")
+	if status == "" {
+		status = alerts.ModerationStatusPending
+	}
+	switch status {
+	case alerts.ModerationStatusPending, alerts.ModerationStatusApproved, alerts.ModerationStatusRejected:
+	default:
+		http.Error(res, "status must be one of 'pending', 'approved', or 'rejected'", http.StatusBadRequest)
+		return
+	}
+
+	rows, err := s.q.GetModeratedAlerts(req.Context(), status)
+	if err != nil {
+		http.Error(res, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	result := make([]ModeratedAlert, 0, len(rows))
+	for _, row := range rows {
+		result = append(result, newModeratedAlert(queries.GetModeratedAlertRow(row)))
+	}
+	if err := json.NewEncoder(res).Encode(result); err != nil {
+		http.Error(res, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+func (s *Server) handleGetModeratedAlert(res http.ResponseWriter, req *http.Request) {
+	id, ok := parseModeratedAlertId(res, req)
+	if !ok {
+		return
+	}
+	s.writeModeratedAlert(res, req, id)
+}
+
+func (s *Server) handleEditModeratedAlert(res http.ResponseWriter, req *http.Request) {
+	id, ok := parseModeratedAlertId(res, req)
+	if !ok {
+		return
+	}
+	actor, ok := identifyActor(res, req)
+	if !ok {
+		return
+	}
+
+	var payload EditModeratedAlertRequest
+	if err := json.NewDecoder(req.Body).Decode(&payload); err != nil {
+		http.Error(res, "invalid request body", http.StatusBadRequest)
+		return
+	}
+	if err := s.moderator.Edit(req.Context(), id, payload.Text, actor); err != nil {
+		writeModerationError(res, err)
+		return
+	}
+	s.writeModeratedAlert(res, req, id)
+}
+
+func (s *Server) handleApproveModeratedAlert(res http.ResponseWriter, req *http.Request) {
+	id, ok := parseModeratedAlertId(res, req)
+	if !ok {
+		return
+	}
+	actor, ok := identifyActor(res, req)
+	if !ok {
+		return
+	}
+	if err := s.moderator.Approve(req.Context(), id, actor); err != nil {
+		writeModerationError(res, err)
+		return
+	}
+	res.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) handleRejectModeratedAlert(res http.ResponseWriter, req *http.Request) {
+	id, ok := parseModeratedAlertId(res, req)
+	if !ok {
+		return
+	}
+	actor, ok := identifyActor(res, req)
+	if !ok {
+		return
+	}
+	if err := s.moderator.Reject(req.Context(), id, actor); err != nil {
+		writeModerationError(res, err)
+		return
+	}
+	res.WriteHeader(http.StatusNoContent)
+}
+
+// writeModeratedAlert responds with the moderated alert that has the given ID,
+// including its full audit trail
+func (s *Server) writeModeratedAlert(res http.ResponseWriter, req *http.Request, id int64) {
+	row, err := s.q.GetModeratedAlert(req.Context(), id)
+	if errors.Is(err, sql.ErrNoRows) {
+		http.Error(res, "no such alert", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(res, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	actionRows, err := s.q.GetModeratedAlertActions(req.Context(), id)
+	if err != nil {
+		http.Error(res, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	alert := newModeratedAlert(row)
+	alert.Actions = make([]ModeratedAlertAction, 0, len(actionRows))
+	for _, actionRow := range actionRows {
+		alert.Actions = append(alert.Actions, ModeratedAlertAction{
+			Action:  actionRow.Action,
+			Actor:   actionRow.Actor,
+			Data:    actionRow.Data,
+			ActedAt: actionRow.ActedAt,
+		})
+	}
+	if err := json.NewEncoder(res).Encode(alert); err != nil {
+		http.Error(res, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+func newModeratedAlert(row queries.GetModeratedAlertRow) ModeratedAlert {
+	alert := ModeratedAlert{
+		Id:           row.ID,
+		ChannelId:    row.ChannelID,
+		Type:         row.Type,
+		Data:         row.Data,
+		OriginalData: row.OriginalData,
+		Status:       row.Status,
+		CreatedAt:    row.CreatedAt,
+	}
+	if row.DecidedAt.Valid {
+		alert.DecidedAt = &row.DecidedAt.Time
+	}
+	if row.DecidedBy.Valid {
+		alert.DecidedBy = row.DecidedBy.String
+	}
+	return alert
+}
+
+// identifyActor returns the display name of the user who's making a request, for the
+// audit trail
+func identifyActor(res http.ResponseWriter, req *http.Request) (string, bool) {
+	claims, err := auth.GetClaims(req)
+	if err != nil {
+		http.Error(res, err.Error(), http.StatusInternalServerError)
+		return "", false
+	}
+	if claims.User == nil {
+		http.Error(res, "failed to identify user", http.StatusInternalServerError)
+		return "", false
+	}
+	return claims.User.DisplayName, true
+}
+
+func parseModeratedAlertId(res http.ResponseWriter, req *http.Request) (int64, bool) {
+	idStr, ok := mux.Vars(req)["id"]
+	if !ok || idStr == "" {
+		http.Error(res, "failed to parse 'id' from URL", http.StatusInternalServerError)
+		return 0, false
+	}
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		http.Error(res, "alert ID must be an integer", http.StatusBadRequest)
+		return 0, false
+	}
+	return id, true
+}
+
+func writeModerationError(res http.ResponseWriter, err error) {
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, alerts.ErrNotPending):
+		status = http.StatusNotFound
+	case errors.Is(err, alerts.ErrNoEditableText):
+		status = http.StatusBadRequest
+	}
+	http.Error(res, err.Error(), status)
+}
@@ -0,0 +1,332 @@
+package admin
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/golden-vcr/showtime/gen/queries"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+var (
+	errNoSuchBroadcast       = errors.New("no such broadcast")
+	errNoNextBroadcast       = errors.New("no subsequent broadcast to merge")
+	errBroadcastEnded        = errors.New("broadcast has already ended")
+	errBroadcastNotEnded     = errors.New("broadcast has not ended")
+	errNotMostRecent         = errors.New("only the most recent broadcast may be resumed")
+	errSplitTimeOutOfBounds  = errors.New("split time must fall between the start and end of the broadcast")
+	errSplitTimeInFuture     = errors.New("split time may not be in the future")
+	errSplitTimeNotSpecified = errors.New("'at' is required")
+)
+
+// SplitBroadcastRequest is the payload for POST /broadcasts/:id/split
+type SplitBroadcastRequest struct {
+	At time.Time `json:"at"`
+}
+
+// SplitBroadcastResult is returned from POST /broadcasts/:id/split, identifying the new
+// broadcast that covers everything from the split time onward
+type SplitBroadcastResult struct {
+	BroadcastId int `json:"broadcastId"`
+}
+
+func (s *Server) handleMergeBroadcast(res http.ResponseWriter, req *http.Request) {
+	broadcastId, ok := parseBroadcastId(res, req)
+	if !ok {
+		return
+	}
+
+	err := s.inTx(req.Context(), func(q *queries.Queries) error {
+		return mergeBroadcast(req.Context(), q, broadcastId)
+	})
+	if err != nil {
+		writeBroadcastEditError(res, err)
+		return
+	}
+	res.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) handleSplitBroadcast(res http.ResponseWriter, req *http.Request) {
+	broadcastId, ok := parseBroadcastId(res, req)
+	if !ok {
+		return
+	}
+
+	var payload SplitBroadcastRequest
+	if err := json.NewDecoder(req.Body).Decode(&payload); err != nil {
+		http.Error(res, "invalid request body", http.StatusBadRequest)
+		return
+	}
+	if payload.At.IsZero() {
+		http.Error(res, errSplitTimeNotSpecified.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var newBroadcastId int32
+	err := s.inTx(req.Context(), func(q *queries.Queries) error {
+		id, err := splitBroadcast(req.Context(), q, broadcastId, payload.At)
+		newBroadcastId = id
+		return err
+	})
+	if err != nil {
+		writeBroadcastEditError(res, err)
+		return
+	}
+	if err := json.NewEncoder(res).Encode(SplitBroadcastResult{BroadcastId: int(newBroadcastId)}); err != nil {
+		http.Error(res, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+func (s *Server) handleEndBroadcast(res http.ResponseWriter, req *http.Request) {
+	broadcastId, ok := parseBroadcastId(res, req)
+	if !ok {
+		return
+	}
+
+	err := s.inTx(req.Context(), func(q *queries.Queries) error {
+		broadcast, err := getBroadcast(req.Context(), q, broadcastId)
+		if err != nil {
+			return err
+		}
+		if broadcast.EndedAt.Valid {
+			return errBroadcastEnded
+		}
+		return q.SetBroadcastEndedAt(req.Context(), queries.SetBroadcastEndedAtParams{
+			EndedAt:     sql.NullTime{Time: time.Now(), Valid: true},
+			BroadcastID: broadcast.ID,
+		})
+	})
+	if err != nil {
+		writeBroadcastEditError(res, err)
+		return
+	}
+	res.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) handleResumeBroadcast(res http.ResponseWriter, req *http.Request) {
+	broadcastId, ok := parseBroadcastId(res, req)
+	if !ok {
+		return
+	}
+
+	err := s.inTx(req.Context(), func(q *queries.Queries) error {
+		broadcast, err := getBroadcast(req.Context(), q, broadcastId)
+		if err != nil {
+			return err
+		}
+		if !broadcast.EndedAt.Valid {
+			return errBroadcastNotEnded
+		}
+
+		// Resuming any broadcast other than the most recent one would leave us with
+		// overlapping broadcasts
+		mostRecent, err := q.GetMostRecentBroadcast(req.Context(), broadcast.ChannelID)
+		if err != nil {
+			return err
+		}
+		if mostRecent.ID != broadcast.ID {
+			return errNotMostRecent
+		}
+		return q.RecordBroadcastResumed(req.Context(), queries.RecordBroadcastResumedParams{
+			BroadcastID: broadcast.ID,
+		})
+	})
+	if err != nil {
+		writeBroadcastEditError(res, err)
+		return
+	}
+	res.WriteHeader(http.StatusNoContent)
+}
+
+// mergeBroadcast folds the broadcast that immediately follows the given broadcast into
+// it, so that the two are recorded as a single, continuous broadcast
+func mergeBroadcast(ctx context.Context, q *queries.Queries, broadcastId int32) error {
+	broadcast, err := getBroadcast(ctx, q, broadcastId)
+	if err != nil {
+		return err
+	}
+	next, err := q.GetNextBroadcast(ctx, broadcast.ID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return errNoNextBroadcast
+	}
+	if err != nil {
+		return fmt.Errorf("GetNextBroadcast failed: %w", err)
+	}
+
+	// Move all screenings etc. into the earlier broadcast, which implicitly moves any
+	// image requests submitted during those screenings along with them
+	if err := q.ReassignBroadcastRecords(ctx, queries.ReassignBroadcastRecordsParams{
+		ToBroadcastID:   broadcast.ID,
+		FromBroadcastID: next.ID,
+	}); err != nil {
+		return fmt.Errorf("ReassignBroadcastRecords failed: %w", err)
+	}
+
+	// Delete the later broadcast before updating the earlier one: the order of the
+	// resulting notifications lets /state forget the deleted broadcast before it learns
+	// that the surviving broadcast is live
+	if err := q.DeleteBroadcast(ctx, next.ID); err != nil {
+		return fmt.Errorf("DeleteBroadcast failed: %w", err)
+	}
+	if err := q.RecordBroadcastsMerged(ctx, queries.RecordBroadcastsMergedParams{
+		EndedAt:        next.EndedAt,
+		VodUrl:         next.VodUrl,
+		TwitchStreamID: next.TwitchStreamID,
+		BroadcastID:    broadcast.ID,
+	}); err != nil {
+		return fmt.Errorf("RecordBroadcastsMerged failed: %w", err)
+	}
+
+	// Ensure that /state is informed of the tape currently being screened, if any
+	if err := q.RenotifyMostRecentScreening(ctx, broadcast.ID); err != nil {
+		return fmt.Errorf("RenotifyMostRecentScreening failed: %w", err)
+	}
+	return nil
+}
+
+// splitBroadcast ends the given broadcast at the given time and records a new
+// broadcast, starting at that time, which receives all screenings etc. that occurred
+// from that point onward. A screening that was in progress at the split time is itself
+// split in two. Returns the ID of the new broadcast.
+func splitBroadcast(ctx context.Context, q *queries.Queries, broadcastId int32, at time.Time) (int32, error) {
+	broadcast, err := getBroadcast(ctx, q, broadcastId)
+	if err != nil {
+		return 0, err
+	}
+	if !at.After(broadcast.StartedAt) || (broadcast.EndedAt.Valid && !at.Before(broadcast.EndedAt.Time)) {
+		return 0, errSplitTimeOutOfBounds
+	}
+	if at.After(time.Now()) {
+		return 0, errSplitTimeInFuture
+	}
+
+	// End the original broadcast at the split time, then record a new broadcast that
+	// picks up from there, ending whenever the original broadcast ended
+	if err := q.SetBroadcastEndedAt(ctx, queries.SetBroadcastEndedAtParams{
+		EndedAt:     sql.NullTime{Time: at, Valid: true},
+		BroadcastID: broadcast.ID,
+	}); err != nil {
+		return 0, fmt.Errorf("SetBroadcastEndedAt failed: %w", err)
+	}
+	newBroadcastId, err := q.RecordBroadcastSplit(ctx, queries.RecordBroadcastSplitParams{
+		ChannelID:  broadcast.ChannelID,
+		StartedAt:  at,
+		EndedAt:    broadcast.EndedAt,
+		StreamType: broadcast.StreamType,
+	})
+	if err != nil {
+		return 0, fmt.Errorf("RecordBroadcastSplit failed: %w", err)
+	}
+
+	// If a tape was being screened at the split time, end that screening and continue
+	// it in the new broadcast, moving over any image requests and raids that occurred
+	// after the split
+	screening, err := q.GetScreeningInProgressAt(ctx, queries.GetScreeningInProgressAtParams{
+		BroadcastID: broadcast.ID,
+		At:          at,
+	})
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return 0, fmt.Errorf("GetScreeningInProgressAt failed: %w", err)
+	}
+	if err == nil {
+		if err := q.SetScreeningEndedAt(ctx, queries.SetScreeningEndedAtParams{
+			EndedAt:     sql.NullTime{Time: at, Valid: true},
+			ScreeningID: uuid.NullUUID{UUID: screening.ID, Valid: true},
+		}); err != nil {
+			return 0, fmt.Errorf("SetScreeningEndedAt failed: %w", err)
+		}
+		newScreeningId, err := q.RecordScreeningSplit(ctx, queries.RecordScreeningSplitParams{
+			BroadcastID: newBroadcastId,
+			TapeID:      screening.TapeID,
+			StartedAt:   at,
+			EndedAt:     screening.EndedAt,
+		})
+		if err != nil {
+			return 0, fmt.Errorf("RecordScreeningSplit failed: %w", err)
+		}
+		if err := q.ReassignScreeningImageRequests(ctx, queries.ReassignScreeningImageRequestsParams{
+			ToScreeningID:   uuid.NullUUID{UUID: newScreeningId, Valid: true},
+			FromScreeningID: uuid.NullUUID{UUID: screening.ID, Valid: true},
+			Since:           at,
+		}); err != nil {
+			return 0, fmt.Errorf("ReassignScreeningImageRequests failed: %w", err)
+		}
+		if err := q.ReassignScreeningRaids(ctx, queries.ReassignScreeningRaidsParams{
+			ToScreeningID:   uuid.NullUUID{UUID: newScreeningId, Valid: true},
+			FromScreeningID: uuid.NullUUID{UUID: screening.ID, Valid: true},
+			Since:           at,
+		}); err != nil {
+			return 0, fmt.Errorf("ReassignScreeningRaids failed: %w", err)
+		}
+	}
+
+	// Move everything else that happened after the split time into the new broadcast
+	if err := q.ReassignBroadcastRecords(ctx, queries.ReassignBroadcastRecordsParams{
+		ToBroadcastID:   newBroadcastId,
+		FromBroadcastID: broadcast.ID,
+		Since:           sql.NullTime{Time: at, Valid: true},
+	}); err != nil {
+		return 0, fmt.Errorf("ReassignBroadcastRecords failed: %w", err)
+	}
+	return newBroadcastId, nil
+}
+
+// inTx runs the given function with a Queries struct bound to a new transaction, which
+// is committed only if the function succeeds
+func (s *Server) inTx(ctx context.Context, f func(q *queries.Queries) error) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := f(s.q.WithTx(tx)); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func getBroadcast(ctx context.Context, q *queries.Queries, broadcastId int32) (*queries.ShowtimeBroadcast, error) {
+	broadcast, err := q.GetBroadcastById(ctx, broadcastId)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, errNoSuchBroadcast
+	}
+	if err != nil {
+		return nil, fmt.Errorf("GetBroadcastById failed: %w", err)
+	}
+	return &broadcast, nil
+}
+
+func parseBroadcastId(res http.ResponseWriter, req *http.Request) (int32, bool) {
+	broadcastIdStr, ok := mux.Vars(req)["id"]
+	if !ok || broadcastIdStr == "" {
+		http.Error(res, "failed to parse 'id' from URL", http.StatusInternalServerError)
+		return 0, false
+	}
+	broadcastId, err := strconv.Atoi(broadcastIdStr)
+	if err != nil {
+		http.Error(res, "broadcast ID must be an integer", http.StatusBadRequest)
+		return 0, false
+	}
+	return int32(broadcastId), true
+}
+
+func writeBroadcastEditError(res http.ResponseWriter, err error) {
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, errNoSuchBroadcast):
+		status = http.StatusNotFound
+	case errors.Is(err, errNoNextBroadcast), errors.Is(err, errBroadcastEnded), errors.Is(err, errBroadcastNotEnded), errors.Is(err, errNotMostRecent):
+		status = http.StatusConflict
+	case errors.Is(err, errSplitTimeOutOfBounds), errors.Is(err, errSplitTimeInFuture):
+		status = http.StatusBadRequest
+	}
+	http.Error(res, err.Error(), status)
+}
@@ -0,0 +1,96 @@
+package admin
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// DeadLetteredEvent describes an EventSub notification that we've given up on
+// processing after repeated failures
+type DeadLetteredEvent struct {
+	MessageId        string          `json:"messageId"`
+	SubscriptionType string          `json:"subscriptionType"`
+	CreatedAt        time.Time       `json:"createdAt"`
+	DeadLetteredAt   time.Time       `json:"deadLetteredAt"`
+	NumAttempts      int             `json:"numAttempts"`
+	LastError        string          `json:"lastError"`
+	Subscription     json.RawMessage `json:"subscription"`
+	Event            json.RawMessage `json:"event"`
+}
+
+func (s *Server) handleGetDeadLetteredEvents(res http.ResponseWriter, req *http.Request) {
+	rows, err := s.q.GetDeadLetteredEventSubInboxEntries(req.Context())
+	if err != nil {
+		http.Error(res, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	events := make([]DeadLetteredEvent, 0, len(rows))
+	for _, row := range rows {
+		// Parse just enough of the subscription to tell the broadcaster what kind of
+		// event this was
+		var subscription struct {
+			Type string `json:"type"`
+		}
+		if err := json.Unmarshal(row.Subscription, &subscription); err != nil {
+			http.Error(res, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		events = append(events, DeadLetteredEvent{
+			MessageId:        row.MessageID,
+			SubscriptionType: subscription.Type,
+			CreatedAt:        row.CreatedAt,
+			DeadLetteredAt:   row.DeadLetteredAt.Time,
+			NumAttempts:      int(row.NumAttempts),
+			LastError:        row.LastError.String,
+			Subscription:     row.Subscription,
+			Event:            row.Event,
+		})
+	}
+	if err := json.NewEncoder(res).Encode(events); err != nil {
+		http.Error(res, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+func (s *Server) handleRetryDeadLetteredEvent(res http.ResponseWriter, req *http.Request) {
+	messageId, ok := mux.Vars(req)["id"]
+	if !ok || messageId == "" {
+		http.Error(res, "failed to parse 'id' from URL", http.StatusInternalServerError)
+		return
+	}
+
+	// Reset the event's attempt count and make it immediately eligible for processing
+	result, err := s.q.RetryDeadLetteredEventSubInboxEntry(req.Context(), messageId)
+	if err != nil {
+		http.Error(res, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if numRows, err := result.RowsAffected(); err != nil || numRows == 0 {
+		http.Error(res, "no such dead-lettered event", http.StatusNotFound)
+		return
+	}
+	res.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) handleDiscardDeadLetteredEvent(res http.ResponseWriter, req *http.Request) {
+	messageId, ok := mux.Vars(req)["id"]
+	if !ok || messageId == "" {
+		http.Error(res, "failed to parse 'id' from URL", http.StatusInternalServerError)
+		return
+	}
+
+	// Flag the event as discarded so it will no longer be listed
+	result, err := s.q.DiscardDeadLetteredEventSubInboxEntry(req.Context(), messageId)
+	if err != nil {
+		http.Error(res, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if numRows, err := result.RowsAffected(); err != nil || numRows == 0 {
+		http.Error(res, "no such dead-lettered event", http.StatusNotFound)
+		return
+	}
+	res.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,112 @@
+package admin
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/golden-vcr/auth"
+	"github.com/golden-vcr/showtime/gen/queries"
+	"github.com/golden-vcr/showtime/internal/points"
+)
+
+// PointsRulesRevision is a single entry in the audit trail of changes to the rules
+// that determine how many fun points viewers are awarded
+type PointsRulesRevision struct {
+	Id        int          `json:"id"`
+	Rules     points.Rules `json:"rules"`
+	CreatedAt time.Time    `json:"createdAt"`
+	CreatedBy string       `json:"createdBy"`
+}
+
+func (s *Server) handleGetPointsRules(res http.ResponseWriter, req *http.Request) {
+	rules := points.DefaultRules
+	data, err := s.q.GetCurrentPointsRules(req.Context())
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		http.Error(res, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if err == nil {
+		if err := json.Unmarshal(data, &rules); err != nil {
+			http.Error(res, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+	if err := json.NewEncoder(res).Encode(rules); err != nil {
+		http.Error(res, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+func (s *Server) handleSetPointsRules(res http.ResponseWriter, req *http.Request) {
+	// Identify the user who's making the change, for the audit trail
+	claims, err := auth.GetClaims(req)
+	if err != nil {
+		http.Error(res, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if claims.User == nil {
+		http.Error(res, "failed to identify user", http.StatusInternalServerError)
+		return
+	}
+
+	// Parse and validate the new rules
+	var rules points.Rules
+	if err := json.NewDecoder(req.Body).Decode(&rules); err != nil {
+		http.Error(res, "invalid request body", http.StatusBadRequest)
+		return
+	}
+	if rules.Bonuses == nil {
+		rules.Bonuses = []points.Bonus{}
+	}
+	if err := rules.Validate(); err != nil {
+		http.Error(res, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Record a new revision of the rules, which takes effect immediately
+	data, err := json.Marshal(rules)
+	if err != nil {
+		http.Error(res, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if _, err := s.q.RecordPointsRules(req.Context(), queries.RecordPointsRulesParams{
+		Rules:     data,
+		CreatedBy: claims.User.DisplayName,
+	}); err != nil {
+		http.Error(res, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	// Respond with the rules as stored
+	if err := json.NewEncoder(res).Encode(rules); err != nil {
+		http.Error(res, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+func (s *Server) handleGetPointsRulesHistory(res http.ResponseWriter, req *http.Request) {
+	rows, err := s.q.GetPointsRulesHistory(req.Context())
+	if err != nil {
+		http.Error(res, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	revisions := make([]PointsRulesRevision, 0, len(rows))
+	for _, row := range rows {
+		var rules points.Rules
+		if err := json.Unmarshal(row.Rules, &rules); err != nil {
+			http.Error(res, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		revisions = append(revisions, PointsRulesRevision{
+			Id:        int(row.ID),
+			Rules:     rules,
+			CreatedAt: row.CreatedAt,
+			CreatedBy: row.CreatedBy,
+		})
+	}
+	if err := json.NewEncoder(res).Encode(revisions); err != nil {
+		http.Error(res, err.Error(), http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,147 @@
+package admin
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/golden-vcr/showtime/gen/queries"
+	"github.com/golden-vcr/showtime/internal/events"
+	"github.com/gorilla/mux"
+)
+
+// RewardConfig describes the action that should be taken when a viewer redeems a
+// particular channel points reward
+type RewardConfig struct {
+	Action       string `json:"action"`
+	NumPoints    int    `json:"numPoints,omitempty"`
+	OverlayEvent string `json:"overlayEvent,omitempty"`
+}
+
+// Reward associates a RewardConfig with the ID of the reward it applies to
+type Reward struct {
+	RewardId string `json:"rewardId"`
+	RewardConfig
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+func (s *Server) handleGetRewards(res http.ResponseWriter, req *http.Request) {
+	rows, err := s.q.GetChannelPointsRewards(req.Context())
+	if err != nil {
+		http.Error(res, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	rewards := make([]Reward, 0, len(rows))
+	for _, row := range rows {
+		rewards = append(rewards, Reward{
+			RewardId: row.RewardID,
+			RewardConfig: RewardConfig{
+				Action:       row.Action,
+				NumPoints:    int(row.NumPoints),
+				OverlayEvent: row.OverlayEvent,
+			},
+			UpdatedAt: row.UpdatedAt,
+		})
+	}
+	if err := json.NewEncoder(res).Encode(rewards); err != nil {
+		http.Error(res, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+func (s *Server) handleSetReward(res http.ResponseWriter, req *http.Request) {
+	rewardId, ok := mux.Vars(req)["id"]
+	if !ok || rewardId == "" {
+		http.Error(res, "failed to parse 'id' from URL", http.StatusInternalServerError)
+		return
+	}
+
+	// Parse and validate the new config for this reward
+	var config RewardConfig
+	if err := json.NewDecoder(req.Body).Decode(&config); err != nil {
+		http.Error(res, "invalid request body", http.StatusBadRequest)
+		return
+	}
+	if err := validateRewardConfig(&config); err != nil {
+		http.Error(res, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Replace any existing config for the reward
+	if err := s.q.SetChannelPointsReward(req.Context(), queries.SetChannelPointsRewardParams{
+		RewardID:     rewardId,
+		Action:       config.Action,
+		NumPoints:    int32(config.NumPoints),
+		OverlayEvent: config.OverlayEvent,
+	}); err != nil {
+		http.Error(res, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	// Respond with the reward's updated config
+	row, err := s.q.GetChannelPointsReward(req.Context(), rewardId)
+	if err != nil {
+		http.Error(res, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	reward := Reward{
+		RewardId: row.RewardID,
+		RewardConfig: RewardConfig{
+			Action:       row.Action,
+			NumPoints:    int(row.NumPoints),
+			OverlayEvent: row.OverlayEvent,
+		},
+		UpdatedAt: row.UpdatedAt,
+	}
+	if err := json.NewEncoder(res).Encode(reward); err != nil {
+		http.Error(res, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+func (s *Server) handleDeleteReward(res http.ResponseWriter, req *http.Request) {
+	rewardId, ok := mux.Vars(req)["id"]
+	if !ok || rewardId == "" {
+		http.Error(res, "failed to parse 'id' from URL", http.StatusInternalServerError)
+		return
+	}
+
+	// Once the config is removed, redemptions of this reward will be left for the
+	// broadcaster to handle manually
+	result, err := s.q.DeleteChannelPointsReward(req.Context(), rewardId)
+	if err != nil {
+		http.Error(res, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if numRows, err := result.RowsAffected(); err != nil || numRows == 0 {
+		http.Error(res, "no such reward", http.StatusNotFound)
+		return
+	}
+	res.WriteHeader(http.StatusNoContent)
+}
+
+func validateRewardConfig(config *RewardConfig) error {
+	switch config.Action {
+	case events.RewardActionAlert:
+		if config.NumPoints != 0 || config.OverlayEvent != "" {
+			return fmt.Errorf("numPoints and overlayEvent may not be set when action is '%s'", config.Action)
+		}
+	case events.RewardActionCredit:
+		if config.NumPoints <= 0 {
+			return fmt.Errorf("numPoints must be positive when action is '%s'", config.Action)
+		}
+		if config.OverlayEvent != "" {
+			return fmt.Errorf("overlayEvent may not be set when action is '%s'", config.Action)
+		}
+	case events.RewardActionOverlay:
+		if config.OverlayEvent == "" {
+			return fmt.Errorf("overlayEvent is required when action is '%s'", config.Action)
+		}
+		if config.NumPoints != 0 {
+			return fmt.Errorf("numPoints may not be set when action is '%s'", config.Action)
+		}
+	default:
+		return fmt.Errorf("action must be one of '%s', '%s', or '%s'", events.RewardActionAlert, events.RewardActionCredit, events.RewardActionOverlay)
+	}
+	return nil
+}
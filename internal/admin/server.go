@@ -8,16 +8,27 @@ import (
 
 	"github.com/golden-vcr/auth"
 	"github.com/golden-vcr/showtime/gen/queries"
+	"github.com/golden-vcr/showtime/internal/alerts"
 	"github.com/gorilla/mux"
 )
 
 type Server struct {
-	q *queries.Queries
+	db        *sql.DB
+	q         *queries.Queries
+	channelId string
+	moderator *alerts.Moderator
 }
 
-func NewServer(q *queries.Queries) *Server {
+// NewServer initializes an admin server: routes that concern the current broadcast
+// apply to the channel with the given Twitch user ID unless another channel is
+// specified in the URL. Alerts that are held for moderation are approved, rejected,
+// and edited via the given Moderator.
+func NewServer(db *sql.DB, q *queries.Queries, channelId string, moderator *alerts.Moderator) *Server {
 	return &Server{
-		q: q,
+		db:        db,
+		q:         q,
+		channelId: channelId,
+		moderator: moderator,
 	}
 }
 
@@ -28,9 +39,48 @@ func (s *Server) RegisterRoutes(c auth.Client, r *mux.Router) {
 	})
 
 	// POST /tape allows the broadcaster to notify the backend that we're now screening
-	// a new tape
+	// a new tape: /channels/:channel/tape does the same for a partner channel
 	r.Path("/tape/{id}").Methods("POST").HandlerFunc(s.handleSetTape)
 	r.Path("/tape").Methods("DELETE").HandlerFunc(s.handleClearTape)
+	r.Path("/channels/{channel}/tape/{id}").Methods("POST").HandlerFunc(s.handleSetTape)
+	r.Path("/channels/{channel}/tape").Methods("DELETE").HandlerFunc(s.handleClearTape)
+
+	// GET /events/dead-letter lists EventSub notifications that we've given up on
+	// processing, allowing the broadcaster to either retry or discard them
+	r.Path("/events/dead-letter").Methods("GET").HandlerFunc(s.handleGetDeadLetteredEvents)
+	r.Path("/events/dead-letter/{id}/retry").Methods("POST").HandlerFunc(s.handleRetryDeadLetteredEvent)
+	r.Path("/events/dead-letter/{id}").Methods("DELETE").HandlerFunc(s.handleDiscardDeadLetteredEvent)
+
+	// GET /rewards lists the actions that are configured to run in response to channel
+	// points redemptions: PUT and DELETE allow the broadcaster to configure the action
+	// for a single reward
+	r.Path("/rewards").Methods("GET").HandlerFunc(s.handleGetRewards)
+	r.Path("/rewards/{id}").Methods("PUT").HandlerFunc(s.handleSetReward)
+	r.Path("/rewards/{id}").Methods("DELETE").HandlerFunc(s.handleDeleteReward)
+
+	// GET /points returns the rules that determine how many fun points viewers are
+	// awarded in response to events: PUT replaces those rules, taking effect
+	// immediately, and GET /points/history lists all prior revisions for auditing
+	r.Path("/points").Methods("GET").HandlerFunc(s.handleGetPointsRules)
+	r.Path("/points").Methods("PUT").HandlerFunc(s.handleSetPointsRules)
+	r.Path("/points/history").Methods("GET").HandlerFunc(s.handleGetPointsRulesHistory)
+
+	// POST /broadcasts/:id/merge, /split, /end, and /resume allow the broadcaster to
+	// correct the record of past broadcasts, e.g. when a dropped connection has caused
+	// a single show to be recorded as two separate broadcasts
+	r.Path("/broadcasts/{id}/merge").Methods("POST").HandlerFunc(s.handleMergeBroadcast)
+	r.Path("/broadcasts/{id}/split").Methods("POST").HandlerFunc(s.handleSplitBroadcast)
+	r.Path("/broadcasts/{id}/end").Methods("POST").HandlerFunc(s.handleEndBroadcast)
+	r.Path("/broadcasts/{id}/resume").Methods("POST").HandlerFunc(s.handleResumeBroadcast)
+
+	// GET /alerts lists alerts that have been held for moderation: the broadcaster may
+	// edit the text of a pending alert before approving or rejecting it, and only
+	// approved alerts are displayed
+	r.Path("/alerts").Methods("GET").HandlerFunc(s.handleGetModeratedAlerts)
+	r.Path("/alerts/{id}").Methods("GET").HandlerFunc(s.handleGetModeratedAlert)
+	r.Path("/alerts/{id}").Methods("PATCH").HandlerFunc(s.handleEditModeratedAlert)
+	r.Path("/alerts/{id}/approve").Methods("POST").HandlerFunc(s.handleApproveModeratedAlert)
+	r.Path("/alerts/{id}/reject").Methods("POST").HandlerFunc(s.handleRejectModeratedAlert)
 }
 
 func (s *Server) handleSetTape(res http.ResponseWriter, req *http.Request) {
@@ -47,7 +97,7 @@ func (s *Server) handleSetTape(res http.ResponseWriter, req *http.Request) {
 	}
 
 	// Resolve the ID of the current broadcast, if it's live (i.e. not ended)
-	broadcast, err := s.q.GetMostRecentBroadcast(req.Context())
+	broadcast, err := s.q.GetMostRecentBroadcast(req.Context(), s.resolveChannelId(req))
 	if errors.Is(err, sql.ErrNoRows) || (err == nil && broadcast.EndedAt.Valid) {
 		http.Error(res, "no broadcast is currently live", http.StatusBadRequest)
 		return
@@ -76,7 +126,7 @@ func (s *Server) handleSetTape(res http.ResponseWriter, req *http.Request) {
 
 func (s *Server) handleClearTape(res http.ResponseWriter, req *http.Request) {
 	// Resolve the ID of the current broadcast, if it's live (i.e. not ended)
-	broadcast, err := s.q.GetMostRecentBroadcast(req.Context())
+	broadcast, err := s.q.GetMostRecentBroadcast(req.Context(), s.resolveChannelId(req))
 	if errors.Is(err, sql.ErrNoRows) || (err == nil && broadcast.EndedAt.Valid) {
 		http.Error(res, "no broadcast is currently live", http.StatusBadRequest)
 		return
@@ -94,3 +144,12 @@ func (s *Server) handleClearTape(res http.ResponseWriter, req *http.Request) {
 	}
 	res.WriteHeader(http.StatusNoContent)
 }
+
+// resolveChannelId returns the Twitch user ID of the channel that a request concerns:
+// the channel named in the URL, if any, or else the server's default channel
+func (s *Server) resolveChannelId(req *http.Request) string {
+	if channelId := mux.Vars(req)["channel"]; channelId != "" {
+		return channelId
+	}
+	return s.channelId
+}
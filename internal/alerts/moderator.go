@@ -0,0 +1,241 @@
+package alerts
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/golden-vcr/showtime/gen/queries"
+)
+
+const (
+	ModerationStatusPending  = "pending"
+	ModerationStatusApproved = "approved"
+	ModerationStatusRejected = "rejected"
+
+	ModerationActionEdit        = "edit"
+	ModerationActionApprove     = "approve"
+	ModerationActionReject      = "reject"
+	ModerationActionAutoApprove = "auto-approve"
+)
+
+// ErrNotPending is returned when attempting to approve, reject, or edit an alert that
+// doesn't exist or that's already been approved or rejected
+var ErrNotPending = errors.New("no such alert is pending moderation")
+
+// autoApproveInterval is how often the Moderator checks for pending alerts that have
+// timed out
+const autoApproveInterval = 5 * time.Second
+
+// ModerationQueries represents the subset of database functionality required to hold
+// alerts for moderation and to record the broadcaster's decisions
+type ModerationQueries interface {
+	EnqueueModeratedAlert(ctx context.Context, arg queries.EnqueueModeratedAlertParams) (int64, error)
+	GetModeratedAlert(ctx context.Context, id int64) (queries.GetModeratedAlertRow, error)
+	EditModeratedAlert(ctx context.Context, arg queries.EditModeratedAlertParams) (int64, error)
+	DecideModeratedAlert(ctx context.Context, arg queries.DecideModeratedAlertParams) (queries.DecideModeratedAlertRow, error)
+	AutoApproveModeratedAlerts(ctx context.Context, arg queries.AutoApproveModeratedAlertsParams) ([]queries.AutoApproveModeratedAlertsRow, error)
+}
+
+// Moderator holds alerts of selected types (e.g. those that display viewer-supplied
+// text) in a queue, only passing them along once they've been approved by the
+// broadcaster. Pending alerts are approved automatically once they've been held for
+// longer than the configured timeout, if any. Alerts of all other types are passed
+// along immediately.
+type Moderator struct {
+	q       ModerationQueries
+	types   map[string]struct{}
+	timeout time.Duration
+	now     func() time.Time
+
+	mu      sync.RWMutex
+	outputs map[string]chan<- *Alert
+}
+
+// NewModerator initializes a Moderator that holds alerts of the given types, approving
+// them automatically after timeout (or never, if timeout is 0)
+func NewModerator(q ModerationQueries, types []string, timeout time.Duration) (*Moderator, error) {
+	typesMap := make(map[string]struct{}, len(types))
+	for _, alertType := range types {
+		if !IsValidAlertType(alertType) {
+			return nil, fmt.Errorf("unsupported alert type '%s'", alertType)
+		}
+		typesMap[alertType] = struct{}{}
+	}
+	return &Moderator{
+		q:       q,
+		types:   typesMap,
+		timeout: timeout,
+		now:     time.Now,
+		outputs: make(map[string]chan<- *Alert),
+	}, nil
+}
+
+// Run reads alerts emitted for the given channel from src, passing along those that
+// don't require moderation to dst immediately, and holding the rest until they're
+// approved, until the context is canceled
+func (m *Moderator) Run(ctx context.Context, channelId string, src <-chan *Alert, dst chan<- *Alert) error {
+	m.mu.Lock()
+	m.outputs[channelId] = dst
+	m.mu.Unlock()
+	defer func() {
+		m.mu.Lock()
+		delete(m.outputs, channelId)
+		m.mu.Unlock()
+	}()
+
+	ticker := time.NewTicker(autoApproveInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			m.autoApprove(ctx, channelId)
+		case alert := <-src:
+			if _, ok := m.types[alert.Type]; !ok {
+				m.publish(ctx, channelId, alert)
+				continue
+			}
+			m.enqueue(ctx, channelId, alert)
+		}
+	}
+}
+
+// Approve records the broadcaster's decision to approve a pending alert, then passes
+// it along to be displayed
+func (m *Moderator) Approve(ctx context.Context, id int64, actor string) error {
+	row, err := m.q.DecideModeratedAlert(ctx, queries.DecideModeratedAlertParams{
+		Status: ModerationStatusApproved,
+		Actor:  actor,
+		ID:     id,
+		Action: ModerationActionApprove,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrNotPending
+	}
+	if err != nil {
+		return err
+	}
+	return m.publishDecided(ctx, row.ChannelID, row.Type, row.Data)
+}
+
+// Reject records the broadcaster's decision to reject a pending alert, which is never
+// displayed
+func (m *Moderator) Reject(ctx context.Context, id int64, actor string) error {
+	_, err := m.q.DecideModeratedAlert(ctx, queries.DecideModeratedAlertParams{
+		Status: ModerationStatusRejected,
+		Actor:  actor,
+		ID:     id,
+		Action: ModerationActionReject,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrNotPending
+	}
+	return err
+}
+
+// Edit replaces the viewer-supplied text of a pending alert: the alert remains pending
+// until it's approved or rejected
+func (m *Moderator) Edit(ctx context.Context, id int64, text string, actor string) error {
+	row, err := m.q.GetModeratedAlert(ctx, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrNotPending
+	}
+	if err != nil {
+		return err
+	}
+	if row.Status != ModerationStatusPending {
+		return ErrNotPending
+	}
+
+	data, err := ParseAlertData(row.Type, row.Data)
+	if err != nil {
+		return err
+	}
+	if err := data.SetText(text); err != nil {
+		return err
+	}
+	edited, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+
+	numRows, err := m.q.EditModeratedAlert(ctx, queries.EditModeratedAlertParams{
+		Data:  edited,
+		ID:    id,
+		Actor: actor,
+	})
+	if err != nil {
+		return err
+	}
+	if numRows == 0 {
+		return ErrNotPending
+	}
+	return nil
+}
+
+func (m *Moderator) enqueue(ctx context.Context, channelId string, alert *Alert) {
+	data, err := json.Marshal(alert.Data)
+	if err != nil {
+		fmt.Printf("Failed to serialize %s alert for moderation; discarding: %v\n", alert.Type, err)
+		return
+	}
+	id, err := m.q.EnqueueModeratedAlert(ctx, queries.EnqueueModeratedAlertParams{
+		ChannelID: channelId,
+		Type:      alert.Type,
+		Data:      data,
+	})
+	if err != nil {
+		fmt.Printf("Failed to hold %s alert for moderation; discarding: %v\n", alert.Type, err)
+		return
+	}
+	fmt.Printf("Holding %s alert for moderation as %d.\n", alert.Type, id)
+}
+
+func (m *Moderator) autoApprove(ctx context.Context, channelId string) {
+	if m.timeout <= 0 {
+		return
+	}
+	rows, err := m.q.AutoApproveModeratedAlerts(ctx, queries.AutoApproveModeratedAlertsParams{
+		ChannelID:     channelId,
+		CreatedBefore: m.now().Add(-m.timeout),
+	})
+	if err != nil {
+		fmt.Printf("Failed to auto-approve pending alerts: %v\n", err)
+		return
+	}
+	for _, row := range rows {
+		fmt.Printf("Auto-approved %s alert %d.\n", row.Type, row.ID)
+		if err := m.publishDecided(ctx, row.ChannelID, row.Type, row.Data); err != nil {
+			fmt.Printf("Failed to publish auto-approved alert %d: %v\n", row.ID, err)
+		}
+	}
+}
+
+func (m *Moderator) publishDecided(ctx context.Context, channelId string, alertType string, data json.RawMessage) error {
+	alertData, err := ParseAlertData(alertType, data)
+	if err != nil {
+		return err
+	}
+	m.publish(ctx, channelId, &Alert{Type: alertType, Data: alertData})
+	return nil
+}
+
+func (m *Moderator) publish(ctx context.Context, channelId string, alert *Alert) {
+	m.mu.RLock()
+	dst, ok := m.outputs[channelId]
+	m.mu.RUnlock()
+	if !ok {
+		fmt.Printf("Discarding %s alert for unrecognized channel %s.\n", alert.Type, channelId)
+		return
+	}
+	select {
+	case <-ctx.Done():
+	case dst <- alert:
+	}
+}
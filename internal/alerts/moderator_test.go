@@ -0,0 +1,164 @@
+package alerts
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/golden-vcr/showtime/gen/queries"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_NewModerator(t *testing.T) {
+	_, err := NewModerator(&mockModerationQueries{}, []string{AlertTypeSubscribe, AlertTypeGeneratedImages}, time.Minute)
+	assert.NoError(t, err)
+	_, err = NewModerator(&mockModerationQueries{}, []string{"bogus"}, time.Minute)
+	assert.Error(t, err)
+}
+
+func Test_Moderator(t *testing.T) {
+	now := time.Date(1997, 9, 1, 12, 0, 0, 0, time.UTC)
+	q := &mockModerationQueries{}
+	m, err := NewModerator(q, []string{AlertTypeSubscribe}, time.Minute)
+	assert.NoError(t, err)
+	m.now = func() time.Time { return now }
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	src := make(chan *Alert)
+	dst := make(chan *Alert, 8)
+	done := make(chan error)
+	go func() {
+		done <- m.Run(ctx, "953753877", src, dst)
+	}()
+
+	// Alerts of types that aren't moderated should be passed along immediately
+	src <- &Alert{Type: AlertTypeFollow, Data: AlertData{Follow: &AlertDataFollow{Username: "alice"}}}
+	alert := <-dst
+	assert.Equal(t, AlertTypeFollow, alert.Type)
+
+	// Moderated alerts should be held until they're approved
+	src <- &Alert{Type: AlertTypeSubscribe, Data: AlertData{Subscribe: &AlertDataSubscribe{Username: "bob", Message: "rude"}}}
+	src <- &Alert{Type: AlertTypeSubscribe, Data: AlertData{Subscribe: &AlertDataSubscribe{Username: "carol", Message: "spam"}}}
+	src <- &Alert{Type: AlertTypeSubscribe, Data: AlertData{Subscribe: &AlertDataSubscribe{Username: "dave", Message: "hi"}}}
+	src <- &Alert{Type: AlertTypeRaid, Data: AlertData{Raid: &AlertDataRaid{Username: "erin", NumViewers: 5}}}
+	alert = <-dst
+	assert.Equal(t, AlertTypeRaid, alert.Type)
+	assert.Len(t, dst, 0)
+	assert.Len(t, q.alerts, 3)
+
+	// Editing a pending alert should change its text before it's approved
+	err = m.Edit(context.Background(), 1, "nice", "GoldenVCR")
+	assert.NoError(t, err)
+	err = m.Approve(context.Background(), 1, "GoldenVCR")
+	assert.NoError(t, err)
+	alert = <-dst
+	assert.Equal(t, &Alert{Type: AlertTypeSubscribe, Data: AlertData{Subscribe: &AlertDataSubscribe{Username: "bob", Message: "nice"}}}, alert)
+
+	// Once decided, an alert can't be edited or decided again
+	assert.ErrorIs(t, m.Edit(context.Background(), 1, "nicer", "GoldenVCR"), ErrNotPending)
+	assert.ErrorIs(t, m.Reject(context.Background(), 1, "GoldenVCR"), ErrNotPending)
+	assert.ErrorIs(t, m.Approve(context.Background(), 99, "GoldenVCR"), ErrNotPending)
+
+	// Rejected alerts should never be passed along
+	err = m.Reject(context.Background(), 2, "GoldenVCR")
+	assert.NoError(t, err)
+	assert.Len(t, dst, 0)
+
+	// Pending alerts that have timed out should be approved automatically
+	m.autoApprove(context.Background(), "953753877")
+	alert = <-dst
+	assert.Equal(t, "dave", alert.Data.Subscribe.Username)
+	assert.Equal(t, now.Add(-time.Minute), q.autoApprovedBefore)
+
+	// Every decision should be recorded
+	assert.Equal(t, []string{"edit:GoldenVCR", "approve:GoldenVCR", "reject:GoldenVCR", "auto-approve:auto"}, q.actions)
+
+	cancel()
+	assert.ErrorIs(t, <-done, context.Canceled)
+}
+
+func Test_AlertData_SetText(t *testing.T) {
+	ad := AlertData{Cheer: &AlertDataCheer{Message: "$0 hi", Cheermotes: []CheermoteToken{{Name: "Cheer100"}}}}
+	assert.NoError(t, ad.SetText("costs $5"))
+	assert.Equal(t, "costs $$5", ad.Cheer.Message)
+	assert.Empty(t, ad.Cheer.Cheermotes)
+
+	ad = AlertData{Follow: &AlertDataFollow{Username: "alice"}}
+	assert.ErrorIs(t, ad.SetText("hi"), ErrNoEditableText)
+}
+
+type mockModerationQueries struct {
+	alerts             []queries.GetModeratedAlertRow
+	actions            []string
+	autoApprovedBefore time.Time
+}
+
+func (m *mockModerationQueries) EnqueueModeratedAlert(ctx context.Context, arg queries.EnqueueModeratedAlertParams) (int64, error) {
+	id := int64(len(m.alerts) + 1)
+	m.alerts = append(m.alerts, queries.GetModeratedAlertRow{
+		ID:           id,
+		ChannelID:    arg.ChannelID,
+		Type:         arg.Type,
+		Data:         arg.Data,
+		OriginalData: arg.Data,
+		Status:       ModerationStatusPending,
+	})
+	return id, nil
+}
+
+func (m *mockModerationQueries) GetModeratedAlert(ctx context.Context, id int64) (queries.GetModeratedAlertRow, error) {
+	for _, alert := range m.alerts {
+		if alert.ID == id {
+			return alert, nil
+		}
+	}
+	return queries.GetModeratedAlertRow{}, sql.ErrNoRows
+}
+
+func (m *mockModerationQueries) EditModeratedAlert(ctx context.Context, arg queries.EditModeratedAlertParams) (int64, error) {
+	for i := range m.alerts {
+		if m.alerts[i].ID == arg.ID && m.alerts[i].Status == ModerationStatusPending {
+			m.alerts[i].Data = arg.Data
+			m.actions = append(m.actions, ModerationActionEdit+":"+arg.Actor)
+			return 1, nil
+		}
+	}
+	return 0, nil
+}
+
+func (m *mockModerationQueries) DecideModeratedAlert(ctx context.Context, arg queries.DecideModeratedAlertParams) (queries.DecideModeratedAlertRow, error) {
+	for i := range m.alerts {
+		if m.alerts[i].ID == arg.ID && m.alerts[i].Status == ModerationStatusPending {
+			m.alerts[i].Status = arg.Status
+			m.actions = append(m.actions, arg.Action+":"+arg.Actor)
+			return queries.DecideModeratedAlertRow{
+				ID:        m.alerts[i].ID,
+				ChannelID: m.alerts[i].ChannelID,
+				Type:      m.alerts[i].Type,
+				Data:      json.RawMessage(m.alerts[i].Data),
+			}, nil
+		}
+	}
+	return queries.DecideModeratedAlertRow{}, sql.ErrNoRows
+}
+
+func (m *mockModerationQueries) AutoApproveModeratedAlerts(ctx context.Context, arg queries.AutoApproveModeratedAlertsParams) ([]queries.AutoApproveModeratedAlertsRow, error) {
+	m.autoApprovedBefore = arg.CreatedBefore
+	rows := make([]queries.AutoApproveModeratedAlertsRow, 0)
+	for i := range m.alerts {
+		if m.alerts[i].ChannelID == arg.ChannelID && m.alerts[i].Status == ModerationStatusPending {
+			m.alerts[i].Status = ModerationStatusApproved
+			m.actions = append(m.actions, ModerationActionAutoApprove+":auto")
+			rows = append(rows, queries.AutoApproveModeratedAlertsRow{
+				ID:        m.alerts[i].ID,
+				ChannelID: m.alerts[i].ChannelID,
+				Type:      m.alerts[i].Type,
+				Data:      m.alerts[i].Data,
+			})
+		}
+	}
+	return rows, nil
+}
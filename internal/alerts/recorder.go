@@ -0,0 +1,150 @@
+package alerts
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/golden-vcr/showtime/gen/queries"
+)
+
+// ErrInvalidEventId is returned from Replay if the supplied event ID is not the ID of
+// an alert
+var ErrInvalidEventId = errors.New("invalid alert event ID")
+
+// purgeInterval is how often the Recorder deletes alerts that have aged out of the
+// retention period
+const purgeInterval = 10 * time.Minute
+
+// Queries represents the subset of database functionality required to record and
+// replay alerts
+type Queries interface {
+	RecordAlert(ctx context.Context, arg queries.RecordAlertParams) (queries.RecordAlertRow, error)
+	GetAlertsSince(ctx context.Context, arg queries.GetAlertsSinceParams) ([]queries.GetAlertsSinceRow, error)
+	PurgeAlerts(ctx context.Context, arg queries.PurgeAlertsParams) (int64, error)
+}
+
+// Recorder persists the alerts emitted for a channel, assigning each alert an ID and a
+// timestamp, so that clients which were disconnected when an alert was emitted can
+// replay it upon reconnecting. Alerts are retained for a limited time.
+type Recorder struct {
+	q         Queries
+	channelId string
+	retention time.Duration
+	now       func() time.Time
+}
+
+// NewRecorder initializes a Recorder for the channel whose broadcaster has the given
+// Twitch user ID, retaining alerts for the given duration
+func NewRecorder(q Queries, channelId string, retention time.Duration) *Recorder {
+	return &Recorder{
+		q:         q,
+		channelId: channelId,
+		retention: retention,
+		now:       time.Now,
+	}
+}
+
+// Run reads alerts from src, records each one, and writes it to dst, until the context
+// is canceled. If an alert can't be recorded, it's still passed along, without an ID.
+// Alerts that are older than the retention period are periodically purged.
+func (r *Recorder) Run(ctx context.Context, src <-chan *Alert, dst chan<- *Alert) error {
+	r.purge(ctx)
+	ticker := time.NewTicker(purgeInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			r.purge(ctx)
+		case alert := <-src:
+			r.record(ctx, alert)
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case dst <- alert:
+			}
+		}
+	}
+}
+
+// Replay returns all alerts that were recorded after the alert with the given ID,
+// excluding any that are older than the retention period. It's intended for use as
+// an sse.Handler's ReplayFunc.
+func (r *Recorder) Replay(ctx context.Context, lastEventId string) ([]*Alert, error) {
+	lastId, err := strconv.ParseInt(lastEventId, 10, 64)
+	if err != nil || lastId < 0 {
+		return nil, ErrInvalidEventId
+	}
+	rows, err := r.q.GetAlertsSince(ctx, queries.GetAlertsSinceParams{
+		ChannelID: r.channelId,
+		LastID:    lastId,
+		NotBefore: r.now().Add(-r.retention),
+	})
+	if err != nil {
+		return nil, err
+	}
+	alerts := make([]*Alert, 0, len(rows))
+	for _, row := range rows {
+		data, err := ParseAlertData(row.Type, row.Data)
+		if err != nil {
+			fmt.Printf("Skipping replay of alert %d: %v\n", row.ID, err)
+			continue
+		}
+		alerts = append(alerts, &Alert{
+			Id:        row.ID,
+			Timestamp: row.CreatedAt,
+			Type:      row.Type,
+			Data:      data,
+		})
+	}
+	return alerts, nil
+}
+
+// GetEventId returns the ID of the given alert, formatted for use as an SSE event ID,
+// or an empty string if the alert was not recorded. It's intended for use as an
+// sse.Handler's EventIdFunc.
+func GetEventId(alert *Alert) string {
+	if alert.Id == 0 {
+		return ""
+	}
+	return strconv.FormatInt(alert.Id, 10)
+}
+
+func (r *Recorder) record(ctx context.Context, alert *Alert) {
+	alert.Timestamp = r.now()
+	data, err := json.Marshal(alert.Data)
+	if err != nil {
+		fmt.Printf("Failed to serialize %s alert for recording: %v\n", alert.Type, err)
+		return
+	}
+	row, err := r.q.RecordAlert(ctx, queries.RecordAlertParams{
+		ChannelID: r.channelId,
+		Type:      alert.Type,
+		Data:      data,
+	})
+	if err != nil {
+		fmt.Printf("Failed to record %s alert: %v\n", alert.Type, err)
+		return
+	}
+	alert.Id = row.ID
+	alert.Timestamp = row.CreatedAt
+}
+
+func (r *Recorder) purge(ctx context.Context) {
+	numPurged, err := r.q.PurgeAlerts(ctx, queries.PurgeAlertsParams{
+		ChannelID: r.channelId,
+		NotBefore: r.now().Add(-r.retention),
+	})
+	if err != nil {
+		fmt.Printf("Failed to purge expired alerts: %v\n", err)
+		return
+	}
+	if numPurged > 0 {
+		fmt.Printf("Purged %d expired alert(s).\n", numPurged)
+	}
+}
@@ -0,0 +1,120 @@
+package alerts
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/golden-vcr/showtime/gen/queries"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Recorder(t *testing.T) {
+	now := time.Date(1997, 9, 1, 12, 0, 0, 0, time.UTC)
+	q := &mockQueries{}
+	r := &Recorder{
+		q:         q,
+		channelId: "953753877",
+		retention: time.Hour,
+		now:       func() time.Time { return now },
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	src := make(chan *Alert)
+	dst := make(chan *Alert)
+	done := make(chan error)
+	go func() {
+		done <- r.Run(ctx, src, dst)
+	}()
+
+	// Each alert should be assigned an ID and timestamp before it's passed along
+	src <- &Alert{Type: AlertTypeFollow, Data: AlertData{Follow: &AlertDataFollow{Username: "alice"}}}
+	alert := <-dst
+	assert.Equal(t, int64(1), alert.Id)
+	assert.Equal(t, now, alert.Timestamp)
+	assert.Equal(t, "1", GetEventId(alert))
+	src <- &Alert{Type: AlertTypeRaid, Data: AlertData{Raid: &AlertDataRaid{Username: "bob", NumViewers: 5}}}
+	alert = <-dst
+	assert.Equal(t, int64(2), alert.Id)
+
+	// If an alert can't be recorded, it should still be passed along without an ID
+	q.err = fmt.Errorf("mock error")
+	src <- &Alert{Type: AlertTypeFollow, Data: AlertData{Follow: &AlertDataFollow{Username: "carol"}}}
+	alert = <-dst
+	assert.Equal(t, int64(0), alert.Id)
+	assert.Equal(t, "", GetEventId(alert))
+	q.err = nil
+
+	cancel()
+	assert.ErrorIs(t, <-done, context.Canceled)
+	assert.Equal(t, now.Add(-time.Hour), q.purgedBefore)
+
+	// Alerts following the last event ID should be replayed
+	replayed, err := r.Replay(context.Background(), "1")
+	assert.NoError(t, err)
+	assert.Equal(t, []*Alert{
+		{
+			Id:        2,
+			Timestamp: now,
+			Type:      AlertTypeRaid,
+			Data:      AlertData{Raid: &AlertDataRaid{Username: "bob", NumViewers: 5}},
+		},
+	}, replayed)
+	assert.Equal(t, now.Add(-time.Hour), q.replayedNotBefore)
+
+	// An event ID that isn't an alert ID should be rejected
+	_, err = r.Replay(context.Background(), "not-an-id")
+	assert.ErrorIs(t, err, ErrInvalidEventId)
+}
+
+type mockQueries struct {
+	err               error
+	alerts            []queries.ShowtimeAlert
+	purgedBefore      time.Time
+	replayedNotBefore time.Time
+}
+
+func (m *mockQueries) RecordAlert(ctx context.Context, arg queries.RecordAlertParams) (queries.RecordAlertRow, error) {
+	if m.err != nil {
+		return queries.RecordAlertRow{}, m.err
+	}
+	alert := queries.ShowtimeAlert{
+		ID:        int64(len(m.alerts) + 1),
+		ChannelID: arg.ChannelID,
+		Type:      arg.Type,
+		Data:      arg.Data,
+		CreatedAt: time.Date(1997, 9, 1, 12, 0, 0, 0, time.UTC),
+	}
+	m.alerts = append(m.alerts, alert)
+	return queries.RecordAlertRow{ID: alert.ID, CreatedAt: alert.CreatedAt}, nil
+}
+
+func (m *mockQueries) GetAlertsSince(ctx context.Context, arg queries.GetAlertsSinceParams) ([]queries.GetAlertsSinceRow, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	m.replayedNotBefore = arg.NotBefore
+	rows := make([]queries.GetAlertsSinceRow, 0)
+	for _, alert := range m.alerts {
+		if alert.ChannelID == arg.ChannelID && alert.ID > arg.LastID {
+			rows = append(rows, queries.GetAlertsSinceRow{
+				ID:        alert.ID,
+				Type:      alert.Type,
+				Data:      json.RawMessage(alert.Data),
+				CreatedAt: alert.CreatedAt,
+			})
+		}
+	}
+	return rows, nil
+}
+
+func (m *mockQueries) PurgeAlerts(ctx context.Context, arg queries.PurgeAlertsParams) (int64, error) {
+	if m.err != nil {
+		return 0, m.err
+	}
+	m.purgedBefore = arg.NotBefore
+	return 0, nil
+}
@@ -2,6 +2,10 @@ package alerts
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
 )
 
 const (
@@ -9,12 +13,31 @@ const (
 	AlertTypeSubscribe       = "subscribe"
 	AlertTypeGiftSub         = "gift-sub"
 	AlertTypeRaid            = "raid"
+	AlertTypeCheer           = "cheer"
 	AlertTypeGeneratedImages = "generated-images"
+	AlertTypeRedemption      = "redemption"
+	AlertTypeOverlayEvent    = "overlay-event"
+	AlertTypeCommandRejected = "command-rejected"
 )
 
+// ErrNoEditableText is returned from AlertData.SetText for alerts that don't carry any
+// viewer-supplied text
+var ErrNoEditableText = errors.New("alert has no editable text")
+
+// IsValidAlertType returns true if the given string is one of the AlertType constants
+func IsValidAlertType(alertType string) bool {
+	_, err := ParseAlertData(alertType, json.RawMessage(`{}`))
+	return err == nil
+}
+
+// Alert is a notification that should be displayed by the stream graphics overlay.
+// Id and Timestamp are assigned when the alert is recorded: Id is 0 if the alert could
+// not be recorded, in which case it can't be replayed.
 type Alert struct {
-	Type string    `json:"type"`
-	Data AlertData `json:"data"`
+	Id        int64     `json:"id,omitempty"`
+	Timestamp time.Time `json:"timestamp"`
+	Type      string    `json:"type"`
+	Data      AlertData `json:"data"`
 }
 
 type AlertData struct {
@@ -22,7 +45,11 @@ type AlertData struct {
 	Subscribe       *AlertDataSubscribe
 	GiftSub         *AlertDataGiftSub
 	Raid            *AlertDataRaid
+	Cheer           *AlertDataCheer
 	GeneratedImages *AlertDataGeneratedImages
+	Redemption      *AlertDataRedemption
+	OverlayEvent    *AlertDataOverlayEvent
+	CommandRejected *AlertDataCommandRejected
 }
 
 type AlertDataFollow struct {
@@ -37,8 +64,9 @@ type AlertDataSubscribe struct {
 }
 
 type AlertDataGiftSub struct {
-	Username         string `json:"username"`
-	NumSubscriptions int    `json:"numSubscriptions"`
+	Username         string   `json:"username"`
+	NumSubscriptions int      `json:"numSubscriptions"`
+	Recipients       []string `json:"recipients"`
 }
 
 type AlertDataRaid struct {
@@ -46,12 +74,54 @@ type AlertDataRaid struct {
 	NumViewers int    `json:"numViewers"`
 }
 
+// AlertDataCheer describes a cheer: Message uses the same format as chat messages,
+// with each occurrence of '$$' representing a literal dollar sign and each occurrence
+// of '$i' representing a reference to Cheermotes[i]
+type AlertDataCheer struct {
+	Username    string           `json:"username"`
+	IsAnonymous bool             `json:"isAnonymous"`
+	NumBits     int              `json:"numBits"`
+	Message     string           `json:"message"`
+	Cheermotes  []CheermoteToken `json:"cheermotes"`
+}
+
+// CheermoteToken is a cheermote used in a cheer message, e.g. 'Cheer100', along with
+// the image that should be displayed in its place: Url is empty if the cheermote
+// could not be resolved to an image
+type CheermoteToken struct {
+	Name    string `json:"name"`
+	NumBits int    `json:"numBits"`
+	Color   string `json:"color"`
+	Url     string `json:"url"`
+}
+
 type AlertDataGeneratedImages struct {
 	Username    string   `json:"username"`
 	Description string   `json:"description"`
 	Urls        []string `json:"urls"`
 }
 
+type AlertDataRedemption struct {
+	Username    string `json:"username"`
+	RewardTitle string `json:"rewardTitle"`
+	Message     string `json:"message"`
+}
+
+type AlertDataOverlayEvent struct {
+	Event    string `json:"event"`
+	Username string `json:"username"`
+	Message  string `json:"message"`
+}
+
+// AlertDataCommandRejected acknowledges that a viewer's cheer invoked a command that
+// could not be carried out: Reason explains why, and any fun points that would have
+// been spent on the command are left in the viewer's balance
+type AlertDataCommandRejected struct {
+	Username string `json:"username"`
+	Command  string `json:"command"`
+	Reason   string `json:"reason"`
+}
+
 func (ad AlertData) MarshalJSON() ([]byte, error) {
 	if ad.Follow != nil {
 		return json.Marshal(ad.Follow)
@@ -65,8 +135,85 @@ func (ad AlertData) MarshalJSON() ([]byte, error) {
 	if ad.Raid != nil {
 		return json.Marshal(ad.Raid)
 	}
+	if ad.Cheer != nil {
+		return json.Marshal(ad.Cheer)
+	}
 	if ad.GeneratedImages != nil {
 		return json.Marshal(ad.GeneratedImages)
 	}
+	if ad.Redemption != nil {
+		return json.Marshal(ad.Redemption)
+	}
+	if ad.OverlayEvent != nil {
+		return json.Marshal(ad.OverlayEvent)
+	}
+	if ad.CommandRejected != nil {
+		return json.Marshal(ad.CommandRejected)
+	}
 	return json.Marshal(nil)
 }
+
+// ParseAlertData decodes a JSON-serialized alert payload, as produced by
+// AlertData.MarshalJSON, for an alert of the given type
+func ParseAlertData(alertType string, data json.RawMessage) (AlertData, error) {
+	var ad AlertData
+	var target any
+	switch alertType {
+	case AlertTypeFollow:
+		ad.Follow = &AlertDataFollow{}
+		target = ad.Follow
+	case AlertTypeSubscribe:
+		ad.Subscribe = &AlertDataSubscribe{}
+		target = ad.Subscribe
+	case AlertTypeGiftSub:
+		ad.GiftSub = &AlertDataGiftSub{}
+		target = ad.GiftSub
+	case AlertTypeRaid:
+		ad.Raid = &AlertDataRaid{}
+		target = ad.Raid
+	case AlertTypeCheer:
+		ad.Cheer = &AlertDataCheer{}
+		target = ad.Cheer
+	case AlertTypeGeneratedImages:
+		ad.GeneratedImages = &AlertDataGeneratedImages{}
+		target = ad.GeneratedImages
+	case AlertTypeRedemption:
+		ad.Redemption = &AlertDataRedemption{}
+		target = ad.Redemption
+	case AlertTypeOverlayEvent:
+		ad.OverlayEvent = &AlertDataOverlayEvent{}
+		target = ad.OverlayEvent
+	case AlertTypeCommandRejected:
+		ad.CommandRejected = &AlertDataCommandRejected{}
+		target = ad.CommandRejected
+	default:
+		return AlertData{}, fmt.Errorf("unsupported alert type '%s'", alertType)
+	}
+	if err := json.Unmarshal(data, target); err != nil {
+		return AlertData{}, fmt.Errorf("failed to decode '%s' alert data: %w", alertType, err)
+	}
+	return ad, nil
+}
+
+// SetText replaces the viewer-supplied text displayed by an alert, e.g. a subscription
+// message or the description of a ghost image, so that the broadcaster can edit it
+// before it's displayed. The text is displayed literally: for cheers, any cheermotes
+// are discarded.
+func (ad *AlertData) SetText(text string) error {
+	switch {
+	case ad.Subscribe != nil:
+		ad.Subscribe.Message = text
+	case ad.Cheer != nil:
+		ad.Cheer.Message = strings.ReplaceAll(text, "$", "$$")
+		ad.Cheer.Cheermotes = []CheermoteToken{}
+	case ad.GeneratedImages != nil:
+		ad.GeneratedImages.Description = text
+	case ad.Redemption != nil:
+		ad.Redemption.Message = text
+	case ad.OverlayEvent != nil:
+		ad.OverlayEvent.Message = text
+	default:
+		return ErrNoEditableText
+	}
+	return nil
+}
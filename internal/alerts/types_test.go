@@ -0,0 +1,46 @@
+package alerts
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_ParseAlertData(t *testing.T) {
+	tests := []AlertData{
+		{Follow: &AlertDataFollow{Username: "alice"}},
+		{Subscribe: &AlertDataSubscribe{Username: "alice", NumCumulativeMonths: 3, Message: "hi"}},
+		{GiftSub: &AlertDataGiftSub{Username: "alice", NumSubscriptions: 2, Recipients: []string{"bob", "carol"}}},
+		{Raid: &AlertDataRaid{Username: "alice", NumViewers: 15}},
+		{Cheer: &AlertDataCheer{Username: "alice", NumBits: 100, Message: "$0 hi", Cheermotes: []CheermoteToken{{Name: "Cheer100", NumBits: 100}}}},
+		{GeneratedImages: &AlertDataGeneratedImages{Username: "alice", Description: "a ghost", Urls: []string{"https://example.com/1.png"}}},
+		{Redemption: &AlertDataRedemption{Username: "alice", RewardTitle: "Hydrate"}},
+		{OverlayEvent: &AlertDataOverlayEvent{Event: "confetti", Username: "alice"}},
+		{CommandRejected: &AlertDataCommandRejected{Username: "alice", Command: "!ghost", Reason: "too many ghosts"}},
+	}
+	alertTypes := []string{
+		AlertTypeFollow,
+		AlertTypeSubscribe,
+		AlertTypeGiftSub,
+		AlertTypeRaid,
+		AlertTypeCheer,
+		AlertTypeGeneratedImages,
+		AlertTypeRedemption,
+		AlertTypeOverlayEvent,
+		AlertTypeCommandRejected,
+	}
+	for i, want := range tests {
+		t.Run(alertTypes[i], func(t *testing.T) {
+			data, err := json.Marshal(want)
+			assert.NoError(t, err)
+			got, err := ParseAlertData(alertTypes[i], data)
+			assert.NoError(t, err)
+			assert.Equal(t, want, got)
+		})
+	}
+	t.Run("unsupported type is an error", func(t *testing.T) {
+		_, err := ParseAlertData("bogus", json.RawMessage(`{}`))
+		assert.Error(t, err)
+	})
+}
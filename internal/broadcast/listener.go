@@ -11,36 +11,53 @@ import (
 	"github.com/lib/pq"
 )
 
+// ChangeListener tracks the state of the current broadcast in each of a fixed set of
+// Twitch channels, identified by the user IDs of their broadcasters
 type ChangeListener struct {
-	pql *pq.Listener
+	pql      *pq.Listener
+	channels map[string]*channelListener
+}
+
+// channelListener tracks the state of the current broadcast in a single channel
+type channelListener struct {
+	channelId string
 
 	lastKnownBroadcastId        int
 	lastKnownBroadcastStartedAt time.Time
 	lastKnownScreeningStartedAt time.Time
+	lastKnownChannelUpdate      ChannelUpdateEventData
 
 	state        State
 	stateChanges chan State
 }
 
-func NewChangeListener(ctx context.Context, pql *pq.Listener, q Queries) (*ChangeListener, error) {
+func NewChangeListener(ctx context.Context, pql *pq.Listener, q Queries, channelIds []string) (*ChangeListener, error) {
 	err := pql.Listen(changeEventNotifyChannel)
 	if err != nil {
 		return nil, err
 	}
-	l := &ChangeListener{pql: pql, stateChanges: make(chan State)}
-	if err := l.initialize(ctx, q); err != nil {
-		return nil, err
+	l := &ChangeListener{pql: pql, channels: make(map[string]*channelListener, len(channelIds))}
+	for _, channelId := range channelIds {
+		c := &channelListener{channelId: channelId, stateChanges: make(chan State)}
+		if err := c.initialize(ctx, q); err != nil {
+			return nil, err
+		}
+		fmt.Printf("STATE INIT [%s]: %+v\n", channelId, c.state)
+		l.channels[channelId] = c
 	}
-	fmt.Printf("STATE INIT: %+v\n", l.state)
 	return l, nil
 }
 
-func (l *ChangeListener) GetState() State {
-	return l.state
+// GetState returns the current state of the broadcast in the given channel, which
+// must be one of the channels that the ChangeListener was initialized with
+func (l *ChangeListener) GetState(channelId string) State {
+	return l.channels[channelId].state
 }
 
-func (l *ChangeListener) GetStateChanges() <-chan State {
-	return l.stateChanges
+// GetStateChanges returns a channel that receives the new state of the broadcast in
+// the given channel whenever it changes
+func (l *ChangeListener) GetStateChanges(channelId string) <-chan State {
+	return l.channels[channelId].stateChanges
 }
 
 func (l *ChangeListener) Run(ctx context.Context) error {
@@ -61,7 +78,19 @@ func (l *ChangeListener) Run(ctx context.Context) error {
 						if err := json.Unmarshal(event.Data, &data); err != nil {
 							return fmt.Errorf("failed to decode JSON data for '%s' event in channel '%s': %w", event.Type, notification.Channel, err)
 						}
-						l.handleBroadcastChange(&data)
+						if c, ok := l.channels[data.ChannelId]; ok {
+							c.handleBroadcastChange(&data)
+						}
+					}
+				case EventTypeBroadcastDeleted:
+					{
+						var data BroadcastEventData
+						if err := json.Unmarshal(event.Data, &data); err != nil {
+							return fmt.Errorf("failed to decode JSON data for '%s' event in channel '%s': %w", event.Type, notification.Channel, err)
+						}
+						if c, ok := l.channels[data.ChannelId]; ok {
+							c.handleBroadcastDeleted(&data)
+						}
 					}
 				case EventTypeScreening:
 					{
@@ -69,7 +98,19 @@ func (l *ChangeListener) Run(ctx context.Context) error {
 						if err := json.Unmarshal(event.Data, &data); err != nil {
 							return fmt.Errorf("failed to decode JSON data for '%s' event in channel '%s': %w", event.Type, notification.Channel, err)
 						}
-						l.handleScreeningChange(&data)
+						if c, ok := l.channels[data.ChannelId]; ok {
+							c.handleScreeningChange(&data)
+						}
+					}
+				case EventTypeChannelUpdate:
+					{
+						var data ChannelUpdateEventData
+						if err := json.Unmarshal(event.Data, &data); err != nil {
+							return fmt.Errorf("failed to decode JSON data for '%s' event in channel '%s': %w", event.Type, notification.Channel, err)
+						}
+						if c, ok := l.channels[data.ChannelId]; ok {
+							c.handleChannelUpdateChange(&data)
+						}
 					}
 				default:
 					return fmt.Errorf("unrecognized event type '%s' in channel '%s'", event.Type, notification.Channel)
@@ -79,8 +120,21 @@ func (l *ChangeListener) Run(ctx context.Context) error {
 	}
 }
 
-func (l *ChangeListener) initialize(ctx context.Context, q Queries) error {
-	broadcast, err := q.GetMostRecentBroadcast(ctx)
+func (l *channelListener) initialize(ctx context.Context, q Queries) error {
+	channelUpdate, err := q.GetMostRecentChannelUpdate(ctx, l.channelId)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+	if err == nil {
+		l.lastKnownChannelUpdate = ChannelUpdateEventData{
+			Title:        channelUpdate.Title,
+			CategoryId:   channelUpdate.CategoryID,
+			CategoryName: channelUpdate.CategoryName,
+			ChangedAt:    channelUpdate.ChangedAt,
+		}
+	}
+
+	broadcast, err := q.GetMostRecentBroadcast(ctx, l.channelId)
 	if err != nil && !errors.Is(err, sql.ErrNoRows) {
 		return err
 	}
@@ -90,6 +144,9 @@ func (l *ChangeListener) initialize(ctx context.Context, q Queries) error {
 		if !broadcast.EndedAt.Valid {
 			l.state.IsLive = true
 			l.state.BroadcastStartedAt = &broadcast.StartedAt
+			l.state.Title = l.lastKnownChannelUpdate.Title
+			l.state.CategoryId = l.lastKnownChannelUpdate.CategoryId
+			l.state.CategoryName = l.lastKnownChannelUpdate.CategoryName
 
 			screening, err := q.GetMostRecentScreening(ctx, broadcast.ID)
 			if err != nil && !errors.Is(err, sql.ErrNoRows) {
@@ -107,7 +164,7 @@ func (l *ChangeListener) initialize(ctx context.Context, q Queries) error {
 	return nil
 }
 
-func (l *ChangeListener) handleBroadcastChange(data *BroadcastEventData) {
+func (l *channelListener) handleBroadcastChange(data *BroadcastEventData) {
 	if data.StartedAt.Before(l.lastKnownBroadcastStartedAt) {
 		return
 	}
@@ -124,7 +181,21 @@ func (l *ChangeListener) handleBroadcastChange(data *BroadcastEventData) {
 	}
 }
 
-func (l *ChangeListener) handleScreeningChange(data *ScreeningEventData) {
+func (l *channelListener) handleBroadcastDeleted(data *BroadcastEventData) {
+	if data.Id != l.lastKnownBroadcastId {
+		return
+	}
+
+	// The broadcast we were tracking no longer exists, which means it's been merged
+	// into the previous broadcast: forget what we know so that the next change to the
+	// surviving broadcast (and its screenings) will be accepted, even though it started
+	// earlier
+	l.lastKnownBroadcastId = 0
+	l.lastKnownBroadcastStartedAt = time.Time{}
+	l.lastKnownScreeningStartedAt = time.Time{}
+}
+
+func (l *channelListener) handleScreeningChange(data *ScreeningEventData) {
 	if data.BroadcastId != l.lastKnownBroadcastId {
 		return
 	}
@@ -151,8 +222,27 @@ func (l *ChangeListener) handleScreeningChange(data *ScreeningEventData) {
 	}
 }
 
-func (l *ChangeListener) updateState(state *State) {
-	fmt.Printf("STATE CHANGE: %+v\n", state)
+func (l *channelListener) handleChannelUpdateChange(data *ChannelUpdateEventData) {
+	if data.ChangedAt.Before(l.lastKnownChannelUpdate.ChangedAt) {
+		return
+	}
+	l.lastKnownChannelUpdate = *data
+
+	// The channel's title and category are only reported while we're live
+	if !l.state.IsLive {
+		return
+	}
+	state := l.state
+	l.updateState(&state)
+}
+
+func (l *channelListener) updateState(state *State) {
+	if state.IsLive {
+		state.Title = l.lastKnownChannelUpdate.Title
+		state.CategoryId = l.lastKnownChannelUpdate.CategoryId
+		state.CategoryName = l.lastKnownChannelUpdate.CategoryName
+	}
+	fmt.Printf("STATE CHANGE [%s]: %+v\n", l.channelId, state)
 	l.state = *state
 	l.stateChanges <- *state
 }
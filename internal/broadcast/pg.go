@@ -13,8 +13,10 @@ const changeEventNotifyChannel = "showtime"
 type EventType string
 
 const (
-	EventTypeBroadcast EventType = "broadcast"
-	EventTypeScreening EventType = "screening"
+	EventTypeBroadcast        EventType = "broadcast"
+	EventTypeBroadcastDeleted EventType = "broadcast_deleted"
+	EventTypeScreening        EventType = "screening"
+	EventTypeChannelUpdate    EventType = "channel_update"
 )
 
 // ChangeEvent is a JSON-encoded payload emitted via ChangeEventNotifyChannel
@@ -24,9 +26,11 @@ type ChangeEvent struct {
 }
 
 // BroadcastEventData is the data for a ChangeEvent of type 'broadcast', representing an
-// insert or update in the showtime.broadcast table
+// insert or update in the showtime.broadcast table, or of type 'broadcast_deleted',
+// representing a broadcast that's been deleted (i.e. merged into the previous one)
 type BroadcastEventData struct {
 	Id        int        `json:"id"`
+	ChannelId string     `json:"channel_id"`
 	StartedAt time.Time  `json:"started_at"`
 	EndedAt   *time.Time `json:"ended_at"`
 }
@@ -34,8 +38,20 @@ type BroadcastEventData struct {
 // ScreeningEventData is the data for a ChangeEvent of type 'screening', representing an
 // insert or update in the showtime.screening table
 type ScreeningEventData struct {
+	ChannelId   string     `json:"channel_id"`
 	BroadcastId int        `json:"broadcast_id"`
 	TapeId      int        `json:"tape_id"`
 	StartedAt   time.Time  `json:"started_at"`
 	EndedAt     *time.Time `json:"ended_at"`
 }
+
+// ChannelUpdateEventData is the data for a ChangeEvent of type 'channel_update',
+// representing an insert in the showtime.channel_update table
+type ChannelUpdateEventData struct {
+	ChannelId    string    `json:"channel_id"`
+	BroadcastId  *int      `json:"broadcast_id"`
+	Title        string    `json:"title"`
+	CategoryId   string    `json:"category_id"`
+	CategoryName string    `json:"category_name"`
+	ChangedAt    time.Time `json:"changed_at"`
+}
@@ -8,8 +8,9 @@ import (
 )
 
 type Queries interface {
-	GetMostRecentBroadcast(ctx context.Context) (queries.GetMostRecentBroadcastRow, error)
+	GetMostRecentBroadcast(ctx context.Context, channelID string) (queries.GetMostRecentBroadcastRow, error)
 	GetMostRecentScreening(ctx context.Context, broadcastID int32) (queries.GetMostRecentScreeningRow, error)
+	GetMostRecentChannelUpdate(ctx context.Context, channelID string) (queries.GetMostRecentChannelUpdateRow, error)
 }
 
 // State describes the state of the broadcast that's currently happening, if any
@@ -24,4 +25,12 @@ type State struct {
 	// ScreeningStartedAt is the UTC timestamp indicating when we started screening the
 	// current tape, if a tape is currently being screened
 	ScreeningStartedAt *time.Time `json:"screeningStartedAt,omitempty"`
+	// Title is the current title of the stream, if a broadcast is live
+	Title string `json:"title,omitempty"`
+	// CategoryId is the ID of the Twitch category that the stream is currently in, if
+	// a broadcast is live
+	CategoryId string `json:"categoryId,omitempty"`
+	// CategoryName is the name of the Twitch category that the stream is currently in,
+	// if a broadcast is live
+	CategoryName string `json:"categoryName,omitempty"`
 }
@@ -0,0 +1,42 @@
+package cheers
+
+import (
+	"context"
+
+	"github.com/golden-vcr/auth"
+)
+
+// Command is an action that a viewer can trigger by cheering with a specific number of
+// bits and a message in the format expected by the command. Since viewers are credited
+// with fun points for every bit they cheer with, a command is paid for by spending
+// those points: if the command fails, the points must be refunded.
+type Command struct {
+	// Name identifies the command, e.g. 'ghost'
+	Name string
+	// NumBits is the exact number of bits with which a viewer must cheer in order to
+	// invoke the command
+	NumBits int
+	// Parse determines whether a cheer message invokes this command, and if so,
+	// extracts its arguments
+	Parse ParseFunc
+	// Run carries out the command on behalf of a viewer, given the arguments returned
+	// by Parse
+	Run RunFunc
+}
+
+// ParseFunc examines the message that accompanied a cheer: if the message doesn't
+// invoke the command, it returns false. If the message invokes the command but can't
+// be carried out as written, it returns true along with an error that explains why,
+// in terms that are suitable to display to the viewer.
+type ParseFunc func(message string) (string, bool, error)
+
+// RunFunc carries out a command on behalf of a viewer. If it returns an error, any
+// fun points spent on the command must have been refunded.
+type RunFunc func(ctx context.Context, viewer *Viewer, args string) error
+
+// Viewer identifies the viewer who invoked a command, along with an authoritative
+// access token that can be used to spend their fun points
+type Viewer struct {
+	User        auth.UserDetails
+	AccessToken string
+}
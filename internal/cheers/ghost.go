@@ -0,0 +1,54 @@
+package cheers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/golden-vcr/auth"
+	"github.com/golden-vcr/showtime/internal/imagegen"
+)
+
+const CommandGhost = "ghost"
+
+// ghostPrefix is the phrase that introduces the subject of a ghost alert, e.g. 'ghost
+// of a friendly cat'
+const ghostPrefix = "ghost of "
+
+// ImageGenerator generates images to be displayed onscreen as ghost alerts, spending
+// the viewer's fun points in the process
+type ImageGenerator interface {
+	Generate(ctx context.Context, accessToken string, user *auth.UserDetails, subject string) error
+}
+
+// NewGhostCommand returns a command that allows viewers to cheer with the given
+// number of bits, with "ghost of <subject>" in their message, in order to generate a
+// ghost alert
+func NewGhostCommand(numBits int, g ImageGenerator) *Command {
+	return &Command{
+		Name:    CommandGhost,
+		NumBits: numBits,
+		Parse:   ParseGhost,
+		Run: func(ctx context.Context, viewer *Viewer, subject string) error {
+			return g.Generate(ctx, viewer.AccessToken, &viewer.User, subject)
+		},
+	}
+}
+
+// ParseGhost extracts the subject of a ghost alert from a cheer message, i.e.
+// everything that follows "ghost of", rejecting any subject that the image generation
+// API would reject
+func ParseGhost(message string) (string, bool, error) {
+	prefixPos := strings.Index(strings.ToLower(message), ghostPrefix)
+	if prefixPos < 0 {
+		return "", false, nil
+	}
+	subject := strings.TrimSpace(message[prefixPos+len(ghostPrefix):])
+	if subject == "" {
+		return "", true, fmt.Errorf("no subject was given after \"ghost of\"")
+	}
+	if err := imagegen.ValidateSubject(subject); err != nil {
+		return "", true, err
+	}
+	return subject, true, nil
+}
@@ -0,0 +1,104 @@
+package cheers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/golden-vcr/auth"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_ParseGhost(t *testing.T) {
+	tests := []struct {
+		name        string
+		message     string
+		wantSubject string
+		wantOk      bool
+		wantErr     string
+	}{
+		{
+			"message without prefix does not invoke command",
+			"Cheer200 great tape",
+			"",
+			false,
+			"",
+		},
+		{
+			"subject follows prefix",
+			"Cheer200 ghost of a friendly cat",
+			"a friendly cat",
+			true,
+			"",
+		},
+		{
+			"prefix is case-insensitive",
+			"Cheer200 Ghost Of Christmas Past  ",
+			"Christmas Past",
+			true,
+			"",
+		},
+		{
+			"empty subject is invalid",
+			"Cheer200 ghost of    ",
+			"",
+			true,
+			"no subject was given after \"ghost of\"",
+		},
+		{
+			"overly long subject is invalid",
+			"ghost of " + strings.Repeat("a", 121),
+			"",
+			true,
+			"'subject' must be <= 120 characters",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			subject, ok, err := ParseGhost(tt.message)
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tt.wantSubject, subject)
+			assert.Equal(t, tt.wantOk, ok)
+		})
+	}
+}
+
+func Test_NewGhostCommand(t *testing.T) {
+	g := &mockImageGenerator{}
+	command := NewGhostCommand(200, g)
+	assert.Equal(t, CommandGhost, command.Name)
+	assert.Equal(t, 200, command.NumBits)
+
+	err := command.Run(context.Background(), &Viewer{
+		User: auth.UserDetails{
+			Id:          "1234",
+			Login:       "bigjim",
+			DisplayName: "BigJim",
+		},
+		AccessToken: "token",
+	}, "a friendly cat")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"token:BigJim:a friendly cat"}, g.requests)
+
+	g.err = fmt.Errorf("mock error")
+	err = command.Run(context.Background(), &Viewer{}, "a friendly cat")
+	assert.EqualError(t, err, "mock error")
+}
+
+type mockImageGenerator struct {
+	err      error
+	requests []string
+}
+
+func (m *mockImageGenerator) Generate(ctx context.Context, accessToken string, user *auth.UserDetails, subject string) error {
+	if m.err != nil {
+		return m.err
+	}
+	m.requests = append(m.requests, fmt.Sprintf("%s:%s:%s", accessToken, user.DisplayName, subject))
+	return nil
+}
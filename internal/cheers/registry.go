@@ -0,0 +1,41 @@
+package cheers
+
+// Registry is the set of commands that viewers can invoke via cheers
+type Registry struct {
+	commands []*Command
+}
+
+// Invocation is a command that a viewer has invoked, along with its parsed arguments
+type Invocation struct {
+	Command *Command
+	Args    string
+}
+
+func NewRegistry(commands ...*Command) *Registry {
+	return &Registry{
+		commands: commands,
+	}
+}
+
+// Match finds the command, if any, that's invoked by a cheer of the given number of
+// bits with the given message. Returns nil if no command is invoked. If a command is
+// invoked but its arguments are invalid, the invocation is returned along with the
+// error reported by the command's parser. A message is only considered to invoke a
+// command if the viewer cheered with exactly the command's price.
+func (r *Registry) Match(numBits int, message string) (*Invocation, error) {
+	for _, command := range r.commands {
+		if command.NumBits != numBits {
+			continue
+		}
+		args, ok, err := command.Parse(message)
+		if !ok {
+			continue
+		}
+		invocation := &Invocation{
+			Command: command,
+			Args:    args,
+		}
+		return invocation, err
+	}
+	return nil, nil
+}
@@ -0,0 +1,99 @@
+package cheers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Registry_Match(t *testing.T) {
+	echo := &Command{
+		Name:    "echo",
+		NumBits: 100,
+		Parse: func(message string) (string, bool, error) {
+			if !strings.HasPrefix(message, "echo") {
+				return "", false, nil
+			}
+			args := strings.TrimSpace(strings.TrimPrefix(message, "echo"))
+			if args == "" {
+				return "", true, fmt.Errorf("nothing to echo")
+			}
+			return args, true, nil
+		},
+		Run: func(ctx context.Context, viewer *Viewer, args string) error {
+			return nil
+		},
+	}
+	ghost := NewGhostCommand(200, &mockImageGenerator{})
+	r := NewRegistry(echo, ghost)
+
+	tests := []struct {
+		name        string
+		numBits     int
+		message     string
+		wantCommand string
+		wantArgs    string
+		wantErr     string
+	}{
+		{
+			"ordinary cheer invokes no command",
+			100,
+			"nice tape",
+			"",
+			"",
+			"",
+		},
+		{
+			"command is invoked with args",
+			100,
+			"echo hello",
+			"echo",
+			"hello",
+			"",
+		},
+		{
+			"command is not invoked if bits do not match price",
+			101,
+			"echo hello",
+			"",
+			"",
+			"",
+		},
+		{
+			"invalid args are reported along with the invocation",
+			100,
+			"echo",
+			"echo",
+			"",
+			"nothing to echo",
+		},
+		{
+			"commands are matched by price",
+			200,
+			"ghost of a friendly cat",
+			"ghost",
+			"a friendly cat",
+			"",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			invocation, err := r.Match(tt.numBits, tt.message)
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+			} else {
+				assert.NoError(t, err)
+			}
+			if tt.wantCommand == "" {
+				assert.Nil(t, invocation)
+			} else {
+				assert.NotNil(t, invocation)
+				assert.Equal(t, tt.wantCommand, invocation.Command.Name)
+				assert.Equal(t, tt.wantArgs, invocation.Args)
+			}
+		})
+	}
+}
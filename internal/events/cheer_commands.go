@@ -0,0 +1,68 @@
+package events
+
+import (
+	"fmt"
+
+	"github.com/golden-vcr/auth"
+	"github.com/golden-vcr/showtime/internal/alerts"
+	"github.com/golden-vcr/showtime/internal/cheers"
+	"github.com/golden-vcr/showtime/internal/points"
+	"github.com/nicklaw5/helix/v2"
+)
+
+// getCheerCommands returns the set of commands that viewers may currently invoke by
+// cheering, priced according to the given rules
+func (h *Handler) getCheerCommands(rules *points.Rules) *cheers.Registry {
+	commands := make([]*cheers.Command, 0)
+	if h.imageGenerator != nil && rules.GhostAlertBits > 0 {
+		commands = append(commands, cheers.NewGhostCommand(rules.GhostAlertBits, h.imageGenerator))
+	}
+	return cheers.NewRegistry(commands...)
+}
+
+// runCheerCommand carries out the command invoked by a cheer, if any, in the
+// background. The viewer has already been credited with fun points for their bits, so
+// the command spends those points: if it's invalid or fails, the points are left in
+// (or refunded to) the viewer's balance, and the viewer is notified via an alert.
+func (h *Handler) runCheerCommand(ev *helix.EventSubChannelCheerEvent, accessToken string, rules *points.Rules) {
+	invocation, err := h.getCheerCommands(rules).Match(ev.Bits, ev.Message)
+	if invocation == nil {
+		return
+	}
+	if err != nil {
+		fmt.Printf("Rejecting invalid '%s' command from user %s: %v\n", invocation.Command.Name, ev.UserName, err)
+		h.emitCommandRejectedAlert(ev.UserName, invocation.Command.Name, err.Error())
+		return
+	}
+
+	viewer := &cheers.Viewer{
+		User: auth.UserDetails{
+			Id:          ev.UserID,
+			Login:       ev.UserLogin,
+			DisplayName: ev.UserName,
+		},
+		AccessToken: accessToken,
+	}
+	fmt.Printf("Running '%s' command on behalf of user %s, with args '%s'\n", invocation.Command.Name, ev.UserName, invocation.Args)
+	go func() {
+		if err := invocation.Command.Run(h.backgroundCtx, viewer, invocation.Args); err != nil {
+			fmt.Printf("Failed to run '%s' command on behalf of user %s: %v\n", invocation.Command.Name, ev.UserName, err)
+			h.emitCommandRejectedAlert(ev.UserName, invocation.Command.Name, "something went wrong; your fun points have been refunded")
+			return
+		}
+		fmt.Printf("Successfully ran '%s' command on behalf of user %s\n", invocation.Command.Name, ev.UserName)
+	}()
+}
+
+func (h *Handler) emitCommandRejectedAlert(username string, command string, reason string) {
+	h.alertsChan <- &alerts.Alert{
+		Type: alerts.AlertTypeCommandRejected,
+		Data: alerts.AlertData{
+			CommandRejected: &alerts.AlertDataCommandRejected{
+				Username: username,
+				Command:  command,
+				Reason:   reason,
+			},
+		},
+	}
+}
@@ -0,0 +1,148 @@
+package events
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golden-vcr/showtime/internal/alerts"
+	"github.com/golden-vcr/showtime/internal/twitch"
+	"github.com/nicklaw5/helix/v2"
+)
+
+// cheermoteTokenRegex matches a single word in a cheer message that may be a
+// cheermote, e.g. 'Cheer100', capturing the prefix and the number of bits
+var cheermoteTokenRegex = regexp.MustCompile(`^([A-Za-z][A-Za-z0-9]*?)([1-9][0-9]*)$`)
+
+// ResolveCheermotesFunc converts the text of a cheer message into the format used by
+// alerts.AlertDataCheer, replacing each cheermote with a reference to a
+// CheermoteToken
+type ResolveCheermotesFunc func(message string) (string, []alerts.CheermoteToken, error)
+
+// CheermoteResolver resolves the cheermotes used in cheer messages to image URLs,
+// using the set of cheermotes available in a channel: that set is fetched from the
+// Twitch API and cached for a while, since it rarely changes
+type CheermoteResolver struct {
+	c             twitch.CheermoteReader
+	broadcasterId string
+	ttl           time.Duration
+	now           func() time.Time
+
+	mu         sync.Mutex
+	cheermotes map[string]*helix.Cheermotes
+	fetchedAt  time.Time
+}
+
+func NewCheermoteResolver(c twitch.CheermoteReader, broadcasterId string) *CheermoteResolver {
+	return &CheermoteResolver{
+		c:             c,
+		broadcasterId: broadcasterId,
+		ttl:           time.Hour,
+		now:           time.Now,
+	}
+}
+
+// Resolve returns the given cheer message with each cheermote replaced by '$i', where
+// i is the index of the corresponding token, and with all literal dollar signs
+// escaped as '$$'. If the set of cheermotes can't be fetched, an error is returned
+// along with a result in which no cheermotes have been resolved.
+func (r *CheermoteResolver) Resolve(message string) (string, []alerts.CheermoteToken, error) {
+	cheermotes, err := r.getCheermotes()
+	text, tokens := substituteCheermotes(message, cheermotes)
+	return text, tokens, err
+}
+
+func (r *CheermoteResolver) getCheermotes() (map[string]*helix.Cheermotes, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.cheermotes != nil && r.now().Sub(r.fetchedAt) < r.ttl {
+		return r.cheermotes, nil
+	}
+
+	res, err := r.c.GetCheermotes(&helix.CheermotesParams{
+		BroadcasterID: r.broadcasterId,
+	})
+	if err == nil && res.ErrorStatus != 0 {
+		err = fmt.Errorf("got response %d from Twitch API: %s", res.ErrorStatus, res.ErrorMessage)
+	}
+	if err != nil {
+		// Keep using stale cheermotes, if we have any, rather than failing outright
+		return r.cheermotes, fmt.Errorf("failed to get cheermotes: %w", err)
+	}
+
+	cheermotes := make(map[string]*helix.Cheermotes)
+	for i := range res.Data.Cheermotes {
+		cheermote := &res.Data.Cheermotes[i]
+		cheermotes[strings.ToLower(cheermote.Prefix)] = cheermote
+	}
+	r.cheermotes = cheermotes
+	r.fetchedAt = r.now()
+	return r.cheermotes, nil
+}
+
+// substituteCheermotes replaces each cheermote in a message with a '$i' reference to
+// a CheermoteToken, given the set of available cheermotes, keyed by lowercase prefix
+func substituteCheermotes(message string, cheermotes map[string]*helix.Cheermotes) (string, []alerts.CheermoteToken) {
+	words := strings.Split(strings.ReplaceAll(message, "$", "$$"), " ")
+	tokens := make([]alerts.CheermoteToken, 0)
+	indicesByName := make(map[string]int)
+	for i, word := range words {
+		// If we've already seen the same cheermote, reuse the existing token
+		if index, ok := indicesByName[word]; ok {
+			words[i] = fmt.Sprintf("$%d", index)
+			continue
+		}
+
+		// Otherwise, check whether this word is a cheermote we know about
+		token, ok := resolveCheermoteToken(word, cheermotes)
+		if !ok {
+			continue
+		}
+		index := len(tokens)
+		tokens = append(tokens, token)
+		indicesByName[word] = index
+		words[i] = fmt.Sprintf("$%d", index)
+	}
+	return strings.Join(words, " "), tokens
+}
+
+func resolveCheermoteToken(word string, cheermotes map[string]*helix.Cheermotes) (alerts.CheermoteToken, bool) {
+	match := cheermoteTokenRegex.FindStringSubmatch(word)
+	if match == nil {
+		return alerts.CheermoteToken{}, false
+	}
+	cheermote, ok := cheermotes[strings.ToLower(match[1])]
+	if !ok {
+		return alerts.CheermoteToken{}, false
+	}
+	numBits, err := strconv.Atoi(match[2])
+	if err != nil {
+		return alerts.CheermoteToken{}, false
+	}
+
+	// Use the image for the highest tier that this number of bits qualifies for
+	tiers := make([]helix.CheermoteTiers, len(cheermote.Tiers))
+	copy(tiers, cheermote.Tiers)
+	sort.Slice(tiers, func(i, j int) bool {
+		return tiers[i].MinBits > tiers[j].MinBits
+	})
+	for _, tier := range tiers {
+		if uint(numBits) >= tier.MinBits {
+			return alerts.CheermoteToken{
+				Name:    word,
+				NumBits: numBits,
+				Color:   tier.Color,
+				Url:     tier.Images.Dark.Animated.Image2,
+			}, true
+		}
+	}
+	return alerts.CheermoteToken{
+		Name:    word,
+		NumBits: numBits,
+	}, true
+}
@@ -0,0 +1,156 @@
+package events
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/golden-vcr/showtime/internal/alerts"
+	"github.com/nicklaw5/helix/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_CheermoteResolver_Resolve(t *testing.T) {
+	tests := []struct {
+		name           string
+		message        string
+		getErr         error
+		wantText       string
+		wantCheermotes []alerts.CheermoteToken
+		wantErr        string
+	}{
+		{
+			"message with no cheermotes is unchanged",
+			"I have $5",
+			nil,
+			"I have $$5",
+			[]alerts.CheermoteToken{},
+			"",
+		},
+		{
+			"cheermotes are resolved to the highest qualifying tier",
+			"Cheer100 nice tape cheer1 Cheer100",
+			nil,
+			"$0 nice tape $1 $0",
+			[]alerts.CheermoteToken{
+				{Name: "Cheer100", NumBits: 100, Color: "#9c3ee8", Url: "https://example.com/cheer/100.gif"},
+				{Name: "cheer1", NumBits: 1, Color: "#979797", Url: "https://example.com/cheer/1.gif"},
+			},
+			"",
+		},
+		{
+			"custom cheermotes are resolved",
+			"GoldenVCR500",
+			nil,
+			"$0",
+			[]alerts.CheermoteToken{
+				{Name: "GoldenVCR500", NumBits: 500, Color: "#ffcc00", Url: "https://example.com/goldenvcr/1.gif"},
+			},
+			"",
+		},
+		{
+			"unknown prefixes are not resolved",
+			"Tape100",
+			nil,
+			"Tape100",
+			[]alerts.CheermoteToken{},
+			"",
+		},
+		{
+			"failure to get cheermotes leaves message unresolved",
+			"Cheer100 nice tape",
+			fmt.Errorf("mock error"),
+			"Cheer100 nice tape",
+			[]alerts.CheermoteToken{},
+			"failed to get cheermotes: mock error",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &CheermoteResolver{
+				c: &mockCheermoteReader{
+					err: tt.getErr,
+				},
+				ttl: time.Hour,
+				now: time.Now,
+			}
+			text, cheermotes, err := r.Resolve(tt.message)
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tt.wantText, text)
+			assert.Equal(t, tt.wantCheermotes, cheermotes)
+		})
+	}
+}
+
+func Test_CheermoteResolver_caching(t *testing.T) {
+	now := time.Date(1997, 9, 1, 12, 0, 0, 0, time.UTC)
+	c := &mockCheermoteReader{}
+	r := &CheermoteResolver{
+		c:   c,
+		ttl: time.Hour,
+		now: func() time.Time {
+			return now
+		},
+	}
+
+	// Cheermotes should be fetched once and reused until they expire
+	_, _, err := r.Resolve("Cheer1")
+	assert.NoError(t, err)
+	_, _, err = r.Resolve("Cheer1")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, c.numCalls)
+
+	// Once expired, we should fall back to stale cheermotes if they can't be refreshed
+	now = now.Add(2 * time.Hour)
+	c.err = fmt.Errorf("mock error")
+	text, cheermotes, err := r.Resolve("Cheer1")
+	assert.Error(t, err)
+	assert.Equal(t, "$0", text)
+	assert.Len(t, cheermotes, 1)
+	assert.Equal(t, 2, c.numCalls)
+}
+
+type mockCheermoteReader struct {
+	err      error
+	numCalls int
+}
+
+func (m *mockCheermoteReader) GetCheermotes(params *helix.CheermotesParams) (*helix.CheermotesResponse, error) {
+	m.numCalls++
+	if m.err != nil {
+		return nil, m.err
+	}
+	tier := func(minBits uint, color string, url string) helix.CheermoteTiers {
+		var images helix.CheermoteTierImages
+		images.Dark.Animated.Image2 = url
+		return helix.CheermoteTiers{
+			MinBits: minBits,
+			Color:   color,
+			Images:  images,
+		}
+	}
+	return &helix.CheermotesResponse{
+		Data: helix.ManyCheermotes{
+			Cheermotes: []helix.Cheermotes{
+				{
+					Prefix: "Cheer",
+					Tiers: []helix.CheermoteTiers{
+						tier(1, "#979797", "https://example.com/cheer/1.gif"),
+						tier(100, "#9c3ee8", "https://example.com/cheer/100.gif"),
+						tier(1000, "#1db2a5", "https://example.com/cheer/1000.gif"),
+					},
+				},
+				{
+					Prefix: "GoldenVCR",
+					Tiers: []helix.CheermoteTiers{
+						tier(1, "#ffcc00", "https://example.com/goldenvcr/1.gif"),
+					},
+				},
+			},
+		},
+	}, nil
+}
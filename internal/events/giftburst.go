@@ -0,0 +1,199 @@
+package events
+
+import (
+	"sync"
+	"time"
+
+	"github.com/golden-vcr/showtime/internal/alerts"
+)
+
+// DefaultGiftBurstWindow is the length of time we'll wait for the individual
+// 'channel.subscribe' events that correspond to a 'channel.subscription.gift' event
+// (or vice versa) before giving up on correlating them
+const DefaultGiftBurstWindow = 5 * time.Second
+
+// giftBurstCoalescer correlates the 'channel.subscription.gift' event that Twitch
+// sends when a viewer gifts a batch of subs with the separate 'channel.subscribe'
+// events that it sends for each recipient, so that the entire burst can be announced
+// with a single alert. Twitch makes no guarantees about the order in which these
+// events arrive, so recipients may be recorded before or after the gift that they
+// belong to: any recipient that can't be matched to a gift within the window is
+// announced individually. Once a burst is complete, the gifter is attributed to each
+// recipient's subscription.
+type giftBurstCoalescer struct {
+	window    time.Duration
+	emit      func(alert *alerts.Alert)
+	attribute func(gifterId string, recipientIds []string)
+
+	mu         sync.Mutex
+	bursts     []*giftBurst
+	recipients []*giftRecipient
+}
+
+// giftBurst is a batch of gift subs that's still waiting for recipients
+type giftBurst struct {
+	gifterId     string
+	gifterName   string
+	tier         string
+	total        int
+	recipients   []string
+	recipientIds []string
+	timer        *time.Timer
+}
+
+// giftRecipient is a viewer who's received a gift sub that hasn't yet been matched to
+// a gifter
+type giftRecipient struct {
+	userId   string
+	username string
+	tier     string
+	timer    *time.Timer
+}
+
+func newGiftBurstCoalescer(window time.Duration, emit func(alert *alerts.Alert), attribute func(gifterId string, recipientIds []string)) *giftBurstCoalescer {
+	return &giftBurstCoalescer{
+		window:    window,
+		emit:      emit,
+		attribute: attribute,
+	}
+}
+
+// addGift registers a batch of gift subs from the given gifter, claiming any
+// unmatched recipients of the same tier. The alert is emitted as soon as all
+// recipients are known, or once the window elapses. gifterId is empty if the gift is
+// anonymous.
+func (c *giftBurstCoalescer) addGift(gifterId string, gifterName string, tier string, total int) {
+	c.mu.Lock()
+	burst := &giftBurst{
+		gifterId:     gifterId,
+		gifterName:   gifterName,
+		tier:         tier,
+		total:        total,
+		recipients:   make([]string, 0, total),
+		recipientIds: make([]string, 0, total),
+	}
+	remaining := make([]*giftRecipient, 0, len(c.recipients))
+	for _, r := range c.recipients {
+		if r.tier == tier && len(burst.recipients) < total {
+			r.timer.Stop()
+			burst.recipients = append(burst.recipients, r.username)
+			burst.recipientIds = append(burst.recipientIds, r.userId)
+		} else {
+			remaining = append(remaining, r)
+		}
+	}
+	c.recipients = remaining
+	if len(burst.recipients) >= total {
+		c.mu.Unlock()
+		c.finish(burst)
+		return
+	}
+	burst.timer = time.AfterFunc(c.window, func() {
+		c.flushBurst(burst)
+	})
+	c.bursts = append(c.bursts, burst)
+	c.mu.Unlock()
+}
+
+// addRecipient registers a viewer who's received a gift sub, adding them to the
+// oldest matching burst that's still waiting for recipients
+func (c *giftBurstCoalescer) addRecipient(userId string, username string, tier string) {
+	c.mu.Lock()
+	for i, burst := range c.bursts {
+		if burst.tier != tier {
+			continue
+		}
+		burst.recipients = append(burst.recipients, username)
+		burst.recipientIds = append(burst.recipientIds, userId)
+		if len(burst.recipients) < burst.total {
+			c.mu.Unlock()
+			return
+		}
+		burst.timer.Stop()
+		c.bursts = append(c.bursts[:i], c.bursts[i+1:]...)
+		c.mu.Unlock()
+		c.finish(burst)
+		return
+	}
+
+	// No gift is waiting for this recipient yet: hold onto them in case the gift
+	// arrives shortly
+	recipient := &giftRecipient{
+		userId:   userId,
+		username: username,
+		tier:     tier,
+	}
+	recipient.timer = time.AfterFunc(c.window, func() {
+		c.flushRecipient(recipient)
+	})
+	c.recipients = append(c.recipients, recipient)
+	c.mu.Unlock()
+}
+
+// flushBurst emits an alert for a burst whose window has elapsed, listing whichever
+// recipients we've seen so far
+func (c *giftBurstCoalescer) flushBurst(burst *giftBurst) {
+	c.mu.Lock()
+	found := false
+	for i := range c.bursts {
+		if c.bursts[i] == burst {
+			c.bursts = append(c.bursts[:i], c.bursts[i+1:]...)
+			found = true
+			break
+		}
+	}
+	c.mu.Unlock()
+	if found {
+		c.finish(burst)
+	}
+}
+
+// flushRecipient announces a recipient who couldn't be matched to a gift within the
+// window
+func (c *giftBurstCoalescer) flushRecipient(recipient *giftRecipient) {
+	c.mu.Lock()
+	found := false
+	for i := range c.recipients {
+		if c.recipients[i] == recipient {
+			c.recipients = append(c.recipients[:i], c.recipients[i+1:]...)
+			found = true
+			break
+		}
+	}
+	c.mu.Unlock()
+	if found {
+		c.emit(&alerts.Alert{
+			Type: alerts.AlertTypeSubscribe,
+			Data: alerts.AlertData{
+				Subscribe: &alerts.AlertDataSubscribe{
+					Username:            recipient.username,
+					IsGift:              true,
+					NumCumulativeMonths: 1,
+					Message:             "",
+				},
+			},
+		})
+	}
+}
+
+// finish announces a burst that's no longer waiting for recipients, and attributes the
+// gifter to each recipient we've seen
+func (c *giftBurstCoalescer) finish(burst *giftBurst) {
+	c.emit(burst.toAlert())
+	if c.attribute != nil && burst.gifterId != "" && len(burst.recipientIds) > 0 {
+		c.attribute(burst.gifterId, burst.recipientIds)
+	}
+}
+
+func (b *giftBurst) toAlert() *alerts.Alert {
+	return &alerts.Alert{
+		Type: alerts.AlertTypeGiftSub,
+		Data: alerts.AlertData{
+			GiftSub: &alerts.AlertDataGiftSub{
+				Username:         b.gifterName,
+				NumSubscriptions: b.total,
+				Recipients:       b.recipients,
+			},
+		},
+	}
+}
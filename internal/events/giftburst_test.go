@@ -0,0 +1,184 @@
+package events
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golden-vcr/showtime/internal/alerts"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_giftBurstCoalescer(t *testing.T) {
+	type giftOrRecipient struct {
+		gifterName    string
+		recipientName string
+		tier          string
+		total         int
+		isAnonymous   bool
+	}
+	gift := func(gifterName string, tier string, total int) giftOrRecipient {
+		return giftOrRecipient{gifterName: gifterName, tier: tier, total: total}
+	}
+	anonymousGift := func(tier string, total int) giftOrRecipient {
+		return giftOrRecipient{gifterName: "Anonymous", tier: tier, total: total, isAnonymous: true}
+	}
+	recipient := func(recipientName string, tier string) giftOrRecipient {
+		return giftOrRecipient{recipientName: recipientName, tier: tier}
+	}
+	tests := []struct {
+		name             string
+		events           []giftOrRecipient
+		wantAlerts       []*alerts.Alert
+		wantAttributions []string
+	}{
+		{
+			"gift followed by all recipients produces a single alert",
+			[]giftOrRecipient{
+				gift("Gifter", "1000", 2),
+				recipient("Alice", "1000"),
+				recipient("Bob", "1000"),
+			},
+			[]*alerts.Alert{
+				giftSubAlert("Gifter", 2, "Alice", "Bob"),
+			},
+			[]string{"Gifter-id:Alice-id,Bob-id"},
+		},
+		{
+			"recipients may arrive before the gift",
+			[]giftOrRecipient{
+				recipient("Alice", "1000"),
+				recipient("Bob", "1000"),
+				gift("Gifter", "1000", 2),
+			},
+			[]*alerts.Alert{
+				giftSubAlert("Gifter", 2, "Alice", "Bob"),
+			},
+			[]string{"Gifter-id:Alice-id,Bob-id"},
+		},
+		{
+			"gift is announced with partial recipients once window elapses",
+			[]giftOrRecipient{
+				gift("Gifter", "1000", 3),
+				recipient("Alice", "1000"),
+			},
+			[]*alerts.Alert{
+				giftSubAlert("Gifter", 3, "Alice"),
+			},
+			[]string{"Gifter-id:Alice-id"},
+		},
+		{
+			"recipients are only matched to gifts of the same tier",
+			[]giftOrRecipient{
+				gift("Gifter", "2000", 1),
+				recipient("Alice", "1000"),
+				recipient("Bob", "2000"),
+			},
+			[]*alerts.Alert{
+				giftSubAlert("Gifter", 1, "Bob"),
+				giftedSubscribeAlert("Alice"),
+			},
+			[]string{"Gifter-id:Bob-id"},
+		},
+		{
+			"concurrent gifts are filled in order",
+			[]giftOrRecipient{
+				gift("First", "1000", 1),
+				gift("Second", "1000", 1),
+				recipient("Alice", "1000"),
+				recipient("Bob", "1000"),
+			},
+			[]*alerts.Alert{
+				giftSubAlert("First", 1, "Alice"),
+				giftSubAlert("Second", 1, "Bob"),
+			},
+			[]string{"First-id:Alice-id", "Second-id:Bob-id"},
+		},
+		{
+			"anonymous gifts are not attributed",
+			[]giftOrRecipient{
+				anonymousGift("1000", 1),
+				recipient("Alice", "1000"),
+			},
+			[]*alerts.Alert{
+				giftSubAlert("Anonymous", 1, "Alice"),
+			},
+			[]string{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			emitted := make(chan *alerts.Alert, 16)
+			var mu sync.Mutex
+			attributions := make([]string, 0)
+			c := newGiftBurstCoalescer(50*time.Millisecond, func(alert *alerts.Alert) {
+				emitted <- alert
+			}, func(gifterId string, recipientIds []string) {
+				mu.Lock()
+				defer mu.Unlock()
+				attributions = append(attributions, fmt.Sprintf("%s:%s", gifterId, strings.Join(recipientIds, ",")))
+			})
+			for _, ev := range tt.events {
+				if ev.gifterName != "" {
+					gifterId := ev.gifterName + "-id"
+					if ev.isAnonymous {
+						gifterId = ""
+					}
+					c.addGift(gifterId, ev.gifterName, ev.tier, ev.total)
+				} else {
+					c.addRecipient(ev.recipientName+"-id", ev.recipientName, ev.tier)
+				}
+			}
+
+			got := make([]*alerts.Alert, 0, len(tt.wantAlerts))
+			for len(got) < len(tt.wantAlerts) {
+				select {
+				case alert := <-emitted:
+					got = append(got, alert)
+				case <-time.After(time.Second):
+					t.Fatalf("timed out waiting for alerts; got %d of %d", len(got), len(tt.wantAlerts))
+				}
+			}
+			assert.Equal(t, tt.wantAlerts, got)
+
+			// Nothing else should be emitted after the window elapses
+			select {
+			case alert := <-emitted:
+				t.Fatalf("got unexpected alert: %+v", alert)
+			case <-time.After(100 * time.Millisecond):
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			assert.Equal(t, tt.wantAttributions, attributions)
+		})
+	}
+}
+
+func giftSubAlert(gifterName string, numSubscriptions int, recipients ...string) *alerts.Alert {
+	return &alerts.Alert{
+		Type: alerts.AlertTypeGiftSub,
+		Data: alerts.AlertData{
+			GiftSub: &alerts.AlertDataGiftSub{
+				Username:         gifterName,
+				NumSubscriptions: numSubscriptions,
+				Recipients:       recipients,
+			},
+		},
+	}
+}
+
+func giftedSubscribeAlert(username string) *alerts.Alert {
+	return &alerts.Alert{
+		Type: alerts.AlertTypeSubscribe,
+		Data: alerts.AlertData{
+			Subscribe: &alerts.AlertDataSubscribe{
+				Username:            username,
+				IsGift:              true,
+				NumCumulativeMonths: 1,
+			},
+		},
+	}
+}
@@ -4,38 +4,115 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"time"
 
 	"github.com/golden-vcr/auth"
 	"github.com/golden-vcr/ledger"
 	"github.com/golden-vcr/showtime/gen/queries"
 	"github.com/golden-vcr/showtime/internal/alerts"
+	"github.com/golden-vcr/showtime/internal/cheers"
+	"github.com/golden-vcr/showtime/internal/progress"
+	"github.com/golden-vcr/showtime/internal/twitch"
 	"github.com/nicklaw5/helix/v2"
 )
 
 var ErrUnsupportedEventType = errors.New("unsupported event type")
+var ErrUnrecognizedChannel = errors.New("event concerns an unrecognized channel")
 
+// Handler handles the events that occur in a single Twitch channel, recording
+// broadcasts, screenings etc. against that channel and writing alerts and progress
+// events to channels that are specific to it
 type Handler struct {
 	q                 *queries.Queries
+	channelUserId     string
 	alertsChan        chan *alerts.Alert
+	progressChan      chan *progress.Event
 	authServiceClient auth.ServiceClient
 	ledgerClient      ledger.Client
-	imagegenUrl       string
-	imagegenCtx       context.Context
+	redemptionUpdater twitch.RedemptionUpdater
+	giftBursts        *giftBurstCoalescer
+	resolveCheermotes ResolveCheermotesFunc
+	cheerAlertMinBits int
+	resumeWindow      time.Duration
+	imageGenerator    cheers.ImageGenerator
+	backgroundCtx     context.Context
 }
 
-func NewHandler(ctx context.Context, q *queries.Queries, alertsChan chan *alerts.Alert, authServiceClient auth.ServiceClient, ledgerClient ledger.Client) *Handler {
-	return &Handler{
+func NewHandler(ctx context.Context, q *queries.Queries, channelUserId string, alertsChan chan *alerts.Alert, progressChan chan *progress.Event, authServiceClient auth.ServiceClient, ledgerClient ledger.Client, redemptionUpdater twitch.RedemptionUpdater, resolveCheermotes ResolveCheermotesFunc, cheerAlertMinBits int, resumeWindow time.Duration, imageGenerator cheers.ImageGenerator) *Handler {
+	h := &Handler{
 		q:                 q,
+		channelUserId:     channelUserId,
 		alertsChan:        alertsChan,
+		progressChan:      progressChan,
 		authServiceClient: authServiceClient,
 		ledgerClient:      ledgerClient,
-		imagegenUrl:       "http://localhost:5001/image-gen",
-		imagegenCtx:       ctx,
+		redemptionUpdater: redemptionUpdater,
+		resolveCheermotes: resolveCheermotes,
+		cheerAlertMinBits: cheerAlertMinBits,
+		resumeWindow:      resumeWindow,
+		imageGenerator:    imageGenerator,
+		backgroundCtx:     ctx,
 	}
+	h.giftBursts = newGiftBurstCoalescer(DefaultGiftBurstWindow, func(alert *alerts.Alert) {
+		h.alertsChan <- alert
+	}, h.recordSubscriptionGifter)
+	return h
+}
+
+type messageIdContextKey struct{}
+
+// WithMessageId returns a context that identifies the EventSub message being handled,
+// so that handlers can ensure that their side effects are only applied once per
+// message
+func WithMessageId(ctx context.Context, messageId string) context.Context {
+	return context.WithValue(ctx, messageIdContextKey{}, messageId)
+}
+
+func messageIdFromContext(ctx context.Context) (string, bool) {
+	messageId, ok := ctx.Value(messageIdContextKey{}).(string)
+	return messageId, ok && messageId != ""
+}
+
+// ChannelUserId returns the Twitch user ID of the broadcaster whose events are
+// handled by this Handler
+func (h *Handler) ChannelUserId() string {
+	return h.channelUserId
+}
+
+// RouteEventsByChannel returns a HandleEventFunc that passes each event to the Handler
+// for the channel that the event's subscription is conditioned on. Events for which
+// no Handler is registered fail with ErrUnrecognizedChannel.
+func RouteEventsByChannel(handlers ...*Handler) HandleEventFunc {
+	handlersByChannelUserId := make(map[string]*Handler, len(handlers))
+	for _, h := range handlers {
+		handlersByChannelUserId[h.channelUserId] = h
+	}
+	return func(ctx context.Context, subscription *helix.EventSubSubscription, data json.RawMessage) error {
+		h, ok := handlersByChannelUserId[getSubscriptionChannelUserId(subscription)]
+		if !ok {
+			return ErrUnrecognizedChannel
+		}
+		return h.HandleEvent(ctx, subscription, data)
+	}
+}
+
+// getSubscriptionChannelUserId returns the user ID of the broadcaster on whose behalf
+// we subscribed to an event: for raids, that's the channel being raided, unless the
+// subscription notifies us of raids from the channel
+func getSubscriptionChannelUserId(subscription *helix.EventSubSubscription) string {
+	if subscription.Type == helix.EventSubTypeChannelRaid {
+		if subscription.Condition.FromBroadcasterUserID != "" {
+			return subscription.Condition.FromBroadcasterUserID
+		}
+		return subscription.Condition.ToBroadcasterUserID
+	}
+	return subscription.Condition.BroadcasterUserID
 }
 
 func (h *Handler) HandleEvent(ctx context.Context, subscription *helix.EventSubSubscription, data json.RawMessage) error {
 	switch subscription.Type {
+	case helix.EventSubTypeChannelUpdate:
+		return h.handleChannelUpdateEvent(ctx, data)
 	case helix.EventSubTypeStreamOnline:
 		return h.handleStreamOnlineEvent(ctx, data)
 	case helix.EventSubTypeStreamOffline:
@@ -43,6 +120,11 @@ func (h *Handler) HandleEvent(ctx context.Context, subscription *helix.EventSubS
 	case helix.EventSubTypeChannelFollow:
 		return h.handleChannelFollowEvent(ctx, data)
 	case helix.EventSubTypeChannelRaid:
+		// We subscribe to raids in both directions: a subscription conditioned on
+		// from_broadcaster_user_id notifies us when we raid another channel
+		if subscription.Condition.FromBroadcasterUserID != "" {
+			return h.handleOutgoingChannelRaidEvent(ctx, data)
+		}
 		return h.handleChannelRaidEvent(ctx, data)
 	case helix.EventSubTypeChannelCheer:
 		return h.handleChannelCheerEvent(ctx, data)
@@ -54,6 +136,28 @@ func (h *Handler) HandleEvent(ctx context.Context, subscription *helix.EventSubS
 		return h.handleChannelSubscriptionGiftEvent(ctx, data)
 	case helix.EventSubTypeChannelSubscriptionMessage:
 		return h.handleChannelSubscriptionMessageEvent(ctx, data)
+	case helix.EventSubTypeChannelPointsCustomRewardRedemptionAdd:
+		return h.handleChannelPointsRedemptionEvent(ctx, data)
+	case helix.EventSubTypeHypeTrainBegin:
+		return h.handleHypeTrainEvent(ctx, progress.PhaseBegin, data)
+	case helix.EventSubTypeHypeTrainProgress:
+		return h.handleHypeTrainEvent(ctx, progress.PhaseProgress, data)
+	case helix.EventSubTypeHypeTrainEnd:
+		return h.handleHypeTrainEndEvent(ctx, data)
+	case helix.EventSubTypeChannelPollBegin:
+		return h.handlePollEvent(ctx, progress.PhaseBegin, data)
+	case helix.EventSubTypeChannelPollProgress:
+		return h.handlePollEvent(ctx, progress.PhaseProgress, data)
+	case helix.EventSubTypeChannelPollEnd:
+		return h.handlePollEndEvent(ctx, data)
+	case helix.EventSubTypeChannelPredictionBegin:
+		return h.handlePredictionEvent(ctx, progress.PhaseBegin, data)
+	case helix.EventSubTypeChannelPredictionProgress:
+		return h.handlePredictionEvent(ctx, progress.PhaseProgress, data)
+	case helix.EventSubTypeChannelPredictionLock:
+		return h.handlePredictionLockEvent(ctx, data)
+	case helix.EventSubTypeChannelPredictionEnd:
+		return h.handlePredictionEndEvent(ctx, data)
 	default:
 		return ErrUnsupportedEventType
 	}
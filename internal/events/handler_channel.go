@@ -1,21 +1,41 @@
 package events
 
 import (
-	"bytes"
 	"context"
+	"database/sql"
 	"encoding/json"
 	"fmt"
-	"io"
-	"net/http"
-	"strings"
 
 	"github.com/golden-vcr/auth"
 	"github.com/golden-vcr/showtime/gen/queries"
 	"github.com/golden-vcr/showtime/internal/alerts"
-	"github.com/golden-vcr/showtime/internal/imagegen"
 	"github.com/nicklaw5/helix/v2"
 )
 
+const (
+	raidDirectionIncoming = "incoming"
+	raidDirectionOutgoing = "outgoing"
+)
+
+func (h *Handler) handleChannelUpdateEvent(ctx context.Context, data json.RawMessage) error {
+	var ev helix.EventSubChannelUpdateEvent
+	if err := json.Unmarshal(data, &ev); err != nil {
+		return fmt.Errorf("failed to unmarshal ChannelUpdateEvent: %w", err)
+	}
+
+	err := h.q.RecordChannelUpdate(ctx, queries.RecordChannelUpdateParams{
+		ChannelID:    h.channelUserId,
+		Title:        ev.Title,
+		CategoryID:   ev.CategoryID,
+		CategoryName: ev.CategoryName,
+	})
+	if err != nil {
+		return fmt.Errorf("RecordChannelUpdate failed: %w", err)
+	}
+	fmt.Printf("Channel updated: title is %q; category is %q\n", ev.Title, ev.CategoryName)
+	return nil
+}
+
 func (h *Handler) handleChannelFollowEvent(ctx context.Context, data json.RawMessage) error {
 	var ev helix.EventSubChannelFollowEvent
 	if err := json.Unmarshal(data, &ev); err != nil {
@@ -48,6 +68,10 @@ func (h *Handler) handleChannelRaidEvent(ctx context.Context, data json.RawMessa
 		return fmt.Errorf("failed to unmarshal ChannelRaidEvent: %w", err)
 	}
 
+	if err := h.recordRaid(ctx, raidDirectionIncoming, ev.FromBroadcasterUserID, ev.FromBroadcasterUserLogin, ev.FromBroadcasterUserName, ev.Viewers); err != nil {
+		return err
+	}
+
 	fmt.Printf("Generating alert for raid by broadcaster %s with %d viewers\n", ev.FromBroadcasterUserName, ev.Viewers)
 	h.alertsChan <- &alerts.Alert{
 		Type: alerts.AlertTypeRaid,
@@ -61,17 +85,58 @@ func (h *Handler) handleChannelRaidEvent(ctx context.Context, data json.RawMessa
 	return nil
 }
 
+func (h *Handler) handleOutgoingChannelRaidEvent(ctx context.Context, data json.RawMessage) error {
+	var ev helix.EventSubChannelRaidEvent
+	if err := json.Unmarshal(data, &ev); err != nil {
+		return fmt.Errorf("failed to unmarshal ChannelRaidEvent: %w", err)
+	}
+
+	if err := h.recordRaid(ctx, raidDirectionOutgoing, ev.ToBroadcasterUserID, ev.ToBroadcasterUserLogin, ev.ToBroadcasterUserName, ev.Viewers); err != nil {
+		return err
+	}
+	fmt.Printf("Raided broadcaster %s with %d viewers\n", ev.ToBroadcasterUserName, ev.Viewers)
+	return nil
+}
+
+func (h *Handler) recordRaid(ctx context.Context, direction string, userId string, userLogin string, userName string, numViewers int) error {
+	// Record the EventSub message ID along with the raid, so that a redelivered
+	// notification doesn't cause the same raid to be recorded twice
+	messageId, _ := messageIdFromContext(ctx)
+	err := h.q.RecordRaid(ctx, queries.RecordRaidParams{
+		ChannelID:         h.channelUserId,
+		EventsubMessageID: sql.NullString{String: messageId, Valid: messageId != ""},
+		Direction:         direction,
+		TwitchUserID:      userId,
+		TwitchLogin:       userLogin,
+		TwitchDisplayName: userName,
+		NumViewers:        int32(numViewers),
+	})
+	if err != nil {
+		return fmt.Errorf("RecordRaid failed: %w", err)
+	}
+	return nil
+}
+
 func (h *Handler) handleChannelCheerEvent(ctx context.Context, data json.RawMessage) error {
 	var ev helix.EventSubChannelCheerEvent
 	if err := json.Unmarshal(data, &ev); err != nil {
 		return fmt.Errorf("failed to unmarshal ChannelCheerEvent: %w", err)
 	}
 
-	// Anonymous cheers can have no effect in the backend; we can't know who to credit
+	// Anonymous cheers can have no effect in the backend; we can't know who to credit,
+	// but we can still acknowledge them onstream
 	if ev.IsAnonymous {
+		h.emitCheerAlert(&ev)
 		return nil
 	}
 
+	// Look up the current rules, which determine how many bits a viewer must cheer
+	// with in order to invoke each cheer command
+	rules, err := h.getPointsRules(ctx)
+	if err != nil {
+		return err
+	}
+
 	// Contact the auth server to request a short-lived JWT that will give us
 	// authoritative access to the backend resources associated with the user who gave
 	// us bits
@@ -97,48 +162,49 @@ func (h *Handler) handleChannelCheerEvent(ctx context.Context, data json.RawMess
 		return fmt.Errorf("RequestCreditFromCheer failed: %v", err)
 	}
 
-	// If the viewer requested that their points be immediately spent on a ghost alert
-	// (by cheering with 200 bits and including "ghost of <subject>" in the message),
-	// submit a request to the imagegen server on their behalf
-	ghostPrefix := "ghost of "
-	ghostSubject := ""
-	if ev.Bits == 200 {
-		ghostPrefixPos := strings.Index(strings.ToLower(ev.Message), ghostPrefix)
-		if ghostPrefixPos >= 0 {
-			ghostSubject = strings.TrimSpace(ev.Message[ghostPrefixPos+len(ghostPrefix):])
-		}
-	}
-	if ghostSubject != "" {
-		fmt.Printf("Requesting ghost alert on behalf of user %s, with subject '%s'\n", ev.UserName, ghostSubject)
-		go func() {
-			payload := imagegen.Request{Subject: ghostSubject}
-			payloadBytes, err := json.Marshal(payload)
-			if err != nil {
-				fmt.Printf("Failed to marshal request payload when processing ghost alert for %s: %v\n", ev.UserName, err)
-				return
-			}
-			req, err := http.NewRequestWithContext(h.imagegenCtx, http.MethodPost, h.imagegenUrl, bytes.NewReader(payloadBytes))
-			if err != nil {
-				fmt.Printf("Failed to initialize HTTP request when processing ghost alert for %s: %v\n", ev.UserName, err)
-				return
-			}
-			req.Header.Set("authorization", fmt.Sprintf("Bearer %s", accessToken))
-
-			res, err := http.DefaultClient.Do(req)
-			if err != nil {
-				fmt.Printf("Failed to complete HTTP request when processing ghost alert for %s: %v\n", ev.UserName, err)
-				return
-			}
-			if res.StatusCode < 200 || res.StatusCode > 299 {
-				suffix := ""
-				if body, err := io.ReadAll(res.Body); err == nil {
-					suffix = fmt.Sprintf(": %s", body)
-				}
-				fmt.Printf("Ghost alert for %s failed with status %d%s\n", ev.UserName, res.StatusCode, suffix)
-				return
-			}
-			fmt.Printf("Successfully submitted ghost alert on behalf of %s\n", ev.UserName)
-		}()
-	}
+	// If the viewer's cheer invoked a command (e.g. cheering with the configured number
+	// of bits and "ghost of <subject>" in the message to request a ghost alert), carry
+	// it out on their behalf, spending the points they've just been credited with
+	h.runCheerCommand(&ev, accessToken, rules)
+
+	// Finally, emit an alert so the cheer will be acknowledged in the on-stream overlay
+	// graphics
+	h.emitCheerAlert(&ev)
 	return nil
 }
+
+// emitCheerAlert emits an alert for the given cheer, provided that the viewer cheered
+// with at least the configured minimum number of bits
+func (h *Handler) emitCheerAlert(ev *helix.EventSubChannelCheerEvent) {
+	if ev.Bits < h.cheerAlertMinBits {
+		fmt.Printf("Not generating alert for cheer of %d bits: minimum is %d\n", ev.Bits, h.cheerAlertMinBits)
+		return
+	}
+
+	// Resolve cheermotes to images so the overlay can render the message as it
+	// appeared in chat: if we can't, the alert is still worth displaying
+	message, cheermotes, err := h.resolveCheermotes(ev.Message)
+	if err != nil {
+		fmt.Printf("Failed to resolve cheermotes in cheer message: %v\n", err)
+	}
+
+	username := ev.UserName
+	if ev.IsAnonymous {
+		username = ""
+		fmt.Printf("Generating alert for anonymous cheer of %d bits\n", ev.Bits)
+	} else {
+		fmt.Printf("Generating alert for cheer of %d bits by user %s\n", ev.Bits, ev.UserName)
+	}
+	h.alertsChan <- &alerts.Alert{
+		Type: alerts.AlertTypeCheer,
+		Data: alerts.AlertData{
+			Cheer: &alerts.AlertDataCheer{
+				Username:    username,
+				IsAnonymous: ev.IsAnonymous,
+				NumBits:     ev.Bits,
+				Message:     message,
+				Cheermotes:  cheermotes,
+			},
+		},
+	}
+}
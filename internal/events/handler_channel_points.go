@@ -0,0 +1,180 @@
+package events
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/golden-vcr/auth"
+	"github.com/golden-vcr/showtime/gen/queries"
+	"github.com/golden-vcr/showtime/internal/alerts"
+	"github.com/nicklaw5/helix/v2"
+)
+
+const (
+	// RewardActionAlert causes a channel points redemption to display an alert
+	RewardActionAlert = "alert"
+	// RewardActionCredit causes a channel points redemption to credit the viewer with
+	// a fixed number of fun points
+	RewardActionCredit = "credit"
+	// RewardActionOverlay causes a channel points redemption to send a named event to
+	// the overlay
+	RewardActionOverlay = "overlay"
+)
+
+const (
+	RedemptionStatusFulfilled = "FULFILLED"
+	RedemptionStatusCanceled  = "CANCELED"
+)
+
+func (h *Handler) handleChannelPointsRedemptionEvent(ctx context.Context, data json.RawMessage) error {
+	var ev helix.EventSubChannelPointsCustomRewardRedemptionEvent
+	if err := json.Unmarshal(data, &ev); err != nil {
+		return fmt.Errorf("failed to unmarshal ChannelPointsCustomRewardRedemptionEvent: %w", err)
+	}
+
+	// Record the redemption before acting on it: if we've already resolved this
+	// redemption (e.g. because the notification is being retried after we failed to
+	// acknowledge it), we shouldn't take the same action twice
+	resolvedAt, err := h.q.RecordChannelPointsRedemption(ctx, queries.RecordChannelPointsRedemptionParams{
+		RedemptionID: ev.ID,
+		RewardID:     ev.Reward.ID,
+		TwitchUserID: ev.UserID,
+		UserInput:    ev.UserInput,
+		RedeemedAt:   ev.RedeemedAt.Time,
+	})
+	if err != nil {
+		return fmt.Errorf("RecordChannelPointsRedemption failed: %w", err)
+	}
+	if resolvedAt.Valid {
+		fmt.Printf("Ignoring redemption %s of reward %q by user %s: already resolved\n", ev.ID, ev.Reward.Title, ev.UserName)
+		return nil
+	}
+
+	// Look up the action that the broadcaster has configured for this reward: if
+	// there's no such action, the reward is presumably handled manually, so we leave
+	// the redemption in the queue
+	reward, err := h.q.GetChannelPointsReward(ctx, ev.Reward.ID)
+	if errors.Is(err, sql.ErrNoRows) {
+		fmt.Printf("Ignoring redemption %s of reward %q by user %s: no action is configured for reward %s\n", ev.ID, ev.Reward.Title, ev.UserName, ev.Reward.ID)
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("GetChannelPointsReward failed: %w", err)
+	}
+
+	// Perform the configured action, then resolve the redemption accordingly: if the
+	// action failed, the redemption is canceled so that the viewer gets their channel
+	// points back
+	status := RedemptionStatusFulfilled
+	errorMessage := sql.NullString{}
+	if err := h.performRewardAction(ctx, &ev, &reward); err != nil {
+		fmt.Printf("Failed to perform %s action for redemption %s of reward %q by user %s: %v\n", reward.Action, ev.ID, ev.Reward.Title, ev.UserName, err)
+		status = RedemptionStatusCanceled
+		errorMessage = sql.NullString{String: err.Error(), Valid: true}
+	}
+	// The action has been taken, so we must not return an error (which would cause the
+	// notification to be retried and the action repeated) even if we can't record the
+	// outcome: likewise, failing to update the redemption's status in Twitch is logged
+	// but not retried
+	if err := h.q.RecordChannelPointsRedemptionResolved(ctx, queries.RecordChannelPointsRedemptionResolvedParams{
+		Status:       sql.NullString{String: status, Valid: true},
+		ErrorMessage: errorMessage,
+		RedemptionID: ev.ID,
+	}); err != nil {
+		fmt.Printf("Failed to record redemption %s as %s: %v\n", ev.ID, status, err)
+	}
+	if err := h.updateRedemptionStatus(&ev, status); err != nil {
+		fmt.Printf("Failed to mark redemption %s as %s: %v\n", ev.ID, status, err)
+	}
+	return nil
+}
+
+func (h *Handler) performRewardAction(ctx context.Context, ev *helix.EventSubChannelPointsCustomRewardRedemptionEvent, reward *queries.GetChannelPointsRewardRow) error {
+	switch reward.Action {
+	case RewardActionAlert:
+		fmt.Printf("Generating alert for redemption of reward %q by user %s\n", ev.Reward.Title, ev.UserName)
+		h.alertsChan <- &alerts.Alert{
+			Type: alerts.AlertTypeRedemption,
+			Data: alerts.AlertData{
+				Redemption: &alerts.AlertDataRedemption{
+					Username:    ev.UserName,
+					RewardTitle: ev.Reward.Title,
+					Message:     ev.UserInput,
+				},
+			},
+		}
+		return nil
+	case RewardActionCredit:
+		// Claim the credit by redemption ID, so that the viewer is never credited twice
+		// for the same redemption, even if the notification is redelivered
+		return h.creditOnceForKey(ctx, ev.ID, ev.UserID, func() error {
+			// Request a JWT that grants us authoritative access to the viewer's ledger
+			fmt.Printf("Requesting JWT in response to redemption of reward %q by user %s\n", ev.Reward.Title, ev.UserName)
+			accessToken, err := h.authServiceClient.RequestServiceToken(ctx, auth.ServiceTokenRequest{
+				Service: "showtime",
+				User: auth.UserDetails{
+					Id:          ev.UserID,
+					Login:       ev.UserLogin,
+					DisplayName: ev.UserName,
+				},
+			})
+			if err != nil {
+				return fmt.Errorf("RequestServiceToken failed: %w", err)
+			}
+
+			// The ledger has no dedicated inflow type for channel points, so the credit is
+			// recorded in the same manner as a cheer, with a message identifying the reward
+			fmt.Printf("Requesting credit of %d points to user %s\n", reward.NumPoints, ev.UserName)
+			message := fmt.Sprintf("Redeemed channel points reward: %s", ev.Reward.Title)
+			if _, err := h.ledgerClient.RequestCreditFromCheer(ctx, accessToken, int(reward.NumPoints), message); err != nil {
+				return fmt.Errorf("RequestCreditFromCheer failed: %w", err)
+			}
+			return nil
+		})
+	case RewardActionOverlay:
+		fmt.Printf("Sending overlay event %q for redemption of reward %q by user %s\n", reward.OverlayEvent, ev.Reward.Title, ev.UserName)
+		h.alertsChan <- &alerts.Alert{
+			Type: alerts.AlertTypeOverlayEvent,
+			Data: alerts.AlertData{
+				OverlayEvent: &alerts.AlertDataOverlayEvent{
+					Event:    reward.OverlayEvent,
+					Username: ev.UserName,
+					Message:  ev.UserInput,
+				},
+			},
+		}
+		return nil
+	}
+	return fmt.Errorf("unsupported reward action %q", reward.Action)
+}
+
+func (h *Handler) updateRedemptionStatus(ev *helix.EventSubChannelPointsCustomRewardRedemptionEvent, status string) error {
+	// Redemptions of rewards that skip the request queue are fulfilled immediately,
+	// and their status can no longer be changed
+	if ev.Status != "" && ev.Status != "unfulfilled" {
+		return nil
+	}
+
+	// We can only update redemptions with a user access token for the broadcaster
+	if h.redemptionUpdater == nil {
+		fmt.Printf("Not marking redemption %s as %s: no user access token is configured\n", ev.ID, status)
+		return nil
+	}
+
+	res, err := h.redemptionUpdater.UpdateChannelCustomRewardsRedemptionStatus(&helix.UpdateChannelCustomRewardsRedemptionStatusParams{
+		ID:            ev.ID,
+		BroadcasterID: ev.BroadcasterUserID,
+		RewardID:      ev.Reward.ID,
+		Status:        status,
+	})
+	if err != nil {
+		return err
+	}
+	if res.ErrorStatus != 0 {
+		return fmt.Errorf("got response %d from Twitch API: %s", res.ErrorStatus, res.ErrorMessage)
+	}
+	return nil
+}
@@ -0,0 +1,69 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/golden-vcr/auth"
+	"github.com/golden-vcr/ledger"
+	"github.com/golden-vcr/server-common/querytest"
+	"github.com/golden-vcr/showtime/gen/queries"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Handler_handleChannelPointsRedemptionEvent_credit(t *testing.T) {
+	tx := querytest.PrepareTx(t)
+	ledgerClient := &mockLedgerClient{}
+	h := &Handler{
+		q:                 queries.New(tx),
+		channelUserId:     "953753877",
+		authServiceClient: &mockAuthServiceClient{},
+		ledgerClient:      ledgerClient,
+	}
+	_, err := tx.Exec(`
+		INSERT INTO showtime.channel_points_reward (reward_id, action, num_points) VALUES
+			('reward-1', 'credit', 100);
+	`)
+	assert.NoError(t, err)
+	data := json.RawMessage(`{"id":"redemption-1","broadcaster_user_id":"953753877","user_id":"1234","user_login":"bigjim","user_name":"BigJim","user_input":"","status":"fulfilled","reward":{"id":"reward-1","title":"Get points"},"redeemed_at":"2023-11-01T12:00:00Z"}`)
+
+	// Redeeming the reward should credit the viewer and resolve the redemption
+	err = h.handleChannelPointsRedemptionEvent(context.Background(), data)
+	assert.NoError(t, err)
+	assert.Equal(t, []int{100}, ledgerClient.credits)
+	querytest.AssertCount(t, tx, 1, `
+		SELECT COUNT(*) FROM showtime.channel_points_redemption
+			WHERE redemption_id = 'redemption-1'
+			AND status = 'FULFILLED'
+			AND resolved_at IS NOT NULL
+	`)
+
+	// If we credited the viewer but failed to record the redemption as resolved, a
+	// redelivered notification should not credit the viewer again
+	_, err = tx.Exec(`
+		UPDATE showtime.channel_points_redemption SET resolved_at = NULL, status = NULL
+			WHERE redemption_id = 'redemption-1';
+	`)
+	assert.NoError(t, err)
+	err = h.handleChannelPointsRedemptionEvent(context.Background(), data)
+	assert.NoError(t, err)
+	assert.Equal(t, []int{100}, ledgerClient.credits)
+}
+
+type mockAuthServiceClient struct{}
+
+func (m *mockAuthServiceClient) RequestServiceToken(ctx context.Context, payload auth.ServiceTokenRequest) (string, error) {
+	return "token-for-" + payload.User.Id, nil
+}
+
+type mockLedgerClient struct {
+	ledger.Client
+	credits []int
+}
+
+func (m *mockLedgerClient) RequestCreditFromCheer(ctx context.Context, accessToken string, numPointsToCredit int, message string) (uuid.UUID, error) {
+	m.credits = append(m.credits, numPointsToCredit)
+	return uuid.New(), nil
+}
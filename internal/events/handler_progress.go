@@ -0,0 +1,303 @@
+package events
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/golden-vcr/showtime/gen/queries"
+	"github.com/golden-vcr/showtime/internal/progress"
+	"github.com/nicklaw5/helix/v2"
+)
+
+// hypeTrainEvent is the payload of a channel.hype_train.begin or .progress event:
+// helix's types omit the ID of the hype train, which we need in order to correlate
+// events
+type hypeTrainEvent struct {
+	ID string `json:"id"`
+	helix.EventSubHypeTrainProgressEvent
+}
+
+// hypeTrainEndEvent is the payload of a channel.hype_train.end event: helix's type
+// omits the ID of the hype train and the time at which it ended
+type hypeTrainEndEvent struct {
+	ID string `json:"id"`
+	helix.EventSubHypeTrainEndEvent
+	EndedAt helix.Time `json:"ended_at"`
+}
+
+// predictionEndEvent is the payload of a channel.prediction.end event: helix's type
+// misspells the JSON key for the time at which the prediction ended
+type predictionEndEvent struct {
+	helix.EventSubChannelPredictionEndEvent
+	EndedAt helix.Time `json:"ended_at"`
+}
+
+func (h *Handler) handleHypeTrainEvent(ctx context.Context, phase string, data json.RawMessage) error {
+	var ev hypeTrainEvent
+	if err := json.Unmarshal(data, &ev); err != nil {
+		return fmt.Errorf("failed to unmarshal HypeTrainEvent: %w", err)
+	}
+
+	expiresAt := ev.ExpiresAt.Time
+	h.progressChan <- &progress.Event{
+		Type:  progress.EventTypeHypeTrain,
+		Phase: phase,
+		Data: progress.EventData{
+			HypeTrain: &progress.EventDataHypeTrain{
+				Id:               ev.ID,
+				Level:            ev.Level,
+				Total:            ev.Total,
+				Progress:         ev.Progress,
+				Goal:             ev.Goal,
+				TopContributions: convertHypeTrainContributions(ev.TopContributions),
+				ExpiresAt:        &expiresAt,
+			},
+		},
+	}
+	return nil
+}
+
+func (h *Handler) handleHypeTrainEndEvent(ctx context.Context, data json.RawMessage) error {
+	var ev hypeTrainEndEvent
+	if err := json.Unmarshal(data, &ev); err != nil {
+		return fmt.Errorf("failed to unmarshal HypeTrainEndEvent: %w", err)
+	}
+
+	// Persist the final results of the hype train before notifying the overlay
+	topContributions := convertHypeTrainContributions(ev.TopContributions)
+	topContributionsJson, err := json.Marshal(topContributions)
+	if err != nil {
+		return fmt.Errorf("failed to marshal hype train contributions: %w", err)
+	}
+	if err := h.q.RecordHypeTrainResult(ctx, queries.RecordHypeTrainResultParams{
+		ChannelID:        h.channelUserId,
+		StartedAt:        ev.StartedAt.Time,
+		ID:               ev.ID,
+		Level:            int32(ev.Level),
+		Total:            int32(ev.Total),
+		TopContributions: topContributionsJson,
+		EndedAt:          resolveEndTime(ev.EndedAt),
+	}); err != nil {
+		return fmt.Errorf("RecordHypeTrainResult failed: %w", err)
+	}
+
+	fmt.Printf("Hype train ended at level %d with a total of %d\n", ev.Level, ev.Total)
+	h.progressChan <- &progress.Event{
+		Type:  progress.EventTypeHypeTrain,
+		Phase: progress.PhaseEnd,
+		Data: progress.EventData{
+			HypeTrain: &progress.EventDataHypeTrain{
+				Id:               ev.ID,
+				Level:            ev.Level,
+				Total:            ev.Total,
+				TopContributions: topContributions,
+			},
+		},
+	}
+	return nil
+}
+
+func (h *Handler) handlePollEvent(ctx context.Context, phase string, data json.RawMessage) error {
+	var ev helix.EventSubChannelPollBeginEvent
+	if err := json.Unmarshal(data, &ev); err != nil {
+		return fmt.Errorf("failed to unmarshal ChannelPollEvent: %w", err)
+	}
+
+	endsAt := ev.EndsAt.Time
+	h.progressChan <- &progress.Event{
+		Type:  progress.EventTypePoll,
+		Phase: phase,
+		Data: progress.EventData{
+			Poll: &progress.EventDataPoll{
+				Id:      ev.ID,
+				Title:   ev.Title,
+				Choices: convertPollChoices(ev.Choices),
+				EndsAt:  &endsAt,
+			},
+		},
+	}
+	return nil
+}
+
+func (h *Handler) handlePollEndEvent(ctx context.Context, data json.RawMessage) error {
+	var ev helix.EventSubChannelPollEndEvent
+	if err := json.Unmarshal(data, &ev); err != nil {
+		return fmt.Errorf("failed to unmarshal ChannelPollEndEvent: %w", err)
+	}
+
+	// Twitch notifies us again when a completed poll is archived, but by then we've
+	// already recorded its results
+	if ev.Status == "archived" {
+		return nil
+	}
+
+	// Persist the final results of the poll before notifying the overlay
+	choices := convertPollChoices(ev.Choices)
+	choicesJson, err := json.Marshal(choices)
+	if err != nil {
+		return fmt.Errorf("failed to marshal poll choices: %w", err)
+	}
+	if err := h.q.RecordPollResult(ctx, queries.RecordPollResultParams{
+		ChannelID: h.channelUserId,
+		StartedAt: ev.StartedAt.Time,
+		ID:        ev.ID,
+		Title:     ev.Title,
+		Status:    ev.Status,
+		Choices:   choicesJson,
+		EndedAt:   resolveEndTime(ev.EndedAt),
+	}); err != nil {
+		return fmt.Errorf("RecordPollResult failed: %w", err)
+	}
+
+	fmt.Printf("Poll %q ended with status %s\n", ev.Title, ev.Status)
+	h.progressChan <- &progress.Event{
+		Type:  progress.EventTypePoll,
+		Phase: progress.PhaseEnd,
+		Data: progress.EventData{
+			Poll: &progress.EventDataPoll{
+				Id:      ev.ID,
+				Title:   ev.Title,
+				Choices: choices,
+				Status:  ev.Status,
+			},
+		},
+	}
+	return nil
+}
+
+func (h *Handler) handlePredictionEvent(ctx context.Context, phase string, data json.RawMessage) error {
+	var ev helix.EventSubChannelPredictionBeginEvent
+	if err := json.Unmarshal(data, &ev); err != nil {
+		return fmt.Errorf("failed to unmarshal ChannelPredictionEvent: %w", err)
+	}
+
+	locksAt := ev.LocksAt.Time
+	h.progressChan <- &progress.Event{
+		Type:  progress.EventTypePrediction,
+		Phase: phase,
+		Data: progress.EventData{
+			Prediction: &progress.EventDataPrediction{
+				Id:       ev.ID,
+				Title:    ev.Title,
+				Outcomes: convertPredictionOutcomes(ev.Outcomes),
+				LocksAt:  &locksAt,
+			},
+		},
+	}
+	return nil
+}
+
+func (h *Handler) handlePredictionLockEvent(ctx context.Context, data json.RawMessage) error {
+	var ev helix.EventSubChannelPredictionLockEvent
+	if err := json.Unmarshal(data, &ev); err != nil {
+		return fmt.Errorf("failed to unmarshal ChannelPredictionLockEvent: %w", err)
+	}
+
+	h.progressChan <- &progress.Event{
+		Type:  progress.EventTypePrediction,
+		Phase: progress.PhaseLock,
+		Data: progress.EventData{
+			Prediction: &progress.EventDataPrediction{
+				Id:       ev.ID,
+				Title:    ev.Title,
+				Outcomes: convertPredictionOutcomes(ev.Outcomes),
+			},
+		},
+	}
+	return nil
+}
+
+func (h *Handler) handlePredictionEndEvent(ctx context.Context, data json.RawMessage) error {
+	var ev predictionEndEvent
+	if err := json.Unmarshal(data, &ev); err != nil {
+		return fmt.Errorf("failed to unmarshal ChannelPredictionEndEvent: %w", err)
+	}
+
+	// Persist the final results of the prediction before notifying the overlay
+	outcomes := convertPredictionOutcomes(ev.Outcomes)
+	outcomesJson, err := json.Marshal(outcomes)
+	if err != nil {
+		return fmt.Errorf("failed to marshal prediction outcomes: %w", err)
+	}
+	if err := h.q.RecordPredictionResult(ctx, queries.RecordPredictionResultParams{
+		ChannelID: h.channelUserId,
+		StartedAt: ev.StartedAt.Time,
+		ID:        ev.ID,
+		Title:     ev.Title,
+		Status:    ev.Status,
+		WinningOutcomeID: sql.NullString{
+			String: ev.WinningOutcomeID,
+			Valid:  ev.WinningOutcomeID != "",
+		},
+		Outcomes: outcomesJson,
+		EndedAt:  resolveEndTime(ev.EndedAt),
+	}); err != nil {
+		return fmt.Errorf("RecordPredictionResult failed: %w", err)
+	}
+
+	fmt.Printf("Prediction %q ended with status %s\n", ev.Title, ev.Status)
+	h.progressChan <- &progress.Event{
+		Type:  progress.EventTypePrediction,
+		Phase: progress.PhaseEnd,
+		Data: progress.EventData{
+			Prediction: &progress.EventDataPrediction{
+				Id:               ev.ID,
+				Title:            ev.Title,
+				Outcomes:         outcomes,
+				Status:           ev.Status,
+				WinningOutcomeId: ev.WinningOutcomeID,
+			},
+		},
+	}
+	return nil
+}
+
+func convertHypeTrainContributions(contributions []helix.EventSubContribution) []progress.HypeTrainContribution {
+	result := make([]progress.HypeTrainContribution, 0, len(contributions))
+	for _, c := range contributions {
+		result = append(result, progress.HypeTrainContribution{
+			Username: c.UserName,
+			Type:     c.Type,
+			Total:    int(c.Total),
+		})
+	}
+	return result
+}
+
+func convertPollChoices(choices []helix.PollChoice) []progress.PollChoice {
+	result := make([]progress.PollChoice, 0, len(choices))
+	for _, c := range choices {
+		result = append(result, progress.PollChoice{
+			Id:       c.ID,
+			Title:    c.Title,
+			NumVotes: c.Votes,
+		})
+	}
+	return result
+}
+
+func convertPredictionOutcomes(outcomes []helix.EventSubOutcome) []progress.PredictionOutcome {
+	result := make([]progress.PredictionOutcome, 0, len(outcomes))
+	for _, o := range outcomes {
+		result = append(result, progress.PredictionOutcome{
+			Id:               o.ID,
+			Title:            o.Title,
+			Color:            o.Color,
+			NumUsers:         o.Users,
+			NumChannelPoints: o.ChannelPoints,
+		})
+	}
+	return result
+}
+
+// resolveEndTime returns the time at which a hype train, poll, or prediction ended,
+// defaulting to the current time if Twitch didn't tell us
+func resolveEndTime(endedAt helix.Time) time.Time {
+	if endedAt.IsZero() {
+		return time.Now()
+	}
+	return endedAt.Time
+}
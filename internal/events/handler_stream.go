@@ -8,23 +8,90 @@ import (
 	"time"
 
 	"github.com/golden-vcr/showtime/gen/queries"
+	"github.com/nicklaw5/helix/v2"
 )
 
+const (
+	streamTypeLive       = "live"
+	streamTypePlaylist   = "playlist"
+	streamTypeWatchParty = "watch_party"
+	streamTypePremiere   = "premiere"
+	streamTypeRerun      = "rerun"
+)
+
+// normalizeStreamType returns the stream type that should be recorded for a
+// stream.online event with the given type. Broadcasts may only record the stream types
+// we know about, so if Twitch introduces a new stream type, we err on the side of
+// treating it as live.
+func normalizeStreamType(streamType string) string {
+	switch streamType {
+	case streamTypeLive, streamTypePlaylist, streamTypeWatchParty, streamTypePremiere, streamTypeRerun:
+		return streamType
+	case "":
+		return streamTypeLive
+	}
+	fmt.Printf("WARNING: received stream.online event with unrecognized type '%s'; treating as live\n", streamType)
+	return streamTypeLive
+}
+
+// shouldOpenBroadcast determines whether a stream of the given type, as reported in a
+// stream.online event, warrants opening (or resuming) a broadcast. Live streams and
+// premieres are new content that viewers can interact with; reruns, playlists and
+// watch parties are not, so we don't track them as broadcasts.
+func shouldOpenBroadcast(streamType string) bool {
+	switch streamType {
+	case streamTypePlaylist, streamTypeWatchParty, streamTypeRerun:
+		return false
+	}
+	return true
+}
+
 func (h *Handler) handleStreamOnlineEvent(ctx context.Context, data json.RawMessage) error {
+	var ev helix.EventSubStreamOnlineEvent
+	if err := json.Unmarshal(data, &ev); err != nil {
+		return fmt.Errorf("failed to unmarshal StreamOnlineEvent: %w", err)
+	}
+	streamType := normalizeStreamType(ev.Type)
+	if !shouldOpenBroadcast(streamType) {
+		fmt.Printf("Stream %s has come online with type '%s'; not opening a broadcast.\n", ev.ID, streamType)
+		return nil
+	}
+
+	// Prefer the time at which Twitch reports the stream as having started, since our
+	// event may be delayed or redelivered
+	startedAt := ev.StartedAt.Time
+	if startedAt.IsZero() {
+		startedAt = time.Now()
+	}
+
 	// Check the most recent broadcast to see if it ended very recently
-	broadcast, err := getMostRecentBroadcast(ctx, h.q)
+	broadcast, err := getMostRecentBroadcast(ctx, h.q, h.channelUserId)
 	if err != nil {
 		return fmt.Errorf("error getting most recent broadcast: %w", err)
 	}
 	if broadcast != nil {
+		// If we've already opened a broadcast for this exact stream, there's nothing
+		// more to do
+		if ev.ID != "" && broadcast.TwitchStreamID.Valid && broadcast.TwitchStreamID.String == ev.ID && !broadcast.EndedAt.Valid {
+			fmt.Printf("[BROADCAST %d] Ignoring duplicate stream.online event for stream %s.\n", broadcast.ID, ev.ID)
+			return nil
+		}
 		if broadcast.EndedAt.Valid {
-			// If the broadcast ended recently, resume it and we're done
-			fifteenMinutesAgo := time.Now().Add(-15 * time.Minute)
-			if broadcast.EndedAt.Time.After(fifteenMinutesAgo) {
-				if err := h.q.RecordBroadcastResumed(ctx, broadcast.ID); err != nil {
+			// If the broadcast ended recently, resume it and we're done: the broadcast
+			// now belongs to the new stream, so that a redelivered stream.online event
+			// for that stream is recognized as a duplicate
+			resumeThreshold := startedAt.Add(-h.resumeWindow)
+			if broadcast.EndedAt.Time.After(resumeThreshold) {
+				if err := h.q.RecordBroadcastResumed(ctx, queries.RecordBroadcastResumedParams{
+					TwitchStreamID: sql.NullString{
+						String: ev.ID,
+						Valid:  ev.ID != "",
+					},
+					BroadcastID: broadcast.ID,
+				}); err != nil {
 					return fmt.Errorf("error resuming broadcast %d: %w", broadcast.ID, err)
 				}
-				fmt.Printf("[BROADCAST %d] Stream has come back online; broadcast is resumed.\n", broadcast.ID)
+				fmt.Printf("[BROADCAST %d] Stream %s has come back online; broadcast is resumed.\n", broadcast.ID, ev.ID)
 				return nil
 			}
 		} else {
@@ -33,20 +100,28 @@ func (h *Handler) handleStreamOnlineEvent(ctx context.Context, data json.RawMess
 		}
 	}
 
-	// We either don't have a previous broadcast, the previous broadcast ended more than
-	// 15 minutes ago, or the previous broadcast is still erroneously showing up as
-	// live: either way, we want to start a new broadcast
-	newBroadcastId, err := h.q.RecordBroadcastStarted(ctx)
+	// We either don't have a previous broadcast, the previous broadcast ended too long
+	// before this stream started to be resumed, or the previous broadcast is still
+	// erroneously showing up as live: either way, we want to start a new broadcast
+	newBroadcastId, err := h.q.RecordBroadcastStarted(ctx, queries.RecordBroadcastStartedParams{
+		ChannelID: h.channelUserId,
+		StartedAt: startedAt,
+		TwitchStreamID: sql.NullString{
+			String: ev.ID,
+			Valid:  ev.ID != "",
+		},
+		StreamType: streamType,
+	})
 	if err != nil {
 		return fmt.Errorf("error recording start of broadcast: %w", err)
 	}
-	fmt.Printf("[BROADCAST %d] Stream has come online; broadcast is started.\n", newBroadcastId)
+	fmt.Printf("[BROADCAST %d] Stream %s has come online; broadcast is started.\n", newBroadcastId, ev.ID)
 	return nil
 }
 
 func (h *Handler) handleStreamOfflineEvent(ctx context.Context, data json.RawMessage) error {
 	// Check the most recent broadcast to see if it's still live
-	broadcast, err := getMostRecentBroadcast(ctx, h.q)
+	broadcast, err := getMostRecentBroadcast(ctx, h.q, h.channelUserId)
 	if err != nil {
 		return fmt.Errorf("error getting most recent broadcast: %w", err)
 	}
@@ -63,15 +138,15 @@ func (h *Handler) handleStreamOfflineEvent(ctx context.Context, data json.RawMes
 	}
 
 	// Flag the broadcast as ended as of right now
-	if err := h.q.RecordBroadcastEnded(ctx); err != nil {
+	if err := h.q.RecordBroadcastEnded(ctx, h.channelUserId); err != nil {
 		return fmt.Errorf("error recording end of broadcast: %w", err)
 	}
 	fmt.Printf("[BROADCAST %d] Stream has gone offline; broadcast is ended.\n", broadcast.ID)
 	return nil
 }
 
-func getMostRecentBroadcast(ctx context.Context, q *queries.Queries) (*queries.GetMostRecentBroadcastRow, error) {
-	result, err := q.GetMostRecentBroadcast(ctx)
+func getMostRecentBroadcast(ctx context.Context, q *queries.Queries, channelUserId string) (*queries.GetMostRecentBroadcastRow, error) {
+	result, err := q.GetMostRecentBroadcast(ctx, channelUserId)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, nil
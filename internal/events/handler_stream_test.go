@@ -0,0 +1,104 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/golden-vcr/server-common/querytest"
+	"github.com/golden-vcr/showtime/gen/queries"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_shouldOpenBroadcast(t *testing.T) {
+	tests := []struct {
+		streamType string
+		want       bool
+	}{
+		{"live", true},
+		{"premiere", true},
+		{"rerun", false},
+		{"playlist", false},
+		{"watch_party", false},
+		{"some_future_type", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.streamType, func(t *testing.T) {
+			assert.Equal(t, tt.want, shouldOpenBroadcast(tt.streamType))
+		})
+	}
+}
+
+func Test_normalizeStreamType(t *testing.T) {
+	tests := []struct {
+		streamType string
+		want       string
+	}{
+		{"live", "live"},
+		{"premiere", "premiere"},
+		{"rerun", "rerun"},
+		{"", "live"},
+		{"some_future_type", "live"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.streamType, func(t *testing.T) {
+			assert.Equal(t, tt.want, normalizeStreamType(tt.streamType))
+		})
+	}
+}
+
+func Test_Handler_handleStreamOnlineEvent_unrecognizedType(t *testing.T) {
+	tx := querytest.PrepareTx(t)
+	h := &Handler{
+		q:             queries.New(tx),
+		channelUserId: "953753877",
+		resumeWindow:  15 * time.Minute,
+	}
+
+	// A stream type that Twitch has introduced since we last updated should open a
+	// broadcast that's recorded as live, since the database only accepts known types
+	err := h.handleStreamOnlineEvent(context.Background(), json.RawMessage(`{"id":"stream-a","broadcaster_user_id":"953753877","type":"some_future_type","started_at":"2023-11-01T12:00:00Z"}`))
+	assert.NoError(t, err)
+	querytest.AssertCount(t, tx, 1, `
+		SELECT COUNT(*) FROM showtime.broadcast
+			WHERE twitch_stream_id = 'stream-a'
+			AND stream_type = 'live'
+			AND ended_at IS NULL
+	`)
+}
+
+func Test_Handler_handleStreamOnlineEvent_resumed(t *testing.T) {
+	tx := querytest.PrepareTx(t)
+	h := &Handler{
+		q:             queries.New(tx),
+		channelUserId: "953753877",
+		resumeWindow:  15 * time.Minute,
+	}
+	streamOnline := func(streamId string, startedAt time.Time) json.RawMessage {
+		return json.RawMessage(fmt.Sprintf(`{"id":"%s","broadcaster_user_id":"953753877","type":"live","started_at":"%s"}`, streamId, startedAt.Format(time.RFC3339Nano)))
+	}
+
+	// Start a broadcast, then go offline briefly
+	err := h.handleStreamOnlineEvent(context.Background(), streamOnline("stream-a", time.Now().Add(-time.Hour)))
+	assert.NoError(t, err)
+	err = h.handleStreamOfflineEvent(context.Background(), json.RawMessage(`{}`))
+	assert.NoError(t, err)
+
+	// A new stream that starts within the resume window should resume the broadcast,
+	// which should now be associated with the new stream
+	err = h.handleStreamOnlineEvent(context.Background(), streamOnline("stream-b", time.Now()))
+	assert.NoError(t, err)
+	querytest.AssertCount(t, tx, 1, `
+		SELECT COUNT(*) FROM showtime.broadcast
+			WHERE ended_at IS NULL
+			AND twitch_stream_id = 'stream-b'
+	`)
+
+	// If the stream.online event for the new stream is redelivered, it should be
+	// ignored as a duplicate rather than opening another broadcast
+	err = h.handleStreamOnlineEvent(context.Background(), streamOnline("stream-b", time.Now()))
+	assert.NoError(t, err)
+	querytest.AssertCount(t, tx, 1, "SELECT COUNT(*) FROM showtime.broadcast")
+}
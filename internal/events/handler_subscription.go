@@ -2,18 +2,17 @@ package events
 
 import (
 	"context"
+	"database/sql"
 	"encoding/json"
 	"fmt"
 
 	"github.com/golden-vcr/auth"
 	"github.com/golden-vcr/showtime/gen/queries"
 	"github.com/golden-vcr/showtime/internal/alerts"
+	"github.com/golden-vcr/showtime/internal/points"
 	"github.com/nicklaw5/helix/v2"
 )
 
-const BasePointsForSubscription = 600
-const BasePointsForGiftSub = 200
-
 // handleChannelSubscriptionEvent responds to 'channel.subscribe': "This subscription
 // type sends a notification when a specified channel receives a subscriber. This does
 // not include resubscribes."
@@ -35,36 +34,68 @@ func (h *Handler) handleChannelSubscriptionEvent(ctx context.Context, data json.
 		return fmt.Errorf("RecordViewerSubscribe failed: %w\n", err)
 	}
 
-	// If the subscription is tier 2 or 3, award 2x or 5x the points
-	multiplier, err := getCreditMultiplierFromTier(ev.Tier)
-	if err != nil {
-		return nil
+	// Record the start of their subscription: if it was gifted, the gifter will be
+	// attributed once we've correlated this event with the corresponding gift
+	if err := h.q.RecordSubscriptionStarted(ctx, queries.RecordSubscriptionStartedParams{
+		TwitchUserID: ev.UserID,
+		Tier:         ev.Tier,
+		IsGift:       ev.IsGift,
+	}); err != nil {
+		return fmt.Errorf("RecordSubscriptionStarted failed: %w", err)
 	}
 
-	// Contact the auth server to request a short-lived JWT that will give us
-	// authoritative access to the backend resources associated with the user who's now
-	// a subscriber
-	fmt.Printf("Requesting JWT in response to subscription for user %s\n", ev.UserName)
-	accessToken, err := h.authServiceClient.RequestServiceToken(ctx, auth.ServiceTokenRequest{
-		Service: "showtime",
-		User: auth.UserDetails{
-			Id:          ev.UserID,
-			Login:       ev.UserLogin,
-			DisplayName: ev.UserName,
-		},
-	})
+	// Determine how many points to award for this subscription, according to the current
+	// rules: awards vary by tier, and bonuses may apply depending on what's on stream
+	basePoints, multiplier, ok, err := h.resolveCredit(ctx, points.EventTypeSubscription, ev.Tier)
 	if err != nil {
-		return fmt.Errorf("RequestServiceToken failed: %w", err)
+		return err
 	}
 
-	// Send a request to the ledger server to request that points be granted to the user
-	fmt.Printf("Requesting credit of %d points (before %.fx multiplier) to user %s\n", BasePointsForSubscription, multiplier, ev.UserName)
-	_, err = h.ledgerClient.RequestCreditFromSubscription(ctx, accessToken, BasePointsForSubscription, true, ev.IsGift, "", multiplier)
-	if err != nil {
-		return fmt.Errorf("RequestCreditFromSubscription failed: %v", err)
+	// Credit the user for their subscription, taking care not to credit them twice if
+	// we've already handled this event: when a viewer gifts a batch of subs, each
+	// recipient is credited individually via their own event
+	if ok {
+		err = h.creditOnce(ctx, ev.UserID, func() error {
+			// Contact the auth server to request a short-lived JWT that will give us
+			// authoritative access to the backend resources associated with the user who's
+			// now a subscriber
+			fmt.Printf("Requesting JWT in response to subscription for user %s\n", ev.UserName)
+			accessToken, err := h.authServiceClient.RequestServiceToken(ctx, auth.ServiceTokenRequest{
+				Service: "showtime",
+				User: auth.UserDetails{
+					Id:          ev.UserID,
+					Login:       ev.UserLogin,
+					DisplayName: ev.UserName,
+				},
+			})
+			if err != nil {
+				return fmt.Errorf("RequestServiceToken failed: %w", err)
+			}
+
+			// Send a request to the ledger server to request that points be granted to the
+			// user
+			fmt.Printf("Requesting credit of %d points (before %gx multiplier) to user %s\n", basePoints, multiplier, ev.UserName)
+			_, err = h.ledgerClient.RequestCreditFromSubscription(ctx, accessToken, basePoints, true, ev.IsGift, "", multiplier)
+			if err != nil {
+				return fmt.Errorf("RequestCreditFromSubscription failed: %v", err)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	} else {
+		fmt.Printf("No points are awarded for %s at tier %s; not crediting user %s\n", points.EventTypeSubscription, ev.Tier, ev.UserName)
+	}
+
+	// If this sub was gifted, it will be announced along with the rest of the gifter's
+	// batch, in a single alert
+	if ev.IsGift {
+		h.giftBursts.addRecipient(ev.UserID, ev.UserName, ev.Tier)
+		return nil
 	}
 
-	// Finally, emit an alert so this user's new subscription will be acknowledged in
+	// Otherwise, emit an alert so this user's new subscription will be acknowledged in
 	// the on-stream overlay graphics
 	h.alertsChan <- &alerts.Alert{
 		Type: alerts.AlertTypeSubscribe,
@@ -94,6 +125,10 @@ func (h *Handler) handleChannelSubscriptionEndEvent(ctx context.Context, data js
 	}
 	fmt.Printf("Got channel.subscription.end: %+v\n", ev)
 
+	// Record the fact that the user's subscription has expired
+	if err := h.q.RecordSubscriptionEnded(ctx, ev.UserID); err != nil {
+		return fmt.Errorf("RecordSubscriptionEnded failed: %w", err)
+	}
 	return nil
 }
 
@@ -110,47 +145,66 @@ func (h *Handler) handleChannelSubscriptionGiftEvent(ctx context.Context, data j
 	}
 	fmt.Printf("Got channel.subscription.gift: %+v\n", ev)
 
-	// If the subscriptions are tier 2 or 3, award 2x or 5x the points
-	multiplier, err := getCreditMultiplierFromTier(ev.Tier)
-	if err != nil {
-		return nil
+	// Ensure that the gifter has a viewer record, so that they can be attributed as the
+	// gifter of each recipient's subscription
+	if !ev.IsAnonymous {
+		if err := h.q.RecordViewerIdentity(ctx, queries.RecordViewerIdentityParams{
+			TwitchUserID:      ev.UserID,
+			TwitchDisplayName: ev.UserName,
+		}); err != nil {
+			return fmt.Errorf("RecordViewerIdentity failed: %w", err)
+		}
 	}
 
-	// Contact the auth server to request a short-lived JWT that will give us
-	// authoritative access to the backend resources associated with the user who's
-	// gifted the subs
-	fmt.Printf("Requesting JWT in response to subs gift from user %s\n", ev.UserName)
-	accessToken, err := h.authServiceClient.RequestServiceToken(ctx, auth.ServiceTokenRequest{
-		Service: "showtime",
-		User: auth.UserDetails{
-			Id:          ev.UserID,
-			Login:       ev.UserLogin,
-			DisplayName: ev.UserName,
-		},
-	})
+	// Determine how many points to award for this gift, according to the current
+	// rules: awards vary by tier, and bonuses may apply depending on what's on stream
+	basePoints, multiplier, ok, err := h.resolveCredit(ctx, points.EventTypeGiftSub, ev.Tier)
 	if err != nil {
-		return fmt.Errorf("RequestServiceToken failed: %w", err)
+		return err
 	}
 
-	// Send a request to the ledger server to request that points be granted to the user
-	fmt.Printf("Requesting credit of %d points x %d gift subs (before %.fx multiplier) to user %s\n", BasePointsForGiftSub, ev.Total, multiplier, ev.UserName)
-	_, err = h.ledgerClient.RequestCreditFromGiftSub(ctx, accessToken, BasePointsForGiftSub, ev.Total, multiplier)
-	if err != nil {
-		return fmt.Errorf("RequestCreditFromGiftSub failed: %v", err)
-	}
+	if ok {
+		err = h.creditOnce(ctx, ev.UserID, func() error {
+			// Contact the auth server to request a short-lived JWT that will give us
+			// authoritative access to the backend resources associated with the user who's
+			// gifted the subs
+			fmt.Printf("Requesting JWT in response to subs gift from user %s\n", ev.UserName)
+			accessToken, err := h.authServiceClient.RequestServiceToken(ctx, auth.ServiceTokenRequest{
+				Service: "showtime",
+				User: auth.UserDetails{
+					Id:          ev.UserID,
+					Login:       ev.UserLogin,
+					DisplayName: ev.UserName,
+				},
+			})
+			if err != nil {
+				return fmt.Errorf("RequestServiceToken failed: %w", err)
+			}
 
-	// Finally, emit an alert so this user's new subscription will be acknowledged in
-	// the on-stream overlay graphics
-	h.alertsChan <- &alerts.Alert{
-		Type: alerts.AlertTypeGiftSub,
-		Data: alerts.AlertData{
-			GiftSub: &alerts.AlertDataGiftSub{
-				Username:         ev.UserName,
-				NumSubscriptions: ev.Total,
-			},
-		},
+			// Send a request to the ledger server to request that points be granted to the
+			// user
+			fmt.Printf("Requesting credit of %d points x %d gift subs (before %gx multiplier) to user %s\n", basePoints, ev.Total, multiplier, ev.UserName)
+			_, err = h.ledgerClient.RequestCreditFromGiftSub(ctx, accessToken, basePoints, ev.Total, multiplier)
+			if err != nil {
+				return fmt.Errorf("RequestCreditFromGiftSub failed: %v", err)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	} else {
+		fmt.Printf("No points are awarded for %s at tier %s; not crediting user %s\n", points.EventTypeGiftSub, ev.Tier, ev.UserName)
 	}
 
+	// Finally, register the gift so that a single alert will be emitted, naming the
+	// gifter and listing the recipients, once we've seen the 'channel.subscribe' event
+	// for each recipient
+	gifterId := ev.UserID
+	if ev.IsAnonymous {
+		gifterId = ""
+	}
+	h.giftBursts.addGift(gifterId, ev.UserName, ev.Tier, ev.Total)
 	return nil
 }
 
@@ -176,33 +230,59 @@ func (h *Handler) handleChannelSubscriptionMessageEvent(ctx context.Context, dat
 		return fmt.Errorf("RecordViewerSubscribe failed: %w\n", err)
 	}
 
-	// If the subscription is tier 2 or 3, award 2x or 5x the points
-	multiplier, err := getCreditMultiplierFromTier(ev.Tier)
-	if err != nil {
-		return nil
+	// Update their ongoing subscription with the tenure they've chosen to share
+	streakMonths := sql.NullInt32{}
+	if ev.StreakMonths > 0 {
+		streakMonths = sql.NullInt32{Valid: true, Int32: int32(ev.StreakMonths)}
+	}
+	if err := h.q.RecordSubscriptionMessage(ctx, queries.RecordSubscriptionMessageParams{
+		TwitchUserID:     ev.UserID,
+		Tier:             ev.Tier,
+		CumulativeMonths: int32(ev.CumulativeMonths),
+		StreakMonths:     streakMonths,
+	}); err != nil {
+		return fmt.Errorf("RecordSubscriptionMessage failed: %w", err)
 	}
 
-	// Contact the auth server to request a short-lived JWT that will give us
-	// authoritative access to the backend resources associated with the user who's now
-	// a subscriber
-	fmt.Printf("Requesting JWT in response to resub message for user %s\n", ev.UserName)
-	accessToken, err := h.authServiceClient.RequestServiceToken(ctx, auth.ServiceTokenRequest{
-		Service: "showtime",
-		User: auth.UserDetails{
-			Id:          ev.UserID,
-			Login:       ev.UserLogin,
-			DisplayName: ev.UserName,
-		},
-	})
+	// Determine how many points to award for this resub, according to the current
+	// rules: awards vary by tier, and bonuses may apply depending on what's on stream
+	basePoints, multiplier, ok, err := h.resolveCredit(ctx, points.EventTypeResubscription, ev.Tier)
 	if err != nil {
-		return fmt.Errorf("RequestServiceToken failed: %w", err)
+		return err
 	}
 
-	// Send a request to the ledger server to request that points be granted to the user
-	fmt.Printf("Requesting credit of %d points (before %.fx multiplier) to user %s\n", BasePointsForSubscription, multiplier, ev.UserName)
-	_, err = h.ledgerClient.RequestCreditFromSubscription(ctx, accessToken, BasePointsForSubscription, false, false, ev.Message.Text, multiplier)
-	if err != nil {
-		return fmt.Errorf("RequestCreditFromSubscription failed: %v", err)
+	if ok {
+		err = h.creditOnce(ctx, ev.UserID, func() error {
+			// Contact the auth server to request a short-lived JWT that will give us
+			// authoritative access to the backend resources associated with the user who's
+			// now a subscriber
+			fmt.Printf("Requesting JWT in response to resub message for user %s\n", ev.UserName)
+			accessToken, err := h.authServiceClient.RequestServiceToken(ctx, auth.ServiceTokenRequest{
+				Service: "showtime",
+				User: auth.UserDetails{
+					Id:          ev.UserID,
+					Login:       ev.UserLogin,
+					DisplayName: ev.UserName,
+				},
+			})
+			if err != nil {
+				return fmt.Errorf("RequestServiceToken failed: %w", err)
+			}
+
+			// Send a request to the ledger server to request that points be granted to the
+			// user
+			fmt.Printf("Requesting credit of %d points (before %gx multiplier) to user %s\n", basePoints, multiplier, ev.UserName)
+			_, err = h.ledgerClient.RequestCreditFromSubscription(ctx, accessToken, basePoints, false, false, ev.Message.Text, multiplier)
+			if err != nil {
+				return fmt.Errorf("RequestCreditFromSubscription failed: %v", err)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	} else {
+		fmt.Printf("No points are awarded for %s at tier %s; not crediting user %s\n", points.EventTypeResubscription, ev.Tier, ev.UserName)
 	}
 
 	// Finally, emit an alert so this user's resub message will be acknowledged in the
@@ -222,20 +302,55 @@ func (h *Handler) handleChannelSubscriptionMessageEvent(ctx context.Context, dat
 	return nil
 }
 
-func getCreditMultiplierFromTier(tier string) (float64, error) {
-	// We expect the "tier" value in EventSub messages to be one of the following:
-	switch tier {
-	case "1000":
-		// Tier 1 subs are the baseline at $5; fun points are credited 1x
-		return 1, nil
-	case "2000":
-		// Tier 2 subs cost $10; fun point credits are doubled
-		return 2, nil
-	case "3000":
-		// Tier 3 subs are $25; so subscribers get 5x fun points
-		return 5, nil
-	}
-
-	// In the event of an unrecognized value, fail
-	return 0, fmt.Errorf("unrecognized tier value '%s'", tier)
+// creditOnce calls credit in order to grant fun points to the given user, unless we've
+// already done so while handling the same EventSub message: this ensures that retrying
+// a partially-handled message won't credit anyone twice
+func (h *Handler) creditOnce(ctx context.Context, twitchUserId string, credit func() error) error {
+	messageId, ok := messageIdFromContext(ctx)
+	if !ok {
+		return credit()
+	}
+	return h.creditOnceForKey(ctx, messageId, twitchUserId, credit)
+}
+
+// creditOnceForKey calls credit in order to grant fun points to the given user, unless
+// we've already done so in response to the same key: the key is typically an EventSub
+// message ID, but may be any ID that's stable across retries (e.g. a redemption ID)
+func (h *Handler) creditOnceForKey(ctx context.Context, messageId string, twitchUserId string, credit func() error) error {
+	creditedAt, err := h.q.ClaimSubscriptionCredit(ctx, queries.ClaimSubscriptionCreditParams{
+		MessageID:    messageId,
+		TwitchUserID: twitchUserId,
+	})
+	if err != nil {
+		return fmt.Errorf("ClaimSubscriptionCredit failed: %w", err)
+	}
+	if creditedAt.Valid {
+		fmt.Printf("User %s has already been credited in response to message %s\n", twitchUserId, messageId)
+		return nil
+	}
+	if err := credit(); err != nil {
+		return err
+	}
+
+	// The credit has been applied, so we must not return an error (which would cause
+	// the message to be retried) even if we can't record that fact
+	if err := h.q.RecordSubscriptionCreditApplied(ctx, queries.RecordSubscriptionCreditAppliedParams{
+		MessageID:    messageId,
+		TwitchUserID: twitchUserId,
+	}); err != nil {
+		fmt.Printf("Failed to record credit to user %s in response to message %s: %v\n", twitchUserId, messageId, err)
+	}
+	return nil
+}
+
+// recordSubscriptionGifter attributes the given gifter to the ongoing subscriptions of
+// the viewers who received their gift subs. This is called in the background once a
+// gift burst is complete, so failures are only logged.
+func (h *Handler) recordSubscriptionGifter(gifterId string, recipientIds []string) {
+	if err := h.q.RecordSubscriptionGifter(h.backgroundCtx, queries.RecordSubscriptionGifterParams{
+		GifterTwitchUserID: sql.NullString{Valid: true, String: gifterId},
+		TwitchUserIds:      recipientIds,
+	}); err != nil {
+		fmt.Printf("Failed to record user %s as gifter of subscriptions to %v: %v\n", gifterId, recipientIds, err)
+	}
 }
@@ -0,0 +1,57 @@
+package events
+
+import (
+	"context"
+	"testing"
+
+	"github.com/nicklaw5/helix/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_RouteEventsByChannel(t *testing.T) {
+	handleEvent := RouteEventsByChannel(&Handler{channelUserId: "1000"}, &Handler{channelUserId: "2000"})
+	err := handleEvent(context.Background(), &helix.EventSubSubscription{
+		Type:      helix.EventSubTypeStreamOnline,
+		Condition: helix.EventSubCondition{BroadcasterUserID: "3000"},
+	}, nil)
+	assert.ErrorIs(t, err, ErrUnrecognizedChannel)
+	assert.False(t, isRetryable(err))
+}
+
+func Test_getSubscriptionChannelUserId(t *testing.T) {
+	tests := []struct {
+		name         string
+		subscription helix.EventSubSubscription
+		want         string
+	}{
+		{
+			"stream.online is conditioned on broadcaster",
+			helix.EventSubSubscription{
+				Type:      helix.EventSubTypeStreamOnline,
+				Condition: helix.EventSubCondition{BroadcasterUserID: "1000"},
+			},
+			"1000",
+		},
+		{
+			"incoming raid is conditioned on the raided channel",
+			helix.EventSubSubscription{
+				Type:      helix.EventSubTypeChannelRaid,
+				Condition: helix.EventSubCondition{ToBroadcasterUserID: "1000"},
+			},
+			"1000",
+		},
+		{
+			"outgoing raid is conditioned on the raiding channel",
+			helix.EventSubSubscription{
+				Type:      helix.EventSubTypeChannelRaid,
+				Condition: helix.EventSubCondition{FromBroadcasterUserID: "2000"},
+			},
+			"2000",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, getSubscriptionChannelUserId(&tt.subscription))
+		})
+	}
+}
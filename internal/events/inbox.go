@@ -0,0 +1,223 @@
+package events
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/golden-vcr/showtime/gen/queries"
+	"github.com/nicklaw5/helix/v2"
+)
+
+// InboxQueries represents the subset of database functionality required to process
+// EventSub notifications from the durable inbox
+type InboxQueries interface {
+	ClaimEventSubInboxEntry(ctx context.Context, lockedUntil time.Time) (queries.ClaimEventSubInboxEntryRow, error)
+	RecordEventSubInboxSuccess(ctx context.Context, messageID string) error
+	RecordEventSubInboxFailure(ctx context.Context, arg queries.RecordEventSubInboxFailureParams) error
+	RecordEventSubInboxDeadLettered(ctx context.Context, arg queries.RecordEventSubInboxDeadLetteredParams) error
+}
+
+// InboxConfig controls how aggressively the Inbox processes and retries notifications
+type InboxConfig struct {
+	// NumWorkers is the number of notifications that may be processed concurrently
+	NumWorkers int
+	// MaxAttempts is the number of times we'll try to process a notification before
+	// giving up on it and dead-lettering it
+	MaxAttempts int
+	// BaseBackoff is the delay before the first retry: each subsequent retry waits
+	// twice as long as the last, up to MaxBackoff
+	BaseBackoff time.Duration
+	// MaxBackoff is the longest we'll ever wait between attempts
+	MaxBackoff time.Duration
+	// LeaseDuration is how long a worker may hold a notification before other workers
+	// assume that it's been abandoned and pick it back up
+	LeaseDuration time.Duration
+	// PollInterval is how often idle workers check the inbox for notifications that
+	// have become ready to retry
+	PollInterval time.Duration
+}
+
+// Inbox processes EventSub notifications that have been durably recorded by the
+// Server, handing each one to a HandleEventFunc in the background. Notifications that
+// fail to be handled are retried with exponential backoff, and once they've exhausted
+// all attempts they're dead-lettered so that the broadcaster can inspect them.
+type Inbox struct {
+	q           InboxQueries
+	handleEvent HandleEventFunc
+	config      InboxConfig
+	wake        chan struct{}
+	now         func() time.Time
+}
+
+func NewInbox(q InboxQueries, handleEvent HandleEventFunc, config InboxConfig) *Inbox {
+	return &Inbox{
+		q:           q,
+		handleEvent: handleEvent,
+		config:      config,
+		wake:        make(chan struct{}, 1),
+		now:         time.Now,
+	}
+}
+
+// Notify wakes up an idle worker so that a newly-enqueued notification will be
+// processed immediately rather than on the next poll
+func (i *Inbox) Notify() {
+	select {
+	case i.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Run starts the configured number of workers and blocks until the context is
+// canceled. Workers that fail to read or update the inbox (e.g. because the database
+// is briefly unavailable) log the error and try again on the next poll: any
+// notification whose result couldn't be recorded is picked up again once its lease
+// expires.
+func (i *Inbox) Run(ctx context.Context) error {
+	numWorkers := i.config.NumWorkers
+	if numWorkers < 1 {
+		numWorkers = 1
+	}
+	var wg sync.WaitGroup
+	for n := 0; n < numWorkers; n++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			i.runWorker(ctx)
+		}()
+	}
+	wg.Wait()
+	return ctx.Err()
+}
+
+func (i *Inbox) runWorker(ctx context.Context) {
+	for ctx.Err() == nil {
+		// Process notifications for as long as any are ready
+		didWork, err := i.processNext(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+
+			// The inbox itself is unusable for now: back off until the next poll
+			fmt.Printf("EventSub inbox worker got an error; retrying in %s: %v\n", i.config.PollInterval, err)
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(i.config.PollInterval):
+			}
+			continue
+		}
+		if didWork {
+			continue
+		}
+
+		// The inbox is empty (or nothing is ready to retry yet): wait until a new
+		// notification arrives or it's time to poll again
+		select {
+		case <-ctx.Done():
+			return
+		case <-i.wake:
+		case <-time.After(i.config.PollInterval):
+		}
+	}
+}
+
+// processNext claims the next notification that's ready to be processed, if any,
+// and attempts to handle it. Returns false if there was nothing to process. Errors
+// encountered while handling the event are recorded in the inbox; an error is only
+// returned if the inbox itself could not be read or updated.
+func (i *Inbox) processNext(ctx context.Context) (bool, error) {
+	// Claim a notification so that no other worker will attempt to process it while
+	// we're working on it
+	entry, err := i.q.ClaimEventSubInboxEntry(ctx, i.now().Add(i.config.LeaseDuration))
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to claim inbox entry: %w", err)
+	}
+
+	// Attempt to handle the event
+	handleErr := i.handle(ctx, &entry)
+	if handleErr == nil {
+		if err := i.q.RecordEventSubInboxSuccess(ctx, entry.MessageID); err != nil {
+			return true, fmt.Errorf("failed to record success for inbox entry %s: %w", entry.MessageID, err)
+		}
+		return true, nil
+	}
+
+	// If we've run out of attempts, or if the failure can't be resolved by retrying,
+	// dead-letter the notification so that it won't be processed again until the
+	// broadcaster intervenes
+	numAttempts := int(entry.NumAttempts) + 1
+	if numAttempts >= i.config.MaxAttempts || !isRetryable(handleErr) {
+		fmt.Printf("Dead-lettering message %s after %d attempt(s): %v\n", entry.MessageID, numAttempts, handleErr)
+		if err := i.q.RecordEventSubInboxDeadLettered(ctx, queries.RecordEventSubInboxDeadLetteredParams{
+			ErrorMessage: handleErr.Error(),
+			MessageID:    entry.MessageID,
+		}); err != nil {
+			return true, fmt.Errorf("failed to dead-letter inbox entry %s: %w", entry.MessageID, err)
+		}
+		return true, nil
+	}
+
+	// Otherwise, schedule another attempt after an exponentially-increasing delay
+	backoff := i.getBackoff(numAttempts)
+	fmt.Printf("Failed to handle message %s (attempt %d of %d); retrying in %s: %v\n", entry.MessageID, numAttempts, i.config.MaxAttempts, backoff, handleErr)
+	if err := i.q.RecordEventSubInboxFailure(ctx, queries.RecordEventSubInboxFailureParams{
+		ErrorMessage:  handleErr.Error(),
+		NextAttemptAt: i.now().Add(backoff),
+		MessageID:     entry.MessageID,
+	}); err != nil {
+		return true, fmt.Errorf("failed to record failure for inbox entry %s: %w", entry.MessageID, err)
+	}
+	return true, nil
+}
+
+func (i *Inbox) handle(ctx context.Context, entry *queries.ClaimEventSubInboxEntryRow) error {
+	var subscription helix.EventSubSubscription
+	if err := json.Unmarshal(entry.Subscription, &subscription); err != nil {
+		return &nonRetryableError{fmt.Errorf("failed to unmarshal subscription: %w", err)}
+	}
+	return i.handleEvent(WithMessageId(ctx, entry.MessageID), &subscription, entry.Event)
+}
+
+// getBackoff returns the delay to wait after the given number of failed attempts
+func (i *Inbox) getBackoff(numAttempts int) time.Duration {
+	backoff := i.config.BaseBackoff
+	for n := 1; n < numAttempts; n++ {
+		backoff *= 2
+		if backoff >= i.config.MaxBackoff {
+			return i.config.MaxBackoff
+		}
+	}
+	return backoff
+}
+
+// nonRetryableError wraps an error that will recur no matter how many times we try
+// to handle the same notification
+type nonRetryableError struct {
+	err error
+}
+
+func (e *nonRetryableError) Error() string {
+	return e.err.Error()
+}
+
+func (e *nonRetryableError) Unwrap() error {
+	return e.err
+}
+
+func isRetryable(err error) bool {
+	if errors.Is(err, ErrUnsupportedEventType) || errors.Is(err, ErrUnrecognizedChannel) {
+		return false
+	}
+	var nre *nonRetryableError
+	return !errors.As(err, &nre)
+}
@@ -0,0 +1,299 @@
+package events
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/golden-vcr/showtime/gen/queries"
+	"github.com/nicklaw5/helix/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Inbox_processNext(t *testing.T) {
+	now := time.Date(1997, 9, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name             string
+		q                *mockInboxQueries
+		handleErr        error
+		wantDidWork      bool
+		wantErr          string
+		wantHandled      bool
+		wantSuccess      bool
+		wantFailure      *queries.RecordEventSubInboxFailureParams
+		wantDeadLettered *queries.RecordEventSubInboxDeadLetteredParams
+	}{
+		{
+			"no-op if inbox is empty",
+			&mockInboxQueries{},
+			nil,
+			false,
+			"",
+			false,
+			false,
+			nil,
+			nil,
+		},
+		{
+			"error claiming entry is returned",
+			&mockInboxQueries{
+				claimErr: fmt.Errorf("mock error"),
+			},
+			nil,
+			false,
+			"failed to claim inbox entry: mock error",
+			false,
+			false,
+			nil,
+			nil,
+		},
+		{
+			"successfully-handled event is recorded as processed",
+			&mockInboxQueries{
+				entry: &queries.ClaimEventSubInboxEntryRow{
+					MessageID:    "message-1",
+					Subscription: json.RawMessage(`{"type":"channel.follow"}`),
+					Event:        json.RawMessage(`{}`),
+				},
+			},
+			nil,
+			true,
+			"",
+			true,
+			true,
+			nil,
+			nil,
+		},
+		{
+			"failed event is scheduled for retry with backoff",
+			&mockInboxQueries{
+				entry: &queries.ClaimEventSubInboxEntryRow{
+					MessageID:    "message-1",
+					Subscription: json.RawMessage(`{"type":"channel.follow"}`),
+					Event:        json.RawMessage(`{}`),
+					NumAttempts:  2,
+				},
+			},
+			fmt.Errorf("mock error"),
+			true,
+			"",
+			true,
+			false,
+			&queries.RecordEventSubInboxFailureParams{
+				ErrorMessage:  "mock error",
+				NextAttemptAt: now.Add(4 * time.Second),
+				MessageID:     "message-1",
+			},
+			nil,
+		},
+		{
+			"failed event is dead-lettered after max attempts",
+			&mockInboxQueries{
+				entry: &queries.ClaimEventSubInboxEntryRow{
+					MessageID:    "message-1",
+					Subscription: json.RawMessage(`{"type":"channel.follow"}`),
+					Event:        json.RawMessage(`{}`),
+					NumAttempts:  4,
+				},
+			},
+			fmt.Errorf("mock error"),
+			true,
+			"",
+			true,
+			false,
+			nil,
+			&queries.RecordEventSubInboxDeadLetteredParams{
+				ErrorMessage: "mock error",
+				MessageID:    "message-1",
+			},
+		},
+		{
+			"unsupported event is dead-lettered immediately",
+			&mockInboxQueries{
+				entry: &queries.ClaimEventSubInboxEntryRow{
+					MessageID:    "message-1",
+					Subscription: json.RawMessage(`{"type":"channel.follow"}`),
+					Event:        json.RawMessage(`{}`),
+				},
+			},
+			ErrUnsupportedEventType,
+			true,
+			"",
+			true,
+			false,
+			nil,
+			&queries.RecordEventSubInboxDeadLetteredParams{
+				ErrorMessage: "unsupported event type",
+				MessageID:    "message-1",
+			},
+		},
+		{
+			"event with invalid subscription is dead-lettered without being handled",
+			&mockInboxQueries{
+				entry: &queries.ClaimEventSubInboxEntryRow{
+					MessageID:    "message-1",
+					Subscription: json.RawMessage(`"bogus"`),
+					Event:        json.RawMessage(`{}`),
+				},
+			},
+			nil,
+			true,
+			"",
+			false,
+			false,
+			nil,
+			&queries.RecordEventSubInboxDeadLetteredParams{
+				ErrorMessage: "failed to unmarshal subscription: json: cannot unmarshal string into Go value of type helix.EventSubSubscription",
+				MessageID:    "message-1",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handled := false
+			i := &Inbox{
+				q: tt.q,
+				handleEvent: func(ctx context.Context, subscription *helix.EventSubSubscription, data json.RawMessage) error {
+					handled = true
+					return tt.handleErr
+				},
+				config: InboxConfig{
+					MaxAttempts:   5,
+					BaseBackoff:   time.Second,
+					MaxBackoff:    time.Minute,
+					LeaseDuration: time.Minute,
+				},
+				now: func() time.Time {
+					return now
+				},
+			}
+			didWork, err := i.processNext(context.Background())
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tt.wantDidWork, didWork)
+			assert.Equal(t, tt.wantHandled, handled)
+			assert.Equal(t, tt.wantSuccess, tt.q.succeeded)
+			assert.Equal(t, tt.wantFailure, tt.q.failure)
+			assert.Equal(t, tt.wantDeadLettered, tt.q.deadLettered)
+		})
+	}
+}
+
+func Test_Inbox_Run(t *testing.T) {
+	// The first attempt to claim an entry fails, as if the database connection had
+	// dropped: the worker should log the error and keep going
+	q := &flakyInboxQueries{
+		mockInboxQueries: mockInboxQueries{
+			entry: &queries.ClaimEventSubInboxEntryRow{
+				MessageID:    "message-1",
+				Subscription: json.RawMessage(`{"type":"channel.follow"}`),
+				Event:        json.RawMessage(`{}`),
+			},
+		},
+	}
+	handled := make(chan struct{}, 1)
+	i := NewInbox(q, func(ctx context.Context, subscription *helix.EventSubSubscription, data json.RawMessage) error {
+		select {
+		case handled <- struct{}{}:
+		default:
+		}
+		return nil
+	}, InboxConfig{
+		NumWorkers:    1,
+		MaxAttempts:   5,
+		BaseBackoff:   time.Second,
+		MaxBackoff:    time.Minute,
+		LeaseDuration: time.Minute,
+		PollInterval:  time.Millisecond,
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	done := make(chan error)
+	go func() {
+		done <- i.Run(ctx)
+	}()
+
+	select {
+	case <-handled:
+	case err := <-done:
+		t.Fatalf("inbox stopped running: %v", err)
+	case <-time.After(time.Second):
+		t.Fatalf("event was never handled")
+	}
+	cancel()
+	assert.ErrorIs(t, <-done, context.Canceled)
+	assert.Greater(t, q.numClaims, 1)
+	assert.True(t, q.succeeded)
+}
+
+func Test_Inbox_getBackoff(t *testing.T) {
+	i := &Inbox{
+		config: InboxConfig{
+			BaseBackoff: 5 * time.Second,
+			MaxBackoff:  time.Minute,
+		},
+	}
+	assert.Equal(t, 5*time.Second, i.getBackoff(1))
+	assert.Equal(t, 10*time.Second, i.getBackoff(2))
+	assert.Equal(t, 20*time.Second, i.getBackoff(3))
+	assert.Equal(t, 40*time.Second, i.getBackoff(4))
+	assert.Equal(t, time.Minute, i.getBackoff(5))
+	assert.Equal(t, time.Minute, i.getBackoff(50))
+}
+
+type mockInboxQueries struct {
+	claimErr     error
+	entry        *queries.ClaimEventSubInboxEntryRow
+	succeeded    bool
+	failure      *queries.RecordEventSubInboxFailureParams
+	deadLettered *queries.RecordEventSubInboxDeadLetteredParams
+}
+
+func (m *mockInboxQueries) ClaimEventSubInboxEntry(ctx context.Context, lockedUntil time.Time) (queries.ClaimEventSubInboxEntryRow, error) {
+	if m.claimErr != nil {
+		return queries.ClaimEventSubInboxEntryRow{}, m.claimErr
+	}
+	if m.entry == nil {
+		return queries.ClaimEventSubInboxEntryRow{}, sql.ErrNoRows
+	}
+	return *m.entry, nil
+}
+
+func (m *mockInboxQueries) RecordEventSubInboxSuccess(ctx context.Context, messageID string) error {
+	m.succeeded = true
+	return nil
+}
+
+func (m *mockInboxQueries) RecordEventSubInboxFailure(ctx context.Context, arg queries.RecordEventSubInboxFailureParams) error {
+	m.failure = &arg
+	return nil
+}
+
+func (m *mockInboxQueries) RecordEventSubInboxDeadLettered(ctx context.Context, arg queries.RecordEventSubInboxDeadLetteredParams) error {
+	m.deadLettered = &arg
+	return nil
+}
+
+var _ InboxQueries = (*mockInboxQueries)(nil)
+
+// flakyInboxQueries fails on its first attempt to claim an entry, then behaves like
+// mockInboxQueries
+type flakyInboxQueries struct {
+	mockInboxQueries
+	numClaims int
+}
+
+func (f *flakyInboxQueries) ClaimEventSubInboxEntry(ctx context.Context, lockedUntil time.Time) (queries.ClaimEventSubInboxEntryRow, error) {
+	f.numClaims++
+	if f.numClaims == 1 {
+		return queries.ClaimEventSubInboxEntryRow{}, fmt.Errorf("connection reset by peer")
+	}
+	return f.mockInboxQueries.ClaimEventSubInboxEntry(ctx, lockedUntil)
+}
@@ -0,0 +1,110 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/golden-vcr/showtime/gen/queries"
+	"github.com/nicklaw5/helix/v2"
+)
+
+// newRecordMessageFunc returns a RecordMessageFunc that records the ID of each
+// message in the database
+func newRecordMessageFunc(q *queries.Queries) RecordMessageFunc {
+	return func(ctx context.Context, messageId string, messageType string, messageTimestamp time.Time, subscription *helix.EventSubSubscription) error {
+		_, err := q.RecordEventSubMessage(ctx, queries.RecordEventSubMessageParams{
+			MessageID:        messageId,
+			MessageType:      messageType,
+			SubscriptionType: subscription.Type,
+			MessageTimestamp: messageTimestamp,
+		})
+		return err
+	}
+}
+
+// newRecordRevocationFunc returns a RecordRevocationFunc that records each revocation
+// in the database, returning false if we've already seen the same message
+func newRecordRevocationFunc(q *queries.Queries) RecordRevocationFunc {
+	return func(ctx context.Context, messageId string, messageTimestamp time.Time, subscription *helix.EventSubSubscription) (bool, error) {
+		conditionData, err := json.Marshal(subscription.Condition)
+		if err != nil {
+			return false, err
+		}
+		result, err := q.RecordEventSubRevocation(ctx, queries.RecordEventSubRevocationParams{
+			MessageID:           messageId,
+			SubscriptionType:    subscription.Type,
+			MessageTimestamp:    messageTimestamp,
+			SubscriptionID:      subscription.ID,
+			SubscriptionVersion: subscription.Version,
+			Reason:              subscription.Status,
+			Condition:           conditionData,
+		})
+		if err != nil {
+			return false, err
+		}
+		numRows, err := result.RowsAffected()
+		if err != nil {
+			return false, err
+		}
+		return numRows > 0, nil
+	}
+}
+
+// RevocationResolver represents the database functionality required to mark
+// revocations as resolved once the revoked subscription has been recreated
+type RevocationResolver interface {
+	ResolveEventSubRevocations(ctx context.Context, arg queries.ResolveEventSubRevocationsParams) (int64, error)
+}
+
+// ResolveRevocations records that we've recreated a subscription with the given type,
+// version, and condition, resolving any prior revocations of that same subscription
+func ResolveRevocations(ctx context.Context, q RevocationResolver, subscriptionType string, subscriptionVersion string, condition *helix.EventSubCondition) error {
+	conditionData, err := json.Marshal(condition)
+	if err != nil {
+		return err
+	}
+	numRows, err := q.ResolveEventSubRevocations(ctx, queries.ResolveEventSubRevocationsParams{
+		SubscriptionType:    subscriptionType,
+		SubscriptionVersion: subscriptionVersion,
+		Condition:           conditionData,
+	})
+	if err != nil {
+		return err
+	}
+	if numRows > 0 {
+		fmt.Printf("Resolved %d revocation(s) of '%s' v%s subscription.\n", numRows, subscriptionType, subscriptionVersion)
+	}
+	return nil
+}
+
+// newEnqueueNotificationFunc returns an EnqueueNotificationFunc that writes each
+// notification to the durable inbox, returning false if we've already seen the same
+// message
+func newEnqueueNotificationFunc(q *queries.Queries) EnqueueNotificationFunc {
+	return func(ctx context.Context, messageId string, messageTimestamp time.Time, subscription *helix.EventSubSubscription, data json.RawMessage) (bool, error) {
+		subscriptionData, err := json.Marshal(subscription)
+		if err != nil {
+			return false, err
+		}
+
+		// We only record each message ID once: if no row is inserted, then we've
+		// already seen this message
+		result, err := q.EnqueueEventSubNotification(ctx, queries.EnqueueEventSubNotificationParams{
+			MessageID:        messageId,
+			SubscriptionType: subscription.Type,
+			MessageTimestamp: messageTimestamp,
+			Subscription:     subscriptionData,
+			Event:            data,
+		})
+		if err != nil {
+			return false, err
+		}
+		numRows, err := result.RowsAffected()
+		if err != nil {
+			return false, err
+		}
+		return numRows > 0, nil
+	}
+}
@@ -0,0 +1,111 @@
+package events
+
+import (
+	"fmt"
+
+	"github.com/nicklaw5/helix/v2"
+)
+
+// SubscriptionPlan describes exactly which EventSub subscriptions need to be deleted
+// and created in order for the subscriptions we own to match RequiredSubscriptions
+type SubscriptionPlan struct {
+	// ToDelete lists existing subscriptions that must be deleted, in the order they
+	// should be deleted
+	ToDelete []PlannedDeletion `json:"toDelete"`
+	// ToCreate lists new subscriptions that must be created once all deletions have
+	// occurred
+	ToCreate []PlannedCreation `json:"toCreate"`
+	// NumUnchanged is the number of required subscriptions that already exist and are
+	// healthy, requiring no changes
+	NumUnchanged int `json:"numUnchanged"`
+}
+
+// PlannedDeletion is an existing subscription that needs to be deleted
+type PlannedDeletion struct {
+	Id      string `json:"id"`
+	Type    string `json:"type"`
+	Version string `json:"version"`
+	Status  string `json:"status"`
+	Reason  string `json:"reason"`
+	// Recreate is true if the subscription is required but unhealthy, in which case a
+	// corresponding entry will be included in ToCreate to replace it
+	Recreate bool `json:"recreate"`
+}
+
+// PlannedCreation is a required subscription that needs to be created
+type PlannedCreation struct {
+	Type      string                  `json:"type"`
+	Version   string                  `json:"version"`
+	Condition helix.EventSubCondition `json:"condition"`
+	Reason    string                  `json:"reason"`
+	// Replaces is the ID of the unhealthy subscription that this subscription will
+	// replace, if any
+	Replaces string `json:"replaces,omitempty"`
+}
+
+// HasChanges returns true if any subscriptions need to be deleted or created
+func (p *SubscriptionPlan) HasChanges() bool {
+	return len(p.ToDelete) > 0 || len(p.ToCreate) > 0
+}
+
+// PlanSubscriptionChanges determines what needs to happen in order to resolve the
+// differences identified by ReconcileRequiredSubscriptions: subscriptions that are
+// not required are deleted; required subscriptions that are neither pending nor
+// enabled are deleted and recreated; and missing subscriptions are created
+func PlanSubscriptionChanges(reconciled *ReconcileResult, channelUserId string) (*SubscriptionPlan, error) {
+	params := RequiredSubscriptionConditionParams{
+		ChannelUserId: channelUserId,
+	}
+	plan := &SubscriptionPlan{
+		ToDelete: make([]PlannedDeletion, 0),
+		ToCreate: make([]PlannedCreation, 0),
+	}
+	addCreation := func(required RequiredSubscription, reason string, replaces string) error {
+		condition, err := params.Format(&required.TemplatedCondition)
+		if err != nil {
+			return fmt.Errorf("failed to format condition for required '%s' subscription: %w", required.Type, err)
+		}
+		plan.ToCreate = append(plan.ToCreate, PlannedCreation{
+			Type:      required.Type,
+			Version:   required.Version,
+			Condition: *condition,
+			Reason:    reason,
+			Replaces:  replaces,
+		})
+		return nil
+	}
+
+	for _, existing := range reconciled.Existing {
+		status := existing.Value.Status
+		if status == helix.EventSubStatusPending || status == helix.EventSubStatusEnabled {
+			plan.NumUnchanged++
+			continue
+		}
+		plan.ToDelete = append(plan.ToDelete, PlannedDeletion{
+			Id:       existing.Value.ID,
+			Type:     existing.Value.Type,
+			Version:  existing.Value.Version,
+			Status:   status,
+			Reason:   fmt.Sprintf("status is '%s'", status),
+			Recreate: true,
+		})
+		if err := addCreation(existing.Required, fmt.Sprintf("replaces subscription %s", existing.Value.ID), existing.Value.ID); err != nil {
+			return nil, err
+		}
+	}
+	for _, subscription := range reconciled.ToDelete {
+		plan.ToDelete = append(plan.ToDelete, PlannedDeletion{
+			Id:      subscription.ID,
+			Type:    subscription.Type,
+			Version: subscription.Version,
+			Status:  subscription.Status,
+			Reason:  "not declared in RequiredSubscriptions",
+		})
+	}
+	for _, required := range reconciled.ToCreate {
+		if err := addCreation(required, "does not exist", ""); err != nil {
+			return nil, err
+		}
+	}
+	return plan, nil
+}
@@ -0,0 +1,86 @@
+package events
+
+import (
+	"testing"
+
+	"github.com/nicklaw5/helix/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_PlanSubscriptionChanges(t *testing.T) {
+	follow := RequiredSubscription{
+		Type:    helix.EventSubTypeChannelFollow,
+		Version: "2",
+		TemplatedCondition: helix.EventSubCondition{
+			BroadcasterUserID: "{{.ChannelUserId}}",
+			ModeratorUserID:   "{{.ChannelUserId}}",
+		},
+	}
+	cheer := RequiredSubscription{
+		Type:    helix.EventSubTypeChannelCheer,
+		Version: "1",
+		TemplatedCondition: helix.EventSubCondition{
+			BroadcasterUserID: "{{.ChannelUserId}}",
+		},
+	}
+	update := RequiredSubscription{
+		Type:    helix.EventSubTypeChannelUpdate,
+		Version: "2",
+		TemplatedCondition: helix.EventSubCondition{
+			BroadcasterUserID: "{{.ChannelUserId}}",
+		},
+	}
+
+	tests := []struct {
+		name       string
+		reconciled *ReconcileResult
+		want       *SubscriptionPlan
+	}{
+		{
+			"no changes are required if all subscriptions exist and are healthy",
+			&ReconcileResult{
+				Existing: []ExistingSubscription{
+					{Value: helix.EventSubSubscription{ID: "a", Status: helix.EventSubStatusEnabled}, Required: follow},
+					{Value: helix.EventSubSubscription{ID: "b", Status: helix.EventSubStatusPending}, Required: cheer},
+				},
+			},
+			&SubscriptionPlan{
+				ToDelete:     []PlannedDeletion{},
+				ToCreate:     []PlannedCreation{},
+				NumUnchanged: 2,
+			},
+		},
+		{
+			"unhealthy subscriptions are replaced, irrelevant subscriptions are deleted, and missing subscriptions are created",
+			&ReconcileResult{
+				ToDelete: []helix.EventSubSubscription{
+					{ID: "old", Type: helix.EventSubTypeChannelBan, Version: "1", Status: helix.EventSubStatusEnabled},
+				},
+				ToCreate: []RequiredSubscription{update},
+				Existing: []ExistingSubscription{
+					{Value: helix.EventSubSubscription{ID: "a", Type: helix.EventSubTypeChannelFollow, Version: "2", Status: helix.EventSubStatusEnabled}, Required: follow},
+					{Value: helix.EventSubSubscription{ID: "b", Type: helix.EventSubTypeChannelCheer, Version: "1", Status: "authorization_revoked"}, Required: cheer},
+				},
+			},
+			&SubscriptionPlan{
+				ToDelete: []PlannedDeletion{
+					{Id: "b", Type: helix.EventSubTypeChannelCheer, Version: "1", Status: "authorization_revoked", Reason: "status is 'authorization_revoked'", Recreate: true},
+					{Id: "old", Type: helix.EventSubTypeChannelBan, Version: "1", Status: helix.EventSubStatusEnabled, Reason: "not declared in RequiredSubscriptions"},
+				},
+				ToCreate: []PlannedCreation{
+					{Type: helix.EventSubTypeChannelCheer, Version: "1", Condition: helix.EventSubCondition{BroadcasterUserID: "1337"}, Reason: "replaces subscription b", Replaces: "b"},
+					{Type: helix.EventSubTypeChannelUpdate, Version: "2", Condition: helix.EventSubCondition{BroadcasterUserID: "1337"}, Reason: "does not exist"},
+				},
+				NumUnchanged: 1,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := PlanSubscriptionChanges(tt.reconciled, "1337")
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+			assert.Equal(t, len(tt.want.ToDelete) > 0 || len(tt.want.ToCreate) > 0, got.HasChanges())
+		})
+	}
+}
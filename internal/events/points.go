@@ -0,0 +1,72 @@
+package events
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/golden-vcr/showtime/internal/points"
+)
+
+// getPointsRules returns the rules that are currently in effect for awarding fun
+// points, falling back to the defaults if none have been configured
+func (h *Handler) getPointsRules(ctx context.Context) (*points.Rules, error) {
+	data, err := h.q.GetCurrentPointsRules(ctx)
+	if errors.Is(err, sql.ErrNoRows) {
+		rules := points.DefaultRules
+		return &rules, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("GetCurrentPointsRules failed: %w", err)
+	}
+	var rules points.Rules
+	if err := json.Unmarshal(data, &rules); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal points rules: %w", err)
+	}
+	return &rules, nil
+}
+
+// getPointsConditions determines whether we're currently live, and if so, which tape
+// (if any) is being screened, so that the appropriate bonuses can be applied
+func (h *Handler) getPointsConditions(ctx context.Context) (points.Conditions, error) {
+	broadcast, err := h.q.GetMostRecentBroadcast(ctx, h.channelUserId)
+	if errors.Is(err, sql.ErrNoRows) {
+		return points.Conditions{}, nil
+	}
+	if err != nil {
+		return points.Conditions{}, fmt.Errorf("GetMostRecentBroadcast failed: %w", err)
+	}
+	if broadcast.EndedAt.Valid {
+		return points.Conditions{}, nil
+	}
+
+	screening, err := h.q.GetMostRecentScreening(ctx, broadcast.ID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return points.Conditions{IsLive: true}, nil
+	}
+	if err != nil {
+		return points.Conditions{}, fmt.Errorf("GetMostRecentScreening failed: %w", err)
+	}
+	if screening.EndedAt.Valid {
+		return points.Conditions{IsLive: true}, nil
+	}
+	return points.Conditions{IsLive: true, TapeId: int(screening.TapeID)}, nil
+}
+
+// resolveCredit returns the number of base points and the multiplier with which a
+// viewer should be credited for an event of the given type and tier, according to the
+// current rules. Returns false if the rules don't award any points for that event.
+func (h *Handler) resolveCredit(ctx context.Context, eventType string, tier string) (int, float64, bool, error) {
+	rules, err := h.getPointsRules(ctx)
+	if err != nil {
+		return 0, 0, false, err
+	}
+	conditions, err := h.getPointsConditions(ctx)
+	if err != nil {
+		return 0, 0, false, err
+	}
+	basePoints, multiplier, ok := rules.Resolve(eventType, tier, conditions)
+	return basePoints, multiplier, ok, nil
+}
@@ -0,0 +1,260 @@
+package events
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/golden-vcr/showtime/gen/queries"
+	"github.com/golden-vcr/showtime/internal/twitch"
+	"github.com/nicklaw5/helix/v2"
+)
+
+const (
+	ReconcileActionDelete = "delete"
+	ReconcileActionCreate = "create"
+)
+
+// ReconcilerQueries represents the subset of database functionality required to
+// record the actions taken by the Reconciler, and to resolve the revocations of any
+// subscriptions that it recreates
+type ReconcilerQueries interface {
+	RevocationResolver
+	RecordEventSubReconciliation(ctx context.Context, arg queries.RecordEventSubReconciliationParams) error
+}
+
+// ReconcileReport describes the outcome of a single pass of the Reconciler
+type ReconcileReport struct {
+	StartedAt  time.Time         `json:"startedAt"`
+	FinishedAt time.Time         `json:"finishedAt"`
+	Actions    []ReconcileAction `json:"actions"`
+	// NumIgnored is the number of subscriptions that notify our callback but are not
+	// declared in RequiredSubscriptions: the Reconciler leaves these alone, since
+	// deleting them is left to cmd/init
+	NumIgnored int `json:"numIgnored"`
+	// Error describes why the Reconciler was unable to determine which subscriptions
+	// needed to be repaired, if applicable
+	Error string `json:"error,omitempty"`
+}
+
+// ReconcileAction is a subscription that the Reconciler deleted or created
+type ReconcileAction struct {
+	Action              string `json:"action"`
+	SubscriptionType    string `json:"subscriptionType"`
+	SubscriptionVersion string `json:"subscriptionVersion"`
+	SubscriptionId      string `json:"subscriptionId,omitempty"`
+	Reason              string `json:"reason"`
+	Error               string `json:"error,omitempty"`
+}
+
+// IsHealthy returns true if the pass completed without any errors
+func (r *ReconcileReport) IsHealthy() bool {
+	if r.Error != "" {
+		return false
+	}
+	for _, action := range r.Actions {
+		if action.Error != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// Reconciler periodically compares our webhook subscriptions against
+// RequiredSubscriptions, recreating any required subscriptions that are missing or
+// that have failed, so that they can be repaired without running cmd/init by hand
+type Reconciler struct {
+	c             twitch.SubscriptionManager
+	q             ReconcilerQueries
+	required      []RequiredSubscription
+	channelUserId string
+	transport     helix.EventSubTransport
+	interval      time.Duration
+	now           func() time.Time
+
+	mu   sync.RWMutex
+	last *ReconcileReport
+}
+
+// NewReconciler initializes a Reconciler that manages webhook subscriptions using the
+// given client, which should be authenticated with an app access token. The transport
+// must specify the callback URL and the secret with which new subscriptions will be
+// created.
+func NewReconciler(c twitch.SubscriptionManager, q ReconcilerQueries, required []RequiredSubscription, channelUserId string, transport helix.EventSubTransport, interval time.Duration) *Reconciler {
+	return &Reconciler{
+		c:             c,
+		q:             q,
+		required:      required,
+		channelUserId: channelUserId,
+		transport:     transport,
+		interval:      interval,
+		now:           time.Now,
+	}
+}
+
+// Run reconciles our subscriptions immediately and then once per interval, until the
+// context is canceled
+func (r *Reconciler) Run(ctx context.Context) error {
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+	for {
+		r.reconcile(ctx)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// GetLastReport returns the outcome of the most recent pass, or nil if the Reconciler
+// has not yet run
+func (r *Reconciler) GetLastReport() *ReconcileReport {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.last
+}
+
+func (r *Reconciler) reconcile(ctx context.Context) {
+	report := r.run(ctx)
+
+	r.mu.Lock()
+	r.last = report
+	r.mu.Unlock()
+
+	// Only record passes that did something interesting, so that we don't accumulate
+	// a row every interval while everything is healthy
+	if len(report.Actions) == 0 && report.Error == "" {
+		return
+	}
+	for _, action := range report.Actions {
+		if action.Error != "" {
+			fmt.Printf("Subscription reconciler failed to %s %s subscription: %s\n", action.Action, action.SubscriptionType, action.Error)
+		} else {
+			fmt.Printf("Subscription reconciler: %s %s v%s (%s)\n", action.Action, action.SubscriptionType, action.SubscriptionVersion, action.Reason)
+		}
+	}
+	if report.Error != "" {
+		fmt.Printf("Subscription reconciler failed: %s\n", report.Error)
+	}
+	actionsJson, err := json.Marshal(report.Actions)
+	if err != nil {
+		fmt.Printf("Failed to marshal subscription reconciler actions: %v\n", err)
+		return
+	}
+	if err := r.q.RecordEventSubReconciliation(ctx, queries.RecordEventSubReconciliationParams{
+		StartedAt:  report.StartedAt,
+		FinishedAt: report.FinishedAt,
+		Actions:    actionsJson,
+		Error: sql.NullString{
+			String: report.Error,
+			Valid:  report.Error != "",
+		},
+	}); err != nil {
+		fmt.Printf("Failed to record subscription reconciliation: %v\n", err)
+	}
+}
+
+func (r *Reconciler) run(ctx context.Context) *ReconcileReport {
+	report := &ReconcileReport{
+		StartedAt: r.now(),
+		Actions:   make([]ReconcileAction, 0),
+	}
+	defer func() {
+		report.FinishedAt = r.now()
+	}()
+
+	owned, err := GetOwnedSubscriptions(r.c, r.channelUserId, r.transport)
+	if err != nil {
+		report.Error = fmt.Sprintf("failed to get subscriptions: %v", err)
+		return report
+	}
+	reconciled, err := ReconcileRequiredSubscriptions(r.required, owned, r.channelUserId, r.transport)
+	if err != nil {
+		report.Error = fmt.Sprintf("failed to reconcile subscriptions: %v", err)
+		return report
+	}
+	plan, err := PlanSubscriptionChanges(reconciled, r.channelUserId)
+	if err != nil {
+		report.Error = fmt.Sprintf("failed to plan subscription changes: %v", err)
+		return report
+	}
+
+	// Delete any required subscriptions that have failed, so that they can be
+	// replaced: if a subscription can't be deleted, don't try to replace it
+	failedDeletions := make(map[string]struct{})
+	for _, deletion := range plan.ToDelete {
+		if !deletion.Recreate {
+			report.NumIgnored++
+			continue
+		}
+		action := ReconcileAction{
+			Action:              ReconcileActionDelete,
+			SubscriptionType:    deletion.Type,
+			SubscriptionVersion: deletion.Version,
+			SubscriptionId:      deletion.Id,
+			Reason:              deletion.Reason,
+		}
+		if err := r.deleteSubscription(deletion.Id); err != nil {
+			action.Error = err.Error()
+			failedDeletions[deletion.Id] = struct{}{}
+		}
+		report.Actions = append(report.Actions, action)
+	}
+
+	// Create all required subscriptions that don't currently exist
+	for _, creation := range plan.ToCreate {
+		if _, ok := failedDeletions[creation.Replaces]; ok {
+			continue
+		}
+		action := ReconcileAction{
+			Action:              ReconcileActionCreate,
+			SubscriptionType:    creation.Type,
+			SubscriptionVersion: creation.Version,
+			Reason:              creation.Reason,
+		}
+		id, err := r.createSubscription(&creation)
+		if err != nil {
+			action.Error = err.Error()
+		} else if err := ResolveRevocations(ctx, r.q, creation.Type, creation.Version, &creation.Condition); err != nil {
+			fmt.Printf("Failed to resolve revocations of '%s' subscription: %v\n", creation.Type, err)
+		}
+		action.SubscriptionId = id
+		report.Actions = append(report.Actions, action)
+	}
+	return report
+}
+
+func (r *Reconciler) deleteSubscription(id string) error {
+	res, err := r.c.RemoveEventSubSubscription(id)
+	if err != nil {
+		return err
+	}
+	if res.StatusCode != http.StatusNoContent {
+		return fmt.Errorf("got response %d: %s", res.StatusCode, res.ErrorMessage)
+	}
+	return nil
+}
+
+func (r *Reconciler) createSubscription(creation *PlannedCreation) (string, error) {
+	res, err := r.c.CreateEventSubSubscription(&helix.EventSubSubscription{
+		Type:      creation.Type,
+		Version:   creation.Version,
+		Condition: creation.Condition,
+		Transport: r.transport,
+	})
+	if err != nil {
+		return "", err
+	}
+	if res.StatusCode != http.StatusAccepted {
+		return "", fmt.Errorf("got response %d: %s", res.StatusCode, res.ErrorMessage)
+	}
+	if len(res.Data.EventSubSubscriptions) > 0 {
+		return res.Data.EventSubSubscriptions[0].ID, nil
+	}
+	return "", nil
+}
@@ -0,0 +1,321 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/golden-vcr/showtime/gen/queries"
+	"github.com/nicklaw5/helix/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Reconciler(t *testing.T) {
+	transport := helix.EventSubTransport{
+		Method:   TransportMethodWebhook,
+		Callback: "https://example.com/callback",
+		Secret:   "shh",
+	}
+	required := []RequiredSubscription{
+		{
+			Type:    helix.EventSubTypeChannelCheer,
+			Version: "1",
+			TemplatedCondition: helix.EventSubCondition{
+				BroadcasterUserID: "{{.ChannelUserId}}",
+			},
+		},
+		{
+			Type:    helix.EventSubTypeChannelUpdate,
+			Version: "2",
+			TemplatedCondition: helix.EventSubCondition{
+				BroadcasterUserID: "{{.ChannelUserId}}",
+			},
+		},
+	}
+	cheer := helix.EventSubSubscription{
+		ID:        "cheer-1",
+		Type:      helix.EventSubTypeChannelCheer,
+		Version:   "1",
+		Status:    helix.EventSubStatusEnabled,
+		Condition: helix.EventSubCondition{BroadcasterUserID: "1234"},
+		Transport: transport,
+	}
+	update := helix.EventSubSubscription{
+		ID:        "update-1",
+		Type:      helix.EventSubTypeChannelUpdate,
+		Version:   "2",
+		Status:    helix.EventSubStatusEnabled,
+		Condition: helix.EventSubCondition{BroadcasterUserID: "1234"},
+		Transport: transport,
+	}
+	withStatus := func(s helix.EventSubSubscription, status string) helix.EventSubSubscription {
+		s.Status = status
+		return s
+	}
+
+	tests := []struct {
+		name          string
+		c             *mockSubscriptionManager
+		wantActions   []ReconcileAction
+		wantIgnored   int
+		wantError     string
+		wantCreated   []string
+		wantDeleted   []string
+		wantRecorded  bool
+		wantIsHealthy bool
+	}{
+		{
+			"nothing is done if all required subscriptions are healthy",
+			&mockSubscriptionManager{
+				subscriptions: []helix.EventSubSubscription{cheer, update},
+			},
+			[]ReconcileAction{},
+			0,
+			"",
+			nil,
+			nil,
+			false,
+			true,
+		},
+		{
+			"missing subscriptions are created",
+			&mockSubscriptionManager{
+				subscriptions: []helix.EventSubSubscription{cheer},
+			},
+			[]ReconcileAction{
+				{
+					Action:              ReconcileActionCreate,
+					SubscriptionType:    helix.EventSubTypeChannelUpdate,
+					SubscriptionVersion: "2",
+					SubscriptionId:      "new-channel.update",
+					Reason:              "does not exist",
+				},
+			},
+			0,
+			"",
+			[]string{helix.EventSubTypeChannelUpdate},
+			nil,
+			true,
+			true,
+		},
+		{
+			"failed subscriptions are deleted and recreated",
+			&mockSubscriptionManager{
+				subscriptions: []helix.EventSubSubscription{
+					withStatus(cheer, helix.EventSubStatusFailed),
+					update,
+				},
+			},
+			[]ReconcileAction{
+				{
+					Action:              ReconcileActionDelete,
+					SubscriptionType:    helix.EventSubTypeChannelCheer,
+					SubscriptionVersion: "1",
+					SubscriptionId:      "cheer-1",
+					Reason:              "status is 'webhook_callback_verification_failed'",
+				},
+				{
+					Action:              ReconcileActionCreate,
+					SubscriptionType:    helix.EventSubTypeChannelCheer,
+					SubscriptionVersion: "1",
+					SubscriptionId:      "new-channel.cheer",
+					Reason:              "replaces subscription cheer-1",
+				},
+			},
+			0,
+			"",
+			[]string{helix.EventSubTypeChannelCheer},
+			[]string{"cheer-1"},
+			true,
+			true,
+		},
+		{
+			"a failed subscription is not recreated if it can't be deleted",
+			&mockSubscriptionManager{
+				subscriptions: []helix.EventSubSubscription{
+					withStatus(cheer, helix.EventSubStatusFailed),
+					update,
+				},
+				removeStatusCode: http.StatusInternalServerError,
+			},
+			[]ReconcileAction{
+				{
+					Action:              ReconcileActionDelete,
+					SubscriptionType:    helix.EventSubTypeChannelCheer,
+					SubscriptionVersion: "1",
+					SubscriptionId:      "cheer-1",
+					Reason:              "status is 'webhook_callback_verification_failed'",
+					Error:               "got response 500: oops",
+				},
+			},
+			0,
+			"",
+			nil,
+			[]string{"cheer-1"},
+			true,
+			false,
+		},
+		{
+			"failure to create a subscription is reported",
+			&mockSubscriptionManager{
+				subscriptions:    []helix.EventSubSubscription{update},
+				createStatusCode: http.StatusBadRequest,
+			},
+			[]ReconcileAction{
+				{
+					Action:              ReconcileActionCreate,
+					SubscriptionType:    helix.EventSubTypeChannelCheer,
+					SubscriptionVersion: "1",
+					Reason:              "does not exist",
+					Error:               "got response 400: oops",
+				},
+			},
+			0,
+			"",
+			[]string{helix.EventSubTypeChannelCheer},
+			nil,
+			true,
+			false,
+		},
+		{
+			"subscriptions that aren't required are left alone",
+			&mockSubscriptionManager{
+				subscriptions: []helix.EventSubSubscription{
+					cheer,
+					update,
+					{
+						ID:        "ban-1",
+						Type:      helix.EventSubTypeChannelBan,
+						Version:   "1",
+						Status:    helix.EventSubStatusEnabled,
+						Condition: helix.EventSubCondition{BroadcasterUserID: "1234"},
+						Transport: transport,
+					},
+				},
+			},
+			[]ReconcileAction{},
+			1,
+			"",
+			nil,
+			nil,
+			false,
+			true,
+		},
+		{
+			"failure to get subscriptions is reported",
+			&mockSubscriptionManager{
+				err: fmt.Errorf("network error"),
+			},
+			[]ReconcileAction{},
+			0,
+			"failed to get subscriptions: network error",
+			nil,
+			nil,
+			true,
+			false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q := &mockReconcilerQueries{}
+			r := NewReconciler(tt.c, q, required, "1234", transport, time.Minute)
+			r.now = func() time.Time {
+				return time.Date(1997, 9, 1, 12, 0, 0, 0, time.UTC)
+			}
+			assert.Nil(t, r.GetLastReport())
+
+			r.reconcile(context.Background())
+			report := r.GetLastReport()
+			assert.NotNil(t, report)
+			assert.Equal(t, tt.wantActions, report.Actions)
+			assert.Equal(t, tt.wantIgnored, report.NumIgnored)
+			assert.Equal(t, tt.wantError, report.Error)
+			assert.Equal(t, tt.wantIsHealthy, report.IsHealthy())
+			assert.Equal(t, tt.wantCreated, tt.c.created)
+			assert.Equal(t, tt.wantDeleted, tt.c.deleted)
+
+			for _, created := range tt.c.createdPayloads {
+				assert.Equal(t, transport, created.Transport)
+				assert.Equal(t, "1234", created.Condition.BroadcasterUserID)
+			}
+
+			if tt.wantRecorded {
+				assert.Len(t, q.recorded, 1)
+				var actions []ReconcileAction
+				err := json.Unmarshal(q.recorded[0].Actions, &actions)
+				assert.NoError(t, err)
+				assert.Equal(t, tt.wantActions, actions)
+				assert.Equal(t, tt.wantError != "", q.recorded[0].Error.Valid)
+				assert.Equal(t, tt.wantError, q.recorded[0].Error.String)
+			} else {
+				assert.Len(t, q.recorded, 0)
+			}
+		})
+	}
+}
+
+type mockSubscriptionManager struct {
+	subscriptions    []helix.EventSubSubscription
+	err              error
+	createStatusCode int
+	removeStatusCode int
+
+	created         []string
+	createdPayloads []helix.EventSubSubscription
+	deleted         []string
+}
+
+func (m *mockSubscriptionManager) GetEventSubSubscriptions(params *helix.EventSubSubscriptionsParams) (*helix.EventSubSubscriptionsResponse, error) {
+	r := &mockSubscriptionReader{subscriptions: m.subscriptions, err: m.err}
+	return r.GetEventSubSubscriptions(params)
+}
+
+func (m *mockSubscriptionManager) CreateEventSubSubscription(payload *helix.EventSubSubscription) (*helix.EventSubSubscriptionsResponse, error) {
+	m.created = append(m.created, payload.Type)
+	m.createdPayloads = append(m.createdPayloads, *payload)
+	if m.createStatusCode != 0 && m.createStatusCode != http.StatusAccepted {
+		return &helix.EventSubSubscriptionsResponse{
+			ResponseCommon: helix.ResponseCommon{StatusCode: m.createStatusCode, ErrorMessage: "oops"},
+		}, nil
+	}
+	created := *payload
+	created.ID = "new-" + payload.Type
+	created.Status = helix.EventSubStatusPending
+	return &helix.EventSubSubscriptionsResponse{
+		ResponseCommon: helix.ResponseCommon{StatusCode: http.StatusAccepted},
+		Data: helix.ManyEventSubSubscriptions{
+			Total:                 1,
+			EventSubSubscriptions: []helix.EventSubSubscription{created},
+		},
+	}, nil
+}
+
+func (m *mockSubscriptionManager) RemoveEventSubSubscription(id string) (*helix.RemoveEventSubSubscriptionParamsResponse, error) {
+	m.deleted = append(m.deleted, id)
+	if m.removeStatusCode != 0 && m.removeStatusCode != http.StatusNoContent {
+		return &helix.RemoveEventSubSubscriptionParamsResponse{
+			ResponseCommon: helix.ResponseCommon{StatusCode: m.removeStatusCode, ErrorMessage: "oops"},
+		}, nil
+	}
+	return &helix.RemoveEventSubSubscriptionParamsResponse{
+		ResponseCommon: helix.ResponseCommon{StatusCode: http.StatusNoContent},
+	}, nil
+}
+
+type mockReconcilerQueries struct {
+	recorded []queries.RecordEventSubReconciliationParams
+	resolved []queries.ResolveEventSubRevocationsParams
+}
+
+func (m *mockReconcilerQueries) ResolveEventSubRevocations(ctx context.Context, arg queries.ResolveEventSubRevocationsParams) (int64, error) {
+	m.resolved = append(m.resolved, arg)
+	return 0, nil
+}
+
+func (m *mockReconcilerQueries) RecordEventSubReconciliation(ctx context.Context, arg queries.RecordEventSubReconciliationParams) error {
+	m.recorded = append(m.recorded, arg)
+	return nil
+}
@@ -7,24 +7,51 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"time"
 
+	"github.com/golden-vcr/showtime/gen/queries"
 	"github.com/nicklaw5/helix/v2"
 )
 
+const (
+	HeaderMessageId        = "Twitch-Eventsub-Message-Id"
+	HeaderMessageTimestamp = "Twitch-Eventsub-Message-Timestamp"
+	HeaderMessageType      = "Twitch-Eventsub-Message-Type"
+)
+
+const (
+	MessageTypeNotification = "notification"
+	MessageTypeVerification = "webhook_callback_verification"
+	MessageTypeRevocation   = "revocation"
+)
+
 type VerifyNotificationFunc func(header http.Header, message string) bool
+type RecordMessageFunc func(ctx context.Context, messageId string, messageType string, messageTimestamp time.Time, subscription *helix.EventSubSubscription) error
+type RecordRevocationFunc func(ctx context.Context, messageId string, messageTimestamp time.Time, subscription *helix.EventSubSubscription) (bool, error)
+type EnqueueNotificationFunc func(ctx context.Context, messageId string, messageTimestamp time.Time, subscription *helix.EventSubSubscription, data json.RawMessage) (bool, error)
 type HandleEventFunc func(ctx context.Context, subscription *helix.EventSubSubscription, data json.RawMessage) error
 
 type Server struct {
-	verifyNotification VerifyNotificationFunc
-	handleEvent        HandleEventFunc
+	verifyNotification  VerifyNotificationFunc
+	recordMessage       RecordMessageFunc
+	recordRevocation    RecordRevocationFunc
+	enqueueNotification EnqueueNotificationFunc
+	notifyEnqueued      func()
+	maxMessageAge       time.Duration
+	now                 func() time.Time
 }
 
-func NewServer(twitchWebhookSecret string, eventHandler *Handler) *Server {
+func NewServer(twitchWebhookSecret string, maxMessageAge time.Duration, q *queries.Queries, inbox *Inbox) *Server {
 	return &Server{
 		verifyNotification: func(header http.Header, message string) bool {
 			return helix.VerifyEventSubNotification(twitchWebhookSecret, header, message)
 		},
-		handleEvent: eventHandler.HandleEvent,
+		recordMessage:       newRecordMessageFunc(q),
+		recordRevocation:    newRecordRevocationFunc(q),
+		enqueueNotification: newEnqueueNotificationFunc(q),
+		notifyEnqueued:      inbox.Notify,
+		maxMessageAge:       maxMessageAge,
+		now:                 time.Now,
 	}
 }
 
@@ -49,6 +76,27 @@ func (s *Server) handlePostCallback(res http.ResponseWriter, req *http.Request)
 		return
 	}
 
+	// Every message from Twitch carries a unique ID and the time at which it was sent:
+	// reject any message that's older than we're willing to accept, in order to guard
+	// against replay attacks
+	messageId := req.Header.Get(HeaderMessageId)
+	if messageId == "" {
+		fmt.Printf("Callback request has no %s header\n", HeaderMessageId)
+		http.Error(res, fmt.Sprintf("%s header is required", HeaderMessageId), http.StatusBadRequest)
+		return
+	}
+	messageTimestamp, err := time.Parse(time.RFC3339Nano, req.Header.Get(HeaderMessageTimestamp))
+	if err != nil {
+		fmt.Printf("Failed to parse %s header from callback request: %v\n", HeaderMessageTimestamp, err)
+		http.Error(res, fmt.Sprintf("%s header is invalid", HeaderMessageTimestamp), http.StatusBadRequest)
+		return
+	}
+	if s.now().Sub(messageTimestamp) > s.maxMessageAge {
+		fmt.Printf("Rejecting message %s: timestamp %s is too old\n", messageId, messageTimestamp.Format(time.RFC3339Nano))
+		http.Error(res, "Message is too old", http.StatusBadRequest)
+		return
+	}
+
 	// Decode the payload from JSON so we can examine the details of the event
 	var payload struct {
 		Subscription helix.EventSubSubscription `json:"subscription"`
@@ -65,19 +113,56 @@ func (s *Server) handlePostCallback(res http.ResponseWriter, req *http.Request)
 	// confirm registration of this event callback: responding with the same value will
 	// enable the event subscription. This occurs after the parseEvent check so that we
 	// won't allow subscriptions to be created until we fully support the relevant
-	// event type.
+	// event type. We record the fact that we've verified a new subscription so that
+	// we'll know that any prior revocation of the same type has been resolved.
+	messageType := req.Header.Get(HeaderMessageType)
 	if payload.Challenge != "" {
+		if err := s.recordMessage(req.Context(), messageId, MessageTypeVerification, messageTimestamp, &payload.Subscription); err != nil {
+			fmt.Printf("Failed to record verification message %s: %v\n", messageId, err)
+		}
 		fmt.Printf("Responding to challenge with %q\n", payload.Challenge)
 		res.Write([]byte(payload.Challenge))
 		return
 	}
 
-	// We can accept the event, so respond with 200
+	// If Twitch is notifying us that one of our subscriptions has been revoked, we
+	// won't receive any further events for that subscription: record the revocation
+	// so that the problem can be surfaced in our health status until the subscription
+	// is recreated
+	if messageType == MessageTypeRevocation {
+		isNew, err := s.recordRevocation(req.Context(), messageId, messageTimestamp, &payload.Subscription)
+		if err != nil {
+			fmt.Printf("Failed to record revocation message %s: %v\n", messageId, err)
+			http.Error(res, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if isNew {
+			fmt.Printf("WARNING: Twitch revoked subscription %s (%s v%s); reason: %s\n", payload.Subscription.ID, payload.Subscription.Type, payload.Subscription.Version, payload.Subscription.Status)
+		}
+		res.WriteHeader(http.StatusOK)
+		return
+	}
+
+	// Write the notification to our durable inbox before acknowledging it, recording
+	// the message ID so that we'll know if Twitch sends us the same message again: if
+	// we've already seen this message, acknowledge it without enqueueing the event a
+	// second time
+	isNew, err := s.enqueueNotification(req.Context(), messageId, messageTimestamp, &payload.Subscription, payload.Event)
+	if err != nil {
+		fmt.Printf("Failed to enqueue message %s: %v\n", messageId, err)
+		http.Error(res, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if !isNew {
+		fmt.Printf("Ignoring duplicate message %s (event of type %q)\n", messageId, payload.Subscription.Type)
+		res.WriteHeader(http.StatusOK)
+		return
+	}
+
+	// The event is safely stored, so respond with 200: the inbox will take care of
+	// processing it asynchronously, retrying as necessary
 	fmt.Printf("Got event of type %q\n", payload.Subscription.Type)
 	fmt.Printf("- %s\n", string(payload.Event))
 	res.WriteHeader(http.StatusOK)
-
-	if err := s.handleEvent(req.Context(), &payload.Subscription, payload.Event); err != nil {
-		fmt.Printf("Failed to handle event of type '%s': %v\n", payload.Subscription.Type, err)
-	}
+	s.notifyEnqueued()
 }
@@ -3,63 +3,176 @@ package events
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 	"io"
 	"net/http"
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/nicklaw5/helix/v2"
 	"github.com/stretchr/testify/assert"
 )
 
 func Test_Server_handlePostCallback(t *testing.T) {
+	now := time.Date(1997, 9, 1, 12, 0, 0, 0, time.UTC)
 	tests := []struct {
-		name                 string
-		requestBody          string
-		signatureIsOK        bool
-		wantStatus           int
-		wantBody             string
-		wantHandledEventData string
+		name                  string
+		requestBody           string
+		signatureIsOK         bool
+		messageId             string
+		messageTimestamp      string
+		seenMessageIds        []string
+		recordErr             error
+		wantStatus            int
+		wantBody              string
+		wantEnqueuedEventData string
+		wantRecordedIds       []string
 	}{
 		{
 			"if signature verification fails, returns 400",
 			"{}",
 			false,
+			"some-message",
+			"1997-09-01T11:59:58Z",
+			nil,
+			nil,
 			http.StatusBadRequest,
 			"Signature verification failed",
 			"",
+			nil,
 		},
 		{
 			"if challenge is set, echoes challenge with 200",
 			`{"subscription":{"id":"some-subscription"},"challenge":"foobar12345"}`,
 			true,
+			"some-message",
+			"1997-09-01T11:59:58Z",
+			nil,
+			nil,
 			http.StatusOK,
 			"foobar12345",
 			"",
+			[]string{"some-message"},
 		},
 		{
-			"valid event is recorded via handle func",
+			"valid event is written to the inbox",
 			`{"subscription":{"id":"some-subscription","type":"test"},"event":{"value":42}}`,
 			true,
+			"some-message",
+			"1997-09-01T11:59:58Z",
+			nil,
+			nil,
 			http.StatusOK,
 			"",
 			`{"value":42}`,
+			[]string{"some-message"},
+		},
+		{
+			"if message ID is missing, returns 400",
+			`{"subscription":{"id":"some-subscription","type":"test"},"event":{"value":42}}`,
+			true,
+			"",
+			"1997-09-01T11:59:58Z",
+			nil,
+			nil,
+			http.StatusBadRequest,
+			"Twitch-Eventsub-Message-Id header is required",
+			"",
+			nil,
+		},
+		{
+			"if message timestamp is invalid, returns 400",
+			`{"subscription":{"id":"some-subscription","type":"test"},"event":{"value":42}}`,
+			true,
+			"some-message",
+			"yesterday",
+			nil,
+			nil,
+			http.StatusBadRequest,
+			"Twitch-Eventsub-Message-Timestamp header is invalid",
+			"",
+			nil,
+		},
+		{
+			"if message is older than max age, returns 400 without enqueueing",
+			`{"subscription":{"id":"some-subscription","type":"test"},"event":{"value":42}}`,
+			true,
+			"some-message",
+			"1997-09-01T11:49:59Z",
+			nil,
+			nil,
+			http.StatusBadRequest,
+			"Message is too old",
+			"",
+			nil,
+		},
+		{
+			"duplicate message is acknowledged with 200 without enqueueing",
+			`{"subscription":{"id":"some-subscription","type":"test"},"event":{"value":42}}`,
+			true,
+			"some-message",
+			"1997-09-01T11:59:58Z",
+			[]string{"some-message"},
+			nil,
+			http.StatusOK,
+			"",
+			"",
+			[]string{"some-message"},
+		},
+		{
+			"if message can't be enqueued, returns 500",
+			`{"subscription":{"id":"some-subscription","type":"test"},"event":{"value":42}}`,
+			true,
+			"some-message",
+			"1997-09-01T11:59:58Z",
+			nil,
+			fmt.Errorf("mock error"),
+			http.StatusInternalServerError,
+			"mock error",
+			"",
+			nil,
 		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			handledEventData := ""
+			enqueuedEventData := ""
+			numNotifications := 0
+			recordedIds := make(map[string]struct{})
+			for _, messageId := range tt.seenMessageIds {
+				recordedIds[messageId] = struct{}{}
+			}
 			s := &Server{
 				verifyNotification: func(header http.Header, message string) bool {
 					return tt.signatureIsOK
 				},
-				handleEvent: func(ctx context.Context, subscription *helix.EventSubSubscription, data json.RawMessage) error {
-					handledEventData = string(data)
+				recordMessage: func(ctx context.Context, messageId string, messageType string, messageTimestamp time.Time, subscription *helix.EventSubSubscription) error {
+					recordedIds[messageId] = struct{}{}
 					return nil
 				},
+				enqueueNotification: func(ctx context.Context, messageId string, messageTimestamp time.Time, subscription *helix.EventSubSubscription, data json.RawMessage) (bool, error) {
+					if tt.recordErr != nil {
+						return false, tt.recordErr
+					}
+					if _, ok := recordedIds[messageId]; ok {
+						return false, nil
+					}
+					recordedIds[messageId] = struct{}{}
+					enqueuedEventData = string(data)
+					return true, nil
+				},
+				notifyEnqueued: func() {
+					numNotifications++
+				},
+				maxMessageAge: 10 * time.Minute,
+				now: func() time.Time {
+					return now
+				},
 			}
 			req := httptest.NewRequest(http.MethodPost, "/callback", strings.NewReader(tt.requestBody))
+			req.Header.Set(HeaderMessageId, tt.messageId)
+			req.Header.Set(HeaderMessageTimestamp, tt.messageTimestamp)
 			res := httptest.NewRecorder()
 			s.handlePostCallback(res, req)
 
@@ -69,7 +182,94 @@ func Test_Server_handlePostCallback(t *testing.T) {
 			assert.Equal(t, tt.wantStatus, res.Code)
 			assert.Equal(t, tt.wantBody, body)
 
-			assert.Equal(t, tt.wantHandledEventData, handledEventData)
+			assert.Equal(t, tt.wantEnqueuedEventData, enqueuedEventData)
+			if tt.wantEnqueuedEventData != "" {
+				assert.Equal(t, 1, numNotifications)
+			} else {
+				assert.Equal(t, 0, numNotifications)
+			}
+			for _, messageId := range tt.wantRecordedIds {
+				assert.Contains(t, recordedIds, messageId)
+			}
+		})
+	}
+}
+
+func Test_Server_handlePostCallback_revocation(t *testing.T) {
+	now := time.Date(1997, 9, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name              string
+		recordErr         error
+		seenMessage       bool
+		wantStatus        int
+		wantBody          string
+		wantRevokedReason string
+	}{
+		{
+			"revocation is recorded with 200",
+			nil,
+			false,
+			http.StatusOK,
+			"",
+			"authorization_revoked",
+		},
+		{
+			"duplicate revocation is acknowledged with 200",
+			nil,
+			true,
+			http.StatusOK,
+			"",
+			"",
+		},
+		{
+			"if revocation can't be recorded, returns 500",
+			fmt.Errorf("mock error"),
+			false,
+			http.StatusInternalServerError,
+			"mock error",
+			"",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			revokedReason := ""
+			s := &Server{
+				verifyNotification: func(header http.Header, message string) bool {
+					return true
+				},
+				recordRevocation: func(ctx context.Context, messageId string, messageTimestamp time.Time, subscription *helix.EventSubSubscription) (bool, error) {
+					if tt.recordErr != nil {
+						return false, tt.recordErr
+					}
+					if tt.seenMessage {
+						return false, nil
+					}
+					revokedReason = subscription.Status
+					return true, nil
+				},
+				enqueueNotification: func(ctx context.Context, messageId string, messageTimestamp time.Time, subscription *helix.EventSubSubscription, data json.RawMessage) (bool, error) {
+					t.Fatalf("revocation message should not be enqueued")
+					return false, nil
+				},
+				notifyEnqueued: func() {},
+				maxMessageAge:  10 * time.Minute,
+				now: func() time.Time {
+					return now
+				},
+			}
+			body := `{"subscription":{"id":"some-subscription","type":"channel.follow","version":"2","status":"authorization_revoked"}}`
+			req := httptest.NewRequest(http.MethodPost, "/callback", strings.NewReader(body))
+			req.Header.Set(HeaderMessageId, "some-message")
+			req.Header.Set(HeaderMessageTimestamp, "1997-09-01T11:59:58Z")
+			req.Header.Set(HeaderMessageType, MessageTypeRevocation)
+			res := httptest.NewRecorder()
+			s.handlePostCallback(res, req)
+
+			b, err := io.ReadAll(res.Body)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantStatus, res.Code)
+			assert.Equal(t, tt.wantBody, strings.TrimSuffix(string(b), "\n"))
+			assert.Equal(t, tt.wantRevokedReason, revokedReason)
 		})
 	}
 }
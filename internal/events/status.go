@@ -15,9 +15,17 @@ var ErrFailedToReconcileSubscriptions = errors.New("Unable to ascertain status o
 var ErrMissingSubscriptions = errors.New("One or more required Twitch event subscriptions do not yet exist. The Golden VCR server may not be receiving all required data from Twitch.")
 var ErrSubscriptionsDisabled = errors.New("One or more required Twitch event subscriptions are disabled. The Golden VCR server may not be receiving all required data from Twitch.")
 
-func VerifySubscriptionStatus(c twitch.SubscriptionReader, required []RequiredSubscription, channelUserId string, webhookCallbackUrl string) (error, error) {
-	// Get a list of all relevant subscriptions for our channel and callback
-	owned, err := GetOwnedSubscriptions(c, channelUserId, webhookCallbackUrl)
+// TransportMethodWebhook and TransportMethodWebSocket identify the ways in which
+// Twitch can deliver EventSub notifications to us: by calling our webhook, or by
+// sending messages over a WebSocket connection that we've opened
+const (
+	TransportMethodWebhook   = "webhook"
+	TransportMethodWebSocket = "websocket"
+)
+
+func VerifySubscriptionStatus(c twitch.SubscriptionReader, required []RequiredSubscription, channelUserId string, transport helix.EventSubTransport) (error, error) {
+	// Get a list of all relevant subscriptions for our channel and transport
+	owned, err := GetOwnedSubscriptions(c, channelUserId, transport)
 	if err != nil {
 		return ErrFailedToGetSubscriptions, err
 	}
@@ -28,7 +36,7 @@ func VerifySubscriptionStatus(c twitch.SubscriptionReader, required []RequiredSu
 	}
 
 	// We must have an existing subscription for all required subscriptions
-	reconciled, err := ReconcileRequiredSubscriptions(required, owned, channelUserId)
+	reconciled, err := ReconcileRequiredSubscriptions(required, owned, channelUserId, transport)
 	if err != nil {
 		return ErrFailedToReconcileSubscriptions, err
 	}
@@ -48,8 +56,12 @@ func VerifySubscriptionStatus(c twitch.SubscriptionReader, required []RequiredSu
 }
 
 // GetOwnedSubscriptions queries the Twitch API to find all relevant EventSub
-// subscriptions that are registered with the given UserID and webhook callback URL
-func GetOwnedSubscriptions(c twitch.SubscriptionReader, channelUserId string, webhookCallbackUrl string) ([]helix.EventSubSubscription, error) {
+// subscriptions that are registered with the given UserID and transport. For the
+// webhook transport, only subscriptions that hit the same callback URL are relevant.
+// For the WebSocket transport, all WebSocket subscriptions are relevant, including
+// those that belong to sessions other than the current one, so that stale
+// subscriptions from previous sessions can be cleaned up.
+func GetOwnedSubscriptions(c twitch.SubscriptionReader, channelUserId string, transport helix.EventSubTransport) ([]helix.EventSubSubscription, error) {
 	subscriptions := make([]helix.EventSubSubscription, 0)
 	params := &helix.EventSubSubscriptionsParams{
 		UserID: channelUserId,
@@ -65,12 +77,12 @@ func GetOwnedSubscriptions(c twitch.SubscriptionReader, channelUserId string, we
 		}
 
 		for i := range r.Data.EventSubSubscriptions {
-			// Ignore any subscriptions that don't hit our webhook API
+			// Ignore any subscriptions that aren't delivered via our transport
 			subscription := r.Data.EventSubSubscriptions[i]
-			if subscription.Transport.Method != "webhook" {
+			if subscription.Transport.Method != transport.Method {
 				continue
 			}
-			if subscription.Transport.Callback != webhookCallbackUrl {
+			if transport.Method == TransportMethodWebhook && subscription.Transport.Callback != transport.Callback {
 				continue
 			}
 			subscriptions = append(subscriptions, subscription)
@@ -85,11 +97,23 @@ func GetOwnedSubscriptions(c twitch.SubscriptionReader, channelUserId string, we
 	return subscriptions, nil
 }
 
-func ReconcileRequiredSubscriptions(required []RequiredSubscription, owned []helix.EventSubSubscription, channelUserId string) (*ReconcileResult, error) {
+// ReconcileRequiredSubscriptions compares the subscriptions that we require against
+// the subscriptions we own, determining which subscriptions need to be created and
+// which are no longer relevant. An owned subscription only satisfies a requirement if
+// it's delivered via the given transport: in the case of the WebSocket transport, that
+// means it must be bound to the current session.
+func ReconcileRequiredSubscriptions(required []RequiredSubscription, owned []helix.EventSubSubscription, channelUserId string, transport helix.EventSubTransport) (*ReconcileResult, error) {
 	params := RequiredSubscriptionConditionParams{
 		ChannelUserId: channelUserId,
 	}
 
+	candidates := make([]helix.EventSubSubscription, 0, len(owned))
+	for i := range owned {
+		if isDeliveredVia(&owned[i].Transport, &transport) {
+			candidates = append(candidates, owned[i])
+		}
+	}
+
 	requiredSubscriptionsByExistingId := make(map[string]RequiredSubscription)
 	requiredSubscriptionsThatDoNotExist := make([]RequiredSubscription, 0)
 	for i, requiredSubscription := range required {
@@ -97,7 +121,7 @@ func ReconcileRequiredSubscriptions(required []RequiredSubscription, owned []hel
 		if err != nil {
 			return nil, fmt.Errorf("failed to format condition for required subscription at index %d: %w", i, err)
 		}
-		subscription := findMatchingSubscription(owned, requiredSubscription.Type, requiredSubscription.Version, requiredCondition)
+		subscription := findMatchingSubscription(candidates, requiredSubscription.Type, requiredSubscription.Version, requiredCondition)
 		if subscription != nil {
 			requiredSubscriptionsByExistingId[subscription.ID] = requiredSubscription
 		} else {
@@ -154,6 +178,19 @@ func findMatchingSubscription(subscriptions []helix.EventSubSubscription, requir
 	return nil
 }
 
+func isDeliveredVia(subscriptionTransport *helix.EventSubTransport, transport *helix.EventSubTransport) bool {
+	if subscriptionTransport.Method != transport.Method {
+		return false
+	}
+	switch transport.Method {
+	case TransportMethodWebhook:
+		return subscriptionTransport.Callback == transport.Callback
+	case TransportMethodWebSocket:
+		return subscriptionTransport.SessionID == transport.SessionID
+	}
+	return false
+}
+
 func areConditionsEqual(lhs *helix.EventSubCondition, rhs *helix.EventSubCondition) bool {
 	return (lhs.BroadcasterUserID == rhs.BroadcasterUserID &&
 		lhs.FromBroadcasterUserID == rhs.FromBroadcasterUserID &&
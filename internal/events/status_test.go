@@ -12,7 +12,10 @@ import (
 
 func Test_VerifySubscriptionStatus(t *testing.T) {
 	channelUserId := "1337"
-	webhookCallbackUrl := "https://example.com/webhook"
+	transport := helix.EventSubTransport{
+		Method:   TransportMethodWebhook,
+		Callback: "https://example.com/webhook",
+	}
 	tests := []struct {
 		name                   string
 		c                      twitch.SubscriptionReader
@@ -203,7 +206,7 @@ func Test_VerifySubscriptionStatus(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			err, secondaryErr := VerifySubscriptionStatus(tt.c, tt.required, channelUserId, webhookCallbackUrl)
+			err, secondaryErr := VerifySubscriptionStatus(tt.c, tt.required, channelUserId, transport)
 			if tt.wantErr != nil {
 				assert.ErrorIs(t, err, tt.wantErr)
 				if tt.wantSecondaryErrSubstr != "" {
@@ -221,7 +224,10 @@ func Test_VerifySubscriptionStatus(t *testing.T) {
 
 func Test_GetOwnedSubscriptions(t *testing.T) {
 	channelUserId := "1337"
-	webhookCallbackUrl := "https://example.com/webhook"
+	transport := helix.EventSubTransport{
+		Method:   TransportMethodWebhook,
+		Callback: "https://example.com/webhook",
+	}
 	tests := []struct {
 		name         string
 		c            twitch.SubscriptionReader
@@ -290,10 +296,33 @@ func Test_GetOwnedSubscriptions(t *testing.T) {
 			false,
 			[]string{"foo"},
 		},
+		{
+			"ignores subscriptions that aren't delivered via webhook",
+			&mockSubscriptionReader{
+				subscriptions: []helix.EventSubSubscription{
+					{
+						ID: "foo",
+						Transport: helix.EventSubTransport{
+							Method:   "webhook",
+							Callback: "https://example.com/webhook",
+						},
+					},
+					{
+						ID: "bar",
+						Transport: helix.EventSubTransport{
+							Method:    "websocket",
+							SessionID: "some-session",
+						},
+					},
+				},
+			},
+			false,
+			[]string{"foo"},
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			owned, err := GetOwnedSubscriptions(tt.c, channelUserId, webhookCallbackUrl)
+			owned, err := GetOwnedSubscriptions(tt.c, channelUserId, transport)
 			if tt.wantErr {
 				assert.Error(t, err)
 			} else {
@@ -325,6 +354,10 @@ func Test_ReconcileRequiredSubscriptions(t *testing.T) {
 			},
 		},
 	}
+	transport := helix.EventSubTransport{
+		Method:   TransportMethodWebhook,
+		Callback: "https://example.com/webhook",
+	}
 	owned := []helix.EventSubSubscription{
 		{
 			ID:      "foo",
@@ -333,6 +366,7 @@ func Test_ReconcileRequiredSubscriptions(t *testing.T) {
 			Condition: helix.EventSubCondition{
 				BroadcasterUserID: "1337",
 			},
+			Transport: transport,
 		},
 		{
 			ID:      "bar",
@@ -342,9 +376,10 @@ func Test_ReconcileRequiredSubscriptions(t *testing.T) {
 				BroadcasterUserID: "1337",
 				ModeratorUserID:   "1337",
 			},
+			Transport: transport,
 		},
 	}
-	reconciled, err := ReconcileRequiredSubscriptions(required, owned, "1337")
+	reconciled, err := ReconcileRequiredSubscriptions(required, owned, "1337", transport)
 	assert.NoError(t, err)
 	assert.NotNil(t, reconciled)
 	assert.Len(t, reconciled.Existing, 1)
@@ -357,12 +392,50 @@ func Test_ReconcileRequiredSubscriptions(t *testing.T) {
 	assert.Equal(t, "bar", reconciled.ToDelete[0].ID)
 	assert.Equal(t, helix.EventSubTypeChannelFollow, reconciled.ToDelete[0].Type)
 
-	reconciledFromEmpty, err := ReconcileRequiredSubscriptions(required, nil, "1337")
+	reconciledFromEmpty, err := ReconcileRequiredSubscriptions(required, nil, "1337", transport)
 	assert.NoError(t, err)
 	assert.NotNil(t, reconciled)
 	assert.Len(t, reconciledFromEmpty.Existing, 0)
 	assert.Len(t, reconciledFromEmpty.ToCreate, 2)
 	assert.Len(t, reconciledFromEmpty.ToDelete, 0)
+
+	// WebSocket subscriptions are only satisfied by subscriptions bound to the current
+	// session: any subscriptions left over from previous sessions should be deleted
+	webSocketTransport := helix.EventSubTransport{
+		Method:    TransportMethodWebSocket,
+		SessionID: "current-session",
+	}
+	ownedViaWebSocket := []helix.EventSubSubscription{
+		{
+			ID:      "foo",
+			Type:    helix.EventSubTypeChannelUpdate,
+			Version: "2",
+			Condition: helix.EventSubCondition{
+				BroadcasterUserID: "1337",
+			},
+			Transport: helix.EventSubTransport{
+				Method:    TransportMethodWebSocket,
+				SessionID: "old-session",
+			},
+		},
+		{
+			ID:      "bar",
+			Type:    helix.EventSubTypeChannelRaid,
+			Version: "1",
+			Condition: helix.EventSubCondition{
+				ToBroadcasterUserID: "1337",
+			},
+			Transport: webSocketTransport,
+		},
+	}
+	reconciledViaWebSocket, err := ReconcileRequiredSubscriptions(required, ownedViaWebSocket, "1337", webSocketTransport)
+	assert.NoError(t, err)
+	assert.Len(t, reconciledViaWebSocket.Existing, 1)
+	assert.Equal(t, "bar", reconciledViaWebSocket.Existing[0].Value.ID)
+	assert.Len(t, reconciledViaWebSocket.ToCreate, 1)
+	assert.Equal(t, helix.EventSubTypeChannelUpdate, reconciledViaWebSocket.ToCreate[0].Type)
+	assert.Len(t, reconciledViaWebSocket.ToDelete, 1)
+	assert.Equal(t, "foo", reconciledViaWebSocket.ToDelete[0].ID)
 }
 
 func Test_findMatchingSubscription(t *testing.T) {
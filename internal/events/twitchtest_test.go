@@ -0,0 +1,175 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golden-vcr/showtime/internal/twitch"
+	"github.com/golden-vcr/showtime/internal/twitchtest"
+	"github.com/nicklaw5/helix/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+// Test_FakeTwitch exercises the Reconciler, VerifySubscriptionStatus, and the webhook
+// callback Server together, against a fake Twitch API server that delivers real,
+// signed EventSub messages to the callback
+func Test_FakeTwitch(t *testing.T) {
+	const channelUserId = "953753877"
+	const webhookSecret = "my-webhook-secret"
+	required := []RequiredSubscription{
+		{
+			Type:               helix.EventSubTypeStreamOnline,
+			Version:            "1",
+			TemplatedCondition: helix.EventSubCondition{BroadcasterUserID: "{{.ChannelUserId}}"},
+		},
+		{
+			Type:               helix.EventSubTypeChannelRaid,
+			Version:            "1",
+			TemplatedCondition: helix.EventSubCondition{ToBroadcasterUserID: "{{.ChannelUserId}}"},
+		},
+		{
+			Type:               helix.EventSubTypeChannelRaid,
+			Version:            "1",
+			TemplatedCondition: helix.EventSubCondition{FromBroadcasterUserID: "{{.ChannelUserId}}"},
+		},
+	}
+	transport := helix.EventSubTransport{
+		Method:   TransportMethodWebhook,
+		Callback: "https://goldenvcr.com/api/showtime/callback",
+		Secret:   webhookSecret,
+	}
+
+	// Serve our webhook callback, with a Server that records messages in memory
+	inbox := &fakeTwitchInbox{}
+	callback := httptest.NewServer(&Server{
+		verifyNotification: func(header http.Header, message string) bool {
+			return helix.VerifyEventSubNotification(webhookSecret, header, message)
+		},
+		recordMessage:       inbox.recordMessage,
+		recordRevocation:    inbox.recordRevocation,
+		enqueueNotification: inbox.enqueueNotification,
+		notifyEnqueued:      func() {},
+		maxMessageAge:       10 * time.Minute,
+		now:                 time.Now,
+	})
+	defer callback.Close()
+
+	// Run a fake Twitch API that delivers all webhook messages to our callback
+	fake := twitchtest.New(twitchtest.Config{
+		ClientId:     "my-client-id",
+		ClientSecret: "my-client-secret",
+		Users: []helix.User{
+			{ID: channelUserId, Login: "goldenvcr", DisplayName: "GoldenVCR"},
+		},
+		ResolveCallback: func(string) string { return callback.URL },
+	})
+	api := httptest.NewServer(fake)
+	defer api.Close()
+	c, err := twitch.NewClientWithAppToken(fake.Endpoints(api.URL), "my-client-id", "my-client-secret")
+	assert.NoError(t, err)
+
+	// Initially, no subscriptions exist
+	status, err := VerifySubscriptionStatus(c, required, channelUserId, transport)
+	assert.NoError(t, err)
+	assert.Equal(t, ErrNoSubscriptionsExist, status)
+
+	// The reconciler should create all required subscriptions, which our callback
+	// should verify by responding to Twitch's challenges
+	q := &mockReconcilerQueries{}
+	r := NewReconciler(c, q, required, channelUserId, transport, time.Minute)
+	r.reconcile(context.Background())
+	report := r.GetLastReport()
+	assert.True(t, report.IsHealthy())
+	assert.Len(t, report.Actions, 3)
+	assert.Equal(t, 3, inbox.numVerified)
+	status, err = VerifySubscriptionStatus(c, required, channelUserId, transport)
+	assert.NoError(t, err)
+	assert.Nil(t, status)
+
+	// An incoming raid should only be delivered via the matching subscription
+	n, err := fake.Notify(helix.EventSubTypeChannelRaid, helix.EventSubCondition{ToBroadcasterUserID: channelUserId}, map[string]any{
+		"from_broadcaster_user_id":    "1234",
+		"from_broadcaster_user_login": "someoneelse",
+		"to_broadcaster_user_id":      channelUserId,
+		"viewers":                     42,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, n)
+	assert.Len(t, inbox.enqueued, 1)
+	assert.Equal(t, channelUserId, inbox.enqueued[0].subscription.Condition.ToBroadcasterUserID)
+	assert.JSONEq(t, `{"from_broadcaster_user_id":"1234","from_broadcaster_user_login":"someoneelse","to_broadcaster_user_id":"953753877","viewers":42}`, string(inbox.enqueued[0].data))
+
+	// If Twitch revokes a subscription, our callback should record the revocation and
+	// our health status should reflect the problem
+	subscriptions := fake.Subscriptions()
+	err = fake.Revoke(subscriptions[0].ID, helix.EventSubStatusAuthorizationRevoked)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{subscriptions[0].ID}, inbox.revoked)
+	status, err = VerifySubscriptionStatus(c, required, channelUserId, transport)
+	assert.NoError(t, err)
+	assert.Equal(t, ErrSubscriptionsDisabled, status)
+
+	// The next pass of the reconciler should replace the revoked subscription, and
+	// resolve the revocation of that subscription only
+	q.resolved = nil
+	r.reconcile(context.Background())
+	report = r.GetLastReport()
+	assert.True(t, report.IsHealthy())
+	assert.Len(t, report.Actions, 2)
+	assert.Equal(t, ReconcileActionDelete, report.Actions[0].Action)
+	assert.Equal(t, subscriptions[0].ID, report.Actions[0].SubscriptionId)
+	assert.Equal(t, ReconcileActionCreate, report.Actions[1].Action)
+	revokedCondition, err := json.Marshal(subscriptions[0].Condition)
+	assert.NoError(t, err)
+	assert.Len(t, q.resolved, 1)
+	assert.Equal(t, subscriptions[0].Type, q.resolved[0].SubscriptionType)
+	assert.Equal(t, subscriptions[0].Version, q.resolved[0].SubscriptionVersion)
+	assert.JSONEq(t, string(revokedCondition), string(q.resolved[0].Condition))
+	status, err = VerifySubscriptionStatus(c, required, channelUserId, transport)
+	assert.NoError(t, err)
+	assert.Nil(t, status)
+}
+
+type fakeTwitchNotification struct {
+	subscription helix.EventSubSubscription
+	data         json.RawMessage
+}
+
+// fakeTwitchInbox records the messages accepted by a Server in memory
+type fakeTwitchInbox struct {
+	mu          sync.Mutex
+	numVerified int
+	revoked     []string
+	enqueued    []fakeTwitchNotification
+}
+
+func (i *fakeTwitchInbox) recordMessage(ctx context.Context, messageId string, messageType string, messageTimestamp time.Time, subscription *helix.EventSubSubscription) error {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	if messageType == MessageTypeVerification {
+		i.numVerified++
+	}
+	return nil
+}
+
+func (i *fakeTwitchInbox) recordRevocation(ctx context.Context, messageId string, messageTimestamp time.Time, subscription *helix.EventSubSubscription) (bool, error) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.revoked = append(i.revoked, subscription.ID)
+	return true, nil
+}
+
+func (i *fakeTwitchInbox) enqueueNotification(ctx context.Context, messageId string, messageTimestamp time.Time, subscription *helix.EventSubSubscription, data json.RawMessage) (bool, error) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.enqueued = append(i.enqueued, fakeTwitchNotification{
+		subscription: *subscription,
+		data:         data,
+	})
+	return true, nil
+}
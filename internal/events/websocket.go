@@ -0,0 +1,363 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/golden-vcr/showtime/gen/queries"
+	"github.com/golden-vcr/showtime/internal/twitch"
+	"github.com/gorilla/websocket"
+	"github.com/nicklaw5/helix/v2"
+)
+
+// DefaultWebSocketUrl is the URL of Twitch's EventSub WebSocket server
+const DefaultWebSocketUrl = "wss://eventsub.wss.twitch.tv/ws"
+
+const (
+	MessageTypeSessionWelcome   = "session_welcome"
+	MessageTypeSessionKeepalive = "session_keepalive"
+	MessageTypeSessionReconnect = "session_reconnect"
+)
+
+// webSocketKeepaliveGracePeriod is how much longer than the keepalive timeout
+// advertised by the server we'll wait for a message before considering the connection
+// dead
+const webSocketKeepaliveGracePeriod = 5 * time.Second
+
+// webSocketWelcomeTimeout is how long we'll wait for the server to welcome us after
+// connecting
+const webSocketWelcomeTimeout = 10 * time.Second
+
+// SessionWelcomeFunc is called whenever a new EventSub WebSocket session is
+// established: it must ensure that all required subscriptions are bound to the new
+// session within 10 seconds, otherwise Twitch will close the connection
+type SessionWelcomeFunc func(ctx context.Context, sessionId string) error
+
+// WebSocketClient connects to the Twitch EventSub WebSocket server and receives
+// notifications over that connection, as an alternative to receiving notifications
+// via the webhook callback handled by Server. Notifications are written to the same
+// durable inbox, so that they're processed by the same Handler.
+type WebSocketClient struct {
+	url                 string
+	dialer              *websocket.Dialer
+	onWelcome           SessionWelcomeFunc
+	recordRevocation    RecordRevocationFunc
+	enqueueNotification EnqueueNotificationFunc
+	notifyEnqueued      func()
+	maxMessageAge       time.Duration
+	reconnectDelay      time.Duration
+	now                 func() time.Time
+
+	mu        sync.RWMutex
+	sessionId string
+}
+
+func NewWebSocketClient(url string, maxMessageAge time.Duration, q *queries.Queries, inbox *Inbox, onWelcome SessionWelcomeFunc) *WebSocketClient {
+	return &WebSocketClient{
+		url:                 url,
+		dialer:              websocket.DefaultDialer,
+		onWelcome:           onWelcome,
+		recordRevocation:    newRecordRevocationFunc(q),
+		enqueueNotification: newEnqueueNotificationFunc(q),
+		notifyEnqueued:      inbox.Notify,
+		maxMessageAge:       maxMessageAge,
+		reconnectDelay:      5 * time.Second,
+		now:                 time.Now,
+	}
+}
+
+// GetTransport returns the EventSub transport that identifies the current WebSocket
+// session, if any
+func (c *WebSocketClient) GetTransport() helix.EventSubTransport {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return helix.EventSubTransport{
+		Method:    TransportMethodWebSocket,
+		SessionID: c.sessionId,
+	}
+}
+
+// Run maintains a connection to the EventSub WebSocket server until the context is
+// canceled, establishing a new session whenever the connection is lost
+func (c *WebSocketClient) Run(ctx context.Context) error {
+	for {
+		err := c.runSession(ctx)
+		c.setSessionId("")
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		fmt.Printf("EventSub WebSocket session ended; reconnecting in %s: %v\n", c.reconnectDelay, err)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(c.reconnectDelay):
+		}
+	}
+}
+
+// runSession connects to the server, establishes a new session, and then receives
+// messages until the connection is lost. If the server asks us to reconnect, we
+// migrate to a new connection within the same session.
+func (c *WebSocketClient) runSession(ctx context.Context) error {
+	// Connect and wait to be welcomed, then make sure we're subscribed to all the
+	// events we need in this session. If we fail to subscribe to some events, we keep
+	// the session open regardless: the missing subscriptions will be reported by our
+	// health status.
+	conn, session, err := c.connect(ctx, c.url)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("EventSub WebSocket session %s established\n", session.Id)
+	c.setSessionId(session.Id)
+	if err := c.onWelcome(ctx, session.Id); err != nil {
+		fmt.Printf("Failed to subscribe to events in EventSub WebSocket session %s: %v\n", session.Id, err)
+	}
+
+	// Ensure that we close whichever connection is current once we're done, including
+	// when our context is canceled
+	var connMu sync.Mutex
+	closeConn := func() {
+		connMu.Lock()
+		defer connMu.Unlock()
+		conn.Close()
+	}
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			closeConn()
+		case <-done:
+			closeConn()
+		}
+	}()
+
+	keepaliveTimeout := session.getKeepaliveTimeout()
+	for {
+		// If we don't hear from the server before the keepalive timeout elapses, assume
+		// the connection is dead
+		connMu.Lock()
+		current := conn
+		connMu.Unlock()
+		current.SetReadDeadline(c.now().Add(keepaliveTimeout))
+		var message webSocketMessage
+		if err := current.ReadJSON(&message); err != nil {
+			return fmt.Errorf("failed to read message: %w", err)
+		}
+
+		reconnectUrl, err := c.handleMessage(ctx, &message)
+		if err != nil {
+			return err
+		}
+		if reconnectUrl == "" {
+			continue
+		}
+
+		// The server wants us to move to a new connection: connect to the URL it gave
+		// us, and once we're welcomed on the new connection, close the old one. Our
+		// subscriptions carry over to the new connection, so we don't need to recreate
+		// them.
+		fmt.Printf("EventSub WebSocket session %s is reconnecting\n", session.Id)
+		newConn, newSession, err := c.connect(ctx, reconnectUrl)
+		if err != nil {
+			return fmt.Errorf("failed to reconnect: %w", err)
+		}
+		connMu.Lock()
+		conn.Close()
+		conn = newConn
+		connMu.Unlock()
+		session = newSession
+		keepaliveTimeout = session.getKeepaliveTimeout()
+		c.setSessionId(session.Id)
+		fmt.Printf("EventSub WebSocket session %s reconnected\n", session.Id)
+	}
+}
+
+// connect opens a new connection to the given URL and waits for the server to send a
+// welcome message, returning the details of the session
+func (c *WebSocketClient) connect(ctx context.Context, url string) (*websocket.Conn, *webSocketSession, error) {
+	conn, res, err := c.dialer.DialContext(ctx, url, nil)
+	if err != nil {
+		if res != nil {
+			return nil, nil, fmt.Errorf("failed to connect to %s: got status %d: %w", url, res.StatusCode, err)
+		}
+		return nil, nil, fmt.Errorf("failed to connect to %s: %w", url, err)
+	}
+
+	conn.SetReadDeadline(c.now().Add(webSocketWelcomeTimeout))
+	var message webSocketMessage
+	if err := conn.ReadJSON(&message); err != nil {
+		conn.Close()
+		return nil, nil, fmt.Errorf("failed to read welcome message: %w", err)
+	}
+	if message.Metadata.MessageType != MessageTypeSessionWelcome || message.Payload.Session == nil {
+		conn.Close()
+		return nil, nil, fmt.Errorf("expected %s message; got %q", MessageTypeSessionWelcome, message.Metadata.MessageType)
+	}
+	return conn, message.Payload.Session, nil
+}
+
+// handleMessage handles a single message received during a session. If the server
+// has asked us to reconnect, returns the URL to reconnect to. An error is only
+// returned if the session can't continue.
+func (c *WebSocketClient) handleMessage(ctx context.Context, message *webSocketMessage) (string, error) {
+	switch message.Metadata.MessageType {
+	case MessageTypeSessionKeepalive:
+		return "", nil
+	case MessageTypeSessionReconnect:
+		if message.Payload.Session == nil || message.Payload.Session.ReconnectUrl == "" {
+			return "", fmt.Errorf("got %s message with no reconnect URL", MessageTypeSessionReconnect)
+		}
+		return message.Payload.Session.ReconnectUrl, nil
+	case MessageTypeNotification:
+		return "", c.handleNotification(ctx, message)
+	case MessageTypeRevocation:
+		return "", c.handleRevocation(ctx, message)
+	default:
+		fmt.Printf("Ignoring unexpected EventSub WebSocket message of type %q\n", message.Metadata.MessageType)
+		return "", nil
+	}
+}
+
+func (c *WebSocketClient) handleNotification(ctx context.Context, message *webSocketMessage) error {
+	messageId := message.Metadata.MessageId
+	if message.Payload.Subscription == nil {
+		fmt.Printf("Ignoring notification %s with no subscription\n", messageId)
+		return nil
+	}
+	if c.now().Sub(message.Metadata.MessageTimestamp) > c.maxMessageAge {
+		fmt.Printf("Rejecting message %s: timestamp %s is too old\n", messageId, message.Metadata.MessageTimestamp.Format(time.RFC3339Nano))
+		return nil
+	}
+
+	// Unlike with webhooks, Twitch will not redeliver a notification if we fail to
+	// handle it, so failing to record it in the inbox is fatal to the session
+	isNew, err := c.enqueueNotification(ctx, messageId, message.Metadata.MessageTimestamp, message.Payload.Subscription, message.Payload.Event)
+	if err != nil {
+		return fmt.Errorf("failed to enqueue message %s: %w", messageId, err)
+	}
+	if !isNew {
+		fmt.Printf("Ignoring duplicate message %s (event of type %q)\n", messageId, message.Payload.Subscription.Type)
+		return nil
+	}
+	c.notifyEnqueued()
+	return nil
+}
+
+func (c *WebSocketClient) handleRevocation(ctx context.Context, message *webSocketMessage) error {
+	messageId := message.Metadata.MessageId
+	subscription := message.Payload.Subscription
+	if subscription == nil {
+		fmt.Printf("Ignoring revocation %s with no subscription\n", messageId)
+		return nil
+	}
+	isNew, err := c.recordRevocation(ctx, messageId, message.Metadata.MessageTimestamp, subscription)
+	if err != nil {
+		return fmt.Errorf("failed to record revocation message %s: %w", messageId, err)
+	}
+	if isNew {
+		fmt.Printf("WARNING: Twitch revoked subscription %s (%s v%s); reason: %s\n", subscription.ID, subscription.Type, subscription.Version, subscription.Status)
+	}
+	return nil
+}
+
+func (c *WebSocketClient) setSessionId(sessionId string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.sessionId = sessionId
+}
+
+// webSocketMessage is the format of every message sent to us by the EventSub
+// WebSocket server
+type webSocketMessage struct {
+	Metadata struct {
+		MessageId           string    `json:"message_id"`
+		MessageType         string    `json:"message_type"`
+		MessageTimestamp    time.Time `json:"message_timestamp"`
+		SubscriptionType    string    `json:"subscription_type,omitempty"`
+		SubscriptionVersion string    `json:"subscription_version,omitempty"`
+	} `json:"metadata"`
+	Payload struct {
+		Session      *webSocketSession           `json:"session,omitempty"`
+		Subscription *helix.EventSubSubscription `json:"subscription,omitempty"`
+		Event        json.RawMessage             `json:"event,omitempty"`
+	} `json:"payload"`
+}
+
+type webSocketSession struct {
+	Id                      string `json:"id"`
+	Status                  string `json:"status"`
+	KeepaliveTimeoutSeconds int    `json:"keepalive_timeout_seconds"`
+	ReconnectUrl            string `json:"reconnect_url"`
+}
+
+func (s *webSocketSession) getKeepaliveTimeout() time.Duration {
+	return time.Duration(s.KeepaliveTimeoutSeconds)*time.Second + webSocketKeepaliveGracePeriod
+}
+
+// EnsureWebSocketSubscriptions returns a SessionWelcomeFunc that binds all required
+// subscriptions to each new session, deleting any subscriptions that were bound to
+// previous sessions. The client must be authenticated with a user access token. Any
+// prior revocations of the subscriptions that are created are marked as resolved.
+func EnsureWebSocketSubscriptions(c twitch.SubscriptionManager, q RevocationResolver, required []RequiredSubscription, channelUserId string) SessionWelcomeFunc {
+	return func(ctx context.Context, sessionId string) error {
+		transport := helix.EventSubTransport{
+			Method:    TransportMethodWebSocket,
+			SessionID: sessionId,
+		}
+		owned, err := GetOwnedSubscriptions(c, channelUserId, transport)
+		if err != nil {
+			return fmt.Errorf("failed to get subscriptions: %w", err)
+		}
+		reconciled, err := ReconcileRequiredSubscriptions(required, owned, channelUserId, transport)
+		if err != nil {
+			return fmt.Errorf("failed to reconcile subscriptions: %w", err)
+		}
+
+		// Subscriptions from disconnected sessions will never deliver any more events,
+		// and they count against our subscription limits
+		for _, subscription := range reconciled.ToDelete {
+			r, err := c.RemoveEventSubSubscription(subscription.ID)
+			if err == nil && r.StatusCode != http.StatusNoContent {
+				err = fmt.Errorf("got response %d: %s", r.StatusCode, r.ErrorMessage)
+			}
+			if err != nil {
+				fmt.Printf("Failed to delete stale subscription %s: %v\n", subscription.ID, err)
+			}
+		}
+
+		// Bind every required subscription to the new session
+		params := RequiredSubscriptionConditionParams{
+			ChannelUserId: channelUserId,
+		}
+		var errs []error
+		for _, requiredSubscription := range reconciled.ToCreate {
+			condition, err := params.Format(&requiredSubscription.TemplatedCondition)
+			if err != nil {
+				return fmt.Errorf("failed to format condition for required '%s' subscription: %w", requiredSubscription.Type, err)
+			}
+			r, err := c.CreateEventSubSubscription(&helix.EventSubSubscription{
+				Type:      requiredSubscription.Type,
+				Version:   requiredSubscription.Version,
+				Condition: *condition,
+				Transport: transport,
+			})
+			if err == nil && r.StatusCode != http.StatusAccepted {
+				err = fmt.Errorf("got response %d: %s", r.StatusCode, r.ErrorMessage)
+			}
+			if err != nil {
+				errs = append(errs, fmt.Errorf("failed to create '%s' subscription: %w", requiredSubscription.Type, err))
+				continue
+			}
+			if err := ResolveRevocations(ctx, q, requiredSubscription.Type, requiredSubscription.Version, condition); err != nil {
+				fmt.Printf("Failed to resolve revocations of '%s' subscription: %v\n", requiredSubscription.Type, err)
+			}
+		}
+		return errors.Join(errs...)
+	}
+}
@@ -0,0 +1,267 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golden-vcr/showtime/internal/twitch"
+	"github.com/golden-vcr/showtime/internal/twitchtest"
+	"github.com/gorilla/websocket"
+	"github.com/nicklaw5/helix/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_WebSocketClient_Run(t *testing.T) {
+	// Our fake EventSub server will welcome us, send us some messages, and then ask us
+	// to reconnect to a second server, which will continue the same session
+	upgrader := websocket.Upgrader{}
+	oldConnClosed := make(chan struct{})
+	reconnectServer := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		conn, err := upgrader.Upgrade(res, req, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		writeMessage(t, conn, "welcome-b", MessageTypeSessionWelcome, `{"session":{"id":"session-a","status":"connected","keepalive_timeout_seconds":10}}`)
+		<-oldConnClosed
+		writeMessage(t, conn, "message-c", MessageTypeNotification, `{"subscription":{"id":"sub-1","type":"channel.follow","version":"2"},"event":{"value":3}}`)
+		conn.ReadMessage()
+	}))
+	defer reconnectServer.Close()
+	server := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		conn, err := upgrader.Upgrade(res, req, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		writeMessage(t, conn, "welcome-a", MessageTypeSessionWelcome, `{"session":{"id":"session-a","status":"connected","keepalive_timeout_seconds":10}}`)
+		writeMessage(t, conn, "message-a", MessageTypeNotification, `{"subscription":{"id":"sub-1","type":"channel.follow","version":"2"},"event":{"value":1}}`)
+		writeMessage(t, conn, "keepalive-a", MessageTypeSessionKeepalive, `{}`)
+		writeMessage(t, conn, "message-b", MessageTypeRevocation, `{"subscription":{"id":"sub-2","type":"channel.raid","version":"1","status":"authorization_revoked"}}`)
+		reconnectUrl := "ws" + strings.TrimPrefix(reconnectServer.URL, "http")
+		writeMessage(t, conn, "reconnect-a", MessageTypeSessionReconnect, fmt.Sprintf(`{"session":{"id":"session-a","status":"reconnecting","reconnect_url":%q}}`, reconnectUrl))
+
+		// Block until the client closes this connection
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				close(oldConnClosed)
+				return
+			}
+		}
+	}))
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var mu sync.Mutex
+	welcomedSessionIds := make([]string, 0)
+	enqueuedEventData := make([]string, 0)
+	revokedSubscriptionIds := make([]string, 0)
+	c := &WebSocketClient{
+		url:    "ws" + strings.TrimPrefix(server.URL, "http"),
+		dialer: websocket.DefaultDialer,
+		onWelcome: func(ctx context.Context, sessionId string) error {
+			mu.Lock()
+			defer mu.Unlock()
+			welcomedSessionIds = append(welcomedSessionIds, sessionId)
+			return nil
+		},
+		recordRevocation: func(ctx context.Context, messageId string, messageTimestamp time.Time, subscription *helix.EventSubSubscription) (bool, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			revokedSubscriptionIds = append(revokedSubscriptionIds, subscription.ID)
+			return true, nil
+		},
+		enqueueNotification: func(ctx context.Context, messageId string, messageTimestamp time.Time, subscription *helix.EventSubSubscription, data json.RawMessage) (bool, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			enqueuedEventData = append(enqueuedEventData, string(data))
+			if len(enqueuedEventData) == 2 {
+				cancel()
+			}
+			return true, nil
+		},
+		notifyEnqueued: func() {},
+		maxMessageAge:  10 * time.Minute,
+		reconnectDelay: time.Second,
+		now:            time.Now,
+	}
+
+	done := make(chan error)
+	go func() {
+		done <- c.Run(ctx)
+	}()
+	select {
+	case err := <-done:
+		assert.ErrorIs(t, err, context.Canceled)
+	case <-time.After(5 * time.Second):
+		t.Fatalf("timed out waiting for client to finish")
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, []string{"session-a"}, welcomedSessionIds)
+	assert.Equal(t, []string{`{"value":1}`, `{"value":3}`}, enqueuedEventData)
+	assert.Equal(t, []string{"sub-2"}, revokedSubscriptionIds)
+}
+
+func Test_WebSocketClient_handleMessage(t *testing.T) {
+	now := time.Date(1997, 9, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name             string
+		message          string
+		enqueueErr       error
+		wantReconnectUrl string
+		wantErr          string
+		wantEnqueued     bool
+	}{
+		{
+			"keepalive is a no-op",
+			`{"metadata":{"message_id":"a","message_type":"session_keepalive","message_timestamp":"1997-09-01T11:59:58Z"},"payload":{}}`,
+			nil,
+			"",
+			"",
+			false,
+		},
+		{
+			"notification is enqueued",
+			`{"metadata":{"message_id":"a","message_type":"notification","message_timestamp":"1997-09-01T11:59:58Z"},"payload":{"subscription":{"type":"channel.follow"},"event":{}}}`,
+			nil,
+			"",
+			"",
+			true,
+		},
+		{
+			"stale notification is ignored",
+			`{"metadata":{"message_id":"a","message_type":"notification","message_timestamp":"1997-09-01T11:49:59Z"},"payload":{"subscription":{"type":"channel.follow"},"event":{}}}`,
+			nil,
+			"",
+			"",
+			false,
+		},
+		{
+			"failure to enqueue notification is an error",
+			`{"metadata":{"message_id":"a","message_type":"notification","message_timestamp":"1997-09-01T11:59:58Z"},"payload":{"subscription":{"type":"channel.follow"},"event":{}}}`,
+			errors.New("mock error"),
+			"",
+			"failed to enqueue message a: mock error",
+			false,
+		},
+		{
+			"reconnect returns reconnect URL",
+			`{"metadata":{"message_id":"a","message_type":"session_reconnect","message_timestamp":"1997-09-01T11:59:58Z"},"payload":{"session":{"id":"foo","reconnect_url":"wss://example.com/reconnect"}}}`,
+			nil,
+			"wss://example.com/reconnect",
+			"",
+			false,
+		},
+		{
+			"reconnect with no URL is an error",
+			`{"metadata":{"message_id":"a","message_type":"session_reconnect","message_timestamp":"1997-09-01T11:59:58Z"},"payload":{"session":{"id":"foo"}}}`,
+			nil,
+			"",
+			"got session_reconnect message with no reconnect URL",
+			false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			enqueued := false
+			c := &WebSocketClient{
+				enqueueNotification: func(ctx context.Context, messageId string, messageTimestamp time.Time, subscription *helix.EventSubSubscription, data json.RawMessage) (bool, error) {
+					if tt.enqueueErr != nil {
+						return false, tt.enqueueErr
+					}
+					enqueued = true
+					return true, nil
+				},
+				notifyEnqueued: func() {},
+				maxMessageAge:  10 * time.Minute,
+				now: func() time.Time {
+					return now
+				},
+			}
+			var message webSocketMessage
+			err := json.Unmarshal([]byte(tt.message), &message)
+			assert.NoError(t, err)
+
+			reconnectUrl, err := c.handleMessage(context.Background(), &message)
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tt.wantReconnectUrl, reconnectUrl)
+			assert.Equal(t, tt.wantEnqueued, enqueued)
+		})
+	}
+}
+
+func writeMessage(t *testing.T, conn *websocket.Conn, messageId string, messageType string, payload string) {
+	data := fmt.Sprintf(
+		`{"metadata":{"message_id":%q,"message_type":%q,"message_timestamp":%q},"payload":%s}`,
+		messageId,
+		messageType,
+		time.Now().Format(time.RFC3339Nano),
+		payload,
+	)
+	if err := conn.WriteMessage(websocket.TextMessage, []byte(data)); err != nil {
+		t.Errorf("failed to write message: %v", err)
+	}
+}
+
+func Test_EnsureWebSocketSubscriptions(t *testing.T) {
+	const channelUserId = "953753877"
+	required := []RequiredSubscription{
+		{
+			Type:               helix.EventSubTypeChannelRaid,
+			Version:            "1",
+			TemplatedCondition: helix.EventSubCondition{ToBroadcasterUserID: "{{.ChannelUserId}}"},
+		},
+		{
+			Type:               helix.EventSubTypeChannelRaid,
+			Version:            "1",
+			TemplatedCondition: helix.EventSubCondition{FromBroadcasterUserID: "{{.ChannelUserId}}"},
+		},
+	}
+	fake := twitchtest.New(twitchtest.Config{
+		ClientId:     "my-client-id",
+		ClientSecret: "my-client-secret",
+		Users: []helix.User{
+			{ID: channelUserId, Login: "goldenvcr", DisplayName: "GoldenVCR"},
+		},
+	})
+	api := httptest.NewServer(fake)
+	defer api.Close()
+	c, err := twitch.NewClientWithUserToken(fake.Endpoints(api.URL), "my-client-id", fake.IssueUserToken(channelUserId, nil).AccessToken)
+	assert.NoError(t, err)
+
+	// WebSocket subscriptions are never verified via a callback: creating them should
+	// resolve any prior revocations of each subscription, keeping the raids to and
+	// from our channel distinct
+	q := &mockReconcilerQueries{}
+	onWelcome := EnsureWebSocketSubscriptions(c, q, required, channelUserId)
+	err = onWelcome(context.Background(), "session-a")
+	assert.NoError(t, err)
+	assert.Len(t, fake.Subscriptions(), 2)
+	assert.Len(t, q.resolved, 2)
+	for i, want := range []helix.EventSubCondition{
+		{ToBroadcasterUserID: channelUserId},
+		{FromBroadcasterUserID: channelUserId},
+	} {
+		wantCondition, err := json.Marshal(want)
+		assert.NoError(t, err)
+		assert.Equal(t, helix.EventSubTypeChannelRaid, q.resolved[i].SubscriptionType)
+		assert.Equal(t, "1", q.resolved[i].SubscriptionVersion)
+		assert.JSONEq(t, string(wantCondition), string(q.resolved[i].Condition))
+	}
+}
@@ -1,54 +1,122 @@
 package health
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"strings"
+	"time"
 
 	"github.com/golden-vcr/showtime"
+	"github.com/golden-vcr/showtime/gen/queries"
 	"github.com/golden-vcr/showtime/internal/events"
+	"github.com/golden-vcr/showtime/internal/twitch"
 	"github.com/nicklaw5/helix/v2"
 )
 
 type GetEventsStatusFunc func() (error, error)
 type GetChatStatusFunc func() error
+type GetRevocationsFunc func(ctx context.Context) ([]Revocation, error)
+type GetTransportFunc func() helix.EventSubTransport
+type GetReconcileReportFunc func() *events.ReconcileReport
 
 type Server struct {
-	getEventsStatus GetEventsStatusFunc
-	getChatStatus   GetChatStatusFunc
+	getEventsStatus    GetEventsStatusFunc
+	getChatStatus      GetChatStatusFunc
+	getRevocations     GetRevocationsFunc
+	getReconcileReport GetReconcileReportFunc
 }
 
-func NewServer(client *helix.Client, channelUserId string, twitchWebhookCallbackUrl string, getChatStatus GetChatStatusFunc) *Server {
+// NewServer initializes a health server that verifies the subscriptions required by
+// each of the given channels: getReconcileReport may be nil if the background
+// subscription reconciler is not enabled
+func NewServer(client twitch.SubscriptionReader, q *queries.Queries, channels []showtime.Channel, getTransport GetTransportFunc, getChatStatus GetChatStatusFunc, getReconcileReport GetReconcileReportFunc) *Server {
 	return &Server{
 		getEventsStatus: func() (error, error) {
-			return events.VerifySubscriptionStatus(
-				client,
-				showtime.RequiredSubscriptions,
-				channelUserId,
-				twitchWebhookCallbackUrl,
-			)
+			for _, channel := range channels {
+				err, secondaryErr := events.VerifySubscriptionStatus(
+					client,
+					channel.RequiredSubscriptions(),
+					channel.UserId,
+					getTransport(),
+				)
+				if err == nil {
+					continue
+				}
+
+				// Let the user know if the problem only affects a partner channel
+				if !channel.IsPrimary {
+					if secondaryErr != nil {
+						secondaryErr = fmt.Errorf("partner channel %s: %w", channel.Name, secondaryErr)
+					} else {
+						secondaryErr = fmt.Errorf("affects partner channel %s", channel.Name)
+					}
+				}
+				return err, secondaryErr
+			}
+			return nil, nil
 		},
 		getChatStatus: getChatStatus,
+		getRevocations: func(ctx context.Context) ([]Revocation, error) {
+			rows, err := q.GetUnresolvedEventSubRevocations(ctx)
+			if err != nil {
+				return nil, err
+			}
+			revocations := make([]Revocation, 0, len(rows))
+			for _, row := range rows {
+				if !isRequiredSubscription(row.SubscriptionType, row.SubscriptionVersion) {
+					continue
+				}
+				revocations = append(revocations, Revocation{
+					SubscriptionType:    row.SubscriptionType,
+					SubscriptionVersion: row.SubscriptionVersion,
+					Reason:              row.Reason,
+					RevokedAt:           row.RevokedAt,
+				})
+			}
+			return revocations, nil
+		},
+		getReconcileReport: getReconcileReport,
 	}
 }
 
 func (s *Server) ServeHTTP(res http.ResponseWriter, req *http.Request) {
-	status := s.resolveStatus()
+	status := s.resolveStatus(req.Context())
 	if err := json.NewEncoder(res).Encode(status); err != nil {
 		http.Error(res, err.Error(), http.StatusInternalServerError)
 	}
 }
 
-func (s *Server) resolveStatus() Status {
+func (s *Server) resolveStatus(ctx context.Context) Status {
+	status := s.resolveReadiness(ctx)
+	if s.getReconcileReport != nil {
+		status.LastReconcile = s.getReconcileReport()
+		if !status.IsReady {
+			status.Message += describeReconcileReport(status.LastReconcile)
+		}
+	}
+	return status
+}
+
+func (s *Server) resolveReadiness(ctx context.Context) Status {
 	err, secondaryErr := s.getEventsStatus()
 	if err != nil {
 		suffix := ""
 		if secondaryErr != nil {
 			suffix = fmt.Sprintf(" (Error: %s)", secondaryErr.Error())
 		}
+
+		// If Twitch has told us that it revoked any of our required subscriptions, let
+		// the user know why those subscriptions are missing
+		revocations, revocationsErr := s.getRevocations(ctx)
+		if revocationsErr != nil {
+			fmt.Printf("Failed to get EventSub revocations: %v\n", revocationsErr)
+		}
 		return Status{
-			IsReady: false,
-			Message: err.Error() + suffix,
+			IsReady:     false,
+			Message:     err.Error() + suffix + describeRevocations(revocations),
+			Revocations: revocations,
 		}
 	}
 
@@ -67,3 +135,50 @@ func (s *Server) resolveStatus() Status {
 		Message: "All required Twitch Event subscriptions are enabled, and chat features are working. The Golden VCR server is fully operational!",
 	}
 }
+
+// describeRevocations returns a user-facing explanation of which required
+// subscriptions have been revoked by Twitch, or an empty string if none have
+func describeRevocations(revocations []Revocation) string {
+	if len(revocations) == 0 {
+		return ""
+	}
+	descriptions := make([]string, 0, len(revocations))
+	for _, r := range revocations {
+		descriptions = append(descriptions, fmt.Sprintf(
+			"%s (v%s) was revoked at %s; reason: %s",
+			r.SubscriptionType,
+			r.SubscriptionVersion,
+			r.RevokedAt.Format(time.RFC3339),
+			r.Reason,
+		))
+	}
+	return fmt.Sprintf(" Twitch has revoked required subscriptions: %s. Run cmd/init to recreate them.", strings.Join(descriptions, "; "))
+}
+
+// describeReconcileReport returns a user-facing explanation of why the subscription
+// reconciler was unable to repair our subscriptions, or an empty string if it last ran
+// successfully
+func describeReconcileReport(report *events.ReconcileReport) string {
+	if report == nil || report.IsHealthy() {
+		return ""
+	}
+	errs := make([]string, 0, len(report.Actions)+1)
+	if report.Error != "" {
+		errs = append(errs, report.Error)
+	}
+	for _, action := range report.Actions {
+		if action.Error != "" {
+			errs = append(errs, fmt.Sprintf("failed to %s %s subscription: %s", action.Action, action.SubscriptionType, action.Error))
+		}
+	}
+	return fmt.Sprintf(" The subscription reconciler last ran at %s and was unable to repair subscriptions: %s.", report.FinishedAt.Format(time.RFC3339), strings.Join(errs, "; "))
+}
+
+func isRequiredSubscription(subscriptionType string, subscriptionVersion string) bool {
+	for _, required := range showtime.RequiredSubscriptions {
+		if required.Type == subscriptionType && required.Version == subscriptionVersion {
+			return true
+		}
+	}
+	return false
+}
@@ -1,12 +1,15 @@
 package health
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
+	"github.com/golden-vcr/showtime/internal/events"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -16,6 +19,7 @@ func Test_Server(t *testing.T) {
 		eventsErr          error
 		eventsSecondaryErr error
 		chatErr            error
+		revocations        []Revocation
 		wantStatus         int
 		wantIsReady        bool
 		wantMessageSubstr  string
@@ -25,6 +29,7 @@ func Test_Server(t *testing.T) {
 			nil,
 			nil,
 			nil,
+			nil,
 			http.StatusOK,
 			true,
 			"fully operational",
@@ -34,6 +39,7 @@ func Test_Server(t *testing.T) {
 			fmt.Errorf("This error is presented directly to the user"),
 			nil,
 			nil,
+			nil,
 			http.StatusOK,
 			false,
 			"This error is presented directly to the user",
@@ -43,6 +49,7 @@ func Test_Server(t *testing.T) {
 			fmt.Errorf("This error is presented directly to the user"),
 			fmt.Errorf("and so is this one"),
 			nil,
+			nil,
 			http.StatusOK,
 			false,
 			"This error is presented directly to the user (Error: and so is this one)",
@@ -52,10 +59,28 @@ func Test_Server(t *testing.T) {
 			nil,
 			nil,
 			fmt.Errorf("mock chat error"),
+			nil,
 			http.StatusOK,
 			false,
 			"chat functionality is degraded. (Error: mock chat error)",
 		},
+		{
+			"revoked subscriptions are explained if events are not healthy",
+			fmt.Errorf("Subscriptions are missing."),
+			nil,
+			nil,
+			[]Revocation{
+				{
+					SubscriptionType:    "channel.follow",
+					SubscriptionVersion: "2",
+					Reason:              "authorization_revoked",
+					RevokedAt:           time.Date(1997, 9, 1, 12, 0, 0, 0, time.UTC),
+				},
+			},
+			http.StatusOK,
+			false,
+			"Subscriptions are missing. Twitch has revoked required subscriptions: channel.follow (v2) was revoked at 1997-09-01T12:00:00Z; reason: authorization_revoked. Run cmd/init to recreate them.",
+		},
 	}
 	for _, tt := range tests {
 		s := &Server{
@@ -65,6 +90,9 @@ func Test_Server(t *testing.T) {
 			getChatStatus: func() error {
 				return tt.chatErr
 			},
+			getRevocations: func(ctx context.Context) ([]Revocation, error) {
+				return tt.revocations, nil
+			},
 		}
 		req := httptest.NewRequest(http.MethodGet, "/", nil)
 		res := httptest.NewRecorder()
@@ -78,5 +106,71 @@ func Test_Server(t *testing.T) {
 		assert.NoError(t, err)
 		assert.Equal(t, tt.wantIsReady, status.IsReady)
 		assert.Contains(t, status.Message, tt.wantMessageSubstr)
+		assert.Equal(t, tt.revocations, status.Revocations)
+	}
+}
+
+func Test_Server_LastReconcile(t *testing.T) {
+	tests := []struct {
+		name              string
+		eventsErr         error
+		report            *events.ReconcileReport
+		wantMessageSubstr string
+	}{
+		{
+			"last reconcile result is reported even if events are healthy",
+			nil,
+			&events.ReconcileReport{
+				StartedAt:  time.Date(1997, 9, 1, 12, 0, 0, 0, time.UTC),
+				FinishedAt: time.Date(1997, 9, 1, 12, 0, 1, 0, time.UTC),
+				Actions:    []events.ReconcileAction{},
+			},
+			"fully operational!",
+		},
+		{
+			"reconciler failures are explained if events are not healthy",
+			fmt.Errorf("Subscriptions are missing."),
+			&events.ReconcileReport{
+				StartedAt:  time.Date(1997, 9, 1, 12, 0, 0, 0, time.UTC),
+				FinishedAt: time.Date(1997, 9, 1, 12, 0, 1, 0, time.UTC),
+				Actions: []events.ReconcileAction{
+					{
+						Action:              events.ReconcileActionCreate,
+						SubscriptionType:    "channel.follow",
+						SubscriptionVersion: "2",
+						Reason:              "does not exist",
+						Error:               "got response 403: subscription missing proper authorization",
+					},
+				},
+			},
+			"Subscriptions are missing. The subscription reconciler last ran at 1997-09-01T12:00:01Z and was unable to repair subscriptions: failed to create channel.follow subscription: got response 403: subscription missing proper authorization.",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &Server{
+				getEventsStatus: func() (error, error) {
+					return tt.eventsErr, nil
+				},
+				getChatStatus: func() error {
+					return nil
+				},
+				getRevocations: func(ctx context.Context) ([]Revocation, error) {
+					return nil, nil
+				},
+				getReconcileReport: func() *events.ReconcileReport {
+					return tt.report
+				},
+			}
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			res := httptest.NewRecorder()
+			s.ServeHTTP(res, req)
+
+			var status Status
+			err := json.NewDecoder(res.Result().Body).Decode(&status)
+			assert.NoError(t, err)
+			assert.Contains(t, status.Message, tt.wantMessageSubstr)
+			assert.Equal(t, tt.report, status.LastReconcile)
+		})
 	}
 }
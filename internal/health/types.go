@@ -1,6 +1,25 @@
 package health
 
+import (
+	"time"
+
+	"github.com/golden-vcr/showtime/internal/events"
+)
+
 type Status struct {
-	IsReady bool   `json:"isReady"`
-	Message string `json:"message"`
+	IsReady     bool         `json:"isReady"`
+	Message     string       `json:"message"`
+	Revocations []Revocation `json:"revocations,omitempty"`
+	// LastReconcile is the outcome of the most recent pass of the background
+	// subscription reconciler, if it's enabled and has run
+	LastReconcile *events.ReconcileReport `json:"lastReconcile,omitempty"`
+}
+
+// Revocation describes a required EventSub subscription that Twitch has revoked, and
+// which has not since been recreated
+type Revocation struct {
+	SubscriptionType    string    `json:"subscriptionType"`
+	SubscriptionVersion string    `json:"subscriptionVersion"`
+	Reason              string    `json:"reason"`
+	RevokedAt           time.Time `json:"revokedAt"`
 }
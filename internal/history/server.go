@@ -10,18 +10,23 @@ import (
 	"time"
 
 	"github.com/golden-vcr/showtime/gen/queries"
+	"github.com/golden-vcr/showtime/internal/progress"
 	"github.com/google/uuid"
 	"github.com/gorilla/mux"
 	"golang.org/x/sync/errgroup"
 )
 
 type Server struct {
-	q Queries
+	q         Queries
+	channelId string
 }
 
-func NewServer(q *queries.Queries) *Server {
+// NewServer initializes a history server that reports on past broadcasts in the
+// channel owned by the Twitch user with the given ID
+func NewServer(q *queries.Queries, channelId string) *Server {
 	return &Server{
-		q: q,
+		q:         q,
+		channelId: channelId,
 	}
 }
 
@@ -29,6 +34,7 @@ func (s *Server) RegisterRoutes(r *mux.Router) {
 	for _, root := range []string{"", "/"} {
 		r.Path(root).Methods("GET").HandlerFunc(s.handleGetSummary)
 	}
+	r.Path("/raids").Methods("GET").HandlerFunc(s.handleGetRaids)
 	r.Path("/{id}").Methods("GET").HandlerFunc(s.handleGetBroadcast)
 	r.Path("/images/{id}").Methods("GET").HandlerFunc(s.handleGetImages)
 }
@@ -36,7 +42,7 @@ func (s *Server) RegisterRoutes(r *mux.Router) {
 func (s *Server) handleGetSummary(res http.ResponseWriter, req *http.Request) {
 	// Get a row for each broadcast in which we've screened any tapes, including which
 	// tape IDs were screened in which broadcasts
-	rows, err := s.q.GetBroadcastHistory(req.Context())
+	rows, err := s.q.GetBroadcastHistory(req.Context(), s.channelId)
 	if err != nil {
 		http.Error(res, err.Error(), http.StatusInternalServerError)
 		return
@@ -54,10 +60,11 @@ func (s *Server) handleGetSummary(res http.ResponseWriter, req *http.Request) {
 			tapeIds = append(tapeIds, int(tapeId))
 		}
 		broadcasts = append(broadcasts, SummarizedBroadcast{
-			Id:        int(row.ID),
-			StartedAt: row.StartedAt,
-			VodUrl:    vodUrl,
-			TapeIds:   tapeIds,
+			Id:             int(row.ID),
+			StartedAt:      row.StartedAt,
+			VodUrl:         vodUrl,
+			TwitchStreamId: row.TwitchStreamID.String,
+			TapeIds:        tapeIds,
 		})
 	}
 
@@ -101,7 +108,7 @@ func (s *Server) handleGetBroadcast(res http.ResponseWriter, req *http.Request)
 
 	// Ensure that a broadcastRow exists with that ID
 	broadcastRow, err := s.q.GetBroadcastById(req.Context(), int32(broadcastId))
-	if errors.Is(err, sql.ErrNoRows) {
+	if errors.Is(err, sql.ErrNoRows) || (err == nil && broadcastRow.ChannelID != s.channelId) {
 		http.Error(res, "no such broadcast", http.StatusNotFound)
 		return
 	}
@@ -110,11 +117,17 @@ func (s *Server) handleGetBroadcast(res http.ResponseWriter, req *http.Request)
 		return
 	}
 
-	// Run two queries concurrently to get the data we need for this request: all the
-	// screenings recorded within that broadcast (including image request summaries
-	// etc.), along with a lookup that maps Twitch User IDs to display names
+	// Run several queries concurrently to get the data we need for this request: all
+	// the screenings recorded within that broadcast (including image request summaries
+	// etc.), a lookup that maps Twitch User IDs to display names, the timeline of
+	// changes to the stream title and category, and the final results of any hype
+	// trains, polls, and predictions
 	screeningsChan := make(chan []queries.GetScreeningsByBroadcastIdRow, 1)
 	viewerLookupChan := make(chan []queries.GetViewerLookupForBroadcastRow, 1)
+	channelUpdatesChan := make(chan []queries.GetChannelUpdatesByBroadcastIdRow, 1)
+	hypeTrainsChan := make(chan []queries.GetHypeTrainsByBroadcastIdRow, 1)
+	pollsChan := make(chan []queries.GetPollsByBroadcastIdRow, 1)
+	predictionsChan := make(chan []queries.GetPredictionsByBroadcastIdRow, 1)
 	wg, queryCtx := errgroup.WithContext(req.Context())
 	wg.Go(func() error {
 		// Find all screening rows recorded within the broadcast
@@ -134,12 +147,52 @@ func (s *Server) handleGetBroadcast(res http.ResponseWriter, req *http.Request)
 		viewerLookupChan <- viewerLookupRows
 		return nil
 	})
+	wg.Go(func() error {
+		// Get all title and category changes that applied to the broadcast
+		channelUpdateRows, err := s.q.GetChannelUpdatesByBroadcastId(queryCtx, broadcastRow.ID)
+		if err != nil {
+			return err
+		}
+		channelUpdatesChan <- channelUpdateRows
+		return nil
+	})
+	wg.Go(func() error {
+		// Get the results of all hype trains that occurred during the broadcast
+		hypeTrainRows, err := s.q.GetHypeTrainsByBroadcastId(queryCtx, broadcastRow.ID)
+		if err != nil {
+			return err
+		}
+		hypeTrainsChan <- hypeTrainRows
+		return nil
+	})
+	wg.Go(func() error {
+		// Get the results of all polls that were run during the broadcast
+		pollRows, err := s.q.GetPollsByBroadcastId(queryCtx, broadcastRow.ID)
+		if err != nil {
+			return err
+		}
+		pollsChan <- pollRows
+		return nil
+	})
+	wg.Go(func() error {
+		// Get the results of all predictions that were run during the broadcast
+		predictionRows, err := s.q.GetPredictionsByBroadcastId(queryCtx, broadcastRow.ID)
+		if err != nil {
+			return err
+		}
+		predictionsChan <- predictionRows
+		return nil
+	})
 	if err := wg.Wait(); err != nil {
 		http.Error(res, err.Error(), http.StatusInternalServerError)
 		return
 	}
 	screeningRows := <-screeningsChan
 	viewerLookupRows := <-viewerLookupChan
+	channelUpdateRows := <-channelUpdatesChan
+	hypeTrainRows := <-hypeTrainsChan
+	pollRows := <-pollsChan
+	predictionRows := <-predictionsChan
 
 	// Build a result struct and return it as JSON
 	screenings := make([]Screening, 0, len(screeningRows))
@@ -177,6 +230,61 @@ func (s *Server) handleGetBroadcast(res http.ResponseWriter, req *http.Request)
 			ImageRequests: imageRequests,
 		})
 	}
+	channelUpdates := make([]ChannelUpdate, 0, len(channelUpdateRows))
+	for _, row := range channelUpdateRows {
+		channelUpdates = append(channelUpdates, ChannelUpdate{
+			Title:        row.Title,
+			CategoryId:   row.CategoryID,
+			CategoryName: row.CategoryName,
+			ChangedAt:    row.ChangedAt,
+		})
+	}
+	hypeTrains := make([]HypeTrain, 0, len(hypeTrainRows))
+	for _, row := range hypeTrainRows {
+		var topContributions []progress.HypeTrainContribution
+		if err := json.Unmarshal(row.TopContributions, &topContributions); err != nil {
+			http.Error(res, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		hypeTrains = append(hypeTrains, HypeTrain{
+			Level:            int(row.Level),
+			Total:            int(row.Total),
+			TopContributions: topContributions,
+			StartedAt:        row.StartedAt,
+			EndedAt:          row.EndedAt,
+		})
+	}
+	polls := make([]Poll, 0, len(pollRows))
+	for _, row := range pollRows {
+		var choices []progress.PollChoice
+		if err := json.Unmarshal(row.Choices, &choices); err != nil {
+			http.Error(res, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		polls = append(polls, Poll{
+			Title:     row.Title,
+			Status:    row.Status,
+			Choices:   choices,
+			StartedAt: row.StartedAt,
+			EndedAt:   row.EndedAt,
+		})
+	}
+	predictions := make([]Prediction, 0, len(predictionRows))
+	for _, row := range predictionRows {
+		var outcomes []progress.PredictionOutcome
+		if err := json.Unmarshal(row.Outcomes, &outcomes); err != nil {
+			http.Error(res, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		predictions = append(predictions, Prediction{
+			Title:            row.Title,
+			Status:           row.Status,
+			WinningOutcomeId: row.WinningOutcomeID.String,
+			Outcomes:         outcomes,
+			StartedAt:        row.StartedAt,
+			EndedAt:          row.EndedAt,
+		})
+	}
 	var broadcastEndedAt *time.Time
 	if broadcastRow.EndedAt.Valid {
 		broadcastEndedAt = &broadcastRow.EndedAt.Time
@@ -186,11 +294,16 @@ func (s *Server) handleGetBroadcast(res http.ResponseWriter, req *http.Request)
 		vodUrl = broadcastRow.VodUrl.String
 	}
 	broadcast := Broadcast{
-		Id:         int(broadcastRow.ID),
-		StartedAt:  broadcastRow.StartedAt,
-		EndedAt:    broadcastEndedAt,
-		Screenings: screenings,
-		VodUrl:     vodUrl,
+		Id:             int(broadcastRow.ID),
+		StartedAt:      broadcastRow.StartedAt,
+		EndedAt:        broadcastEndedAt,
+		Screenings:     screenings,
+		ChannelUpdates: channelUpdates,
+		HypeTrains:     hypeTrains,
+		Polls:          polls,
+		Predictions:    predictions,
+		VodUrl:         vodUrl,
+		TwitchStreamId: broadcastRow.TwitchStreamID.String,
 	}
 	if err := json.NewEncoder(res).Encode(broadcast); err != nil {
 		http.Error(res, err.Error(), http.StatusInternalServerError)
@@ -220,6 +333,51 @@ func (s *Server) handleGetImages(res http.ResponseWriter, req *http.Request) {
 	}
 }
 
+func (s *Server) handleGetRaids(res http.ResponseWriter, req *http.Request) {
+	raidRows, err := s.q.GetRaidHistory(req.Context(), s.channelId)
+	if err != nil {
+		http.Error(res, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	summaryRows, err := s.q.GetRaidSummaryByBroadcaster(req.Context(), s.channelId)
+	if err != nil {
+		http.Error(res, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	raids := make([]Raid, 0, len(raidRows))
+	for _, row := range raidRows {
+		raids = append(raids, Raid{
+			Direction:   row.Direction,
+			UserId:      row.TwitchUserID,
+			UserLogin:   row.TwitchLogin,
+			Username:    row.TwitchDisplayName,
+			NumViewers:  int(row.NumViewers),
+			BroadcastId: int(row.BroadcastID.Int32),
+			TapeId:      int(row.TapeID.Int32),
+			RaidedAt:    row.RaidedAt,
+		})
+	}
+	raiders := make([]RaiderSummary, 0, len(summaryRows))
+	for _, row := range summaryRows {
+		raiders = append(raiders, RaiderSummary{
+			UserId:             row.TwitchUserID,
+			Username:           row.TwitchDisplayName,
+			NumIncomingRaids:   int(row.NumIncomingRaids),
+			NumIncomingViewers: int(row.NumIncomingViewers),
+			NumOutgoingRaids:   int(row.NumOutgoingRaids),
+			NumOutgoingViewers: int(row.NumOutgoingViewers),
+			LastRaidedAt:       row.LastRaidedAt,
+		})
+	}
+	if err := json.NewEncoder(res).Encode(RaidHistory{
+		Raids:   raids,
+		Raiders: raiders,
+	}); err != nil {
+		http.Error(res, err.Error(), http.StatusInternalServerError)
+	}
+}
+
 func formatUsername(viewerLookupRows []queries.GetViewerLookupForBroadcastRow, twitchUserId string) string {
 	for _, row := range viewerLookupRows {
 		if row.TwitchUserID == twitchUserId {
@@ -36,10 +36,11 @@ func Test_Server_handleGetSummary(t *testing.T) {
 			&mockQueries{
 				broadcasts: []mockBroadcast{
 					{
-						id:        1,
-						startedAt: time.Date(1997, 9, 1, 12, 0, 0, 0, time.UTC),
-						endedAt:   sql.NullTime{Valid: true, Time: time.Date(1997, 9, 1, 14, 0, 0, 0, time.UTC)},
-						vodUrl:    "https://vods.com/1",
+						id:             1,
+						startedAt:      time.Date(1997, 9, 1, 12, 0, 0, 0, time.UTC),
+						endedAt:        sql.NullTime{Valid: true, Time: time.Date(1997, 9, 1, 14, 0, 0, 0, time.UTC)},
+						vodUrl:         "https://vods.com/1",
+						twitchStreamId: "40078987165",
 					},
 					{
 						id:        2,
@@ -75,7 +76,7 @@ func Test_Server_handleGetSummary(t *testing.T) {
 				},
 			},
 			http.StatusOK,
-			`{"broadcasts":[{"id":1,"startedAt":"1997-09-01T12:00:00Z","vodUrl":"https://vods.com/1","tapeIds":[44,22]},{"id":2,"startedAt":"1997-09-01T18:00:00Z","vodUrl":"","tapeIds":[66]},{"id":3,"startedAt":"1997-09-01T23:00:00Z","vodUrl":"","tapeIds":[44,11]}],"broadcastIdsByTapeId":{"11":[3],"22":[1],"44":[1,3],"66":[2]}}`,
+			`{"broadcasts":[{"id":1,"startedAt":"1997-09-01T12:00:00Z","vodUrl":"https://vods.com/1","twitchStreamId":"40078987165","tapeIds":[44,22]},{"id":2,"startedAt":"1997-09-01T18:00:00Z","vodUrl":"","tapeIds":[66]},{"id":3,"startedAt":"1997-09-01T23:00:00Z","vodUrl":"","tapeIds":[44,11]}],"broadcastIdsByTapeId":{"11":[3],"22":[1],"44":[1,3],"66":[2]}}`,
 		},
 		{
 			"database error is a 500",
@@ -137,7 +138,7 @@ func Test_Server_handleGetBroadcast(t *testing.T) {
 			},
 			1,
 			http.StatusOK,
-			`{"id":1,"startedAt":"1997-09-01T12:00:00Z","endedAt":"1997-09-01T14:00:00Z","screenings":[{"tapeId":44,"startedAt":"1997-09-01T12:15:00Z","endedAt":"1997-09-01T12:45:00Z","imageRequests":[]},{"tapeId":22,"startedAt":"1997-09-01T12:55:00Z","endedAt":"1997-09-01T13:30:00Z","imageRequests":[]}]}`,
+			`{"id":1,"startedAt":"1997-09-01T12:00:00Z","endedAt":"1997-09-01T14:00:00Z","screenings":[{"tapeId":44,"startedAt":"1997-09-01T12:15:00Z","endedAt":"1997-09-01T12:45:00Z","imageRequests":[]},{"tapeId":22,"startedAt":"1997-09-01T12:55:00Z","endedAt":"1997-09-01T13:30:00Z","imageRequests":[]}],"channelUpdates":[],"hypeTrains":[],"polls":[],"predictions":[]}`,
 		},
 		{
 			"image requests made during each screening are reported",
@@ -167,7 +168,7 @@ func Test_Server_handleGetBroadcast(t *testing.T) {
 			},
 			1,
 			http.StatusOK,
-			`{"id":1,"startedAt":"1997-09-01T12:00:00Z","endedAt":"1997-09-01T14:00:00Z","screenings":[{"tapeId":44,"startedAt":"1997-09-01T12:15:00Z","endedAt":"1997-09-01T12:45:00Z","imageRequests":[{"id":"4c511c13-e4e6-48eb-94e2-45beed2fd11c","username":"User 1234","subject":"a big rock"}]}]}`,
+			`{"id":1,"startedAt":"1997-09-01T12:00:00Z","endedAt":"1997-09-01T14:00:00Z","screenings":[{"tapeId":44,"startedAt":"1997-09-01T12:15:00Z","endedAt":"1997-09-01T12:45:00Z","imageRequests":[{"id":"4c511c13-e4e6-48eb-94e2-45beed2fd11c","username":"User 1234","subject":"a big rock"}]}],"channelUpdates":[],"hypeTrains":[],"polls":[],"predictions":[]}`,
 		},
 		{
 			"twitch display names are resolved from  user ids for image requests",
@@ -203,7 +204,7 @@ func Test_Server_handleGetBroadcast(t *testing.T) {
 			},
 			1,
 			http.StatusOK,
-			`{"id":1,"startedAt":"1997-09-01T12:00:00Z","endedAt":"1997-09-01T14:00:00Z","screenings":[{"tapeId":44,"startedAt":"1997-09-01T12:15:00Z","endedAt":"1997-09-01T12:45:00Z","imageRequests":[{"id":"4c511c13-e4e6-48eb-94e2-45beed2fd11c","username":"PersonMan","subject":"a big rock"}]}]}`,
+			`{"id":1,"startedAt":"1997-09-01T12:00:00Z","endedAt":"1997-09-01T14:00:00Z","screenings":[{"tapeId":44,"startedAt":"1997-09-01T12:15:00Z","endedAt":"1997-09-01T12:45:00Z","imageRequests":[{"id":"4c511c13-e4e6-48eb-94e2-45beed2fd11c","username":"PersonMan","subject":"a big rock"}]}],"channelUpdates":[],"hypeTrains":[],"polls":[],"predictions":[]}`,
 		},
 		{
 			"if screening end time is invalid, broadcast end time is substituted",
@@ -225,7 +226,7 @@ func Test_Server_handleGetBroadcast(t *testing.T) {
 			},
 			1,
 			http.StatusOK,
-			`{"id":1,"startedAt":"1997-09-01T12:00:00Z","endedAt":"1997-09-01T14:00:00Z","screenings":[{"tapeId":44,"startedAt":"1997-09-01T12:15:00Z","endedAt":"1997-09-01T14:00:00Z","imageRequests":[]}]}`,
+			`{"id":1,"startedAt":"1997-09-01T12:00:00Z","endedAt":"1997-09-01T14:00:00Z","screenings":[{"tapeId":44,"startedAt":"1997-09-01T12:15:00Z","endedAt":"1997-09-01T14:00:00Z","imageRequests":[]}],"channelUpdates":[],"hypeTrains":[],"polls":[],"predictions":[]}`,
 		},
 		{
 			"if broadcast is in progress, Broadcast.endedAt is null and Screening.endedAt may be null as well",
@@ -246,7 +247,71 @@ func Test_Server_handleGetBroadcast(t *testing.T) {
 			},
 			1,
 			http.StatusOK,
-			`{"id":1,"startedAt":"1997-09-01T12:00:00Z","endedAt":null,"screenings":[{"tapeId":44,"startedAt":"1997-09-01T12:15:00Z","endedAt":null,"imageRequests":[]}]}`,
+			`{"id":1,"startedAt":"1997-09-01T12:00:00Z","endedAt":null,"screenings":[{"tapeId":44,"startedAt":"1997-09-01T12:15:00Z","endedAt":null,"imageRequests":[]}],"channelUpdates":[],"hypeTrains":[],"polls":[],"predictions":[]}`,
+		},
+		{
+			"title and category changes during the broadcast are reported",
+			&mockQueries{
+				broadcasts: []mockBroadcast{
+					{
+						id:        1,
+						startedAt: time.Date(1997, 9, 1, 12, 0, 0, 0, time.UTC),
+						endedAt:   sql.NullTime{Valid: true, Time: time.Date(1997, 9, 1, 14, 0, 0, 0, time.UTC)},
+					},
+				},
+				channelUpdates: []mockChannelUpdate{
+					{
+						broadcastId:  1,
+						title:        "Watching tapes",
+						categoryName: "Retro",
+						changedAt:    time.Date(1997, 9, 1, 11, 55, 0, 0, time.UTC),
+					},
+					{
+						broadcastId:  1,
+						title:        "Still watching tapes",
+						categoryName: "Retro",
+						changedAt:    time.Date(1997, 9, 1, 13, 0, 0, 0, time.UTC),
+					},
+				},
+			},
+			1,
+			http.StatusOK,
+			`{"id":1,"startedAt":"1997-09-01T12:00:00Z","endedAt":"1997-09-01T14:00:00Z","screenings":[],"channelUpdates":[{"title":"Watching tapes","categoryId":"category-Retro","categoryName":"Retro","changedAt":"1997-09-01T11:55:00Z"},{"title":"Still watching tapes","categoryId":"category-Retro","categoryName":"Retro","changedAt":"1997-09-01T13:00:00Z"}],"hypeTrains":[],"polls":[],"predictions":[]}`,
+		},
+		{
+			"results of polls and predictions during the broadcast are reported",
+			&mockQueries{
+				broadcasts: []mockBroadcast{
+					{
+						id:        1,
+						startedAt: time.Date(1997, 9, 1, 12, 0, 0, 0, time.UTC),
+						endedAt:   sql.NullTime{Valid: true, Time: time.Date(1997, 9, 1, 14, 0, 0, 0, time.UTC)},
+					},
+				},
+				polls: []mockPoll{
+					{
+						broadcastId: 1,
+						title:       "Which tape next?",
+						status:      "completed",
+						choices:     `[{"id":"a","title":"Tape 44","numVotes":12},{"id":"b","title":"Tape 22","numVotes":7}]`,
+						startedAt:   time.Date(1997, 9, 1, 12, 30, 0, 0, time.UTC),
+						endedAt:     time.Date(1997, 9, 1, 12, 32, 0, 0, time.UTC),
+					},
+				},
+				predictionRows: []queries.GetPredictionsByBroadcastIdRow{
+					{
+						Title:            "Will the tape be watchable?",
+						Status:           "resolved",
+						WinningOutcomeID: sql.NullString{Valid: true, String: "y"},
+						Outcomes:         []byte(`[{"id":"y","title":"Yes","color":"blue","numUsers":3,"numChannelPoints":500},{"id":"n","title":"No","color":"pink","numUsers":1,"numChannelPoints":100}]`),
+						StartedAt:        time.Date(1997, 9, 1, 13, 0, 0, 0, time.UTC),
+						EndedAt:          time.Date(1997, 9, 1, 13, 10, 0, 0, time.UTC),
+					},
+				},
+			},
+			1,
+			http.StatusOK,
+			`{"id":1,"startedAt":"1997-09-01T12:00:00Z","endedAt":"1997-09-01T14:00:00Z","screenings":[],"channelUpdates":[],"hypeTrains":[],"polls":[{"title":"Which tape next?","status":"completed","choices":[{"id":"a","title":"Tape 44","numVotes":12},{"id":"b","title":"Tape 22","numVotes":7}],"startedAt":"1997-09-01T12:30:00Z","endedAt":"1997-09-01T12:32:00Z"}],"predictions":[{"title":"Will the tape be watchable?","status":"resolved","winningOutcomeId":"y","outcomes":[{"id":"y","title":"Yes","color":"blue","numUsers":3,"numChannelPoints":500},{"id":"n","title":"No","color":"pink","numUsers":1,"numChannelPoints":100}],"startedAt":"1997-09-01T13:00:00Z","endedAt":"1997-09-01T13:10:00Z"}]}`,
 		},
 		{
 			"invalid broadcast ID is a 404",
@@ -255,6 +320,21 @@ func Test_Server_handleGetBroadcast(t *testing.T) {
 			http.StatusNotFound,
 			"no such broadcast",
 		},
+		{
+			"broadcast from another channel is a 404",
+			&mockQueries{
+				broadcasts: []mockBroadcast{
+					{
+						id:        1,
+						channelId: "1234",
+						startedAt: time.Date(1997, 9, 1, 12, 0, 0, 0, time.UTC),
+					},
+				},
+			},
+			1,
+			http.StatusNotFound,
+			"no such broadcast",
+		},
 		{
 			"database error is a 500",
 			&mockQueries{
@@ -282,18 +362,103 @@ func Test_Server_handleGetBroadcast(t *testing.T) {
 	}
 }
 
+func Test_Server_handleGetRaids(t *testing.T) {
+	tests := []struct {
+		name       string
+		q          *mockQueries
+		wantStatus int
+		wantBody   string
+	}{
+		{
+			"result is empty when no raids exist",
+			&mockQueries{},
+			http.StatusOK,
+			`{"raids":[],"raiders":[]}`,
+		},
+		{
+			"raids are listed along with per-broadcaster aggregates",
+			&mockQueries{
+				raidRows: []queries.GetRaidHistoryRow{
+					{
+						Direction:         "outgoing",
+						TwitchUserID:      "1234",
+						TwitchLogin:       "friend",
+						TwitchDisplayName: "Friend",
+						NumViewers:        12,
+						BroadcastID:       sql.NullInt32{Int32: 2, Valid: true},
+						RaidedAt:          time.Date(1997, 9, 2, 14, 0, 0, 0, time.UTC),
+					},
+					{
+						Direction:         "incoming",
+						TwitchUserID:      "1234",
+						TwitchLogin:       "friend",
+						TwitchDisplayName: "Friend",
+						NumViewers:        30,
+						BroadcastID:       sql.NullInt32{Int32: 1, Valid: true},
+						TapeID:            sql.NullInt32{Int32: 44, Valid: true},
+						RaidedAt:          time.Date(1997, 9, 1, 12, 30, 0, 0, time.UTC),
+					},
+				},
+				raiderRows: []queries.GetRaidSummaryByBroadcasterRow{
+					{
+						TwitchUserID:       "1234",
+						TwitchDisplayName:  "Friend",
+						NumIncomingRaids:   1,
+						NumIncomingViewers: 30,
+						NumOutgoingRaids:   1,
+						NumOutgoingViewers: 12,
+						LastRaidedAt:       time.Date(1997, 9, 2, 14, 0, 0, 0, time.UTC),
+					},
+				},
+			},
+			http.StatusOK,
+			`{"raids":[{"direction":"outgoing","userId":"1234","userLogin":"friend","username":"Friend","numViewers":12,"broadcastId":2,"raidedAt":"1997-09-02T14:00:00Z"},{"direction":"incoming","userId":"1234","userLogin":"friend","username":"Friend","numViewers":30,"broadcastId":1,"tapeId":44,"raidedAt":"1997-09-01T12:30:00Z"}],"raiders":[{"userId":"1234","username":"Friend","numIncomingRaids":1,"numIncomingViewers":30,"numOutgoingRaids":1,"numOutgoingViewers":12,"lastRaidedAt":"1997-09-02T14:00:00Z"}]}`,
+		},
+		{
+			"database error is a 500",
+			&mockQueries{
+				err: fmt.Errorf("mock error"),
+			},
+			http.StatusInternalServerError,
+			"mock error",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := Server{q: tt.q}
+			req := httptest.NewRequest(http.MethodGet, "/raids", nil)
+			res := httptest.NewRecorder()
+			s.handleGetRaids(res, req)
+
+			b, err := io.ReadAll(res.Body)
+			assert.NoError(t, err)
+			body := strings.TrimSuffix(string(b), "\n")
+			assert.Equal(t, tt.wantStatus, res.Code)
+			assert.Equal(t, tt.wantBody, body)
+		})
+	}
+}
+
 type mockQueries struct {
 	err              error
 	broadcasts       []mockBroadcast
 	screenings       []mockScreening
 	viewerLookupRows []queries.GetViewerLookupForBroadcastRow
+	channelUpdates   []mockChannelUpdate
+	hypeTrainRows    []queries.GetHypeTrainsByBroadcastIdRow
+	polls            []mockPoll
+	predictionRows   []queries.GetPredictionsByBroadcastIdRow
+	raidRows         []queries.GetRaidHistoryRow
+	raiderRows       []queries.GetRaidSummaryByBroadcasterRow
 }
 
 type mockBroadcast struct {
-	id        int32
-	startedAt time.Time
-	endedAt   sql.NullTime
-	vodUrl    string
+	id             int32
+	channelId      string
+	startedAt      time.Time
+	endedAt        sql.NullTime
+	vodUrl         string
+	twitchStreamId string
 }
 
 type mockScreening struct {
@@ -304,7 +469,23 @@ type mockScreening struct {
 	imageRequests []imageRequestSummary
 }
 
-func (m *mockQueries) GetBroadcastHistory(ctx context.Context) ([]queries.GetBroadcastHistoryRow, error) {
+type mockChannelUpdate struct {
+	broadcastId  int32
+	title        string
+	categoryName string
+	changedAt    time.Time
+}
+
+type mockPoll struct {
+	broadcastId int32
+	title       string
+	status      string
+	choices     string
+	startedAt   time.Time
+	endedAt     time.Time
+}
+
+func (m *mockQueries) GetBroadcastHistory(ctx context.Context, channelID string) ([]queries.GetBroadcastHistoryRow, error) {
 	if m.err != nil {
 		return nil, m.err
 	}
@@ -329,6 +510,10 @@ func (m *mockQueries) GetBroadcastHistory(ctx context.Context) ([]queries.GetBro
 				Valid:  broadcast.vodUrl != "",
 				String: broadcast.vodUrl,
 			},
+			TwitchStreamID: sql.NullString{
+				Valid:  broadcast.twitchStreamId != "",
+				String: broadcast.twitchStreamId,
+			},
 			TapeIds: tapeIds,
 		})
 	}
@@ -343,8 +528,13 @@ func (m *mockQueries) GetBroadcastById(ctx context.Context, broadcastID int32) (
 		if b.id == broadcastID {
 			return queries.ShowtimeBroadcast{
 				ID:        b.id,
+				ChannelID: b.channelId,
 				StartedAt: b.startedAt,
 				EndedAt:   b.endedAt,
+				TwitchStreamID: sql.NullString{
+					Valid:  b.twitchStreamId != "",
+					String: b.twitchStreamId,
+				},
 			}, nil
 		}
 	}
@@ -380,8 +570,73 @@ func (m *mockQueries) GetViewerLookupForBroadcast(ctx context.Context, broadcast
 	return m.viewerLookupRows, nil
 }
 
+func (m *mockQueries) GetChannelUpdatesByBroadcastId(ctx context.Context, broadcastID int32) ([]queries.GetChannelUpdatesByBroadcastIdRow, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	rows := make([]queries.GetChannelUpdatesByBroadcastIdRow, 0)
+	for _, u := range m.channelUpdates {
+		if u.broadcastId == broadcastID {
+			rows = append(rows, queries.GetChannelUpdatesByBroadcastIdRow{
+				Title:        u.title,
+				CategoryID:   fmt.Sprintf("category-%s", u.categoryName),
+				CategoryName: u.categoryName,
+				ChangedAt:    u.changedAt,
+			})
+		}
+	}
+	return rows, nil
+}
+
+func (m *mockQueries) GetHypeTrainsByBroadcastId(ctx context.Context, broadcastID int32) ([]queries.GetHypeTrainsByBroadcastIdRow, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	return m.hypeTrainRows, nil
+}
+
+func (m *mockQueries) GetPollsByBroadcastId(ctx context.Context, broadcastID int32) ([]queries.GetPollsByBroadcastIdRow, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	rows := make([]queries.GetPollsByBroadcastIdRow, 0)
+	for _, p := range m.polls {
+		if p.broadcastId == broadcastID {
+			rows = append(rows, queries.GetPollsByBroadcastIdRow{
+				Title:     p.title,
+				Status:    p.status,
+				Choices:   []byte(p.choices),
+				StartedAt: p.startedAt,
+				EndedAt:   p.endedAt,
+			})
+		}
+	}
+	return rows, nil
+}
+
+func (m *mockQueries) GetPredictionsByBroadcastId(ctx context.Context, broadcastID int32) ([]queries.GetPredictionsByBroadcastIdRow, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	return m.predictionRows, nil
+}
+
 func (m *mockQueries) GetImagesForRequest(ctx context.Context, imageRequestID uuid.UUID) ([]string, error) {
 	return nil, nil
 }
 
+func (m *mockQueries) GetRaidHistory(ctx context.Context, channelID string) ([]queries.GetRaidHistoryRow, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	return m.raidRows, nil
+}
+
+func (m *mockQueries) GetRaidSummaryByBroadcaster(ctx context.Context, channelID string) ([]queries.GetRaidSummaryByBroadcasterRow, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	return m.raiderRows, nil
+}
+
 var _ Queries = (*mockQueries)(nil)
@@ -5,15 +5,22 @@ import (
 	"time"
 
 	"github.com/golden-vcr/showtime/gen/queries"
+	"github.com/golden-vcr/showtime/internal/progress"
 	"github.com/google/uuid"
 )
 
 type Queries interface {
-	GetBroadcastHistory(ctx context.Context) ([]queries.GetBroadcastHistoryRow, error)
+	GetBroadcastHistory(ctx context.Context, channelID string) ([]queries.GetBroadcastHistoryRow, error)
 	GetBroadcastById(ctx context.Context, broadcastID int32) (queries.ShowtimeBroadcast, error)
 	GetScreeningsByBroadcastId(ctx context.Context, broadcastID int32) ([]queries.GetScreeningsByBroadcastIdRow, error)
 	GetViewerLookupForBroadcast(ctx context.Context, broadcastID int32) ([]queries.GetViewerLookupForBroadcastRow, error)
+	GetChannelUpdatesByBroadcastId(ctx context.Context, broadcastID int32) ([]queries.GetChannelUpdatesByBroadcastIdRow, error)
+	GetHypeTrainsByBroadcastId(ctx context.Context, broadcastID int32) ([]queries.GetHypeTrainsByBroadcastIdRow, error)
+	GetPollsByBroadcastId(ctx context.Context, broadcastID int32) ([]queries.GetPollsByBroadcastIdRow, error)
+	GetPredictionsByBroadcastId(ctx context.Context, broadcastID int32) ([]queries.GetPredictionsByBroadcastIdRow, error)
 	GetImagesForRequest(ctx context.Context, imageRequestID uuid.UUID) ([]string, error)
+	GetRaidHistory(ctx context.Context, channelID string) ([]queries.GetRaidHistoryRow, error)
+	GetRaidSummaryByBroadcaster(ctx context.Context, channelID string) ([]queries.GetRaidSummaryByBroadcasterRow, error)
 }
 
 type Summary struct {
@@ -22,18 +29,24 @@ type Summary struct {
 }
 
 type SummarizedBroadcast struct {
-	Id        int       `json:"id"`
-	StartedAt time.Time `json:"startedAt"`
-	VodUrl    string    `json:"vodUrl"`
-	TapeIds   []int     `json:"tapeIds"`
+	Id             int       `json:"id"`
+	StartedAt      time.Time `json:"startedAt"`
+	VodUrl         string    `json:"vodUrl"`
+	TwitchStreamId string    `json:"twitchStreamId,omitempty"`
+	TapeIds        []int     `json:"tapeIds"`
 }
 
 type Broadcast struct {
-	Id         int         `json:"id"`
-	StartedAt  time.Time   `json:"startedAt"`
-	EndedAt    *time.Time  `json:"endedAt"`
-	Screenings []Screening `json:"screenings"`
-	VodUrl     string      `json:"vodUrl,omitempty"`
+	Id             int             `json:"id"`
+	StartedAt      time.Time       `json:"startedAt"`
+	EndedAt        *time.Time      `json:"endedAt"`
+	Screenings     []Screening     `json:"screenings"`
+	ChannelUpdates []ChannelUpdate `json:"channelUpdates"`
+	HypeTrains     []HypeTrain     `json:"hypeTrains"`
+	Polls          []Poll          `json:"polls"`
+	Predictions    []Prediction    `json:"predictions"`
+	VodUrl         string          `json:"vodUrl,omitempty"`
+	TwitchStreamId string          `json:"twitchStreamId,omitempty"`
 }
 
 type Screening struct {
@@ -43,8 +56,68 @@ type Screening struct {
 	ImageRequests []ImageRequest `json:"imageRequests"`
 }
 
+type ChannelUpdate struct {
+	Title        string    `json:"title"`
+	CategoryId   string    `json:"categoryId"`
+	CategoryName string    `json:"categoryName"`
+	ChangedAt    time.Time `json:"changedAt"`
+}
+
+type HypeTrain struct {
+	Level            int                              `json:"level"`
+	Total            int                              `json:"total"`
+	TopContributions []progress.HypeTrainContribution `json:"topContributions"`
+	StartedAt        time.Time                        `json:"startedAt"`
+	EndedAt          time.Time                        `json:"endedAt"`
+}
+
+type Poll struct {
+	Title     string                `json:"title"`
+	Status    string                `json:"status"`
+	Choices   []progress.PollChoice `json:"choices"`
+	StartedAt time.Time             `json:"startedAt"`
+	EndedAt   time.Time             `json:"endedAt"`
+}
+
+type Prediction struct {
+	Title            string                       `json:"title"`
+	Status           string                       `json:"status"`
+	WinningOutcomeId string                       `json:"winningOutcomeId,omitempty"`
+	Outcomes         []progress.PredictionOutcome `json:"outcomes"`
+	StartedAt        time.Time                    `json:"startedAt"`
+	EndedAt          time.Time                    `json:"endedAt"`
+}
+
 type ImageRequest struct {
 	Id       uuid.UUID `json:"id"`
 	Username string    `json:"username"`
 	Subject  string    `json:"subject"`
 }
+
+type RaidHistory struct {
+	Raids   []Raid          `json:"raids"`
+	Raiders []RaiderSummary `json:"raiders"`
+}
+
+type Raid struct {
+	Direction   string    `json:"direction"`
+	UserId      string    `json:"userId"`
+	UserLogin   string    `json:"userLogin"`
+	Username    string    `json:"username"`
+	NumViewers  int       `json:"numViewers"`
+	BroadcastId int       `json:"broadcastId,omitempty"`
+	TapeId      int       `json:"tapeId,omitempty"`
+	RaidedAt    time.Time `json:"raidedAt"`
+}
+
+// RaiderSummary aggregates all raids exchanged with a single other broadcaster, in
+// both directions
+type RaiderSummary struct {
+	UserId             string    `json:"userId"`
+	Username           string    `json:"username"`
+	NumIncomingRaids   int       `json:"numIncomingRaids"`
+	NumIncomingViewers int       `json:"numIncomingViewers"`
+	NumOutgoingRaids   int       `json:"numOutgoingRaids"`
+	NumOutgoingViewers int       `json:"numOutgoingViewers"`
+	LastRaidedAt       time.Time `json:"lastRaidedAt"`
+}
@@ -29,6 +29,7 @@ const ImageAlertPointsCost = 200
 
 type Server struct {
 	q                 Queries
+	channelId         string
 	ledger            ledger.Client
 	generation        GenerationClient
 	storage           StorageClient
@@ -36,9 +37,10 @@ type Server struct {
 	alertsChan        chan *alerts.Alert
 }
 
-func NewServer(q *queries.Queries, ledger ledger.Client, generation GenerationClient, storage StorageClient, discordWebhookUrl string, alertsChan chan *alerts.Alert) *Server {
+func NewServer(q *queries.Queries, channelId string, ledger ledger.Client, generation GenerationClient, storage StorageClient, discordWebhookUrl string, alertsChan chan *alerts.Alert) *Server {
 	return &Server{
 		q:                 q,
+		channelId:         channelId,
 		ledger:            ledger,
 		generation:        generation,
 		storage:           storage,
@@ -89,22 +91,50 @@ func (s *Server) handleRequest(res http.ResponseWriter, req *http.Request) {
 		http.Error(res, fmt.Sprintf("invalid request payload: %v", err), http.StatusBadRequest)
 		return
 	}
-	if payload.Subject == "" {
-		http.Error(res, "invalid request payload: 'subject' is required", http.StatusBadRequest)
+	if err := ValidateSubject(payload.Subject); err != nil {
+		http.Error(res, fmt.Sprintf("invalid request payload: %v", err), http.StatusBadRequest)
 		return
 	}
-	if len(payload.Subject) > MaxSubjectLen {
-		http.Error(res, "invalid request payload: 'subject' must be <= 120 characters", http.StatusBadRequest)
+
+	// Generate the requested images and display them onscreen, deducting the cost from
+	// the user's balance
+	if err := s.Generate(req.Context(), auth.GetToken(req), claims.User, payload.Subject); err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, ledger.ErrNotEnoughPoints) {
+			status = http.StatusConflict
+		} else if errors.Is(err, ErrRejected) {
+			status = http.StatusBadRequest
+		}
+		http.Error(res, err.Error(), status)
 		return
 	}
+	res.WriteHeader(http.StatusNoContent)
+}
+
+// ValidateSubject returns an error if the given subject can't be used to generate an
+// image
+func ValidateSubject(subject string) error {
+	if subject == "" {
+		return fmt.Errorf("'subject' is required")
+	}
+	if len(subject) > MaxSubjectLen {
+		return fmt.Errorf("'subject' must be <= %d characters", MaxSubjectLen)
+	}
+	return nil
+}
 
+// Generate generates an image of the given subject on behalf of the given user and
+// displays it onscreen as an alert. The user's access token is used to debit the cost
+// of the alert from their fun points balance: if generation fails for any reason, the
+// points are refunded. Errors wrap ledger.ErrNotEnoughPoints if the user can't afford
+// the alert, or ErrRejected if the image generation API refused the prompt.
+func (s *Server) Generate(ctx context.Context, accessToken string, user *auth.UserDetails, subject string) error {
 	// Check whether there's a screening in progress: if so, record the image request as
 	// taking place during that screening
 	var screeningId uuid.NullUUID
-	screening, err := s.q.GetCurrentScreening(req.Context())
+	screening, err := s.q.GetCurrentScreening(ctx, s.channelId)
 	if err != nil && !errors.Is(err, sql.ErrNoRows) {
-		http.Error(res, err.Error(), http.StatusInternalServerError)
-		return
+		return err
 	}
 	if err == nil && !screening.EndedAt.Valid {
 		screeningId.Valid = true
@@ -112,78 +142,54 @@ func (s *Server) handleRequest(res http.ResponseWriter, req *http.Request) {
 	}
 
 	// Ensure that the user has a viewer record in the database
-	if err := s.q.RecordViewerIdentity(req.Context(), queries.RecordViewerIdentityParams{
-		TwitchUserID:      claims.User.Id,
-		TwitchDisplayName: claims.User.DisplayName,
+	if err := s.q.RecordViewerIdentity(ctx, queries.RecordViewerIdentityParams{
+		TwitchUserID:      user.Id,
+		TwitchDisplayName: user.DisplayName,
 	}); err != nil {
-		http.Error(res, err.Error(), http.StatusInternalServerError)
-		return
+		return err
 	}
 
 	// Contact the ledger service to create a pending transaction
 	imageRequestId := uuid.New()
 	alertMetadata := json.RawMessage([]byte(fmt.Sprintf(`{"imageRequestId":"%s"}`, imageRequestId)))
-	transaction, err := s.ledger.RequestAlertRedemption(req.Context(), auth.GetToken(req), ImageAlertPointsCost, string(ImageAlertType), &alertMetadata)
+	transaction, err := s.ledger.RequestAlertRedemption(ctx, accessToken, ImageAlertPointsCost, string(ImageAlertType), &alertMetadata)
 	if err != nil {
-		status := http.StatusInternalServerError
-		if errors.Is(err, ledger.ErrNotEnoughPoints) {
-			status = http.StatusConflict
-		}
-		http.Error(res, err.Error(), status)
-		return
+		return err
 	}
-	defer transaction.Finalize(req.Context())
+	defer transaction.Finalize(ctx)
 
 	// Record our image generation request in the database, and prepare a function that
 	// we can use to record its failure (prior to returning) in the event of any error
-	prompt := formatPrompt(payload.Subject)
-	if err := s.q.RecordImageRequest(req.Context(), queries.RecordImageRequestParams{
+	prompt := formatPrompt(subject)
+	if err := s.q.RecordImageRequest(ctx, queries.RecordImageRequestParams{
 		ImageRequestID:    imageRequestId,
-		TwitchUserID:      claims.User.Id,
-		SubjectNounClause: payload.Subject,
+		TwitchUserID:      user.Id,
+		SubjectNounClause: subject,
 		Prompt:            prompt,
 		ScreeningID:       screeningId,
 	}); err != nil {
-		http.Error(res, err.Error(), http.StatusInternalServerError)
-		return
+		return err
 	}
 	recordFailure := func(err error) error {
-		_, dbErr := s.q.RecordImageRequestFailure(req.Context(), queries.RecordImageRequestFailureParams{
+		if _, dbErr := s.q.RecordImageRequestFailure(ctx, queries.RecordImageRequestFailureParams{
 			ImageRequestID: imageRequestId,
 			ErrorMessage:   err.Error(),
-		})
-		return dbErr
+		}); dbErr != nil {
+			fmt.Printf("Failed to record failure of image request %s: %v\n", imageRequestId, dbErr)
+		}
+		return err
 	}
 
 	// Attempt to generate N images from our prompt, fetching the contents of each PNG
 	// concurrently, converting them to JPEG, and buffering their image data in-memory
-	generatedImages, err := s.generation.GenerateImages(req.Context(), prompt, NumImagesToGeneratePerPrompt, claims.User.Id)
+	generatedImages, err := s.generation.GenerateImages(ctx, prompt, NumImagesToGeneratePerPrompt, user.Id)
 	if err != nil {
-		// Record the request as failed
-		if dbErr := recordFailure(err); dbErr != nil {
-			http.Error(res, err.Error(), http.StatusInternalServerError)
-			return
-		}
-		// If the API rejected our prompt and refused to generate images, respond with a
-		// 400 error
-		if errors.Is(err, ErrRejected) {
-			http.Error(res, err.Error(), http.StatusBadRequest)
-			return
-		}
-		// Otherwise, it's a 500 error: something failed on our end
-		http.Error(res, err.Error(), http.StatusInternalServerError)
-		return
+		return recordFailure(err)
 	}
 
 	// Sanity-check: ensure that we got the requested number of images
 	if len(generatedImages) != NumImagesToGeneratePerPrompt {
-		err := fmt.Errorf("invalid image generation result: expected to get %d images; got %d", NumImagesToGeneratePerPrompt, len(generatedImages))
-		if dbErr := recordFailure(err); dbErr != nil {
-			http.Error(res, err.Error(), http.StatusInternalServerError)
-			return
-		}
-		http.Error(res, err.Error(), http.StatusInternalServerError)
-		return
+		return recordFailure(fmt.Errorf("invalid image generation result: expected to get %d images; got %d", NumImagesToGeneratePerPrompt, len(generatedImages)))
 	}
 
 	// Kick off a goroutine for each image that was generated, uploading it to our
@@ -192,23 +198,17 @@ func (s *Server) handleRequest(res http.ResponseWriter, req *http.Request) {
 	imageUrlsChan := make(chan string, len(generatedImages))
 	for i := range generatedImages {
 		image := &generatedImages[i]
-		thunk := getStoreImageFunc(req.Context(), imageRequestId, s.q, s.storage, image, imageUrlsChan)
+		thunk := getStoreImageFunc(ctx, imageRequestId, s.q, s.storage, image, imageUrlsChan)
 		wg.Go(thunk)
 	}
 	if err := wg.Wait(); err != nil {
-		if dbErr := recordFailure(err); dbErr != nil {
-			http.Error(res, err.Error(), http.StatusInternalServerError)
-			return
-		}
-		http.Error(res, err.Error(), http.StatusInternalServerError)
-		return
+		return recordFailure(err)
 	}
 
 	// If we successfully handled all generated images, mark the image generation
 	// request as finished successfully
-	if _, err := s.q.RecordImageRequestSuccess(req.Context(), imageRequestId); err != nil {
-		http.Error(res, err.Error(), http.StatusInternalServerError)
-		return
+	if _, err := s.q.RecordImageRequestSuccess(ctx, imageRequestId); err != nil {
+		return err
 	}
 
 	// Generate an alert that will display these images onscreen during the stream
@@ -218,13 +218,13 @@ func (s *Server) handleRequest(res http.ResponseWriter, req *http.Request) {
 	}
 	sort.Strings(imageUrls)
 
-	fmt.Printf("Generating an %d-image alert for user %s with subject '%s'\n", len(imageUrls), claims.User.DisplayName, payload.Subject)
-	description := payload.Subject
+	fmt.Printf("Generating an %d-image alert for user %s with subject '%s'\n", len(imageUrls), user.DisplayName, subject)
+	description := subject
 	s.alertsChan <- &alerts.Alert{
 		Type: alerts.AlertTypeGeneratedImages,
 		Data: alerts.AlertData{
 			GeneratedImages: &alerts.AlertDataGeneratedImages{
-				Username:    claims.User.DisplayName,
+				Username:    user.DisplayName,
 				Description: description,
 				Urls:        imageUrls,
 			},
@@ -235,9 +235,8 @@ func (s *Server) handleRequest(res http.ResponseWriter, req *http.Request) {
 	// transaction to deduct the points we debited from them - if we don't make it here,
 	// our deferred called to transaction.Finalize will reject the transaction instead,
 	// causing the debited points to be refunded
-	if err := transaction.Accept(req.Context()); err != nil {
-		http.Error(res, fmt.Sprintf("failed to finalize transaction: %v", err), http.StatusInternalServerError)
-		return
+	if err := transaction.Accept(ctx); err != nil {
+		return fmt.Errorf("failed to finalize transaction: %w", err)
 	}
 
 	// Don't hold up the request to do this; just initiate a fire-and-forget HTTP
@@ -245,14 +244,13 @@ func (s *Server) handleRequest(res http.ResponseWriter, req *http.Request) {
 	// channel in the Discord server. If the request fails, we'll simply print an error.
 	if s.discordWebhookUrl != "" {
 		go func() {
-			err := discord.PostGhostAlert(s.discordWebhookUrl, claims.User.DisplayName, description, imageUrls[0])
+			err := discord.PostGhostAlert(s.discordWebhookUrl, user.DisplayName, description, imageUrls[0])
 			if err != nil {
 				fmt.Printf("ERROR: Failed to post ghost alert to Discord: %v\n", err)
 			}
 		}()
 	}
-
-	res.WriteHeader(http.StatusNoContent)
+	return nil
 }
 
 func formatPrompt(subjectNounClause string) string {
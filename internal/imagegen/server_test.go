@@ -305,7 +305,7 @@ func (r mockSqlResult) RowsAffected() (int64, error) {
 	return r.numRowsAffected, nil
 }
 
-func (m *mockQueries) GetCurrentScreening(ctx context.Context) (queries.GetCurrentScreeningRow, error) {
+func (m *mockQueries) GetCurrentScreening(ctx context.Context, channelID string) (queries.GetCurrentScreeningRow, error) {
 	if m.currentScreeningId.Valid {
 		return queries.GetCurrentScreeningRow{
 			ID:        m.currentScreeningId.UUID,
@@ -0,0 +1,166 @@
+package points
+
+import (
+	"fmt"
+)
+
+const (
+	// EventTypeSubscription is a viewer subscribing for the first time, including via
+	// a gift sub: the recipient of the gift is credited
+	EventTypeSubscription = "subscription"
+	// EventTypeResubscription is a viewer sharing a resubscription message
+	EventTypeResubscription = "resubscription"
+	// EventTypeGiftSub is a viewer gifting subs to others: the gifter is credited once
+	// per sub gifted
+	EventTypeGiftSub = "gift-sub"
+)
+
+const (
+	Tier1 = "1000"
+	Tier2 = "2000"
+	Tier3 = "3000"
+)
+
+const (
+	// BonusKindLive applies a multiplier to all credits awarded while a broadcast is
+	// live
+	BonusKindLive = "live"
+	// BonusKindScreening applies a multiplier to all credits awarded while a specific
+	// tape is being screened
+	BonusKindScreening = "screening"
+)
+
+// Rules defines how many fun points viewers are awarded in response to events
+type Rules struct {
+	// Awards defines the points awarded for each type of event, per subscription tier
+	Awards []Award `json:"awards"`
+	// Bonuses defines additional multipliers that apply depending on what's happening
+	// on stream when the event occurs
+	Bonuses []Bonus `json:"bonuses"`
+	// GhostAlertBits is the exact number of bits with which a viewer must cheer in
+	// order to request a ghost alert: 0 disables ghost alerts via cheers
+	GhostAlertBits int `json:"ghostAlertBits"`
+}
+
+// Award defines the credit given for a particular type of event at a particular tier:
+// the viewer is credited with BasePoints times Multiplier (along with any applicable
+// bonuses)
+type Award struct {
+	EventType  string  `json:"eventType"`
+	Tier       string  `json:"tier"`
+	BasePoints int     `json:"basePoints"`
+	Multiplier float64 `json:"multiplier"`
+}
+
+// Bonus defines a multiplier that's applied on top of all awards under certain
+// conditions
+type Bonus struct {
+	Kind       string  `json:"kind"`
+	TapeId     int     `json:"tapeId,omitempty"`
+	Multiplier float64 `json:"multiplier"`
+}
+
+// Conditions describes what's happening on stream at the time of an event, in order to
+// determine which bonuses apply
+type Conditions struct {
+	IsLive bool
+	TapeId int
+}
+
+// DefaultRules are the rules that apply if none have been configured
+var DefaultRules = Rules{
+	Awards: []Award{
+		{EventTypeSubscription, Tier1, 600, 1},
+		{EventTypeSubscription, Tier2, 600, 2},
+		{EventTypeSubscription, Tier3, 600, 5},
+		{EventTypeResubscription, Tier1, 600, 1},
+		{EventTypeResubscription, Tier2, 600, 2},
+		{EventTypeResubscription, Tier3, 600, 5},
+		{EventTypeGiftSub, Tier1, 200, 1},
+		{EventTypeGiftSub, Tier2, 200, 2},
+		{EventTypeGiftSub, Tier3, 200, 5},
+	},
+	Bonuses:        []Bonus{},
+	GhostAlertBits: 200,
+}
+
+// Validate returns an error describing the first problem found with the rules, if any
+func (r *Rules) Validate() error {
+	if r.Awards == nil {
+		return fmt.Errorf("awards is required")
+	}
+	seen := make(map[string]struct{})
+	for i, award := range r.Awards {
+		switch award.EventType {
+		case EventTypeSubscription, EventTypeResubscription, EventTypeGiftSub:
+		default:
+			return fmt.Errorf("awards[%d]: eventType must be one of '%s', '%s', or '%s'", i, EventTypeSubscription, EventTypeResubscription, EventTypeGiftSub)
+		}
+		switch award.Tier {
+		case Tier1, Tier2, Tier3:
+		default:
+			return fmt.Errorf("awards[%d]: tier must be one of '%s', '%s', or '%s'", i, Tier1, Tier2, Tier3)
+		}
+		key := award.EventType + ":" + award.Tier
+		if _, ok := seen[key]; ok {
+			return fmt.Errorf("awards[%d]: duplicate award for eventType '%s' and tier '%s'", i, award.EventType, award.Tier)
+		}
+		seen[key] = struct{}{}
+		if award.BasePoints < 0 {
+			return fmt.Errorf("awards[%d]: basePoints may not be negative", i)
+		}
+		if award.Multiplier <= 0 {
+			return fmt.Errorf("awards[%d]: multiplier must be positive", i)
+		}
+	}
+	for i, bonus := range r.Bonuses {
+		switch bonus.Kind {
+		case BonusKindLive:
+			if bonus.TapeId != 0 {
+				return fmt.Errorf("bonuses[%d]: tapeId may not be set when kind is '%s'", i, bonus.Kind)
+			}
+		case BonusKindScreening:
+			if bonus.TapeId <= 0 {
+				return fmt.Errorf("bonuses[%d]: tapeId is required when kind is '%s'", i, bonus.Kind)
+			}
+		default:
+			return fmt.Errorf("bonuses[%d]: kind must be one of '%s' or '%s'", i, BonusKindLive, BonusKindScreening)
+		}
+		if bonus.Multiplier <= 0 {
+			return fmt.Errorf("bonuses[%d]: multiplier must be positive", i)
+		}
+	}
+	if r.GhostAlertBits < 0 {
+		return fmt.Errorf("ghostAlertBits may not be negative")
+	}
+	return nil
+}
+
+// Resolve returns the number of base points and the total multiplier that should be
+// credited for an event of the given type and tier, under the given conditions.
+// Returns false if no award is defined for that event type and tier.
+func (r *Rules) Resolve(eventType string, tier string, conditions Conditions) (int, float64, bool) {
+	for _, award := range r.Awards {
+		if award.EventType != eventType || award.Tier != tier {
+			continue
+		}
+		multiplier := award.Multiplier
+		for _, bonus := range r.Bonuses {
+			if bonus.appliesTo(conditions) {
+				multiplier *= bonus.Multiplier
+			}
+		}
+		return award.BasePoints, multiplier, true
+	}
+	return 0, 0, false
+}
+
+func (b *Bonus) appliesTo(conditions Conditions) bool {
+	switch b.Kind {
+	case BonusKindLive:
+		return conditions.IsLive
+	case BonusKindScreening:
+		return conditions.IsLive && conditions.TapeId == b.TapeId
+	}
+	return false
+}
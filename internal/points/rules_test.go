@@ -0,0 +1,166 @@
+package points
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Rules_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		rules   Rules
+		wantErr string
+	}{
+		{
+			"default rules are valid",
+			DefaultRules,
+			"",
+		},
+		{
+			"awards are required",
+			Rules{},
+			"awards is required",
+		},
+		{
+			"unknown event types are rejected",
+			Rules{Awards: []Award{{"follow", Tier1, 100, 1}}},
+			"awards[0]: eventType must be one of 'subscription', 'resubscription', or 'gift-sub'",
+		},
+		{
+			"unknown tiers are rejected",
+			Rules{Awards: []Award{{EventTypeSubscription, "4000", 100, 1}}},
+			"awards[0]: tier must be one of '1000', '2000', or '3000'",
+		},
+		{
+			"duplicate awards are rejected",
+			Rules{Awards: []Award{
+				{EventTypeSubscription, Tier1, 100, 1},
+				{EventTypeSubscription, Tier1, 200, 1},
+			}},
+			"awards[1]: duplicate award for eventType 'subscription' and tier '1000'",
+		},
+		{
+			"negative base points are rejected",
+			Rules{Awards: []Award{{EventTypeGiftSub, Tier1, -1, 1}}},
+			"awards[0]: basePoints may not be negative",
+		},
+		{
+			"zero multiplier is rejected",
+			Rules{Awards: []Award{{EventTypeGiftSub, Tier1, 100, 0}}},
+			"awards[0]: multiplier must be positive",
+		},
+		{
+			"live bonus may not specify a tape",
+			Rules{Awards: []Award{}, Bonuses: []Bonus{{BonusKindLive, 12, 2}}},
+			"bonuses[0]: tapeId may not be set when kind is 'live'",
+		},
+		{
+			"screening bonus requires a tape",
+			Rules{Awards: []Award{}, Bonuses: []Bonus{{BonusKindScreening, 0, 2}}},
+			"bonuses[0]: tapeId is required when kind is 'screening'",
+		},
+		{
+			"unknown bonus kinds are rejected",
+			Rules{Awards: []Award{}, Bonuses: []Bonus{{"weekend", 0, 2}}},
+			"bonuses[0]: kind must be one of 'live' or 'screening'",
+		},
+		{
+			"negative bonus multiplier is rejected",
+			Rules{Awards: []Award{}, Bonuses: []Bonus{{BonusKindLive, 0, -1}}},
+			"bonuses[0]: multiplier must be positive",
+		},
+		{
+			"negative ghost alert bits are rejected",
+			Rules{Awards: []Award{}, GhostAlertBits: -200},
+			"ghostAlertBits may not be negative",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.rules.Validate()
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func Test_Rules_Resolve(t *testing.T) {
+	rules := Rules{
+		Awards: []Award{
+			{EventTypeSubscription, Tier1, 600, 1},
+			{EventTypeSubscription, Tier3, 600, 5},
+			{EventTypeGiftSub, Tier1, 200, 1},
+		},
+		Bonuses: []Bonus{
+			{BonusKindLive, 0, 1.5},
+			{BonusKindScreening, 42, 2},
+		},
+	}
+	tests := []struct {
+		name           string
+		eventType      string
+		tier           string
+		conditions     Conditions
+		wantBasePoints int
+		wantMultiplier float64
+		wantOk         bool
+	}{
+		{
+			"offline subscription uses award multiplier only",
+			EventTypeSubscription,
+			Tier3,
+			Conditions{},
+			600,
+			5,
+			true,
+		},
+		{
+			"live bonus applies while live",
+			EventTypeGiftSub,
+			Tier1,
+			Conditions{IsLive: true},
+			200,
+			1.5,
+			true,
+		},
+		{
+			"screening bonus stacks with live bonus during that screening",
+			EventTypeSubscription,
+			Tier1,
+			Conditions{IsLive: true, TapeId: 42},
+			600,
+			3,
+			true,
+		},
+		{
+			"screening bonus does not apply to other tapes",
+			EventTypeSubscription,
+			Tier1,
+			Conditions{IsLive: true, TapeId: 43},
+			600,
+			1.5,
+			true,
+		},
+		{
+			"events with no award are not credited",
+			EventTypeSubscription,
+			Tier2,
+			Conditions{IsLive: true},
+			0,
+			0,
+			false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			basePoints, multiplier, ok := rules.Resolve(tt.eventType, tt.tier, tt.conditions)
+			assert.Equal(t, tt.wantBasePoints, basePoints)
+			assert.Equal(t, tt.wantMultiplier, multiplier)
+			assert.Equal(t, tt.wantOk, ok)
+		})
+	}
+}
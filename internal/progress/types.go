@@ -0,0 +1,100 @@
+package progress
+
+import (
+	"encoding/json"
+	"time"
+)
+
+const (
+	EventTypeHypeTrain  = "hype-train"
+	EventTypePoll       = "poll"
+	EventTypePrediction = "prediction"
+)
+
+const (
+	// PhaseBegin indicates that a hype train, poll, or prediction has just started
+	PhaseBegin = "begin"
+	// PhaseProgress indicates that the totals for an ongoing hype train, poll, or
+	// prediction have changed
+	PhaseProgress = "progress"
+	// PhaseLock indicates that a prediction is no longer accepting predictions and is
+	// waiting to be resolved
+	PhaseLock = "lock"
+	// PhaseEnd indicates that a hype train, poll, or prediction has concluded, and its
+	// results are final
+	PhaseEnd = "end"
+)
+
+// Event carries the latest state of a hype train, poll, or prediction, so that an
+// overlay can render its progress in real time
+type Event struct {
+	Type  string    `json:"type"`
+	Phase string    `json:"phase"`
+	Data  EventData `json:"data"`
+}
+
+type EventData struct {
+	HypeTrain  *EventDataHypeTrain
+	Poll       *EventDataPoll
+	Prediction *EventDataPrediction
+}
+
+type EventDataHypeTrain struct {
+	Id               string                  `json:"id"`
+	Level            int                     `json:"level"`
+	Total            int                     `json:"total"`
+	Progress         int                     `json:"progress"`
+	Goal             int                     `json:"goal"`
+	TopContributions []HypeTrainContribution `json:"topContributions"`
+	ExpiresAt        *time.Time              `json:"expiresAt,omitempty"`
+}
+
+type HypeTrainContribution struct {
+	Username string `json:"username"`
+	Type     string `json:"type"`
+	Total    int    `json:"total"`
+}
+
+type EventDataPoll struct {
+	Id      string       `json:"id"`
+	Title   string       `json:"title"`
+	Choices []PollChoice `json:"choices"`
+	Status  string       `json:"status,omitempty"`
+	EndsAt  *time.Time   `json:"endsAt,omitempty"`
+}
+
+type PollChoice struct {
+	Id       string `json:"id"`
+	Title    string `json:"title"`
+	NumVotes int    `json:"numVotes"`
+}
+
+type EventDataPrediction struct {
+	Id               string              `json:"id"`
+	Title            string              `json:"title"`
+	Outcomes         []PredictionOutcome `json:"outcomes"`
+	Status           string              `json:"status,omitempty"`
+	WinningOutcomeId string              `json:"winningOutcomeId,omitempty"`
+	LocksAt          *time.Time          `json:"locksAt,omitempty"`
+}
+
+type PredictionOutcome struct {
+	Id               string `json:"id"`
+	Title            string `json:"title"`
+	Color            string `json:"color"`
+	NumUsers         int    `json:"numUsers"`
+	NumChannelPoints int    `json:"numChannelPoints"`
+}
+
+func (ed EventData) MarshalJSON() ([]byte, error) {
+	if ed.HypeTrain != nil {
+		return json.Marshal(ed.HypeTrain)
+	}
+	if ed.Poll != nil {
+		return json.Marshal(ed.Poll)
+	}
+	if ed.Prediction != nil {
+		return json.Marshal(ed.Prediction)
+	}
+	return json.Marshal(nil)
+}
@@ -0,0 +1,122 @@
+package replay
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/nicklaw5/helix/v2"
+)
+
+// Notification is a raw EventSub notification that we've previously received from
+// Twitch, along with the ID and timestamp of the message that carried it
+type Notification struct {
+	MessageId        string                     `json:"messageId,omitempty"`
+	MessageTimestamp time.Time                  `json:"messageTimestamp"`
+	Subscription     helix.EventSubSubscription `json:"subscription"`
+	Event            json.RawMessage            `json:"event"`
+}
+
+// Filter selects which notifications should be replayed
+type Filter struct {
+	// Types is the set of subscription types to replay, e.g. "channel.cheer": if
+	// empty, notifications of all types are replayed
+	Types []string
+	// Since, if nonzero, excludes notifications sent before this time
+	Since time.Time
+	// Until, if nonzero, excludes notifications sent at or after this time
+	Until time.Time
+}
+
+// Matches returns true if the given notification should be replayed. Notifications
+// with no known timestamp never match a filter that specifies a time range.
+func (f *Filter) Matches(n *Notification) bool {
+	if len(f.Types) > 0 {
+		found := false
+		for _, t := range f.Types {
+			if t == n.Subscription.Type {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if !f.Since.IsZero() || !f.Until.IsZero() {
+		if n.MessageTimestamp.IsZero() {
+			return false
+		}
+		if !f.Since.IsZero() && n.MessageTimestamp.Before(f.Since) {
+			return false
+		}
+		if !f.Until.IsZero() && !n.MessageTimestamp.Before(f.Until) {
+			return false
+		}
+	}
+	return true
+}
+
+// Target is the destination to which notifications are replayed
+type Target interface {
+	// Describe returns a short, human-readable summary of what replaying the given
+	// notification will do
+	Describe(n *Notification) string
+	// Replay delivers the notification, returning an error if it was not handled
+	// successfully
+	Replay(ctx context.Context, n *Notification) error
+}
+
+// Result summarizes the outcome of replaying a set of notifications
+type Result struct {
+	NumReplayed int
+	NumSkipped  int
+	NumFailed   int
+}
+
+// Run replays every notification that matches the filter, in order, to the given
+// target, writing a line to w for each notification. If dryRun is true, notifications
+// are only described and nothing is sent. A failure to replay one notification does
+// not prevent the rest from being replayed.
+func Run(ctx context.Context, notifications []Notification, filter Filter, target Target, dryRun bool, w io.Writer) Result {
+	result := Result{}
+	for i := range notifications {
+		if ctx.Err() != nil {
+			break
+		}
+		n := &notifications[i]
+		if !filter.Matches(n) {
+			result.NumSkipped++
+			continue
+		}
+
+		if dryRun {
+			fmt.Fprintf(w, "[dry run] %s\n", target.Describe(n))
+			result.NumReplayed++
+			continue
+		}
+
+		fmt.Fprintf(w, "%s\n", target.Describe(n))
+		if err := target.Replay(ctx, n); err != nil {
+			fmt.Fprintf(w, "- FAILED: %v\n", err)
+			result.NumFailed++
+			continue
+		}
+		result.NumReplayed++
+	}
+	return result
+}
+
+func describe(n *Notification) string {
+	messageId := n.MessageId
+	if messageId == "" {
+		messageId = "(no message ID)"
+	}
+	timestamp := "(no timestamp)"
+	if !n.MessageTimestamp.IsZero() {
+		timestamp = n.MessageTimestamp.Format(time.RFC3339)
+	}
+	return fmt.Sprintf("%s %s %s", timestamp, n.Subscription.Type, messageId)
+}
@@ -0,0 +1,147 @@
+package replay
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/nicklaw5/helix/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Filter_Matches(t *testing.T) {
+	at := time.Date(1997, 9, 1, 12, 0, 0, 0, time.UTC)
+	cheer := &Notification{
+		MessageId:        "cheer-message",
+		MessageTimestamp: at,
+		Subscription:     helix.EventSubSubscription{Type: helix.EventSubTypeChannelCheer},
+	}
+	untimed := &Notification{
+		Subscription: helix.EventSubSubscription{Type: helix.EventSubTypeChannelCheer},
+	}
+	tests := []struct {
+		name   string
+		filter Filter
+		n      *Notification
+		want   bool
+	}{
+		{
+			"empty filter matches everything",
+			Filter{},
+			cheer,
+			true,
+		},
+		{
+			"notification of listed type matches",
+			Filter{Types: []string{helix.EventSubTypeChannelFollow, helix.EventSubTypeChannelCheer}},
+			cheer,
+			true,
+		},
+		{
+			"notification of unlisted type does not match",
+			Filter{Types: []string{helix.EventSubTypeChannelFollow}},
+			cheer,
+			false,
+		},
+		{
+			"since is inclusive",
+			Filter{Since: at},
+			cheer,
+			true,
+		},
+		{
+			"notification before since does not match",
+			Filter{Since: at.Add(time.Second)},
+			cheer,
+			false,
+		},
+		{
+			"until is exclusive",
+			Filter{Until: at},
+			cheer,
+			false,
+		},
+		{
+			"notification before until matches",
+			Filter{Until: at.Add(time.Second)},
+			cheer,
+			true,
+		},
+		{
+			"notification with no timestamp does not match time range",
+			Filter{Since: at},
+			untimed,
+			false,
+		},
+		{
+			"notification with no timestamp matches filter with no time range",
+			Filter{Types: []string{helix.EventSubTypeChannelCheer}},
+			untimed,
+			true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.filter.Matches(tt.n))
+		})
+	}
+}
+
+func Test_Run(t *testing.T) {
+	at := time.Date(1997, 9, 1, 12, 0, 0, 0, time.UTC)
+	notifications := []Notification{
+		{
+			MessageId:        "message-1",
+			MessageTimestamp: at,
+			Subscription:     helix.EventSubSubscription{Type: helix.EventSubTypeChannelCheer},
+		},
+		{
+			MessageId:        "message-2",
+			MessageTimestamp: at.Add(time.Minute),
+			Subscription:     helix.EventSubSubscription{Type: helix.EventSubTypeChannelFollow},
+		},
+		{
+			MessageId:        "message-3",
+			MessageTimestamp: at.Add(2 * time.Minute),
+			Subscription:     helix.EventSubSubscription{Type: helix.EventSubTypeChannelCheer},
+		},
+	}
+	filter := Filter{Types: []string{helix.EventSubTypeChannelCheer}}
+
+	t.Run("matching notifications are replayed in order, continuing past failures", func(t *testing.T) {
+		target := &mockTarget{failMessageIds: map[string]bool{"message-1": true}}
+		var out bytes.Buffer
+		result := Run(context.Background(), notifications, filter, target, false, &out)
+		assert.Equal(t, Result{NumReplayed: 1, NumSkipped: 1, NumFailed: 1}, result)
+		assert.Equal(t, []string{"message-1", "message-3"}, target.replayed)
+		assert.Equal(t, "replay message-1\n- FAILED: mock error\nreplay message-3\n", out.String())
+	})
+
+	t.Run("dry run only describes notifications", func(t *testing.T) {
+		target := &mockTarget{}
+		var out bytes.Buffer
+		result := Run(context.Background(), notifications, filter, target, true, &out)
+		assert.Equal(t, Result{NumReplayed: 2, NumSkipped: 1}, result)
+		assert.Len(t, target.replayed, 0)
+		assert.Equal(t, "[dry run] replay message-1\n[dry run] replay message-3\n", out.String())
+	})
+}
+
+type mockTarget struct {
+	failMessageIds map[string]bool
+	replayed       []string
+}
+
+func (m *mockTarget) Describe(n *Notification) string {
+	return fmt.Sprintf("replay %s", n.MessageId)
+}
+
+func (m *mockTarget) Replay(ctx context.Context, n *Notification) error {
+	m.replayed = append(m.replayed, n.MessageId)
+	if m.failMessageIds[n.MessageId] {
+		return fmt.Errorf("mock error")
+	}
+	return nil
+}
@@ -0,0 +1,88 @@
+package replay
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/golden-vcr/showtime/gen/queries"
+	"github.com/nicklaw5/helix/v2"
+)
+
+// maxLineSize is the maximum length of a single line in a JSONL file: EventSub
+// notifications are well below this size
+const maxLineSize = 1024 * 1024
+
+// Queries represents the subset of database functionality required to load stored
+// notifications for replay
+type Queries interface {
+	GetEventSubNotificationsForReplay(ctx context.Context, arg queries.GetEventSubNotificationsForReplayParams) ([]queries.GetEventSubNotificationsForReplayRow, error)
+}
+
+// LoadFromDB returns all notifications from the EventSub inbox that were sent within
+// the filter's time range, in the order they were sent
+func LoadFromDB(ctx context.Context, q Queries, filter Filter) ([]Notification, error) {
+	params := queries.GetEventSubNotificationsForReplayParams{
+		Since: filter.Since,
+		Until: filter.Until,
+	}
+	if params.Until.IsZero() {
+		params.Until = time.Now().Add(time.Hour)
+	}
+	rows, err := q.GetEventSubNotificationsForReplay(ctx, params)
+	if err != nil {
+		return nil, fmt.Errorf("GetEventSubNotificationsForReplay failed: %w", err)
+	}
+
+	notifications := make([]Notification, 0, len(rows))
+	for _, row := range rows {
+		var subscription helix.EventSubSubscription
+		if err := json.Unmarshal(row.Subscription, &subscription); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal subscription for message %s: %w", row.MessageID, err)
+		}
+		notifications = append(notifications, Notification{
+			MessageId:        row.MessageID,
+			MessageTimestamp: row.MessageTimestamp,
+			Subscription:     subscription,
+			Event:            row.Event,
+		})
+	}
+	return notifications, nil
+}
+
+// ReadJsonl parses notifications from JSONL data, with one notification per line.
+// Each line is a JSON object with 'subscription' and 'event' keys, as in the body of
+// an EventSub webhook notification, optionally accompanied by 'messageId' and
+// 'messageTimestamp'. Blank lines are ignored.
+func ReadJsonl(r io.Reader) ([]Notification, error) {
+	notifications := make([]Notification, 0)
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxLineSize)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		var n Notification
+		if err := json.Unmarshal(line, &n); err != nil {
+			return nil, fmt.Errorf("failed to parse line %d: %w", lineNumber, err)
+		}
+		if n.Subscription.Type == "" {
+			return nil, fmt.Errorf("line %d: subscription.type is required", lineNumber)
+		}
+		if len(n.Event) == 0 {
+			return nil, fmt.Errorf("line %d: event is required", lineNumber)
+		}
+		notifications = append(notifications, n)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return notifications, nil
+}
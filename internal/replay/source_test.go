@@ -0,0 +1,82 @@
+package replay
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golden-vcr/showtime/gen/queries"
+	"github.com/nicklaw5/helix/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_ReadJsonl(t *testing.T) {
+	notifications, err := ReadJsonl(strings.NewReader(`{"messageId": "message-1", "messageTimestamp": "1997-09-01T12:00:00Z", "subscription": {"type": "channel.cheer", "version": "1"}, "event": {"bits": 100}}
+
+{"subscription": {"type": "channel.follow", "version": "2"}, "event": {"user_id": "1337"}}
+`))
+	assert.NoError(t, err)
+	assert.Equal(t, []Notification{
+		{
+			MessageId:        "message-1",
+			MessageTimestamp: time.Date(1997, 9, 1, 12, 0, 0, 0, time.UTC),
+			Subscription:     helix.EventSubSubscription{Type: "channel.cheer", Version: "1"},
+			Event:            json.RawMessage(`{"bits": 100}`),
+		},
+		{
+			Subscription: helix.EventSubSubscription{Type: "channel.follow", Version: "2"},
+			Event:        json.RawMessage(`{"user_id": "1337"}`),
+		},
+	}, notifications)
+}
+
+func Test_ReadJsonl_errors(t *testing.T) {
+	_, err := ReadJsonl(strings.NewReader(`{"subscription": {"type": "channel.cheer"}, "event": {}}
+not json`))
+	assert.ErrorContains(t, err, "failed to parse line 2")
+
+	_, err = ReadJsonl(strings.NewReader(`{"event": {}}`))
+	assert.EqualError(t, err, "line 1: subscription.type is required")
+
+	_, err = ReadJsonl(strings.NewReader(`{"subscription": {"type": "channel.cheer"}}`))
+	assert.EqualError(t, err, "line 1: event is required")
+}
+
+func Test_LoadFromDB(t *testing.T) {
+	since := time.Date(1997, 9, 1, 12, 0, 0, 0, time.UTC)
+	until := since.Add(time.Hour)
+	q := &mockQueries{
+		rows: []queries.GetEventSubNotificationsForReplayRow{
+			{
+				MessageID:        "message-1",
+				SubscriptionType: "channel.cheer",
+				MessageTimestamp: since.Add(time.Minute),
+				Subscription:     json.RawMessage(`{"id":"sub-1","type":"channel.cheer","version":"1"}`),
+				Event:            json.RawMessage(`{"bits":100}`),
+			},
+		},
+	}
+	notifications, err := LoadFromDB(context.Background(), q, Filter{Since: since, Until: until})
+	assert.NoError(t, err)
+	assert.Equal(t, queries.GetEventSubNotificationsForReplayParams{Since: since, Until: until}, q.params)
+	assert.Equal(t, []Notification{
+		{
+			MessageId:        "message-1",
+			MessageTimestamp: since.Add(time.Minute),
+			Subscription:     helix.EventSubSubscription{ID: "sub-1", Type: "channel.cheer", Version: "1"},
+			Event:            json.RawMessage(`{"bits":100}`),
+		},
+	}, notifications)
+}
+
+type mockQueries struct {
+	rows   []queries.GetEventSubNotificationsForReplayRow
+	params queries.GetEventSubNotificationsForReplayParams
+}
+
+func (m *mockQueries) GetEventSubNotificationsForReplay(ctx context.Context, arg queries.GetEventSubNotificationsForReplayParams) ([]queries.GetEventSubNotificationsForReplayRow, error) {
+	m.params = arg
+	return m.rows, nil
+}
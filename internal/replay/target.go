@@ -0,0 +1,120 @@
+package replay
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/golden-vcr/showtime/internal/events"
+	"github.com/google/uuid"
+	"github.com/nicklaw5/helix/v2"
+)
+
+const headerMessageSignature = "Twitch-Eventsub-Message-Signature"
+
+// WebhookTarget replays notifications by POSTing them to the EventSub callback
+// endpoint of a running server, signed with the webhook secret exactly as Twitch would
+// sign them. Since the server ignores messages it's already seen and rejects messages
+// that are too old, each notification is sent with a new message ID and the current
+// time: side effects that are only applied once per message ID will be applied again.
+type WebhookTarget struct {
+	url          string
+	secret       string
+	client       *http.Client
+	now          func() time.Time
+	newMessageId func() string
+}
+
+func NewWebhookTarget(url string, secret string) *WebhookTarget {
+	return &WebhookTarget{
+		url:    url,
+		secret: secret,
+		client: http.DefaultClient,
+		now:    time.Now,
+		newMessageId: func() string {
+			return uuid.NewString()
+		},
+	}
+}
+
+func (t *WebhookTarget) Describe(n *Notification) string {
+	return fmt.Sprintf("POST %s: %s (resent as new message)", t.url, describe(n))
+}
+
+func (t *WebhookTarget) Replay(ctx context.Context, n *Notification) error {
+	body, err := json.Marshal(struct {
+		Subscription helix.EventSubSubscription `json:"subscription"`
+		Event        json.RawMessage            `json:"event"`
+	}{
+		Subscription: n.Subscription,
+		Event:        n.Event,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to encode message payload: %w", err)
+	}
+	message := string(body)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, t.url, strings.NewReader(message))
+	if err != nil {
+		return fmt.Errorf("error initializing HTTP request: %w", err)
+	}
+	req.Header.Set("content-type", "application/json")
+	req.Header.Set(events.HeaderMessageId, t.newMessageId())
+	req.Header.Set(events.HeaderMessageTimestamp, t.now().UTC().Format(time.RFC3339Nano))
+	req.Header.Set(events.HeaderMessageType, events.MessageTypeNotification)
+	req.Header.Set(headerMessageSignature, computeSignature(t.secret, req.Header, message))
+
+	res, err := t.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("error sending HTTP request: %w", err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		resBody, _ := io.ReadAll(res.Body)
+		return fmt.Errorf("got response %d: %s", res.StatusCode, bytes.TrimSpace(resBody))
+	}
+	return nil
+}
+
+// computeSignature signs an EventSub message in the manner expected by
+// helix.VerifyEventSubNotification
+func computeSignature(secret string, h http.Header, message string) string {
+	hmacMessage := []byte(fmt.Sprintf("%s%s%s", h.Get(events.HeaderMessageId), h.Get(events.HeaderMessageTimestamp), message))
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(hmacMessage)
+	return fmt.Sprintf("sha256=%s", hex.EncodeToString(mac.Sum(nil)))
+}
+
+// HandlerTarget replays notifications in-process by passing them directly to an
+// events.HandleEventFunc, bypassing the server and the inbox. Each notification is
+// handled under its original message ID, so side effects that have already been
+// applied for that message (such as crediting fun points) are not applied twice.
+type HandlerTarget struct {
+	handleEvent events.HandleEventFunc
+}
+
+func NewHandlerTarget(handleEvent events.HandleEventFunc) *HandlerTarget {
+	return &HandlerTarget{
+		handleEvent: handleEvent,
+	}
+}
+
+func (t *HandlerTarget) Describe(n *Notification) string {
+	return fmt.Sprintf("HandleEvent: %s", describe(n))
+}
+
+func (t *HandlerTarget) Replay(ctx context.Context, n *Notification) error {
+	if n.MessageId != "" {
+		ctx = events.WithMessageId(ctx, n.MessageId)
+	}
+	subscription := n.Subscription
+	return t.handleEvent(ctx, &subscription, n.Event)
+}
@@ -0,0 +1,86 @@
+package replay
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golden-vcr/showtime/internal/events"
+	"github.com/nicklaw5/helix/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_WebhookTarget_Replay(t *testing.T) {
+	var gotHeader http.Header
+	var gotBody string
+	var verified bool
+	srv := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		body, _ := io.ReadAll(req.Body)
+		gotHeader = req.Header
+		gotBody = string(body)
+		verified = helix.VerifyEventSubNotification("webhook-secret", req.Header, gotBody)
+		res.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	now := time.Date(1997, 9, 1, 12, 0, 0, 0, time.UTC)
+	target := NewWebhookTarget(srv.URL, "webhook-secret")
+	target.now = func() time.Time { return now }
+	target.newMessageId = func() string { return "new-message-id" }
+
+	err := target.Replay(context.Background(), &Notification{
+		MessageId:        "original-message-id",
+		MessageTimestamp: now.Add(-24 * time.Hour),
+		Subscription:     helix.EventSubSubscription{Type: "channel.cheer", Version: "1"},
+		Event:            json.RawMessage(`{"bits":100}`),
+	})
+	assert.NoError(t, err)
+	assert.True(t, verified)
+	assert.Equal(t, "new-message-id", gotHeader.Get(events.HeaderMessageId))
+	assert.Equal(t, "1997-09-01T12:00:00Z", gotHeader.Get(events.HeaderMessageTimestamp))
+	assert.Equal(t, events.MessageTypeNotification, gotHeader.Get(events.HeaderMessageType))
+
+	var payload struct {
+		Subscription helix.EventSubSubscription `json:"subscription"`
+		Event        json.RawMessage            `json:"event"`
+	}
+	assert.NoError(t, json.Unmarshal([]byte(gotBody), &payload))
+	assert.Equal(t, "channel.cheer", payload.Subscription.Type)
+	assert.Equal(t, `{"bits":100}`, string(payload.Event))
+}
+
+func Test_WebhookTarget_Replay_error(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		http.Error(res, "Message is too old", http.StatusBadRequest)
+	}))
+	defer srv.Close()
+
+	target := NewWebhookTarget(srv.URL, "webhook-secret")
+	err := target.Replay(context.Background(), &Notification{
+		Subscription: helix.EventSubSubscription{Type: "channel.cheer"},
+		Event:        json.RawMessage(`{}`),
+	})
+	assert.EqualError(t, err, "got response 400: Message is too old")
+}
+
+func Test_HandlerTarget_Replay(t *testing.T) {
+	var gotSubscription *helix.EventSubSubscription
+	var gotData json.RawMessage
+	target := NewHandlerTarget(func(ctx context.Context, subscription *helix.EventSubSubscription, data json.RawMessage) error {
+		gotSubscription = subscription
+		gotData = data
+		return nil
+	})
+	err := target.Replay(context.Background(), &Notification{
+		MessageId:    "message-1",
+		Subscription: helix.EventSubSubscription{Type: "channel.cheer"},
+		Event:        json.RawMessage(`{"bits":100}`),
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "channel.cheer", gotSubscription.Type)
+	assert.Equal(t, `{"bits":100}`, string(gotData))
+}
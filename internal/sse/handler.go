@@ -15,6 +15,18 @@ type Handler[T any] struct {
 	b   bus[T]
 
 	OnConnectEventFunc func() T
+
+	// EventIdFunc, if set, resolves the ID of each message, which is sent to the client
+	// in the event's 'id' field: browsers send the ID of the last event they received
+	// in the Last-Event-ID header when they reconnect. Messages with an empty ID are
+	// sent without one.
+	EventIdFunc func(message T) string
+
+	// ReplayFunc, if set, is called when a client connects with a Last-Event-ID header
+	// (or a 'lastEventId' query parameter, for clients that can't set headers), and
+	// returns all messages following that event, which are sent to the client before
+	// any new messages
+	ReplayFunc func(ctx context.Context, lastEventId string) ([]T, error)
 }
 
 // NewHandler initializes an SSE handler that will read messages from the given channel
@@ -53,6 +65,12 @@ func (h *Handler[T]) ServeHTTP(res http.ResponseWriter, req *http.Request) {
 		return
 	}
 
+	// Open a channel to receive message structs (i.e. any JSON-serializable value that
+	// we want to send over our stream) as they're emitted: we register it before
+	// replaying any missed messages so that nothing emitted in the meantime is lost
+	ch := make(chan T, 32)
+	h.b.register(ch)
+
 	// Keep the connection alive and open a text/event-stream response body
 	res.Header().Set("content-type", "text/event-stream")
 	res.Header().Set("cache-control", "no-cache")
@@ -78,10 +96,9 @@ func (h *Handler[T]) ServeHTTP(res http.ResponseWriter, req *http.Request) {
 		res.(http.Flusher).Flush()
 	}
 
-	// Open a channel to receive message structs (i.e. any JSON-serializable value that
-	// we want to send over our stream) as they're emitted
-	ch := make(chan T, 32)
-	h.b.register(ch)
+	// If the client is reconnecting, send it any messages that it missed, taking note
+	// of their IDs so that we don't send the same message twice
+	replayedIds := h.replay(res, req)
 
 	// Send all incoming messages to the client for as long as the connection is open
 	fmt.Printf("Opened SSE connection to %s...\n", req.RemoteAddr)
@@ -91,13 +108,12 @@ func (h *Handler[T]) ServeHTTP(res http.ResponseWriter, req *http.Request) {
 			res.Write([]byte(":\n\n"))
 			res.(http.Flusher).Flush()
 		case message := <-ch:
-			data, err := json.Marshal(message)
-			if err != nil {
-				fmt.Printf("Failed to serialize SSE message as JSON: %v\n", err)
-				continue
+			if len(replayedIds) > 0 {
+				if _, ok := replayedIds[h.EventIdFunc(message)]; ok {
+					continue
+				}
 			}
-			fmt.Fprintf(res, "data: %s\n\n", data)
-			res.(http.Flusher).Flush()
+			h.writeMessage(res, message)
 		case <-h.ctx.Done():
 			fmt.Printf("Server is shutting down; abandoning SSE connection to %s.\n", req.RemoteAddr)
 			h.b.unregister(ch)
@@ -109,3 +125,51 @@ func (h *Handler[T]) ServeHTTP(res http.ResponseWriter, req *http.Request) {
 		}
 	}
 }
+
+// replay writes all messages that a reconnecting client has missed, if the handler
+// supports replay and the client supplied the ID of the last event it received,
+// returning the set of IDs that were sent
+func (h *Handler[T]) replay(res http.ResponseWriter, req *http.Request) map[string]struct{} {
+	if h.ReplayFunc == nil || h.EventIdFunc == nil {
+		return nil
+	}
+	lastEventId := req.Header.Get("last-event-id")
+	if lastEventId == "" {
+		lastEventId = req.URL.Query().Get("lastEventId")
+	}
+	if lastEventId == "" {
+		return nil
+	}
+
+	messages, err := h.ReplayFunc(req.Context(), lastEventId)
+	if err != nil {
+		fmt.Printf("Failed to replay SSE messages after event %s for %s: %v\n", lastEventId, req.RemoteAddr, err)
+		return nil
+	}
+	replayedIds := make(map[string]struct{}, len(messages))
+	for _, message := range messages {
+		replayedIds[h.EventIdFunc(message)] = struct{}{}
+		h.writeMessage(res, message)
+	}
+	if len(messages) > 0 {
+		fmt.Printf("Replayed %d SSE message(s) after event %s for %s.\n", len(messages), lastEventId, req.RemoteAddr)
+	}
+	return replayedIds
+}
+
+// writeMessage writes a single message to the response body as a text/event-stream
+// event, with its ID (if applicable) and its JSON-encoded payload as 'data'
+func (h *Handler[T]) writeMessage(res http.ResponseWriter, message T) {
+	data, err := json.Marshal(message)
+	if err != nil {
+		fmt.Printf("Failed to serialize SSE message as JSON: %v\n", err)
+		return
+	}
+	if h.EventIdFunc != nil {
+		if id := h.EventIdFunc(message); id != "" {
+			fmt.Fprintf(res, "id: %s\n", id)
+		}
+	}
+	fmt.Fprintf(res, "data: %s\n\n", data)
+	res.(http.Flusher).Flush()
+}
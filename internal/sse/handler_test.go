@@ -2,6 +2,7 @@ package sse
 
 import (
 	"context"
+	"fmt"
 	"io"
 	"net/http"
 	"net/http/httptest"
@@ -109,6 +110,51 @@ func Test_Handler(t *testing.T) {
 		assert.NoError(t, err)
 		assert.Equal(t, ":\n\ndata: {\"x\":222,\"y\":0}\n\n", string(body))
 	})
+	t.Run("reconnecting clients are sent the messages they missed, with IDs", func(t *testing.T) {
+		coords := make(chan coordinate, 32)
+		h := NewHandler[coordinate](context.Background(), coords)
+		h.EventIdFunc = func(c coordinate) string {
+			return fmt.Sprintf("%d", c.Y)
+		}
+		h.ReplayFunc = func(ctx context.Context, lastEventId string) ([]coordinate, error) {
+			assert.Equal(t, "1", lastEventId)
+			return []coordinate{{200, 2}, {300, 3}}, nil
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		req := httptest.NewRequest(http.MethodGet, "/", nil).WithContext(ctx)
+		req.Header.Set("last-event-id", "1")
+		res := httptest.NewRecorder()
+		go h.ServeHTTP(res, req)
+		waitForResponseSubstring(t, res, `"x":300`)
+
+		// A message that was already replayed should not be sent again
+		coords <- coordinate{300, 3}
+		coords <- coordinate{400, 4}
+		waitForResponseSubstring(t, res, `"x":400`)
+
+		body, err := io.ReadAll(res.Body)
+		assert.NoError(t, err)
+		assert.Equal(t, ":\n\nid: 2\ndata: {\"x\":200,\"y\":2}\n\nid: 3\ndata: {\"x\":300,\"y\":3}\n\nid: 4\ndata: {\"x\":400,\"y\":4}\n\n", string(body))
+	})
+	t.Run("lastEventId may be supplied as a query parameter", func(t *testing.T) {
+		h := NewHandler[coordinate](context.Background(), make(chan coordinate))
+		h.EventIdFunc = func(c coordinate) string {
+			return fmt.Sprintf("%d", c.Y)
+		}
+		h.ReplayFunc = func(ctx context.Context, lastEventId string) ([]coordinate, error) {
+			assert.Equal(t, "7", lastEventId)
+			return []coordinate{{800, 8}}, nil
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		req := httptest.NewRequest(http.MethodGet, "/?lastEventId=7", nil).WithContext(ctx)
+		res := httptest.NewRecorder()
+		go h.ServeHTTP(res, req)
+		waitForResponseSubstring(t, res, "id: 8\ndata: {\"x\":800,\"y\":8}\n\n")
+	})
 }
 
 type coordinate struct {
@@ -14,14 +14,15 @@ import (
 	"github.com/pkg/browser"
 )
 
-const TwitchAuthorizeUrl = "https://id.twitch.tv/oauth2/authorize"
-
 // AuthorizationCode is the code returned to us from the Twitch API after the user
 // grants access to our app in the browser
 // See: https://dev.twitch.tv/docs/authentication/getting-tokens-oauth/#authorization-code-grant-flow
 type AuthorizationCode struct {
 	Value  string
 	Scopes []string
+	// RedirectUri is the URI that Twitch redirected the user to with the code: it must
+	// be supplied again when exchanging the code for a token
+	RedirectUri string
 }
 
 // PromptForCodeGrant spins up a small HTTP server on http://localhost:<port>, then
@@ -30,7 +31,7 @@ type AuthorizationCode struct {
 // them back to that server so that we can capture and parse the access code. The
 // Twitch App must be configured with 'http://localhost:<port>/auth' as a valid
 // redirect URI.
-func PromptForCodeGrant(ctx context.Context, twitchAppClientId string, scopes []string, port uint16) (*AuthorizationCode, error) {
+func PromptForCodeGrant(ctx context.Context, endpoints Endpoints, twitchAppClientId string, scopes []string, port uint16) (*AuthorizationCode, error) {
 	// We'll run a tiny in-memory HTTP server so that the Twitch OAuth flow has
 	// something to redirect to
 	callbackUrl := fmt.Sprintf("http://localhost:%d/auth", port)
@@ -62,16 +63,17 @@ func PromptForCodeGrant(ctx context.Context, twitchAppClientId string, scopes []
 			errorChannel <- err
 			return
 		}
-		servePage(res, http.StatusOK, "Authentication OK", fmt.Sprintf("A Twitch user access token has been granted. (%v)", code))
+		code.RedirectUri = callbackUrl
+		servePage(res, http.StatusOK, "Authentication OK", fmt.Sprintf("Access has been granted with scopes: %s", strings.Join(code.Scopes, ", ")))
 		codeChannel <- code
 	}
 
 	// Prepare a URL to a Twitch OAuth page that will request that the user authorize
 	// our app to access their account with the given scopes, then redirect them back
 	// to the callback URL that we specify
-	authorizeUrl, err := url.Parse(TwitchAuthorizeUrl)
+	authorizeUrl, err := url.Parse(endpoints.AuthUrl + "/authorize")
 	if err != nil {
-		log.Fatalf("failed to parse URL from %q: %v", TwitchAuthorizeUrl, err)
+		return nil, fmt.Errorf("failed to parse authorize URL from %q: %w", endpoints.AuthUrl, err)
 	}
 	q := authorizeUrl.Query()
 	q.Add("response_type", "code")
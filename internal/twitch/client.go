@@ -1,29 +1,329 @@
 package twitch
 
 import (
+	"context"
 	"fmt"
 	"net/http"
+	"strconv"
+	"sync"
+	"time"
 
 	"github.com/nicklaw5/helix/v2"
 )
 
-func NewClientWithAppToken(clientId string, clientSecret string) (*helix.Client, error) {
+// AppTokenRefreshMargin is how long before an app access token expires that Client
+// will request a new one
+const AppTokenRefreshMargin = time.Hour
+
+// MaxRateLimitRetries is the number of times Client will retry a request that's
+// rejected with a 429 before giving up and returning the 429 response
+const MaxRateLimitRetries = 3
+
+// MaxRateLimitWait is the longest Client will wait for its rate limit to reset before
+// making a request: Twitch refills its token bucket every minute, so a longer wait
+// implies that the reset time we've been given is wrong
+const MaxRateLimitWait = time.Minute
+
+// Client is a Twitch API client that authenticates with either an app access token or
+// a user access token. Unlike a bare helix.Client, it observes the Ratelimit-Remaining
+// and Ratelimit-Reset headers, waiting for the rate limit to reset (and backing off and
+// retrying on 429) rather than failing. When using an app access token, it also
+// requests a new token when the current one is due to expire or is rejected with a
+// 401. Client implements all of the narrow interfaces declared in types.go, so callers
+// should accept one of those interfaces instead.
+type Client struct {
+	c         *helix.Client
+	tokens    AppTokenRequester
+	validator TokenValidator
+	now       func() time.Time
+	sleep     func(d time.Duration)
+
+	// tokenMu guards our access token along with the helix.Client's copy of it: since
+	// helix reads its token without synchronization, each API call holds a read lock
+	// for its duration, and the token is only replaced while holding the write lock
+	tokenMu        sync.RWMutex
+	appToken       string
+	tokenExpiresAt time.Time
+
+	rateLimitMu      sync.Mutex
+	rateLimitResetAt time.Time
+}
+
+// NewClientWithAppToken initializes a Client with an app access token, obtained using
+// our client ID and secret
+func NewClientWithAppToken(endpoints Endpoints, clientId string, clientSecret string) (*Client, error) {
 	c, err := helix.NewClient(&helix.Options{
 		ClientID:     clientId,
 		ClientSecret: clientSecret,
+		APIBaseURL:   endpoints.ApiUrl,
 	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to initialize Twitch API client: %w", err)
 	}
+	oauth := NewOAuthClient(endpoints, clientId, clientSecret)
+	return newClient(c, oauth, oauth)
+}
 
-	res, err := c.RequestAppAccessToken(nil)
-	if err == nil && res.StatusCode != http.StatusOK {
-		err = fmt.Errorf("got status %d: %s", res.StatusCode, res.ErrorMessage)
+func newClient(c *helix.Client, tokens AppTokenRequester, validator TokenValidator) (*Client, error) {
+	client := &Client{
+		c:         c,
+		tokens:    tokens,
+		validator: validator,
+		now:       time.Now,
+		sleep:     time.Sleep,
 	}
-	if err != nil {
+	if err := client.refreshAppToken(""); err != nil {
 		return nil, fmt.Errorf("failed to get app access token from Twitch API: %w", err)
 	}
+	return client, nil
+}
+
+func (c *Client) GetUsers(params *helix.UsersParams) (*helix.UsersResponse, error) {
+	var res *helix.UsersResponse
+	err := c.do(func() (*helix.ResponseCommon, error) {
+		var err error
+		res, err = c.c.GetUsers(params)
+		if err != nil {
+			return nil, err
+		}
+		return &res.ResponseCommon, nil
+	})
+	return res, err
+}
+
+func (c *Client) GetEventSubSubscriptions(params *helix.EventSubSubscriptionsParams) (*helix.EventSubSubscriptionsResponse, error) {
+	var res *helix.EventSubSubscriptionsResponse
+	err := c.do(func() (*helix.ResponseCommon, error) {
+		var err error
+		res, err = c.c.GetEventSubSubscriptions(params)
+		if err != nil {
+			return nil, err
+		}
+		return &res.ResponseCommon, nil
+	})
+	return res, err
+}
+
+func (c *Client) CreateEventSubSubscription(payload *helix.EventSubSubscription) (*helix.EventSubSubscriptionsResponse, error) {
+	var res *helix.EventSubSubscriptionsResponse
+	err := c.do(func() (*helix.ResponseCommon, error) {
+		var err error
+		res, err = c.c.CreateEventSubSubscription(payload)
+		if err != nil {
+			return nil, err
+		}
+		return &res.ResponseCommon, nil
+	})
+	return res, err
+}
+
+func (c *Client) RemoveEventSubSubscription(id string) (*helix.RemoveEventSubSubscriptionParamsResponse, error) {
+	var res *helix.RemoveEventSubSubscriptionParamsResponse
+	err := c.do(func() (*helix.ResponseCommon, error) {
+		var err error
+		res, err = c.c.RemoveEventSubSubscription(id)
+		if err != nil {
+			return nil, err
+		}
+		return &res.ResponseCommon, nil
+	})
+	return res, err
+}
+
+func (c *Client) UpdateChannelCustomRewardsRedemptionStatus(params *helix.UpdateChannelCustomRewardsRedemptionStatusParams) (*helix.ChannelCustomRewardsRedemptionResponse, error) {
+	var res *helix.ChannelCustomRewardsRedemptionResponse
+	err := c.do(func() (*helix.ResponseCommon, error) {
+		var err error
+		res, err = c.c.UpdateChannelCustomRewardsRedemptionStatus(params)
+		if err != nil {
+			return nil, err
+		}
+		return &res.ResponseCommon, nil
+	})
+	return res, err
+}
+
+func (c *Client) GetCheermotes(params *helix.CheermotesParams) (*helix.CheermotesResponse, error) {
+	var res *helix.CheermotesResponse
+	err := c.do(func() (*helix.ResponseCommon, error) {
+		var err error
+		res, err = c.c.GetCheermotes(params)
+		if err != nil {
+			return nil, err
+		}
+		return &res.ResponseCommon, nil
+	})
+	return res, err
+}
+
+// ValidateToken checks the validity of an arbitrary access token: it doesn't use our
+// app access token and isn't subject to the Helix API rate limit
+func (c *Client) ValidateToken(accessToken string) (bool, *helix.ValidateTokenResponse, error) {
+	return c.validator.ValidateToken(accessToken)
+}
+
+// SetUserAccessToken replaces the user access token used by a Client that was
+// initialized with NewClientWithUserToken, e.g. once the token has been refreshed
+func (c *Client) SetUserAccessToken(accessToken string) {
+	c.tokenMu.Lock()
+	defer c.tokenMu.Unlock()
+	c.c.SetUserAccessToken(accessToken)
+}
 
-	c.SetAppAccessToken(res.Data.AccessToken)
-	return c, nil
+// do makes an API request via the given function, ensuring that we have a valid app
+// access token (if we're using one) and that we stay within the rate limit
+func (c *Client) do(call func() (*helix.ResponseCommon, error)) error {
+	didRefresh := false
+	numRetries := 0
+	for {
+		// Make sure our app access token isn't about to expire, and wait for the rate
+		// limit to reset if we've used up all our requests
+		token, err := c.ensureAppToken()
+		if err != nil {
+			return err
+		}
+		c.waitForRateLimit()
+
+		c.tokenMu.RLock()
+		res, err := call()
+		c.tokenMu.RUnlock()
+		if err != nil {
+			return err
+		}
+		c.recordRateLimit(res)
+
+		// If Twitch rejects our app access token (e.g. because it's been revoked), get
+		// a new one and try again, but only once: user access tokens are refreshed by
+		// UserTokenManager instead
+		if res.StatusCode == http.StatusUnauthorized && c.tokens != nil && !didRefresh {
+			didRefresh = true
+			if err := c.refreshAppToken(token); err != nil {
+				return fmt.Errorf("failed to refresh app access token after 401: %w", err)
+			}
+			continue
+		}
+
+		// If we've exceeded the rate limit, back off and try again: recordRateLimit will
+		// have recorded the reset time if Twitch gave us one
+		if res.StatusCode == http.StatusTooManyRequests && numRetries < MaxRateLimitRetries {
+			numRetries++
+			c.backOff(numRetries)
+			continue
+		}
+		return nil
+	}
+}
+
+// ensureAppToken returns our current app access token, requesting a new one first if
+// it's due to expire, or an empty string if we're using a user access token
+func (c *Client) ensureAppToken() (string, error) {
+	if c.tokens == nil {
+		return "", nil
+	}
+
+	c.tokenMu.RLock()
+	token := c.appToken
+	expiresAt := c.tokenExpiresAt
+	c.tokenMu.RUnlock()
+
+	if c.now().Before(expiresAt.Add(-AppTokenRefreshMargin)) {
+		return token, nil
+	}
+	if err := c.refreshAppToken(token); err != nil {
+		return "", fmt.Errorf("failed to refresh app access token: %w", err)
+	}
+
+	c.tokenMu.RLock()
+	defer c.tokenMu.RUnlock()
+	return c.appToken, nil
+}
+
+// refreshAppToken requests a new app access token, unless the current token has
+// already been replaced by a token other than the stale one
+func (c *Client) refreshAppToken(staleToken string) error {
+	c.tokenMu.Lock()
+	defer c.tokenMu.Unlock()
+
+	if c.appToken != staleToken && c.now().Before(c.tokenExpiresAt.Add(-AppTokenRefreshMargin)) {
+		return nil
+	}
+	token, err := c.tokens.RequestAppToken(context.Background())
+	if err != nil {
+		return err
+	}
+	c.appToken = token.AccessToken
+	c.c.SetAppAccessToken(token.AccessToken)
+	c.tokenExpiresAt = token.ExpiresAt
+	return nil
+}
+
+// recordRateLimit notes when the rate limit will reset if the response indicates that
+// we have no requests remaining
+func (c *Client) recordRateLimit(res *helix.ResponseCommon) {
+	remaining := res.Header.Get("Ratelimit-Remaining")
+	reset := res.Header.Get("Ratelimit-Reset")
+	if remaining == "" || reset == "" {
+		return
+	}
+	numRemaining, err := strconv.Atoi(remaining)
+	if err != nil || (numRemaining > 0 && res.StatusCode != http.StatusTooManyRequests) {
+		return
+	}
+	resetUnix, err := strconv.ParseInt(reset, 10, 64)
+	if err != nil {
+		return
+	}
+	c.rateLimitMu.Lock()
+	c.rateLimitResetAt = time.Unix(resetUnix, 0)
+	c.rateLimitMu.Unlock()
+}
+
+// waitForRateLimit blocks until the rate limit resets, if we've run out of requests
+func (c *Client) waitForRateLimit() {
+	c.rateLimitMu.Lock()
+	resetAt := c.rateLimitResetAt
+	c.rateLimitResetAt = time.Time{}
+	c.rateLimitMu.Unlock()
+
+	if resetAt.IsZero() {
+		return
+	}
+	if wait := resetAt.Sub(c.now()); wait > 0 {
+		if wait > MaxRateLimitWait {
+			wait = MaxRateLimitWait
+		}
+		c.sleep(wait)
+	}
+}
+
+// backOff waits before retrying a request that was rejected with a 429: if we know when
+// the rate limit will reset, waitForRateLimit will wait until then, so we only need to
+// back off exponentially if Twitch didn't tell us
+func (c *Client) backOff(numRetries int) {
+	c.rateLimitMu.Lock()
+	knowsReset := !c.rateLimitResetAt.IsZero()
+	c.rateLimitMu.Unlock()
+	if !knowsReset {
+		c.sleep(time.Duration(1<<(numRetries-1)) * time.Second)
+	}
+}
+
+// NewClientWithUserToken initializes a Client that authenticates using the given user
+// access token. EventSub subscriptions that use the WebSocket transport may only be
+// created and viewed with a user access token.
+func NewClientWithUserToken(endpoints Endpoints, clientId string, userAccessToken string) (*Client, error) {
+	c, err := helix.NewClient(&helix.Options{
+		ClientID:        clientId,
+		UserAccessToken: userAccessToken,
+		APIBaseURL:      endpoints.ApiUrl,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize Twitch API client: %w", err)
+	}
+	return &Client{
+		c:         c,
+		validator: NewOAuthClient(endpoints, clientId, ""),
+		now:       time.Now,
+		sleep:     time.Sleep,
+	}, nil
 }
@@ -0,0 +1,317 @@
+package twitch
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/nicklaw5/helix/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Client_AppToken(t *testing.T) {
+	now := time.Date(1997, 9, 1, 12, 0, 0, 0, time.UTC)
+	api := newFakeHelixServer(t)
+	defer api.Close()
+	tokens := &mockAppTokenRequester{now: func() time.Time { return now }}
+	c := api.newClient(tokens, func() time.Time { return now }, nil)
+
+	// A token should be requested when the client is initialized
+	assert.Equal(t, 1, tokens.numRequested)
+	api.validToken = "app-1"
+	res, err := c.GetUsers(&helix.UsersParams{Logins: []string{"goldenvcr"}})
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Equal(t, 1, tokens.numRequested)
+
+	// If Twitch rejects our token, we should get a new one and retry
+	api.validToken = "app-2"
+	res, err = c.GetUsers(&helix.UsersParams{Logins: []string{"goldenvcr"}})
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Equal(t, 2, tokens.numRequested)
+	assert.Equal(t, []string{"app-1", "app-1", "app-2"}, api.gotTokens)
+
+	// If the new token is rejected as well, we should give up and return the 401
+	api.validToken = "nope"
+	res, err = c.GetUsers(&helix.UsersParams{Logins: []string{"goldenvcr"}})
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusUnauthorized, res.StatusCode)
+	assert.Equal(t, 3, tokens.numRequested)
+
+	// Once our token is about to expire, we should get a new one before it's rejected
+	api.validToken = "app-4"
+	now = now.Add(tokenLifetime - AppTokenRefreshMargin)
+	api.gotTokens = nil
+	res, err = c.GetUsers(&helix.UsersParams{Logins: []string{"goldenvcr"}})
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Equal(t, 4, tokens.numRequested)
+	assert.Equal(t, []string{"app-4"}, api.gotTokens)
+}
+
+func Test_Client_RateLimit(t *testing.T) {
+	now := time.Date(1997, 9, 1, 12, 0, 0, 0, time.UTC)
+	resetAt := now.Add(30 * time.Second)
+
+	tests := []struct {
+		name        string
+		responses   []fakeHelixResponse
+		wantStatus  int
+		wantSleeps  []time.Duration
+		wantNumReqs int
+	}{
+		{
+			"requests are made immediately while the rate limit has not been exhausted",
+			[]fakeHelixResponse{
+				{http.StatusOK, 799, resetAt},
+				{http.StatusOK, 798, resetAt},
+			},
+			http.StatusOK,
+			[]time.Duration{},
+			2,
+		},
+		{
+			"once no requests are remaining, we wait for the rate limit to reset",
+			[]fakeHelixResponse{
+				{http.StatusOK, 0, resetAt},
+				{http.StatusOK, 799, resetAt.Add(time.Minute)},
+			},
+			http.StatusOK,
+			[]time.Duration{30 * time.Second},
+			2,
+		},
+		{
+			"a 429 is retried once the rate limit resets",
+			[]fakeHelixResponse{
+				{http.StatusOK, 10, resetAt},
+				{http.StatusTooManyRequests, 0, resetAt},
+				{http.StatusOK, 799, resetAt.Add(time.Minute)},
+			},
+			http.StatusOK,
+			[]time.Duration{30 * time.Second},
+			3,
+		},
+		{
+			"a 429 with no rate limit headers is retried with exponential backoff",
+			[]fakeHelixResponse{
+				{http.StatusOK, 10, resetAt},
+				{http.StatusTooManyRequests, -1, time.Time{}},
+				{http.StatusTooManyRequests, -1, time.Time{}},
+				{http.StatusTooManyRequests, -1, time.Time{}},
+				{http.StatusTooManyRequests, -1, time.Time{}},
+			},
+			http.StatusTooManyRequests,
+			[]time.Duration{time.Second, 2 * time.Second, 4 * time.Second},
+			5,
+		},
+		{
+			"an implausible reset time is capped",
+			[]fakeHelixResponse{
+				{http.StatusOK, 0, resetAt.Add(time.Hour)},
+				{http.StatusOK, 799, resetAt},
+			},
+			http.StatusOK,
+			[]time.Duration{MaxRateLimitWait},
+			2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			api := newFakeHelixServer(t)
+			defer api.Close()
+			api.validToken = "app-1"
+			api.responses = tt.responses
+
+			sleeps := make([]time.Duration, 0)
+			tokens := &mockAppTokenRequester{now: func() time.Time { return now }}
+			c := api.newClient(tokens, func() time.Time { return now }, func(d time.Duration) {
+				sleeps = append(sleeps, d)
+			})
+
+			// The first request primes our rate limit state; the second may need to wait
+			res, err := c.GetUsers(&helix.UsersParams{Logins: []string{"goldenvcr"}})
+			assert.NoError(t, err)
+			assert.Equal(t, tt.responses[0].statusCode, res.StatusCode)
+			res, err = c.GetUsers(&helix.UsersParams{Logins: []string{"goldenvcr"}})
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantStatus, res.StatusCode)
+			assert.Equal(t, tt.wantSleeps, sleeps)
+			assert.Equal(t, tt.wantNumReqs, len(api.gotTokens))
+		})
+	}
+}
+
+func Test_Client_AppToken_concurrent(t *testing.T) {
+	api := newFakeHelixServer(t)
+	defer api.Close()
+	api.validToken = "app-1"
+
+	// Issue tokens that are already due to expire, so that every request replaces our
+	// app access token while other requests are in flight: this test is only useful
+	// when run with -race
+	issuedAt := time.Date(1997, 9, 1, 12, 0, 0, 0, time.UTC)
+	tokens := &mockAppTokenRequester{now: func() time.Time { return issuedAt }}
+	c := api.newClient(tokens, func() time.Time { return issuedAt.Add(tokenLifetime) }, nil)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := c.GetUsers(&helix.UsersParams{Logins: []string{"goldenvcr"}})
+			assert.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+	assert.Greater(t, tokens.numRequested, 1)
+}
+
+func Test_Client_UserToken(t *testing.T) {
+	api := newFakeHelixServer(t)
+	defer api.Close()
+	api.validToken = "user-1"
+	api.responses = []fakeHelixResponse{
+		{http.StatusTooManyRequests, -1, time.Time{}},
+		{http.StatusOK, 799, time.Now().Add(time.Minute)},
+	}
+	c, err := NewClientWithUserToken(Endpoints{ApiUrl: api.URL}, "my-client-id", "user-1")
+	assert.NoError(t, err)
+	sleeps := make([]time.Duration, 0)
+	c.sleep = func(d time.Duration) { sleeps = append(sleeps, d) }
+
+	// Requests made with a user access token should be subject to the same rate limit
+	// handling as requests made with an app access token
+	res, err := c.GetUsers(&helix.UsersParams{})
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Equal(t, []time.Duration{time.Second}, sleeps)
+	assert.Equal(t, []string{"user-1", "user-1"}, api.gotTokens)
+
+	// If Twitch rejects our user access token, we can't get a new one ourselves, so we
+	// should return the 401 without retrying
+	api.validToken = "user-2"
+	api.gotTokens = nil
+	res, err = c.GetUsers(&helix.UsersParams{})
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusUnauthorized, res.StatusCode)
+	assert.Equal(t, []string{"user-1"}, api.gotTokens)
+
+	// Once the token has been refreshed, requests should use the new token
+	c.SetUserAccessToken("user-2")
+	api.gotTokens = nil
+	res, err = c.GetUsers(&helix.UsersParams{})
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Equal(t, []string{"user-2"}, api.gotTokens)
+}
+
+const tokenLifetime = 60 * 24 * time.Hour
+
+type mockAppTokenRequester struct {
+	now          func() time.Time
+	numRequested int
+}
+
+func (m *mockAppTokenRequester) RequestAppToken(ctx context.Context) (*AppToken, error) {
+	m.numRequested++
+	return &AppToken{
+		AccessToken: fmt.Sprintf("app-%d", m.numRequested),
+		ExpiresAt:   m.now().Add(tokenLifetime),
+	}, nil
+}
+
+type fakeHelixResponse struct {
+	statusCode int
+	remaining  int
+	resetAt    time.Time
+}
+
+// fakeHelixServer emulates the Twitch Helix API's GET /users endpoint, rejecting any
+// request that doesn't use validToken and otherwise returning the next of a scripted
+// series of responses
+type fakeHelixServer struct {
+	*httptest.Server
+	t          *testing.T
+	mu         sync.Mutex
+	validToken string
+	responses  []fakeHelixResponse
+	gotTokens  []string
+}
+
+func newFakeHelixServer(t *testing.T) *fakeHelixServer {
+	s := &fakeHelixServer{t: t}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.handle))
+	return s
+}
+
+func (s *fakeHelixServer) newClient(tokens AppTokenRequester, now func() time.Time, sleep func(time.Duration)) *Client {
+	hc, err := helix.NewClient(&helix.Options{
+		ClientID:   "my-client-id",
+		APIBaseURL: s.URL,
+	})
+	assert.NoError(s.t, err)
+	c, err := newClient(hc, tokens, nil)
+	assert.NoError(s.t, err)
+	c.now = now
+	if sleep != nil {
+		c.sleep = sleep
+	}
+	return c
+}
+
+func (s *fakeHelixServer) handle(res http.ResponseWriter, req *http.Request) {
+	assert.Equal(s.t, "/users", req.URL.Path)
+	assert.Equal(s.t, "my-client-id", req.Header.Get("client-id"))
+	token := req.Header.Get("authorization")
+	if len(token) > len("Bearer ") {
+		token = token[len("Bearer "):]
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.gotTokens = append(s.gotTokens, token)
+
+	res.Header().Set("content-type", "application/json")
+	if token != s.validToken {
+		res.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(res).Encode(map[string]any{
+			"error":   "Unauthorized",
+			"status":  http.StatusUnauthorized,
+			"message": "Invalid OAuth token",
+		})
+		return
+	}
+
+	statusCode := http.StatusOK
+	if len(s.responses) > 0 {
+		r := s.responses[0]
+		s.responses = s.responses[1:]
+		statusCode = r.statusCode
+		if r.remaining >= 0 {
+			res.Header().Set("ratelimit-limit", "800")
+			res.Header().Set("ratelimit-remaining", strconv.Itoa(r.remaining))
+			res.Header().Set("ratelimit-reset", strconv.FormatInt(r.resetAt.Unix(), 10))
+		}
+	}
+	res.WriteHeader(statusCode)
+	if statusCode == http.StatusTooManyRequests {
+		json.NewEncoder(res).Encode(map[string]any{
+			"error":   "Too Many Requests",
+			"status":  http.StatusTooManyRequests,
+			"message": "Too Many Requests",
+		})
+		return
+	}
+	json.NewEncoder(res).Encode(map[string]any{
+		"data": []map[string]any{
+			{"id": "953753877", "login": "goldenvcr", "display_name": "GoldenVCR"},
+		},
+	})
+}
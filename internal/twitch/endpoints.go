@@ -0,0 +1,18 @@
+package twitch
+
+import "github.com/nicklaw5/helix/v2"
+
+// Endpoints identifies the base URLs of the Twitch APIs that we call, so that they can
+// be pointed at a fake Twitch server (see internal/twitchtest) for testing
+type Endpoints struct {
+	// ApiUrl is the base URL of the Helix API, e.g. 'https://api.twitch.tv/helix'
+	ApiUrl string
+	// AuthUrl is the base URL of the OAuth API, e.g. 'https://id.twitch.tv/oauth2'
+	AuthUrl string
+}
+
+// DefaultEndpoints identifies the real Twitch APIs
+var DefaultEndpoints = Endpoints{
+	ApiUrl:  helix.DefaultAPIBaseURL,
+	AuthUrl: helix.AuthBaseURL,
+}
@@ -0,0 +1,200 @@
+package twitch
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/nicklaw5/helix/v2"
+)
+
+// UserToken is an OAuth user access token that a Twitch user has granted to our app,
+// along with the refresh token that can be used to obtain a new access token once it
+// expires
+type UserToken struct {
+	UserId       string
+	AccessToken  string
+	RefreshToken string
+	Scopes       []string
+	ExpiresAt    time.Time
+}
+
+// AppToken is an OAuth app access token, which authorizes requests made on behalf of
+// our app rather than any particular user
+type AppToken struct {
+	AccessToken string
+	ExpiresAt   time.Time
+}
+
+// AppTokenRequester represents the subset of Twitch OAuth operations required to
+// obtain app access tokens
+type AppTokenRequester interface {
+	RequestAppToken(ctx context.Context) (*AppToken, error)
+}
+
+// TokenRefresher represents the subset of Twitch OAuth operations required to keep a
+// user access token from expiring
+type TokenRefresher interface {
+	RefreshUserToken(ctx context.Context, refreshToken string) (*UserToken, error)
+}
+
+// OAuthClient makes requests to the Twitch OAuth token endpoint in order to obtain app
+// access tokens, to exchange authorization codes for user access tokens, and to
+// refresh those user access tokens
+// See: https://dev.twitch.tv/docs/authentication/refresh-tokens/
+type OAuthClient struct {
+	clientId     string
+	clientSecret string
+	authUrl      string
+	httpClient   *http.Client
+	now          func() time.Time
+}
+
+func NewOAuthClient(endpoints Endpoints, clientId string, clientSecret string) *OAuthClient {
+	return &OAuthClient{
+		clientId:     clientId,
+		clientSecret: clientSecret,
+		authUrl:      endpoints.AuthUrl,
+		httpClient:   http.DefaultClient,
+		now:          time.Now,
+	}
+}
+
+// ExchangeCode exchanges an authorization code, obtained via PromptForCodeGrant, for
+// a user access token and refresh token. The UserId of the resulting token is not
+// populated, since Twitch does not tell us which user granted it: ValidateUserToken
+// should be used to verify that the token belongs to the expected user.
+func (c *OAuthClient) ExchangeCode(ctx context.Context, code *AuthorizationCode) (*UserToken, error) {
+	return c.requestToken(ctx, url.Values{
+		"client_id":     {c.clientId},
+		"client_secret": {c.clientSecret},
+		"code":          {code.Value},
+		"grant_type":    {"authorization_code"},
+		"redirect_uri":  {code.RedirectUri},
+	})
+}
+
+// RefreshUserToken obtains a new user access token using the given refresh token. The
+// refresh token itself may also be replaced, so the caller should store the entire
+// result.
+func (c *OAuthClient) RefreshUserToken(ctx context.Context, refreshToken string) (*UserToken, error) {
+	return c.requestToken(ctx, url.Values{
+		"client_id":     {c.clientId},
+		"client_secret": {c.clientSecret},
+		"grant_type":    {"refresh_token"},
+		"refresh_token": {refreshToken},
+	})
+}
+
+// RequestAppToken obtains a new app access token via the client credentials grant flow
+// See: https://dev.twitch.tv/docs/authentication/getting-tokens-oauth/#client-credentials-grant-flow
+func (c *OAuthClient) RequestAppToken(ctx context.Context) (*AppToken, error) {
+	result, requestedAt, err := c.postToken(ctx, url.Values{
+		"client_id":     {c.clientId},
+		"client_secret": {c.clientSecret},
+		"grant_type":    {"client_credentials"},
+	})
+	if err != nil {
+		return nil, err
+	}
+	if result.AccessToken == "" {
+		return nil, fmt.Errorf("token response did not include an access token")
+	}
+	return &AppToken{
+		AccessToken: result.AccessToken,
+		ExpiresAt:   requestedAt.Add(time.Duration(result.ExpiresIn) * time.Second),
+	}, nil
+}
+
+func (c *OAuthClient) requestToken(ctx context.Context, values url.Values) (*UserToken, error) {
+	result, requestedAt, err := c.postToken(ctx, values)
+	if err != nil {
+		return nil, err
+	}
+	if result.AccessToken == "" || result.RefreshToken == "" {
+		return nil, fmt.Errorf("token response did not include an access token and refresh token")
+	}
+	return &UserToken{
+		AccessToken:  result.AccessToken,
+		RefreshToken: result.RefreshToken,
+		Scopes:       result.Scopes,
+		ExpiresAt:    requestedAt.Add(time.Duration(result.ExpiresIn) * time.Second),
+	}, nil
+}
+
+type tokenResponse struct {
+	AccessToken  string   `json:"access_token"`
+	RefreshToken string   `json:"refresh_token"`
+	ExpiresIn    int      `json:"expires_in"`
+	Scopes       []string `json:"scope"`
+}
+
+func (c *OAuthClient) postToken(ctx context.Context, values url.Values) (*tokenResponse, time.Time, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.authUrl+"/token", strings.NewReader(values.Encode()))
+	if err != nil {
+		return nil, time.Time{}, fmt.Errorf("error initializing HTTP request: %w", err)
+	}
+	req.Header.Set("content-type", "application/x-www-form-urlencoded")
+
+	requestedAt := c.now()
+	res, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, time.Time{}, fmt.Errorf("error sending token request: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		var failure struct {
+			Message string `json:"message"`
+		}
+		if err := json.NewDecoder(res.Body).Decode(&failure); err != nil || failure.Message == "" {
+			return nil, time.Time{}, fmt.Errorf("got response %d from token request", res.StatusCode)
+		}
+		return nil, time.Time{}, fmt.Errorf("got response %d from token request: %s", res.StatusCode, failure.Message)
+	}
+
+	var result tokenResponse
+	if err := json.NewDecoder(res.Body).Decode(&result); err != nil {
+		return nil, time.Time{}, fmt.Errorf("failed to decode token response: %w", err)
+	}
+	return &result, requestedAt, nil
+}
+
+// ValidateToken checks whether the given access token is valid, returning details
+// about the token if so. Its signature matches helix.Client.ValidateToken, so that
+// OAuthClient implements TokenValidator.
+// See: https://dev.twitch.tv/docs/authentication/validate-tokens/
+func (c *OAuthClient) ValidateToken(accessToken string) (bool, *helix.ValidateTokenResponse, error) {
+	req, err := http.NewRequest(http.MethodGet, c.authUrl+"/validate", nil)
+	if err != nil {
+		return false, nil, fmt.Errorf("error initializing HTTP request: %w", err)
+	}
+	req.Header.Set("authorization", "OAuth "+accessToken)
+
+	res, err := c.httpClient.Do(req)
+	if err != nil {
+		return false, nil, fmt.Errorf("error sending validate request: %w", err)
+	}
+	defer res.Body.Close()
+
+	result := &helix.ValidateTokenResponse{}
+	result.StatusCode = res.StatusCode
+	result.Header = res.Header
+	if res.StatusCode != http.StatusOK {
+		var failure struct {
+			Message string `json:"message"`
+		}
+		if err := json.NewDecoder(res.Body).Decode(&failure); err == nil {
+			result.ErrorMessage = failure.Message
+		}
+		return false, result, nil
+	}
+	if err := json.NewDecoder(res.Body).Decode(&result.Data); err != nil {
+		return false, nil, fmt.Errorf("failed to decode validate response: %w", err)
+	}
+	return true, result, nil
+}
@@ -0,0 +1,191 @@
+package twitch
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_OAuthClient_ExchangeCode(t *testing.T) {
+	tests := []struct {
+		name      string
+		code      string
+		want      *UserToken
+		wantErr   string
+		wantCalls int
+	}{
+		{
+			"valid code is exchanged for a token",
+			"good-code",
+			&UserToken{
+				AccessToken:  "access-1",
+				RefreshToken: "refresh-1",
+				Scopes:       []string{"bits:read", "channel:read:subscriptions"},
+				ExpiresAt:    time.Date(1997, 9, 1, 12, 0, 0, 0, time.UTC).Add(4 * time.Hour),
+			},
+			"",
+			1,
+		},
+		{
+			"invalid code results in an error",
+			"bad-code",
+			nil,
+			"got response 400 from token request: Invalid authorization code",
+			1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			oauth := newFakeOAuthServer(t)
+			defer oauth.Close()
+			c := oauth.newClient()
+			got, err := c.ExchangeCode(context.Background(), &AuthorizationCode{
+				Value:       tt.code,
+				Scopes:      []string{"bits:read", "channel:read:subscriptions"},
+				RedirectUri: "http://localhost:3033/auth",
+			})
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tt.want, got)
+			assert.Equal(t, tt.wantCalls, oauth.numRequests)
+		})
+	}
+}
+
+func Test_OAuthClient_RefreshUserToken(t *testing.T) {
+	oauth := newFakeOAuthServer(t)
+	defer oauth.Close()
+	c := oauth.newClient()
+
+	got, err := c.RefreshUserToken(context.Background(), "refresh-1")
+	assert.NoError(t, err)
+	assert.Equal(t, &UserToken{
+		AccessToken:  "access-2",
+		RefreshToken: "refresh-2",
+		Scopes:       []string{"bits:read", "channel:read:subscriptions"},
+		ExpiresAt:    time.Date(1997, 9, 1, 12, 0, 0, 0, time.UTC).Add(4 * time.Hour),
+	}, got)
+
+	// Twitch invalidates the old refresh token when it issues a new one
+	_, err = c.RefreshUserToken(context.Background(), "refresh-1")
+	assert.EqualError(t, err, "got response 400 from token request: Invalid refresh token")
+}
+
+func Test_OAuthClient_RequestAppToken(t *testing.T) {
+	oauth := newFakeOAuthServer(t)
+	defer oauth.Close()
+	c := oauth.newClient()
+
+	got, err := c.RequestAppToken(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, &AppToken{
+		AccessToken: "app-1",
+		ExpiresAt:   time.Date(1997, 9, 1, 12, 0, 0, 0, time.UTC).Add(60 * 24 * time.Hour),
+	}, got)
+
+	got, err = c.RequestAppToken(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, "app-2", got.AccessToken)
+
+	c.clientSecret = "wrong-secret"
+	_, err = c.RequestAppToken(context.Background())
+	assert.EqualError(t, err, "got response 400 from token request: Invalid client")
+}
+
+// fakeOAuthServer emulates the Twitch OAuth token endpoint: it accepts a single valid
+// authorization code, issues a new access token and refresh token each time a valid
+// refresh token is supplied, and issues a new app access token on every request
+type fakeOAuthServer struct {
+	*httptest.Server
+	t            *testing.T
+	numRequests  int
+	numIssued    int
+	numAppIssued int
+	refreshToken string
+}
+
+func newFakeOAuthServer(t *testing.T) *fakeOAuthServer {
+	s := &fakeOAuthServer{t: t}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.handleToken))
+	return s
+}
+
+func (s *fakeOAuthServer) newClient() *OAuthClient {
+	c := NewOAuthClient(Endpoints{AuthUrl: s.URL + "/oauth2"}, "my-client-id", "my-client-secret")
+	c.now = func() time.Time {
+		return time.Date(1997, 9, 1, 12, 0, 0, 0, time.UTC)
+	}
+	return c
+}
+
+func (s *fakeOAuthServer) handleToken(res http.ResponseWriter, req *http.Request) {
+	s.numRequests++
+	assert.Equal(s.t, http.MethodPost, req.Method)
+	assert.Equal(s.t, "/oauth2/token", req.URL.Path)
+	if err := req.ParseForm(); err != nil {
+		s.fail(res, "Invalid request")
+		return
+	}
+	if req.PostForm.Get("client_id") != "my-client-id" || req.PostForm.Get("client_secret") != "my-client-secret" {
+		s.fail(res, "Invalid client")
+		return
+	}
+
+	switch req.PostForm.Get("grant_type") {
+	case "client_credentials":
+		s.numAppIssued++
+		res.Header().Set("content-type", "application/json")
+		json.NewEncoder(res).Encode(map[string]any{
+			"access_token": fmt.Sprintf("app-%d", s.numAppIssued),
+			"expires_in":   60 * 24 * 60 * 60,
+			"token_type":   "bearer",
+		})
+		return
+	case "authorization_code":
+		if req.PostForm.Get("code") != "good-code" || req.PostForm.Get("redirect_uri") != "http://localhost:3033/auth" {
+			s.fail(res, "Invalid authorization code")
+			return
+		}
+	case "refresh_token":
+		if s.refreshToken == "" {
+			s.refreshToken = "refresh-1"
+			s.numIssued = 1
+		}
+		if req.PostForm.Get("refresh_token") != s.refreshToken {
+			s.fail(res, "Invalid refresh token")
+			return
+		}
+	default:
+		s.fail(res, "Invalid grant type")
+		return
+	}
+
+	s.numIssued++
+	s.refreshToken = fmt.Sprintf("refresh-%d", s.numIssued)
+	res.Header().Set("content-type", "application/json")
+	json.NewEncoder(res).Encode(map[string]any{
+		"access_token":  fmt.Sprintf("access-%d", s.numIssued),
+		"refresh_token": s.refreshToken,
+		"expires_in":    4 * 60 * 60,
+		"scope":         []string{"bits:read", "channel:read:subscriptions"},
+		"token_type":    "bearer",
+	})
+}
+
+func (s *fakeOAuthServer) fail(res http.ResponseWriter, message string) {
+	res.Header().Set("content-type", "application/json")
+	res.WriteHeader(http.StatusBadRequest)
+	json.NewEncoder(res).Encode(map[string]any{
+		"status":  http.StatusBadRequest,
+		"message": message,
+	})
+}
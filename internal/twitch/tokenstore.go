@@ -0,0 +1,140 @@
+package twitch
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+
+	"github.com/golden-vcr/showtime/gen/queries"
+)
+
+// ErrNoUserToken is returned by a TokenStore if no token has been stored for the
+// requested user
+var ErrNoUserToken = errors.New("no user access token has been stored")
+
+// TokenStore persists the user access tokens that have been granted to our app
+type TokenStore interface {
+	GetUserToken(ctx context.Context, userId string) (*UserToken, error)
+	SaveUserToken(ctx context.Context, token *UserToken) error
+}
+
+// TokenStoreQueries represents the subset of database functionality required to
+// persist user access tokens
+type TokenStoreQueries interface {
+	GetTwitchUserToken(ctx context.Context, twitchUserID string) (queries.GetTwitchUserTokenRow, error)
+	StoreTwitchUserToken(ctx context.Context, arg queries.StoreTwitchUserTokenParams) error
+}
+
+// DBTokenStore is a TokenStore that keeps tokens in the database, encrypting the
+// access and refresh tokens with AES-GCM so that they aren't readable by anyone with
+// access to the database but not the encryption key
+type DBTokenStore struct {
+	q    TokenStoreQueries
+	aead cipher.AEAD
+}
+
+// ParseTokenEncryptionKey decodes a hex-encoded 256-bit AES key, as supplied via the
+// TWITCH_TOKEN_ENCRYPTION_KEY environment variable
+func ParseTokenEncryptionKey(s string) ([]byte, error) {
+	key, err := hex.DecodeString(s)
+	if err != nil {
+		return nil, fmt.Errorf("token encryption key must be hex-encoded: %w", err)
+	}
+	if len(key) != 32 {
+		return nil, fmt.Errorf("token encryption key must be 32 bytes; got %d", len(key))
+	}
+	return key, nil
+}
+
+func NewDBTokenStore(q TokenStoreQueries, encryptionKey []byte) (*DBTokenStore, error) {
+	block, err := aes.NewCipher(encryptionKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize cipher: %w", err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize cipher: %w", err)
+	}
+	return &DBTokenStore{
+		q:    q,
+		aead: aead,
+	}, nil
+}
+
+func (s *DBTokenStore) GetUserToken(ctx context.Context, userId string) (*UserToken, error) {
+	row, err := s.q.GetTwitchUserToken(ctx, userId)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNoUserToken
+		}
+		return nil, err
+	}
+	accessToken, err := s.decrypt(row.AccessTokenEncrypted, userId)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt access token: %w", err)
+	}
+	refreshToken, err := s.decrypt(row.RefreshTokenEncrypted, userId)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt refresh token: %w", err)
+	}
+	return &UserToken{
+		UserId:       userId,
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		Scopes:       row.Scopes,
+		ExpiresAt:    row.ExpiresAt,
+	}, nil
+}
+
+func (s *DBTokenStore) SaveUserToken(ctx context.Context, token *UserToken) error {
+	if token.UserId == "" {
+		return fmt.Errorf("token has no user ID")
+	}
+	accessTokenEncrypted, err := s.encrypt(token.AccessToken, token.UserId)
+	if err != nil {
+		return fmt.Errorf("failed to encrypt access token: %w", err)
+	}
+	refreshTokenEncrypted, err := s.encrypt(token.RefreshToken, token.UserId)
+	if err != nil {
+		return fmt.Errorf("failed to encrypt refresh token: %w", err)
+	}
+	scopes := token.Scopes
+	if scopes == nil {
+		scopes = []string{}
+	}
+	return s.q.StoreTwitchUserToken(ctx, queries.StoreTwitchUserTokenParams{
+		TwitchUserID:          token.UserId,
+		AccessTokenEncrypted:  accessTokenEncrypted,
+		RefreshTokenEncrypted: refreshTokenEncrypted,
+		Scopes:                scopes,
+		ExpiresAt:             token.ExpiresAt,
+	})
+}
+
+// encrypt seals the given value, prepending the random nonce to the ciphertext. The
+// user ID is used as additional data, so a token can't be decrypted if it's copied to
+// another user's row.
+func (s *DBTokenStore) encrypt(value string, userId string) ([]byte, error) {
+	nonce := make([]byte, s.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return s.aead.Seal(nonce, nonce, []byte(value), []byte(userId)), nil
+}
+
+func (s *DBTokenStore) decrypt(data []byte, userId string) (string, error) {
+	nonceSize := s.aead.NonceSize()
+	if len(data) < nonceSize {
+		return "", fmt.Errorf("ciphertext is too short")
+	}
+	plaintext, err := s.aead.Open(nil, data[:nonceSize], data[nonceSize:], []byte(userId))
+	if err != nil {
+		return "", err
+	}
+	return string(plaintext), nil
+}
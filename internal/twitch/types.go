@@ -2,8 +2,40 @@ package twitch
 
 import "github.com/nicklaw5/helix/v2"
 
+// UserReader represents the subset of Twitch Helix API operations required to look up
+// Twitch users by ID or login
+type UserReader interface {
+	GetUsers(params *helix.UsersParams) (*helix.UsersResponse, error)
+}
+
 // SubscriptionReader represents the subset of Twitch Helix API operations required to
 // view the state of EventSub subscriptions, with read-only access
 type SubscriptionReader interface {
 	GetEventSubSubscriptions(params *helix.EventSubSubscriptionsParams) (*helix.EventSubSubscriptionsResponse, error)
 }
+
+// SubscriptionManager represents the subset of Twitch Helix API operations required to
+// create and delete EventSub subscriptions in addition to viewing them
+type SubscriptionManager interface {
+	SubscriptionReader
+	CreateEventSubSubscription(payload *helix.EventSubSubscription) (*helix.EventSubSubscriptionsResponse, error)
+	RemoveEventSubSubscription(id string) (*helix.RemoveEventSubSubscriptionParamsResponse, error)
+}
+
+// RedemptionUpdater represents the subset of Twitch Helix API operations required to
+// mark channel points redemptions as fulfilled or canceled
+type RedemptionUpdater interface {
+	UpdateChannelCustomRewardsRedemptionStatus(params *helix.UpdateChannelCustomRewardsRedemptionStatusParams) (*helix.ChannelCustomRewardsRedemptionResponse, error)
+}
+
+// CheermoteReader represents the subset of Twitch Helix API operations required to
+// look up the cheermotes that viewers can use when cheering in a channel
+type CheermoteReader interface {
+	GetCheermotes(params *helix.CheermotesParams) (*helix.CheermotesResponse, error)
+}
+
+// TokenValidator represents the subset of Twitch API operations required to check
+// whether an access token is valid and which scopes it grants
+type TokenValidator interface {
+	ValidateToken(accessToken string) (bool, *helix.ValidateTokenResponse, error)
+}
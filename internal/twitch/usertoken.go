@@ -0,0 +1,164 @@
+package twitch
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// DefaultUserTokenRefreshMargin is how long before a user access token expires that
+// UserTokenManager will refresh it
+const DefaultUserTokenRefreshMargin = 10 * time.Minute
+
+// UserTokenSource supplies a valid access token for a single Twitch user on demand:
+// it's shared by anything that needs to call the Twitch API with user scopes, so that
+// all of those callers use the same token and are unaffected when it's refreshed
+type UserTokenSource interface {
+	GetUserAccessToken(ctx context.Context) (string, error)
+}
+
+// UserTokenManager is a UserTokenSource that loads a user's token from a TokenStore,
+// refreshes it before it expires, and stores the refreshed token
+type UserTokenManager struct {
+	store     TokenStore
+	refresher TokenRefresher
+	userId    string
+	margin    time.Duration
+	now       func() time.Time
+
+	mu        sync.Mutex
+	token     *UserToken
+	unsaved   bool
+	onRefresh []func(accessToken string)
+}
+
+func NewUserTokenManager(store TokenStore, refresher TokenRefresher, userId string) *UserTokenManager {
+	return &UserTokenManager{
+		store:     store,
+		refresher: refresher,
+		userId:    userId,
+		margin:    DefaultUserTokenRefreshMargin,
+		now:       time.Now,
+	}
+}
+
+// GetUserAccessToken returns the user's current access token, refreshing it first if
+// it's due to expire soon. Returns ErrNoUserToken if the user has never granted a
+// token, in which case cmd/init must be run to obtain one.
+func (m *UserTokenManager) GetUserAccessToken(ctx context.Context) (string, error) {
+	token, err := m.getToken(ctx)
+	if err != nil {
+		return "", err
+	}
+	return token.AccessToken, nil
+}
+
+// OnRefresh registers a function that will be called with the new access token each
+// time the token is refreshed
+func (m *UserTokenManager) OnRefresh(f func(accessToken string)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.onRefresh = append(m.onRefresh, f)
+}
+
+// NewClient initializes a Twitch API client that authenticates with the user's access
+// token, and that's updated with the new token whenever it's refreshed
+func (m *UserTokenManager) NewClient(ctx context.Context, endpoints Endpoints, clientId string) (*Client, error) {
+	accessToken, err := m.GetUserAccessToken(ctx)
+	if err != nil {
+		return nil, err
+	}
+	c, err := NewClientWithUserToken(endpoints, clientId, accessToken)
+	if err != nil {
+		return nil, err
+	}
+	m.OnRefresh(c.SetUserAccessToken)
+	return c, nil
+}
+
+// Run refreshes the user's token shortly before it expires, for as long as the
+// context is active, so that clients which are not actively requesting the token
+// (such as long-lived connections) always have a valid token
+func (m *UserTokenManager) Run(ctx context.Context) error {
+	for {
+		delay := time.Minute
+		token, err := m.getToken(ctx)
+		if err != nil {
+			fmt.Printf("Failed to refresh Twitch user access token: %v\n", err)
+		} else if untilRefresh := token.ExpiresAt.Add(-m.margin).Sub(m.now()); untilRefresh > delay && !m.hasUnsavedToken() {
+			// If we failed to store the refreshed token, we retry after the usual short
+			// delay; otherwise we can wait until the token is due to expire
+			delay = untilRefresh
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delay):
+		}
+	}
+}
+
+func (m *UserTokenManager) getToken(ctx context.Context) (*UserToken, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// Lazy-load the token from the store the first time it's needed
+	if m.token == nil {
+		token, err := m.store.GetUserToken(ctx, m.userId)
+		if err != nil {
+			return nil, err
+		}
+		m.token = token
+	}
+
+	// If we previously failed to store a refreshed token, try again
+	if m.unsaved {
+		m.saveToken(ctx)
+	}
+
+	// If the token is still valid for a while, we can use it as-is
+	if m.now().Before(m.token.ExpiresAt.Add(-m.margin)) {
+		return m.token, nil
+	}
+
+	// Otherwise, get a new token: Twitch may have invalidated our old refresh token in
+	// the process, so we start using the new token immediately, even if we can't store
+	// it
+	refreshed, err := m.refresher.RefreshUserToken(ctx, m.token.RefreshToken)
+	if err != nil {
+		return nil, fmt.Errorf("failed to refresh user access token for user %s (run cmd/init to grant a new token): %w", m.userId, err)
+	}
+	refreshed.UserId = m.userId
+	if len(refreshed.Scopes) == 0 {
+		refreshed.Scopes = m.token.Scopes
+	}
+	m.token = refreshed
+	for _, f := range m.onRefresh {
+		f(refreshed.AccessToken)
+	}
+
+	// Store the new token so that it persists across restarts
+	m.unsaved = true
+	m.saveToken(ctx)
+	return m.token, nil
+}
+
+// saveToken stores our current token, logging on failure so that the caller can
+// continue to use the token: the token remains flagged as unsaved until it's stored
+// successfully. Must be called while holding mu.
+func (m *UserTokenManager) saveToken(ctx context.Context) {
+	if err := m.store.SaveUserToken(ctx, m.token); err != nil {
+		fmt.Printf("Failed to store refreshed Twitch user access token for user %s; will retry: %v\n", m.userId, err)
+		return
+	}
+	m.unsaved = false
+}
+
+// hasUnsavedToken returns true if we've refreshed our token but failed to store it
+func (m *UserTokenManager) hasUnsavedToken() bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.unsaved
+}
@@ -0,0 +1,206 @@
+package twitch
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/golden-vcr/showtime/gen/queries"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_DBTokenStore(t *testing.T) {
+	q := &mockTokenStoreQueries{}
+	store, err := NewDBTokenStore(q, bytes.Repeat([]byte{0x42}, 32))
+	assert.NoError(t, err)
+
+	// Before any token is stored, we should get ErrNoUserToken
+	_, err = store.GetUserToken(context.Background(), "1234")
+	assert.ErrorIs(t, err, ErrNoUserToken)
+
+	// Tokens should round-trip, but should not be stored in plaintext
+	token := &UserToken{
+		UserId:       "1234",
+		AccessToken:  "access-1",
+		RefreshToken: "refresh-1",
+		Scopes:       []string{"bits:read"},
+		ExpiresAt:    time.Date(1997, 9, 1, 16, 0, 0, 0, time.UTC),
+	}
+	err = store.SaveUserToken(context.Background(), token)
+	assert.NoError(t, err)
+	row := q.rows["1234"]
+	assert.NotContains(t, string(row.AccessTokenEncrypted), "access-1")
+	assert.NotContains(t, string(row.RefreshTokenEncrypted), "refresh-1")
+	got, err := store.GetUserToken(context.Background(), "1234")
+	assert.NoError(t, err)
+	assert.Equal(t, token, got)
+
+	// A token copied into another user's row should not be usable
+	q.rows["5678"] = row
+	_, err = store.GetUserToken(context.Background(), "5678")
+	assert.ErrorContains(t, err, "failed to decrypt access token")
+
+	// A store with a different key should not be able to read the token
+	otherStore, err := NewDBTokenStore(q, bytes.Repeat([]byte{0x43}, 32))
+	assert.NoError(t, err)
+	_, err = otherStore.GetUserToken(context.Background(), "1234")
+	assert.ErrorContains(t, err, "failed to decrypt access token")
+}
+
+func Test_ParseTokenEncryptionKey(t *testing.T) {
+	key, err := ParseTokenEncryptionKey("4242424242424242424242424242424242424242424242424242424242424242")
+	assert.NoError(t, err)
+	assert.Len(t, key, 32)
+
+	_, err = ParseTokenEncryptionKey("42424242")
+	assert.EqualError(t, err, "token encryption key must be 32 bytes; got 4")
+
+	_, err = ParseTokenEncryptionKey("not hex")
+	assert.ErrorContains(t, err, "token encryption key must be hex-encoded")
+}
+
+func Test_UserTokenManager(t *testing.T) {
+	oauth := newFakeOAuthServer(t)
+	defer oauth.Close()
+
+	q := &mockTokenStoreQueries{}
+	store, err := NewDBTokenStore(q, bytes.Repeat([]byte{0x42}, 32))
+	assert.NoError(t, err)
+
+	// If no token has been stored, the manager can't supply one
+	now := time.Date(1997, 9, 1, 12, 0, 0, 0, time.UTC)
+	c := oauth.newClient()
+	c.now = func() time.Time { return now }
+	m := NewUserTokenManager(store, c, "1234")
+	m.now = func() time.Time { return now }
+	_, err = m.GetUserAccessToken(context.Background())
+	assert.ErrorIs(t, err, ErrNoUserToken)
+
+	// Once a token has been stored, it should be used as-is while it's valid
+	err = store.SaveUserToken(context.Background(), &UserToken{
+		UserId:       "1234",
+		AccessToken:  "access-1",
+		RefreshToken: "refresh-1",
+		Scopes:       []string{"bits:read", "channel:read:subscriptions"},
+		ExpiresAt:    now.Add(4 * time.Hour),
+	})
+	assert.NoError(t, err)
+	refreshed := make([]string, 0)
+	m.OnRefresh(func(accessToken string) {
+		refreshed = append(refreshed, accessToken)
+	})
+	accessToken, err := m.GetUserAccessToken(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, "access-1", accessToken)
+	assert.Equal(t, 0, oauth.numRequests)
+
+	// Once the token is close to expiring, it should be refreshed and stored
+	now = now.Add(4*time.Hour - DefaultUserTokenRefreshMargin)
+	accessToken, err = m.GetUserAccessToken(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, "access-2", accessToken)
+	assert.Equal(t, 1, oauth.numRequests)
+	assert.Equal(t, []string{"access-2"}, refreshed)
+	stored, err := store.GetUserToken(context.Background(), "1234")
+	assert.NoError(t, err)
+	assert.Equal(t, "access-2", stored.AccessToken)
+	assert.Equal(t, "refresh-2", stored.RefreshToken)
+
+	// The refreshed token should then be used until it's close to expiring
+	accessToken, err = m.GetUserAccessToken(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, "access-2", accessToken)
+	assert.Equal(t, 1, oauth.numRequests)
+
+	// If the refresh token has been revoked, we should get an error
+	oauth.refreshToken = "revoked"
+	now = now.Add(4 * time.Hour)
+	_, err = m.GetUserAccessToken(context.Background())
+	assert.ErrorContains(t, err, "run cmd/init to grant a new token")
+	assert.ErrorContains(t, err, "Invalid refresh token")
+}
+
+func Test_UserTokenManager_storeFailure(t *testing.T) {
+	oauth := newFakeOAuthServer(t)
+	defer oauth.Close()
+
+	q := &mockTokenStoreQueries{}
+	store, err := NewDBTokenStore(q, bytes.Repeat([]byte{0x42}, 32))
+	assert.NoError(t, err)
+	now := time.Date(1997, 9, 1, 12, 0, 0, 0, time.UTC)
+	err = store.SaveUserToken(context.Background(), &UserToken{
+		UserId:       "1234",
+		AccessToken:  "access-1",
+		RefreshToken: "refresh-1",
+		ExpiresAt:    now.Add(4 * time.Hour),
+	})
+	assert.NoError(t, err)
+
+	c := oauth.newClient()
+	c.now = func() time.Time { return now }
+	m := NewUserTokenManager(store, c, "1234")
+	m.now = func() time.Time { return now }
+	refreshed := make([]string, 0)
+	m.OnRefresh(func(accessToken string) {
+		refreshed = append(refreshed, accessToken)
+	})
+
+	// If we can't store the refreshed token, we should still use it, since Twitch has
+	// invalidated our old refresh token
+	q.storeErr = fmt.Errorf("database is unavailable")
+	now = now.Add(4*time.Hour - DefaultUserTokenRefreshMargin)
+	accessToken, err := m.GetUserAccessToken(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, "access-2", accessToken)
+	assert.Equal(t, []string{"access-2"}, refreshed)
+	assert.True(t, m.hasUnsavedToken())
+
+	// Once the database is available again, the refreshed token should be stored
+	q.storeErr = nil
+	accessToken, err = m.GetUserAccessToken(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, "access-2", accessToken)
+	assert.False(t, m.hasUnsavedToken())
+	stored, err := store.GetUserToken(context.Background(), "1234")
+	assert.NoError(t, err)
+	assert.Equal(t, "refresh-2", stored.RefreshToken)
+
+	// Subsequent refreshes should use the new refresh token
+	now = now.Add(4 * time.Hour)
+	accessToken, err = m.GetUserAccessToken(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, "access-3", accessToken)
+	assert.Equal(t, 2, oauth.numRequests)
+}
+
+type mockTokenStoreQueries struct {
+	rows     map[string]queries.GetTwitchUserTokenRow
+	storeErr error
+}
+
+func (m *mockTokenStoreQueries) GetTwitchUserToken(ctx context.Context, twitchUserID string) (queries.GetTwitchUserTokenRow, error) {
+	row, ok := m.rows[twitchUserID]
+	if !ok {
+		return queries.GetTwitchUserTokenRow{}, sql.ErrNoRows
+	}
+	return row, nil
+}
+
+func (m *mockTokenStoreQueries) StoreTwitchUserToken(ctx context.Context, arg queries.StoreTwitchUserTokenParams) error {
+	if m.storeErr != nil {
+		return m.storeErr
+	}
+	if m.rows == nil {
+		m.rows = make(map[string]queries.GetTwitchUserTokenRow)
+	}
+	m.rows[arg.TwitchUserID] = queries.GetTwitchUserTokenRow{
+		AccessTokenEncrypted:  arg.AccessTokenEncrypted,
+		RefreshTokenEncrypted: arg.RefreshTokenEncrypted,
+		Scopes:                arg.Scopes,
+		ExpiresAt:             arg.ExpiresAt,
+	}
+	return nil
+}
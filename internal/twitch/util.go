@@ -2,11 +2,12 @@ package twitch
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/nicklaw5/helix/v2"
 )
 
-func GetChannelUserId(client *helix.Client, channelName string) (string, error) {
+func GetChannelUserId(client UserReader, channelName string) (string, error) {
 	r, err := client.GetUsers(&helix.UsersParams{
 		Logins: []string{channelName},
 	})
@@ -21,3 +22,33 @@ func GetChannelUserId(client *helix.Client, channelName string) (string, error)
 	}
 	return r.Data.Users[0].ID, nil
 }
+
+// ValidateUserToken verifies that the given user access token is currently valid,
+// that it belongs to the user with the given ID, and that it grants all of the given
+// scopes
+func ValidateUserToken(c TokenValidator, userAccessToken string, userId string, scopes []string) error {
+	isValid, r, err := c.ValidateToken(userAccessToken)
+	if err != nil {
+		return fmt.Errorf("failed to validate user access token: %w", err)
+	}
+	if !isValid {
+		return fmt.Errorf("user access token is invalid or expired: got response %d: %s", r.StatusCode, r.ErrorMessage)
+	}
+	if r.Data.UserID != userId {
+		return fmt.Errorf("user access token belongs to user %s (%s); expected user %s", r.Data.UserID, r.Data.Login, userId)
+	}
+	granted := make(map[string]struct{}, len(r.Data.Scopes))
+	for _, scope := range r.Data.Scopes {
+		granted[scope] = struct{}{}
+	}
+	missing := make([]string, 0)
+	for _, scope := range scopes {
+		if _, ok := granted[scope]; !ok {
+			missing = append(missing, scope)
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("user access token is missing required scopes: %s", strings.Join(missing, ", "))
+	}
+	return nil
+}
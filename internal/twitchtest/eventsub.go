@@ -0,0 +1,380 @@
+package twitchtest
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nicklaw5/helix/v2"
+)
+
+// MaxTotalCost is the max_total_cost reported in EventSub subscription responses
+const MaxTotalCost = 10000
+
+const (
+	messageTypeNotification = "notification"
+	messageTypeVerification = "webhook_callback_verification"
+	messageTypeRevocation   = "revocation"
+)
+
+// handleEventSubSubscriptions emulates GET, POST, and DELETE
+// https://api.twitch.tv/helix/eventsub/subscriptions. Webhook subscriptions may only
+// be managed with an app access token, and WebSocket subscriptions only with a user
+// access token.
+func (s *Server) handleEventSubSubscriptions(res http.ResponseWriter, req *http.Request, c *caller) {
+	switch req.Method {
+	case http.MethodGet:
+		s.handleGetEventSubSubscriptions(res, req, c)
+	case http.MethodPost:
+		s.handleCreateEventSubSubscription(res, req, c)
+	case http.MethodDelete:
+		s.handleDeleteEventSubSubscription(res, req, c)
+	default:
+		writeError(res, http.StatusMethodNotAllowed, "Method not allowed")
+	}
+}
+
+func (s *Server) handleGetEventSubSubscriptions(res http.ResponseWriter, req *http.Request, c *caller) {
+	query := req.URL.Query()
+	status := query.Get("status")
+	subscriptionType := query.Get("type")
+	userId := query.Get("user_id")
+	subscriptionId := query.Get("subscription_id")
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	matching := make([]helix.EventSubSubscription, 0)
+	totalCost := 0
+	for _, sub := range s.subscriptions {
+		if !c.canManage(&sub.value) {
+			continue
+		}
+		totalCost += sub.value.Cost
+		if status != "" && sub.value.Status != status {
+			continue
+		}
+		if subscriptionType != "" && sub.value.Type != subscriptionType {
+			continue
+		}
+		if userId != "" && !conditionInvolvesUser(&sub.value.Condition, userId) {
+			continue
+		}
+		if subscriptionId != "" && sub.value.ID != subscriptionId {
+			continue
+		}
+		matching = append(matching, sub.value)
+	}
+
+	page, cursor, ok := paginate(len(matching), "", s.pageSize, query.Get("after"))
+	if !ok {
+		writeError(res, http.StatusBadRequest, "Invalid cursor")
+		return
+	}
+	writeJson(res, http.StatusOK, helix.ManyEventSubSubscriptions{
+		Total:                 len(matching),
+		TotalCost:             totalCost,
+		MaxTotalCost:          MaxTotalCost,
+		EventSubSubscriptions: matching[page[0]:page[1]],
+		Pagination:            helix.Pagination{Cursor: cursor},
+	})
+}
+
+func (s *Server) handleCreateEventSubSubscription(res http.ResponseWriter, req *http.Request, c *caller) {
+	var payload helix.EventSubSubscription
+	if err := json.NewDecoder(req.Body).Decode(&payload); err != nil {
+		writeError(res, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if payload.Type == "" || payload.Version == "" {
+		writeError(res, http.StatusBadRequest, "type and version are required")
+		return
+	}
+
+	sub := &subscription{
+		value: helix.EventSubSubscription{
+			ID:        uuid.NewString(),
+			Type:      payload.Type,
+			Version:   payload.Version,
+			Condition: payload.Condition,
+			Transport: helix.EventSubTransport{
+				Method: payload.Transport.Method,
+			},
+			CreatedAt: helix.Time{Time: s.now().UTC()},
+			Cost:      1,
+		},
+		secret: payload.Transport.Secret,
+	}
+	switch payload.Transport.Method {
+	case "webhook":
+		if c.user != nil {
+			writeError(res, http.StatusBadRequest, "An app access token is required to create webhook subscriptions")
+			return
+		}
+		if payload.Transport.Callback == "" {
+			writeError(res, http.StatusBadRequest, "transport.callback is required")
+			return
+		}
+		if len(payload.Transport.Secret) < 10 || len(payload.Transport.Secret) > 100 {
+			writeError(res, http.StatusBadRequest, "transport.secret must be between 10 and 100 characters")
+			return
+		}
+		sub.value.Status = helix.EventSubStatusPending
+		sub.value.Transport.Callback = payload.Transport.Callback
+	case "websocket":
+		if c.user == nil {
+			writeError(res, http.StatusBadRequest, "A user access token is required to create WebSocket subscriptions")
+			return
+		}
+		if payload.Transport.SessionID == "" {
+			writeError(res, http.StatusBadRequest, "transport.session_id is required")
+			return
+		}
+		sub.value.Status = helix.EventSubStatusEnabled
+		sub.value.Transport.SessionID = payload.Transport.SessionID
+	default:
+		writeError(res, http.StatusBadRequest, "transport.method must be webhook or websocket")
+		return
+	}
+
+	// Reject the request if the same subscription already exists
+	s.mu.Lock()
+	for _, existing := range s.subscriptions {
+		if existing.value.Type == sub.value.Type && existing.value.Version == sub.value.Version && existing.value.Condition == sub.value.Condition && existing.value.Transport == sub.value.Transport {
+			s.mu.Unlock()
+			writeError(res, http.StatusConflict, "subscription already exists")
+			return
+		}
+	}
+	s.subscriptions = append(s.subscriptions, sub)
+	s.mu.Unlock()
+
+	// Twitch verifies new webhook subscriptions by sending a challenge to the callback,
+	// only enabling the subscription if the callback echoes the challenge back. Real
+	// Twitch does this asynchronously; we do it before responding so that the state of
+	// the subscription is deterministic once the request completes.
+	if sub.value.Transport.Method == "webhook" {
+		status := helix.EventSubStatusFailed
+		if err := s.sendChallenge(sub); err != nil {
+			fmt.Printf("Webhook callback verification failed for subscription %s: %v\n", sub.value.ID, err)
+		} else {
+			status = helix.EventSubStatusEnabled
+		}
+		s.mu.Lock()
+		sub.value.Status = status
+		s.mu.Unlock()
+	}
+
+	s.mu.Lock()
+	created := sub.value
+	s.mu.Unlock()
+	writeJson(res, http.StatusAccepted, helix.ManyEventSubSubscriptions{
+		Total:                 1,
+		TotalCost:             created.Cost,
+		MaxTotalCost:          MaxTotalCost,
+		EventSubSubscriptions: []helix.EventSubSubscription{created},
+	})
+}
+
+func (s *Server) handleDeleteEventSubSubscription(res http.ResponseWriter, req *http.Request, c *caller) {
+	id := req.URL.Query().Get("id")
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, sub := range s.subscriptions {
+		if sub.value.ID == id && c.canManage(&sub.value) {
+			s.subscriptions = append(s.subscriptions[:i], s.subscriptions[i+1:]...)
+			res.WriteHeader(http.StatusNoContent)
+			return
+		}
+	}
+	writeError(res, http.StatusNotFound, "subscription not found")
+}
+
+// Subscriptions returns a snapshot of all EventSub subscriptions on the server
+func (s *Server) Subscriptions() []helix.EventSubSubscription {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	subscriptions := make([]helix.EventSubSubscription, 0, len(s.subscriptions))
+	for _, sub := range s.subscriptions {
+		subscriptions = append(subscriptions, sub.value)
+	}
+	return subscriptions
+}
+
+// Notify delivers a notification carrying the given event to every enabled webhook
+// subscription of the given type whose condition matches all non-empty fields of the
+// given condition, returning the number of notifications delivered. The event may be
+// a json.RawMessage or any value that can be encoded as JSON.
+func (s *Server) Notify(subscriptionType string, condition helix.EventSubCondition, event any) (int, error) {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return 0, fmt.Errorf("failed to encode event: %w", err)
+	}
+
+	s.mu.Lock()
+	targets := make([]subscription, 0)
+	for _, sub := range s.subscriptions {
+		if sub.value.Type != subscriptionType || sub.value.Status != helix.EventSubStatusEnabled || sub.value.Transport.Method != "webhook" {
+			continue
+		}
+		if !conditionMatches(&sub.value.Condition, &condition) {
+			continue
+		}
+		targets = append(targets, *sub)
+	}
+	s.mu.Unlock()
+
+	for i := range targets {
+		body := map[string]any{
+			"subscription": targets[i].value,
+			"event":        json.RawMessage(data),
+		}
+		status, _, err := s.deliver(&targets[i], messageTypeNotification, body)
+		if err != nil {
+			return i, err
+		}
+		if status < 200 || status > 299 {
+			return i, fmt.Errorf("callback for subscription %s responded to notification with %d", targets[i].value.ID, status)
+		}
+	}
+	return len(targets), nil
+}
+
+// Revoke changes the status of the given subscription to the given reason (e.g.
+// helix.EventSubStatusAuthorizationRevoked) and, if it's a webhook subscription,
+// notifies the callback of the revocation
+func (s *Server) Revoke(subscriptionId string, reason string) error {
+	s.mu.Lock()
+	var target *subscription
+	for _, sub := range s.subscriptions {
+		if sub.value.ID == subscriptionId {
+			sub.value.Status = reason
+			copied := *sub
+			target = &copied
+			break
+		}
+	}
+	s.mu.Unlock()
+
+	if target == nil {
+		return fmt.Errorf("no subscription with ID %s", subscriptionId)
+	}
+	if target.value.Transport.Method != "webhook" {
+		return nil
+	}
+	status, _, err := s.deliver(target, messageTypeRevocation, map[string]any{
+		"subscription": target.value,
+	})
+	if err != nil {
+		return err
+	}
+	if status < 200 || status > 299 {
+		return fmt.Errorf("callback for subscription %s responded to revocation with %d", subscriptionId, status)
+	}
+	return nil
+}
+
+// sendChallenge sends a webhook_callback_verification message to the callback of the
+// given subscription, returning an error unless the callback echoes the challenge
+func (s *Server) sendChallenge(sub *subscription) error {
+	challenge := uuid.NewString()
+	status, body, err := s.deliver(sub, messageTypeVerification, map[string]any{
+		"challenge":    challenge,
+		"subscription": sub.value,
+	})
+	if err != nil {
+		return err
+	}
+	if status != http.StatusOK {
+		return fmt.Errorf("callback responded with %d", status)
+	}
+	if body != challenge {
+		return fmt.Errorf("callback responded with %q instead of challenge", body)
+	}
+	return nil
+}
+
+// deliver sends an EventSub message of the given type to the callback of the given
+// webhook subscription, signed with the subscription's secret, returning the status
+// and body of the response
+func (s *Server) deliver(sub *subscription, messageType string, body any) (int, string, error) {
+	message, err := json.Marshal(body)
+	if err != nil {
+		return 0, "", fmt.Errorf("failed to encode message: %w", err)
+	}
+	req, err := http.NewRequest(http.MethodPost, s.resolveCallback(sub.value.Transport.Callback), bytes.NewReader(message))
+	if err != nil {
+		return 0, "", fmt.Errorf("error initializing HTTP request: %w", err)
+	}
+
+	messageId := uuid.NewString()
+	timestamp := s.now().UTC().Format(time.RFC3339Nano)
+	mac := hmac.New(sha256.New, []byte(sub.secret))
+	mac.Write([]byte(messageId + timestamp + string(message)))
+	req.Header.Set("content-type", "application/json")
+	req.Header.Set("Twitch-Eventsub-Message-Id", messageId)
+	req.Header.Set("Twitch-Eventsub-Message-Retry", "0")
+	req.Header.Set("Twitch-Eventsub-Message-Type", messageType)
+	req.Header.Set("Twitch-Eventsub-Message-Signature", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	req.Header.Set("Twitch-Eventsub-Message-Timestamp", timestamp)
+	req.Header.Set("Twitch-Eventsub-Subscription-Type", sub.value.Type)
+	req.Header.Set("Twitch-Eventsub-Subscription-Version", sub.value.Version)
+
+	res, err := s.httpClient.Do(req)
+	if err != nil {
+		return 0, "", fmt.Errorf("error delivering %s message: %w", messageType, err)
+	}
+	defer res.Body.Close()
+	resBody, err := io.ReadAll(res.Body)
+	if err != nil {
+		return 0, "", fmt.Errorf("error reading response to %s message: %w", messageType, err)
+	}
+	return res.StatusCode, string(resBody), nil
+}
+
+// canManage returns true if the caller's token may be used to view and delete the
+// given subscription
+func (c *caller) canManage(sub *helix.EventSubSubscription) bool {
+	if sub.Transport.Method == "websocket" {
+		return c.user != nil
+	}
+	return c.user == nil
+}
+
+// conditionInvolvesUser returns true if any of the user ID fields in the given
+// condition identify the given user, matching the semantics of the user_id filter
+func conditionInvolvesUser(condition *helix.EventSubCondition, userId string) bool {
+	return condition.BroadcasterUserID == userId ||
+		condition.FromBroadcasterUserID == userId ||
+		condition.ToBroadcasterUserID == userId ||
+		condition.ModeratorUserID == userId ||
+		condition.UserID == userId
+}
+
+// conditionMatches returns true if every non-empty field in filter has the same value
+// in condition
+func conditionMatches(condition *helix.EventSubCondition, filter *helix.EventSubCondition) bool {
+	pairs := [][2]string{
+		{condition.BroadcasterUserID, filter.BroadcasterUserID},
+		{condition.FromBroadcasterUserID, filter.FromBroadcasterUserID},
+		{condition.ModeratorUserID, filter.ModeratorUserID},
+		{condition.ToBroadcasterUserID, filter.ToBroadcasterUserID},
+		{condition.RewardID, filter.RewardID},
+		{condition.ClientID, filter.ClientID},
+		{condition.ExtensionClientID, filter.ExtensionClientID},
+		{condition.UserID, filter.UserID},
+	}
+	for _, pair := range pairs {
+		if pair[1] != "" && pair[0] != pair[1] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,248 @@
+package twitchtest
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/nicklaw5/helix/v2"
+)
+
+// handleGetUsers emulates GET https://api.twitch.tv/helix/users
+func (s *Server) handleGetUsers(res http.ResponseWriter, req *http.Request, c *caller) {
+	ids := req.URL.Query()["id"]
+	logins := req.URL.Query()["login"]
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	users := make([]helix.User, 0)
+	if len(ids) == 0 && len(logins) == 0 {
+		// With no params, the user is identified by the bearer token
+		if c.user == nil {
+			writeError(res, http.StatusBadRequest, "The ID, login, or bearer token of a user is required")
+			return
+		}
+		if user := s.findUser(c.user.userId, ""); user != nil {
+			users = append(users, *user)
+		}
+	}
+	for _, id := range ids {
+		if user := s.findUser(id, ""); user != nil {
+			users = append(users, *user)
+		}
+	}
+	for _, login := range logins {
+		if user := s.findUser("", login); user != nil {
+			users = append(users, *user)
+		}
+	}
+	writeJson(res, http.StatusOK, helix.ManyUsers{Users: users})
+}
+
+// handleGetStreams emulates GET https://api.twitch.tv/helix/streams, returning the
+// live streams of the requested users
+func (s *Server) handleGetStreams(res http.ResponseWriter, req *http.Request, c *caller) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	streams := make([]helix.Stream, 0)
+	for _, id := range req.URL.Query()["user_id"] {
+		if stream, ok := s.streams[id]; ok {
+			streams = append(streams, stream)
+		}
+	}
+	for _, login := range req.URL.Query()["user_login"] {
+		if user := s.findUser("", login); user != nil {
+			if stream, ok := s.streams[user.ID]; ok {
+				streams = append(streams, stream)
+			}
+		}
+	}
+	writeJson(res, http.StatusOK, helix.ManyStreams{Streams: streams})
+}
+
+// handleGetVideos emulates GET https://api.twitch.tv/helix/videos, filtering by video
+// ID or by user ID and type, and paginating with the 'first' and 'after' params
+func (s *Server) handleGetVideos(res http.ResponseWriter, req *http.Request, c *caller) {
+	query := req.URL.Query()
+	ids := query["id"]
+	userId := query.Get("user_id")
+	if len(ids) == 0 && userId == "" {
+		writeError(res, http.StatusBadRequest, "One of id, user_id, or game_id is required")
+		return
+	}
+	videoType := query.Get("type")
+	if videoType == "" {
+		videoType = "all"
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	matching := make([]helix.Video, 0)
+	for _, video := range s.videos {
+		if len(ids) > 0 && !contains(ids, video.ID) {
+			continue
+		}
+		if userId != "" && video.UserID != userId {
+			continue
+		}
+		if videoType != "all" && video.Type != videoType {
+			continue
+		}
+		matching = append(matching, video)
+	}
+
+	page, cursor, ok := paginate(len(matching), query.Get("first"), 20, query.Get("after"))
+	if !ok {
+		writeError(res, http.StatusBadRequest, "Invalid pagination params")
+		return
+	}
+	writeJson(res, http.StatusOK, helix.ManyVideos{
+		Videos:     matching[page[0]:page[1]],
+		Pagination: helix.Pagination{Cursor: cursor},
+	})
+}
+
+// handleChannels emulates GET and PATCH https://api.twitch.tv/helix/channels
+func (s *Server) handleChannels(res http.ResponseWriter, req *http.Request, c *caller) {
+	switch req.Method {
+	case http.MethodGet:
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		channels := make([]helix.ChannelInformation, 0)
+		for _, id := range req.URL.Query()["broadcaster_id"] {
+			if channel, ok := s.channels[id]; ok {
+				channels = append(channels, channel)
+			}
+		}
+		writeJson(res, http.StatusOK, helix.ManyChannelInformation{Channels: channels})
+	case http.MethodPatch:
+		broadcasterId := req.URL.Query().Get("broadcaster_id")
+		if c.user == nil || c.user.userId != broadcasterId {
+			writeError(res, http.StatusUnauthorized, "The user access token must belong to the broadcaster")
+			return
+		}
+		if !c.hasScope("channel:manage:broadcast") {
+			writeError(res, http.StatusUnauthorized, "Missing scope: channel:manage:broadcast")
+			return
+		}
+		var params helix.EditChannelInformationParams
+		if err := json.NewDecoder(req.Body).Decode(&params); err != nil {
+			writeError(res, http.StatusBadRequest, "Invalid request body")
+			return
+		}
+
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		channel, ok := s.channels[broadcasterId]
+		if !ok {
+			channel.BroadcasterID = broadcasterId
+			if user := s.findUser(broadcasterId, ""); user != nil {
+				channel.BroadcasterName = user.DisplayName
+			}
+		}
+		if params.Title != "" {
+			channel.Title = params.Title
+		}
+		if params.GameID != "" {
+			channel.GameID = params.GameID
+		}
+		if params.BroadcasterLanguage != "" {
+			channel.BroadcasterLanguage = params.BroadcasterLanguage
+		}
+		if params.Tags != nil {
+			channel.Tags = params.Tags
+		}
+		s.channels[broadcasterId] = channel
+		res.WriteHeader(http.StatusNoContent)
+	default:
+		writeError(res, http.StatusMethodNotAllowed, "Method not allowed")
+	}
+}
+
+// handleGetCheermotes emulates GET https://api.twitch.tv/helix/bits/cheermotes
+func (s *Server) handleGetCheermotes(res http.ResponseWriter, req *http.Request, c *caller) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	cheermotes := s.cheermotes
+	if cheermotes == nil {
+		cheermotes = []helix.Cheermotes{}
+	}
+	writeJson(res, http.StatusOK, helix.ManyCheermotes{Cheermotes: cheermotes})
+}
+
+// handleUpdateRedemptions emulates PATCH
+// https://api.twitch.tv/helix/channel_points/custom_rewards/redemptions, recording
+// each request so that tests can inspect it via RedemptionUpdates
+func (s *Server) handleUpdateRedemptions(res http.ResponseWriter, req *http.Request, c *caller) {
+	params := helix.UpdateChannelCustomRewardsRedemptionStatusParams{
+		ID:            req.URL.Query().Get("id"),
+		BroadcasterID: req.URL.Query().Get("broadcaster_id"),
+		RewardID:      req.URL.Query().Get("reward_id"),
+	}
+	if c.user == nil || c.user.userId != params.BroadcasterID {
+		writeError(res, http.StatusUnauthorized, "The user access token must belong to the broadcaster")
+		return
+	}
+	if !c.hasScope("channel:manage:redemptions") {
+		writeError(res, http.StatusUnauthorized, "Missing scope: channel:manage:redemptions")
+		return
+	}
+	var body struct {
+		Status string `json:"status"`
+	}
+	if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+		writeError(res, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	params.Status = body.Status
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.redemptionCalls = append(s.redemptionCalls, params)
+	writeJson(res, http.StatusOK, helix.ManyChannelCustomRewardsRedemptions{
+		Redemptions: []helix.ChannelCustomRewardsRedemption{
+			{
+				ID:            params.ID,
+				BroadcasterID: params.BroadcasterID,
+				Status:        params.Status,
+			},
+		},
+	})
+}
+
+// paginate returns the [start, end) range of the page of numItems items selected by
+// the given 'first' and 'after' params, along with the cursor for the next page (which
+// is empty if this is the last page). Cursors are simply item offsets.
+func paginate(numItems int, first string, defaultFirst int, after string) ([2]int, string, bool) {
+	pageSize := defaultFirst
+	if first != "" {
+		n, err := strconv.Atoi(first)
+		if err != nil || n < 1 || n > 100 {
+			return [2]int{}, "", false
+		}
+		pageSize = n
+	}
+	start := 0
+	if after != "" {
+		n, err := strconv.Atoi(after)
+		if err != nil || n < 0 || n > numItems {
+			return [2]int{}, "", false
+		}
+		start = n
+	}
+	end := min(start+pageSize, numItems)
+	cursor := ""
+	if end < numItems {
+		cursor = strconv.Itoa(end)
+	}
+	return [2]int{start, end}, cursor, true
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,142 @@
+package twitchtest
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// handleAuthorize emulates GET https://id.twitch.tv/oauth2/authorize: rather than
+// prompting the user to log in, it immediately approves the request on behalf of the
+// user configured as AuthorizeAs, redirecting to the redirect URI with a code
+func (s *Server) handleAuthorize(res http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		writeError(res, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+	query := req.URL.Query()
+	if query.Get("client_id") != s.clientId || query.Get("response_type") != "code" {
+		writeError(res, http.StatusBadRequest, "Invalid client or response type")
+		return
+	}
+	redirectUrl, err := url.Parse(query.Get("redirect_uri"))
+	if err != nil || query.Get("redirect_uri") == "" {
+		writeError(res, http.StatusBadRequest, "Invalid redirect URI")
+		return
+	}
+	if s.authorizeAs == "" {
+		writeError(res, http.StatusForbidden, "No user is configured to approve authorization requests")
+		return
+	}
+
+	scopes := strings.Fields(query.Get("scope"))
+	code := s.GrantAuthorizationCode(s.authorizeAs, scopes, query.Get("redirect_uri"))
+	q := redirectUrl.Query()
+	q.Set("code", code)
+	q.Set("scope", strings.Join(scopes, " "))
+	q.Set("state", query.Get("state"))
+	redirectUrl.RawQuery = q.Encode()
+	http.Redirect(res, req, redirectUrl.String(), http.StatusFound)
+}
+
+// handleToken emulates POST https://id.twitch.tv/oauth2/token, supporting the client
+// credentials, authorization code, and refresh token grant flows
+func (s *Server) handleToken(res http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		writeError(res, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+	if err := req.ParseForm(); err != nil {
+		writeError(res, http.StatusBadRequest, "Invalid request")
+		return
+	}
+	if req.PostForm.Get("client_id") != s.clientId || req.PostForm.Get("client_secret") != s.clientSecret {
+		writeError(res, http.StatusBadRequest, "Invalid client")
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	switch req.PostForm.Get("grant_type") {
+	case "client_credentials":
+		token := s.nextId("app")
+		s.appTokens[token] = s.now().Add(AppTokenLifetime)
+		writeJson(res, http.StatusOK, map[string]any{
+			"access_token": token,
+			"expires_in":   int(AppTokenLifetime.Seconds()),
+			"token_type":   "bearer",
+		})
+	case "authorization_code":
+		code, ok := s.codes[req.PostForm.Get("code")]
+		if !ok || code.redirectUri != req.PostForm.Get("redirect_uri") {
+			writeError(res, http.StatusBadRequest, "Invalid authorization code")
+			return
+		}
+		delete(s.codes, req.PostForm.Get("code"))
+		s.writeUserToken(res, s.issueUserToken(code.userId, code.scopes))
+	case "refresh_token":
+		grant, ok := s.refreshTokens[req.PostForm.Get("refresh_token")]
+		if !ok {
+			writeError(res, http.StatusBadRequest, "Invalid refresh token")
+			return
+		}
+
+		// Twitch invalidates both the old access token and the old refresh token
+		delete(s.refreshTokens, grant.refreshToken)
+		delete(s.userTokens, grant.accessToken)
+		s.writeUserToken(res, s.issueUserToken(grant.userId, grant.scopes))
+	default:
+		writeError(res, http.StatusBadRequest, "Invalid grant type")
+	}
+}
+
+func (s *Server) writeUserToken(res http.ResponseWriter, grant *userGrant) {
+	writeJson(res, http.StatusOK, map[string]any{
+		"access_token":  grant.accessToken,
+		"refresh_token": grant.refreshToken,
+		"expires_in":    int(grant.expiresAt.Sub(s.now()).Seconds()),
+		"scope":         grant.scopes,
+		"token_type":    "bearer",
+	})
+}
+
+// handleValidate emulates GET https://id.twitch.tv/oauth2/validate
+func (s *Server) handleValidate(res http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		writeError(res, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+	token, ok := strings.CutPrefix(req.Header.Get("authorization"), "OAuth ")
+	if !ok {
+		writeJson(res, http.StatusUnauthorized, map[string]any{"status": http.StatusUnauthorized, "message": "missing authorization token"})
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now()
+	if expiresAt, ok := s.appTokens[token]; ok && now.Before(expiresAt) {
+		writeJson(res, http.StatusOK, map[string]any{
+			"client_id":  s.clientId,
+			"scopes":     []string{},
+			"expires_in": int(expiresAt.Sub(now) / time.Second),
+		})
+		return
+	}
+	if grant, ok := s.userTokens[token]; ok && now.Before(grant.expiresAt) {
+		login := ""
+		if user := s.findUser(grant.userId, ""); user != nil {
+			login = user.Login
+		}
+		writeJson(res, http.StatusOK, map[string]any{
+			"client_id":  s.clientId,
+			"login":      login,
+			"user_id":    grant.userId,
+			"scopes":     grant.scopes,
+			"expires_in": int(grant.expiresAt.Sub(now) / time.Second),
+		})
+		return
+	}
+	writeJson(res, http.StatusUnauthorized, map[string]any{"status": http.StatusUnauthorized, "message": "invalid access token"})
+}
@@ -0,0 +1,414 @@
+// Package twitchtest provides a fake implementation of the Twitch APIs that showtime
+// depends on, so that our commands and our server can be exercised end to end
+// without network access. The fake emulates the OAuth token endpoints along with the
+// Helix endpoints we call, and it can sign and deliver EventSub webhook messages
+// (challenges, notifications and revocations) to a callback.
+package twitchtest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golden-vcr/showtime/internal/twitch"
+	"github.com/google/uuid"
+	"github.com/nicklaw5/helix/v2"
+)
+
+// DefaultRateLimit is the number of Helix API requests that each access token may make
+// per minute, unless overridden in Config
+const DefaultRateLimit = 800
+
+// DefaultPageSize is the maximum number of EventSub subscriptions returned in a single
+// page, unless overridden in Config
+const DefaultPageSize = 100
+
+// AppTokenLifetime and UserTokenLifetime are the durations for which newly-issued
+// tokens remain valid, approximating the lifetimes of real Twitch tokens
+const (
+	AppTokenLifetime  = 60 * 24 * time.Hour
+	UserTokenLifetime = 4 * time.Hour
+)
+
+// Config describes the initial state of a fake Twitch server
+type Config struct {
+	// ClientId and ClientSecret are the credentials of the only app that the server
+	// recognizes
+	ClientId     string
+	ClientSecret string
+	// Users are the Twitch users that exist on the server
+	Users []helix.User
+	// AuthorizeAs, if set, is the ID of the user who automatically approves every
+	// request made to GET /oauth2/authorize, so that the authorization code grant flow
+	// can complete without any interaction
+	AuthorizeAs string
+	// ResolveCallback, if set, maps the callback URL of a webhook subscription to the
+	// URL to which messages are actually delivered. The helix client insists that
+	// callbacks use HTTPS on port 443, so this allows messages to be redirected to a
+	// local server in tests.
+	ResolveCallback func(callback string) string
+	// RateLimit is the number of Helix API requests that each token may make per
+	// minute: if zero, DefaultRateLimit is used
+	RateLimit int
+	// PageSize is the maximum number of EventSub subscriptions to return in a single
+	// page: if zero, DefaultPageSize is used
+	PageSize int
+	// Now, if set, overrides the server's clock
+	Now func() time.Time
+}
+
+// Server is a fake Twitch API server. It's an http.Handler that serves the OAuth API
+// under /oauth2 and the Helix API under /helix: Endpoints returns the base URLs that
+// clients should use, given the URL at which the server is running.
+type Server struct {
+	clientId        string
+	clientSecret    string
+	authorizeAs     string
+	resolveCallback func(callback string) string
+	rateLimit       int
+	pageSize        int
+	now             func() time.Time
+	httpClient      *http.Client
+	mux             *http.ServeMux
+
+	mu              sync.Mutex
+	users           []helix.User
+	numIssued       int
+	appTokens       map[string]time.Time
+	userTokens      map[string]*userGrant
+	refreshTokens   map[string]*userGrant
+	codes           map[string]*codeGrant
+	rateLimits      map[string]*rateLimitBucket
+	subscriptions   []*subscription
+	streams         map[string]helix.Stream
+	videos          []helix.Video
+	channels        map[string]helix.ChannelInformation
+	cheermotes      []helix.Cheermotes
+	redemptionCalls []helix.UpdateChannelCustomRewardsRedemptionStatusParams
+}
+
+// userGrant records a user access token issued to our app
+type userGrant struct {
+	userId       string
+	accessToken  string
+	refreshToken string
+	scopes       []string
+	expiresAt    time.Time
+}
+
+// codeGrant records an authorization code that may be exchanged for a user token
+type codeGrant struct {
+	userId      string
+	scopes      []string
+	redirectUri string
+}
+
+// rateLimitBucket tracks the number of requests made with a token in the current
+// minute
+type rateLimitBucket struct {
+	resetAt   time.Time
+	remaining int
+}
+
+// subscription is an EventSub subscription, along with the secret used to sign the
+// messages delivered to it (which Twitch never returns in API responses)
+type subscription struct {
+	value  helix.EventSubSubscription
+	secret string
+}
+
+// New initializes a fake Twitch server with the given initial state
+func New(config Config) *Server {
+	s := &Server{
+		clientId:        config.ClientId,
+		clientSecret:    config.ClientSecret,
+		authorizeAs:     config.AuthorizeAs,
+		resolveCallback: config.ResolveCallback,
+		rateLimit:       config.RateLimit,
+		pageSize:        config.PageSize,
+		now:             config.Now,
+		httpClient:      &http.Client{Timeout: 10 * time.Second},
+		users:           append([]helix.User(nil), config.Users...),
+		appTokens:       make(map[string]time.Time),
+		userTokens:      make(map[string]*userGrant),
+		refreshTokens:   make(map[string]*userGrant),
+		codes:           make(map[string]*codeGrant),
+		rateLimits:      make(map[string]*rateLimitBucket),
+		streams:         make(map[string]helix.Stream),
+		channels:        make(map[string]helix.ChannelInformation),
+	}
+	if s.resolveCallback == nil {
+		s.resolveCallback = func(callback string) string { return callback }
+	}
+	if s.rateLimit == 0 {
+		s.rateLimit = DefaultRateLimit
+	}
+	if s.pageSize == 0 {
+		s.pageSize = DefaultPageSize
+	}
+	if s.now == nil {
+		s.now = time.Now
+	}
+
+	s.mux = http.NewServeMux()
+	s.mux.HandleFunc("/oauth2/authorize", s.handleAuthorize)
+	s.mux.HandleFunc("/oauth2/token", s.handleToken)
+	s.mux.HandleFunc("/oauth2/validate", s.handleValidate)
+	s.mux.HandleFunc("/helix/users", s.helix(http.MethodGet, s.handleGetUsers))
+	s.mux.HandleFunc("/helix/eventsub/subscriptions", s.helix("", s.handleEventSubSubscriptions))
+	s.mux.HandleFunc("/helix/streams", s.helix(http.MethodGet, s.handleGetStreams))
+	s.mux.HandleFunc("/helix/videos", s.helix(http.MethodGet, s.handleGetVideos))
+	s.mux.HandleFunc("/helix/channels", s.helix("", s.handleChannels))
+	s.mux.HandleFunc("/helix/bits/cheermotes", s.helix(http.MethodGet, s.handleGetCheermotes))
+	s.mux.HandleFunc("/helix/channel_points/custom_rewards/redemptions", s.helix(http.MethodPatch, s.handleUpdateRedemptions))
+	return s
+}
+
+// Endpoints returns the endpoints that a client should use in order to talk to this
+// server, given the base URL at which it's being served (e.g. httptest.Server.URL)
+func (s *Server) Endpoints(baseUrl string) twitch.Endpoints {
+	baseUrl = strings.TrimSuffix(baseUrl, "/")
+	return twitch.Endpoints{
+		ApiUrl:  baseUrl + "/helix",
+		AuthUrl: baseUrl + "/oauth2",
+	}
+}
+
+func (s *Server) ServeHTTP(res http.ResponseWriter, req *http.Request) {
+	s.mux.ServeHTTP(res, req)
+}
+
+// AddUser adds a Twitch user to the server, or replaces an existing user with the
+// same ID
+func (s *Server) AddUser(user helix.User) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i := range s.users {
+		if s.users[i].ID == user.ID {
+			s.users[i] = user
+			return
+		}
+	}
+	s.users = append(s.users, user)
+}
+
+// GrantAuthorizationCode simulates the given user approving our app's request for the
+// given scopes, returning the authorization code that Twitch would deliver to the
+// redirect URI. The code may be exchanged for a user access token once.
+func (s *Server) GrantAuthorizationCode(userId string, scopes []string, redirectUri string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	code := uuid.NewString()
+	s.codes[code] = &codeGrant{
+		userId:      userId,
+		scopes:      scopes,
+		redirectUri: redirectUri,
+	}
+	return code
+}
+
+// IssueUserToken issues a new user access token and refresh token on behalf of the
+// given user, as if they'd completed the authorization code grant flow
+func (s *Server) IssueUserToken(userId string, scopes []string) *twitch.UserToken {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	grant := s.issueUserToken(userId, scopes)
+	return &twitch.UserToken{
+		UserId:       grant.userId,
+		AccessToken:  grant.accessToken,
+		RefreshToken: grant.refreshToken,
+		Scopes:       grant.scopes,
+		ExpiresAt:    grant.expiresAt,
+	}
+}
+
+// ExpireTokens invalidates all access tokens that have been issued so far, as if they
+// had expired or been revoked. Refresh tokens remain valid.
+func (s *Server) ExpireTokens() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for token := range s.appTokens {
+		delete(s.appTokens, token)
+	}
+	for token, grant := range s.userTokens {
+		grant.expiresAt = s.now()
+		delete(s.userTokens, token)
+	}
+}
+
+// SetStream marks the given user as live with the given stream details
+func (s *Server) SetStream(stream helix.Stream) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.streams[stream.UserID] = stream
+}
+
+// EndStream marks the given user as offline
+func (s *Server) EndStream(userId string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.streams, userId)
+}
+
+// AddVideo adds a video to the server: GET /videos returns videos newest-first
+func (s *Server) AddVideo(video helix.Video) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.videos = append([]helix.Video{video}, s.videos...)
+}
+
+// SetChannel sets the channel information for the given broadcaster
+func (s *Server) SetChannel(channel helix.ChannelInformation) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.channels[channel.BroadcasterID] = channel
+}
+
+// GetChannel returns the channel information for the given broadcaster, reflecting
+// any changes made via PATCH /channels
+func (s *Server) GetChannel(broadcasterId string) (helix.ChannelInformation, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	channel, ok := s.channels[broadcasterId]
+	return channel, ok
+}
+
+// SetCheermotes sets the cheermotes returned by GET /bits/cheermotes
+func (s *Server) SetCheermotes(cheermotes []helix.Cheermotes) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.cheermotes = cheermotes
+}
+
+// RedemptionUpdates returns the parameters of every request that's been made to
+// update the status of channel points redemptions, in order
+func (s *Server) RedemptionUpdates() []helix.UpdateChannelCustomRewardsRedemptionStatusParams {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]helix.UpdateChannelCustomRewardsRedemptionStatusParams(nil), s.redemptionCalls...)
+}
+
+// nextId returns a new token value with the given prefix: must be called with mu held
+func (s *Server) nextId(prefix string) string {
+	s.numIssued++
+	return fmt.Sprintf("%s-%d", prefix, s.numIssued)
+}
+
+// issueUserToken records a new user grant: must be called with mu held
+func (s *Server) issueUserToken(userId string, scopes []string) *userGrant {
+	grant := &userGrant{
+		userId:       userId,
+		accessToken:  s.nextId("user"),
+		refreshToken: s.nextId("refresh"),
+		scopes:       append([]string(nil), scopes...),
+		expiresAt:    s.now().Add(UserTokenLifetime),
+	}
+	s.userTokens[grant.accessToken] = grant
+	s.refreshTokens[grant.refreshToken] = grant
+	return grant
+}
+
+// findUser returns the user with the given ID or login: must be called with mu held
+func (s *Server) findUser(id string, login string) *helix.User {
+	for i := range s.users {
+		if (id != "" && s.users[i].ID == id) || (login != "" && strings.EqualFold(s.users[i].Login, login)) {
+			return &s.users[i]
+		}
+	}
+	return nil
+}
+
+// caller identifies the access token with which a Helix API request was made
+type caller struct {
+	token string
+	user  *userGrant
+}
+
+// helix wraps a Helix API handler so that it only accepts requests with the given
+// method (if any), made with our client ID and a valid access token, and so that it
+// enforces and reports rate limits in the same manner as the real API
+func (s *Server) helix(method string, handler func(res http.ResponseWriter, req *http.Request, c *caller)) http.HandlerFunc {
+	return func(res http.ResponseWriter, req *http.Request) {
+		if method != "" && req.Method != method {
+			writeError(res, http.StatusMethodNotAllowed, "Method not allowed")
+			return
+		}
+		if req.Header.Get("client-id") != s.clientId {
+			writeError(res, http.StatusUnauthorized, "Client ID and OAuth token do not match")
+			return
+		}
+		token, ok := strings.CutPrefix(req.Header.Get("authorization"), "Bearer ")
+		if !ok {
+			writeError(res, http.StatusUnauthorized, "OAuth token is missing")
+			return
+		}
+
+		s.mu.Lock()
+		c := &caller{token: token}
+		valid := false
+		if expiresAt, ok := s.appTokens[token]; ok && s.now().Before(expiresAt) {
+			valid = true
+		} else if grant, ok := s.userTokens[token]; ok && s.now().Before(grant.expiresAt) {
+			valid = true
+			c.user = grant
+		}
+		var bucket rateLimitBucket
+		if valid {
+			bucket = s.consumeRateLimit(token)
+		}
+		s.mu.Unlock()
+
+		if !valid {
+			writeError(res, http.StatusUnauthorized, "Invalid OAuth token")
+			return
+		}
+		res.Header().Set("ratelimit-limit", strconv.Itoa(s.rateLimit))
+		res.Header().Set("ratelimit-remaining", strconv.Itoa(max(bucket.remaining, 0)))
+		res.Header().Set("ratelimit-reset", strconv.FormatInt(bucket.resetAt.Unix(), 10))
+		if bucket.remaining < 0 {
+			writeError(res, http.StatusTooManyRequests, "Too Many Requests")
+			return
+		}
+		handler(res, req, c)
+	}
+}
+
+// consumeRateLimit counts a request against the given token's rate limit, returning
+// the state of its bucket afterwards: remaining is negative if the request should be
+// rejected. Must be called with mu held.
+func (s *Server) consumeRateLimit(token string) rateLimitBucket {
+	now := s.now()
+	bucket, ok := s.rateLimits[token]
+	if !ok || !now.Before(bucket.resetAt) {
+		bucket = &rateLimitBucket{
+			resetAt:   now.Truncate(time.Minute).Add(time.Minute),
+			remaining: s.rateLimit,
+		}
+		s.rateLimits[token] = bucket
+	}
+	bucket.remaining--
+	return *bucket
+}
+
+// hasScope returns true if the caller is a user that has granted the given scope
+func (c *caller) hasScope(scope string) bool {
+	return c.user != nil && contains(c.user.scopes, scope)
+}
+
+func writeJson(res http.ResponseWriter, status int, value any) {
+	res.Header().Set("content-type", "application/json")
+	res.WriteHeader(status)
+	json.NewEncoder(res).Encode(value)
+}
+
+func writeError(res http.ResponseWriter, status int, message string) {
+	writeJson(res, status, map[string]any{
+		"error":   http.StatusText(status),
+		"status":  status,
+		"message": message,
+	})
+}
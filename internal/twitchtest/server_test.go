@@ -0,0 +1,401 @@
+package twitchtest
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golden-vcr/showtime/internal/twitch"
+	"github.com/nicklaw5/helix/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	testClientId      = "my-client-id"
+	testClientSecret  = "my-client-secret"
+	testWebhookSecret = "my-webhook-secret"
+	testChannelId     = "953753877"
+)
+
+func Test_Server_OAuth(t *testing.T) {
+	fake, endpoints := newTestServer(t, nil)
+	oauth := twitch.NewOAuthClient(endpoints, testClientId, testClientSecret)
+
+	// App tokens should be issued for valid client credentials only
+	appToken, err := oauth.RequestAppToken(context.Background())
+	assert.NoError(t, err)
+	ok, validated, err := oauth.ValidateToken(appToken.AccessToken)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, testClientId, validated.Data.ClientID)
+	assert.Equal(t, "", validated.Data.UserID)
+	_, err = twitch.NewOAuthClient(endpoints, testClientId, "wrong-secret").RequestAppToken(context.Background())
+	assert.EqualError(t, err, "got response 400 from token request: Invalid client")
+
+	// An authorization code should be exchangeable for a user token exactly once
+	code := fake.GrantAuthorizationCode(testChannelId, []string{"bits:read"}, "http://localhost:3033/auth")
+	userToken, err := oauth.ExchangeCode(context.Background(), &twitch.AuthorizationCode{
+		Value:       code,
+		Scopes:      []string{"bits:read"},
+		RedirectUri: "http://localhost:3033/auth",
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"bits:read"}, userToken.Scopes)
+	_, err = oauth.ExchangeCode(context.Background(), &twitch.AuthorizationCode{
+		Value:       code,
+		RedirectUri: "http://localhost:3033/auth",
+	})
+	assert.EqualError(t, err, "got response 400 from token request: Invalid authorization code")
+
+	// The user token should identify the user who granted it
+	ok, validated, err = oauth.ValidateToken(userToken.AccessToken)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, testChannelId, validated.Data.UserID)
+	assert.Equal(t, "goldenvcr", validated.Data.Login)
+	assert.Equal(t, []string{"bits:read"}, validated.Data.Scopes)
+
+	// Refreshing should invalidate the old access token and refresh token
+	refreshed, err := oauth.RefreshUserToken(context.Background(), userToken.RefreshToken)
+	assert.NoError(t, err)
+	assert.NotEqual(t, userToken.AccessToken, refreshed.AccessToken)
+	ok, validated, err = oauth.ValidateToken(userToken.AccessToken)
+	assert.NoError(t, err)
+	assert.False(t, ok)
+	assert.Equal(t, http.StatusUnauthorized, validated.StatusCode)
+	_, err = oauth.RefreshUserToken(context.Background(), userToken.RefreshToken)
+	assert.EqualError(t, err, "got response 400 from token request: Invalid refresh token")
+}
+
+func Test_Server_Authorize(t *testing.T) {
+	fake := New(Config{
+		ClientId:     testClientId,
+		ClientSecret: testClientSecret,
+		Users:        testUsers,
+		AuthorizeAs:  testChannelId,
+	})
+	s := httptest.NewServer(fake)
+	defer s.Close()
+
+	// The authorize endpoint should redirect straight back to us with a code
+	req := httptest.NewRequest(http.MethodGet, "/oauth2/authorize?client_id=my-client-id&response_type=code&redirect_uri=http%3A%2F%2Flocalhost%3A3033%2Fauth&scope=bits%3Aread+channel%3Aread%3Asubscriptions&state=abc123", nil)
+	res := httptest.NewRecorder()
+	fake.ServeHTTP(res, req)
+	assert.Equal(t, http.StatusFound, res.Code)
+	redirect, err := url.Parse(res.Header().Get("location"))
+	assert.NoError(t, err)
+	assert.Equal(t, "localhost:3033", redirect.Host)
+	assert.Equal(t, "abc123", redirect.Query().Get("state"))
+	assert.Equal(t, "bits:read channel:read:subscriptions", redirect.Query().Get("scope"))
+
+	// The code should be exchangeable for a token belonging to the approving user
+	oauth := twitch.NewOAuthClient(fake.Endpoints(s.URL), testClientId, testClientSecret)
+	token, err := oauth.ExchangeCode(context.Background(), &twitch.AuthorizationCode{
+		Value:       redirect.Query().Get("code"),
+		RedirectUri: "http://localhost:3033/auth",
+	})
+	assert.NoError(t, err)
+	ok, validated, err := oauth.ValidateToken(token.AccessToken)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, testChannelId, validated.Data.UserID)
+}
+
+func Test_Server_AppTokenClient(t *testing.T) {
+	fake, endpoints := newTestServer(t, nil)
+	c, err := twitch.NewClientWithAppToken(endpoints, testClientId, testClientSecret)
+	assert.NoError(t, err)
+
+	// The client should be able to look up users by login
+	channelUserId, err := twitch.GetChannelUserId(c, "goldenvcr")
+	assert.NoError(t, err)
+	assert.Equal(t, testChannelId, channelUserId)
+
+	// If our app token is invalidated, the client should transparently get a new one
+	fake.ExpireTokens()
+	res, err := c.GetUsers(&helix.UsersParams{IDs: []string{testChannelId}})
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Len(t, res.Data.Users, 1)
+	assert.Equal(t, "800", res.Header.Get("ratelimit-limit"))
+}
+
+func Test_Server_RateLimit(t *testing.T) {
+	now := time.Date(1997, 9, 1, 12, 0, 30, 0, time.UTC)
+	fake := New(Config{
+		ClientId:     testClientId,
+		ClientSecret: testClientSecret,
+		RateLimit:    2,
+		Now:          func() time.Time { return now },
+	})
+	token := fake.IssueUserToken(testChannelId, nil)
+
+	doRequest := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/helix/bits/cheermotes", nil)
+		req.Header.Set("client-id", testClientId)
+		req.Header.Set("authorization", "Bearer "+token.AccessToken)
+		res := httptest.NewRecorder()
+		fake.ServeHTTP(res, req)
+		return res
+	}
+	res := doRequest()
+	assert.Equal(t, http.StatusOK, res.Code)
+	assert.Equal(t, "1", res.Header().Get("ratelimit-remaining"))
+	res = doRequest()
+	assert.Equal(t, http.StatusOK, res.Code)
+	assert.Equal(t, "0", res.Header().Get("ratelimit-remaining"))
+	res = doRequest()
+	assert.Equal(t, http.StatusTooManyRequests, res.Code)
+	assert.Equal(t, "873115260", res.Header().Get("ratelimit-reset"))
+
+	// Once the minute is up, our requests should be replenished
+	now = now.Add(30 * time.Second)
+	res = doRequest()
+	assert.Equal(t, http.StatusOK, res.Code)
+	assert.Equal(t, "1", res.Header().Get("ratelimit-remaining"))
+}
+
+func Test_Server_EventSub(t *testing.T) {
+	callback := newTestCallback(t)
+	fake, endpoints := newTestServer(t, func(string) string { return callback.URL })
+	c, err := twitch.NewClientWithAppToken(endpoints, testClientId, testClientSecret)
+	assert.NoError(t, err)
+
+	// Creating a webhook subscription should send a signed challenge to the callback,
+	// enabling the subscription once the callback echoes it
+	payload := &helix.EventSubSubscription{
+		Type:      helix.EventSubTypeStreamOnline,
+		Version:   "1",
+		Condition: helix.EventSubCondition{BroadcasterUserID: testChannelId},
+		Transport: helix.EventSubTransport{
+			Method:   "webhook",
+			Callback: "https://goldenvcr.com/api/showtime/callback",
+			Secret:   testWebhookSecret,
+		},
+	}
+	res, err := c.CreateEventSubSubscription(payload)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusAccepted, res.StatusCode)
+	assert.Len(t, res.Data.EventSubSubscriptions, 1)
+	created := res.Data.EventSubSubscriptions[0]
+	assert.Equal(t, helix.EventSubStatusEnabled, created.Status)
+	assert.Equal(t, "", created.Transport.Secret)
+	assert.Equal(t, []string{"webhook_callback_verification"}, callback.messageTypes())
+
+	// Creating the same subscription again should fail with 409
+	res, err = c.CreateEventSubSubscription(payload)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusConflict, res.StatusCode)
+
+	// A subscription whose callback doesn't answer the challenge should fail
+	callback.rejectChallenges = true
+	payload.Type = helix.EventSubTypeStreamOffline
+	res, err = c.CreateEventSubSubscription(payload)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusAccepted, res.StatusCode)
+	assert.Equal(t, helix.EventSubStatusFailed, res.Data.EventSubSubscriptions[0].Status)
+
+	// Both subscriptions should be listed, but not to a user token
+	list, err := c.GetEventSubSubscriptions(&helix.EventSubSubscriptionsParams{UserID: testChannelId})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, list.Data.Total)
+	userClient, err := twitch.NewClientWithUserToken(endpoints, testClientId, fake.IssueUserToken(testChannelId, nil).AccessToken)
+	assert.NoError(t, err)
+	list, err = userClient.GetEventSubSubscriptions(&helix.EventSubSubscriptionsParams{})
+	assert.NoError(t, err)
+	assert.Equal(t, 0, list.Data.Total)
+
+	// Notifications should only be delivered to enabled subscriptions
+	n, err := fake.Notify(helix.EventSubTypeStreamOnline, helix.EventSubCondition{BroadcasterUserID: testChannelId}, map[string]any{"id": "1234"})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, n)
+	n, err = fake.Notify(helix.EventSubTypeStreamOffline, helix.EventSubCondition{}, map[string]any{})
+	assert.NoError(t, err)
+	assert.Equal(t, 0, n)
+	assert.Equal(t, []string{"webhook_callback_verification", "webhook_callback_verification", "notification"}, callback.messageTypes())
+	assert.JSONEq(t, `{"id":"1234"}`, string(callback.lastEvent()))
+
+	// Revoking a subscription should notify the callback and update its status
+	err = fake.Revoke(created.ID, helix.EventSubStatusAuthorizationRevoked)
+	assert.NoError(t, err)
+	assert.Equal(t, "revocation", callback.messageTypes()[3])
+	assert.Equal(t, helix.EventSubStatusAuthorizationRevoked, fake.Subscriptions()[0].Status)
+
+	// Deleting a subscription should remove it
+	deleted, err := c.RemoveEventSubSubscription(created.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusNoContent, deleted.StatusCode)
+	deleted, err = c.RemoveEventSubSubscription(created.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusNotFound, deleted.StatusCode)
+	assert.Len(t, fake.Subscriptions(), 1)
+}
+
+func Test_Server_EventSubPagination(t *testing.T) {
+	callback := newTestCallback(t)
+	fake := New(Config{
+		ClientId:        testClientId,
+		ClientSecret:    testClientSecret,
+		Users:           testUsers,
+		ResolveCallback: func(string) string { return callback.URL },
+		PageSize:        2,
+	})
+	s := httptest.NewServer(fake)
+	t.Cleanup(s.Close)
+	c, err := twitch.NewClientWithAppToken(fake.Endpoints(s.URL), testClientId, testClientSecret)
+	assert.NoError(t, err)
+
+	for _, subscriptionType := range []string{helix.EventSubTypeStreamOnline, helix.EventSubTypeStreamOffline, helix.EventSubTypeChannelUpdate} {
+		res, err := c.CreateEventSubSubscription(&helix.EventSubSubscription{
+			Type:      subscriptionType,
+			Version:   "1",
+			Condition: helix.EventSubCondition{BroadcasterUserID: testChannelId},
+			Transport: helix.EventSubTransport{
+				Method:   "webhook",
+				Callback: "https://goldenvcr.com/api/showtime/callback",
+				Secret:   testWebhookSecret,
+			},
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusAccepted, res.StatusCode)
+	}
+
+	res, err := c.GetEventSubSubscriptions(&helix.EventSubSubscriptionsParams{})
+	assert.NoError(t, err)
+	assert.Len(t, res.Data.EventSubSubscriptions, 2)
+	assert.Equal(t, 3, res.Data.Total)
+	assert.NotEqual(t, "", res.Data.Pagination.Cursor)
+	res, err = c.GetEventSubSubscriptions(&helix.EventSubSubscriptionsParams{After: res.Data.Pagination.Cursor})
+	assert.NoError(t, err)
+	assert.Len(t, res.Data.EventSubSubscriptions, 1)
+	assert.Equal(t, "", res.Data.Pagination.Cursor)
+}
+
+func Test_Server_Helix(t *testing.T) {
+	fake, endpoints := newTestServer(t, nil)
+	fake.SetStream(helix.Stream{ID: "40001", UserID: testChannelId, UserLogin: "goldenvcr", Title: "Tape 42"})
+	fake.AddVideo(helix.Video{ID: "50001", UserID: testChannelId, Type: "archive", Title: "Tape 41"})
+	fake.AddVideo(helix.Video{ID: "50002", UserID: testChannelId, Type: "highlight", Title: "Tape 41 highlights"})
+	fake.SetChannel(helix.ChannelInformation{BroadcasterID: testChannelId, BroadcasterName: "GoldenVCR", Title: "Tape 42"})
+
+	// Use a bare helix.Client, since twitch.Client only wraps the calls we use in
+	// production
+	token := fake.IssueUserToken(testChannelId, []string{"channel:manage:broadcast"})
+	c, err := helix.NewClient(&helix.Options{
+		ClientID:        testClientId,
+		UserAccessToken: token.AccessToken,
+		APIBaseURL:      endpoints.ApiUrl,
+	})
+	assert.NoError(t, err)
+
+	// With no params, GET /users should identify the owner of the token
+	users, err := c.GetUsers(&helix.UsersParams{})
+	assert.NoError(t, err)
+	assert.Len(t, users.Data.Users, 1)
+	assert.Equal(t, "goldenvcr", users.Data.Users[0].Login)
+
+	streams, err := c.GetStreams(&helix.StreamsParams{UserLogins: []string{"goldenvcr", "someoneelse"}})
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, streams.StatusCode)
+	assert.Len(t, streams.Data.Streams, 1)
+	assert.Equal(t, "Tape 42", streams.Data.Streams[0].Title)
+
+	videos, err := c.GetVideos(&helix.VideosParams{UserID: testChannelId, Type: "archive"})
+	assert.NoError(t, err)
+	assert.Len(t, videos.Data.Videos, 1)
+	assert.Equal(t, "50001", videos.Data.Videos[0].ID)
+
+	edited, err := c.EditChannelInformation(&helix.EditChannelInformationParams{BroadcasterID: testChannelId, Title: "Tape 43"})
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusNoContent, edited.StatusCode)
+	channels, err := c.GetChannelInformation(&helix.GetChannelInformationParams{BroadcasterIDs: []string{testChannelId}})
+	assert.NoError(t, err)
+	assert.Len(t, channels.Data.Channels, 1)
+	assert.Equal(t, "Tape 43", channels.Data.Channels[0].Title)
+
+	// Editing another broadcaster's channel should be rejected
+	edited, err = c.EditChannelInformation(&helix.EditChannelInformationParams{BroadcasterID: "1234", Title: "Hijacked"})
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusUnauthorized, edited.StatusCode)
+}
+
+var testUsers = []helix.User{
+	{ID: testChannelId, Login: "goldenvcr", DisplayName: "GoldenVCR"},
+	{ID: "1234", Login: "someoneelse", DisplayName: "SomeoneElse"},
+}
+
+func newTestServer(t *testing.T, resolveCallback func(string) string) (*Server, twitch.Endpoints) {
+	fake := New(Config{
+		ClientId:        testClientId,
+		ClientSecret:    testClientSecret,
+		Users:           testUsers,
+		ResolveCallback: resolveCallback,
+	})
+	s := httptest.NewServer(fake)
+	t.Cleanup(s.Close)
+	return fake, fake.Endpoints(s.URL)
+}
+
+// testCallback is an EventSub webhook callback that verifies message signatures,
+// echoes challenges (unless rejectChallenges is set), and records what it receives
+type testCallback struct {
+	*httptest.Server
+	t                *testing.T
+	rejectChallenges bool
+
+	mu       sync.Mutex
+	received []string
+	events   []json.RawMessage
+}
+
+func newTestCallback(t *testing.T) *testCallback {
+	cb := &testCallback{t: t}
+	cb.Server = httptest.NewServer(http.HandlerFunc(cb.handle))
+	t.Cleanup(cb.Close)
+	return cb
+}
+
+func (cb *testCallback) handle(res http.ResponseWriter, req *http.Request) {
+	body, err := io.ReadAll(req.Body)
+	assert.NoError(cb.t, err)
+	assert.True(cb.t, helix.VerifyEventSubNotification(testWebhookSecret, req.Header, string(body)))
+
+	var payload struct {
+		Challenge string          `json:"challenge"`
+		Event     json.RawMessage `json:"event"`
+	}
+	assert.NoError(cb.t, json.Unmarshal(body, &payload))
+
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	cb.received = append(cb.received, req.Header.Get("Twitch-Eventsub-Message-Type"))
+	if payload.Event != nil {
+		cb.events = append(cb.events, payload.Event)
+	}
+	if payload.Challenge != "" && !cb.rejectChallenges {
+		res.Write([]byte(payload.Challenge))
+		return
+	}
+	res.WriteHeader(http.StatusOK)
+}
+
+func (cb *testCallback) messageTypes() []string {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	return append([]string(nil), cb.received...)
+}
+
+func (cb *testCallback) lastEvent() json.RawMessage {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	if len(cb.events) == 0 {
+		return nil
+	}
+	return cb.events[len(cb.events)-1]
+}
@@ -0,0 +1,101 @@
+package viewers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/golden-vcr/showtime/gen/queries"
+	"github.com/gorilla/mux"
+)
+
+type Server struct {
+	q Queries
+}
+
+func NewServer(q *queries.Queries) *Server {
+	return &Server{
+		q: q,
+	}
+}
+
+func (s *Server) RegisterRoutes(r *mux.Router) {
+	r.Path("/{id}/subscription").Methods("GET").HandlerFunc(s.handleGetSubscription)
+}
+
+func (s *Server) handleGetSubscription(res http.ResponseWriter, req *http.Request) {
+	twitchUserId, ok := mux.Vars(req)["id"]
+	if !ok || twitchUserId == "" {
+		http.Error(res, "failed to parse 'id' from URL", http.StatusInternalServerError)
+		return
+	}
+
+	// Look up the viewer, returning 404 if we've never seen them
+	viewer, err := s.q.GetViewer(req.Context(), twitchUserId)
+	if errors.Is(err, sql.ErrNoRows) {
+		http.Error(res, "no such viewer", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(res, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	// Get the full history of the viewer's subscriptions, most recent first
+	rows, err := s.q.GetViewerSubscriptions(req.Context(), twitchUserId)
+	if err != nil {
+		http.Error(res, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	status := SubscriptionStatus{
+		TwitchUserId:  viewer.TwitchUserID,
+		DisplayName:   viewer.TwitchDisplayName,
+		Subscriptions: make([]Subscription, 0, len(rows)),
+	}
+	if viewer.FirstSubscribedAt.Valid {
+		status.FirstSubscribedAt = &viewer.FirstSubscribedAt.Time
+	}
+	for i, row := range rows {
+		subscription := Subscription{
+			Tier:             row.Tier,
+			IsGift:           row.IsGift,
+			CumulativeMonths: int(row.CumulativeMonths),
+			StartedAt:        row.StartedAt,
+		}
+		if row.GifterTwitchUserID.Valid {
+			subscription.Gifter = &Gifter{
+				TwitchUserId: row.GifterTwitchUserID.String,
+				DisplayName:  row.GifterTwitchDisplayName.String,
+			}
+		}
+		if row.StreakMonths.Valid {
+			streakMonths := int(row.StreakMonths.Int32)
+			subscription.StreakMonths = &streakMonths
+		}
+		if row.EndedAt.Valid {
+			subscription.EndedAt = &rows[i].EndedAt.Time
+		}
+		status.Subscriptions = append(status.Subscriptions, subscription)
+
+		// The viewer's tenure is the greatest number of cumulative months that Twitch
+		// has reported for any of their subscriptions
+		if subscription.CumulativeMonths > status.CumulativeMonths {
+			status.CumulativeMonths = subscription.CumulativeMonths
+		}
+	}
+
+	// The viewer is currently subscribed if their most recent subscription hasn't
+	// ended
+	if len(status.Subscriptions) > 0 && status.Subscriptions[0].EndedAt == nil {
+		current := &status.Subscriptions[0]
+		status.IsSubscribed = true
+		status.Tier = current.Tier
+		status.StreakMonths = current.StreakMonths
+	}
+
+	if err := json.NewEncoder(res).Encode(status); err != nil {
+		http.Error(res, err.Error(), http.StatusInternalServerError)
+	}
+}
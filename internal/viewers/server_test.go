@@ -0,0 +1,141 @@
+package viewers
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golden-vcr/showtime/gen/queries"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Server_handleGetSubscription(t *testing.T) {
+	tests := []struct {
+		name       string
+		q          *mockQueries
+		wantStatus int
+		wantBody   string
+	}{
+		{
+			"viewer who has never subscribed is not subscribed",
+			&mockQueries{
+				viewers: []queries.GetViewerRow{
+					{TwitchUserID: "1234", TwitchDisplayName: "BigJim"},
+				},
+			},
+			http.StatusOK,
+			`{"twitchUserId":"1234","displayName":"BigJim","isSubscribed":false,"firstSubscribedAt":null,"cumulativeMonths":0,"streakMonths":null,"subscriptions":[]}`,
+		},
+		{
+			"viewer with ongoing subscription is subscribed, with tenure from all subscriptions",
+			&mockQueries{
+				viewers: []queries.GetViewerRow{
+					{
+						TwitchUserID:      "1234",
+						TwitchDisplayName: "BigJim",
+						FirstSubscribedAt: sql.NullTime{Valid: true, Time: time.Date(1997, 9, 1, 12, 0, 0, 0, time.UTC)},
+					},
+				},
+				subscriptionRows: []queries.GetViewerSubscriptionsRow{
+					{
+						Tier:             "2000",
+						IsGift:           false,
+						CumulativeMonths: 4,
+						StreakMonths:     sql.NullInt32{Valid: true, Int32: 1},
+						StartedAt:        time.Date(1998, 3, 1, 12, 0, 0, 0, time.UTC),
+					},
+					{
+						Tier:                    "1000",
+						IsGift:                  true,
+						GifterTwitchUserID:      sql.NullString{Valid: true, String: "5678"},
+						GifterTwitchDisplayName: sql.NullString{Valid: true, String: "Gifter"},
+						CumulativeMonths:        3,
+						StartedAt:               time.Date(1997, 9, 1, 12, 0, 0, 0, time.UTC),
+						EndedAt:                 sql.NullTime{Valid: true, Time: time.Date(1997, 12, 1, 12, 0, 0, 0, time.UTC)},
+					},
+				},
+			},
+			http.StatusOK,
+			`{"twitchUserId":"1234","displayName":"BigJim","isSubscribed":true,"tier":"2000","firstSubscribedAt":"1997-09-01T12:00:00Z","cumulativeMonths":4,"streakMonths":1,"subscriptions":[{"tier":"2000","isGift":false,"gifter":null,"cumulativeMonths":4,"streakMonths":1,"startedAt":"1998-03-01T12:00:00Z","endedAt":null},{"tier":"1000","isGift":true,"gifter":{"twitchUserId":"5678","displayName":"Gifter"},"cumulativeMonths":3,"streakMonths":null,"startedAt":"1997-09-01T12:00:00Z","endedAt":"1997-12-01T12:00:00Z"}]}`,
+		},
+		{
+			"viewer whose subscription has ended is not subscribed",
+			&mockQueries{
+				viewers: []queries.GetViewerRow{
+					{TwitchUserID: "1234", TwitchDisplayName: "BigJim"},
+				},
+				subscriptionRows: []queries.GetViewerSubscriptionsRow{
+					{
+						Tier:             "1000",
+						CumulativeMonths: 1,
+						StartedAt:        time.Date(1997, 9, 1, 12, 0, 0, 0, time.UTC),
+						EndedAt:          sql.NullTime{Valid: true, Time: time.Date(1997, 10, 1, 12, 0, 0, 0, time.UTC)},
+					},
+				},
+			},
+			http.StatusOK,
+			`{"twitchUserId":"1234","displayName":"BigJim","isSubscribed":false,"firstSubscribedAt":null,"cumulativeMonths":1,"streakMonths":null,"subscriptions":[{"tier":"1000","isGift":false,"gifter":null,"cumulativeMonths":1,"streakMonths":null,"startedAt":"1997-09-01T12:00:00Z","endedAt":"1997-10-01T12:00:00Z"}]}`,
+		},
+		{
+			"unknown viewer is a 404",
+			&mockQueries{},
+			http.StatusNotFound,
+			"no such viewer",
+		},
+		{
+			"database error is a 500",
+			&mockQueries{
+				err: fmt.Errorf("mock error"),
+			},
+			http.StatusInternalServerError,
+			"mock error",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := Server{q: tt.q}
+			req := httptest.NewRequest(http.MethodGet, "/1234/subscription", nil)
+			req = mux.SetURLVars(req, map[string]string{"id": "1234"})
+			res := httptest.NewRecorder()
+			s.handleGetSubscription(res, req)
+
+			b, err := io.ReadAll(res.Body)
+			assert.NoError(t, err)
+			body := strings.TrimSuffix(string(b), "\n")
+			assert.Equal(t, tt.wantStatus, res.Code)
+			assert.Equal(t, tt.wantBody, body)
+		})
+	}
+}
+
+type mockQueries struct {
+	err              error
+	viewers          []queries.GetViewerRow
+	subscriptionRows []queries.GetViewerSubscriptionsRow
+}
+
+func (m *mockQueries) GetViewer(ctx context.Context, twitchUserID string) (queries.GetViewerRow, error) {
+	if m.err != nil {
+		return queries.GetViewerRow{}, m.err
+	}
+	for _, v := range m.viewers {
+		if v.TwitchUserID == twitchUserID {
+			return v, nil
+		}
+	}
+	return queries.GetViewerRow{}, sql.ErrNoRows
+}
+
+func (m *mockQueries) GetViewerSubscriptions(ctx context.Context, twitchUserID string) ([]queries.GetViewerSubscriptionsRow, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	return m.subscriptionRows, nil
+}
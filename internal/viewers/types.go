@@ -0,0 +1,45 @@
+package viewers
+
+import (
+	"context"
+	"time"
+
+	"github.com/golden-vcr/showtime/gen/queries"
+)
+
+type Queries interface {
+	GetViewer(ctx context.Context, twitchUserID string) (queries.GetViewerRow, error)
+	GetViewerSubscriptions(ctx context.Context, twitchUserID string) ([]queries.GetViewerSubscriptionsRow, error)
+}
+
+// SubscriptionStatus describes whether a viewer is currently subscribed, along with
+// their tenure as a subscriber and the history of their subscriptions, most recent
+// first
+type SubscriptionStatus struct {
+	TwitchUserId      string         `json:"twitchUserId"`
+	DisplayName       string         `json:"displayName"`
+	IsSubscribed      bool           `json:"isSubscribed"`
+	Tier              string         `json:"tier,omitempty"`
+	FirstSubscribedAt *time.Time     `json:"firstSubscribedAt"`
+	CumulativeMonths  int            `json:"cumulativeMonths"`
+	StreakMonths      *int           `json:"streakMonths"`
+	Subscriptions     []Subscription `json:"subscriptions"`
+}
+
+// Subscription is a period of time during which a viewer was subscribed: EndedAt is
+// nil if the subscription is ongoing
+type Subscription struct {
+	Tier             string     `json:"tier"`
+	IsGift           bool       `json:"isGift"`
+	Gifter           *Gifter    `json:"gifter"`
+	CumulativeMonths int        `json:"cumulativeMonths"`
+	StreakMonths     *int       `json:"streakMonths"`
+	StartedAt        time.Time  `json:"startedAt"`
+	EndedAt          *time.Time `json:"endedAt"`
+}
+
+// Gifter identifies the user who gifted a subscription
+type Gifter struct {
+	TwitchUserId string `json:"twitchUserId"`
+	DisplayName  string `json:"displayName"`
+}